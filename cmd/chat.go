@@ -2,33 +2,50 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/Arvintian/chat-agent/pkg/chatbot"
 	"github.com/Arvintian/chat-agent/pkg/config"
 	"github.com/Arvintian/chat-agent/pkg/logger"
+	"github.com/Arvintian/chat-agent/pkg/providers"
 	"github.com/Arvintian/chat-agent/pkg/utils"
 
 	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
 
 	"github.com/Arvintian/readline"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 var (
 	configPath          string
+	configProfile       string
 	disableLocalCommand bool
 	startAt             string
 	once                string
+	outputFormat        string
+	showToolResults     bool
+	markdownStream      bool
+	promptLogPath       string
+	auditLogPath        string
+	dryRun              bool
 )
 
+// errTaskFailed exits with a non-zero status after a one-time task failed, the error
+// was already printed
+var errTaskFailed = errors.New("task failed")
+
 // Global variables for chat switching functionality
 var (
 	availableChats  map[string]config.Chat
@@ -60,7 +77,11 @@ func switchChat(ctx context.Context, cfg *config.Config, chatName string, debug
 		}
 	}
 
-	return chatbot.InitChatSession(ctx, cfg, chatName, sessionID, debug)
+	session, err := chatbot.InitChatSession(ctx, cfg, chatName, sessionID, debug)
+	if err == nil && session.MCPInitErr != nil {
+		fmt.Printf("Warning: some MCP servers failed to initialize: %v\n", session.MCPInitErr)
+	}
+	return session, err
 }
 
 // RootCmd represents the base command when called without any subcommands
@@ -69,14 +90,41 @@ var RootCmd = &cobra.Command{
 	Short: "Chat Agent CLI tool",
 	Long:  `A command line interface for llm agent`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		// Input piped to stdin is a one-time task, appended to the --once prompt if any
+		piped, err := readPipedInput()
+		if err != nil {
+			return err
+		}
+		if piped != "" {
+			once = joinPrompt(once, piped)
+		}
+		switch {
+		case outputFormat != "text" && outputFormat != "json":
+			return fmt.Errorf("unsupported output format %q, use text or json", outputFormat)
+		case outputFormat == "json" && once == "":
+			return fmt.Errorf("--output json is only supported with --once or piped input")
+		}
 		if err := logger.Init(); err != nil {
 			return err
 		}
+		if debug, _ := cmd.Flags().GetBool("debug"); debug {
+			providers.SetDebugLog(true)
+		}
+		closePromptLog, err := openPromptLog()
+		if err != nil {
+			return err
+		}
+		defer closePromptLog()
 		// Load configuration file
-		cfg, err := config.LoadConfig(configPath)
+		cfg, err := config.LoadConfigProfile(configPath, configProfile)
 		if err != nil {
 			return err
 		}
+		closeAuditLog, err := openAuditLog(cfg)
+		if err != nil {
+			return err
+		}
+		defer closeAuditLog()
 
 		// Store available chats globally
 		availableChats = cfg.Chats
@@ -113,14 +161,34 @@ var RootCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		if session.MCPInitErr != nil && outputFormat == "json" {
+			fmt.Fprintf(os.Stderr, "Warning: some MCP servers failed to initialize: %v\n", session.MCPInitErr)
+		} else if session.MCPInitErr != nil && !dryRun {
+			fmt.Printf("Warning: some MCP servers failed to initialize: %v\n", session.MCPInitErr)
+		}
 		defer func() {
 			if session != nil {
 				if err := session.Close(); err != nil {
-					fmt.Printf("Error closing session: %v\n", err)
+					fmt.Fprintf(os.Stderr, "Error closing session: %v\n", err)
 				}
 			}
 		}()
 
+		// show what would be sent to the model and exit
+		if dryRun {
+			return printDryRun(session)
+		}
+
+		// one-time task with JSON events on stdout, for scripting
+		if outputFormat == "json" {
+			return taskResult(cmd, runOnceJSON(cmd.Context(), debug, session))
+		}
+
+		// piped input runs without readline, stdin is not a terminal
+		if piped != "" {
+			return taskResult(cmd, runOnce(cmd.Context(), debug, session))
+		}
+
 		// init readline
 		placeholder := "Send a message (/h for help)"
 		homeDir, err := os.UserHomeDir()
@@ -141,7 +209,13 @@ var RootCmd = &cobra.Command{
 		fmt.Print(readline.StartBracketedPaste)
 		defer fmt.Printf(readline.EndBracketedPaste)
 
+		// The welcome message supports the same template variables as the system prompt
 		welcome, _ := cmd.Flags().GetString("welcome")
+		if rendered, err := chatbot.RenderSystemPrompt(welcome); err != nil {
+			logger.Warn("chat", fmt.Sprintf("Failed to render welcome message: %v", err))
+		} else {
+			welcome = rendered
+		}
 		fmt.Printf("%s\n", welcome)
 
 		// Display loaded context info if any
@@ -151,8 +225,7 @@ var RootCmd = &cobra.Command{
 		}
 
 		// init chatbot with persistence store
-		persistenceStore := session.PersistenceStore()
-		cb := chatbot.NewChatBot(context.WithValue(cmd.Context(), "debug", debug), session.Agent, session.Manager, scanner, persistenceStore)
+		cb := newChatBot(cmd.Context(), debug, session, scanner)
 
 		// ignore ctrl+c and break llm generate
 		var chatCancel context.CancelFunc = func() {}
@@ -169,23 +242,25 @@ var RootCmd = &cobra.Command{
 		chatctx, cancel := context.WithCancel(cmd.Context())
 		chatCancel = cancel
 		if startAt != "" {
-			err = cb.StreamChat(chatctx, startAt)
+			err = cb.StreamChat(chatctx, startAt, nil)
 			if err != nil {
 				os.Stderr.WriteString("\nerror: " + err.Error() + "\n")
 				return nil
 			}
 		} else if once != "" {
 			// one-time task or chat
-			err = cb.StreamChat(chatctx, once)
+			err = cb.StreamChat(chatctx, once, nil)
 			if err != nil {
 				os.Stderr.WriteString("\nerror: " + err.Error() + "\n")
 			}
-			return nil
+			return taskResult(cmd, err)
 		}
 
 		// chat loop
 		var sb strings.Builder
 		var multiline MultilineState
+		// files attached with /file to the next message
+		var attachments []chatbot.FileData
 		for {
 			if scanner.Prompt.Placeholder != placeholder {
 				scanner.Prompt.Placeholder = placeholder
@@ -250,6 +325,81 @@ var RootCmd = &cobra.Command{
 					sb.Reset()
 					continue
 				}
+				// change a CLI setting with /set, eg: `/set toolresults on`
+				if strings.HasPrefix(input, "/set ") {
+					handleSet(strings.Fields(strings.TrimPrefix(input, "/set")), &cb)
+					sb.Reset()
+					continue
+				}
+				// export or import the conversation, eg: `/save chat.json`, `/load chat.json --force`
+				if strings.HasPrefix(input, "/save ") {
+					if err := saveConversation(strings.TrimSpace(strings.TrimPrefix(input, "/save")), session); err != nil {
+						fmt.Printf("Error saving conversation: %v\n", err)
+					}
+					sb.Reset()
+					continue
+				}
+				if strings.HasPrefix(input, "/load ") {
+					if err := loadConversation(strings.TrimSpace(strings.TrimPrefix(input, "/load")), session); err != nil {
+						fmt.Printf("Error loading conversation: %v\n", err)
+					}
+					sb.Reset()
+					continue
+				}
+				// keep the session with a label for the hook, eg: `/keep bug-report`
+				if strings.HasPrefix(input, "/keep ") || strings.HasPrefix(input, "/k ") {
+					label := strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(input, "/keep"), "/k"))
+					if err := session.OnKeep(label); err != nil {
+						fmt.Printf("Error executing keep hook: %v\n", err)
+					} else {
+						fmt.Printf("Session keep hook executed successfully with label %q\n", label)
+					}
+					sb.Reset()
+					continue
+				}
+				// pin a user message so it is never summarized, eg: `/pin 1`, `/unpin`
+				if input == "/pin" || strings.HasPrefix(input, "/pin ") {
+					handlePin(strings.TrimSpace(strings.TrimPrefix(input, "/pin")), session, true)
+					sb.Reset()
+					continue
+				}
+				if input == "/unpin" || strings.HasPrefix(input, "/unpin ") {
+					handlePin(strings.TrimSpace(strings.TrimPrefix(input, "/unpin")), session, false)
+					sb.Reset()
+					continue
+				}
+				// attach a file to the next message, eg: `/file chart.png`, `/file clear`
+				if input == "/file" || strings.HasPrefix(input, "/file ") {
+					attachments = handleFile(strings.TrimSpace(strings.TrimPrefix(input, "/file")), session, attachments)
+					sb.Reset()
+					continue
+				}
+				// print the content of a skill, eg: `/skill pdf`
+				if input == "/skill" || strings.HasPrefix(input, "/skill ") {
+					printSkill(chatctx, session, strings.TrimSpace(strings.TrimPrefix(input, "/skill")))
+					sb.Reset()
+					continue
+				}
+				// switch the model of the chat, eg: `/model gpt-4o`
+				if strings.HasPrefix(input, "/model ") {
+					modelName := strings.TrimSpace(strings.TrimPrefix(input, "/model"))
+					if err := session.SwitchModel(cmd.Context(), cfg, modelName); err != nil {
+						fmt.Printf("Error switching model: %v\n", err)
+					} else {
+						cb = newChatBot(cmd.Context(), debug, session, scanner)
+						fmt.Printf("Switched to model: %s\n", modelName)
+					}
+					sb.Reset()
+					continue
+				}
+				// turn the thinking of the model on or off, eg: `/think off`
+				if input == "/think" || strings.HasPrefix(input, "/think ") {
+					if handleThink(cmd.Context(), strings.TrimSpace(strings.TrimPrefix(input, "/think")), cfg, session) {
+						cb = newChatBot(cmd.Context(), debug, session, scanner)
+					}
+					sb.Reset()
+					continue
+				}
 				// switch chat start with /s, eg: `/s code`
 				if strings.HasPrefix(input, "/s ") {
 					targetName := strings.TrimSpace(strings.TrimPrefix(input, "/s"))
@@ -260,8 +410,7 @@ var RootCmd = &cobra.Command{
 					} else {
 						session = newSession
 						currentChatName = targetName
-						persistenceStore := session.PersistenceStore()
-						cb = chatbot.NewChatBot(context.WithValue(cmd.Context(), "debug", debug), session.Agent, session.Manager, scanner, persistenceStore)
+						cb = newChatBot(cmd.Context(), debug, session, scanner)
 						fmt.Printf("Switched to chat: %s\n", targetName)
 					}
 					sb.Reset()
@@ -274,24 +423,23 @@ var RootCmd = &cobra.Command{
 				case "/clear", "/c":
 					session.Clear()
 					fmt.Println("The conversation context is cleared")
-				case "/redo", "/r":
-					lastMsg := session.GetLastUserMessage()
-					if lastMsg == "" {
-						fmt.Println("No previous user message to redo")
+				case "/retry", "/redo", "/r":
+					lastMsg := session.PopLastAssistantRound()
+					if lastMsg == nil {
+						fmt.Println("No previous user message to retry")
 					} else {
-						session.RemoveLastRound()
-						fmt.Printf("Redoing last message: %s\n", lastMsg)
-						chatctx, cancel := context.WithCancel(cmd.Context())
-						chatCancel = cancel
-						err = cb.StreamChat(chatctx, lastMsg)
+						fmt.Printf("Retrying last message: %s\n", lastMsg.Content)
+						err = cb.StreamChat(chatctx, lastMsg.Content, nil)
 						session, cb = handleStreamError(err, cmd.Context(), cfg, debug, session, sessionID, scanner, cb)
 					}
 				case "/keep", "/k":
-					if err := session.OnKeep(); err != nil {
+					if err := session.OnKeep(""); err != nil {
 						fmt.Printf("Error executing keep hook: %v\n", err)
 					} else {
 						fmt.Println("Session keep hook executed successfully")
 					}
+				case "/compact":
+					handleCompact(chatctx, session)
 				case "/history", "/i":
 					os.Stdout.WriteString(session.Manager.GetSummary())
 					fmt.Println()
@@ -302,13 +450,24 @@ var RootCmd = &cobra.Command{
 					}
 				case "/tools", "/l":
 					printTools(session.Tools)
+				case "/tools reload", "/l reload":
+					cfg, session, cb = reloadTools(cmd.Context(), cfg, debug, session, scanner, cb)
 				case "/chat":
 					printChats()
+				case "/skills":
+					printSkills(session)
+				case "/model":
+					printModels(cfg, session.Preset.Model)
 				case "/quit", "/exit", "/bye", "/q":
 					os.Stdout.WriteString("bye!\n")
 					return nil
 				default:
-					err = cb.StreamChat(chatctx, input)
+					message, files := input, attachments
+					if len(files) > 0 {
+						message, files = prepareFiles(chatctx, session, message, files)
+						attachments = nil
+					}
+					err = cb.StreamChat(chatctx, message, files)
 					session, cb = handleStreamError(err, cmd.Context(), cfg, debug, session, sessionID, scanner, cb)
 				}
 				sb.Reset()
@@ -317,22 +476,385 @@ var RootCmd = &cobra.Command{
 	},
 }
 
+// openPromptLog enables the prompt log when --prompt-log is set and returns a function closing it
+func openPromptLog() (func(), error) {
+	if promptLogPath == "" {
+		return func() {}, nil
+	}
+	promptLog, err := chatbot.OpenPromptLog(promptLogPath)
+	if err != nil {
+		return nil, err
+	}
+	chatbot.SetPromptLog(promptLog)
+	return func() {
+		chatbot.SetPromptLog(nil)
+		promptLog.Close()
+	}, nil
+}
+
+// openAuditLog enables the audit log when --audit-log or the audit path of the config is
+// set and returns a function closing it
+func openAuditLog(cfg *config.Config) (func(), error) {
+	var audit config.Audit
+	if cfg.Audit != nil {
+		audit = *cfg.Audit
+	}
+	if auditLogPath != "" {
+		audit.Path = auditLogPath
+	}
+	if audit.Path == "" {
+		return func() {}, nil
+	}
+	auditLog, err := chatbot.OpenAuditLog(audit.Path, audit.MaxSize, audit.MaxBackups)
+	if err != nil {
+		return nil, err
+	}
+	chatbot.SetAuditLog(auditLog)
+	return func() {
+		chatbot.SetAuditLog(nil)
+		auditLog.Close()
+	}, nil
+}
+
 func printHelp() {
 	fmt.Println("Available commands:")
 	fmt.Println("  /help    or /h   - Show this help message")
 	fmt.Println("  /history or /i   - Get conversation history")
 	fmt.Println("  /clear   or /c   - Clear conversation context")
-	fmt.Println("  /redo    or /r   - Redo last round")
-	fmt.Println("  /keep    or /k   - Execute session keep hook")
+	fmt.Println("  /retry   or /r   - Regenerate the last response (also /redo)")
+	fmt.Println("  /keep    or /k [label] - Execute session keep hook, passing an optional label")
 	fmt.Println("  /tools   or /l   - List the loaded tools")
+	fmt.Println("  /tools reload    - Reload the configuration and re-initialize tools")
 	fmt.Println("  /chat            - List available chats")
 	fmt.Println("  /s <name>        - Switch to another chat directly")
+	fmt.Println("  /skills          - List the skills of the chat")
+	fmt.Println("  /skill <name>    - Print the content of a skill")
+	fmt.Println("  /model [name]    - List the models or switch the model of the chat")
+	fmt.Println("  /think [on|off]  - Show, or turn on or off, the thinking of the model")
+	fmt.Println("  /compact         - Summarize the context except the last round now")
+	fmt.Println("  /pin [n]         - Pin the n-th user message (default: the last) so it is never summarized")
+	fmt.Println("  /unpin [n]       - Unpin the n-th pinned message (default: all)")
+	fmt.Println("  /file [path]     - Attach a file to the next message, or list the attached files")
+	fmt.Println("  /file clear      - Drop the attached files")
+	fmt.Println("  /set toolresults on|off - Show or hide tool results")
+	fmt.Println("  /save <path>     - Save the conversation to a JSON file")
+	fmt.Println("  /load <path> [--force] - Load a saved conversation, --force loads one of another chat")
 	if !disableLocalCommand {
 		fmt.Println("  /t <cmd>         - Execute local command")
 	}
 	fmt.Println("  /exit    or /q   - Exit program")
 }
 
+// savedConversation is the file format of /save and /load
+type savedConversation struct {
+	Chat     string            `json:"chat"`
+	SavedAt  time.Time         `json:"saved_at"`
+	Messages []*schema.Message `json:"messages"`
+}
+
+// saveConversation writes the current conversation context to a JSON file
+func saveConversation(path string, session *chatbot.ChatSession) error {
+	if path == "" {
+		return fmt.Errorf("usage: /save <path>")
+	}
+	path, err := utils.ExpandPath(path)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(savedConversation{
+		Chat:     session.Name,
+		SavedAt:  time.Now(),
+		Messages: session.Manager.GetMessages(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	fmt.Printf("Conversation saved to %s\n", path)
+	return nil
+}
+
+// loadConversation replaces the conversation context with a saved one. A conversation
+// saved from another chat is only loaded with --force.
+func loadConversation(args string, session *chatbot.ChatSession) error {
+	path, force := strings.CutSuffix(args, "--force")
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return fmt.Errorf("usage: /load <path> [--force]")
+	}
+	path, err := utils.ExpandPath(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var saved savedConversation
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("invalid conversation file: %w", err)
+	}
+	if saved.Chat != session.Name && !force {
+		return fmt.Errorf("conversation was saved from chat %q, not %q, append --force to load it anyway", saved.Chat, session.Name)
+	}
+	session.ReplaceMessages(saved.Messages)
+	fmt.Printf("Loaded %d messages saved at %s\n", session.GetMessageCount(), saved.SavedAt.Format(time.RFC3339))
+	return nil
+}
+
+// newChatBot creates the CLI chatbot for a session, applying the current CLI settings
+func newChatBot(ctx context.Context, debug bool, session *chatbot.ChatSession, scanner *readline.Instance) chatbot.ChatBot {
+	cb := chatbot.NewChatBot(context.WithValue(ctx, "debug", debug), session.Agent, session.Manager, scanner, session.PersistenceStore())
+	cb.SetApprovalMemory(session.Approvals)
+	cb.SetStoreReasoning(session.Preset.StoresReasoning())
+	cb.SetReasoningDisplay(session.Preset.ReasoningDisplay)
+	cb.SetMaxChunkLength(session.Preset.MaxChunkLength)
+	cb.SetShowToolResults(showToolResults)
+	cb.SetMarkdownStream(markdownStream)
+	cb.SetResponseHook(session.OnResponse)
+	return cb
+}
+
+// runOnceJSON runs the one-time task, writing its events to stdout as newline-delimited JSON.
+// Errors are reported as error events, like the other events of the turn.
+func runOnceJSON(ctx context.Context, debug bool, session *chatbot.ChatSession) error {
+	cb := newChatBot(ctx, debug, session, nil)
+	cb.SetHandler(chatbot.NewJSONChatHandler(os.Stdout))
+
+	chatctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	return cb.StreamChatWithHandler(chatctx, once, nil)
+}
+
+// runOnce runs the one-time task of piped input. There is no terminal to ask, tool calls
+// that require approval are denied and questions get an empty answer.
+func runOnce(ctx context.Context, debug bool, session *chatbot.ChatSession) error {
+	cb := newChatBot(ctx, debug, session, nil)
+
+	chatctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	if err := cb.StreamChat(chatctx, once, nil); err != nil {
+		os.Stderr.WriteString("\nerror: " + err.Error() + "\n")
+		return err
+	}
+	return nil
+}
+
+// taskResult turns the error of a one-time task into a non-zero exit status, without
+// printing it again
+func taskResult(cmd *cobra.Command, err error) error {
+	if err == nil {
+		return nil
+	}
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	return errTaskFailed
+}
+
+// readPipedInput reads all of stdin when it is not a terminal. It returns an empty
+// string for a terminal, or when nothing was piped, e.g. stdin is /dev/null.
+func readPipedInput() (string, error) {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", nil
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("failed to read stdin: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// joinPrompt appends the piped input to the prompt, separated by a blank line
+func joinPrompt(prompt, piped string) string {
+	if prompt == "" {
+		return piped
+	}
+	return prompt + "\n\n" + piped
+}
+
+// handleSet changes a CLI setting, eg: `toolresults on`
+func handleSet(args []string, cb *chatbot.ChatBot) {
+	if len(args) != 2 {
+		fmt.Println("Usage: /set toolresults on|off")
+		return
+	}
+	switch args[0] {
+	case "toolresults":
+		switch args[1] {
+		case "on":
+			showToolResults = true
+		case "off":
+			showToolResults = false
+		default:
+			fmt.Println("Usage: /set toolresults on|off")
+			return
+		}
+		cb.SetShowToolResults(showToolResults)
+		fmt.Printf("Tool results display: %s\n", args[1])
+	default:
+		fmt.Printf("Unknown setting: %s\n", args[0])
+	}
+}
+
+// handleFile attaches a file to the next message, lists the attached files when arg is
+// empty or drops them on `clear`. It returns the attached files.
+func handleFile(arg string, session *chatbot.ChatSession, attachments []chatbot.FileData) []chatbot.FileData {
+	switch arg {
+	case "":
+		if len(attachments) == 0 {
+			fmt.Println("No attached files, usage: /file <path>")
+			return attachments
+		}
+		fmt.Println("Attached files:")
+		for _, file := range attachments {
+			fmt.Printf("  - %s (%s, %d bytes)\n", file.Name, file.Type, file.FileSize)
+		}
+		return attachments
+	case "clear":
+		fmt.Printf("Dropped %d attached files\n", len(attachments))
+		return nil
+	}
+	path, err := utils.ExpandPath(arg)
+	if err != nil {
+		fmt.Printf("Error attaching file: %v\n", err)
+		return attachments
+	}
+	file, err := chatbot.LoadFile(path)
+	if err != nil {
+		fmt.Printf("Error attaching file: %v\n", err)
+		return attachments
+	}
+	attachments = append(attachments, file)
+	fmt.Printf("Attached %s (%s, %d bytes), %d files will be sent with the next message\n", file.Name, file.Type, file.FileSize, len(attachments))
+	if !session.Multimodal() {
+		fmt.Printf("Warning: model %s is not configured as multimodal, it may not read the file\n", session.Preset.Model)
+	}
+	return attachments
+}
+
+// handleCompact summarizes the context except the last round and reports its new size
+func handleCompact(ctx context.Context, session *chatbot.ChatSession) {
+	result, err := session.Manager.ForceCompress(ctx)
+	if err != nil {
+		fmt.Printf("Error compacting the context: %v\n", err)
+		return
+	}
+	fmt.Printf("Compacted %d rounds, the context has %d messages (~%d tokens)\n", result.Rounds, result.Messages, result.Tokens)
+}
+
+// handlePin pins or unpins a message of the context and prints the pinned messages. arg
+// is the number of the message, empty for the default one.
+func handlePin(arg string, session *chatbot.ChatSession, pin bool) {
+	n := 0
+	if arg != "" {
+		var err error
+		if n, err = strconv.Atoi(arg); err != nil || n < 1 {
+			fmt.Println("Usage: /pin [n] or /unpin [n], n counts from 1")
+			return
+		}
+	}
+	if pin {
+		if _, err := session.Manager.PinMessage(n); err != nil {
+			fmt.Printf("Error pinning message: %v\n", err)
+			return
+		}
+	} else if err := session.Manager.UnpinMessage(n); err != nil {
+		fmt.Printf("Error unpinning message: %v\n", err)
+		return
+	}
+	pinned := session.Manager.PinnedMessages()
+	if len(pinned) == 0 {
+		fmt.Println("No pinned messages")
+		return
+	}
+	fmt.Println("Pinned messages:")
+	for i, msg := range pinned {
+		fmt.Printf("  %d. %s\n", i+1, chatbot.TruncateToolResult(strings.Join(strings.Fields(msg.Content), " "), 80))
+	}
+}
+
+// printSkills lists the skills of the session with their descriptions
+func printSkills(session *chatbot.ChatSession) {
+	if session.Skills == nil {
+		fmt.Println("No skills are configured for this chat")
+		return
+	}
+	skills := session.Skills.GetMetadata()
+	if len(skills) == 0 {
+		fmt.Println("No skills found in the skill directory")
+		return
+	}
+	fmt.Println("Skills:")
+	for _, skill := range skills {
+		fmt.Printf("  - %s: %s\n", skill.Name, skill.Description)
+	}
+}
+
+// printSkill prints the full content of a skill of the session
+func printSkill(ctx context.Context, session *chatbot.ChatSession, name string) {
+	if name == "" {
+		fmt.Println("Usage: /skill <name>, /skills lists the skills")
+		return
+	}
+	if session.Skills == nil {
+		fmt.Println("No skills are configured for this chat")
+		return
+	}
+	content, err := session.Skills.GetContent(ctx, name)
+	if err != nil {
+		fmt.Printf("Error loading skill: %v\n", err)
+		return
+	}
+	fmt.Println(content)
+}
+
+// printDryRun prints the rendered system prompt, the tools with their parameters and the
+// status of the MCP servers of the session
+func printDryRun(session *chatbot.ChatSession) error {
+	inspection, err := session.Inspect()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Chat: %s (model: %s)\n", session.Name, session.Preset.Model)
+	fmt.Printf("\n=== System prompt ===\n%s\n", inspection.SystemPrompt)
+	if inspection.InitSystemPrompt != "" {
+		fmt.Printf("\n=== System prompt of the first round (initSystem) ===\n%s\n", inspection.InitSystemPrompt)
+	}
+
+	fmt.Printf("\n=== Tools (%d) ===\n", len(inspection.Tools))
+	for _, info := range inspection.Tools {
+		fmt.Printf("(%s) %s\n", info.Name, strings.TrimSpace(info.Desc))
+		if info.ParamsOneOf == nil {
+			continue
+		}
+		params, err := info.ParamsOneOf.ToJSONSchema()
+		if err != nil {
+			fmt.Printf("  parameters: invalid schema: %v\n", err)
+			continue
+		}
+		raw, err := json.Marshal(params)
+		if err != nil {
+			fmt.Printf("  parameters: invalid schema: %v\n", err)
+			continue
+		}
+		fmt.Printf("  parameters: %s\n", raw)
+	}
+
+	if len(inspection.MCPServers) > 0 {
+		fmt.Printf("\n=== MCP servers ===\n")
+		for _, server := range inspection.MCPServers {
+			if server.Err != nil {
+				fmt.Printf("%s: failed, %v\n", server.Name, server.Err)
+			} else {
+				fmt.Printf("%s: ok, %d tools\n", server.Name, server.Tools)
+			}
+		}
+	}
+	return nil
+}
+
 func printTools(tools []tool.BaseTool) {
 	for _, item := range tools {
 		info, err := item.Info(context.TODO())
@@ -349,6 +871,44 @@ func printTools(tools []tool.BaseTool) {
 	}
 }
 
+// reloadTools re-reads the configuration and rebuilds the session's tools and agent while
+// keeping the conversation context. Returns the (possibly new) config, session and chatbot.
+func reloadTools(ctx context.Context, cfg *config.Config, debug bool, session *chatbot.ChatSession, scanner *readline.Instance, cb chatbot.ChatBot) (*config.Config, *chatbot.ChatSession, chatbot.ChatBot) {
+	newCfg, err := config.LoadConfigProfile(configPath, configProfile)
+	if err != nil {
+		fmt.Printf("Error reloading config, keeping the current one: %v\n", err)
+		newCfg = cfg
+	}
+	newSession, err := chatbot.ReloadChatSession(ctx, newCfg, session, debug)
+	if err != nil {
+		fmt.Printf("Error reloading tools: %v\n", err)
+		return cfg, session, cb
+	}
+	if newSession.MCPInitErr != nil {
+		fmt.Printf("Warning: some MCP servers failed to initialize: %v\n", newSession.MCPInitErr)
+	}
+	availableChats = newCfg.Chats
+	added, removed := chatbot.DiffToolNames(ctx, session.Tools, newSession.Tools)
+	fmt.Println(toolChangesMessage(len(newSession.Tools), added, removed))
+	newCB := newChatBot(ctx, debug, newSession, scanner)
+	return newCfg, newSession, newCB
+}
+
+// toolChangesMessage describes the result of a tool reload
+func toolChangesMessage(total int, added, removed []string) string {
+	msg := fmt.Sprintf("Reloaded %d tools", total)
+	if len(added) == 0 && len(removed) == 0 {
+		return msg + ", no changes"
+	}
+	if len(added) > 0 {
+		msg += fmt.Sprintf(", added: %s", strings.Join(added, ", "))
+	}
+	if len(removed) > 0 {
+		msg += fmt.Sprintf(", removed: %s", strings.Join(removed, ", "))
+	}
+	return msg
+}
+
 // printChats prints the list of available chats
 func printChats() {
 	fmt.Println("Available chats:")
@@ -367,16 +927,68 @@ func printChats() {
 	}
 }
 
+// handleThink turns the thinking of the session's model on or off, or prints whether it
+// thinks without an argument. It reports whether the agent was rebuilt.
+func handleThink(ctx context.Context, arg string, cfg *config.Config, session *chatbot.ChatSession) bool {
+	var enabled bool
+	switch arg {
+	case "":
+		state := "off"
+		if session.Thinking(cfg) {
+			state = "on"
+		}
+		fmt.Printf("Thinking is %s for model %s\n", state, session.Preset.Model)
+		return false
+	case "on":
+		enabled = true
+	case "off":
+		enabled = false
+	default:
+		fmt.Println("Usage: /think [on|off]")
+		return false
+	}
+	if err := session.SetThinking(ctx, cfg, enabled); err != nil {
+		fmt.Printf("Error setting thinking: %v\n", err)
+		return false
+	}
+	fmt.Printf("Thinking turned %s for model %s\n", arg, session.Preset.Model)
+	return true
+}
+
+// printModels prints the models of the config
+func printModels(cfg *config.Config, current string) {
+	fmt.Println("Available models:")
+	for _, name := range sortedKeys(cfg.Models) {
+		marker := ""
+		if name == current {
+			marker = " (current)"
+		}
+		fmt.Printf("  - %s%s\n", name, marker)
+	}
+}
+
 // recoverSessionAfterMCPError attempts to reinitialize the session after an MCP transport error.
 // Returns the new session and chatbot if recovery succeeded, or the originals if not.
 func recoverSessionAfterMCPError(ctx context.Context, cfg *config.Config, debug bool, session *chatbot.ChatSession, sessionID string, scanner *readline.Instance, cb chatbot.ChatBot) (*chatbot.ChatSession, chatbot.ChatBot) {
 	if newSession, err := switchChat(ctx, cfg, currentChatName, debug, session, sessionID); err != nil {
 		fmt.Printf("Error reinit chat: %v\n", err)
 	} else {
+		// Keep the model switched to with /model
+		if session.Preset.Model != newSession.Preset.Model {
+			if err := newSession.SwitchModel(ctx, cfg, session.Preset.Model); err != nil {
+				fmt.Printf("Error keeping model %s: %v\n", session.Preset.Model, err)
+			}
+		}
+		// Keep the thinking turned on or off with /think
+		if thinking := session.Thinking(cfg); thinking != newSession.Thinking(cfg) {
+			if err := newSession.SetThinking(ctx, cfg, thinking); err != nil {
+				fmt.Printf("Error keeping thinking %v: %v\n", thinking, err)
+			}
+		}
 		session.Manager.SetChatModel(newSession.Manager.GetChatModel())
 		newSession.Manager = session.Manager
-		persistenceStore := newSession.PersistenceStore()
-		newCB := chatbot.NewChatBot(context.WithValue(ctx, "debug", debug), newSession.Agent, newSession.Manager, scanner, persistenceStore)
+		newSession.Approvals = session.Approvals
+		newCB := newChatBot(ctx, debug, newSession, scanner)
 		fmt.Printf("Reinit chat session for refresh mcp client: %v\n", currentChatName)
 		return newSession, newCB
 	}
@@ -398,7 +1010,9 @@ func handleStreamError(err error, ctx context.Context, cfg *config.Config, debug
 
 func Execute() {
 	if err := RootCmd.Execute(); err != nil {
-		fmt.Println(err)
+		if !errors.Is(err, errTaskFailed) {
+			fmt.Println(err)
+		}
 		os.Exit(1)
 	}
 }
@@ -413,10 +1027,17 @@ func init() {
 
 	// Add global parameters
 	RootCmd.PersistentFlags().StringVarP(&configPath, "config", "f", defaultConfigPath, "Configuration file path")
+	RootCmd.PersistentFlags().StringVarP(&configProfile, "profile", "", "", "Profile of the configuration file merged over it (from config file profiles)")
 	RootCmd.PersistentFlags().BoolP("debug", "", false, "Enable debug mode")
+	RootCmd.PersistentFlags().StringVarP(&promptLogPath, "prompt-log", "", "", "Write the exact model input of every turn to this file (secrets redacted)")
+	RootCmd.PersistentFlags().StringVarP(&auditLogPath, "audit-log", "", "", "Write a record of every tool call, its arguments and approval decision to this file (overrides audit.path of the config)")
 	RootCmd.Flags().StringP("chat", "c", "", "Specify chat preset name (from config file chats)")
-	RootCmd.PersistentFlags().StringP("welcome", "w", "Welcome to Chat-Agent", "Specify chat welcome message")
+	RootCmd.PersistentFlags().StringP("welcome", "w", "Welcome to Chat-Agent", "Specify chat welcome message (supports system prompt template variables)")
 	RootCmd.Flags().StringVarP(&once, "once", "", "", "Prompt for one-time task")
+	RootCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format of --once: text, or json for newline-delimited JSON events")
 	RootCmd.Flags().StringVarP(&startAt, "start-at", "", "", "Prompt for task and start chat")
 	RootCmd.Flags().BoolVar(&disableLocalCommand, "disable-local-command", false, "Disable exec local command")
+	RootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the rendered system prompt, the tools and the MCP server status of the chat, then exit without calling the model")
+	RootCmd.Flags().BoolVar(&showToolResults, "show-tool-results", false, "Show a truncated preview of tool results")
+	RootCmd.Flags().BoolVar(&markdownStream, "markdown-stream", false, "Print code fences of responses whole once they are closed, for terminals rendering markdown")
 }
@@ -39,10 +39,11 @@ type handler struct {
 	awaitingApproval   bool
 	approvalID         string            // stored approval ID from OnApprovalRequest
 	approvalTargets    []string          // stored target IDs from OnApprovalRequest
+	questionID         string            // pending question ID from OnQuestion
 	responseDone       chan struct{}     // signaled when a server response completes
-	streamingToolArgs  map[string]string // index -> accumulated incremental arguments
-	streamingToolNames map[string]string // index -> tool name (for display)
-	activeToolIndices  []string          // ordered list of indices currently streaming (for liveterm multi-line)
+	streamingToolArgs  map[string]string // tool call ID -> accumulated incremental arguments
+	streamingToolNames map[string]string // tool call ID -> tool name (for display)
+	activeToolIndices  []string          // ordered list of tool call IDs currently streaming (for liveterm multi-line)
 	livetermActive     bool              // whether liveterm is currently running
 }
 
@@ -132,11 +133,13 @@ func (h *handler) OnChunk(payload *serve.ChunkPayload) {
 func (h *handler) OnToolCall(payload *serve.ToolCallPayload) {
 	h.resetChunk()
 
+	id := payload.CallID()
+
 	h.mu.Lock()
 	// Accumulate incremental arguments for streaming tool calls
 	if payload.Streaming {
-		h.streamingToolArgs[payload.Index] = serve.ConcatToolArguments(h.streamingToolArgs[payload.Index], payload.Arguments)
-		h.streamingToolNames[payload.Index] = payload.Name
+		h.streamingToolArgs[id] = serve.ConcatToolArguments(h.streamingToolArgs[id], payload.Arguments)
+		h.streamingToolNames[id] = payload.Name
 	}
 
 	streaming := payload.Streaming
@@ -149,13 +152,13 @@ func (h *handler) OnToolCall(payload *serve.ToolCallPayload) {
 		// Register this tool call in the active multi-line display
 		found := false
 		for _, idx := range h.activeToolIndices {
-			if idx == payload.Index {
+			if idx == id {
 				found = true
 				break
 			}
 		}
 		if !found {
-			h.activeToolIndices = append(h.activeToolIndices, payload.Index)
+			h.activeToolIndices = append(h.activeToolIndices, id)
 		}
 
 		if !h.livetermActive {
@@ -165,13 +168,13 @@ func (h *handler) OnToolCall(payload *serve.ToolCallPayload) {
 	} else {
 		// Remove from active indices
 		for i, idx := range h.activeToolIndices {
-			if idx == payload.Index {
+			if idx == id {
 				h.activeToolIndices = append(h.activeToolIndices[:i], h.activeToolIndices[i+1:]...)
 				break
 			}
 		}
-		delete(h.streamingToolArgs, payload.Index)
-		delete(h.streamingToolNames, payload.Index)
+		delete(h.streamingToolArgs, id)
+		delete(h.streamingToolNames, id)
 		line := fmt.Sprintf("ToolCall: (%s) Completed\n---", payload.Name)
 		truncated, _ := chatbot.TruncateToTermWidth(line)
 		printLine = truncated
@@ -256,12 +259,29 @@ func (h *handler) OnApprovalRequest(payload *serve.ApprovalRequestPayload) {
 	h.signalDone()
 }
 
+func (h *handler) OnQuestion(payload *serve.QuestionPayload) {
+	h.resetLiveTerm()
+
+	h.mu.Lock()
+	h.questionID = payload.QuestionID
+	h.mu.Unlock()
+
+	fmt.Printf("Question: %s\n", payload.Question)
+	fmt.Printf("Type your answer and press Enter\n")
+
+	// Wake up the main loop so the user can answer the question.
+	h.signalDone()
+}
+
 func (h *handler) OnMessageCount(_ *serve.MessageCountPayload) {
 	// CLI mode doesn't show message count; silent
 }
 
-func (h *handler) OnStopped(_ *serve.StoppedPayload) {
+func (h *handler) OnStopped(payload *serve.StoppedPayload) {
 	h.resetLiveTerm()
+	if payload.Resumable {
+		h.rawLine("Response stopped. Use /resume to continue it")
+	}
 	h.signalDone()
 }
 
@@ -275,6 +295,27 @@ func (h *handler) OnCleared(_ *serve.ClearedPayload) {
 	h.signalDone()
 }
 
+func (h *handler) OnToolsReloaded(payload *serve.ToolsReloadedPayload) {
+	h.rawLine(payload.Message)
+	h.signalDone()
+}
+
+func (h *handler) OnTools(payload *serve.ToolsPayload) {
+	var sb strings.Builder
+	for _, t := range payload.Tools {
+		state := " "
+		if !t.Enabled {
+			state = "x"
+		}
+		fmt.Fprintf(&sb, "[%s] (%s) %s\n", state, t.Name, strings.TrimSpace(t.Description))
+	}
+	if payload.Message != "" {
+		sb.WriteString(payload.Message + "\n")
+	}
+	h.rawLine(strings.TrimSuffix(sb.String(), "\n"))
+	h.signalDone()
+}
+
 func (h *handler) OnDisconnected(err error) {
 	if err != nil {
 		h.rawLine(fmt.Sprintf("[Disconnected] %v", err))
@@ -318,6 +359,22 @@ func (h *handler) resetApproval() {
 	h.approvalTargets = nil
 }
 
+// takeQuestionID returns the pending question ID, if any, and clears it.
+func (h *handler) takeQuestionID() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	id := h.questionID
+	h.questionID = ""
+	return id
+}
+
+// isAwaitingAnswer returns true if a clarifying question is waiting for an answer.
+func (h *handler) isAwaitingAnswer() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.questionID != ""
+}
+
 func (h *handler) resetLiveTerm() {
 	if h.livetermActive {
 		liveterm.Stop(false)
@@ -355,8 +412,12 @@ func printHelp() {
 	fmt.Println("Available commands:")
 	fmt.Println("  /help    or /h   - Show this help message")
 	fmt.Println("  /clear   or /c   - Clear conversation context")
-	fmt.Println("  /keep    or /k   - Execute session keep hook")
+	fmt.Println("  /keep    or /k [label] - Execute session keep hook, passing an optional label")
 	fmt.Println("  /stop    or /s   - Stop current response")
+	fmt.Println("  /resume  or /r   - Continue the stopped response")
+	fmt.Println("  /tools           - List the tools of the current chat, [x] marks disabled tools")
+	fmt.Println("  /tools enable <name> or /tools disable <name> - Toggle a tool from the next turn on")
+	fmt.Println("  /tools reload    - Re-initialize the tools of the current chat")
 	fmt.Println("  /approve         - Approve all pending tool calls")
 	fmt.Println("  /deny [reason]   - Deny all pending tool calls")
 	fmt.Println("  /quit    or /q   - Exit program")
@@ -447,8 +508,8 @@ Examples:
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 		go func() {
 			for range sigChan {
-				// If awaiting approval, don't stop on Ctrl+C
-				if h.isAwaitingApproval() {
+				// If awaiting approval or an answer, don't stop on Ctrl+C
+				if h.isAwaitingApproval() || h.isAwaitingAnswer() {
 					continue
 				}
 				client.Stop()
@@ -506,10 +567,17 @@ Examples:
 				input := strings.TrimSpace(sb.String())
 
 				switch {
+				case h.isAwaitingAnswer():
+					// Send the input as the answer to the pending question
+					questionID := h.takeQuestionID()
+					h.drainDone()
+					client.SendQuestionResponse(questionID, input)
+					<-h.responseDone
 				case h.isAwaitingApproval():
 					// Handle approval responses
 					switch {
-					case input == "/approve":
+					case input == "/approve" || input == "/approve always":
+						always := input == "/approve always"
 						approvalID := h.getApprovalID()
 						targetIDs := h.getApprovalTargets()
 						h.resetApproval()
@@ -517,7 +585,7 @@ Examples:
 
 						results := make(map[string]serve.ApprovalItem, len(targetIDs))
 						for _, id := range targetIDs {
-							results[id] = serve.ApprovalItem{Approved: true}
+							results[id] = serve.ApprovalItem{Approved: true, Always: always}
 						}
 						client.SendApprovalResponse(approvalID, results)
 						<-h.responseDone
@@ -535,7 +603,7 @@ Examples:
 						client.SendApprovalResponse(approvalID, results)
 						<-h.responseDone
 					default:
-						fmt.Println("Approval required. Use /approve or /deny [reason]")
+						fmt.Println("Approval required. Use /approve, /approve always or /deny [reason]")
 					}
 
 				case input == "/help" || input == "/h":
@@ -548,10 +616,39 @@ Examples:
 					h.drainDone()
 					client.Keep()
 					<-h.responseDone
+				case strings.HasPrefix(input, "/keep ") || strings.HasPrefix(input, "/k "):
+					label := strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(input, "/keep"), "/k"))
+					h.drainDone()
+					client.KeepWithLabel(label)
+					<-h.responseDone
+				case input == "/tools reload":
+					h.drainDone()
+					client.ReloadTools()
+					<-h.responseDone
+				case input == "/tools":
+					h.drainDone()
+					client.ListTools()
+					<-h.responseDone
+				case strings.HasPrefix(input, "/tools enable ") || strings.HasPrefix(input, "/tools disable "):
+					fields := strings.Fields(input)
+					if len(fields) != 3 {
+						fmt.Println("Usage: /tools enable <name> or /tools disable <name>")
+						break
+					}
+					h.drainDone()
+					client.ToggleTool(fields[2], fields[1] == "enable")
+					<-h.responseDone
 				case input == "/stop" || input == "/s":
 					h.drainDone()
 					client.Stop()
 					<-h.responseDone
+				case input == "/resume" || input == "/r":
+					h.drainDone()
+					if err := client.Resume(); err != nil {
+						fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					} else {
+						<-h.responseDone
+					}
 				case input == "/quit" || input == "/exit" || input == "/bye" || input == "/q":
 					fmt.Println("bye!")
 					return nil
@@ -0,0 +1,350 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+	"github.com/Arvintian/chat-agent/pkg/hook"
+	"github.com/Arvintian/chat-agent/pkg/logger"
+	"github.com/Arvintian/chat-agent/pkg/mcp"
+	"github.com/Arvintian/chat-agent/pkg/providers"
+	builtintools "github.com/Arvintian/chat-agent/pkg/tools"
+	"github.com/Arvintian/chat-agent/pkg/utils"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/spf13/cobra"
+)
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the configuration and environment for common setup problems",
+	Long: `Run a self-test of the configuration and report every check as PASS, WARN or FAIL,
+with a hint on how to fix the failures.
+
+The checks cover loading the configuration, creating every model, a short request to
+one model of each provider, starting every MCP server, loading the built-in tools, the
+shell used by the command tools, skill directories and hook scripts.
+
+Examples:
+  chat-agent doctor
+  chat-agent doctor --no-ping`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := logger.Init(); err != nil {
+			return err
+		}
+		noPing, _ := cmd.Flags().GetBool("no-ping")
+		timeout, _ := cmd.Flags().GetInt("timeout")
+
+		d := &doctor{out: os.Stdout, timeout: time.Duration(timeout) * time.Second, ping: !noPing}
+		d.run(cmd.Context())
+
+		fmt.Fprintf(d.out, "\n%d passed, %d warnings, %d failed\n", d.passed, d.warnings, d.failed)
+		if d.failed > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%d checks failed", d.failed)
+		}
+		return nil
+	},
+}
+
+// doctor runs the checks and prints the report as it goes, since pinging providers
+// and starting MCP servers can take a while
+type doctor struct {
+	out     io.Writer
+	timeout time.Duration
+	ping    bool
+
+	passed, warnings, failed int
+}
+
+func (d *doctor) section(title string) {
+	fmt.Fprintf(d.out, "\n%s\n", title)
+}
+
+func (d *doctor) pass(name, detail string) {
+	d.passed++
+	fmt.Fprintf(d.out, "  [PASS] %s: %s\n", name, detail)
+}
+
+func (d *doctor) warn(name, detail, hint string) {
+	d.warnings++
+	d.report("WARN", name, detail, hint)
+}
+
+func (d *doctor) fail(name, detail, hint string) {
+	d.failed++
+	d.report("FAIL", name, detail, hint)
+}
+
+func (d *doctor) report(status, name, detail, hint string) {
+	fmt.Fprintf(d.out, "  [%s] %s: %s\n", status, name, detail)
+	if hint != "" {
+		fmt.Fprintf(d.out, "         hint: %s\n", hint)
+	}
+}
+
+func (d *doctor) run(ctx context.Context) {
+	d.section("Configuration")
+	cfg, err := config.LoadConfigProfile(configPath, configProfile)
+	if err != nil {
+		d.fail(configPath, err.Error(), "run with --config to point to your configuration, see config.yml.example for the format")
+		return
+	}
+	d.pass(configPath, fmt.Sprintf("loaded %d providers, %d models, %d chats, %d MCP servers, %d tools",
+		len(cfg.Providers), len(cfg.Models), len(cfg.Chats), len(cfg.MCPServers), len(cfg.Tools)))
+	d.checkReferences(cfg)
+
+	d.section("Shell")
+	d.checkShell()
+
+	d.section("Models")
+	d.checkModels(ctx, cfg)
+
+	d.section("MCP servers")
+	d.checkMCPServers(ctx, cfg)
+
+	d.section("Built-in tools")
+	d.checkTools(ctx, cfg)
+
+	d.section("Skills and hooks")
+	d.checkSkillsAndHooks(cfg)
+}
+
+// checkReferences checks that the chats only refer to configured models, tools and servers
+func (d *doctor) checkReferences(cfg *config.Config) {
+	if err := mcp.ValidateConfig(cfg); err != nil {
+		d.fail("mcpServers", err.Error(), "fix the MCP server configuration, stdio servers need cmd and sse/http servers need url")
+	}
+	for _, chatName := range sortedKeys(cfg.Chats) {
+		chat := cfg.Chats[chatName]
+		name := "chat " + chatName
+		ok := true
+		if _, exists := cfg.Models[chat.Model]; !exists {
+			d.fail(name, fmt.Sprintf("model %q is not configured", chat.Model), "add it under models or change the model of the chat")
+			ok = false
+		}
+		for _, toolName := range chat.Tools {
+			if _, exists := cfg.Tools[toolName]; !exists {
+				d.fail(name, fmt.Sprintf("tool %q is not configured", toolName), "add it under tools or remove it from the chat")
+				ok = false
+			}
+		}
+		for _, serverName := range chat.MCPServers {
+			if _, exists := cfg.MCPServers[serverName]; !exists {
+				d.fail(name, fmt.Sprintf("MCP server %q is not configured", serverName), "add it under mcpServers or remove it from the chat")
+				ok = false
+			}
+		}
+		if _, err := config.ChatSystemPrompt(cfg, chat); err != nil {
+			d.fail(name, fmt.Sprintf("system prompt: %v", err), "check the systemFiles, @file: path or systemPrompts reference of the chat")
+			ok = false
+		}
+		if ok {
+			d.pass(name, "model, tools and MCP servers are configured")
+		}
+	}
+}
+
+// checkShell checks for the shell the command tools run commands with
+func (d *doctor) checkShell() {
+	shell, hint := "sh", "install a POSIX shell and make sure it is on PATH"
+	if runtime.GOOS == "windows" {
+		shell, hint = "powershell", "install Windows PowerShell and make sure powershell.exe is on PATH"
+	}
+	path, err := exec.LookPath(shell)
+	if err != nil {
+		d.fail(shell, "not found, the cmd tools can't run commands", hint)
+		return
+	}
+	d.pass(shell, path)
+}
+
+// checkModels creates every model and sends a short request to one model of each
+// provider, which verifies the base URL and the API key
+func (d *doctor) checkModels(ctx context.Context, cfg *config.Config) {
+	factory := providers.NewFactory(cfg)
+	pingModels := make(map[string]string)
+	for _, modelName := range sortedKeys(cfg.Models) {
+		modelCfg := cfg.Models[modelName]
+		if _, err := factory.CreateChatModel(ctx, modelName); err != nil {
+			d.fail("model "+modelName, err.Error(), "check the provider, model name and parameters of the model")
+			continue
+		}
+		d.pass("model "+modelName, "created")
+		if len(modelCfg.Mixed) == 0 {
+			if _, ok := pingModels[modelCfg.Provider]; !ok {
+				pingModels[modelCfg.Provider] = modelName
+			}
+		}
+	}
+
+	for _, providerName := range sortedKeys(cfg.Providers) {
+		name := "provider " + providerName
+		modelName, ok := pingModels[providerName]
+		if !ok {
+			d.warn(name, "no working model uses this provider, it was not checked", "")
+			continue
+		}
+		if !d.ping {
+			continue
+		}
+		d.pingModel(ctx, factory, name, modelName, cfg.Providers[providerName])
+	}
+}
+
+func (d *doctor) pingModel(ctx context.Context, factory *providers.Factory, name, modelName string, providerCfg config.Provider) {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	chatModel, err := factory.CreateChatModel(ctx, modelName)
+	if err != nil {
+		d.fail(name, err.Error(), "")
+		return
+	}
+	start := time.Now()
+	if _, err := chatModel.Generate(ctx, []*schema.Message{schema.UserMessage("Reply with OK.")}); err != nil {
+		hint := "check the apiKey of the provider and that its baseUrl is reachable from this machine"
+		if ctx.Err() != nil {
+			hint = fmt.Sprintf("no response within %v, check that the baseUrl %q is reachable or raise --timeout", d.timeout, providerCfg.BaseURL)
+		}
+		d.fail(name, fmt.Sprintf("request to model %s failed: %v", modelName, err), hint)
+		return
+	}
+	d.pass(name, fmt.Sprintf("model %s responded in %v", modelName, time.Since(start).Round(time.Millisecond)))
+}
+
+// checkMCPServers starts every MCP server on its own and lists its tools
+func (d *doctor) checkMCPServers(ctx context.Context, cfg *config.Config) {
+	if len(cfg.MCPServers) == 0 {
+		d.pass("mcpServers", "none configured")
+		return
+	}
+	for _, serverName := range sortedKeys(cfg.MCPServers) {
+		serverCfg := cfg.MCPServers[serverName]
+		name := "MCP server " + serverName
+		hint := fmt.Sprintf("check that %q is reachable", serverCfg.URL)
+		if serverCfg.Cmd != "" {
+			hint = fmt.Sprintf("check that %q is installed and runs on its own, and that it is allowed by mcpAllowedCommands", serverCfg.Cmd)
+		}
+
+		serverCtx, cancel := context.WithTimeout(ctx, d.timeout)
+		client := mcp.NewClient(cfg)
+		err := client.InitializeForChat(serverCtx, config.Chat{MCPServers: []string{serverName}})
+		tools := client.GetToolListForServers([]string{serverName})
+		client.Close()
+		cancel()
+		if err != nil {
+			d.fail(name, err.Error(), hint)
+			continue
+		}
+		d.pass(name, fmt.Sprintf("started with %d tools", len(tools)))
+	}
+}
+
+// checkTools loads every built-in tool configuration
+func (d *doctor) checkTools(ctx context.Context, cfg *config.Config) {
+	if len(cfg.Tools) == 0 {
+		d.pass("tools", "none configured")
+		return
+	}
+	cleanupRegistry := utils.NewCleanupRegistry()
+	defer cleanupRegistry.Execute()
+	ctx = context.WithValue(ctx, "cleanup", cleanupRegistry)
+
+	for _, toolName := range sortedKeys(cfg.Tools) {
+		toolCfg := cfg.Tools[toolName]
+		tools, err := builtintools.GetBuiltinTools(ctx, toolCfg.Category, toolCfg.Params)
+		if err != nil {
+			d.fail("tool "+toolName, err.Error(), fmt.Sprintf("check the category (%q) and params of the tool", toolCfg.Category))
+			continue
+		}
+		d.pass("tool "+toolName, fmt.Sprintf("loaded %d tools", len(tools)))
+	}
+}
+
+// checkSkillsAndHooks checks the skill directories and hook scripts of every chat
+func (d *doctor) checkSkillsAndHooks(cfg *config.Config) {
+	checked := false
+	for _, chatName := range sortedKeys(cfg.Chats) {
+		chat := cfg.Chats[chatName]
+		if chat.Skill != nil {
+			checked = true
+			d.checkDir(fmt.Sprintf("chat %s skill dir", chatName), chat.Skill.Dir)
+			if chat.Skill.WorkDir != "" {
+				d.checkDir(fmt.Sprintf("chat %s skill workDir", chatName), chat.Skill.WorkDir)
+			}
+		}
+		if chat.Hooks != nil {
+			paths := hook.NewHookManager(chat.Hooks).ScriptPaths()
+			for _, hookName := range sortedKeys(paths) {
+				checked = true
+				d.checkScript(fmt.Sprintf("chat %s %s hook", chatName, hookName), paths[hookName])
+			}
+		}
+	}
+	if !checked {
+		d.pass("skills and hooks", "none configured")
+	}
+}
+
+func (d *doctor) checkDir(name, dir string) {
+	path, err := utils.ExpandPath(dir)
+	if err != nil {
+		d.fail(name, err.Error(), "set the directory in the chat's skill configuration")
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		d.fail(name, err.Error(), fmt.Sprintf("create %s or fix the path", path))
+		return
+	}
+	if !info.IsDir() {
+		d.fail(name, path+" is not a directory", "point the path to a directory")
+		return
+	}
+	d.pass(name, path)
+}
+
+func (d *doctor) checkScript(name, path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		d.fail(name, err.Error(), "create the script or fix scriptPath, relative paths are resolved against ~/.chat-agent/hooks")
+		return
+	}
+	if info.IsDir() {
+		d.fail(name, path+" is a directory", "point scriptPath to the script file")
+		return
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0111 == 0 {
+		// The hook manager makes the script executable before running it, which only
+		// works if the script belongs to the current user
+		d.warn(name, path+" is not executable", "chmod +x "+path)
+		return
+	}
+	d.pass(name, path)
+}
+
+// sortedKeys returns the keys of a map in order, so the report is stable
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func init() {
+	doctorCmd.Flags().Bool("no-ping", false, "Create the models without sending a request to the providers")
+	doctorCmd.Flags().Int("timeout", 30, "Timeout in seconds for each provider request and MCP server start")
+
+	RootCmd.AddCommand(doctorCmd)
+}
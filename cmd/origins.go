@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// OriginPolicy decides which browser origins may call the server cross-origin
+type OriginPolicy struct {
+	allowAll bool
+	patterns []string // lowercased origins, with a *. subdomain wildcard and optionally without scheme
+}
+
+// parseAllowedOrigins parses a comma-separated list of origins such as
+// "https://app.example.com,https://*.example.com,*.example.org". An entry without a
+// scheme matches any scheme, "*" allows any origin. Returns nil for an empty list.
+func parseAllowedOrigins(raw string) *OriginPolicy {
+	var policy OriginPolicy
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimRight(strings.ToLower(strings.TrimSpace(entry)), "/")
+		switch entry {
+		case "":
+			continue
+		case "*":
+			policy.allowAll = true
+		default:
+			policy.patterns = append(policy.patterns, entry)
+		}
+	}
+	if !policy.allowAll && len(policy.patterns) == 0 {
+		return nil
+	}
+	return &policy
+}
+
+// Allowed reports whether the origin of the request may call the server. Requests
+// without an Origin header (not from a browser) and same-origin requests are allowed.
+func (p *OriginPolicy) Allowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || p.allowAll {
+		return true
+	}
+	u, err := url.Parse(strings.ToLower(origin))
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, pattern := range p.patterns {
+		if matchOrigin(pattern, u.Scheme, u.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchOrigin matches a scheme and host against an allowed origin pattern. "*.example.com"
+// matches the subdomains of example.com at any depth, not example.com itself.
+func matchOrigin(pattern, scheme, host string) bool {
+	if s, h, ok := strings.Cut(pattern, "://"); ok {
+		if s != scheme {
+			return false
+		}
+		pattern = h
+	}
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(host, "."+suffix)
+	}
+	return host == pattern
+}
+
+// CORSMiddleware rejects requests from disallowed origins with 403, before a WebSocket
+// upgrade, and adds the CORS headers to the responses of allowed cross-origin requests.
+// Preflight requests are answered directly, since browsers send them without credentials.
+// With "*" any origin may call the server, but browsers don't send it credentials.
+// A nil policy leaves the requests unchanged, allowing any origin.
+func CORSMiddleware(policy *OriginPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if policy == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !policy.Allowed(r) {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte("403 Forbidden: origin not allowed"))
+				return
+			}
+
+			header := w.Header()
+			if policy.allowAll {
+				// Without credentials, otherwise any website could read authenticated responses
+				header.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				header.Add("Vary", "Origin")
+				header.Set("Access-Control-Allow-Origin", origin)
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				header.Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+				allowHeaders := r.Header.Get("Access-Control-Request-Headers")
+				if allowHeaders == "" {
+					allowHeaders = "Authorization, Content-Type"
+				}
+				header.Set("Access-Control-Allow-Headers", allowHeaders)
+				header.Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CheckOrigin is the WebSocket upgrader's origin check for the policy
+func (p *OriginPolicy) CheckOrigin(r *http.Request) bool {
+	return p == nil || p.Allowed(r)
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOriginPolicyAllowed(t *testing.T) {
+	tests := []struct {
+		allowed string
+		origin  string
+		want    bool
+	}{
+		{"https://app.example.com", "https://app.example.com", true},
+		{"https://app.example.com/", "https://APP.example.com", true},
+		{"https://app.example.com", "http://app.example.com", false},
+		{"https://app.example.com", "https://app.example.com.evil.com", false},
+		// Without a scheme a pattern matches any scheme
+		{"app.example.com", "http://app.example.com", true},
+		{"app.example.com", "https://app.example.com", true},
+		{"*.example.com", "http://app.example.com", true},
+		// A wildcard matches subdomains at any depth but not the bare apex domain
+		{"https://*.example.com", "https://app.example.com", true},
+		{"https://*.example.com", "https://a.b.example.com", true},
+		{"https://*.example.com", "https://example.com", false},
+		{"https://*.example.com", "https://evilexample.com", false},
+		{"https://*.example.com", "http://app.example.com", false},
+		{"https://a.example.com,https://b.example.com", "https://b.example.com", true},
+		// Same-origin requests are always allowed
+		{"https://app.example.com", "http://chat.local:8080", true},
+		{"https://app.example.com", "http://chat.local:9090", false},
+		{"*", "https://anywhere.example.org", true},
+		{"https://app.example.com", "null", false},
+	}
+	for _, tt := range tests {
+		policy := parseAllowedOrigins(tt.allowed)
+		r := httptest.NewRequest(http.MethodGet, "http://chat.local:8080/chats", nil)
+		r.Header.Set("Origin", tt.origin)
+		if got := policy.Allowed(r); got != tt.want {
+			t.Errorf("parseAllowedOrigins(%q).Allowed(%q) = %v, want %v", tt.allowed, tt.origin, got, tt.want)
+		}
+	}
+
+	if policy := parseAllowedOrigins(" , "); policy != nil {
+		t.Errorf("parseAllowedOrigins() of an empty list = %+v, want nil", policy)
+	}
+}
+
+func TestCORSMiddlewareCredentials(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	tests := []struct {
+		allowed, origin, wantOrigin, wantCredentials string
+	}{
+		{"https://app.example.com", "https://app.example.com", "https://app.example.com", "true"},
+		// Any website may call the server, but not with the credentials of the user
+		{"*", "https://evil.example.org", "*", ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://chat.local:8080/chats", nil)
+		r.Header.Set("Origin", tt.origin)
+		CORSMiddleware(parseAllowedOrigins(tt.allowed))(next).ServeHTTP(w, r)
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+			t.Errorf("%q: Access-Control-Allow-Origin = %q, want %q", tt.allowed, got, tt.wantOrigin)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Credentials"); got != tt.wantCredentials {
+			t.Errorf("%q: Access-Control-Allow-Credentials = %q, want %q", tt.allowed, got, tt.wantCredentials)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "http://chat.local:8080/chats", nil)
+	r.Header.Set("Origin", "https://evil.example.org")
+	CORSMiddleware(parseAllowedOrigins("https://app.example.com"))(next).ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("disallowed origin got %d, want 403", w.Code)
+	}
+}
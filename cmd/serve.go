@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net"
@@ -14,16 +15,23 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/Arvintian/chat-agent/pkg/chatbot"
 	"github.com/Arvintian/chat-agent/pkg/config"
 	"github.com/Arvintian/chat-agent/pkg/logger"
+	"github.com/Arvintian/chat-agent/pkg/manager"
 	"github.com/Arvintian/chat-agent/pkg/mcp"
+	"github.com/Arvintian/chat-agent/pkg/metrics"
+	"github.com/Arvintian/chat-agent/pkg/providers"
+	"github.com/Arvintian/chat-agent/pkg/store"
+	"github.com/Arvintian/chat-agent/pkg/utils"
 	"github.com/Arvintian/chat-agent/pkg/web"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/spf13/cobra"
 )
@@ -214,21 +222,47 @@ Each client connection can select a chat and start independent conversation sess
 Examples:
   chat-agent serve --port 8080
   chat-agent serve --port 8080 --basic-auth "alice:pwd1,bob:pwd2"
-  chat-agent serve --port 8080 --basic-auth-file /etc/chat-agent/users`,
+  chat-agent serve --port 8080 --basic-auth-file /etc/chat-agent/users
+  chat-agent serve --port 8080 --session-dir ~/.chat-agent/sessions
+  chat-agent serve --port 8080 --allowed-origins "https://chat.example.com,https://*.example.com"`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if err := logger.Init(); err != nil {
 			return err
 		}
-		cfg, err := config.LoadConfig(configPath)
+		if debug, _ := cmd.Flags().GetBool("debug"); debug {
+			providers.SetDebugLog(true)
+		}
+		closePromptLog, err := openPromptLog()
+		if err != nil {
+			return err
+		}
+		defer closePromptLog()
+		cfg, err := config.LoadConfigProfile(configPath, configProfile)
 		if err != nil {
 			return err
 		}
+		closeAuditLog, err := openAuditLog(cfg)
+		if err != nil {
+			return err
+		}
+		defer closeAuditLog()
 
 		port, _ := cmd.Flags().GetInt("port")
 		host, _ := cmd.Flags().GetString("host")
 		welcome, _ := cmd.Flags().GetString("welcome")
 		basicAuth, _ := cmd.Flags().GetString("basic-auth")
 		basicAuthFile, _ := cmd.Flags().GetString("basic-auth-file")
+		sessionDir, _ := cmd.Flags().GetString("session-dir")
+		maxPendingApprovals, _ := cmd.Flags().GetInt("max-pending-approvals")
+		sharedConnections, _ := cmd.Flags().GetBool("shared-connections")
+		drainTimeout, _ := cmd.Flags().GetInt("drain-timeout")
+		sessionTTL, _ := cmd.Flags().GetInt("session-ttl")
+		maxChatRequestSize, _ := cmd.Flags().GetInt64("max-chat-request-size")
+		allowedOrigins, _ := cmd.Flags().GetString("allowed-origins")
+		enableMetrics, _ := cmd.Flags().GetBool("metrics")
+		rateLimit, _ := cmd.Flags().GetInt("rate-limit")
+		rateBurst, _ := cmd.Flags().GetInt("rate-burst")
+		maxStreams, _ := cmd.Flags().GetInt("max-streams")
 
 		// Merge credentials: start with file-based, then overlay inline (inline takes precedence)
 		credentials := make(map[string]string)
@@ -245,26 +279,69 @@ Examples:
 			credentials[u] = p
 		}
 
-		wsHandler := NewWebSocketHandler(cfg)
+		// Sessions are kept in memory only, unless a session directory is given
+		var sessionStore store.SessionStore
+		if sessionDir != "" {
+			dir, err := utils.ExpandPath(sessionDir)
+			if err != nil {
+				return err
+			}
+			fileStore, err := store.NewFileSessionStore(dir)
+			if err != nil {
+				return err
+			}
+			sessionStore = fileStore
+		}
+
+		wsHandler := NewWebSocketHandler(cfg, sessionStore)
+		wsHandler.sessionManager.SetMaxPendingApprovals(maxPendingApprovals)
+		wsHandler.sessionManager.SetSharedConnections(sharedConnections)
+		wsHandler.sessionManager.SetSessionTTL(time.Duration(sessionTTL) * time.Minute)
+		wsHandler.maxRequestSize = maxChatRequestSize << 20
+		if rateLimit > 0 || maxStreams > 0 {
+			wsHandler.limiter = NewChatLimiter(rateLimit, rateBurst, maxStreams)
+		}
 
 		authMiddleware := BasicAuthMiddleware(credentials)
 
+		origins := parseAllowedOrigins(allowedOrigins)
+		if origins == nil {
+			log.Printf("Warning: --allowed-origins is not set, any website can connect to this server from a browser; set it in production")
+		}
+		upgrader.CheckOrigin = origins.CheckOrigin
+
 		router := mux.NewRouter()
 		router.Use(authMiddleware)
 		router.Use(AccessLogMiddleware)
 		router.HandleFunc("/ws", wsHandler.HandleWebSocket)
+		router.HandleFunc("/sse", wsHandler.HandleSSE).Methods(http.MethodGet)
+		router.HandleFunc("/sse/send", wsHandler.HandleSSESend).Methods(http.MethodPost)
+		router.HandleFunc("/chat", chatHTTPHandler(wsHandler.config, maxChatRequestSize<<20)).Methods(http.MethodPost)
+		router.HandleFunc("/sessions/{id}", wsHandler.sessionInfoHandler).Methods(http.MethodGet)
+		// The admin endpoints close sessions, they are only served behind basic auth
+		if len(credentials) > 0 {
+			router.HandleFunc("/admin/sessions", wsHandler.adminSessionsHandler).Methods(http.MethodGet)
+			router.HandleFunc("/admin/sessions/{id}", wsHandler.adminCloseSessionHandler).Methods(http.MethodDelete)
+		}
+		if enableMetrics {
+			metrics.Register(wsHandler.sessionManager.metricsCollectors()...)
+			router.Handle("/metrics", promhttp.Handler()).Methods(http.MethodGet)
+		}
 
 		router.HandleFunc("/chats", func(w http.ResponseWriter, r *http.Request) {
 			type ChatInfo struct {
 				Name        string `json:"name"`
+				Model       string `json:"model"`
 				HasKeepHook bool   `json:"has_keep_hook"`
 			}
+			cfg := wsHandler.config()
 			chats := make([]ChatInfo, 0, len(cfg.Chats))
 			defaultChat := ""
 			for name, chatCfg := range cfg.Chats {
 				hasKeepHook := chatCfg.Hooks != nil && chatCfg.Hooks.Keep != nil && chatCfg.Hooks.Keep.Enabled
 				chats = append(chats, ChatInfo{
 					Name:        name,
+					Model:       chatCfg.Model,
 					HasKeepHook: hasKeepHook,
 				})
 				if chatCfg.Default {
@@ -291,6 +368,15 @@ Examples:
 			})
 		})
 
+		// The configured models with their capabilities, e.g. for the client to tell
+		// whether images can be attached
+		router.HandleFunc("/models", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"models": providers.DescribeModels(wsHandler.config()),
+			})
+		}).Methods(http.MethodGet)
+
 		router.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Content-Type", "application/json")
 			title := welcome
@@ -312,8 +398,9 @@ Examples:
 		log.Printf("HTTP endpoint: http://%s/", addr)
 
 		server := &http.Server{
-			Addr:    addr,
-			Handler: router,
+			Addr: addr,
+			// The origin check runs before basic auth, since preflight requests carry no credentials
+			Handler: CORSMiddleware(origins)(router),
 		}
 
 		go func() {
@@ -322,13 +409,29 @@ Examples:
 			}
 		}()
 
+		stopJanitor := make(chan struct{})
+		go wsHandler.runSessionJanitor(stopJanitor)
+
+		// SIGHUP reloads the configuration, SIGINT and SIGTERM stop the server
 		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-		<-sigChan
+		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+		for sig := <-sigChan; sig == syscall.SIGHUP; sig = <-sigChan {
+			if err := wsHandler.reloadConfig(configPath, configProfile); err != nil {
+				log.Printf("Failed to reload config, keeping the current one: %v", err)
+				continue
+			}
+			log.Printf("Config reloaded from %s, new sessions use it", configPath)
+		}
 
 		log.Printf("Shutting down server...")
 
+		// Let the in-flight responses complete before the sessions are closed
+		if left := wsHandler.sessionManager.Drain(time.Duration(drainTimeout) * time.Second); left > 0 {
+			log.Printf("Drain timed out with %d requests in flight", left)
+		}
+
 		// Cleanup all sessions on server shutdown
+		close(stopJanitor)
 		wsHandler.sessionManager.CloseAllSessions()
 
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -356,10 +459,76 @@ type ChatRequest struct {
 	Files    []FilePayload `json:"files,omitempty"`
 }
 
+// toFileData converts the files of a chat request
+func toFileData(files []FilePayload) []chatbot.FileData {
+	if len(files) == 0 {
+		return nil
+	}
+	fileData := make([]chatbot.FileData, len(files))
+	for i, file := range files {
+		fileData[i] = chatbot.FileData{
+			URL:      file.URL,
+			Type:     file.Type,
+			Name:     file.Name,
+			FileSize: file.FileSize,
+		}
+	}
+	return fileData
+}
+
+// prepareChatMessage pre-processes the files of a chat request: files matching the chat's
+// fileRouting rules are replaced by the tool output, then documents are replaced by their
+// text when extractDocuments is set. The text is appended to the message.
+func prepareChatMessage(ctx context.Context, session *chatbot.ChatSession, message string, files []FilePayload) (string, []chatbot.FileData) {
+	return prepareFiles(ctx, session, message, toFileData(files))
+}
+
+// prepareFiles routes and extracts the files of a message, see prepareChatMessage
+func prepareFiles(ctx context.Context, session *chatbot.ChatSession, message string, fileData []chatbot.FileData) (string, []chatbot.FileData) {
+	fileData, routed := session.RouteFiles(ctx, fileData)
+	fileData, extracted := session.ExtractDocuments(ctx, fileData)
+	for _, text := range []string{routed, extracted} {
+		if text != "" {
+			message = strings.TrimSpace(message + "\n\n" + text)
+		}
+	}
+	return message, fileData
+}
+
+// KeepRequest is the payload of a keep request
+type KeepRequest struct {
+	Label string `json:"label,omitempty"`
+}
+
+// PinRequest is the payload of a pin request. Index is the number of the user message to
+// pin, or of the pinned message to unpin, counting from 1; 0 pins the last user message
+// or unpins all of them.
+type PinRequest struct {
+	Index int  `json:"index,omitempty"`
+	Unpin bool `json:"unpin,omitempty"`
+}
+
+// EditMessageRequest is the payload of an edit_message request. Round is the number of the
+// round whose user message is edited, counting from 0 over the rounds of the context
+// with a user message, or from the end when negative. Message is the new text.
+type EditMessageRequest struct {
+	Round   int    `json:"round"`
+	Message string `json:"message"`
+}
+
+// ToggleToolRequest is the payload of a toggle_tool request
+type ToggleToolRequest struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+}
+
 // ChatState represents the state of a single chat within a session
 type ChatState struct {
 	ChatSession *chatbot.ChatSession
 	ChatBot     *chatbot.ChatBot
+	// Restored is the context of a chat loaded from the session store. The chat
+	// session is initialized on first use, since MCP clients and tools cannot be stored.
+	Restored *manager.State
 }
 
 type SessionInfo struct {
@@ -367,6 +536,9 @@ type SessionInfo struct {
 	ChatName  string                // Current active chat
 	Chats     map[string]*ChatState // All chats in this session
 	CreatedAt time.Time
+	// LastActivity is the time of the last message or connection change, the session
+	// expires once it is older than the session TTL
+	LastActivity time.Time
 }
 
 // ApprovalResponsePayload represents the approval response from the client
@@ -379,6 +551,18 @@ type ApprovalResponsePayload struct {
 type ApprovalItem struct {
 	Approved bool   `json:"approved"`
 	Reason   string `json:"reason,omitempty"`
+	Always   bool   `json:"always,omitempty"` // approve the same call for the rest of the session
+}
+
+// CancelApprovalPayload represents the cancellation of a pending approval request by the client
+type CancelApprovalPayload struct {
+	ApprovalID string `json:"approval_id"`
+}
+
+// QuestionResponsePayload represents the answer to a clarifying question from the client
+type QuestionResponsePayload struct {
+	QuestionID string `json:"question_id"`
+	Answer     string `json:"answer"`
 }
 
 // WebSocket ping/pong configuration
@@ -389,33 +573,275 @@ const (
 	pingPeriod = (pongWait * 8) / 10
 )
 
+// Time to wait for an in-flight turn to stop when switching chats
+const turnCancelTimeout = 5 * time.Second
+
+// drainPollInterval is how often the in-flight requests are checked while draining
+const drainPollInterval = 100 * time.Millisecond
+
+// drainAllowedMessages are the messages still processed while the server drains: they
+// answer or stop in-flight turns instead of starting new work
+var drainAllowedMessages = map[string]bool{
+	"stop":              true,
+	"approval_response": true,
+	"cancel_approval":   true,
+	"question_response": true,
+}
+
 // WebSocket upgrader
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
 	CheckOrigin: func(r *http.Request) bool {
-		return true // Allow all origins in development
+		return true // Replaced by the --allowed-origins check when serving
 	},
 }
 
 // SessionManager manages chat sessions
 type SessionManager struct {
 	sessions map[string]*SessionInfo
-	cfg      *config.Config
-	mu       sync.RWMutex
+	// cfg is swapped when the configuration is reloaded, sessions created before keep
+	// the agents built from the previous one
+	cfg   atomic.Pointer[config.Config]
+	store store.SessionStore // nil keeps sessions in memory only
+	mu    sync.RWMutex
 	// connectionCount tracks the number of active WebSocket connections per session
 	connectionCount map[string]int
 	// activeChats tracks which chats are currently active per session
 	// sessionId -> chatName -> connection count
 	activeChats map[string]map[string]int
+	// pendingApprovals counts the approval requests waiting for an answer in all
+	// sessions, capped by maxPendingApprovals (0 means no cap)
+	pendingApprovals    int
+	maxPendingApprovals int
+	// sharedConnections lets the connections of a session share one WSSession, so
+	// messages are broadcast to all of them. liveSessions holds the shared WSSessions.
+	sharedConnections bool
+	liveSessions      map[string]*chatbot.WSSession
+	// wsSessions holds the WSSessions of the open connections, told when the server
+	// shuts down
+	wsSessions map[*chatbot.WSSession]bool
+	// inFlight counts the messages being processed per session. Once draining is set
+	// only the messages in drainAllowedMessages are accepted.
+	inFlight map[string]int
+	draining bool
+	// sessionTTL is how long a session without connections is kept after its last
+	// activity, 0 keeps it until the server stops
+	sessionTTL time.Duration
 }
 
-func NewSessionManager(cfg *config.Config) *SessionManager {
-	return &SessionManager{
+// NewSessionManager creates a session manager. Sessions saved in the store are loaded
+// back, their chats are initialized when they are selected again.
+func NewSessionManager(cfg *config.Config, sessionStore store.SessionStore) *SessionManager {
+	sm := &SessionManager{
 		sessions:        make(map[string]*SessionInfo),
-		cfg:             cfg,
+		store:           sessionStore,
 		connectionCount: make(map[string]int),
 		activeChats:     make(map[string]map[string]int),
+		liveSessions:    make(map[string]*chatbot.WSSession),
+		wsSessions:      make(map[*chatbot.WSSession]bool),
+		inFlight:        make(map[string]int),
+	}
+	sm.cfg.Store(cfg)
+	if sessionStore == nil {
+		return sm
+	}
+	stored, err := sessionStore.Load()
+	if err != nil {
+		log.Printf("Failed to load stored sessions: %v", err)
+		return sm
+	}
+	for _, s := range stored {
+		chats := make(map[string]*ChatState)
+		for chatName, chat := range s.Chats {
+			// Skip chats whose preset was removed from the config
+			if _, ok := cfg.Chats[chatName]; !ok || chat == nil {
+				continue
+			}
+			chats[chatName] = &ChatState{Restored: &chat.Context}
+		}
+		sm.sessions[s.ID] = &SessionInfo{
+			ID:           s.ID,
+			ChatName:     s.ChatName,
+			Chats:        chats,
+			CreatedAt:    s.CreatedAt,
+			LastActivity: time.Now(),
+		}
+	}
+	log.Printf("Loaded %d stored sessions", len(stored))
+	return sm
+}
+
+// SetMaxPendingApprovals caps the approval requests pending at the same time across all
+// sessions; 0 means no cap
+func (sm *SessionManager) SetMaxPendingApprovals(max int) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.maxPendingApprovals = max
+}
+
+// SetSharedConnections makes the connections of a session share one WSSession: all of
+// them receive the messages of the session and any of them can answer approvals
+func (sm *SessionManager) SetSharedConnections(shared bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.sharedConnections = shared
+}
+
+func (sm *SessionManager) sharesConnections() bool {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.sharedConnections
+}
+
+// attachConnection returns the WSSession serving a new connection. With shared
+// connections the connection joins the live WSSession of the session if there is one,
+// joined is true then; otherwise create is called for a new WSSession.
+func (sm *SessionManager) attachConnection(sessionID string, conn chatbot.Conn, create func() *chatbot.WSSession) (session *chatbot.WSSession, joined bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if live, ok := sm.liveSessions[sessionID]; ok && sm.sharedConnections {
+		live.AddConn(conn)
+		return live, true
+	}
+	session = create()
+	if sm.sharedConnections {
+		sm.liveSessions[sessionID] = session
+	}
+	sm.wsSessions[session] = true
+	if sm.draining {
+		session.BeginShutdown(0)
+	}
+	return session, false
+}
+
+// detachConnection removes a closed connection from its WSSession and returns the number
+// of connections still using it. The WSSession is only closed when none is left.
+func (sm *SessionManager) detachConnection(sessionID string, session *chatbot.WSSession, conn chatbot.Conn) int {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	left := session.RemoveConn(conn)
+	if left == 0 {
+		delete(sm.wsSessions, session)
+		if sm.liveSessions[sessionID] == session {
+			delete(sm.liveSessions, sessionID)
+		}
+	}
+	return left
+}
+
+// beginRequest counts a message of the session as in flight, or returns false if the
+// server is draining and the message would start new work
+func (sm *SessionManager) beginRequest(sessionID, msgType string) bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.draining && !drainAllowedMessages[msgType] {
+		return false
+	}
+	sm.touchLocked(sessionID)
+	sm.inFlight[sessionID]++
+	return true
+}
+
+// endRequest marks a message counted by beginRequest as processed
+func (sm *SessionManager) endRequest(sessionID string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.touchLocked(sessionID)
+	sm.inFlight[sessionID]--
+	if sm.inFlight[sessionID] <= 0 {
+		delete(sm.inFlight, sessionID)
+	}
+}
+
+// inFlightCount returns the number of messages being processed in all sessions
+func (sm *SessionManager) inFlightCount() int {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	count := 0
+	for _, n := range sm.inFlight {
+		count += n
+	}
+	return count
+}
+
+// Drain stops accepting new work, tells the clients that the server shuts down and waits
+// up to timeout for the messages in flight, such as streaming responses, to be processed.
+// Pending approvals are denied, so turns waiting for one go on right away. Returns the
+// number of messages still in flight.
+func (sm *SessionManager) Drain(timeout time.Duration) int {
+	sm.mu.Lock()
+	sm.draining = true
+	sessions := make([]*chatbot.WSSession, 0, len(sm.wsSessions))
+	for session := range sm.wsSessions {
+		sessions = append(sessions, session)
+	}
+	sm.mu.Unlock()
+
+	for _, session := range sessions {
+		session.BeginShutdown(timeout)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		left := sm.inFlightCount()
+		if left == 0 || !time.Now().Before(deadline) {
+			return left
+		}
+		time.Sleep(drainPollInterval)
+	}
+}
+
+// AcquireApproval implements chatbot.ApprovalLimiter
+func (sm *SessionManager) AcquireApproval() bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.maxPendingApprovals > 0 && sm.pendingApprovals >= sm.maxPendingApprovals {
+		return false
+	}
+	sm.pendingApprovals++
+	return true
+}
+
+// ReleaseApproval implements chatbot.ApprovalLimiter
+func (sm *SessionManager) ReleaseApproval() {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.pendingApprovals > 0 {
+		sm.pendingApprovals--
+	}
+}
+
+// saveLocked writes a session to the store. The caller must hold sm.mu.
+func (sm *SessionManager) saveLocked(session *SessionInfo) {
+	if sm.store == nil || len(session.Chats) == 0 {
+		return
+	}
+	stored := &store.StoredSession{
+		ID:        session.ID,
+		ChatName:  session.ChatName,
+		Chats:     make(map[string]*store.StoredChat, len(session.Chats)),
+		CreatedAt: session.CreatedAt,
+	}
+	for chatName, state := range session.Chats {
+		switch {
+		case state.ChatSession != nil:
+			stored.Chats[chatName] = &store.StoredChat{Context: state.ChatSession.Manager.Snapshot()}
+		case state.Restored != nil:
+			stored.Chats[chatName] = &store.StoredChat{Context: *state.Restored}
+		}
+	}
+	if err := sm.store.Save(stored); err != nil {
+		log.Printf("Failed to save session %s: %v", session.ID, err)
+	}
+}
+
+// SaveSession writes the current state of a session to the store
+func (sm *SessionManager) SaveSession(sessionID string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if session, ok := sm.sessions[sessionID]; ok {
+		sm.saveLocked(session)
 	}
 }
 
@@ -425,6 +851,7 @@ func (sm *SessionManager) tryRegisterConnection(sessionID string) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 	sm.connectionCount[sessionID]++
+	sm.touchLocked(sessionID)
 	log.Printf("Session %s: connection count increased to %d", sessionID, sm.connectionCount[sessionID])
 }
 
@@ -493,6 +920,8 @@ func (sm *SessionManager) unregisterConnection(sessionID string) {
 			sm.connectionCount[sessionID] = count - 1
 		}
 	}
+	// The TTL of a session runs from the time its last connection closed
+	sm.touchLocked(sessionID)
 	log.Printf("Session %s: connection count decreased to %d", sessionID, sm.connectionCount[sessionID])
 }
 
@@ -514,11 +943,13 @@ func (sm *SessionManager) AddSession(sessionID string, chatName string, chatSess
 			}
 		}
 		sm.sessions[sessionID] = &SessionInfo{
-			ID:        sessionID,
-			ChatName:  chatName,
-			Chats:     chats,
-			CreatedAt: time.Now(),
+			ID:           sessionID,
+			ChatName:     chatName,
+			Chats:        chats,
+			CreatedAt:    time.Now(),
+			LastActivity: time.Now(),
 		}
+		sm.saveLocked(sm.sessions[sessionID])
 	}
 }
 
@@ -543,12 +974,14 @@ func (sm *SessionManager) UpdateChatSessionWithBot(sessionID string, chatName st
 			ChatBot:     chatBot,
 		}
 		sm.sessions[sessionID] = &SessionInfo{
-			ID:        sessionID,
-			ChatName:  chatName,
-			Chats:     chats,
-			CreatedAt: time.Now(),
+			ID:           sessionID,
+			ChatName:     chatName,
+			Chats:        chats,
+			CreatedAt:    time.Now(),
+			LastActivity: time.Now(),
 		}
 	}
+	sm.saveLocked(sm.sessions[sessionID])
 }
 
 // GetChatState gets the chat state for a specific chat in a session
@@ -582,6 +1015,11 @@ func (sm *SessionManager) RemoveSession(sessionID string) {
 		}
 	}
 	delete(sm.sessions, sessionID)
+	if sm.store != nil {
+		if err := sm.store.Delete(sessionID); err != nil {
+			log.Printf("Failed to delete stored session %s: %v", sessionID, err)
+		}
+	}
 }
 
 func (sm *SessionManager) CloseAllSessions() {
@@ -592,6 +1030,8 @@ func (sm *SessionManager) CloseAllSessions() {
 		delete(sm.activeChats, sessionID)
 	}
 	for sessionID, session := range sm.sessions {
+		// Save before closing, so the conversations are restored on the next start
+		sm.saveLocked(session)
 		for chatName, state := range session.Chats {
 			if state.ChatSession != nil {
 				if err := state.ChatSession.Close(); err != nil {
@@ -606,15 +1046,38 @@ func (sm *SessionManager) CloseAllSessions() {
 // WebSocketHandler handles WebSocket connections
 type WebSocketHandler struct {
 	sessionManager *SessionManager
-	cfg            *config.Config
+	// sseStreams holds the open event streams by session ID
+	sseStreams map[string]*sseStream
+	sseMu      sync.Mutex
+	// maxRequestSize bounds the body of POST /sse/send, in bytes
+	maxRequestSize int64
+	// limiter limits the responses started by the clients, nil means no limit
+	limiter *ChatLimiter
 }
 
 // NewWebSocketHandler creates a new WebSocket handler
-func NewWebSocketHandler(cfg *config.Config) *WebSocketHandler {
+func NewWebSocketHandler(cfg *config.Config, sessionStore store.SessionStore) *WebSocketHandler {
 	return &WebSocketHandler{
-		sessionManager: NewSessionManager(cfg),
-		cfg:            cfg,
+		sessionManager: NewSessionManager(cfg, sessionStore),
+		sseStreams:     make(map[string]*sseStream),
+	}
+}
+
+// config returns the current configuration, held by the session manager
+func (h *WebSocketHandler) config() *config.Config {
+	return h.sessionManager.cfg.Load()
+}
+
+// reloadConfig loads the configuration file again for the sessions and chats initialized
+// from now on, the ones already running keep their agents. An invalid file keeps the
+// current configuration.
+func (h *WebSocketHandler) reloadConfig(path, profile string) error {
+	cfg, err := config.LoadConfigProfile(path, profile)
+	if err != nil {
+		return err
 	}
+	h.sessionManager.cfg.Store(cfg)
+	return nil
 }
 
 func (h *WebSocketHandler) CloseAllSessions() {
@@ -637,31 +1100,14 @@ func (h *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Reques
 	}
 	log.Printf("WebSocket connection: %s", sessionID)
 
-	// Allow multiple tabs/windows to share the same session
-	// Each tab gets its own WSSession wrapper but shares the underlying ChatSession
-	h.sessionManager.tryRegisterConnection(sessionID)
-
 	// Track the chat that this connection has active
 	connectionActiveChat := ""
 
-	// Check if session already exists
-	existingSession, exists := h.sessionManager.GetSession(sessionID)
-	var session *chatbot.WSSession
-
-	if exists && len(existingSession.Chats) > 0 {
-		// Reuse existing session - create new WSSession with same ID but new connection
-		// Don't auto-restore any chat - let the client explicitly select one.
-		// This prevents conflicts when multiple tabs share a session.
-		session = chatbot.NewWSSession(conn, sessionID, h.cfg)
+	session := h.openConnection(sessionID, conn, func() *chatbot.WSSession {
+		session := chatbot.NewWSSession(conn, sessionID, h.config())
 		session.SetReadTimeout(pongWait)
-		log.Printf("Reconnected to existing session %s with %d chats", sessionID, len(existingSession.Chats))
-	} else {
-		// Create new session
-		session = chatbot.NewWSSession(conn, sessionID, h.cfg)
-		session.SetReadTimeout(pongWait)
-		h.sessionManager.AddSession(sessionID, "", nil)
-		log.Printf("Created new session %s", sessionID)
-	}
+		return session
+	})
 
 	// Send session ID to client
 	session.SendMessage("session_init", map[string]interface{}{
@@ -685,7 +1131,7 @@ func (h *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Reques
 		for {
 			select {
 			case <-ticker.C:
-				session.SendPing()
+				session.SendPing(conn)
 			case <-pingDone:
 				return
 			}
@@ -694,24 +1140,7 @@ func (h *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Reques
 
 	// Ensure cleanup on connection close
 	defer func() {
-		// Mark session as closed first, so that any in-flight goroutines
-		// (from processMessage) stop writing to the connection.
-		session.MarkClosed()
-
-		// Mark chat inactive if this connection had one active
-		if connectionActiveChat != "" {
-			h.sessionManager.markChatInactive(sessionID, connectionActiveChat)
-		}
-		// Cleanup handler and logging
-		if session.ChatSession != nil {
-			session.WSHandler = nil
-			log.Printf("Session %s disconnected (kept in memory, chat: %s)", sessionID, session.ChatName)
-		} else {
-			h.sessionManager.RemoveSession(sessionID)
-			log.Printf("Session %s closed (no active chat)", sessionID)
-		}
-		// Unregister connection to allow reuse of session ID
-		h.sessionManager.unregisterConnection(sessionID)
+		h.closeConnection(sessionID, session, conn, connectionActiveChat)
 	}()
 
 	// Handle messages
@@ -731,8 +1160,79 @@ func (h *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Reques
 			continue
 		}
 
-		go h.processMessage(session, &wsMsg, &connectionActiveChat)
+		if !h.sessionManager.beginRequest(sessionID, wsMsg.Type) {
+			session.SendError("The server is shutting down, please reconnect later")
+			continue
+		}
+		go func() {
+			defer h.sessionManager.endRequest(sessionID)
+			h.processMessage(session, &wsMsg, &connectionActiveChat)
+		}()
+	}
+}
+
+// openConnection attaches a new connection of a client, a WebSocket or an event stream,
+// to its session. create makes the WSSession of the connection, unless it joins a shared
+// one. The caller sends the session ID to the client.
+func (h *WebSocketHandler) openConnection(sessionID string, conn chatbot.Conn, create func() *chatbot.WSSession) *chatbot.WSSession {
+	// Allow multiple tabs/windows to share the same session
+	// Each tab gets its own WSSession wrapper but shares the underlying ChatSession
+	h.sessionManager.tryRegisterConnection(sessionID)
+
+	// Check if session already exists
+	existingSession, exists := h.sessionManager.GetSession(sessionID)
+	session, joined := h.sessionManager.attachConnection(sessionID, conn, func() *chatbot.WSSession {
+		session := create()
+		session.SetApprovalLimiter(h.sessionManager)
+		return session
+	})
+
+	if joined {
+		// Shared connections - the connection receives everything the session sends
+		log.Printf("Joined live session %s (%d connections)", sessionID, session.ConnCount())
+	} else if exists && len(existingSession.Chats) > 0 {
+		// Reuse existing session - create new WSSession with same ID but new connection
+		// Don't auto-restore any chat - let the client explicitly select one.
+		// This prevents conflicts when multiple tabs share a session.
+		log.Printf("Reconnected to existing session %s with %d chats", sessionID, len(existingSession.Chats))
+	} else {
+		// Create new session
+		h.sessionManager.AddSession(sessionID, "", nil)
+		log.Printf("Created new session %s", sessionID)
+	}
+	return session
+}
+
+// closeConnection detaches a closed connection from its session. activeChat is the chat
+// the connection had selected.
+func (h *WebSocketHandler) closeConnection(sessionID string, session *chatbot.WSSession, conn chatbot.Conn, activeChat string) {
+	// Mark chat inactive if this connection had one active
+	if activeChat != "" {
+		h.sessionManager.markChatInactive(sessionID, activeChat)
+	}
+	// Other connections still share the session, keep it running for them
+	if left := h.sessionManager.detachConnection(sessionID, session, conn); left > 0 {
+		log.Printf("Connection left session %s (%d connections remain)", sessionID, left)
+		h.sessionManager.unregisterConnection(sessionID)
+		return
 	}
+
+	// Mark session as closed first, so that any in-flight goroutines
+	// (from processMessage) stop writing to the connection.
+	session.MarkClosed()
+	// Cleanup handler and logging
+	if session.ChatSession != nil {
+		session.WSHandler = nil
+		log.Printf("Session %s disconnected (kept in memory, chat: %s)", sessionID, session.ChatName)
+	} else if info, ok := h.sessionManager.GetSession(sessionID); ok && len(info.Chats) > 0 {
+		log.Printf("Session %s disconnected without selecting a chat (kept with %d chats)", sessionID, len(info.Chats))
+	} else {
+		h.sessionManager.RemoveSession(sessionID)
+		h.limiter.forget(sessionID)
+		log.Printf("Session %s closed (no active chat)", sessionID)
+	}
+	// Unregister connection to allow reuse of session ID
+	h.sessionManager.unregisterConnection(sessionID)
 }
 
 // processMessage processes a WebSocket message
@@ -741,24 +1241,39 @@ func (h *WebSocketHandler) processMessage(session *chatbot.WSSession, msg *chatb
 	case "select_chat":
 		h.handleSelectChat(session, msg, connectionActiveChat)
 	case "chat":
-		h.handleChat(session, msg)
+		h.handleChat(session, msg, false)
 	case "regenerate":
-		// Remove last round (user message + assistant response) before re-processing
-		if session.ChatSession != nil {
-			session.ChatSession.RemoveLastRound()
-		}
-		// Then process as normal chat
-		h.handleChat(session, msg)
+		h.handleChat(session, msg, true)
+	case "edit_message":
+		h.handleEditMessage(session, msg)
 	case "stop":
 		h.handleStop(session)
+	case "resume":
+		h.handleResume(session)
 	case "clear":
 		h.handleClear(session)
 	case "keep":
-		h.handleKeep(session)
+		h.handleKeep(session, msg)
+	case "pin":
+		h.handlePin(session, msg)
+	case "compact":
+		h.handleCompact(session)
 	case "approval_response":
 		h.handleApprovalResponse(session, msg)
+	case "cancel_approval":
+		h.handleCancelApproval(session, msg)
 	case "deselect_chat":
 		h.handleDeselectChat(session, connectionActiveChat)
+	case "question_response":
+		h.handleQuestionResponse(session, msg)
+	case "reload_tools":
+		h.handleReloadTools(session)
+	case "list_tools":
+		h.handleListTools(session, "")
+	case "toggle_tool":
+		h.handleToggleTool(session, msg)
+	case "session_info":
+		h.handleSessionInfo(session)
 	default:
 		session.SendError(fmt.Sprintf("Unknown message type: %s", msg.Type))
 	}
@@ -772,8 +1287,10 @@ func (h *WebSocketHandler) handleSelectChat(session *chatbot.WSSession, msg *cha
 		return
 	}
 
-	// Verify chat exists
-	chatCfg, ok := h.cfg.Chats[req.ChatName]
+	// Verify chat exists, the chat is initialized with the same configuration if it is
+	// reloaded meanwhile
+	cfg := h.config()
+	chatCfg, ok := cfg.Chats[req.ChatName]
 	if !ok {
 		session.SendError(fmt.Sprintf("Chat '%s' not found", req.ChatName))
 		return
@@ -794,6 +1311,7 @@ func (h *WebSocketHandler) handleSelectChat(session *chatbot.WSSession, msg *cha
 		log.Printf("Session %s: Reactivating existing chat session for '%s'", session.SessionID, req.ChatName)
 		// Reinitialize WSHandler with current connection
 		session.WSHandler = chatbot.NewWSChatHandler(session)
+		session.SetApprovalTimeout(time.Duration(chatCfg.ApprovalTimeout) * time.Second)
 		if session.ChatBot != nil {
 			session.ChatBot.SetHandler(session.WSHandler)
 		}
@@ -815,7 +1333,8 @@ func (h *WebSocketHandler) handleSelectChat(session *chatbot.WSSession, msg *cha
 
 	// Check if this chat is already active in another connection of the same session.
 	// The 5s ping/pong mechanism ensures dead connections are cleaned up quickly.
-	if h.sessionManager.isChatActive(session.SessionID, req.ChatName) {
+	// Shared connections use the same WSSession, so they can't conflict.
+	if !h.sessionManager.sharesConnections() && h.sessionManager.isChatActive(session.SessionID, req.ChatName) {
 		session.SendError(fmt.Sprintf("Chat '%s' is already active in another connection of this session", req.ChatName))
 		return
 	}
@@ -824,6 +1343,12 @@ func (h *WebSocketHandler) handleSelectChat(session *chatbot.WSSession, msg *cha
 	previousChat := session.ChatName
 	if previousChat != "" {
 		log.Printf("Session %s: Switching chat from '%s' to '%s'", session.SessionID, previousChat, req.ChatName)
+		// Stop the response still streaming in the previous chat, so it does not keep
+		// writing into the new one
+		reason := fmt.Sprintf("Response in chat '%s' stopped to switch to '%s'", previousChat, req.ChatName)
+		if session.CancelTurn(reason, turnCancelTimeout) {
+			log.Printf("Session %s: Cancelled in-flight turn of chat '%s'", session.SessionID, previousChat)
+		}
 		// Mark old chat as inactive
 		h.sessionManager.markChatInactive(session.SessionID, previousChat)
 		// Note: We don't close the previous chat session, just save its state
@@ -846,6 +1371,7 @@ func (h *WebSocketHandler) handleSelectChat(session *chatbot.WSSession, msg *cha
 		session.ChatBot = chatState.ChatBot
 		// Reinitialize WSHandler with current connection
 		session.WSHandler = chatbot.NewWSChatHandler(session)
+		session.SetApprovalTimeout(time.Duration(chatCfg.ApprovalTimeout) * time.Second)
 		if session.ChatBot != nil {
 			session.ChatBot.SetHandler(session.WSHandler)
 		}
@@ -865,9 +1391,10 @@ func (h *WebSocketHandler) handleSelectChat(session *chatbot.WSSession, msg *cha
 		return
 	}
 
-	// Initialize new chat session
+	// Initialize new chat session. A chat loaded from the session store gets its
+	// tools and MCP clients initialized here, then its saved context back.
 	ctx := context.Background()
-	chatSession, err := chatbot.InitChatSession(ctx, h.cfg, req.ChatName, session.SessionID, false)
+	chatSession, err := chatbot.InitChatSession(ctx, cfg, req.ChatName, session.SessionID, false)
 	if err != nil {
 		// Clean up active chat tracking on failure
 		h.sessionManager.markChatInactive(session.SessionID, req.ChatName)
@@ -875,17 +1402,23 @@ func (h *WebSocketHandler) handleSelectChat(session *chatbot.WSSession, msg *cha
 		session.SendError(fmt.Sprintf("Failed to initialize chat session: %v", err))
 		return
 	}
+	selectedMessage := fmt.Sprintf("Selected chat: %s", req.ChatName)
+	if chatState, ok := h.sessionManager.GetChatState(session.SessionID, req.ChatName); ok && chatState.Restored != nil {
+		chatSession.Manager.Restore(*chatState.Restored)
+		selectedMessage = fmt.Sprintf("Restored chat: %s", req.ChatName)
+		log.Printf("Session %s: Restored stored context for chat '%s'", session.SessionID, req.ChatName)
+	}
 
 	// Initialize ChatBot with persistence store
-	cb := chatbot.NewChatBot(ctx, chatSession.Agent, chatSession.Manager, nil, chatSession.PersistenceStore())
 	wsHandler := chatbot.NewWSChatHandler(session)
-	cb.SetHandler(wsHandler)
+	cb := newWSChatBot(ctx, chatSession, chatSession.Manager, wsHandler)
 
 	// Save chat session and bot
 	session.ChatName = req.ChatName
 	session.ChatSession = chatSession
 	session.ChatBot = &cb
 	session.WSHandler = wsHandler
+	session.SetApprovalTimeout(time.Duration(chatCfg.ApprovalTimeout) * time.Second)
 
 	// Update session manager with chat session and bot
 	h.sessionManager.UpdateChatSessionWithBot(session.SessionID, req.ChatName, chatSession, &cb)
@@ -897,13 +1430,17 @@ func (h *WebSocketHandler) handleSelectChat(session *chatbot.WSSession, msg *cha
 		"session_id":    session.SessionID,
 		"chat_name":     req.ChatName,
 		"description":   chatCfg.Desc,
-		"message":       fmt.Sprintf("Selected chat: %s", req.ChatName),
+		"message":       selectedMessage,
 		"message_count": msgCount,
 	})
+	if chatSession.MCPInitErr != nil {
+		session.SendError(fmt.Sprintf("Some MCP servers failed to initialize: %v", chatSession.MCPInitErr))
+	}
 }
 
-// handleChat handles chat messages
-func (h *WebSocketHandler) handleChat(session *chatbot.WSSession, msg *chatbot.WSMessage) {
+// handleChat handles chat messages. regenerate replaces the last round (user message and
+// assistant response) by the answer to the message.
+func (h *WebSocketHandler) handleChat(session *chatbot.WSSession, msg *chatbot.WSMessage, regenerate bool) {
 	var req ChatRequest
 	if err := json.Unmarshal(msg.Payload, &req); err != nil {
 		session.SendError("Invalid chat request")
@@ -915,45 +1452,149 @@ func (h *WebSocketHandler) handleChat(session *chatbot.WSSession, msg *chatbot.W
 		session.SendError("Please select a chat first")
 		return
 	}
+	files := toFileData(req.Files)
+	if err := chatbot.ValidateFiles(session.ChatSession.Preset.Uploads, files); err != nil {
+		session.SendError(err.Error())
+		return
+	}
+
+	// A rate limited message leaves the conversation unchanged
+	release, ok := h.limiter.acquire(session)
+	if !ok {
+		return
+	}
+	defer release()
+	if regenerate {
+		session.ChatSession.RemoveLastRound()
+	}
 
 	// Reset cancel state for new request
 	session.ResetCancel()
 
 	// Create a cancellable context
 	ctx, cancelFunc := context.WithCancel(context.Background())
-	session.SetCancelFunc(cancelFunc)
-
-	// Convert FilePayload to FileData
-	var fileData []chatbot.FileData
-	if len(req.Files) > 0 {
-		fileData = make([]chatbot.FileData, len(req.Files))
-		for i, file := range req.Files {
-			fileData[i] = chatbot.FileData{
-				URL:      file.URL,
-				Type:     file.Type,
-				Name:     file.Name,
-				FileSize: file.FileSize,
-			}
-		}
-	}
+	endTurn := session.BeginTurn(cancelFunc)
+	defer endTurn()
+
+	// Pre-process files routed to tools or whose text is extracted for the model
+	message, fileData := prepareFiles(ctx, session.ChatSession, req.Message, files)
 
 	// Use pre-initialized ChatBot to process message with files
-	err := session.ChatBot.StreamChatWithHandler(ctx, req.Message, fileData)
+	err := session.ChatBot.StreamChatWithHandler(ctx, message, fileData)
+	h.finishTurn(session, err)
+}
+
+// handleEditMessage replaces the user message of a round by a new text and answers it
+// again, dropping the rounds after it
+func (h *WebSocketHandler) handleEditMessage(session *chatbot.WSSession, msg *chatbot.WSMessage) {
+	var req EditMessageRequest
+	if err := json.Unmarshal(msg.Payload, &req); err != nil || strings.TrimSpace(req.Message) == "" {
+		session.SendError("Invalid edit_message request")
+		return
+	}
+	if session.ChatName == "" || session.ChatSession == nil || session.WSHandler == nil {
+		session.SendError("Please select a chat first")
+		return
+	}
+	if session.InTurn() {
+		session.SendError("Cannot edit a message while a response is in progress")
+		return
+	}
+	release, ok := h.limiter.acquire(session)
+	if !ok {
+		return
+	}
+	defer release()
+
+	userMsg, err := session.ChatSession.EditUserMessage(req.Round, req.Message)
+	if err != nil {
+		session.SendError(fmt.Sprintf("Failed to edit message: %v", err))
+		return
+	}
+
+	session.ResetCancel()
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	endTurn := session.BeginTurn(cancelFunc)
+	defer endTurn()
+
+	err = session.ChatBot.RerunWithHandler(ctx, userMsg)
+	h.finishTurn(session, err)
+}
+
+// handleResume continues the last response after it was stopped
+func (h *WebSocketHandler) handleResume(session *chatbot.WSSession) {
+	if session.ChatName == "" || session.ChatSession == nil || session.WSHandler == nil {
+		session.SendError("Please select a chat first")
+		return
+	}
+	if session.InTurn() {
+		session.SendError("A response is already in progress")
+		return
+	}
+	if !session.ChatBot.CanResume() {
+		session.SendError("No stopped response to resume")
+		return
+	}
+	release, ok := h.limiter.acquire(session)
+	if !ok {
+		return
+	}
+	defer release()
+
+	session.ResetCancel()
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	endTurn := session.BeginTurn(cancelFunc)
+	defer endTurn()
+
+	log.Printf("Session %s: Resuming stopped response", session.SessionID)
+	err := session.ChatBot.ResumeWithHandler(ctx)
+	h.finishTurn(session, err)
+}
+
+// newWSChatBot creates the ChatBot of a chat session of a WebSocket session, answering
+// through handler. The conversation is kept in mgr, which may be the one of the chat
+// session replaced by chatSession.
+func newWSChatBot(ctx context.Context, chatSession *chatbot.ChatSession, mgr *manager.Manager, handler *chatbot.WSChatHandler) chatbot.ChatBot {
+	cb := chatbot.NewChatBot(ctx, chatSession.Agent, mgr, nil, chatSession.PersistenceStore())
+	cb.SetApprovalMemory(chatSession.Approvals)
+	cb.SetPlanGate(chatSession.PlanGate)
+	cb.SetApprovalLimit(chatSession.Preset.MaxApprovalTargets, chatSession.Preset.ApprovalOverflow)
+	cb.SetStoreReasoning(chatSession.Preset.StoresReasoning())
+	cb.SetReasoningDisplay(chatSession.Preset.ReasoningDisplay)
+	cb.SetRequestTimeout(time.Duration(chatSession.Preset.RequestTimeout) * time.Second)
+	cb.SetMaxChunkLength(chatSession.Preset.MaxChunkLength)
+	cb.SetResponseHook(chatSession.OnResponse)
+	cb.SetHandler(handler)
+	return cb
+}
+
+// finishTurn reports the outcome of a chat turn: errors are sent to the client, and a
+// stopped turn is reported together with whether it can be resumed
+func (h *WebSocketHandler) finishTurn(session *chatbot.WSSession, err error) {
+	defer h.sessionManager.SaveSession(session.SessionID)
 	if err != nil && !session.IsCancelled() {
+		// A response blocked by the content filter was already reported as content_filtered,
+		// a timed out turn completed with a note
+		if errors.Is(err, providers.ErrContentFiltered) || errors.Is(err, chatbot.ErrRequestTimeout) {
+			return
+		}
 		session.SendError(err.Error())
 		if strings.Contains(err.Error(), "failed to call mcp tool") && strings.Contains(err.Error(), "transport error") {
 			ctx := context.Background()
-			chatSession, err := chatbot.InitChatSession(ctx, h.cfg, session.ChatName, session.SessionID, false)
+			chatSession, err := chatbot.InitChatSession(ctx, h.config(), session.ChatName, session.SessionID, false)
 			if err != nil {
 				session.SendError(fmt.Sprintf("Failed to initialize chat session: %v", err))
 				return
 			}
 			session.ChatSession.Close()
 			session.ChatSession.Manager.SetChatModel(chatSession.Manager.GetChatModel())
-			cb := chatbot.NewChatBot(ctx, chatSession.Agent, session.ChatSession.Manager, nil, chatSession.PersistenceStore())
-			cb.SetHandler(session.WSHandler)
+			chatSession.Approvals = session.ChatSession.Approvals
+			cb := newWSChatBot(ctx, chatSession, session.ChatSession.Manager, session.WSHandler)
 			session.ChatSession = chatSession
 			session.ChatBot = &cb
+			// Closing the session closes the chat sessions it holds, the new one included
+			// so that its MCP clients and background tasks don't outlive it
+			h.sessionManager.UpdateChatSessionWithBot(session.SessionID, session.ChatName, chatSession, &cb)
 			session.SendError("Reinit chat session for refresh mcp client")
 		}
 		return
@@ -962,16 +1603,109 @@ func (h *WebSocketHandler) handleChat(session *chatbot.WSSession, msg *chatbot.W
 	// If cancelled, send stopped message
 	if session.IsCancelled() {
 		session.SendMessage("stopped", map[string]interface{}{
-			"message": "Response stopped by user",
+			"message":   session.CancelReason(),
+			"resumable": session.ChatBot.CanResume(),
 		})
 	}
 }
 
+// handleReloadTools re-initializes the tools of the current chat, keeping its conversation context.
+// The configuration file is re-read so MCP server changes are picked up; the reloaded config is
+// only used for this session and does not replace the one shared by other connections.
+func (h *WebSocketHandler) handleReloadTools(session *chatbot.WSSession) {
+	if session.ChatSession == nil {
+		session.SendError("No active chat session. Please select a chat first.")
+		return
+	}
+	// Reloading closes the current MCP clients, which the running turn may still be using
+	if session.InTurn() {
+		session.SendError("Cannot reload tools while a response is in progress. Stop it or wait for it to finish.")
+		return
+	}
+
+	cfg, err := config.LoadConfigProfile(configPath, configProfile)
+	if err != nil {
+		log.Printf("Session %s: Failed to reload config, keeping the current one: %v", session.SessionID, err)
+		cfg = h.config()
+	}
+
+	ctx := context.Background()
+	oldSession := session.ChatSession
+	chatSession, err := chatbot.ReloadChatSession(ctx, cfg, oldSession, false)
+	if err != nil {
+		session.SendError(fmt.Sprintf("Failed to reload tools: %v", err))
+		return
+	}
+	cb := newWSChatBot(ctx, chatSession, chatSession.Manager, session.WSHandler)
+	session.ChatSession = chatSession
+	session.ChatBot = &cb
+	h.sessionManager.UpdateChatSessionWithBot(session.SessionID, session.ChatName, chatSession, &cb)
+
+	added, removed := chatbot.DiffToolNames(ctx, oldSession.Tools, chatSession.Tools)
+	log.Printf("Session %s: Reloaded %d tools for chat '%s'", session.SessionID, len(chatSession.Tools), session.ChatName)
+	session.SendMessage("tools_reloaded", map[string]interface{}{
+		"chat_name":  session.ChatName,
+		"message":    toolChangesMessage(len(chatSession.Tools), added, removed),
+		"tool_count": len(chatSession.Tools),
+		"added":      added,
+		"removed":    removed,
+	})
+	if chatSession.MCPInitErr != nil {
+		session.SendError(fmt.Sprintf("Some MCP servers failed to initialize: %v", chatSession.MCPInitErr))
+	}
+}
+
+// handleListTools sends the tools of the current chat and whether each is enabled
+func (h *WebSocketHandler) handleListTools(session *chatbot.WSSession, message string) {
+	if session.ChatSession == nil {
+		session.SendError("No active chat session. Please select a chat first.")
+		return
+	}
+	session.SendMessage("tools", map[string]interface{}{
+		"chat_name": session.ChatName,
+		"tools":     session.ChatSession.ToolStates(context.Background()),
+		"message":   message,
+	})
+}
+
+// handleToggleTool enables or disables a tool of the current chat. The change applies from
+// the next turn on, so it is allowed while a response is in progress.
+func (h *WebSocketHandler) handleToggleTool(session *chatbot.WSSession, msg *chatbot.WSMessage) {
+	var req ToggleToolRequest
+	if err := json.Unmarshal(msg.Payload, &req); err != nil || req.Name == "" {
+		session.SendError("Invalid toggle_tool request")
+		return
+	}
+	if session.ChatSession == nil {
+		session.SendError("No active chat session. Please select a chat first.")
+		return
+	}
+	if err := session.ChatSession.SetToolEnabled(context.Background(), req.Name, req.Enabled); err != nil {
+		session.SendError(fmt.Sprintf("Failed to toggle tool: %v", err))
+		return
+	}
+
+	state := "disabled"
+	if req.Enabled {
+		state = "enabled"
+	}
+	log.Printf("Session %s: Tool %s %s for chat '%s'", session.SessionID, req.Name, state, session.ChatName)
+	message := fmt.Sprintf("Tool %s %s", req.Name, state)
+	if session.InTurn() {
+		message += ", the change applies from the next turn"
+	}
+	h.handleListTools(session, message)
+}
+
 // handleClear handles clear context request
 func (h *WebSocketHandler) handleClear(session *chatbot.WSSession) {
 	// Clear conversation record for the current chat only
 	if session.ChatSession != nil {
 		session.ChatSession.Clear()
+		if session.ChatBot != nil {
+			session.ChatBot.DiscardStopped()
+		}
+		h.sessionManager.SaveSession(session.SessionID)
 		// Get updated message count (should be 0 after clear)
 		msgCount := session.ChatSession.GetMessageCount()
 		session.SendMessage("cleared", map[string]interface{}{
@@ -987,9 +1721,16 @@ func (h *WebSocketHandler) handleClear(session *chatbot.WSSession) {
 }
 
 // handleKeep handles keep session request (execute keep hook)
-func (h *WebSocketHandler) handleKeep(session *chatbot.WSSession) {
+func (h *WebSocketHandler) handleKeep(session *chatbot.WSSession, msg *chatbot.WSMessage) {
+	var req KeepRequest
+	if len(msg.Payload) > 0 {
+		if err := json.Unmarshal(msg.Payload, &req); err != nil {
+			session.SendError("Invalid keep request")
+			return
+		}
+	}
 	if session.ChatSession != nil {
-		if err := session.ChatSession.OnKeep(); err != nil {
+		if err := session.ChatSession.OnKeep(strings.TrimSpace(req.Label)); err != nil {
 			log.Printf("Session %s: Keep hook failed: %v", session.SessionID, err)
 			session.SendMessage("kept", map[string]interface{}{
 				"chat_name": session.ChatName,
@@ -1009,6 +1750,69 @@ func (h *WebSocketHandler) handleKeep(session *chatbot.WSSession) {
 	}
 }
 
+// handlePin pins a user message of the current chat so it is never summarized, or unpins
+// it, and sends the pinned messages
+func (h *WebSocketHandler) handlePin(session *chatbot.WSSession, msg *chatbot.WSMessage) {
+	var req PinRequest
+	if len(msg.Payload) > 0 {
+		if err := json.Unmarshal(msg.Payload, &req); err != nil || req.Index < 0 {
+			session.SendError("Invalid pin request")
+			return
+		}
+	}
+	if session.ChatSession == nil {
+		session.SendError("No active chat session. Please select a chat first.")
+		return
+	}
+	message := "Message unpinned"
+	if req.Unpin {
+		if err := session.ChatSession.Manager.UnpinMessage(req.Index); err != nil {
+			session.SendError(fmt.Sprintf("Failed to unpin message: %v", err))
+			return
+		}
+	} else if _, err := session.ChatSession.Manager.PinMessage(req.Index); err != nil {
+		session.SendError(fmt.Sprintf("Failed to pin message: %v", err))
+		return
+	} else {
+		message = "Message pinned"
+	}
+	h.sessionManager.SaveSession(session.SessionID)
+
+	pinned := make([]string, 0)
+	for _, m := range session.ChatSession.Manager.PinnedMessages() {
+		pinned = append(pinned, m.Content)
+	}
+	session.SendMessage("pinned", map[string]interface{}{
+		"chat_name": session.ChatName,
+		"message":   message,
+		"pinned":    pinned,
+	})
+}
+
+// handleCompact summarizes the context of the current chat except the last round
+func (h *WebSocketHandler) handleCompact(session *chatbot.WSSession) {
+	if session.ChatSession == nil {
+		session.SendError("No active chat session. Please select a chat first.")
+		return
+	}
+	if session.InTurn() {
+		session.SendError("Cannot compact the context while a response is in progress")
+		return
+	}
+	result, err := session.ChatSession.Manager.ForceCompress(context.Background())
+	if err != nil {
+		session.SendError(fmt.Sprintf("Failed to compact the context: %v", err))
+		return
+	}
+	h.sessionManager.SaveSession(session.SessionID)
+	session.SendMessage("compacted", map[string]interface{}{
+		"chat_name":     session.ChatName,
+		"rounds":        result.Rounds,
+		"message_count": result.Messages,
+		"tokens":        result.Tokens,
+	})
+}
+
 // handleStop handles stop request for ongoing chat
 func (h *WebSocketHandler) handleStop(session *chatbot.WSSession) {
 	log.Printf("Session %s: Stop requested", session.SessionID)
@@ -1048,6 +1852,7 @@ func (h *WebSocketHandler) handleApprovalResponse(session *chatbot.WSSession, ms
 	for id, item := range payload.Results {
 		result := &mcp.ApprovalResult{
 			Approved: item.Approved,
+			Always:   item.Always,
 		}
 		if item.Reason != "" {
 			result.DisapproveReason = &item.Reason
@@ -1059,12 +1864,48 @@ func (h *WebSocketHandler) handleApprovalResponse(session *chatbot.WSSession, ms
 	session.HandleApprovalResponse(payload.ApprovalID, results)
 }
 
+// handleCancelApproval denies the tool calls of a pending approval request as cancelled,
+// without stopping the turn
+func (h *WebSocketHandler) handleCancelApproval(session *chatbot.WSSession, msg *chatbot.WSMessage) {
+	var payload CancelApprovalPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		log.Printf("Invalid cancel_approval format: %v", err)
+		session.SendError("Invalid cancel_approval format")
+		return
+	}
+	if !session.CancelApproval(payload.ApprovalID) {
+		log.Printf("Session %s: No pending approval request %s to cancel", session.SessionID, payload.ApprovalID)
+	}
+}
+
+// handleQuestionResponse handles the answer to a clarifying question from the client
+func (h *WebSocketHandler) handleQuestionResponse(session *chatbot.WSSession, msg *chatbot.WSMessage) {
+	var payload QuestionResponsePayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		log.Printf("Invalid question_response format: %v", err)
+		session.SendError("Invalid question_response format")
+		return
+	}
+	session.HandleQuestionResponse(payload.QuestionID, payload.Answer)
+}
+
 func init() {
 	// Add serve command
 	serveCmd.Flags().StringP("host", "", "0.0.0.0", "Host to listen on")
 	serveCmd.Flags().IntP("port", "", 8080, "Port to listen on")
 	serveCmd.Flags().StringP("basic-auth", "", "", "Basic auth credentials as comma-separated user:pass pairs (e.g., \"alice:pwd1,bob:pwd2\")")
 	serveCmd.Flags().StringP("basic-auth-file", "", "", "Path to a file containing user:password pairs (one per line, # for comments)")
+	serveCmd.Flags().IntP("max-pending-approvals", "", 0, "Maximum approval requests waiting for an answer across all sessions, further requests are rejected (default: 0, no limit)")
+	serveCmd.Flags().Bool("shared-connections", false, "Let the connections of a session share it: messages are sent to all of them and any can answer approvals")
+	serveCmd.Flags().Int64("max-chat-request-size", 20, "Maximum size in MB of a POST /chat or /sse/send request body, including the attached files")
+	serveCmd.Flags().Int("drain-timeout", 30, "Seconds to wait on shutdown for in-flight responses to complete before the sessions are closed")
+	serveCmd.Flags().Int("session-ttl", 0, "Minutes a session without connections is kept after its last activity before it is closed and removed, also from --session-dir (default: 0, kept until the server stops)")
+	serveCmd.Flags().String("allowed-origins", "", "Comma-separated origins allowed to call the server from a browser, e.g. \"https://app.example.com,https://*.example.com\"; \"*\" allows any (default: any, insecure)")
+	serveCmd.Flags().Int("rate-limit", 0, "Maximum chat messages per minute per session, further messages get a rate_limited reply (default: 0, no limit)")
+	serveCmd.Flags().Int("rate-burst", 0, "Chat messages a session may send at once within --rate-limit (default: the rate limit)")
+	serveCmd.Flags().Int("max-streams", 0, "Maximum responses streamed at the same time across all sessions, responses waiting for an approval excluded (default: 0, no limit)")
+	serveCmd.Flags().Bool("metrics", false, "Expose Prometheus metrics of the requests, tool calls, approvals and sessions on /metrics")
+	serveCmd.Flags().StringP("session-dir", "", "", "Directory to save sessions in, so conversations survive restarts (default: in memory only)")
 
 	RootCmd.AddCommand(serveCmd)
 }
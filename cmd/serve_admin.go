@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Arvintian/chat-agent/pkg/chatbot"
+
+	"github.com/gorilla/mux"
+)
+
+// closeSessionTimeout is how long closing a session waits for its in-flight turn to stop
+const closeSessionTimeout = 5 * time.Second
+
+// ListSessions returns the metadata of all sessions, sorted by ID
+func (sm *SessionManager) ListSessions() []SessionDetails {
+	sm.mu.RLock()
+	ids := make([]string, 0, len(sm.sessions))
+	for id := range sm.sessions {
+		ids = append(ids, id)
+	}
+	sm.mu.RUnlock()
+	sort.Strings(ids)
+
+	sessions := make([]SessionDetails, 0, len(ids))
+	for _, id := range ids {
+		// A session removed meanwhile is skipped
+		if details, ok := sm.SessionDetails(id); ok {
+			sessions = append(sessions, *details)
+		}
+	}
+	return sessions
+}
+
+// CloseSession closes a session for an operator: its in-flight turn is stopped, its
+// connections are closed and it is removed with its chats, from the session store too.
+// Returns false if the session doesn't exist.
+func (sm *SessionManager) CloseSession(sessionID string) bool {
+	sm.mu.RLock()
+	_, ok := sm.sessions[sessionID]
+	var wsSessions []*chatbot.WSSession
+	for ws := range sm.wsSessions {
+		if ws.SessionID == sessionID {
+			wsSessions = append(wsSessions, ws)
+		}
+	}
+	sm.mu.RUnlock()
+	if !ok {
+		return false
+	}
+
+	for _, ws := range wsSessions {
+		ws.CancelTurn("The session was closed by the server", closeSessionTimeout)
+		ws.SendError("The session was closed by the server")
+		ws.MarkClosed()
+		ws.CloseConns()
+	}
+	sm.RemoveSession(sessionID)
+	return true
+}
+
+// adminSessionsHandler serves GET /admin/sessions. The sessions can be filtered by the
+// query parameters chat (sessions with this chat), connected and streaming (true or false).
+func (h *WebSocketHandler) adminSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	boolFilter := func(name string) (*bool, bool) {
+		value := query.Get(name)
+		if value == "" {
+			return nil, true
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, name+" must be true or false", http.StatusBadRequest)
+			return nil, false
+		}
+		return &b, true
+	}
+	connected, ok := boolFilter("connected")
+	if !ok {
+		return
+	}
+	streaming, ok := boolFilter("streaming")
+	if !ok {
+		return
+	}
+	chat := query.Get("chat")
+
+	sessions := make([]SessionDetails, 0)
+	for _, session := range h.sessionManager.ListSessions() {
+		if connected != nil && (session.Connections > 0) != *connected {
+			continue
+		}
+		if streaming != nil && session.Streaming != *streaming {
+			continue
+		}
+		if chat != "" && !hasChat(session, chat) {
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"sessions": sessions,
+	})
+}
+
+// adminCloseSessionHandler serves DELETE /admin/sessions/{id}
+func (h *WebSocketHandler) adminCloseSessionHandler(w http.ResponseWriter, r *http.Request) {
+	sessionID := mux.Vars(r)["id"]
+	if !h.sessionManager.CloseSession(sessionID) {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	h.limiter.forget(sessionID)
+	log.Printf("Session %s closed by an operator", sessionID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func hasChat(session SessionDetails, name string) bool {
+	for _, chat := range session.Chats {
+		if chat.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Arvintian/chat-agent/pkg/chatbot"
+	"github.com/Arvintian/chat-agent/pkg/config"
+	"github.com/Arvintian/chat-agent/pkg/providers"
+)
+
+// ChatHTTPResponse is the body returned by POST /chat. Error is set when the turn failed,
+// the response and tool calls then hold what was produced before.
+type ChatHTTPResponse struct {
+	chatbot.BufferedResult
+	Error string `json:"error,omitempty"`
+	// Code is the machine-readable code of Error, see chatbot.ErrorCode
+	Code string `json:"code,omitempty"`
+}
+
+// chatHTTPHandler runs a single chat turn per request and returns the whole response as
+// JSON, for clients that can't use the WebSocket. Each request gets its own session,
+// which is discarded afterwards. The approval query parameter decides the tool calls
+// that require approval: "auto" approves them, "deny" (default) rejects them. getConfig
+// returns the current configuration, which is reloaded on SIGHUP.
+func chatHTTPHandler(getConfig func() *config.Config, maxRequestSize int64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cfg := getConfig()
+		var autoApprove bool
+		switch r.URL.Query().Get("approval") {
+		case "", "deny":
+		case "auto":
+			autoApprove = true
+		default:
+			writeChatHTTPError(w, http.StatusBadRequest, "approval must be auto or deny")
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+		var req ChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeChatHTTPError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body larger than %d bytes", maxRequestSize))
+				return
+			}
+			writeChatHTTPError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+			return
+		}
+		if strings.TrimSpace(req.Message) == "" && len(req.Files) == 0 {
+			writeChatHTTPError(w, http.StatusBadRequest, "message or files are required")
+			return
+		}
+		chatName := req.ChatName
+		if chatName == "" {
+			chatName = defaultChatName(cfg)
+		}
+		chatCfg, ok := cfg.Chats[chatName]
+		if !ok {
+			writeChatHTTPError(w, http.StatusNotFound, fmt.Sprintf("chat '%s' not found", chatName))
+			return
+		}
+		if err := chatbot.ValidateFiles(chatCfg.Uploads, toFileData(req.Files)); err != nil {
+			writeChatHTTPError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// The turn stops when the client goes away
+		ctx := r.Context()
+		sessionID := fmt.Sprintf("http-%d", time.Now().UnixNano())
+		chatSession, err := chatbot.InitChatSession(ctx, cfg, chatName, sessionID, false)
+		if err != nil {
+			writeChatHTTPError(w, http.StatusInternalServerError, fmt.Sprintf("failed to initialize chat session: %v", err))
+			return
+		}
+		defer func() {
+			// Nothing of the session is kept
+			if persistence := chatSession.PersistenceStore(); persistence != nil {
+				if err := persistence.Clear(); err != nil {
+					log.Printf("HTTP chat %s: %v", sessionID, err)
+				}
+			}
+			if err := chatSession.Close(); err != nil {
+				log.Printf("HTTP chat %s: failed to close the session: %v", sessionID, err)
+			}
+		}()
+
+		cb := chatbot.NewChatBot(ctx, chatSession.Agent, chatSession.Manager, nil, nil)
+		cb.SetApprovalMemory(chatSession.Approvals)
+		cb.SetPlanGate(chatSession.PlanGate)
+		cb.SetStoreReasoning(chatCfg.StoresReasoning())
+		cb.SetReasoningDisplay(chatCfg.ReasoningDisplay)
+		cb.SetRequestTimeout(time.Duration(chatCfg.RequestTimeout) * time.Second)
+		cb.SetResponseHook(chatSession.OnResponse)
+		handler := chatbot.NewBufferedChatHandler(autoApprove)
+		cb.SetHandler(handler)
+
+		message, fileData := prepareChatMessage(ctx, chatSession, req.Message, req.Files)
+		err = cb.StreamChatWithHandler(ctx, message, fileData)
+
+		resp := ChatHTTPResponse{BufferedResult: handler.Result()}
+		status := http.StatusOK
+		if err != nil {
+			status = chatHTTPErrorStatus(ctx, err)
+			resp.Error = err.Error()
+			resp.Code = chatbot.ErrorCode(err)
+			log.Printf("HTTP chat %s: %v", sessionID, err)
+		}
+		writeChatHTTPResponse(w, status, resp)
+	}
+}
+
+// chatHTTPErrorStatus maps the error of a turn to an HTTP status
+func chatHTTPErrorStatus(ctx context.Context, err error) int {
+	switch {
+	case errors.Is(err, chatbot.ErrRequestTimeout):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, providers.ErrContentFiltered):
+		return http.StatusUnprocessableEntity
+	case ctx.Err() != nil:
+		// The client went away, nobody reads the status
+		return http.StatusRequestTimeout
+	default:
+		// The model or a tool failed upstream
+		return http.StatusBadGateway
+	}
+}
+
+// defaultChatName returns the chat marked as default, empty if there is none
+func defaultChatName(cfg *config.Config) string {
+	for name, chatCfg := range cfg.Chats {
+		if chatCfg.Default {
+			return name
+		}
+	}
+	return ""
+}
+
+func writeChatHTTPError(w http.ResponseWriter, status int, message string) {
+	writeChatHTTPResponse(w, status, ChatHTTPResponse{Error: message})
+}
+
+func writeChatHTTPResponse(w http.ResponseWriter, status int, resp ChatHTTPResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
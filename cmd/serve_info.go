@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Arvintian/chat-agent/pkg/chatbot"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// SessionDetails is the metadata of a session sent in reply to session_info and by
+// GET /sessions/{id}. It holds counts and names, never the conversation.
+type SessionDetails struct {
+	SessionID    string        `json:"session_id"`
+	ChatName     string        `json:"chat_name"`
+	CreatedAt    time.Time     `json:"created_at"`
+	LastActivity time.Time     `json:"last_activity"`
+	Connections  int           `json:"connections"`
+	Streaming    bool          `json:"streaming"`
+	Chats        []ChatDetails `json:"chats"`
+}
+
+// ChatDetails is the metadata of a chat of a session. A chat restored from the session
+// store is not initialized until it is selected again, it has no tools then.
+type ChatDetails struct {
+	Name         string   `json:"name"`
+	Active       bool     `json:"active"`
+	Initialized  bool     `json:"initialized"`
+	MessageCount int      `json:"message_count"`
+	Tools        []string `json:"tools"`
+}
+
+// ServerStats are the counters of the sessions reported on /metrics
+type ServerStats struct {
+	Sessions          int
+	ConnectedSessions int
+	Connections       int
+	StreamingSessions int
+	InFlightRequests  int
+	PendingApprovals  int
+}
+
+// SessionDetails returns the metadata of a session, false if the session doesn't exist
+func (sm *SessionManager) SessionDetails(sessionID string) (*SessionDetails, bool) {
+	sm.mu.RLock()
+	info, ok := sm.sessions[sessionID]
+	if !ok {
+		sm.mu.RUnlock()
+		return nil, false
+	}
+	details := &SessionDetails{
+		SessionID:    info.ID,
+		ChatName:     info.ChatName,
+		CreatedAt:    info.CreatedAt,
+		LastActivity: info.LastActivity,
+		Connections:  sm.connectionCount[sessionID],
+		Chats:        make([]ChatDetails, 0, len(info.Chats)),
+	}
+	states := make(map[string]*ChatState, len(info.Chats))
+	for name, state := range info.Chats {
+		states[name] = state
+	}
+	active := sm.activeChats[sessionID]
+	var wsSessions []*chatbot.WSSession
+	for ws := range sm.wsSessions {
+		if ws.SessionID == sessionID {
+			wsSessions = append(wsSessions, ws)
+		}
+	}
+	sm.mu.RUnlock()
+
+	// The chat sessions and WSSessions have their own locks, they are read without sm.mu
+	for name, state := range states {
+		chat := ChatDetails{Name: name, Active: active[name] > 0, Tools: make([]string, 0)}
+		switch {
+		case state.ChatSession != nil:
+			chat.Initialized = true
+			chat.MessageCount = state.ChatSession.GetMessageCount()
+			for _, tool := range state.ChatSession.ToolStates(context.Background()) {
+				chat.Tools = append(chat.Tools, tool.Name)
+			}
+		case state.Restored != nil:
+			for _, round := range state.Restored.Messages {
+				chat.MessageCount += len(round)
+			}
+			for _, round := range state.Restored.CompressBuffer {
+				chat.MessageCount += len(round)
+			}
+		}
+		details.Chats = append(details.Chats, chat)
+	}
+	sort.Slice(details.Chats, func(i, j int) bool {
+		return details.Chats[i].Name < details.Chats[j].Name
+	})
+	for _, ws := range wsSessions {
+		if ws.InTurn() {
+			details.Streaming = true
+		}
+	}
+	return details, true
+}
+
+// Stats returns the counters of the sessions and connections of the server
+func (sm *SessionManager) Stats() ServerStats {
+	sm.mu.RLock()
+	stats := ServerStats{
+		Sessions:          len(sm.sessions),
+		ConnectedSessions: len(sm.connectionCount),
+		PendingApprovals:  sm.pendingApprovals,
+	}
+	for _, n := range sm.connectionCount {
+		stats.Connections += n
+	}
+	for _, n := range sm.inFlight {
+		stats.InFlightRequests += n
+	}
+	streaming := make(map[string]bool)
+	var wsSessions []*chatbot.WSSession
+	for ws := range sm.wsSessions {
+		wsSessions = append(wsSessions, ws)
+	}
+	sm.mu.RUnlock()
+
+	for _, ws := range wsSessions {
+		if ws.InTurn() {
+			streaming[ws.SessionID] = true
+		}
+	}
+	stats.StreamingSessions = len(streaming)
+	return stats
+}
+
+// handleSessionInfo sends the metadata of the session of the connection
+func (h *WebSocketHandler) handleSessionInfo(session *chatbot.WSSession) {
+	details, ok := h.sessionManager.SessionDetails(session.SessionID)
+	if !ok {
+		// The session has no chat yet, it is only known by its connection
+		details = &SessionDetails{
+			SessionID:   session.SessionID,
+			Connections: session.ConnCount(),
+			Streaming:   session.InTurn(),
+			Chats:       make([]ChatDetails, 0),
+		}
+	}
+	session.SendMessage("session_info", details)
+}
+
+// sessionInfoHandler serves GET /sessions/{id}
+func (h *WebSocketHandler) sessionInfoHandler(w http.ResponseWriter, r *http.Request) {
+	details, ok := h.sessionManager.SessionDetails(mux.Vars(r)["id"])
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(details)
+}
+
+// metricsCollectors returns gauges reporting the stats of the sessions, read when
+// /metrics is scraped
+func (sm *SessionManager) metricsCollectors() []prometheus.Collector {
+	gauge := func(name, help string, value func(ServerStats) int) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "chat_agent",
+			Name:      name,
+			Help:      help,
+		}, func() float64 { return float64(value(sm.Stats())) })
+	}
+	return []prometheus.Collector{
+		gauge("sessions", "Sessions kept by the server, including the disconnected ones.",
+			func(s ServerStats) int { return s.Sessions }),
+		gauge("active_sessions", "Sessions with at least one open connection.",
+			func(s ServerStats) int { return s.ConnectedSessions }),
+		gauge("connections", "Open WebSocket and event stream connections.",
+			func(s ServerStats) int { return s.Connections }),
+		gauge("streaming_sessions", "Sessions with a response in progress.",
+			func(s ServerStats) int { return s.StreamingSessions }),
+		gauge("inflight_requests", "Client messages being processed.",
+			func(s ServerStats) int { return s.InFlightRequests }),
+		gauge("pending_approvals", "Approval requests waiting for an answer.",
+			func(s ServerStats) int { return s.PendingApprovals }),
+	}
+}
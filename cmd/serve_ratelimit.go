@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Arvintian/chat-agent/pkg/chatbot"
+	"github.com/Arvintian/chat-agent/pkg/utils"
+)
+
+// streamRetryAfter is the retry hint sent when all concurrent streams are taken
+const streamRetryAfter = 5 * time.Second
+
+// RateLimitedPayload is sent instead of starting a response when a limit is reached
+type RateLimitedPayload struct {
+	Reason string `json:"reason"`
+	// RetryAfter is the number of seconds after which the message may be sent again
+	RetryAfter int `json:"retry_after"`
+}
+
+// ChatLimiter limits the responses started by the clients: the chat messages of each
+// session with a token bucket, and the responses streamed at the same time across the
+// server. A response waiting for an approval does not count as streaming, and approval
+// responses are never limited.
+type ChatLimiter struct {
+	messages          *utils.RateLimiter // nil means no limit per session
+	messagesPerMinute int
+	maxStreams        int // 0 means no cap
+
+	mu      sync.Mutex
+	streams map[*chatbot.WSSession]int
+}
+
+// NewChatLimiter creates a limiter of messagesPerMinute chat messages per session, in
+// bursts of up to burst messages, and maxStreams responses streamed at the same time.
+// 0 disables a limit.
+func NewChatLimiter(messagesPerMinute, burst, maxStreams int) *ChatLimiter {
+	l := &ChatLimiter{
+		messagesPerMinute: messagesPerMinute,
+		maxStreams:        maxStreams,
+		streams:           make(map[*chatbot.WSSession]int),
+	}
+	if messagesPerMinute > 0 {
+		l.messages = utils.NewRateLimiter(messagesPerMinute, burst)
+	}
+	return l
+}
+
+// acquire checks the limits before a response of the session starts. It returns the
+// function to call when the response ends, or false after telling the client to retry.
+func (l *ChatLimiter) acquire(session *chatbot.WSSession) (func(), bool) {
+	if l == nil {
+		return func() {}, true
+	}
+	l.mu.Lock()
+	reason, retryAfter := l.check(session.SessionID)
+	if reason == "" {
+		l.streams[session]++
+	}
+	l.mu.Unlock()
+
+	if reason != "" {
+		session.SendMessage("rate_limited", RateLimitedPayload{
+			Reason:     reason,
+			RetryAfter: int(math.Ceil(retryAfter.Seconds())),
+		})
+		return nil, false
+	}
+	return func() { l.release(session) }, true
+}
+
+// check returns why a response of the session can't start, empty if it can. A rejected
+// response takes no token of the session. The caller must hold l.mu.
+func (l *ChatLimiter) check(sessionID string) (string, time.Duration) {
+	if l.maxStreams > 0 && l.streamingLocked() >= l.maxStreams {
+		return fmt.Sprintf("the server is streaming %d responses, its maximum", l.maxStreams), streamRetryAfter
+	}
+	if l.messages != nil {
+		if ok, wait := l.messages.Allow(sessionID); !ok {
+			return fmt.Sprintf("at most %d messages per minute are allowed per session", l.messagesPerMinute), wait
+		}
+	}
+	return "", 0
+}
+
+// streamingLocked counts the responses in progress that don't wait for an approval. The
+// caller must hold l.mu.
+func (l *ChatLimiter) streamingLocked() int {
+	n := 0
+	for session, count := range l.streams {
+		if !session.AwaitingApproval() {
+			n += count
+		}
+	}
+	return n
+}
+
+func (l *ChatLimiter) release(session *chatbot.WSSession) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.streams[session] <= 1 {
+		delete(l.streams, session)
+		return
+	}
+	l.streams[session]--
+}
+
+// forget drops the message count of a removed session
+func (l *ChatLimiter) forget(sessionID string) {
+	if l != nil && l.messages != nil {
+		l.messages.Forget(sessionID)
+	}
+}
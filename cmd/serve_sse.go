@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Arvintian/chat-agent/pkg/chatbot"
+)
+
+// sseKeepAlive is the interval of the comments keeping an idle event stream open
+const sseKeepAlive = 15 * time.Second
+
+// sseStream is an open event stream, the transport for clients that can't use the
+// WebSocket: the server messages are streamed on GET /sse and the client messages are
+// posted to /sse/send
+type sseStream struct {
+	session *chatbot.WSSession
+	// activeChat is the chat selected by the client, like connectionActiveChat of a WebSocket
+	activeChat string
+}
+
+// SSESendRequest is the body of POST /sse/send: a client message of the WebSocket
+// protocol for the event stream of the session
+type SSESendRequest struct {
+	SessionID string `json:"session_id"`
+	chatbot.WSMessage
+}
+
+// HandleSSE streams the messages of a session as server-sent events. The events carry
+// the same {type, payload} JSON as the WebSocket messages. A session has one event
+// stream at a time.
+func (h *WebSocketHandler) HandleSSE(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.URL.Query().Get("session_id")
+	if sessionID == "" {
+		sessionID = fmt.Sprintf("session-%d", time.Now().UnixNano())
+	}
+
+	h.sseMu.Lock()
+	if _, ok := h.sseStreams[sessionID]; ok {
+		h.sseMu.Unlock()
+		http.Error(w, "an event stream is already open for the session", http.StatusConflict)
+		return
+	}
+	stream := &sseStream{}
+	h.sseStreams[sessionID] = stream
+	h.sseMu.Unlock()
+
+	conn := chatbot.NewSSEConn(w)
+	log.Printf("SSE connection: %s", sessionID)
+	session := h.openConnection(sessionID, conn, func() *chatbot.WSSession {
+		session := chatbot.NewWSSession(nil, sessionID, h.config())
+		session.AddConn(conn)
+		return session
+	})
+	h.sseMu.Lock()
+	stream.session = session
+	h.sseMu.Unlock()
+
+	defer func() {
+		conn.Close()
+		h.sseMu.Lock()
+		delete(h.sseStreams, sessionID)
+		h.sseMu.Unlock()
+		h.closeConnection(sessionID, session, conn, stream.activeChat)
+	}()
+
+	session.SendMessage("session_init", map[string]interface{}{
+		"session_id": sessionID,
+	})
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := conn.Ping(); err != nil {
+				return
+			}
+		case <-conn.Done():
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// HandleSSESend processes a client message for the event stream of a session. The
+// message is processed like one received on a WebSocket, its results are sent on the
+// stream; the request returns once the message is accepted.
+func (h *WebSocketHandler) HandleSSESend(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxRequestSize)
+	var req SSESendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("request body larger than %d bytes", h.maxRequestSize), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Type == "" {
+		http.Error(w, "type is required", http.StatusBadRequest)
+		return
+	}
+
+	h.sseMu.Lock()
+	stream, ok := h.sseStreams[req.SessionID]
+	if ok && stream.session == nil {
+		ok = false
+	}
+	h.sseMu.Unlock()
+	if !ok {
+		http.Error(w, "no event stream is open for the session", http.StatusNotFound)
+		return
+	}
+
+	if !h.sessionManager.beginRequest(req.SessionID, req.Type) {
+		http.Error(w, "the server is shutting down, please reconnect later", http.StatusServiceUnavailable)
+		return
+	}
+	msg := req.WSMessage
+	go func() {
+		defer h.sessionManager.endRequest(req.SessionID)
+		h.processMessage(stream.session, &msg, &stream.activeChat)
+	}()
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"log"
+	"time"
+)
+
+// maxJanitorInterval is the longest time between two runs of the session janitor
+const maxJanitorInterval = time.Minute
+
+// SetSessionTTL sets how long a session without connections is kept after its last
+// activity; 0 keeps the sessions until the server stops
+func (sm *SessionManager) SetSessionTTL(ttl time.Duration) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.sessionTTL = ttl
+}
+
+// touchLocked records activity in a session. The caller must hold sm.mu.
+func (sm *SessionManager) touchLocked(sessionID string) {
+	if session, ok := sm.sessions[sessionID]; ok {
+		session.LastActivity = time.Now()
+	}
+}
+
+// expireSessions closes and removes the sessions idle for longer than the TTL at now.
+// A session with an open connection or a message in flight is never expired, and a
+// reconnection registers its connection under sm.mu, so it can't be expired halfway.
+// Returns the IDs of the removed sessions.
+func (sm *SessionManager) expireSessions(now time.Time) []string {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.sessionTTL <= 0 {
+		return nil
+	}
+	var expired []string
+	for sessionID, session := range sm.sessions {
+		if sm.connectionCount[sessionID] > 0 || sm.inFlight[sessionID] > 0 {
+			continue
+		}
+		if now.Sub(session.LastActivity) <= sm.sessionTTL {
+			continue
+		}
+		for chatName, state := range session.Chats {
+			if state.ChatSession != nil {
+				if err := state.ChatSession.Close(); err != nil {
+					log.Printf("Error closing session %s chat %s: %v", sessionID, chatName, err)
+				}
+			}
+		}
+		delete(sm.sessions, sessionID)
+		delete(sm.activeChats, sessionID)
+		if sm.store != nil {
+			if err := sm.store.Delete(sessionID); err != nil {
+				log.Printf("Failed to delete stored session %s: %v", sessionID, err)
+			}
+		}
+		expired = append(expired, sessionID)
+	}
+	return expired
+}
+
+// runSessionJanitor removes the idle sessions until stop is closed. It returns right
+// away when no TTL is set.
+func (h *WebSocketHandler) runSessionJanitor(stop <-chan struct{}) {
+	h.sessionManager.mu.RLock()
+	ttl := h.sessionManager.sessionTTL
+	h.sessionManager.mu.RUnlock()
+	if ttl <= 0 {
+		return
+	}
+	ticker := time.NewTicker(min(ttl/2, maxJanitorInterval))
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			for _, sessionID := range h.sessionManager.expireSessions(now) {
+				h.limiter.forget(sessionID)
+				log.Printf("Session %s expired after %s without activity", sessionID, ttl)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+	"github.com/Arvintian/chat-agent/pkg/logger"
+	"github.com/Arvintian/chat-agent/pkg/mcp"
+	builtintools "github.com/Arvintian/chat-agent/pkg/tools"
+	"github.com/Arvintian/chat-agent/pkg/utils"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/spf13/cobra"
+)
+
+// toolTestCmd represents the tool-test command
+var toolTestCmd = &cobra.Command{
+	Use:   "tool-test",
+	Short: "Invoke a single tool directly without a model",
+	Long: `Load a tool from the configuration and invoke it directly with the given arguments.
+
+Built-in tools are looked up by their tool name (e.g. cmd, read_file), MCP tools
+by serverName_toolName. Use --chat to restrict the lookup to the tools of a chat preset.
+
+Examples:
+  chat-agent tool-test --tool cmd --args '{"command":"ls"}'
+  chat-agent tool-test --chat coding --tool web_search_search --args '{"query":"golang"}'`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := logger.Init(); err != nil {
+			return err
+		}
+		cfg, err := config.LoadConfigProfile(configPath, configProfile)
+		if err != nil {
+			return err
+		}
+
+		toolName, _ := cmd.Flags().GetString("tool")
+		toolArgs, _ := cmd.Flags().GetString("args")
+		chatName, _ := cmd.Flags().GetString("chat")
+		timeout, _ := cmd.Flags().GetInt("timeout")
+		if toolName == "" {
+			return fmt.Errorf("--tool is required")
+		}
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), time.Duration(timeout)*time.Second)
+		defer cancel()
+
+		cleanupRegistry := utils.NewCleanupRegistry()
+		defer cleanupRegistry.Execute()
+
+		// Determine which tools and MCP servers to search
+		toolNames := make([]string, 0, len(cfg.Tools))
+		serverNames := make([]string, 0, len(cfg.MCPServers))
+		if chatName != "" {
+			preset, ok := cfg.Chats[chatName]
+			if !ok {
+				return fmt.Errorf("chat preset does not exist: %s", chatName)
+			}
+			toolNames = append(toolNames, preset.Tools...)
+			serverNames = append(serverNames, preset.MCPServers...)
+		} else {
+			for name := range cfg.Tools {
+				toolNames = append(toolNames, name)
+			}
+			for name := range cfg.MCPServers {
+				serverNames = append(serverNames, name)
+			}
+		}
+
+		target, mcpClient, err := findTool(context.WithValue(ctx, "cleanup", cleanupRegistry), cfg, toolName, toolNames, serverNames)
+		if mcpClient != nil {
+			defer mcpClient.Close()
+		}
+		if err != nil {
+			return err
+		}
+
+		start := time.Now()
+		result, err := target.InvokableRun(ctx, toolArgs)
+		if err != nil {
+			return fmt.Errorf("tool %s failed after %v: %w", toolName, time.Since(start), err)
+		}
+		fmt.Println(result)
+		fmt.Fprintf(os.Stderr, "\n(tool %s completed in %v)\n", toolName, time.Since(start))
+		return nil
+	},
+}
+
+// findTool searches the configured built-in tools first, then MCP servers, for a tool
+// with the given name. Approval wrappers are removed since the invocation is explicit.
+func findTool(ctx context.Context, cfg *config.Config, toolName string, toolNames, serverNames []string) (tool.InvokableTool, *mcp.Client, error) {
+	for _, name := range toolNames {
+		toolCfg, ok := cfg.Tools[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("tool config %s not found", name)
+		}
+		builtinToolList, err := builtintools.GetBuiltinTools(ctx, toolCfg.Category, toolCfg.Params)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load tool config %s: %w", name, err)
+		}
+		if t := matchTool(ctx, builtinToolList, toolName); t != nil {
+			return t, nil, nil
+		}
+	}
+
+	if len(serverNames) == 0 {
+		return nil, nil, fmt.Errorf("tool not found: %s", toolName)
+	}
+
+	// Servers that fail to initialize are reported, the tools of the others are still searched
+	mcpClient := mcp.NewClient(cfg)
+	if err := mcpClient.InitializeForChat(ctx, config.Chat{MCPServers: serverNames}); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: some MCP servers failed to initialize: %v\n", err)
+	}
+	if t := matchTool(ctx, mcpClient.GetToolListForServers(serverNames), toolName); t != nil {
+		return t, mcpClient, nil
+	}
+	return nil, mcpClient, fmt.Errorf("tool not found: %s", toolName)
+}
+
+// matchTool returns the invokable tool with the given name from the list, if any
+func matchTool(ctx context.Context, tools []tool.BaseTool, toolName string) tool.InvokableTool {
+	for _, item := range tools {
+		info, err := item.Info(ctx)
+		if err != nil || info.Name != toolName {
+			continue
+		}
+		if approvable, ok := item.(mcp.InvokableApprovableTool); ok {
+			return approvable.InvokableTool
+		}
+		if invokable, ok := item.(tool.InvokableTool); ok {
+			return invokable
+		}
+	}
+	return nil
+}
+
+func init() {
+	toolTestCmd.Flags().StringP("tool", "t", "", "Name of the tool to invoke (MCP tools use serverName_toolName)")
+	toolTestCmd.Flags().StringP("args", "a", "{}", "Tool arguments in JSON")
+	toolTestCmd.Flags().StringP("chat", "c", "", "Only search the tools of this chat preset")
+	toolTestCmd.Flags().IntP("timeout", "", 60, "Timeout in seconds for loading and invoking the tool")
+
+	RootCmd.AddCommand(toolTestCmd)
+}
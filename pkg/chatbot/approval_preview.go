@@ -0,0 +1,161 @@
+package chatbot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// approvalPreviewLines caps the lines of content shown in an approval preview
+const approvalPreviewLines = 200
+
+// ApprovalFormatter renders the arguments of a tool call as a readable preview for its
+// approval. An error falls back to the JSON arguments.
+type ApprovalFormatter func(argumentsInJSON string) (string, error)
+
+var (
+	approvalFormattersMu sync.RWMutex
+	// approvalFormatters are keyed by tool name, the filesystem tools show the file
+	// content written or the diff of the edit
+	approvalFormatters = map[string]ApprovalFormatter{
+		"write_file":  formatWriteFile,
+		"edit_file":   formatEditFile,
+		"modify_file": formatModifyFile,
+	}
+)
+
+// RegisterApprovalFormatter sets the formatter of the approval previews of a tool
+func RegisterApprovalFormatter(toolName string, formatter ApprovalFormatter) {
+	approvalFormattersMu.Lock()
+	defer approvalFormattersMu.Unlock()
+	approvalFormatters[toolName] = formatter
+}
+
+// FormatApproval returns the preview of a tool call by the formatter of the tool, and false
+// if the tool has none or it failed
+func FormatApproval(toolName, argumentsInJSON string) (string, bool) {
+	approvalFormattersMu.RLock()
+	formatter, ok := approvalFormatters[toolName]
+	approvalFormattersMu.RUnlock()
+	if !ok {
+		return "", false
+	}
+	preview, err := formatter(argumentsInJSON)
+	if err != nil {
+		return "", false
+	}
+	return preview, true
+}
+
+// ApprovalPreview returns the preview of a tool call for its approval, the indented JSON
+// arguments when the tool has no formatter
+func ApprovalPreview(toolName, argumentsInJSON string) string {
+	if preview, ok := FormatApproval(toolName, argumentsInJSON); ok {
+		return preview
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, []byte(argumentsInJSON), "", "  "); err != nil {
+		return argumentsInJSON
+	}
+	return indented.String()
+}
+
+// formatWriteFile shows the content written
+func formatWriteFile(argumentsInJSON string) (string, error) {
+	var args struct {
+		Path    string `json:"path"`
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal([]byte(argumentsInJSON), &args); err != nil || args.Path == "" {
+		return "", fmt.Errorf("invalid write_file arguments")
+	}
+	lines := strings.Split(strings.TrimSuffix(args.Content, "\n"), "\n")
+	if args.Content == "" {
+		lines = nil
+	}
+	return fmt.Sprintf("Write %s (%d lines):\n%s", args.Path, len(lines), capLines(lines)), nil
+}
+
+// formatEditFile shows the diff of each edit of an edit_file call
+func formatEditFile(argumentsInJSON string) (string, error) {
+	var args struct {
+		Path  string `json:"path"`
+		Edits []struct {
+			OldText string `json:"oldText"`
+			NewText string `json:"newText"`
+		} `json:"edits"`
+	}
+	if err := json.Unmarshal([]byte(argumentsInJSON), &args); err != nil || args.Path == "" || len(args.Edits) == 0 {
+		return "", fmt.Errorf("invalid edit_file arguments")
+	}
+	parts := []string{fmt.Sprintf("Edit %s:", args.Path)}
+	for _, edit := range args.Edits {
+		diff, err := textDiff(args.Path, edit.OldText, edit.NewText)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, diff)
+	}
+	return strings.Join(parts, "\n"), nil
+}
+
+// formatModifyFile shows the diff of the replacement of a modify_file call
+func formatModifyFile(argumentsInJSON string) (string, error) {
+	var args struct {
+		Path           string `json:"path"`
+		Find           string `json:"find"`
+		Replace        string `json:"replace"`
+		AllOccurrences *bool  `json:"all_occurrences"`
+		Regex          bool   `json:"regex"`
+	}
+	if err := json.Unmarshal([]byte(argumentsInJSON), &args); err != nil || args.Path == "" || args.Find == "" {
+		return "", fmt.Errorf("invalid modify_file arguments")
+	}
+	occurrences := "all occurrences"
+	if args.AllOccurrences != nil && !*args.AllOccurrences {
+		occurrences = "the first occurrence"
+	}
+	pattern := "text"
+	if args.Regex {
+		pattern = "regular expression"
+	}
+	diff, err := textDiff(args.Path, args.Find, args.Replace)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Modify %s, replacing %s of the %s:\n%s", args.Path, occurrences, pattern, diff), nil
+}
+
+// textDiff returns the unified diff of a replaced text of a file
+func textDiff(path, oldText, newText string) (string, error) {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(ensureNewline(oldText)),
+		B:        difflib.SplitLines(ensureNewline(newText)),
+		FromFile: "a/" + strings.TrimPrefix(path, "/"),
+		ToFile:   "b/" + strings.TrimPrefix(path, "/"),
+		Context:  3,
+	})
+	if err != nil {
+		return "", err
+	}
+	return capLines(strings.Split(strings.TrimSuffix(diff, "\n"), "\n")), nil
+}
+
+func ensureNewline(s string) string {
+	if s == "" || strings.HasSuffix(s, "\n") {
+		return s
+	}
+	return s + "\n"
+}
+
+// capLines joins the lines, keeping at most approvalPreviewLines of them
+func capLines(lines []string) string {
+	if len(lines) <= approvalPreviewLines {
+		return strings.Join(lines, "\n")
+	}
+	return strings.Join(lines[:approvalPreviewLines], "\n") + fmt.Sprintf("\n... (%d more lines)", len(lines)-approvalPreviewLines)
+}
@@ -0,0 +1,53 @@
+package chatbot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApprovalPreview(t *testing.T) {
+	tests := []struct {
+		name string
+		tool string
+		args string
+		want []string
+	}{
+		{"write", "write_file", `{"path":"/src/a.go","content":"package a\n\nfunc A() {}\n"}`, []string{"Write /src/a.go (3 lines):\npackage a\n\nfunc A() {}"}},
+		{"edit", "edit_file", `{"path":"a.go","edits":[{"oldText":"one\ntwo\nthree","newText":"one\n2\nthree"}]}`, []string{"Edit a.go:", "--- a/a.go", "+++ b/a.go", " one\n-two\n+2\n three"}},
+		{"modify", "modify_file", `{"path":"a.go","find":"foo","replace":"bar","all_occurrences":false}`, []string{"Modify a.go, replacing the first occurrence of the text:", "-foo\n+bar"}},
+		{"default", "cmd", `{"command":"ls"}`, []string{"{\n  \"command\": \"ls\"\n}"}},
+		{"invalid", "write_file", `{"content":"x"}`, []string{"{\n  \"content\": \"x\"\n}"}},
+		{"not json", "cmd", `ls`, []string{"ls"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ApprovalPreview(tt.tool, tt.args)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("ApprovalPreview() = %q, want it to contain %q", got, want)
+				}
+			}
+		})
+	}
+
+	if _, ok := FormatApproval("cmd", `{}`); ok {
+		t.Error("FormatApproval() of a tool without formatter reported a preview")
+	}
+	RegisterApprovalFormatter("deploy", func(args string) (string, error) { return "Deploy to production", nil })
+	defer func() {
+		approvalFormattersMu.Lock()
+		delete(approvalFormatters, "deploy")
+		approvalFormattersMu.Unlock()
+	}()
+	if got := ApprovalPreview("deploy", `{}`); got != "Deploy to production" {
+		t.Errorf("ApprovalPreview() = %q, want the registered formatter's preview", got)
+	}
+}
+
+func TestApprovalPreviewCapsLines(t *testing.T) {
+	content := strings.Repeat("line\n", approvalPreviewLines+5)
+	got := ApprovalPreview("write_file", `{"path":"a.txt","content":"`+strings.ReplaceAll(content, "\n", `\n`)+`"}`)
+	if !strings.HasSuffix(got, "... (5 more lines)") {
+		t.Errorf("ApprovalPreview() ends with %q, want the count of the lines left out", got[len(got)-40:])
+	}
+}
@@ -0,0 +1,37 @@
+package chatbot
+
+import "sync"
+
+// approvalKey identifies a tool call by the tool name and its exact arguments
+type approvalKey struct {
+	toolName  string
+	arguments string
+}
+
+// ApprovalMemory remembers the tool calls the user approved for the rest of a session.
+// A call is only skipped when both the tool and its arguments match an approved call.
+// It is kept on the ChatSession rather than in the conversation context, so trimming
+// or compressing the context does not reset it.
+type ApprovalMemory struct {
+	mu    sync.RWMutex
+	calls map[approvalKey]bool
+}
+
+// NewApprovalMemory creates an empty approval memory
+func NewApprovalMemory() *ApprovalMemory {
+	return &ApprovalMemory{calls: make(map[approvalKey]bool)}
+}
+
+// Remember marks a tool call with the given arguments as approved for the rest of the session
+func (a *ApprovalMemory) Remember(toolName, arguments string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.calls[approvalKey{toolName: toolName, arguments: arguments}] = true
+}
+
+// IsApproved reports whether the tool call with the given arguments was approved for the rest of the session
+func (a *ApprovalMemory) IsApproved(toolName, arguments string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.calls[approvalKey{toolName: toolName, arguments: arguments}]
+}
@@ -0,0 +1,156 @@
+package chatbot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+	"github.com/Arvintian/chat-agent/pkg/mcp"
+)
+
+func TestApprovalMemoryMatchesExactCall(t *testing.T) {
+	memory := NewApprovalMemory()
+	memory.Remember("cmd", `{"command":"ls"}`)
+
+	if !memory.IsApproved("cmd", `{"command":"ls"}`) {
+		t.Error("IsApproved() = false for the remembered call")
+	}
+	if memory.IsApproved("cmd", `{"command":"rm -rf /"}`) {
+		t.Error("IsApproved() = true for the same tool with other arguments")
+	}
+	if memory.IsApproved("write_file", `{"command":"ls"}`) {
+		t.Error("IsApproved() = true for another tool with the same arguments")
+	}
+}
+
+func TestRememberApproval(t *testing.T) {
+	tests := []struct {
+		name   string
+		result *mcp.ApprovalResult
+		want   bool
+	}{
+		{"approved always", &mcp.ApprovalResult{Approved: true, Always: true}, true},
+		{"approved once", &mcp.ApprovalResult{Approved: true}, false},
+		{"denied with always", &mcp.ApprovalResult{Approved: false, Always: true}, false},
+		{"no result", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb := &ChatBot{}
+			cb.SetApprovalMemory(NewApprovalMemory())
+			cb.rememberApproval("cmd", `{"command":"ls"}`, tt.result)
+			if got := cb.isRemembered("cmd", `{"command":"ls"}`); got != tt.want {
+				t.Errorf("isRemembered() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRememberedWithoutMemory(t *testing.T) {
+	cb := &ChatBot{}
+	cb.rememberApproval("cmd", "{}", &mcp.ApprovalResult{Approved: true, Always: true})
+	if cb.isRemembered("cmd", "{}") {
+		t.Error("isRemembered() = true without an approval memory")
+	}
+}
+
+func TestReloadChatSessionKeepsApprovals(t *testing.T) {
+	cfg := &config.Config{
+		Providers: map[string]config.Provider{
+			"local": {Type: "openai", BaseURL: "http://127.0.0.1:1/v1", APIKey: "test"},
+		},
+		Models: map[string]config.Model{
+			"test": {ModelParams: config.ModelParams{Provider: "local", Model: "test"}},
+		},
+		Chats: map[string]config.Chat{
+			"default": {Model: "test", System: "You are a test assistant."},
+		},
+	}
+	ctx := context.Background()
+	old, err := InitChatSession(ctx, cfg, "default", "approvals", false)
+	if err != nil {
+		t.Fatalf("InitChatSession() error = %v", err)
+	}
+	old.Approvals.Remember("cmd", `{"command":"ls"}`)
+
+	session, err := ReloadChatSession(ctx, cfg, old, false)
+	if err != nil {
+		t.Fatalf("ReloadChatSession() error = %v", err)
+	}
+	defer session.Close()
+
+	if session.Approvals != old.Approvals {
+		t.Error("reloaded session has a new approval memory")
+	}
+	if !session.Approvals.IsApproved("cmd", `{"command":"ls"}`) {
+		t.Error("remembered approval was lost on reload")
+	}
+}
+
+func approvalTargets(n int) []ApprovalTarget {
+	targets := make([]ApprovalTarget, n)
+	for i := range targets {
+		targets[i] = ApprovalTarget{ID: fmt.Sprint(i), ToolName: "cmd"}
+	}
+	return targets
+}
+
+func TestRequestApprovalsInBatches(t *testing.T) {
+	handler := &recordingHandler{}
+	cb := &ChatBot{handler: handler}
+	cb.SetApprovalLimit(2, config.ApprovalOverflowBatch)
+
+	results, err := cb.requestApprovals(approvalTargets(5))
+	if err != nil {
+		t.Fatalf("requestApprovals() error = %v", err)
+	}
+	if handler.approvals != 3 {
+		t.Errorf("handler got %d approval requests, want 3", handler.approvals)
+	}
+	if len(results) != 5 {
+		t.Fatalf("got %d results, want 5", len(results))
+	}
+	for id, result := range results {
+		if !result.Approved {
+			t.Errorf("target %s was not approved", id)
+		}
+	}
+}
+
+func TestRequestApprovalsDenyOverflow(t *testing.T) {
+	handler := &recordingHandler{}
+	cb := &ChatBot{handler: handler}
+	cb.SetApprovalLimit(2, config.ApprovalOverflowDeny)
+
+	results, err := cb.requestApprovals(approvalTargets(5))
+	if err != nil {
+		t.Fatalf("requestApprovals() error = %v", err)
+	}
+	if handler.approvals != 1 {
+		t.Errorf("handler got %d approval requests, want 1", handler.approvals)
+	}
+	for _, id := range []string{"0", "1"} {
+		if !results[id].Approved {
+			t.Errorf("target %s within the cap was not approved", id)
+		}
+	}
+	for _, id := range []string{"2", "3", "4"} {
+		if results[id] == nil || results[id].Approved || results[id].DisapproveReason == nil {
+			t.Errorf("target %s beyond the cap = %+v, want denied with a reason", id, results[id])
+		}
+	}
+}
+
+func TestRequestApprovalsWithoutLimit(t *testing.T) {
+	handler := &recordingHandler{}
+	cb := &ChatBot{handler: handler}
+
+	results, err := cb.requestApprovals(approvalTargets(5))
+	if err != nil {
+		t.Fatalf("requestApprovals() error = %v", err)
+	}
+	if handler.approvals != 1 || len(results) != 5 {
+		t.Errorf("got %d requests and %d results, want 1 request for all 5 targets", handler.approvals, len(results))
+	}
+}
@@ -0,0 +1,146 @@
+package chatbot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/Arvintian/chat-agent/pkg/chatbot/middleware"
+	"github.com/Arvintian/chat-agent/pkg/logger"
+)
+
+const (
+	// DefaultAuditMaxSize is the size in MB of the audit log before it is rotated
+	DefaultAuditMaxSize = 100
+	// DefaultAuditMaxBackups is the number of rotated audit logs kept
+	DefaultAuditMaxBackups = 5
+)
+
+// AuditLog writes a record of every tool call, with its arguments, approval decision and
+// result summary, to a file. Each line is a JSON record. Arguments are not redacted, so
+// the file is only readable by its owner. The file is rotated when it exceeds maxSize:
+// path.1 is the most recent backup, at most maxBackups are kept.
+type AuditLog struct {
+	mu         sync.Mutex
+	path       string
+	file       *os.File
+	size       int64
+	maxSize    int64
+	maxBackups int
+}
+
+var (
+	auditLogMu sync.RWMutex
+	auditLog   *AuditLog
+)
+
+// OpenAuditLog opens the audit log file for appending, creating it if needed. maxSize is
+// in MB, 0 uses the defaults for maxSize and maxBackups.
+func OpenAuditLog(path string, maxSize, maxBackups int) (*AuditLog, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultAuditMaxSize
+	}
+	if maxBackups <= 0 {
+		maxBackups = DefaultAuditMaxBackups
+	}
+	l := &AuditLog{path: path, maxSize: int64(maxSize) << 20, maxBackups: maxBackups}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// SetAuditLog sets the audit log used by chat sessions; nil disables audit logging
+func SetAuditLog(log *AuditLog) {
+	auditLogMu.Lock()
+	defer auditLogMu.Unlock()
+	auditLog = log
+}
+
+// currentAuditLog returns the audit log used by chat sessions, or nil
+func currentAuditLog() *AuditLog {
+	auditLogMu.RLock()
+	defer auditLogMu.RUnlock()
+	return auditLog
+}
+
+// open opens the log file, restricting the permissions of an existing file
+func (l *AuditLog) open() error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	if err := file.Chmod(0600); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to set audit log permissions: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	l.file = file
+	l.size = info.Size()
+	return nil
+}
+
+// WriteAudit appends a record to the log, rotating the file first if the record would
+// exceed its maximum size
+func (l *AuditLog) WriteAudit(record middleware.AuditRecord) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		logger.Warn("chatbot", fmt.Sprintf("Failed to encode audit record: %v", err))
+		return
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return
+	}
+	if l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			logger.Warn("chatbot", fmt.Sprintf("Failed to rotate audit log: %v", err))
+			if l.file == nil {
+				return
+			}
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		logger.Warn("chatbot", fmt.Sprintf("Failed to write audit log: %v", err))
+	}
+}
+
+// rotate renames the log to path.1, shifting older backups and dropping the oldest,
+// and opens a new log
+func (l *AuditLog) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	l.file = nil
+	os.Remove(fmt.Sprintf("%s.%d", l.path, l.maxBackups))
+	for i := l.maxBackups - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", l.path, i), fmt.Sprintf("%s.%d", l.path, i+1))
+	}
+	if err := os.Rename(l.path, l.path+".1"); err != nil {
+		// Keep appending to the current file rather than losing records
+		logger.Warn("chatbot", fmt.Sprintf("Failed to rename audit log: %v", err))
+	}
+	return l.open()
+}
+
+// Close closes the audit log file
+func (l *AuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
@@ -0,0 +1,72 @@
+package chatbot
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Arvintian/chat-agent/pkg/chatbot/middleware"
+)
+
+func TestAuditLogWritesPrivateRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	auditLog, err := OpenAuditLog(path, 0, 0)
+	if err != nil {
+		t.Fatalf("OpenAuditLog() error = %v", err)
+	}
+	auditLog.WriteAudit(middleware.AuditRecord{Session: "s1", Chat: "coder", Tool: "cmd", Arguments: `{"command":"ls"}`, Approval: "approved", Result: "a.txt"})
+	if err := auditLog.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("audit log permissions = %o, want 600", perm)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record middleware.AuditRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("invalid audit log line %q: %v", data, err)
+	}
+	if record.Session != "s1" || record.Tool != "cmd" || record.Arguments != `{"command":"ls"}` || record.Approval != "approved" {
+		t.Errorf("record = %+v, want the cmd call", record)
+	}
+}
+
+func TestAuditLogRotatesBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	auditLog, err := OpenAuditLog(path, 1, 2)
+	if err != nil {
+		t.Fatalf("OpenAuditLog() error = %v", err)
+	}
+	defer auditLog.Close()
+	auditLog.maxSize = 200
+
+	for _, tool := range []string{"first", "second", "third", "fourth"} {
+		auditLog.WriteAudit(middleware.AuditRecord{Tool: tool, Result: strings.Repeat("x", 100)})
+	}
+
+	for name, want := range map[string]string{path: "fourth", path + ".1": "third", path + ".2": "second"} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lines := strings.Count(string(data), "\n"); lines != 1 || !strings.Contains(string(data), `"tool":"`+want+`"`) {
+			t.Errorf("%s = %s, want only the %s record", filepath.Base(name), data, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("%s.3 exists, want at most 2 backups", filepath.Base(path))
+	}
+}
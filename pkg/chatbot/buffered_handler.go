@@ -0,0 +1,124 @@
+package chatbot
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/Arvintian/chat-agent/pkg/mcp"
+)
+
+// bufferedApprovalDenied is the reason given to the model for tool calls that need
+// approval when the caller did not allow them
+const bufferedApprovalDenied = "tool calls that require approval were rejected by the request"
+
+// BufferedToolCall is a tool call made during a buffered turn
+type BufferedToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// BufferedResult is the outcome of a turn collected by a BufferedChatHandler
+type BufferedResult struct {
+	// Response is the text of the final response, after the last tool call
+	Response        string             `json:"response"`
+	ToolCalls       []BufferedToolCall `json:"tool_calls"`
+	Usage           TokenUsage         `json:"usage"`
+	Errors          []string           `json:"errors,omitempty"`
+	ContentFiltered bool               `json:"content_filtered,omitempty"`
+}
+
+// BufferedChatHandler collects the events of a turn instead of sending them, for callers
+// that want the whole response at once. Nobody can be asked during the turn: tool calls
+// that require approval are all approved or all denied, as chosen when the handler is
+// created, and clarifying questions get an empty answer.
+type BufferedChatHandler struct {
+	mu          sync.Mutex
+	autoApprove bool
+	response    strings.Builder
+	result      BufferedResult
+}
+
+// NewBufferedChatHandler creates a handler approving the tool calls that require approval
+// if autoApprove is set, denying them otherwise
+func NewBufferedChatHandler(autoApprove bool) *BufferedChatHandler {
+	return &BufferedChatHandler{autoApprove: autoApprove}
+}
+
+// Result returns what the turn produced so far
+func (h *BufferedChatHandler) Result() BufferedResult {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	result := h.result
+	result.Response = h.response.String()
+	result.ToolCalls = append([]BufferedToolCall{}, h.result.ToolCalls...)
+	result.Errors = append([]string(nil), h.result.Errors...)
+	return result
+}
+
+func (h *BufferedChatHandler) SendChunk(content string, first, last bool, contentType string) {
+	if contentType != "response" {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.response.WriteString(content)
+}
+
+// SendToolCall records completed tool calls, streaming updates are skipped
+func (h *BufferedChatHandler) SendToolCall(name string, arguments string, id string, streaming bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// Text before a tool call is not part of the final response
+	h.response.Reset()
+	if streaming {
+		return
+	}
+	h.result.ToolCalls = append(h.result.ToolCalls, BufferedToolCall{ID: id, Name: name, Arguments: arguments})
+}
+
+func (h *BufferedChatHandler) SendThinking(status bool) {}
+
+func (h *BufferedChatHandler) SendComplete(message string) {}
+
+func (h *BufferedChatHandler) SendError(err string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.result.Errors = append(h.result.Errors, err)
+}
+
+// SendContentFiltered records that the provider blocked the response
+func (h *BufferedChatHandler) SendContentFiltered(message string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.result.ContentFiltered = true
+}
+
+// SendUsage records the tokens used by the turn
+func (h *BufferedChatHandler) SendUsage(turn, session TokenUsage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.result.Usage = turn
+}
+
+// SendApprovalRequest approves or denies all targets, as chosen for the handler
+func (h *BufferedChatHandler) SendApprovalRequest(targets []ApprovalTarget) (ApprovalResultMap, error) {
+	results := make(ApprovalResultMap, len(targets))
+	for _, t := range targets {
+		if h.autoApprove {
+			results[t.ID] = &mcp.ApprovalResult{Approved: true}
+			continue
+		}
+		reason := bufferedApprovalDenied
+		results[t.ID] = &mcp.ApprovalResult{Approved: false, DisapproveReason: &reason}
+	}
+	return results, nil
+}
+
+// SendMessageCount is a no-op, the message count is not useful without a chat UI
+func (h *BufferedChatHandler) SendMessageCount() {}
+
+// SendQuestion answers the question with an empty answer
+func (h *BufferedChatHandler) SendQuestion(question string) (string, error) {
+	return "", nil
+}
@@ -0,0 +1,43 @@
+package chatbot
+
+import (
+	"testing"
+)
+
+func TestBufferedChatHandlerResult(t *testing.T) {
+	h := NewBufferedChatHandler(false)
+
+	h.SendChunk("Let me check.", true, false, "response")
+	h.SendToolCall("cmd", `{"comm`, "call-1", true)
+	h.SendToolCall("cmd", `{"command":"ls"}`, "call-1", false)
+	h.SendChunk("hmm", true, false, "thinking")
+	h.SendChunk("Two ", true, false, "response")
+	h.SendChunk("files.", false, true, "response")
+	h.SendUsage(TokenUsage{TotalTokens: 12}, TokenUsage{TotalTokens: 40})
+	results, err := h.SendApprovalRequest([]ApprovalTarget{{ID: "1", ToolName: "cmd"}})
+	if err != nil || results["1"] == nil || results["1"].Approved || results["1"].DisapproveReason == nil {
+		t.Errorf("SendApprovalRequest() = %+v, %v, want denied with a reason", results["1"], err)
+	}
+
+	result := h.Result()
+	if result.Response != "Two files." {
+		t.Errorf("Response = %q, want the text after the last tool call", result.Response)
+	}
+	if len(result.ToolCalls) != 1 || result.ToolCalls[0] != (BufferedToolCall{ID: "call-1", Name: "cmd", Arguments: `{"command":"ls"}`}) {
+		t.Errorf("ToolCalls = %+v, want the completed call", result.ToolCalls)
+	}
+	if result.Usage.TotalTokens != 12 {
+		t.Errorf("Usage = %+v, want the turn usage", result.Usage)
+	}
+}
+
+func TestBufferedChatHandlerAutoApprove(t *testing.T) {
+	h := NewBufferedChatHandler(true)
+	results, err := h.SendApprovalRequest([]ApprovalTarget{{ID: "1"}, {ID: "2"}})
+	if err != nil || !results["1"].Approved || !results["2"].Approved {
+		t.Errorf("SendApprovalRequest() = %+v, %v, want all approved", results, err)
+	}
+	if result := h.Result(); result.ToolCalls == nil {
+		t.Error("ToolCalls is nil, want an empty list")
+	}
+}
@@ -6,14 +6,21 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/Arvintian/chat-agent/pkg/chatbot/middleware"
+	"github.com/Arvintian/chat-agent/pkg/config"
+	chaterrors "github.com/Arvintian/chat-agent/pkg/errors"
 	"github.com/Arvintian/chat-agent/pkg/manager"
 	"github.com/Arvintian/chat-agent/pkg/mcp"
+	"github.com/Arvintian/chat-agent/pkg/metrics"
+	"github.com/Arvintian/chat-agent/pkg/providers"
 	"github.com/Arvintian/chat-agent/pkg/store"
+	builtintools "github.com/Arvintian/chat-agent/pkg/tools"
 	"github.com/Arvintian/readline"
 
 	"github.com/cloudwego/eino/adk"
@@ -27,6 +34,8 @@ type ApprovalTarget struct {
 	ID            string
 	ToolName      string
 	ArgumentsInfo string
+	// Preview is the readable preview of the call by the formatter of the tool, if any
+	Preview string
 }
 
 // ApprovalResultMap holds approval results for multiple targets
@@ -40,8 +49,8 @@ type Handler interface {
 	// contentType: "response" or "thinking"
 	SendChunk(content string, first, last bool, contentType string)
 
-	// SendToolCall sends a tool call notification with name, arguments, index and streaming status
-	// index: the tool call index
+	// SendToolCall sends a tool call notification with name, arguments, id and streaming status
+	// id: the tool call ID, the same value is used for streaming updates and the completion
 	// streaming: true if this is a streaming update (arguments may be partial), false when complete
 	SendToolCall(name string, arguments string, id string, streaming bool)
 
@@ -61,6 +70,41 @@ type Handler interface {
 
 	// SendMessageCount sends the current message count to the client
 	SendMessageCount()
+
+	// SendQuestion asks the user a clarifying question and waits for the free-text answer
+	SendQuestion(question string) (string, error)
+}
+
+// ContentFilterHandler is an optional interface for a Handler that reports responses
+// blocked by the provider's content filter separately from other errors. Handlers
+// without it get the block reported through SendError.
+type ContentFilterHandler interface {
+	SendContentFiltered(message string)
+}
+
+// ContextEventHandler is an optional interface for a Handler that reports the context
+// events of the manager, such as older turns being summarized.
+type ContextEventHandler interface {
+	SendContextEvent(event manager.Event)
+}
+
+// ToolProgressHandler is an optional interface for a Handler that shows the intermediate
+// output of running tool calls, such as the lines printed by a command before it exits.
+// Handlers without it only see the tool calls and their results.
+type ToolProgressHandler interface {
+	SendToolProgress(id string, progress string)
+}
+
+// ErrorCodeHandler is an optional interface for a Handler that sends the code of an
+// error alongside its message, see ErrorCode. Handlers without it only get the message.
+type ErrorCodeHandler interface {
+	SendErrorCode(message, code string)
+}
+
+// ModelFallbackHandler is an optional interface for a Handler that reports a model call
+// answered by a fallback model of the chat because the provider of the model failed.
+type ModelFallbackHandler interface {
+	SendModelFallback(from, to, reason string)
 }
 
 // ChatBot struct for the chatbot
@@ -80,6 +124,63 @@ type ChatBot struct {
 
 	// handler for output (CLI or WebSocket)
 	handler Handler
+
+	// approvals remembers the tool calls approved for the rest of the session
+	approvals *ApprovalMemory
+
+	// planGate holds the first tool calls of a turn until the user approves the plan
+	planGate *middleware.PlanGate
+
+	// maxApprovalTargets caps the tool calls in a single approval request, 0 means no cap
+	maxApprovalTargets int
+	// approvalOverflow is the policy for tool calls beyond maxApprovalTargets
+	approvalOverflow string
+
+	// showToolResults prints a truncated preview of tool results in the CLI
+	showToolResults bool
+
+	// markdownStream holds back the code fences of the response until they are complete
+	markdownStream bool
+
+	// stripReasoning leaves the reasoning out of the assistant messages added to the context
+	stripReasoning bool
+
+	// reasoningDisplay is how the reasoning is shown, one of the config.ReasoningDisplay modes
+	reasoningDisplay string
+
+	// requestTimeout bounds a turn run with a handler, 0 means no timeout
+	requestTimeout time.Duration
+
+	// maxChunkLength splits content chunks longer than this many characters, 0 means no limit
+	maxChunkLength int
+
+	// stopped records the last turn if it was stopped before it completed, so it can be resumed
+	stopped *stoppedTurn
+
+	// awaitingInterrupt is true while a turn waits for approvals or answers
+	awaitingInterrupt bool
+
+	// usage counts the tokens reported by the provider since the chatbot was created
+	usage TokenUsage
+
+	// responseHook may replace the final assistant message stored in the context
+	responseHook ResponseHook
+	// userMessage is the user message of the current turn, passed to the response hook
+	userMessage *schema.Message
+}
+
+// ResponseHook returns the message stored in the context in place of the final assistant
+// message of a turn, or the response itself to store it unchanged
+type ResponseHook func(ctx context.Context, userMessage, response *schema.Message) *schema.Message
+
+// ErrRequestTimeout is returned when a turn runs longer than the request timeout of the chat
+var ErrRequestTimeout = errors.New("request timed out")
+
+// stoppedTurn describes a turn that was stopped before it completed
+type stoppedTurn struct {
+	// interrupted is true if the turn was stopped while waiting for approvals or answers.
+	// Its state is kept in the checkpoint store and the interrupts are asked again on resume.
+	interrupted bool
 }
 
 func NewChatBot(ctx context.Context, agent *adk.ChatModelAgent, manager *manager.Manager, scanner *readline.Instance, persistence *store.PersistenceStore) ChatBot {
@@ -106,16 +207,174 @@ func NewChatBot(ctx context.Context, agent *adk.ChatModelAgent, manager *manager
 // SetHandler sets the output handler for the chatbot
 func (cb *ChatBot) SetHandler(handler Handler) {
 	cb.handler = handler
+	if cb.manager == nil {
+		return
+	}
+	if eventHandler, ok := handler.(ContextEventHandler); ok {
+		cb.manager.SetEventHook(eventHandler.SendContextEvent)
+	} else {
+		cb.manager.SetEventHook(nil)
+	}
+}
+
+// SetApprovalMemory sets the session's approval memory, so tools approved for the
+// rest of the session are not asked for again
+func (cb *ChatBot) SetApprovalMemory(approvals *ApprovalMemory) {
+	cb.approvals = approvals
+}
+
+// SetPlanGate enables plan mode for the turns run with a handler: the tool calls of a
+// turn wait for the user to approve the plan. nil disables it.
+func (cb *ChatBot) SetPlanGate(gate *middleware.PlanGate) {
+	cb.planGate = gate
+}
+
+// SetApprovalLimit caps the tool calls sent to the handler in a single approval request.
+// Tool calls beyond the cap are asked in further requests, or denied when overflow is
+// config.ApprovalOverflowDeny.
+func (cb *ChatBot) SetApprovalLimit(maxTargets int, overflow string) {
+	cb.maxApprovalTargets = maxTargets
+	cb.approvalOverflow = overflow
+}
+
+// requestApprovals sends the targets to the handler, at most maxApprovalTargets per request
+func (cb *ChatBot) requestApprovals(targets []ApprovalTarget) (ApprovalResultMap, error) {
+	limit := cb.maxApprovalTargets
+	if limit <= 0 || len(targets) <= limit {
+		return cb.handler.SendApprovalRequest(targets)
+	}
+
+	results := make(ApprovalResultMap, len(targets))
+	if cb.approvalOverflow == config.ApprovalOverflowDeny {
+		reason := fmt.Sprintf("denied automatically, at most %d tool calls can be approved at once", limit)
+		for _, target := range targets[limit:] {
+			results[target.ID] = &mcp.ApprovalResult{Approved: false, DisapproveReason: &reason}
+		}
+		targets = targets[:limit]
+	}
+	for start := 0; start < len(targets); start += limit {
+		batch, err := cb.handler.SendApprovalRequest(targets[start:min(start+limit, len(targets))])
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(results, batch)
+	}
+	return results, nil
+}
+
+// isRemembered reports whether the tool call was approved for the rest of the session
+func (cb *ChatBot) isRemembered(toolName, arguments string) bool {
+	return cb.approvals != nil && cb.approvals.IsApproved(toolName, arguments)
 }
 
-// StreamChat performs streaming chat conversation with CLI output
-func (cb *ChatBot) StreamChat(ctx context.Context, userInput string) error {
+// rememberApproval records an approval the user asked to keep for the rest of the session
+func (cb *ChatBot) rememberApproval(toolName, arguments string, result *mcp.ApprovalResult) {
+	if cb.approvals != nil && result != nil && result.Approved && result.Always {
+		cb.approvals.Remember(toolName, arguments)
+	}
+}
+
+// SetShowToolResults enables printing a preview of tool results in StreamChat
+func (cb *ChatBot) SetShowToolResults(show bool) {
+	cb.showToolResults = show
+}
+
+// SetMarkdownStream makes StreamChat print the code fences of the response whole, for
+// terminals rendering markdown, at the cost of printing a fence only once it is closed
+func (cb *ChatBot) SetMarkdownStream(markdown bool) {
+	cb.markdownStream = markdown
+}
+
+// SetRequestTimeout bounds the time of a turn run with a handler, 0 means no timeout
+func (cb *ChatBot) SetRequestTimeout(timeout time.Duration) {
+	cb.requestTimeout = timeout
+}
+
+// withRequestTimeout returns the context of a turn, bounded by the request timeout
+func (cb *ChatBot) withRequestTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if cb.requestTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, cb.requestTimeout)
+}
+
+// SetMaxChunkLength splits content chunks sent to the handler that are longer than max
+// characters, for providers that stream whole paragraphs at once. 0 means no limit.
+func (cb *ChatBot) SetMaxChunkLength(max int) {
+	cb.maxChunkLength = max
+}
+
+// sendChunk sends a content chunk to the handler, split into parts of at most maxChunkLength
+func (cb *ChatBot) sendChunk(content string, first bool, contentType string) {
+	for i, part := range SplitChunk(content, cb.maxChunkLength) {
+		cb.handler.SendChunk(part, first && i == 0, false, contentType)
+	}
+}
+
+// SetResponseHook sets the hook applied to the final assistant message of each turn before
+// it is added to the context. The response streamed to the user is not changed.
+func (cb *ChatBot) SetResponseHook(hook ResponseHook) {
+	cb.responseHook = hook
+}
+
+// addResponse adds the final assistant message of a turn to the context, through the
+// response hook if any
+func (cb *ChatBot) addResponse(ctx context.Context, response *schema.Message) {
+	if cb.responseHook != nil {
+		response = cb.responseHook(ctx, cb.userMessage, response)
+	}
+	cb.manager.AddMessage(ctx, response)
+}
+
+// SetStoreReasoning sets whether the reasoning of the model is kept in the context.
+// Without it only the final content is sent again on the next turns.
+func (cb *ChatBot) SetStoreReasoning(store bool) {
+	cb.stripReasoning = !store
+}
+
+// SetReasoningDisplay sets how the reasoning of the model is shown: config.ReasoningDisplayShow
+// (default) streams it, config.ReasoningDisplaySummary only shows that the model is
+// thinking and config.ReasoningDisplayHide shows nothing. The reasoning is still stored.
+func (cb *ChatBot) SetReasoningDisplay(mode string) {
+	cb.reasoningDisplay = mode
+}
+
+// showsReasoning reports whether the reasoning itself is shown
+func (cb *ChatBot) showsReasoning() bool {
+	return cb.reasoningDisplay == "" || cb.reasoningDisplay == config.ReasoningDisplayShow
+}
+
+// storedReasoning returns the reasoning to keep in the context
+func (cb *ChatBot) storedReasoning(reasoning string) string {
+	if cb.stripReasoning {
+		return ""
+	}
+	return reasoning
+}
+
+// AddHandler registers an additional output handler. The first handler keeps answering
+// approval requests; all handlers receive the streamed output.
+func (cb *ChatBot) AddHandler(handler Handler) {
+	switch current := cb.handler.(type) {
+	case nil:
+		cb.handler = handler
+	case *MultiHandler:
+		current.Add(handler)
+	default:
+		cb.handler = NewMultiHandler(current, handler)
+	}
+}
+
+// StreamChat performs streaming chat conversation with CLI output. The files, if any, are
+// attached to the user message.
+func (cb *ChatBot) StreamChat(ctx context.Context, userInput string, files []FileData) error {
 	// Get context messages
 	messages := cb.manager.GetMessages()
 
 	cb.manager.IncRound()
 
-	userMessage := schema.UserMessage(userInput)
+	userMessage := createMultimodalUserMessage(userInput, files)
+	cb.userMessage = userMessage
 
 	// Add user message to context
 	cb.manager.AddMessage(ctx, userMessage)
@@ -123,12 +382,14 @@ func (cb *ChatBot) StreamChat(ctx context.Context, userInput string) error {
 	messages = append(messages, userMessage)
 
 	// Generate streaming response
+	ctx = providers.WithFallbackReporter(ctx, cb)
 	streamReader := cb.runner.Run(ctx, messages, adk.WithCheckPointID("local"))
 
 	response, reasoningContent, debug := strings.Builder{}, strings.Builder{}, false
 	if v, ok := cb.ctx.Value("debug").(bool); ok {
 		debug = v
 	}
+	turnUsage := TokenUsage{}
 
 	for {
 		event, ok := streamReader.Next()
@@ -143,13 +404,33 @@ func (cb *ChatBot) StreamChat(ctx context.Context, userInput string) error {
 			var err error
 			targets := map[string]any{}
 			for _, intCtx := range event.Action.Interrupted.InterruptContexts {
+				if questionInfo, ok := intCtx.Info.(*builtintools.QuestionInfo); ok {
+					answer, err := cb.readAnswer(questionInfo)
+					if err != nil {
+						return err
+					}
+					targets[intCtx.ID] = &builtintools.QuestionAnswer{Answer: answer}
+					continue
+				}
 				approvalInfo, ok := intCtx.Info.(*mcp.ApprovalInfo)
 				if !ok {
 					continue
 				}
+				if cb.isRemembered(approvalInfo.ToolName, approvalInfo.ArgumentsInJSON) {
+					targets[intCtx.ID] = &mcp.ApprovalResult{Approved: true}
+					continue
+				}
+				if cb.scanner == nil {
+					// Piped input, there is no terminal to ask
+					fmt.Fprintf(os.Stderr, "Denied %s, approvals need an interactive terminal\n", approvalInfo.ToolName)
+					reason := noTerminalApprovalDenied
+					targets[intCtx.ID] = &mcp.ApprovalResult{Approved: false, DisapproveReason: &reason}
+					continue
+				}
 				var apResult *mcp.ApprovalResult
 				cb.scanner.Prompt.Placeholder = "Y/N"
 				cb.scanner.HistoryDisable()
+				fmt.Println(ApprovalPreview(approvalInfo.ToolName, approvalInfo.ArgumentsInJSON))
 				for {
 					fmt.Printf("%s\n", approvalInfo.String())
 					line, err := cb.scanner.Readline()
@@ -170,9 +451,13 @@ func (cb *ChatBot) StreamChat(ctx context.Context, userInput string) error {
 					} else if strings.ToUpper(input) == "N" {
 						apResult = &mcp.ApprovalResult{Approved: false}
 						break
+					} else if strings.ToUpper(input) == "A" {
+						apResult = &mcp.ApprovalResult{Approved: true, Always: true}
+						break
 					}
-					fmt.Println("Invalid input, please input Y or N")
+					fmt.Println("Invalid input, please input Y, N or A")
 				}
+				cb.rememberApproval(approvalInfo.ToolName, approvalInfo.ArgumentsInJSON, apResult)
 				targets[intCtx.ID] = apResult
 			}
 			if len(targets) < 1 {
@@ -195,6 +480,11 @@ func (cb *ChatBot) StreamChat(ctx context.Context, userInput string) error {
 			cb.manager.AddMessage(ctx, event.Output.MessageOutput.Message)
 			fmt.Printf("ToolCall: (%s) Completed", event.Output.MessageOutput.ToolName)
 			if !debug {
+				if cb.showToolResults && event.Output.MessageOutput.Message != nil {
+					if preview := TruncateToolResult(event.Output.MessageOutput.Message.Content, toolResultPreviewLimit); preview != "" {
+						fmt.Printf("\n%s", preview)
+					}
+				}
 				fmt.Print("\n---\n")
 				continue
 			} else {
@@ -210,7 +500,11 @@ func (cb *ChatBot) StreamChat(ctx context.Context, userInput string) error {
 			// Use separate filters for thinking and response to avoid output interleaving
 			thinkingFilter := NewStreamFilter()
 			responseFilter := NewStreamFilter()
+			if cb.markdownStream {
+				responseFilter = NewMarkdownStreamFilter()
+			}
 			finalToolMap, toolStart, toolOutput, toolMu := map[int][]*schema.Message{}, false, strings.Builder{}, sync.Mutex{}
+			usage := responseUsage{}
 			for {
 				message, err := event.Output.MessageOutput.MessageStream.Recv()
 				if err == io.EOF {
@@ -219,6 +513,7 @@ func (cb *ChatBot) StreamChat(ctx context.Context, userInput string) error {
 				if err != nil {
 					return fmt.Errorf("error receiving message stream: %w", err)
 				}
+				usage.observe(message)
 				if len(message.ToolCalls) > 0 {
 					if !toolStart {
 						fmt.Print("\n")
@@ -292,18 +587,25 @@ func (cb *ChatBot) StreamChat(ctx context.Context, userInput string) error {
 							// Strip leading whitespace from the first meaningful thinking chunk
 							decodedReasoning = TrimLeadingWhitespace(decodedReasoning)
 							if decodedReasoning != "" {
-								fmt.Print("Thinking:\n")
+								switch {
+								case cb.showsReasoning():
+									fmt.Print("Thinking:\n")
+								case cb.reasoningDisplay == config.ReasoningDisplaySummary:
+									fmt.Print("Thinking…\n")
+								}
 							}
 						}
-						if out := thinkingFilter.Process(decodedReasoning); out != nil {
-							fmt.Print(*out)
+						if cb.showsReasoning() {
+							if out := thinkingFilter.Process(decodedReasoning); out != nil {
+								fmt.Print(*out)
+							}
 						}
 						reasoningContent.WriteString(decodedReasoning)
 					}
 				}
 				if message.Content != "" && reasoning && !firstword {
 					// Transition from thinking to response: flush thinking filter first, then separator
-					if reasoningContent.Len() > 0 {
+					if reasoningContent.Len() > 0 && cb.showsReasoning() {
 						if out := thinkingFilter.Finish(); out != nil {
 							fmt.Print(*out)
 						}
@@ -326,6 +628,7 @@ func (cb *ChatBot) StreamChat(ctx context.Context, userInput string) error {
 					}
 				}
 			}
+			usage.addTo(&turnUsage)
 			// Flush remaining buffers at end
 			if out := thinkingFilter.Finish(); out != nil {
 				fmt.Print(*out)
@@ -374,6 +677,9 @@ func (cb *ChatBot) StreamChat(ctx context.Context, userInput string) error {
 			fmt.Print(event.Output.MessageOutput.Message.Content)
 			response.WriteString(event.Output.MessageOutput.Message.Content)
 			reasoningContent.WriteString(event.Output.MessageOutput.Message.ReasoningContent)
+			usage := responseUsage{}
+			usage.observe(event.Output.MessageOutput.Message)
+			usage.addTo(&turnUsage)
 		}
 		if event.Output.MessageOutput.Role == schema.Tool {
 			fmt.Print("\n---\n")
@@ -383,7 +689,7 @@ func (cb *ChatBot) StreamChat(ctx context.Context, userInput string) error {
 				Role:             schema.Assistant,
 				ToolCalls:        make([]schema.ToolCall, len(toolMap)),
 				Content:          response.String(),
-				ReasoningContent: reasoningContent.String(),
+				ReasoningContent: cb.storedReasoning(reasoningContent.String()),
 			}
 			for index, msgs := range toolMap {
 				m, err := schema.ConcatMessages(msgs)
@@ -397,16 +703,48 @@ func (cb *ChatBot) StreamChat(ctx context.Context, userInput string) error {
 	}
 
 	fmt.Print("\n")
-	cb.manager.AddMessage(ctx, &schema.Message{
+	cb.addResponse(ctx, &schema.Message{
 		Role:             schema.Assistant,
 		Content:          response.String(),
-		ReasoningContent: reasoningContent.String(),
+		ReasoningContent: cb.storedReasoning(reasoningContent.String()),
 	})
+	if !turnUsage.IsZero() {
+		cb.finishUsage(turnUsage)
+		fmt.Printf("Tokens: %s (session total: %d)\n", turnUsage, cb.usage.TotalTokens)
+	}
 
 	return nil
 }
 
-// StreamChatWithHandler performs streaming chat with a custom handler
+// noTerminalApprovalDenied is the reason given to the model for tool calls that need
+// approval when the CLI runs without a terminal
+const noTerminalApprovalDenied = "tool calls that require approval are not available without an interactive terminal"
+
+// readAnswer prompts the user for a free-text answer to a clarifying question
+func (cb *ChatBot) readAnswer(questionInfo *builtintools.QuestionInfo) (string, error) {
+	if cb.scanner == nil {
+		fmt.Fprintf(os.Stderr, "%s\n(no interactive terminal, answered with an empty answer)\n", questionInfo.String())
+		return "", nil
+	}
+	cb.scanner.Prompt.Placeholder = "Your answer"
+	cb.scanner.HistoryDisable()
+	fmt.Printf("%s\n", questionInfo.String())
+	line, err := cb.scanner.Readline()
+	switch {
+	case errors.Is(err, io.EOF):
+		return "", fmt.Errorf("wait answer error")
+	case errors.Is(err, readline.ErrInterrupt):
+		return "", fmt.Errorf("wait answer error")
+	case err != nil:
+		return "", err
+	}
+	cb.scanner.History.Buf.Remove(cb.scanner.History.Size() - 1)
+	cb.scanner.History.Pos = cb.scanner.History.Size()
+	return strings.TrimSpace(line), nil
+}
+
+// StreamChatWithHandler performs streaming chat with a custom handler. Like StreamChat,
+// the files, if any, are attached to the user message.
 func (cb *ChatBot) StreamChatWithHandler(ctx context.Context, userInput string, files []FileData) error {
 	if cb.handler == nil {
 		return fmt.Errorf("handler not set")
@@ -417,34 +755,215 @@ func (cb *ChatBot) StreamChatWithHandler(ctx context.Context, userInput string,
 
 	cb.manager.IncRound()
 
-	var userMessage *schema.Message
-
 	// Add user message to context (with files if present)
-	if len(files) > 0 {
-		// Create multimodal message with text and files
-		userMessage = createMultimodalUserMessage(userInput, files)
-	} else {
-		userMessage = schema.UserMessage(userInput)
-	}
+	userMessage := createMultimodalUserMessage(userInput, files)
+	cb.userMessage = userMessage
 
 	cb.manager.AddMessage(ctx, userMessage)
 
 	// Send message count update after adding user message
 	cb.handler.SendMessageCount()
 
-	messages = append(messages, userMessage)
+	return cb.runWithHandler(ctx, append(messages, userMessage))
+}
+
+// RerunWithHandler answers the user message ending the context again, without adding a
+// message, e.g. after it was edited
+func (cb *ChatBot) RerunWithHandler(ctx context.Context, userMessage *schema.Message) error {
+	if cb.handler == nil {
+		return fmt.Errorf("handler not set")
+	}
+	cb.userMessage = userMessage
+	cb.handler.SendMessageCount()
+	return cb.runWithHandler(ctx, cb.manager.GetMessages())
+}
+
+// runWithHandler runs a turn of the agent on the messages, streaming it to the handler
+func (cb *ChatBot) runWithHandler(ctx context.Context, messages []*schema.Message) error {
+	ctx, cancel := cb.withRequestTimeout(ctx)
+	defer cancel()
+	ctx = builtintools.WithProgressReporter(ctx, cb)
+	ctx = providers.WithFallbackReporter(ctx, cb)
+	if cb.planGate != nil {
+		cb.planGate.Arm()
+	}
 
 	// Generate streaming response
+	start := time.Now()
 	streamReader := cb.runner.Run(ctx, messages, adk.WithCheckPointID("web"))
+	err := cb.streamTurn(ctx, streamReader)
+	metrics.ObserveChatRequest(turnResult(ctx, err), time.Since(start))
+	return err
+}
+
+// turnResult classifies how a turn ended for the metrics
+func turnResult(ctx context.Context, err error) string {
+	switch {
+	case errors.Is(err, ErrRequestTimeout):
+		return metrics.ResultTimeout
+	case ctx.Err() != nil:
+		return metrics.ResultStopped
+	case err != nil:
+		return metrics.ResultError
+	}
+	return metrics.ResultOK
+}
+
+// CanResume reports whether the last turn was stopped and can be resumed
+func (cb *ChatBot) CanResume() bool {
+	return cb.stopped != nil
+}
+
+// DiscardStopped forgets the stopped turn, e.g. after the context was cleared
+func (cb *ChatBot) DiscardStopped() {
+	cb.stopped = nil
+}
+
+// ResumeWithHandler continues the last turn after it was stopped. A turn stopped while
+// waiting for approvals or answers is resumed from its checkpoint, which asks for them
+// again; otherwise the agent is re-run on the context collected so far, without adding
+// a new user message.
+func (cb *ChatBot) ResumeWithHandler(ctx context.Context) error {
+	if cb.handler == nil {
+		return fmt.Errorf("handler not set")
+	}
+	if cb.stopped == nil {
+		return fmt.Errorf("no stopped response to resume")
+	}
+
+	ctx, cancel := cb.withRequestTimeout(ctx)
+	defer cancel()
+	ctx = builtintools.WithProgressReporter(ctx, cb)
+	ctx = providers.WithFallbackReporter(ctx, cb)
+
+	var streamReader *adk.AsyncIterator[*adk.AgentEvent]
+	if cb.stopped.interrupted {
+		var err error
+		streamReader, err = cb.runner.Resume(ctx, "web")
+		if err != nil {
+			return fmt.Errorf("failed to resume from checkpoint: %w", err)
+		}
+	} else {
+		streamReader = cb.runner.Run(ctx, cb.manager.GetMessages(), adk.WithCheckPointID("web"))
+	}
+	cb.handler.SendThinking(true)
+	start := time.Now()
+	err := cb.streamTurn(ctx, streamReader)
+	metrics.ObserveChatRequest(turnResult(ctx, err), time.Since(start))
+	return err
+}
+
+// streamTurn streams the events of a turn to the handler and records whether the
+// turn was stopped before it completed
+func (cb *ChatBot) streamTurn(ctx context.Context, streamReader *adk.AsyncIterator[*adk.AgentEvent]) error {
+	cb.stopped = nil
+	cb.awaitingInterrupt = false
+	err := cb.handleStream(ctx, streamReader)
+	// A timed out turn ended with its partial response, it was not stopped by the user
+	if ctx.Err() != nil && !errors.Is(err, ErrRequestTimeout) {
+		cb.stopped = &stoppedTurn{interrupted: cb.awaitingInterrupt}
+	}
+	cb.awaitingInterrupt = false
+	return err
+}
+
+// ReportToolProgress forwards the intermediate output of a running tool call to the
+// handler, if it shows progress. It implements builtintools.ProgressReporter.
+func (cb *ChatBot) ReportToolProgress(toolCallID, progress string) {
+	if handler, ok := cb.handler.(ToolProgressHandler); ok {
+		handler.SendToolProgress(toolCallID, progress)
+	}
+}
+
+// ReportModelFallback tells the handler that a model call falls back to the next model
+// of the chat, or prints it in the terminal chat. It implements providers.FallbackReporter.
+func (cb *ChatBot) ReportModelFallback(from, to string, err error) {
+	if cb.handler == nil {
+		fmt.Fprintf(os.Stderr, "\nModel %s failed, falling back to %s: %v\n", from, to, err)
+		return
+	}
+	if handler, ok := cb.handler.(ModelFallbackHandler); ok {
+		handler.SendModelFallback(from, to, err.Error())
+	}
+}
+
+// sendError reports an error of the turn to the handler, with its code when the handler
+// takes one
+func (cb *ChatBot) sendError(err error) {
+	if errors.Is(err, providers.ErrContentFiltered) {
+		if handler, ok := cb.handler.(ContentFilterHandler); ok {
+			handler.SendContentFiltered(providers.ErrContentFiltered.Error())
+			return
+		}
+	}
+	if handler, ok := cb.handler.(ErrorCodeHandler); ok {
+		handler.SendErrorCode(err.Error(), ErrorCode(err))
+		return
+	}
+	cb.handler.SendError(err.Error())
+}
+
+// Error codes of the turn errors without a class in pkg/errors
+const (
+	CodeContentFiltered = "content_filtered"
+	CodeRequestTimeout  = "request_timeout"
+)
+
+// ErrorCode returns the machine-readable code of an error of a turn: the code of its
+// class in pkg/errors, or of the errors of this package and the providers
+func ErrorCode(err error) string {
+	switch {
+	case errors.Is(err, providers.ErrContentFiltered):
+		return CodeContentFiltered
+	case errors.Is(err, ErrRequestTimeout):
+		return CodeRequestTimeout
+	default:
+		return chaterrors.Code(err)
+	}
+}
+
+// timedOut reports whether the turn was stopped by the request timeout
+func timedOut(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
+
+// finishTimedOut ends a turn stopped by the request timeout. The partial response that
+// was not recorded yet is added to the context, so the next turn can build on it.
+func (cb *ChatBot) finishTimedOut(ctx context.Context, response, reasoning string) error {
+	cb.handler.SendComplete(fmt.Sprintf("Request timed out after %v, the response may be incomplete", cb.requestTimeout))
+	if response != "" || reasoning != "" {
+		cb.addResponse(ctx, &schema.Message{
+			Role:             schema.Assistant,
+			Content:          response,
+			ReasoningContent: cb.storedReasoning(reasoning),
+		})
+		cb.handler.SendMessageCount()
+	}
+	return fmt.Errorf("%w after %v", ErrRequestTimeout, cb.requestTimeout)
+}
 
+// handleStream forwards the agent events to the handler and records the messages in the context
+func (cb *ChatBot) handleStream(ctx context.Context, streamReader *adk.AsyncIterator[*adk.AgentEvent]) error {
 	response := strings.Builder{}
 	reasoningContent := strings.Builder{}
 	firstChunk := true
+	// recorded is true once the current response was added to the context with its tool calls
+	recorded := false
+	// Tokens are reported however the turn ends, they were used either way
+	turnUsage := TokenUsage{}
+	defer func() { cb.finishUsage(turnUsage) }()
 
 	for {
 		// Check for context cancellation
 		select {
 		case <-ctx.Done():
+			if timedOut(ctx) {
+				if recorded {
+					response.Reset()
+					reasoningContent.Reset()
+				}
+				return cb.finishTimedOut(ctx, response.String(), reasoningContent.String())
+			}
 			cb.handler.SendComplete("")
 			return ctx.Err()
 		default:
@@ -455,45 +974,98 @@ func (cb *ChatBot) StreamChatWithHandler(ctx context.Context, userInput string,
 			break
 		}
 		if event.Err != nil {
-			cb.handler.SendError(event.Err.Error())
+			if timedOut(ctx) {
+				if recorded {
+					response.Reset()
+					reasoningContent.Reset()
+				}
+				return cb.finishTimedOut(ctx, response.String(), reasoningContent.String())
+			}
+			if ctx.Err() == nil {
+				metrics.ObserveModelError()
+			}
+			cb.sendError(event.Err)
 			return event.Err
 		}
 
 		if event.Action != nil && event.Action.Interrupted != nil {
 			// Handle interruption (approval requests) via handler
 			cb.handler.SendThinking(false)
+			cb.awaitingInterrupt = true
 
-			// Collect all approval targets from interrupt contexts
+			// Ask clarifying questions first, then collect all approval targets from interrupt contexts
+			targets := make(map[string]any, len(event.Action.Interrupted.InterruptContexts))
 			approvalTargets := make([]ApprovalTarget, 0, len(event.Action.Interrupted.InterruptContexts))
+			var plannedCalls []plannedCall
 			for _, intCtx := range event.Action.Interrupted.InterruptContexts {
+				if planInfo, ok := intCtx.Info.(*middleware.PlanInfo); ok {
+					plannedCalls = append(plannedCalls, plannedCall{id: intCtx.ID, info: planInfo})
+					continue
+				}
+				if questionInfo, ok := intCtx.Info.(*builtintools.QuestionInfo); ok {
+					answer, err := cb.handler.SendQuestion(questionInfo.Question)
+					if err != nil {
+						cb.sendError(err)
+						return err
+					}
+					targets[intCtx.ID] = &builtintools.QuestionAnswer{Answer: answer}
+					continue
+				}
 				approvalInfo, ok := intCtx.Info.(*mcp.ApprovalInfo)
 				if !ok {
 					continue
 				}
+				if cb.isRemembered(approvalInfo.ToolName, approvalInfo.ArgumentsInJSON) {
+					targets[intCtx.ID] = &mcp.ApprovalResult{Approved: true}
+					continue
+				}
+				preview, _ := FormatApproval(approvalInfo.ToolName, approvalInfo.ArgumentsInJSON)
 				approvalTargets = append(approvalTargets, ApprovalTarget{
 					ID:            intCtx.ID,
 					ToolName:      approvalInfo.ToolName,
 					ArgumentsInfo: approvalInfo.ArgumentsInJSON,
+					Preview:       preview,
 				})
 			}
 
-			if len(approvalTargets) < 1 {
-				err := fmt.Errorf("wait approval error")
-				cb.handler.SendError(err.Error())
-				return err
+			if len(plannedCalls) > 0 {
+				result, err := cb.requestPlanApproval(response.String(), plannedCalls)
+				if err != nil {
+					cb.sendError(err)
+					return err
+				}
+				if !result.Approved {
+					return cb.rejectPlan(ctx, plannedCalls, result)
+				}
+				if cb.planGate != nil {
+					cb.planGate.Approve()
+				}
+				for _, call := range plannedCalls {
+					targets[call.id] = result
+				}
 			}
 
-			// Send approval request to handler and wait for result
-			approvalResultMap, err := cb.handler.SendApprovalRequest(approvalTargets)
-			if err != nil {
-				cb.handler.SendError(err.Error())
-				return err
+			if len(approvalTargets) > 0 {
+				// Send approval request to handler and wait for result
+				approvalResultMap, err := cb.requestApprovals(approvalTargets)
+				if err != nil {
+					cb.sendError(err)
+					return err
+				}
+
+				// Add approval results to targets map for resume
+				for _, target := range approvalTargets {
+					cb.rememberApproval(target.ToolName, target.ArgumentsInfo, approvalResultMap[target.ID])
+				}
+				for id, result := range approvalResultMap {
+					targets[id] = result
+				}
 			}
 
-			// Convert approval results to targets map for resume
-			targets := make(map[string]any, len(approvalResultMap))
-			for id, result := range approvalResultMap {
-				targets[id] = result
+			if len(targets) < 1 {
+				err := fmt.Errorf("wait approval error")
+				cb.sendError(err)
+				return err
 			}
 
 			var resumeErr error
@@ -501,9 +1073,10 @@ func (cb *ChatBot) StreamChatWithHandler(ctx context.Context, userInput string,
 				Targets: targets,
 			})
 			if resumeErr != nil {
-				cb.handler.SendError(resumeErr.Error())
+				cb.sendError(resumeErr)
 				return resumeErr
 			}
+			cb.awaitingInterrupt = false
 			cb.handler.SendThinking(true)
 			continue
 		}
@@ -513,10 +1086,11 @@ func (cb *ChatBot) StreamChatWithHandler(ctx context.Context, userInput string,
 		}
 
 		if event.Output.MessageOutput.Role == schema.Tool {
+			metrics.ObserveToolCall(event.Output.MessageOutput.ToolName)
 			cb.manager.AddMessage(ctx, event.Output.MessageOutput.Message)
 			// Send message count update
 			cb.handler.SendMessageCount()
-			// Send completion signal for tool call using ToolCallID to find the correct index
+			// Send completion signal for tool call, correlated with the streaming updates by ToolCallID
 			cb.handler.SendToolCall(
 				event.Output.MessageOutput.ToolName,
 				"",
@@ -530,20 +1104,30 @@ func (cb *ChatBot) StreamChatWithHandler(ctx context.Context, userInput string,
 
 		response.Reset()
 		reasoningContent.Reset()
+		recorded = false
 		toolMap := map[int][]*schema.Message{}
 		if event.Output.MessageOutput.MessageStream != nil {
 			reasoning, firstword := false, false
 			toolStart := false
+			toolIDs := toolCallIDs{}
+			usage := responseUsage{}
 			for {
 				message, err := event.Output.MessageOutput.MessageStream.Recv()
 				if err == io.EOF {
 					break
 				}
 				if err != nil {
+					usage.addTo(&turnUsage)
+					if timedOut(ctx) {
+						// End the interrupted response before the turn
+						cb.handler.SendChunk("", false, true, "response")
+						return cb.finishTimedOut(ctx, response.String(), reasoningContent.String())
+					}
 					err = fmt.Errorf("error receiving message stream: %w", err)
-					cb.handler.SendError(err.Error())
+					cb.sendError(err)
 					return err
 				}
+				usage.observe(message)
 
 				if len(message.ToolCalls) > 0 {
 					// Only send tool call notification at the start of tool invocation
@@ -557,58 +1141,41 @@ func (cb *ChatBot) StreamChatWithHandler(ctx context.Context, userInput string,
 						if index == nil {
 							index = &i
 						}
-						_, exists := toolMap[*index]
-						if !exists {
-							// First time seeing this tool call, send initial notification
-							toolMap[*index] = append(toolMap[*index], &schema.Message{
-								Role: message.Role,
-								ToolCalls: []schema.ToolCall{
-									{
-										ID:    tc.ID,
-										Type:  tc.Type,
-										Index: index,
-										Function: schema.FunctionCall{
-											Name:      tc.Function.Name,
-											Arguments: tc.Function.Arguments,
-										},
-									},
-								},
-							})
-							// Send initial tool call with streaming=true
-							cb.handler.SendToolCall(tc.Function.Name, tc.Function.Arguments, tc.ID, true)
-						} else {
-							// Already sent, accumulate and send update with streaming arguments
-							toolMap[*index] = append(toolMap[*index], &schema.Message{
-								Role: message.Role,
-								ToolCalls: []schema.ToolCall{
-									{
-										ID:    tc.ID,
-										Type:  tc.Type,
-										Index: index,
-										Function: schema.FunctionCall{
-											Name:      tc.Function.Name,
-											Arguments: tc.Function.Arguments,
-										},
+						id := toolIDs.resolve(*index, tc.ID)
+						toolMap[*index] = append(toolMap[*index], &schema.Message{
+							Role: message.Role,
+							ToolCalls: []schema.ToolCall{
+								{
+									ID:    tc.ID,
+									Type:  tc.Type,
+									Index: index,
+									Function: schema.FunctionCall{
+										Name:      tc.Function.Name,
+										Arguments: tc.Function.Arguments,
 									},
 								},
-							})
-
+							},
+						})
+						name := tc.Function.Name
+						if name == "" {
 							m, _ := schema.ConcatMessages(toolMap[*index])
-							if len(m.ToolCalls) > 0 {
-								// Send update with current accumulated arguments (streaming)
-								//cb.handler.SendToolCall(m.ToolCalls[0].Function.Name, m.ToolCalls[0].Function.Arguments, m.ToolCalls[0].ID, true)
-								// Send current arguments (streaming)
-								cb.handler.SendToolCall(m.ToolCalls[0].Function.Name, tc.Function.Arguments, m.ToolCalls[0].ID, true)
+							if m != nil && len(m.ToolCalls) > 0 {
+								name = m.ToolCalls[0].Function.Name
 							}
 						}
+						// Send current arguments delta (streaming)
+						cb.handler.SendToolCall(name, tc.Function.Arguments, id, true)
 					}
 					// Reset firstChunk after tool call for new response content
 					firstChunk = true
 				}
 
-				// Handle thinking/reasoning content
+				// Handle thinking/reasoning content. Hidden reasoning still ends the thinking
+				// indicator of the turn when the response starts.
 				if message.ReasoningContent != "" && !reasoning {
-					cb.handler.SendThinking(true)
+					if cb.reasoningDisplay != config.ReasoningDisplayHide {
+						cb.handler.SendThinking(true)
+					}
 					reasoning = true
 				}
 
@@ -624,8 +1191,9 @@ func (cb *ChatBot) StreamChatWithHandler(ctx context.Context, userInput string,
 						if reasoningContent.Len() == 0 {
 							decodedReasoning = TrimLeadingWhitespace(decodedReasoning)
 						}
-						if decodedReasoning != "" {
-							cb.handler.SendChunk(decodedReasoning, firstChunk, false, "thinking")
+						// Only sent chunks count as the first chunk of the response
+						if decodedReasoning != "" && cb.showsReasoning() {
+							cb.sendChunk(decodedReasoning, firstChunk, "thinking")
 							firstChunk = false
 						}
 						reasoningContent.WriteString(decodedReasoning)
@@ -647,13 +1215,14 @@ func (cb *ChatBot) StreamChatWithHandler(ctx context.Context, userInput string,
 							content = TrimLeadingWhitespace(content)
 						}
 						if content != "" {
-							cb.handler.SendChunk(content, firstChunk, false, "response")
+							cb.sendChunk(content, firstChunk, "response")
 							firstChunk = false
 						}
 						response.WriteString(content)
 					}
 				}
 			}
+			usage.addTo(&turnUsage)
 			// Send final chunk marker to indicate stream end
 			// contentType "response" indicates the end of the entire response
 			cb.handler.SendChunk("", false, true, "response")
@@ -685,8 +1254,11 @@ func (cb *ChatBot) StreamChatWithHandler(ctx context.Context, userInput string,
 				// Reset firstChunk after tool call
 				firstChunk = true
 			}
+			usage := responseUsage{}
+			usage.observe(event.Output.MessageOutput.Message)
+			usage.addTo(&turnUsage)
 			if event.Output.MessageOutput.Message.Content != "" {
-				cb.handler.SendChunk(event.Output.MessageOutput.Message.Content, firstChunk, false, "response")
+				cb.sendChunk(event.Output.MessageOutput.Message.Content, firstChunk, "response")
 				firstChunk = false
 				response.WriteString(event.Output.MessageOutput.Message.Content)
 				reasoningContent.WriteString(event.Output.MessageOutput.Message.ReasoningContent)
@@ -699,7 +1271,7 @@ func (cb *ChatBot) StreamChatWithHandler(ctx context.Context, userInput string,
 				Role:             schema.Assistant,
 				ToolCalls:        make([]schema.ToolCall, len(toolMap)),
 				Content:          response.String(),
-				ReasoningContent: reasoningContent.String(),
+				ReasoningContent: cb.storedReasoning(reasoningContent.String()),
 			}
 			for index, msgs := range toolMap {
 				m, err := schema.ConcatMessages(msgs)
@@ -709,16 +1281,17 @@ func (cb *ChatBot) StreamChatWithHandler(ctx context.Context, userInput string,
 				toolMsg.ToolCalls[index] = m.ToolCalls[0]
 			}
 			cb.manager.AddMessage(ctx, &toolMsg)
+			recorded = true
 			// Send message count update after adding tool call message
 			cb.handler.SendMessageCount()
 		}
 	}
 
 	cb.handler.SendComplete("")
-	cb.manager.AddMessage(ctx, &schema.Message{
+	cb.addResponse(ctx, &schema.Message{
 		Role:             schema.Assistant,
 		Content:          response.String(),
-		ReasoningContent: reasoningContent.String(),
+		ReasoningContent: cb.storedReasoning(reasoningContent.String()),
 	})
 
 	// Send message count update after assistant response is complete
@@ -734,5 +1307,6 @@ func (cb *ChatBot) GetContextSummary() string {
 
 // ClearContext clears the context
 func (cb *ChatBot) ClearContext() {
+	cb.stopped = nil
 	cb.manager.Clear()
 }
@@ -0,0 +1,102 @@
+package chatbot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+	"github.com/Arvintian/chat-agent/pkg/logger"
+	"github.com/Arvintian/chat-agent/pkg/mcp"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/eino-contrib/jsonschema"
+)
+
+// describedTool presents a tool to the model with overridden descriptions
+// while leaving its behavior unchanged
+type describedTool struct {
+	base     tool.InvokableTool
+	override config.ToolDescription
+}
+
+func (d *describedTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	info, err := d.base.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	copied := *info
+	if d.override.Desc != "" {
+		copied.Desc = d.override.Desc
+	}
+	if len(d.override.Params) > 0 {
+		params, err := overrideParamDescriptions(info.ParamsOneOf, d.override.Params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to override parameter descriptions of tool %s: %w", info.Name, err)
+		}
+		copied.ParamsOneOf = params
+	}
+	return &copied, nil
+}
+
+func (d *describedTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	return d.base.InvokableRun(ctx, argumentsInJSON, opts...)
+}
+
+// overrideParamDescriptions returns a copy of the parameters with the given descriptions.
+// The schema is copied so that the original tool keeps its own descriptions.
+func overrideParamDescriptions(params *schema.ParamsOneOf, descs map[string]string) (*schema.ParamsOneOf, error) {
+	s, err := params.ToJSONSchema()
+	if err != nil || s == nil {
+		return params, err
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	copied := &jsonschema.Schema{}
+	if err := json.Unmarshal(data, copied); err != nil {
+		return nil, err
+	}
+	if copied.Properties == nil {
+		return params, nil
+	}
+	for name, desc := range descs {
+		if prop, ok := copied.Properties.Get(name); ok && prop != nil {
+			prop.Description = desc
+		}
+	}
+	return schema.NewParamsOneOfByJSONSchema(copied), nil
+}
+
+// applyToolDescriptions wraps the tools that have a description override in the config.
+// Approval wrapping is kept on the outside so approval checks still see the tool as approvable.
+func applyToolDescriptions(ctx context.Context, tools []tool.BaseTool, descriptions map[string]config.ToolDescription) ([]tool.BaseTool, error) {
+	if len(descriptions) == 0 {
+		return tools, nil
+	}
+	result := make([]tool.BaseTool, 0, len(tools))
+	for _, item := range tools {
+		info, err := item.Info(ctx)
+		if err != nil {
+			return nil, err
+		}
+		override, ok := descriptions[info.Name]
+		if !ok {
+			result = append(result, item)
+			continue
+		}
+		switch t := item.(type) {
+		case mcp.InvokableApprovableTool:
+			t.InvokableTool = &describedTool{base: t.InvokableTool, override: override}
+			result = append(result, t)
+		case tool.InvokableTool:
+			result = append(result, &describedTool{base: t, override: override})
+		default:
+			logger.Warn("session", fmt.Sprintf("Description override for tool %s ignored, the tool is not invokable", info.Name))
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
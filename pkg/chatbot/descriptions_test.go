@@ -0,0 +1,90 @@
+package chatbot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+	"github.com/Arvintian/chat-agent/pkg/mcp"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// echoTool is an invokable tool with one parameter for description tests
+type echoTool struct{}
+
+func (echoTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: "echo",
+		Desc: "Echo the text",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"text": {Type: schema.String, Desc: "Text to echo", Required: true},
+		}),
+	}, nil
+}
+
+func (echoTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	return argumentsInJSON, nil
+}
+
+func paramDesc(t *testing.T, info *schema.ToolInfo, name string) string {
+	t.Helper()
+	s, err := info.ParamsOneOf.ToJSONSchema()
+	if err != nil {
+		t.Fatalf("ToJSONSchema() error = %v", err)
+	}
+	prop, ok := s.Properties.Get(name)
+	if !ok {
+		t.Fatalf("parameter %s not found", name)
+	}
+	return prop.Description
+}
+
+func TestApplyToolDescriptions(t *testing.T) {
+	ctx := context.Background()
+	tools := []tool.BaseTool{mcp.InvokableApprovableTool{InvokableTool: echoTool{}}}
+	descriptions := map[string]config.ToolDescription{
+		"echo": {Desc: "Repeat the given text", Params: map[string]string{"text": "The text to repeat", "missing": "ignored"}},
+	}
+
+	got, err := applyToolDescriptions(ctx, tools, descriptions)
+	if err != nil {
+		t.Fatalf("applyToolDescriptions() error = %v", err)
+	}
+	if _, ok := got[0].(mcp.InvokableApprovableTool); !ok {
+		t.Fatalf("tool = %T, want it to still require approval", got[0])
+	}
+	info, err := got[0].Info(ctx)
+	if err != nil {
+		t.Fatalf("Info() error = %v", err)
+	}
+	if info.Desc != "Repeat the given text" {
+		t.Errorf("Desc = %q, want the override", info.Desc)
+	}
+	if desc := paramDesc(t, info, "text"); desc != "The text to repeat" {
+		t.Errorf("text description = %q, want the override", desc)
+	}
+
+	original, _ := echoTool{}.Info(ctx)
+	if desc := paramDesc(t, original, "text"); desc != "Text to echo" {
+		t.Errorf("original text description = %q, want it unchanged", desc)
+	}
+
+	out, err := got[0].(mcp.InvokableApprovableTool).InvokableTool.InvokableRun(ctx, `{"text":"hi"}`)
+	if err != nil || out != `{"text":"hi"}` {
+		t.Errorf("InvokableRun() = %q, %v, want the base tool result", out, err)
+	}
+}
+
+func TestApplyToolDescriptionsKeepsOtherTools(t *testing.T) {
+	ctx := context.Background()
+	tools := []tool.BaseTool{echoTool{}}
+	got, err := applyToolDescriptions(ctx, tools, map[string]config.ToolDescription{"other": {Desc: "x"}})
+	if err != nil {
+		t.Fatalf("applyToolDescriptions() error = %v", err)
+	}
+	if _, ok := got[0].(echoTool); !ok {
+		t.Errorf("tool = %T, want it unwrapped", got[0])
+	}
+}
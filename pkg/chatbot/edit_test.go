@@ -0,0 +1,33 @@
+package chatbot
+
+import (
+	"testing"
+
+	"github.com/Arvintian/chat-agent/pkg/manager"
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestEditUserMessageAfterCompressBuffer(t *testing.T) {
+	m := manager.NewManager(manager.Config{MaxMessageRounds: 10})
+	m.Restore(manager.State{
+		CompressBuffer: [][]*schema.Message{{schema.UserMessage("question 0"), schema.AssistantMessage("answer 0", nil)}},
+		Messages: [][]*schema.Message{
+			{schema.UserMessage("question 1"), schema.AssistantMessage("answer 1", nil)},
+			{schema.UserMessage("question 2"), schema.AssistantMessage("answer 2", nil)},
+			{schema.UserMessage("question 3"), schema.AssistantMessage("answer 3", nil)},
+		},
+	})
+	session := &ChatSession{Manager: m}
+
+	edited, err := session.EditUserMessage(1, "edited question")
+	if err != nil {
+		t.Fatalf("EditUserMessage(1) error = %v", err)
+	}
+	if edited.Content != "edited question" {
+		t.Errorf("edited message = %q, want the new text", edited.Content)
+	}
+	state := m.Snapshot()
+	if len(state.CompressBuffer) != 1 || len(state.Messages) != 1 || len(state.Messages[0]) != 1 || state.Messages[0][0] != edited {
+		t.Errorf("buffer = %v, rounds = %v, want the buffer kept and only the edited message after it", state.CompressBuffer, state.Messages)
+	}
+}
@@ -0,0 +1,120 @@
+package chatbot
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Arvintian/chat-agent/pkg/extract"
+	"github.com/Arvintian/chat-agent/pkg/logger"
+)
+
+const (
+	documentFetchTimeout = 30       // in seconds
+	maxDocumentSize      = 50 << 20 // in bytes
+	// maxExtractedLength caps the characters of text extracted from one document
+	maxExtractedLength = 100000
+)
+
+// ExtractDocuments replaces attached documents by their text when the chat's
+// extractDocuments option is set, so their content reaches models that only read text.
+// The text is returned labeled with the file name, to append to the user message; the
+// remaining files are returned unchanged. Files whose text can't be extracted are kept
+// as regular attachments, and nothing is extracted when the model is multimodal.
+func (s *ChatSession) ExtractDocuments(ctx context.Context, files []FileData) ([]FileData, string) {
+	if !s.Preset.ExtractDocuments || s.multimodal || len(files) == 0 {
+		return files, ""
+	}
+
+	remaining := make([]FileData, 0, len(files))
+	var extracted []string
+	for _, file := range files {
+		if !extract.Supported(file.Type, file.Name) {
+			remaining = append(remaining, file)
+			continue
+		}
+		text, err := extractDocument(ctx, file)
+		if err != nil {
+			logger.Warn("session", fmt.Sprintf("Failed to extract the text of %s, sending the file as is: %v", file.Name, err))
+			remaining = append(remaining, file)
+			continue
+		}
+		if s.redactor != nil {
+			text = s.redactor.Redact(text)
+		}
+		extracted = append(extracted, fmt.Sprintf("[File %s]\n%s", file.Name, truncateExtracted(text)))
+	}
+	return remaining, strings.Join(extracted, "\n\n")
+}
+
+// extractDocument returns the text of an uploaded (data URL) or remote document
+func extractDocument(ctx context.Context, file FileData) (string, error) {
+	var data []byte
+	if strings.HasPrefix(file.URL, "data:") {
+		_, encoded := parseDataURL(file.URL)
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			// Not base64, the data is percent-encoded
+			unescaped, uerr := url.PathUnescape(encoded)
+			if uerr != nil {
+				return "", fmt.Errorf("failed to decode data URL: %w", err)
+			}
+			decoded = []byte(unescaped)
+		}
+		data = decoded
+	} else {
+		fetched, err := fetchDocument(ctx, file.URL)
+		if err != nil {
+			return "", err
+		}
+		data = fetched
+	}
+	return extract.Text(data, file.Type, file.Name)
+}
+
+// fetchDocument downloads a remote document
+func fetchDocument(ctx context.Context, documentURL string) ([]byte, error) {
+	if !strings.HasPrefix(documentURL, "http://") && !strings.HasPrefix(documentURL, "https://") {
+		return nil, fmt.Errorf("unsupported document url")
+	}
+	client := &http.Client{
+		Timeout: documentFetchTimeout * time.Second,
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, documentURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create document request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch document: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("document url returned status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDocumentSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document: %w", err)
+	}
+	if len(body) > maxDocumentSize {
+		return nil, fmt.Errorf("document exceeds %d bytes", maxDocumentSize)
+	}
+	return body, nil
+}
+
+// truncateExtracted caps extracted text to maxExtractedLength characters
+func truncateExtracted(text string) string {
+	total := utf8.RuneCountInString(text)
+	if total <= maxExtractedLength {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:maxExtractedLength]) + fmt.Sprintf("\n[truncated, %d of %d characters shown]", maxExtractedLength, total)
+}
@@ -0,0 +1,55 @@
+package chatbot
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+)
+
+func TestExtractDocuments(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("remote notes"))
+	}))
+	defer server.Close()
+
+	files := []FileData{
+		{Name: "notes.txt", Type: "text/plain", URL: "data:text/plain;base64," + base64.StdEncoding.EncodeToString([]byte("local notes"))},
+		{Name: "photo.png", Type: "image/png", URL: "data:image/png;base64,AAAA"},
+		{Name: "remote.md", Type: "text/markdown", URL: server.URL + "/remote.md"},
+		{Name: "missing.txt", Type: "text/plain", URL: server.URL + "/missing.txt"},
+	}
+
+	session := &ChatSession{Preset: config.Chat{ExtractDocuments: true}}
+	remaining, text := session.ExtractDocuments(context.Background(), files)
+	if want := "[File notes.txt]\nlocal notes\n\n[File remote.md]\nremote notes"; text != want {
+		t.Errorf("got text %q, want %q", text, want)
+	}
+	if len(remaining) != 2 || remaining[0].Name != "photo.png" || remaining[1].Name != "missing.txt" {
+		t.Errorf("got remaining files %+v, want the image and the failed document", remaining)
+	}
+
+	session.multimodal = true
+	if remaining, text := session.ExtractDocuments(context.Background(), files); text != "" || len(remaining) != len(files) {
+		t.Errorf("multimodal model: got %q and %d files, want the files unchanged", text, len(remaining))
+	}
+}
+
+func TestTruncateExtracted(t *testing.T) {
+	text := strings.Repeat("é", maxExtractedLength+10)
+	got := truncateExtracted(text)
+	if !strings.HasPrefix(got, strings.Repeat("é", maxExtractedLength)+"\n[truncated") {
+		t.Errorf("got %q..., want the text cut at %d characters", got[:20], maxExtractedLength)
+	}
+	if truncateExtracted("short") != "short" {
+		t.Error("short text truncated")
+	}
+}
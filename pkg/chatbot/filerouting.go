@@ -0,0 +1,158 @@
+package chatbot
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+	"github.com/Arvintian/chat-agent/pkg/logger"
+	"github.com/Arvintian/chat-agent/pkg/mcp"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// routedFile is the data available to a file route's arguments template
+type routedFile struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	URL     string `json:"url"`
+	Content string `json:"content"` // decoded content of data URLs, empty for remote URLs
+}
+
+// RouteFiles pre-processes attached files matching the chat's fileRouting rules with the
+// configured tool before the message reaches the model. Routed files are replaced by the
+// tool output, returned as text to append to the user message; the remaining files are
+// returned unchanged. A file whose tool fails is kept as a regular attachment. The tool
+// output is redacted like any other tool result when redaction is enabled.
+func (s *ChatSession) RouteFiles(ctx context.Context, files []FileData) ([]FileData, string) {
+	if len(s.Preset.FileRouting) == 0 || len(files) == 0 {
+		return files, ""
+	}
+
+	remaining := make([]FileData, 0, len(files))
+	var routed []string
+	for _, file := range files {
+		route := matchFileRoute(s.Preset.FileRouting, file)
+		if route == nil {
+			remaining = append(remaining, file)
+			continue
+		}
+		output, err := s.runFileRoute(ctx, route, file)
+		if err != nil {
+			logger.Warn("session", fmt.Sprintf("File routing of %s to tool %s failed, sending the file as is: %v", file.Name, route.Tool, err))
+			remaining = append(remaining, file)
+			continue
+		}
+		if s.redactor != nil {
+			output = s.redactor.Redact(output)
+		}
+		routed = append(routed, fmt.Sprintf("[File %s processed by %s]\n%s", file.Name, route.Tool, output))
+	}
+	return remaining, strings.Join(routed, "\n\n")
+}
+
+// runFileRoute invokes the route's tool with the arguments rendered for the file
+func (s *ChatSession) runFileRoute(ctx context.Context, route *config.FileRoute, file FileData) (string, error) {
+	target, err := s.findInvokableTool(ctx, route.Tool)
+	if err != nil {
+		return "", err
+	}
+
+	data := routedFile{Name: file.Name, Type: file.Type, URL: file.URL}
+	if strings.HasPrefix(file.URL, "data:") {
+		data.Content = decodeDataURL(file.URL)
+	}
+
+	var args string
+	if route.Args == "" {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return "", err
+		}
+		args = string(raw)
+	} else {
+		tmpl, err := template.New("fileRoute").Funcs(template.FuncMap{
+			"json": func(v any) (string, error) {
+				raw, err := json.Marshal(v)
+				return string(raw), err
+			},
+		}).Parse(route.Args)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse file route args: %w", err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("failed to render file route args: %w", err)
+		}
+		args = buf.String()
+	}
+
+	return target.InvokableRun(ctx, args)
+}
+
+// findInvokableTool returns the session tool with the given name. File routes run without
+// asking the user, so tools that require approval are rejected.
+func (s *ChatSession) findInvokableTool(ctx context.Context, name string) (tool.InvokableTool, error) {
+	for _, item := range s.Tools {
+		info, err := item.Info(ctx)
+		if err != nil || info.Name != name {
+			continue
+		}
+		if _, ok := item.(mcp.InvokableApprovableTool); ok {
+			return nil, fmt.Errorf("tool %s requires approval and cannot be used for file routing, enable auto approval for it", name)
+		}
+		if invokable, ok := item.(tool.InvokableTool); ok {
+			return invokable, nil
+		}
+	}
+	return nil, fmt.Errorf("tool %s is not loaded in chat %s", name, s.Name)
+}
+
+// matchFileRoute returns the first route whose types match the file, or nil.
+// Types are MIME types (text/csv), MIME prefixes (text/*) or file extensions (.csv).
+func matchFileRoute(routes []config.FileRoute, file FileData) *config.FileRoute {
+	for i := range routes {
+		if matchFileType(routes[i].Types, file.Name, file.Type) {
+			return &routes[i]
+		}
+	}
+	return nil
+}
+
+// matchFileType reports whether a file of the name and MIME type matches one of the types:
+// MIME types, MIME prefixes like text/* or extensions like .csv
+func matchFileType(types []string, name, mimeType string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	mimeType = strings.ToLower(mimeType)
+	for _, t := range types {
+		t = strings.ToLower(t)
+		switch {
+		case strings.HasPrefix(t, "."):
+			if ext == t {
+				return true
+			}
+		case strings.HasSuffix(t, "/*"):
+			if strings.HasPrefix(mimeType, strings.TrimSuffix(t, "*")) {
+				return true
+			}
+		case mimeType == t:
+			return true
+		}
+	}
+	return false
+}
+
+// decodeDataURL returns the decoded content of a data URL, or the raw data if it is not base64
+func decodeDataURL(dataURL string) string {
+	_, data := parseDataURL(dataURL)
+	if decoded, err := base64.StdEncoding.DecodeString(data); err == nil {
+		return string(decoded)
+	}
+	return data
+}
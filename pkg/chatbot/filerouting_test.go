@@ -0,0 +1,133 @@
+package chatbot
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Arvintian/chat-agent/pkg/chatbot/middleware"
+	"github.com/Arvintian/chat-agent/pkg/config"
+	"github.com/Arvintian/chat-agent/pkg/mcp"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// fakeTool is an invokable tool that returns the result of run
+type fakeTool struct {
+	name string
+	run  func(args string) (string, error)
+}
+
+func (f *fakeTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{Name: f.name}, nil
+}
+
+func (f *fakeTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	return f.run(argumentsInJSON)
+}
+
+func TestMatchFileRoute(t *testing.T) {
+	routes := []config.FileRoute{
+		{Types: []string{".CSV"}, Tool: "csv"},
+		{Types: []string{"text/*"}, Tool: "text"},
+		{Types: []string{"application/pdf"}, Tool: "pdf"},
+	}
+
+	tests := []struct {
+		name string
+		file FileData
+		want string
+	}{
+		{"extension, case insensitive", FileData{Name: "data.csv", Type: "text/csv"}, "csv"},
+		{"first matching route wins", FileData{Name: "DATA.CSV", Type: "application/octet-stream"}, "csv"},
+		{"mime prefix", FileData{Name: "notes.md", Type: "text/markdown"}, "text"},
+		{"exact mime", FileData{Name: "report", Type: "Application/PDF"}, "pdf"},
+		{"no match", FileData{Name: "photo.png", Type: "image/png"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := matchFileRoute(routes, tt.file)
+			got := ""
+			if route != nil {
+				got = route.Tool
+			}
+			if got != tt.want {
+				t.Errorf("matchFileRoute() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteFiles(t *testing.T) {
+	csvTool := &fakeTool{name: "csv_summary", run: func(args string) (string, error) {
+		var file routedFile
+		if err := json.Unmarshal([]byte(args), &file); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d rows, token secret-1234", strings.Count(file.Content, "\n")), nil
+	}}
+	failingTool := &fakeTool{name: "broken", run: func(args string) (string, error) {
+		return "", fmt.Errorf("boom")
+	}}
+	gatedTool := mcp.InvokableApprovableTool{InvokableTool: &fakeTool{name: "cmd", run: func(args string) (string, error) {
+		t.Error("approval-gated tool must not run for file routing")
+		return "", nil
+	}}}
+
+	redactor, err := middleware.NewRedactor([]string{`secret-[0-9]+`}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := &ChatSession{
+		Name: "test",
+		Preset: config.Chat{FileRouting: []config.FileRoute{
+			{Types: []string{".csv"}, Tool: "csv_summary"},
+			{Types: []string{".log"}, Tool: "broken"},
+			{Types: []string{".sh"}, Tool: "cmd"},
+			{Types: []string{".txt"}, Tool: "missing"},
+		}},
+		Tools:    []tool.BaseTool{csvTool, failingTool, gatedTool},
+		redactor: redactor,
+	}
+
+	csvContent := base64.StdEncoding.EncodeToString([]byte("a,b\n1,2\n"))
+	files := []FileData{
+		{Name: "data.csv", Type: "text/csv", URL: "data:text/csv;base64," + csvContent},
+		{Name: "photo.png", Type: "image/png", URL: "data:image/png;base64,AAAA"},
+		{Name: "app.log", Type: "text/plain", URL: "data:text/plain;base64,AAAA"},
+		{Name: "run.sh", Type: "text/x-sh", URL: "data:text/x-sh;base64,AAAA"},
+		{Name: "notes.txt", Type: "text/plain", URL: "data:text/plain;base64,AAAA"},
+	}
+
+	remaining, routed := session.RouteFiles(context.Background(), files)
+
+	want := "[File data.csv processed by csv_summary]\n2 rows, token [REDACTED]"
+	if routed != want {
+		t.Errorf("routed = %q, want %q", routed, want)
+	}
+	var names []string
+	for _, file := range remaining {
+		names = append(names, file.Name)
+	}
+	if got := strings.Join(names, ","); got != "photo.png,app.log,run.sh,notes.txt" {
+		t.Errorf("remaining files = %s, want photo.png,app.log,run.sh,notes.txt", got)
+	}
+}
+
+func TestRouteFilesWithoutRoutes(t *testing.T) {
+	session := &ChatSession{Name: "test"}
+	files := []FileData{{Name: "data.csv", Type: "text/csv"}}
+
+	remaining, routed := session.RouteFiles(context.Background(), files)
+	if routed != "" {
+		t.Errorf("routed = %q, want empty", routed)
+	}
+	if len(remaining) != 1 {
+		t.Errorf("got %d remaining files, want 1", len(remaining))
+	}
+}
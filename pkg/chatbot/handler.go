@@ -0,0 +1,146 @@
+package chatbot
+
+import (
+	"fmt"
+	"sync"
+)
+
+// MultiHandler fans out Handler calls to several handlers, e.g. a WebSocket handler
+// alongside an audit or logging handler. Approval requests and questions need a single
+// decision, so they are answered by the first handler only.
+type MultiHandler struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+// NewMultiHandler creates a handler that forwards every call to the given handlers
+func NewMultiHandler(handlers ...Handler) *MultiHandler {
+	return &MultiHandler{handlers: handlers}
+}
+
+// Add registers another handler
+func (m *MultiHandler) Add(handler Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers = append(m.handlers, handler)
+}
+
+// each calls fn for every registered handler
+func (m *MultiHandler) each(fn func(Handler)) {
+	m.mu.RLock()
+	handlers := m.handlers
+	m.mu.RUnlock()
+	for _, handler := range handlers {
+		fn(handler)
+	}
+}
+
+// first returns the handler answering approvals and questions. The lock is not held
+// while it blocks waiting for the user, so Add is not blocked meanwhile.
+func (m *MultiHandler) first() Handler {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.handlers) == 0 {
+		return nil
+	}
+	return m.handlers[0]
+}
+
+// SendChunk forwards a content chunk to all handlers
+func (m *MultiHandler) SendChunk(content string, first, last bool, contentType string) {
+	m.each(func(h Handler) { h.SendChunk(content, first, last, contentType) })
+}
+
+// SendToolCall forwards a tool call notification to all handlers
+func (m *MultiHandler) SendToolCall(name string, arguments string, id string, streaming bool) {
+	m.each(func(h Handler) { h.SendToolCall(name, arguments, id, streaming) })
+}
+
+// SendToolProgress forwards the progress of a tool call to the handlers that show it
+func (m *MultiHandler) SendToolProgress(id string, progress string) {
+	m.each(func(h Handler) {
+		if handler, ok := h.(ToolProgressHandler); ok {
+			handler.SendToolProgress(id, progress)
+		}
+	})
+}
+
+// SendModelFallback forwards a model fallback to the handlers that report it
+func (m *MultiHandler) SendModelFallback(from, to, reason string) {
+	m.each(func(h Handler) {
+		if handler, ok := h.(ModelFallbackHandler); ok {
+			handler.SendModelFallback(from, to, reason)
+		}
+	})
+}
+
+// SendThinking forwards a thinking indicator to all handlers
+func (m *MultiHandler) SendThinking(status bool) {
+	m.each(func(h Handler) { h.SendThinking(status) })
+}
+
+// SendComplete forwards a completion signal to all handlers
+func (m *MultiHandler) SendComplete(message string) {
+	m.each(func(h Handler) { h.SendComplete(message) })
+}
+
+// SendError forwards an error message to all handlers
+func (m *MultiHandler) SendError(err string) {
+	m.each(func(h Handler) { h.SendError(err) })
+}
+
+// SendErrorCode forwards an error with its code to all handlers, as a plain error to
+// those that do not take the code
+func (m *MultiHandler) SendErrorCode(message, code string) {
+	m.each(func(h Handler) {
+		if handler, ok := h.(ErrorCodeHandler); ok {
+			handler.SendErrorCode(message, code)
+		} else {
+			h.SendError(message)
+		}
+	})
+}
+
+// SendContentFiltered reports a response blocked by the content filter to all handlers,
+// as an error to those that do not report it separately
+func (m *MultiHandler) SendContentFiltered(message string) {
+	m.each(func(h Handler) {
+		if handler, ok := h.(ContentFilterHandler); ok {
+			handler.SendContentFiltered(message)
+		} else {
+			h.SendError(message)
+		}
+	})
+}
+
+// SendUsage forwards the token usage to the handlers that report it
+func (m *MultiHandler) SendUsage(turn, session TokenUsage) {
+	m.each(func(h Handler) {
+		if handler, ok := h.(UsageHandler); ok {
+			handler.SendUsage(turn, session)
+		}
+	})
+}
+
+// SendApprovalRequest asks the first handler for approval
+func (m *MultiHandler) SendApprovalRequest(targets []ApprovalTarget) (ApprovalResultMap, error) {
+	handler := m.first()
+	if handler == nil {
+		return nil, fmt.Errorf("no handler available for approval")
+	}
+	return handler.SendApprovalRequest(targets)
+}
+
+// SendMessageCount forwards the message count to all handlers
+func (m *MultiHandler) SendMessageCount() {
+	m.each(func(h Handler) { h.SendMessageCount() })
+}
+
+// SendQuestion asks the first handler a clarifying question
+func (m *MultiHandler) SendQuestion(question string) (string, error) {
+	handler := m.first()
+	if handler == nil {
+		return "", fmt.Errorf("no handler available for questions")
+	}
+	return handler.SendQuestion(question)
+}
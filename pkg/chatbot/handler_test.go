@@ -0,0 +1,245 @@
+package chatbot
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	chaterrors "github.com/Arvintian/chat-agent/pkg/errors"
+	"github.com/Arvintian/chat-agent/pkg/mcp"
+	"github.com/Arvintian/chat-agent/pkg/providers"
+)
+
+// recordingHandler records the events it receives. Approvals and questions block
+// until release is closed, when it is set.
+type recordingHandler struct {
+	mu        sync.Mutex
+	events    []string
+	approvals int
+	questions int
+	release   chan struct{}
+}
+
+func (h *recordingHandler) record(event string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, event)
+}
+
+func (h *recordingHandler) recorded() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.events...)
+}
+
+func (h *recordingHandler) SendChunk(content string, first, last bool, contentType string) {
+	h.record("chunk:" + content)
+}
+
+func (h *recordingHandler) SendToolCall(name string, arguments string, id string, streaming bool) {
+	h.record("tool:" + name)
+}
+
+func (h *recordingHandler) SendThinking(status bool) { h.record("thinking") }
+
+func (h *recordingHandler) SendComplete(message string) { h.record("complete") }
+
+func (h *recordingHandler) SendError(err string) { h.record("error:" + err) }
+
+func (h *recordingHandler) SendMessageCount() { h.record("count") }
+
+func (h *recordingHandler) SendApprovalRequest(targets []ApprovalTarget) (ApprovalResultMap, error) {
+	h.mu.Lock()
+	h.approvals++
+	h.mu.Unlock()
+	if h.release != nil {
+		<-h.release
+	}
+	results := make(ApprovalResultMap, len(targets))
+	for _, target := range targets {
+		results[target.ID] = &mcp.ApprovalResult{Approved: true}
+	}
+	return results, nil
+}
+
+func (h *recordingHandler) SendQuestion(question string) (string, error) {
+	h.mu.Lock()
+	h.questions++
+	h.mu.Unlock()
+	return "answer", nil
+}
+
+func TestMultiHandlerFanOut(t *testing.T) {
+	first, second := &recordingHandler{}, &recordingHandler{}
+	multi := NewMultiHandler(first)
+	multi.Add(second)
+
+	multi.SendThinking(true)
+	multi.SendToolCall("cmd", "{}", "call_1", false)
+	multi.SendChunk("hello", true, true, "response")
+	multi.SendMessageCount()
+	multi.SendError("oops")
+	multi.SendComplete("")
+
+	want := []string{"thinking", "tool:cmd", "chunk:hello", "count", "error:oops", "complete"}
+	for name, h := range map[string]*recordingHandler{"first": first, "second": second} {
+		got := h.recorded()
+		if len(got) != len(want) {
+			t.Fatalf("%s handler got events %v, want %v", name, got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s handler event %d = %q, want %q", name, i, got[i], want[i])
+			}
+		}
+	}
+
+	// Approvals and questions need a single decision, only the first handler answers
+	results, err := multi.SendApprovalRequest([]ApprovalTarget{{ID: "1", ToolName: "cmd"}})
+	if err != nil || !results["1"].Approved {
+		t.Errorf("SendApprovalRequest() = %v, %v", results, err)
+	}
+	if answer, err := multi.SendQuestion("which file?"); err != nil || answer != "answer" {
+		t.Errorf("SendQuestion() = %q, %v", answer, err)
+	}
+	if first.approvals != 1 || first.questions != 1 {
+		t.Errorf("first handler answered %d approvals and %d questions, want 1 and 1", first.approvals, first.questions)
+	}
+	if second.approvals != 0 || second.questions != 0 {
+		t.Errorf("second handler answered %d approvals and %d questions, want none", second.approvals, second.questions)
+	}
+}
+
+func TestMultiHandlerWithoutHandlers(t *testing.T) {
+	multi := NewMultiHandler()
+	if _, err := multi.SendApprovalRequest(nil); err == nil {
+		t.Error("SendApprovalRequest() error = nil without handlers")
+	}
+	if _, err := multi.SendQuestion("?"); err == nil {
+		t.Error("SendQuestion() error = nil without handlers")
+	}
+}
+
+func TestMultiHandlerAddDuringApproval(t *testing.T) {
+	first := &recordingHandler{release: make(chan struct{})}
+	multi := NewMultiHandler(first)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		multi.SendApprovalRequest([]ApprovalTarget{{ID: "1"}})
+	}()
+
+	// Wait until the approval is pending
+	deadline := time.Now().Add(time.Second)
+	for {
+		first.mu.Lock()
+		pending := first.approvals == 1
+		first.mu.Unlock()
+		if pending {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("approval request was not forwarded")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	added := make(chan struct{})
+	go func() {
+		multi.Add(&recordingHandler{})
+		close(added)
+	}()
+	select {
+	case <-added:
+	case <-time.After(time.Second):
+		t.Error("Add() blocked while an approval request was pending")
+	}
+
+	close(first.release)
+	<-done
+}
+
+func TestChatBotAddHandler(t *testing.T) {
+	var cb ChatBot
+	first, second, third := &recordingHandler{}, &recordingHandler{}, &recordingHandler{}
+
+	cb.AddHandler(first)
+	if cb.handler != first {
+		t.Fatalf("first AddHandler should set the handler directly, got %T", cb.handler)
+	}
+	cb.AddHandler(second)
+	cb.AddHandler(third)
+	multi, ok := cb.handler.(*MultiHandler)
+	if !ok {
+		t.Fatalf("handler = %T, want *MultiHandler", cb.handler)
+	}
+
+	multi.SendComplete("")
+	for i, h := range []*recordingHandler{first, second, third} {
+		if got := h.recorded(); len(got) != 1 || got[0] != "complete" {
+			t.Errorf("handler %d got events %v, want [complete]", i, got)
+		}
+	}
+}
+
+// filterHandler is a recordingHandler that reports content filter blocks separately
+type filterHandler struct {
+	recordingHandler
+}
+
+func (h *filterHandler) SendContentFiltered(message string) { h.record("filtered:" + message) }
+
+func TestSendErrorContentFiltered(t *testing.T) {
+	err := fmt.Errorf("error receiving message stream: %w", providers.ErrContentFiltered)
+	plain, filter := &recordingHandler{}, &filterHandler{}
+
+	cb := &ChatBot{handler: NewMultiHandler(plain, filter)}
+	cb.sendError(err)
+
+	if got := plain.recorded(); len(got) != 1 || got[0] != "error:"+providers.ErrContentFiltered.Error() {
+		t.Errorf("plain handler got %v, want the block as an error", got)
+	}
+	if got := filter.recorded(); len(got) != 1 || got[0] != "filtered:"+providers.ErrContentFiltered.Error() {
+		t.Errorf("filter handler got %v, want a content filtered event", got)
+	}
+
+	cb = &ChatBot{handler: filter}
+	cb.sendError(errors.New("boom"))
+	if got := filter.recorded(); got[len(got)-1] != "error:boom" {
+		t.Errorf("other errors got %v, want them sent as errors", got)
+	}
+}
+
+// codeHandler is a recordingHandler that takes the codes of the errors
+type codeHandler struct {
+	recordingHandler
+}
+
+func (h *codeHandler) SendErrorCode(message, code string) { h.record(code + ":" + message) }
+
+func TestSendErrorCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{fmt.Errorf("failed to invoke tool[name:cmd]: %w", chaterrors.Wrap(chaterrors.ErrToolExecution, errors.New("exit 1"))), chaterrors.CodeToolExecution},
+		{chaterrors.Wrap(chaterrors.ErrProviderUnavailable, errors.New("status code: 503")), chaterrors.CodeProviderUnavailable},
+		{fmt.Errorf("%w after 1s", ErrRequestTimeout), CodeRequestTimeout},
+		{errors.New("boom"), chaterrors.CodeInternal},
+	}
+	for _, tt := range tests {
+		plain, coded := &recordingHandler{}, &codeHandler{}
+		cb := &ChatBot{handler: NewMultiHandler(plain, coded)}
+		cb.sendError(tt.err)
+
+		if got := plain.recorded(); len(got) != 1 || got[0] != "error:"+tt.err.Error() {
+			t.Errorf("plain handler got %v, want the error message", got)
+		}
+		if got := coded.recorded(); len(got) != 1 || got[0] != tt.want+":"+tt.err.Error() {
+			t.Errorf("code handler got %v, want the error with code %s", got, tt.want)
+		}
+	}
+}
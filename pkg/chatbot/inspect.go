@@ -0,0 +1,78 @@
+package chatbot
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Arvintian/chat-agent/pkg/mcp"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// MCPServerStatus is the initialization outcome of an MCP server of a chat
+type MCPServerStatus struct {
+	Name  string
+	Tools int   // tools loaded from the server
+	Err   error // nil if the server initialized
+}
+
+// SessionInspection is what a session sends to the model besides the conversation
+type SessionInspection struct {
+	// SystemPrompt is the rendered system prompt of every turn
+	SystemPrompt string
+	// InitSystemPrompt is the rendered system prompt of the first turn, empty when the chat
+	// has no initSystem
+	InitSystemPrompt string
+	// Tools are the tools offered to the model, after description overrides
+	Tools []*schema.ToolInfo
+	// MCPServers holds the status of the chat's MCP servers, in configuration order
+	MCPServers []MCPServerStatus
+}
+
+// Inspect returns the rendered system prompts, the tools and the MCP server status of the
+// session, without calling the model. Template variables such as the date are rendered
+// as of now.
+func (s *ChatSession) Inspect() (*SessionInspection, error) {
+	s.mu.Lock()
+	instruction := s.agentConfig.Instruction
+	s.mu.Unlock()
+
+	inspection := &SessionInspection{Tools: s.toolSchemas}
+	var err error
+	if inspection.SystemPrompt, err = RenderSystemPrompt(instruction); err != nil {
+		return nil, fmt.Errorf("failed to render the system prompt: %w", err)
+	}
+	if s.initSystem != "" {
+		if inspection.InitSystemPrompt, err = RenderSystemPrompt(s.initSystem); err != nil {
+			return nil, fmt.Errorf("failed to render the init system prompt: %w", err)
+		}
+	}
+	for _, name := range s.Preset.MCPServers {
+		status := MCPServerStatus{Name: name, Err: mcpServerError(s.MCPInitErr, name)}
+		if s.MCPClient != nil {
+			status.Tools = len(s.MCPClient.GetToolsForServers([]string{name}))
+		}
+		inspection.MCPServers = append(inspection.MCPServers, status)
+	}
+	return inspection, nil
+}
+
+// mcpServerError returns the initialization error of a server from the joined errors of
+// InitializeForChat. An error not tied to a server, such as an invalid configuration,
+// applies to every server.
+func mcpServerError(initErr error, serverName string) error {
+	if initErr == nil {
+		return nil
+	}
+	errs := []error{initErr}
+	if joined, ok := initErr.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	for _, err := range errs {
+		var mcpErr *mcp.MCPError
+		if !errors.As(err, &mcpErr) || mcpErr.Server == "" || mcpErr.Server == serverName {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package chatbot
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Arvintian/chat-agent/pkg/mcp"
+)
+
+func TestMCPServerError(t *testing.T) {
+	timeout := mcp.NewMCPError("initialize", "slow", "", errors.New("timeout"))
+	initErr := errors.Join(timeout, mcp.NewMCPError("list_tools", "broken", "", errors.New("EOF")))
+
+	if err := mcpServerError(initErr, "slow"); err != timeout {
+		t.Errorf("mcpServerError(slow) = %v, want %v", err, timeout)
+	}
+	if err := mcpServerError(initErr, "ok"); err != nil {
+		t.Errorf("mcpServerError(ok) = %v, want nil", err)
+	}
+	if err := mcpServerError(nil, "ok"); err != nil {
+		t.Errorf("mcpServerError(nil) = %v, want nil", err)
+	}
+
+	// An error without a server applies to every server
+	invalid := errors.New("invalid MCP configuration")
+	if err := mcpServerError(invalid, "ok"); err != invalid {
+		t.Errorf("mcpServerError(ok) = %v, want %v", err, invalid)
+	}
+}
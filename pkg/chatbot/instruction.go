@@ -0,0 +1,118 @@
+package chatbot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+	"github.com/Arvintian/chat-agent/pkg/logger"
+)
+
+const (
+	defaultRemoteInstructionTimeout  = 10  // in seconds
+	defaultRemoteInstructionCacheTTL = 300 // in seconds
+	remoteInstructionCacheDir        = ".chat-agent/cache/instructions"
+	maxRemoteInstructionSize         = 1 << 20 // in bytes
+)
+
+// fetchRemoteInstruction returns the instruction fragment configured for a chat.
+// A cached copy younger than the cache TTL is used without fetching. When the fetch
+// fails, a stale cached copy is used if one exists, otherwise the error is returned.
+func fetchRemoteInstruction(ctx context.Context, cfg *config.RemoteInstruction) (string, error) {
+	if cfg.URL == "" {
+		return "", fmt.Errorf("remote instruction url is required")
+	}
+	cacheTTL := cfg.CacheTTL
+	if cacheTTL <= 0 {
+		cacheTTL = defaultRemoteInstructionCacheTTL
+	}
+
+	cacheFile := remoteInstructionCacheFile(cfg.URL)
+	cached, cacheErr := os.ReadFile(cacheFile)
+	if cacheErr == nil {
+		if info, err := os.Stat(cacheFile); err == nil && time.Since(info.ModTime()) < time.Duration(cacheTTL)*time.Second {
+			return string(cached), nil
+		}
+	}
+
+	fragment, err := downloadRemoteInstruction(ctx, cfg)
+	if err != nil {
+		if cacheErr == nil {
+			logger.Warn("session", fmt.Sprintf("Failed to fetch remote instruction %s, using cached copy: %v", cfg.URL, err))
+			return string(cached), nil
+		}
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cacheFile), 0755); err != nil {
+		logger.Warn("session", fmt.Sprintf("Failed to create remote instruction cache directory: %v", err))
+	} else if err := os.WriteFile(cacheFile, []byte(fragment), 0644); err != nil {
+		logger.Warn("session", fmt.Sprintf("Failed to cache remote instruction %s: %v", cfg.URL, err))
+	}
+	return fragment, nil
+}
+
+// downloadRemoteInstruction fetches the instruction fragment over HTTP
+func downloadRemoteInstruction(ctx context.Context, cfg *config.RemoteInstruction) (string, error) {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultRemoteInstructionTimeout
+	}
+	client := &http.Client{
+		Timeout: time.Duration(timeout) * time.Second,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cfg.URL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create remote instruction request: %w", err)
+	}
+	for key, value := range cfg.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch remote instruction: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("remote instruction returned status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteInstructionSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read remote instruction: %w", err)
+	}
+	if len(body) > maxRemoteInstructionSize {
+		return "", fmt.Errorf("remote instruction exceeds %d bytes", maxRemoteInstructionSize)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// remoteInstructionCacheFile returns the cache file path for the given URL
+func remoteInstructionCacheFile(url string) string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "."
+	}
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(homeDir, remoteInstructionCacheDir, hex.EncodeToString(sum[:])+".txt")
+}
+
+// appendInstruction appends a remote instruction fragment to the system prompt.
+// The fragment is escaped so it is kept literally when the prompt is rendered as a template.
+func appendInstruction(systemPrompt, fragment string) string {
+	fragment = strings.ReplaceAll(fragment, "{{", `{{"{{"}}`)
+	if systemPrompt == "" {
+		return fragment
+	}
+	return systemPrompt + "\n\n" + fragment
+}
@@ -0,0 +1,120 @@
+package chatbot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+)
+
+// newInstructionServer serves the given status and body and counts the requests it receives
+func newInstructionServer(t *testing.T, status int, body string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+// writeInstructionCache stores a cached fragment for url with the given age
+func writeInstructionCache(t *testing.T, url, fragment string, age time.Duration) {
+	t.Helper()
+	cacheFile := remoteInstructionCacheFile(url)
+	if err := os.MkdirAll(filepath.Dir(cacheFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cacheFile, []byte(fragment), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Now().Add(-age)
+	if err := os.Chtimes(cacheFile, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFetchRemoteInstructionFreshCache(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv, hits := newInstructionServer(t, http.StatusOK, "remote")
+	writeInstructionCache(t, srv.URL, "cached", time.Second)
+
+	got, err := fetchRemoteInstruction(context.Background(), &config.RemoteInstruction{URL: srv.URL, CacheTTL: 60})
+	if err != nil {
+		t.Fatalf("fetchRemoteInstruction() error = %v", err)
+	}
+	if got != "cached" {
+		t.Errorf("fetchRemoteInstruction() = %q, want the cached copy", got)
+	}
+	if hits.Load() != 0 {
+		t.Errorf("server was called %d times, want 0 for a fresh cache", hits.Load())
+	}
+}
+
+func TestFetchRemoteInstructionStaleCacheFallback(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv, hits := newInstructionServer(t, http.StatusInternalServerError, "boom")
+	writeInstructionCache(t, srv.URL, "stale", time.Hour)
+
+	got, err := fetchRemoteInstruction(context.Background(), &config.RemoteInstruction{URL: srv.URL, CacheTTL: 60})
+	if err != nil {
+		t.Fatalf("fetchRemoteInstruction() error = %v", err)
+	}
+	if got != "stale" {
+		t.Errorf("fetchRemoteInstruction() = %q, want the stale cached copy", got)
+	}
+	if hits.Load() != 1 {
+		t.Errorf("server was called %d times, want 1 for an expired cache", hits.Load())
+	}
+}
+
+func TestFetchRemoteInstructionRefreshesCache(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv, _ := newInstructionServer(t, http.StatusOK, "  remote\n")
+
+	got, err := fetchRemoteInstruction(context.Background(), &config.RemoteInstruction{URL: srv.URL})
+	if err != nil {
+		t.Fatalf("fetchRemoteInstruction() error = %v", err)
+	}
+	if got != "remote" {
+		t.Errorf("fetchRemoteInstruction() = %q, want %q", got, "remote")
+	}
+	cached, err := os.ReadFile(remoteInstructionCacheFile(srv.URL))
+	if err != nil {
+		t.Fatalf("fragment was not cached: %v", err)
+	}
+	if string(cached) != "remote" {
+		t.Errorf("cached fragment = %q, want %q", cached, "remote")
+	}
+}
+
+func TestFetchRemoteInstructionNon2xx(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv, _ := newInstructionServer(t, http.StatusNotFound, "not found")
+
+	_, err := fetchRemoteInstruction(context.Background(), &config.RemoteInstruction{URL: srv.URL})
+	if err == nil {
+		t.Fatal("fetchRemoteInstruction() error = nil, want an error for status 404")
+	}
+	if !strings.Contains(err.Error(), "404") {
+		t.Errorf("error = %v, want it to mention the status", err)
+	}
+}
+
+func TestFetchRemoteInstructionTooLarge(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv, _ := newInstructionServer(t, http.StatusOK, strings.Repeat("a", maxRemoteInstructionSize+1))
+
+	if _, err := fetchRemoteInstruction(context.Background(), &config.RemoteInstruction{URL: srv.URL}); err == nil {
+		t.Fatal("fetchRemoteInstruction() error = nil, want an error for an oversized fragment")
+	}
+}
@@ -0,0 +1,142 @@
+package chatbot
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/Arvintian/chat-agent/pkg/mcp"
+)
+
+// jsonApprovalDenied is the reason given to the model for tool calls that need approval,
+// since nobody can approve them while the output is consumed by a program
+const jsonApprovalDenied = "tool calls that require approval are not available in JSON output mode"
+
+// JSONChatHandler writes the events of a turn to a writer as newline-delimited JSON, using
+// the same {"type": ..., "payload": ...} messages as the WebSocket protocol. It is meant for
+// non-interactive use: tool calls that require approval are denied and clarifying
+// questions are answered with an empty answer. The complete event carries the assembled
+// text of the final response.
+type JSONChatHandler struct {
+	mu       sync.Mutex
+	encoder  *json.Encoder
+	response strings.Builder
+}
+
+// NewJSONChatHandler creates a handler writing events to w
+func NewJSONChatHandler(w io.Writer) *JSONChatHandler {
+	return &JSONChatHandler{encoder: json.NewEncoder(w)}
+}
+
+// send writes a single event
+func (h *JSONChatHandler) send(msgType string, payload interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sendLocked(msgType, payload)
+}
+
+func (h *JSONChatHandler) sendLocked(msgType string, payload interface{}) {
+	data, _ := json.Marshal(payload)
+	h.encoder.Encode(WSMessage{Type: msgType, Payload: data})
+}
+
+func (h *JSONChatHandler) SendChunk(content string, first, last bool, contentType string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if contentType == "response" {
+		h.response.WriteString(content)
+	}
+	h.sendLocked("chunk", map[string]interface{}{
+		"content":      content,
+		"first":        first,
+		"last":         last,
+		"content_type": contentType,
+	})
+}
+
+func (h *JSONChatHandler) SendToolCall(name string, arguments string, id string, streaming bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// Text before a tool call is not part of the final response
+	h.response.Reset()
+	h.sendLocked("tool_call", map[string]interface{}{
+		"name":      name,
+		"arguments": arguments,
+		"id":        id,
+		"streaming": streaming,
+	})
+}
+
+func (h *JSONChatHandler) SendThinking(status bool) {
+	h.send("thinking", map[string]interface{}{"status": status})
+}
+
+func (h *JSONChatHandler) SendComplete(message string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sendLocked("complete", map[string]interface{}{
+		"message":  message,
+		"response": h.response.String(),
+	})
+	h.response.Reset()
+}
+
+func (h *JSONChatHandler) SendError(err string) {
+	h.send("error", map[string]string{"error": err})
+}
+
+// SendErrorCode reports an error with its code
+func (h *JSONChatHandler) SendErrorCode(message, code string) {
+	h.send("error", map[string]string{"error": message, "code": code})
+}
+
+// SendContentFiltered reports a response blocked by the provider's content filter
+func (h *JSONChatHandler) SendContentFiltered(message string) {
+	h.send("content_filtered", map[string]string{"message": message})
+}
+
+// SendModelFallback reports a model call answered by a fallback model
+func (h *JSONChatHandler) SendModelFallback(from, to, reason string) {
+	h.send("model_fallback", map[string]string{"from": from, "to": to, "reason": reason})
+}
+
+// SendUsage reports the tokens used by the turn
+func (h *JSONChatHandler) SendUsage(turn, session TokenUsage) {
+	h.send("usage", map[string]TokenUsage{
+		"turn":    turn,
+		"session": session,
+	})
+}
+
+// SendApprovalRequest reports the approval request and denies all targets
+func (h *JSONChatHandler) SendApprovalRequest(targets []ApprovalTarget) (ApprovalResultMap, error) {
+	targetList := make([]map[string]interface{}, len(targets))
+	results := make(ApprovalResultMap, len(targets))
+	for i, t := range targets {
+		targetList[i] = map[string]interface{}{
+			"id":      t.ID,
+			"tool":    t.ToolName,
+			"details": t.ArgumentsInfo,
+		}
+		if t.Preview != "" {
+			targetList[i]["preview"] = t.Preview
+		}
+		reason := jsonApprovalDenied
+		results[t.ID] = &mcp.ApprovalResult{Approved: false, DisapproveReason: &reason}
+	}
+	h.send("approval_request", map[string]interface{}{
+		"targets": targetList,
+		"denied":  jsonApprovalDenied,
+	})
+	return results, nil
+}
+
+// SendMessageCount is a no-op, the message count is not useful outside a chat UI
+func (h *JSONChatHandler) SendMessageCount() {}
+
+// SendQuestion reports the question and answers it with an empty answer
+func (h *JSONChatHandler) SendQuestion(question string) (string, error) {
+	h.send("question", map[string]string{"question": question})
+	return "", nil
+}
@@ -0,0 +1,53 @@
+package chatbot
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONChatHandlerEvents(t *testing.T) {
+	var out bytes.Buffer
+	h := NewJSONChatHandler(&out)
+
+	h.SendChunk("Let me check.", true, false, "response")
+	h.SendToolCall("cmd", `{"command":"ls"}`, "call-1", false)
+	h.SendChunk("thinking", true, false, "thinking")
+	h.SendChunk("Two ", true, false, "response")
+	h.SendChunk("files.", false, false, "response")
+	h.SendChunk("", false, true, "response")
+	results, err := h.SendApprovalRequest([]ApprovalTarget{{ID: "1", ToolName: "cmd"}})
+	if err != nil {
+		t.Fatalf("SendApprovalRequest() error = %v", err)
+	}
+	if results["1"] == nil || results["1"].Approved {
+		t.Errorf("approval result = %+v, want denied", results["1"])
+	}
+	h.SendComplete("")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	var types []string
+	var complete struct {
+		Response string `json:"response"`
+	}
+	for _, line := range lines {
+		var msg WSMessage
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			t.Fatalf("line %q is not a JSON event: %v", line, err)
+		}
+		types = append(types, msg.Type)
+		if msg.Type == "complete" {
+			if err := json.Unmarshal(msg.Payload, &complete); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	want := "chunk,tool_call,chunk,chunk,chunk,chunk,approval_request,complete"
+	if got := strings.Join(types, ","); got != want {
+		t.Errorf("event types = %s, want %s", got, want)
+	}
+	if complete.Response != "Two files." {
+		t.Errorf("complete response = %q, want the text after the last tool call", complete.Response)
+	}
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/Arvintian/chat-agent/pkg/mcp"
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+)
+
+// maxAuditResultLength is the length of the result summary of an audit record
+const maxAuditResultLength = 500
+
+// AuditRecord is the record of a tool call the model made
+type AuditRecord struct {
+	Time      time.Time `json:"time"`
+	Session   string    `json:"session"`
+	Chat      string    `json:"chat"`
+	Tool      string    `json:"tool"`
+	Arguments string    `json:"arguments"`
+	// Approval is how the call was approved, one of the mcp.Approval decisions
+	Approval       string `json:"approval"`
+	ApprovalReason string `json:"approvalReason,omitempty"`
+	// Result is the start of the tool result, Error the error the call failed with
+	Result string `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// AuditWriter stores audit records
+type AuditWriter interface {
+	WriteAudit(record AuditRecord)
+}
+
+// AuditToolCalls writes a record of every tool call of a session, with its approval
+// decision and a summary of its result. Calls waiting for approval are recorded once
+// the approval is resolved.
+type AuditToolCalls struct {
+	*adk.BaseChatModelAgentMiddleware
+	writer  AuditWriter
+	session string
+	chat    string
+}
+
+// NewAuditToolCalls creates the middleware writing the records of the tool calls of a session to writer
+func NewAuditToolCalls(writer AuditWriter, session, chat string) *AuditToolCalls {
+	return &AuditToolCalls{
+		BaseChatModelAgentMiddleware: &adk.BaseChatModelAgentMiddleware{},
+		writer:                       writer,
+		session:                      session,
+		chat:                         chat,
+	}
+}
+
+func (a *AuditToolCalls) WrapInvokableToolCall(ctx context.Context, endpoint adk.InvokableToolCallEndpoint, tCtx *adk.ToolContext) (adk.InvokableToolCallEndpoint, error) {
+	return func(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+		decision := &mcp.ApprovalDecision{Decision: mcp.ApprovalNotRequired}
+		result, err := endpoint(mcp.WithApprovalDecision(ctx, decision), argumentsInJSON, opts...)
+		if _, interrupted := compose.IsInterruptRerunError(err); interrupted {
+			return result, err
+		}
+		record := AuditRecord{
+			Time:           time.Now(),
+			Session:        a.session,
+			Chat:           a.chat,
+			Tool:           tCtx.Name,
+			Arguments:      argumentsInJSON,
+			Approval:       decision.Decision,
+			ApprovalReason: decision.Reason,
+			Result:         summarizeResult(result),
+		}
+		if err != nil {
+			record.Error = err.Error()
+		}
+		a.writer.WriteAudit(record)
+		return result, err
+	}, nil
+}
+
+// summarizeResult returns the start of a tool result
+func summarizeResult(result string) string {
+	runes := []rune(result)
+	if len(runes) <= maxAuditResultLength {
+		return result
+	}
+	return string(runes[:maxAuditResultLength]) + "..."
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Arvintian/chat-agent/pkg/mcp"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+type memoryAuditWriter struct {
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+func (w *memoryAuditWriter) WriteAudit(record AuditRecord) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.records = append(w.records, record)
+}
+
+func newAuditRunner(t *testing.T, writer AuditWriter, echo tool.BaseTool) *adk.Runner {
+	t.Helper()
+	ctx := context.Background()
+	agent, err := adk.NewChatModelAgent(ctx, &adk.ChatModelAgentConfig{
+		Name:        "audit",
+		Description: "audit",
+		Instruction: "audit",
+		Model:       &planModel{},
+		ToolsConfig: adk.ToolsConfig{ToolsNodeConfig: compose.ToolsNodeConfig{Tools: []tool.BaseTool{echo}}},
+		Handlers:    []adk.ChatModelAgentMiddleware{NewAuditToolCalls(writer, "s1", "coder")},
+	})
+	if err != nil {
+		t.Fatalf("NewChatModelAgent() error = %v", err)
+	}
+	return adk.NewRunner(ctx, adk.RunnerConfig{
+		Agent:           agent,
+		EnableStreaming: true,
+		CheckPointStore: &memoryCheckPoints{m: make(map[string][]byte)},
+	})
+}
+
+func TestAuditToolCallsRecordsApprovalDecisions(t *testing.T) {
+	ctx := context.Background()
+	writer := &memoryAuditWriter{}
+	runs := 0
+	runner := newAuditRunner(t, writer, mcp.InvokableApprovableTool{InvokableTool: echoTool{runs: &runs}})
+
+	intCtx, _ := runUntilInterrupt(t, runner.Run(ctx, []adk.Message{schema.UserMessage("hi")}, adk.WithCheckPointID("approve")))
+	if intCtx == nil || len(writer.records) != 0 {
+		t.Fatalf("interrupt = %v, records = %+v, want no record while the call waits for approval", intCtx, writer.records)
+	}
+	reason := "not now"
+	events, err := runner.ResumeWithParams(ctx, "approve", &adk.ResumeParams{Targets: map[string]any{intCtx.ID: &mcp.ApprovalResult{DisapproveReason: &reason}}})
+	if err != nil {
+		t.Fatalf("ResumeWithParams() error = %v", err)
+	}
+	runUntilInterrupt(t, events)
+
+	if len(writer.records) != 1 {
+		t.Fatalf("records = %+v, want one record", writer.records)
+	}
+	record := writer.records[0]
+	if record.Session != "s1" || record.Chat != "coder" || record.Tool != "echo" || record.Arguments != `{"x":1}` {
+		t.Errorf("record = %+v, want the echo call of the session", record)
+	}
+	if record.Approval != mcp.ApprovalDenied || record.ApprovalReason != reason || !strings.Contains(record.Result, "disapproved") || runs != 0 {
+		t.Errorf("record = %+v, runs = %d, want the denied call", record, runs)
+	}
+}
+
+func TestAuditToolCallsRecordsCallsWithoutApproval(t *testing.T) {
+	writer := &memoryAuditWriter{}
+	runs := 0
+	runner := newAuditRunner(t, writer, echoTool{runs: &runs})
+
+	runUntilInterrupt(t, runner.Run(context.Background(), []adk.Message{schema.UserMessage("hi")}))
+	if len(writer.records) != 1 {
+		t.Fatalf("records = %+v, want one record", writer.records)
+	}
+	if record := writer.records[0]; record.Approval != mcp.ApprovalNotRequired || record.Result != `{"x":1}` || record.Error != "" {
+		t.Errorf("record = %+v, want the call run without approval", record)
+	}
+}
+
+func TestSummarizeResult(t *testing.T) {
+	long := strings.Repeat("é", maxAuditResultLength+1)
+	if got := summarizeResult(long); got != strings.Repeat("é", maxAuditResultLength)+"..." {
+		t.Errorf("summarizeResult() = %q, want the result cut at %d characters", got, maxAuditResultLength)
+	}
+}
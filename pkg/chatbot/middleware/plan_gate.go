@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// PlanInstruction is appended to the system prompt of chats in plan mode
+const PlanInstruction = "Plan mode is enabled: before you call any tool, reply with a numbered plan of the steps " +
+	"and tool calls you intend to make, then call the tools of the first step. No tool runs until the user " +
+	"approves the plan; if the user rejects it, do not repeat it unchanged."
+
+// planSegment is the address segment the tool calls run in below the gate
+const planSegment compose.AddressSegmentType = "plan"
+
+// PlanInfo is the interrupt info of a tool call held until the plan of the turn is approved
+type PlanInfo struct {
+	ToolName        string
+	ArgumentsInJSON string
+	ToolCallID      string
+}
+
+// PlanGate holds the first tool calls of a turn until the user approves the plan. It is
+// armed at the start of a turn; while armed every tool call interrupts with a PlanInfo,
+// once approved the tool calls of the turn run as usual. A gate that is never armed,
+// e.g. in the interactive chat, lets all calls through.
+type PlanGate struct {
+	*adk.BaseChatModelAgentMiddleware
+
+	mu    sync.Mutex
+	armed bool
+}
+
+// NewPlanGate creates a disarmed gate
+func NewPlanGate() *PlanGate {
+	return &PlanGate{BaseChatModelAgentMiddleware: &adk.BaseChatModelAgentMiddleware{}}
+}
+
+// Arm holds the next tool calls until Approve is called
+func (g *PlanGate) Arm() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.armed = true
+}
+
+// Approve lets the tool calls through for the rest of the turn
+func (g *PlanGate) Approve() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.armed = false
+}
+
+// Armed reports whether the tool calls are held
+func (g *PlanGate) Armed() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.armed
+}
+
+// WrapInvokableToolCall interrupts the tool calls while the gate is armed. The tools run
+// one address segment below the gate, so the interrupt of the plan is not mistaken for
+// their own: a tool asking for approval still asks once the plan is approved.
+func (g *PlanGate) WrapInvokableToolCall(ctx context.Context, endpoint adk.InvokableToolCallEndpoint, tCtx *adk.ToolContext) (adk.InvokableToolCallEndpoint, error) {
+	return func(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+		if !g.Armed() {
+			return endpoint(compose.AppendAddressSegment(ctx, planSegment, tCtx.CallID), argumentsInJSON, opts...)
+		}
+		if wasInterrupted, _, storedArguments := compose.GetInterruptState[string](ctx); wasInterrupted {
+			argumentsInJSON = storedArguments
+		}
+		return "", compose.StatefulInterrupt(ctx, &PlanInfo{
+			ToolName:        tCtx.Name,
+			ArgumentsInJSON: argumentsInJSON,
+			ToolCallID:      tCtx.CallID,
+		}, argumentsInJSON)
+	}, nil
+}
+
+func init() {
+	schema.Register[*PlanInfo]()
+}
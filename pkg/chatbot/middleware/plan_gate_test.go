@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/Arvintian/chat-agent/pkg/mcp"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// memoryCheckPoints keeps the checkpoints of the runner in memory
+type memoryCheckPoints struct {
+	mu sync.Mutex
+	m  map[string][]byte
+}
+
+func (s *memoryCheckPoints) Get(ctx context.Context, id string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.m[id]
+	return v, ok, nil
+}
+
+func (s *memoryCheckPoints) Set(ctx context.Context, id string, b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[id] = b
+	return nil
+}
+
+// planModel calls the echo tool, then answers with the tool result
+type planModel struct{}
+
+func (m *planModel) Generate(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	if last := in[len(in)-1]; last.Role == schema.Tool {
+		return schema.AssistantMessage("done: "+last.Content, nil), nil
+	}
+	return schema.AssistantMessage("1. echo x", []schema.ToolCall{{ID: "call-1", Function: schema.FunctionCall{Name: "echo", Arguments: `{"x":1}`}}}), nil
+}
+
+func (m *planModel) Stream(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	msg, err := m.Generate(ctx, in, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return schema.StreamReaderFromArray([]*schema.Message{msg}), nil
+}
+
+func (m *planModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+type echoTool struct{ runs *int }
+
+func (e echoTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{Name: "echo", Desc: "echo the arguments"}, nil
+}
+
+func (e echoTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	*e.runs++
+	return argumentsInJSON, nil
+}
+
+// runUntilInterrupt reads the events of a run, returning the first interrupt context and
+// the content of the last message
+func runUntilInterrupt(t *testing.T, events *adk.AsyncIterator[*adk.AgentEvent]) (*adk.InterruptCtx, string) {
+	t.Helper()
+	var content string
+	for {
+		event, ok := events.Next()
+		if !ok {
+			return nil, content
+		}
+		if event.Err != nil {
+			t.Fatalf("run error = %v", event.Err)
+		}
+		if event.Action != nil && event.Action.Interrupted != nil {
+			return event.Action.Interrupted.InterruptContexts[0], content
+		}
+		if event.Output != nil && event.Output.MessageOutput != nil {
+			if msg, _ := event.Output.MessageOutput.GetMessage(); msg != nil {
+				content = msg.Content
+			}
+		}
+	}
+}
+
+func newPlanRunner(t *testing.T, gate *PlanGate, runs *int) *adk.Runner {
+	t.Helper()
+	ctx := context.Background()
+	agent, err := adk.NewChatModelAgent(ctx, &adk.ChatModelAgentConfig{
+		Name:        "plan",
+		Description: "plan",
+		Instruction: "plan",
+		Model:       &planModel{},
+		ToolsConfig: adk.ToolsConfig{ToolsNodeConfig: compose.ToolsNodeConfig{
+			Tools: []tool.BaseTool{mcp.InvokableApprovableTool{InvokableTool: echoTool{runs: runs}}},
+		}},
+		Handlers: []adk.ChatModelAgentMiddleware{gate},
+	})
+	if err != nil {
+		t.Fatalf("NewChatModelAgent() error = %v", err)
+	}
+	return adk.NewRunner(ctx, adk.RunnerConfig{
+		Agent:           agent,
+		EnableStreaming: true,
+		CheckPointStore: &memoryCheckPoints{m: make(map[string][]byte)},
+	})
+}
+
+func TestPlanGateHoldsToolCallsUntilApproved(t *testing.T) {
+	ctx := context.Background()
+	gate := NewPlanGate()
+	runs := 0
+	runner := newPlanRunner(t, gate, &runs)
+
+	gate.Arm()
+	intCtx, _ := runUntilInterrupt(t, runner.Run(ctx, []adk.Message{schema.UserMessage("hi")}, adk.WithCheckPointID("web")))
+	info, ok := intCtx.Info.(*PlanInfo)
+	if !ok || info.ToolName != "echo" || info.ArgumentsInJSON != `{"x":1}` || info.ToolCallID != "call-1" {
+		t.Fatalf("interrupt info = %#v, want the echo call held by the plan", intCtx.Info)
+	}
+
+	// Approving the plan lets the call through to its own approval
+	gate.Approve()
+	events, err := runner.ResumeWithParams(ctx, "web", &adk.ResumeParams{Targets: map[string]any{intCtx.ID: &mcp.ApprovalResult{Approved: true}}})
+	if err != nil {
+		t.Fatalf("ResumeWithParams() error = %v", err)
+	}
+	intCtx, _ = runUntilInterrupt(t, events)
+	if intCtx == nil {
+		t.Fatalf("tool ran %d times without asking for its approval", runs)
+	}
+	if _, ok := intCtx.Info.(*mcp.ApprovalInfo); !ok {
+		t.Fatalf("interrupt info = %#v, want the approval of the tool", intCtx.Info)
+	}
+
+	events, err = runner.ResumeWithParams(ctx, "web", &adk.ResumeParams{Targets: map[string]any{intCtx.ID: &mcp.ApprovalResult{Approved: true}}})
+	if err != nil {
+		t.Fatalf("ResumeWithParams() error = %v", err)
+	}
+	intCtx, content := runUntilInterrupt(t, events)
+	if intCtx != nil || runs != 1 || content != `done: {"x":1}` {
+		t.Errorf("after approval: interrupt = %v, runs = %d, content = %q, want the tool to run once", intCtx, runs, content)
+	}
+}
+
+func TestPlanGateDisarmedLetsCallsThrough(t *testing.T) {
+	ctx := context.Background()
+	runs := 0
+	runner := newPlanRunner(t, NewPlanGate(), &runs)
+
+	intCtx, _ := runUntilInterrupt(t, runner.Run(ctx, []adk.Message{schema.UserMessage("hi")}, adk.WithCheckPointID("local")))
+	if _, ok := intCtx.Info.(*mcp.ApprovalInfo); !ok {
+		t.Fatalf("interrupt info = %#v, want the approval of the tool", intCtx.Info)
+	}
+	events, err := runner.ResumeWithParams(ctx, "local", &adk.ResumeParams{Targets: map[string]any{intCtx.ID: &mcp.ApprovalResult{Approved: true}}})
+	if err != nil {
+		t.Fatalf("ResumeWithParams() error = %v", err)
+	}
+	if intCtx, _ := runUntilInterrupt(t, events); intCtx != nil || runs != 1 {
+		t.Errorf("after approval: interrupt = %v, runs = %d, want the tool to run once", intCtx, runs)
+	}
+}
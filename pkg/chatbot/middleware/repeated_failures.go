@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// DefaultFailureMarkers are the texts that mark a tool result as failed, for tools that
+// report failures in their result instead of returning an error
+var DefaultFailureMarkers = []string{
+	"EXIT ERROR:",
+	"failed to parse arguments",
+	"failed to call mcp tool",
+	InvalidArgumentsMarker,
+}
+
+// failedCall is the last failure of a tool called with the same arguments
+type failedCall struct {
+	failure string
+	count   int
+}
+
+// RepeatedFailureGuard breaks retry loops within a turn. A tool call with the same
+// arguments that already failed maxRepeats times with the same failure is not run
+// again; the model gets a message asking it to change its approach instead.
+type RepeatedFailureGuard struct {
+	*adk.BaseChatModelAgentMiddleware
+	maxRepeats int
+	markers    []string
+
+	mu    sync.Mutex
+	calls map[string]*failedCall
+}
+
+// NewRepeatedFailureGuard creates the guard. Results containing one of the markers
+// count as failures in addition to tool errors.
+func NewRepeatedFailureGuard(maxRepeats int, markers []string) *RepeatedFailureGuard {
+	return &RepeatedFailureGuard{
+		BaseChatModelAgentMiddleware: &adk.BaseChatModelAgentMiddleware{},
+		maxRepeats:                   maxRepeats,
+		markers:                      markers,
+		calls:                        make(map[string]*failedCall),
+	}
+}
+
+// BeforeAgent forgets the failures of the previous turn
+func (g *RepeatedFailureGuard) BeforeAgent(ctx context.Context, runCtx *adk.ChatModelAgentContext) (context.Context, *adk.ChatModelAgentContext, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	clear(g.calls)
+	return ctx, runCtx, nil
+}
+
+func (g *RepeatedFailureGuard) WrapInvokableToolCall(ctx context.Context, endpoint adk.InvokableToolCallEndpoint, tCtx *adk.ToolContext) (adk.InvokableToolCallEndpoint, error) {
+	return func(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+		key := tCtx.Name + "\x00" + argumentsInJSON
+		if failure, count := g.lastFailure(key); count >= g.maxRepeats {
+			return fmt.Sprintf("Tool %s was not run: this exact call already failed %d times in this turn with: %s\nDo not repeat it. Change the arguments or try a different approach.", tCtx.Name, count, failure), nil
+		}
+
+		result, err := endpoint(ctx, argumentsInJSON, opts...)
+		if err != nil {
+			g.record(key, err.Error())
+		} else if g.failed(result) {
+			g.record(key, result)
+		} else {
+			g.forget(key)
+		}
+		return result, err
+	}, nil
+}
+
+// failed reports whether a tool result contains a failure marker
+func (g *RepeatedFailureGuard) failed(result string) bool {
+	for _, marker := range g.markers {
+		if strings.Contains(result, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *RepeatedFailureGuard) lastFailure(key string) (string, int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if call, ok := g.calls[key]; ok {
+		return call.failure, call.count
+	}
+	return "", 0
+}
+
+// record counts a failure; a different failure for the same call starts counting again
+func (g *RepeatedFailureGuard) record(key, failure string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if call, ok := g.calls[key]; ok && call.failure == failure {
+		call.count++
+		return
+	}
+	g.calls[key] = &failedCall{failure: failure, count: 1}
+}
+
+func (g *RepeatedFailureGuard) forget(key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.calls, key)
+}
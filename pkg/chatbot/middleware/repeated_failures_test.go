@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// countingEndpoint returns the result and error for every call and counts the calls
+func countingEndpoint(calls *int, result string, err error) adk.InvokableToolCallEndpoint {
+	return func(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+		*calls++
+		return result, err
+	}
+}
+
+func TestRepeatedFailureGuardShortCircuits(t *testing.T) {
+	ctx := context.Background()
+	guard := NewRepeatedFailureGuard(2, DefaultFailureMarkers)
+	calls := 0
+	endpoint, _ := guard.WrapInvokableToolCall(ctx, countingEndpoint(&calls, "STDERR:\nlss: not found\nEXIT ERROR: exit status 127", nil), &adk.ToolContext{Name: "cmd"})
+
+	for range 2 {
+		if _, err := endpoint(ctx, `{"command":"lss"}`); err != nil {
+			t.Fatalf("endpoint() error = %v", err)
+		}
+	}
+	result, err := endpoint(ctx, `{"command":"lss"}`)
+	if err != nil {
+		t.Fatalf("endpoint() error = %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("tool ran %d times, want 2", calls)
+	}
+	if !strings.Contains(result, "different approach") || !strings.Contains(result, "exit status 127") {
+		t.Errorf("result = %q, want a nudge with the last failure", result)
+	}
+
+	// Other arguments are not affected
+	if _, err := endpoint(ctx, `{"command":"ls"}`); err != nil || calls != 3 {
+		t.Errorf("call with other arguments: calls = %d, err = %v, want the tool to run", calls, err)
+	}
+
+	// A new turn starts counting again
+	guard.BeforeAgent(ctx, &adk.ChatModelAgentContext{})
+	if _, err := endpoint(ctx, `{"command":"lss"}`); err != nil || calls != 4 {
+		t.Errorf("call in a new turn: calls = %d, err = %v, want the tool to run", calls, err)
+	}
+}
+
+func TestRepeatedFailureGuardCountsErrors(t *testing.T) {
+	ctx := context.Background()
+	guard := NewRepeatedFailureGuard(1, nil)
+	calls := 0
+	endpoint, _ := guard.WrapInvokableToolCall(ctx, countingEndpoint(&calls, "", errors.New("permission denied")), &adk.ToolContext{Name: "write_file"})
+
+	if _, err := endpoint(ctx, `{}`); err == nil {
+		t.Fatal("endpoint() error = nil, want the tool error")
+	}
+	result, err := endpoint(ctx, `{}`)
+	if err != nil || calls != 1 || !strings.Contains(result, "permission denied") {
+		t.Errorf("second call = %q, %v after %d runs, want a short-circuit", result, err, calls)
+	}
+}
+
+func TestRepeatedFailureGuardIgnoresSuccess(t *testing.T) {
+	ctx := context.Background()
+	guard := NewRepeatedFailureGuard(1, DefaultFailureMarkers)
+	calls := 0
+	endpoint, _ := guard.WrapInvokableToolCall(ctx, countingEndpoint(&calls, "STDOUT:\nok", nil), &adk.ToolContext{Name: "cmd"})
+
+	for range 3 {
+		endpoint(ctx, `{"command":"echo ok"}`)
+	}
+	if calls != 3 {
+		t.Errorf("tool ran %d times, want 3", calls)
+	}
+}
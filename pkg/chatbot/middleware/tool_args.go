@@ -0,0 +1,219 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/eino-contrib/jsonschema"
+)
+
+// InvalidArgumentsMarker starts the result of a tool call rejected for its arguments
+const InvalidArgumentsMarker = "Invalid arguments for tool"
+
+// maxArgumentProblems caps the problems listed for a single tool call
+const maxArgumentProblems = 10
+
+// ToolArgumentValidator checks the arguments of each tool call against the JSON schema
+// of the tool's parameters before the tool runs. A call with invalid arguments is not
+// run: the model gets the list of problems so it can correct the call, instead of an
+// error from deep inside the tool. Only type, enum, required, properties,
+// additionalProperties and items are checked, other keywords are left to the tool.
+type ToolArgumentValidator struct {
+	*adk.BaseChatModelAgentMiddleware
+	schemas map[string]*jsonschema.Schema
+}
+
+// NewToolArgumentValidator creates a validator for the tools. Tools without parameters
+// are not validated.
+func NewToolArgumentValidator(tools []*schema.ToolInfo) (*ToolArgumentValidator, error) {
+	schemas := make(map[string]*jsonschema.Schema, len(tools))
+	for _, info := range tools {
+		params, err := info.ParamsOneOf.ToJSONSchema()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the parameters of tool %s: %w", info.Name, err)
+		}
+		if params != nil {
+			schemas[info.Name] = params
+		}
+	}
+	return &ToolArgumentValidator{
+		BaseChatModelAgentMiddleware: &adk.BaseChatModelAgentMiddleware{},
+		schemas:                      schemas,
+	}, nil
+}
+
+func (v *ToolArgumentValidator) WrapInvokableToolCall(ctx context.Context, endpoint adk.InvokableToolCallEndpoint, tCtx *adk.ToolContext) (adk.InvokableToolCallEndpoint, error) {
+	params, ok := v.schemas[tCtx.Name]
+	if !ok {
+		return endpoint, nil
+	}
+	return func(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+		if problems := ValidateToolArguments(params, argumentsInJSON); len(problems) > 0 {
+			return fmt.Sprintf("%s %s, the tool was not run:\n- %s\nFix the arguments and call the tool again.",
+				InvalidArgumentsMarker, tCtx.Name, strings.Join(problems, "\n- ")), nil
+		}
+		return endpoint(ctx, argumentsInJSON, opts...)
+	}, nil
+}
+
+// ValidateToolArguments returns the problems of the arguments against the schema of the
+// parameters, none when they are valid. Empty arguments are taken as an empty object.
+func ValidateToolArguments(params *jsonschema.Schema, argumentsInJSON string) []string {
+	if strings.TrimSpace(argumentsInJSON) == "" {
+		argumentsInJSON = "{}"
+	}
+	decoder := json.NewDecoder(strings.NewReader(argumentsInJSON))
+	decoder.UseNumber()
+	var args any
+	if err := decoder.Decode(&args); err != nil {
+		return []string{fmt.Sprintf("arguments are not valid JSON: %v", err)}
+	}
+	if decoder.More() {
+		return []string{"arguments are not valid JSON: unexpected data after the arguments object"}
+	}
+	var problems []string
+	validateValue(params, args, "", &problems)
+	if len(problems) > maxArgumentProblems {
+		problems = append(problems[:maxArgumentProblems], fmt.Sprintf("and %d more problems", len(problems)-maxArgumentProblems))
+	}
+	return problems
+}
+
+// validateValue appends the problems of value against s to problems, path locates value
+// in the arguments
+func validateValue(s *jsonschema.Schema, value any, path string, problems *[]string) {
+	if s == nil {
+		return
+	}
+	if reflect.DeepEqual(s, jsonschema.FalseSchema) {
+		*problems = append(*problems, fmt.Sprintf("%s: is not allowed", describePath(path)))
+		return
+	}
+	types := s.TypeEnhanced
+	if s.Type != "" {
+		types = []string{s.Type}
+	}
+	if len(types) > 0 && !slices.ContainsFunc(types, func(t string) bool { return hasType(value, t) }) {
+		*problems = append(*problems, fmt.Sprintf("%s: expected %s, got %s", describePath(path), strings.Join(types, " or "), typeOf(value)))
+		return
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return equalJSON(e, value) }) {
+		*problems = append(*problems, fmt.Sprintf("%s: must be one of %s", describePath(path), formatEnum(s.Enum)))
+	}
+
+	switch value := value.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := value[name]; !ok {
+				*problems = append(*problems, fmt.Sprintf("%s: is required", describePath(joinPath(path, name))))
+			}
+		}
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			var property *jsonschema.Schema
+			if s.Properties != nil {
+				property, _ = s.Properties.Get(name)
+			}
+			if property == nil {
+				property = s.AdditionalProperties
+			}
+			validateValue(property, value[name], joinPath(path, name), problems)
+		}
+	case []any:
+		for i, item := range value {
+			validateValue(s.Items, item, fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+	}
+}
+
+// hasType reports whether value is of the JSON schema type t. Unknown types match.
+func hasType(value any, t string) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := n.Float64()
+		return err == nil && f == math.Trunc(f)
+	}
+	return true
+}
+
+// typeOf returns the JSON type of a decoded value
+func typeOf(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	}
+	return "null"
+}
+
+// equalJSON reports whether an enum value equals a decoded value
+func equalJSON(enum, value any) bool {
+	a, errA := json.Marshal(enum)
+	b, errB := json.Marshal(value)
+	return errA == nil && errB == nil && bytes.Equal(a, b)
+}
+
+func formatEnum(enum []any) string {
+	values := make([]string, len(enum))
+	for i, e := range enum {
+		b, _ := json.Marshal(e)
+		values[i] = string(b)
+	}
+	return strings.Join(values, ", ")
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func describePath(path string) string {
+	if path == "" {
+		return "arguments"
+	}
+	return path
+}
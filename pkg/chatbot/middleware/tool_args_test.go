@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/schema"
+	"github.com/eino-contrib/jsonschema"
+)
+
+func TestToolArgumentValidatorRejectsInvalidCalls(t *testing.T) {
+	ctx := context.Background()
+	validator, err := NewToolArgumentValidator([]*schema.ToolInfo{
+		{
+			Name: "read_file",
+			ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+				"path":  {Type: schema.String, Required: true},
+				"limit": {Type: schema.Integer},
+				"mode":  {Type: schema.String, Enum: []string{"text", "binary"}},
+			}),
+		},
+		{Name: "no_params"},
+	})
+	if err != nil {
+		t.Fatalf("NewToolArgumentValidator() error = %v", err)
+	}
+
+	calls := 0
+	endpoint, _ := validator.WrapInvokableToolCall(ctx, countingEndpoint(&calls, "ok", nil), &adk.ToolContext{Name: "read_file"})
+	tests := []struct {
+		args string
+		want []string
+	}{
+		{`{"limit":10}`, []string{"path: is required"}},
+		{``, []string{"path: is required"}},
+		{`{"path":3,"limit":1.5,"mode":"hex"}`, []string{"path: expected string, got number", "limit: expected integer, got number", `mode: must be one of "text", "binary"`}},
+		{`{"path":`, []string{"arguments are not valid JSON"}},
+		{`["a.txt"]`, []string{"arguments: expected object, got array"}},
+	}
+	for _, tt := range tests {
+		result, err := endpoint(ctx, tt.args)
+		if err != nil {
+			t.Fatalf("endpoint(%q) error = %v", tt.args, err)
+		}
+		if !strings.HasPrefix(result, InvalidArgumentsMarker+" read_file") {
+			t.Errorf("endpoint(%q) = %q, want the invalid arguments marker", tt.args, result)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(result, want) {
+				t.Errorf("endpoint(%q) = %q, want it to contain %q", tt.args, result, want)
+			}
+		}
+	}
+	if calls != 0 {
+		t.Errorf("tool ran %d times with invalid arguments", calls)
+	}
+
+	if result, _ := endpoint(ctx, `{"path":"a.txt","limit":2.0,"mode":"text"}`); result != "ok" || calls != 1 {
+		t.Errorf("endpoint() = %q after %d calls, want the valid call to run", result, calls)
+	}
+	unchecked, _ := validator.WrapInvokableToolCall(ctx, countingEndpoint(&calls, "ok", nil), &adk.ToolContext{Name: "no_params"})
+	if result, _ := unchecked(ctx, `not json`); result != "ok" {
+		t.Errorf("endpoint() = %q, want tools without parameters to run unchecked", result)
+	}
+}
+
+func TestValidateToolArgumentsNested(t *testing.T) {
+	var params jsonschema.Schema
+	err := params.UnmarshalJSON([]byte(`{
+		"type": "object",
+		"required": ["edits"],
+		"additionalProperties": false,
+		"properties": {
+			"edits": {"type": "array", "items": {"type": "object", "required": ["oldText"], "properties": {"oldText": {"type": "string"}}}},
+			"dryRun": {"type": ["boolean", "null"]}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("UnmarshalJSON() error = %v", err)
+	}
+
+	problems := ValidateToolArguments(&params, `{"edits":[{"oldText":"a"},{"newText":"b"}],"dryRun":null,"extra":1}`)
+	want := []string{"edits[1].oldText: is required", "extra: is not allowed"}
+	if strings.Join(problems, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ValidateToolArguments() = %q, want %q", problems, want)
+	}
+	if problems := ValidateToolArguments(&params, `{"edits":[],"dryRun":true}`); len(problems) != 0 {
+		t.Errorf("ValidateToolArguments() = %q for valid arguments", problems)
+	}
+}
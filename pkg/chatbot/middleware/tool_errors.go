@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"context"
+
+	chaterrors "github.com/Arvintian/chat-agent/pkg/errors"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// ClassifyToolErrors puts the errors of the tool calls in the ErrToolExecution class of
+// pkg/errors, so a turn ended by a failing tool can be told apart from a provider
+// failure. Interrupts, like approval requests, are returned as is.
+type ClassifyToolErrors struct {
+	*adk.BaseChatModelAgentMiddleware
+}
+
+// NewClassifyToolErrors creates the middleware
+func NewClassifyToolErrors() *ClassifyToolErrors {
+	return &ClassifyToolErrors{BaseChatModelAgentMiddleware: &adk.BaseChatModelAgentMiddleware{}}
+}
+
+func (m *ClassifyToolErrors) WrapInvokableToolCall(ctx context.Context, endpoint adk.InvokableToolCallEndpoint, tCtx *adk.ToolContext) (adk.InvokableToolCallEndpoint, error) {
+	return func(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+		result, err := endpoint(ctx, argumentsInJSON, opts...)
+		return result, classifyToolError(err)
+	}, nil
+}
+
+func (m *ClassifyToolErrors) WrapStreamableToolCall(ctx context.Context, endpoint adk.StreamableToolCallEndpoint, tCtx *adk.ToolContext) (adk.StreamableToolCallEndpoint, error) {
+	return func(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (*schema.StreamReader[string], error) {
+		stream, err := endpoint(ctx, argumentsInJSON, opts...)
+		if err != nil {
+			return nil, classifyToolError(err)
+		}
+		return schema.StreamReaderWithConvert(stream, func(chunk string) (string, error) {
+			return chunk, nil
+		}, schema.WithErrWrapper(classifyToolError)), nil
+	}, nil
+}
+
+// classifyToolError puts the error of a tool call in ErrToolExecution
+func classifyToolError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, interrupted := compose.IsInterruptRerunError(err); interrupted {
+		return err
+	}
+	return chaterrors.Wrap(chaterrors.ErrToolExecution, err)
+}
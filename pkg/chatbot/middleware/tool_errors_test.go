@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	chaterrors "github.com/Arvintian/chat-agent/pkg/errors"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/compose"
+)
+
+func TestClassifyToolErrors(t *testing.T) {
+	ctx := context.Background()
+	m := NewClassifyToolErrors()
+	calls := 0
+
+	failing, _ := m.WrapInvokableToolCall(ctx, countingEndpoint(&calls, "", errors.New("permission denied")), &adk.ToolContext{Name: "write_file"})
+	if _, err := failing(ctx, `{}`); !errors.Is(err, chaterrors.ErrToolExecution) || err.Error() != "permission denied" {
+		t.Errorf("error = %v, want the tool error in ErrToolExecution", err)
+	}
+
+	ok, _ := m.WrapInvokableToolCall(ctx, countingEndpoint(&calls, "done", nil), &adk.ToolContext{Name: "write_file"})
+	if result, err := ok(ctx, `{}`); result != "done" || err != nil {
+		t.Errorf("result = %q, %v, want the tool result", result, err)
+	}
+
+	interrupt := compose.Interrupt(ctx, "approve?")
+	interrupted, _ := m.WrapInvokableToolCall(ctx, countingEndpoint(&calls, "", interrupt), &adk.ToolContext{Name: "cmd"})
+	if _, err := interrupted(ctx, `{}`); err != interrupt {
+		t.Errorf("error = %v, want the interrupt as is", err)
+	}
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/schema"
+)
+
+// ToolFilter hides disabled tools from the model. Tools are disabled and enabled at
+// runtime and the change applies from the next turn on: the set of disabled tools is
+// taken when a turn starts. Disabled tools stay registered with the agent, so a call the
+// model already made, for example one waiting for approval, still completes.
+type ToolFilter struct {
+	*adk.BaseChatModelAgentMiddleware
+
+	mu       sync.Mutex
+	disabled map[string]bool
+	turn     map[string]bool // disabled tools of the running turn
+}
+
+// NewToolFilter creates a filter with all tools enabled
+func NewToolFilter() *ToolFilter {
+	return &ToolFilter{
+		BaseChatModelAgentMiddleware: &adk.BaseChatModelAgentMiddleware{},
+		disabled:                     make(map[string]bool),
+	}
+}
+
+// SetEnabled enables or disables a tool by name for the following turns
+func (f *ToolFilter) SetEnabled(name string, enabled bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if enabled {
+		delete(f.disabled, name)
+	} else {
+		f.disabled[name] = true
+	}
+}
+
+// Enabled reports whether a tool is enabled for the following turns
+func (f *ToolFilter) Enabled(name string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return !f.disabled[name]
+}
+
+// Disabled returns the names of the disabled tools
+func (f *ToolFilter) Disabled() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	names := make([]string, 0, len(f.disabled))
+	for name := range f.disabled {
+		names = append(names, name)
+	}
+	return names
+}
+
+// BeforeAgent takes the disabled tools for the turn
+func (f *ToolFilter) BeforeAgent(ctx context.Context, runCtx *adk.ChatModelAgentContext) (context.Context, *adk.ChatModelAgentContext, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.turn = make(map[string]bool, len(f.disabled))
+	for name := range f.disabled {
+		f.turn[name] = true
+	}
+	return ctx, runCtx, nil
+}
+
+// BeforeModelRewriteState removes the disabled tools from the tools sent to the model
+func (f *ToolFilter) BeforeModelRewriteState(ctx context.Context, state *adk.ChatModelAgentState, mc *adk.ModelContext) (context.Context, *adk.ChatModelAgentState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.turn) == 0 {
+		return ctx, state, nil
+	}
+	toolInfos := make([]*schema.ToolInfo, 0, len(state.ToolInfos))
+	for _, info := range state.ToolInfos {
+		if !f.turn[info.Name] {
+			toolInfos = append(toolInfos, info)
+		}
+	}
+	state.ToolInfos = toolInfos
+	return ctx, state, nil
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/schema"
+)
+
+// modelToolNames runs the filter before a model call and returns the names of the tools sent
+func modelToolNames(t *testing.T, f *ToolFilter, names ...string) []string {
+	t.Helper()
+	state := &adk.ChatModelAgentState{}
+	for _, name := range names {
+		state.ToolInfos = append(state.ToolInfos, &schema.ToolInfo{Name: name})
+	}
+	_, state, err := f.BeforeModelRewriteState(context.Background(), state, &adk.ModelContext{})
+	if err != nil {
+		t.Fatalf("BeforeModelRewriteState() error = %v", err)
+	}
+	var sent []string
+	for _, info := range state.ToolInfos {
+		sent = append(sent, info.Name)
+	}
+	return sent
+}
+
+func TestToolFilterAppliesFromNextTurn(t *testing.T) {
+	ctx := context.Background()
+	f := NewToolFilter()
+	f.BeforeAgent(ctx, &adk.ChatModelAgentContext{})
+
+	// Disabled while a turn is running: the running turn keeps the tool
+	f.SetEnabled("cmd", false)
+	if f.Enabled("cmd") {
+		t.Error("Enabled(cmd) = true after disabling it")
+	}
+	if sent := modelToolNames(t, f, "cmd", "read_file"); len(sent) != 2 {
+		t.Errorf("tools sent in the running turn = %v, want both", sent)
+	}
+
+	f.BeforeAgent(ctx, &adk.ChatModelAgentContext{})
+	if sent := modelToolNames(t, f, "cmd", "read_file"); len(sent) != 1 || sent[0] != "read_file" {
+		t.Errorf("tools sent in the next turn = %v, want [read_file]", sent)
+	}
+
+	f.SetEnabled("cmd", true)
+	f.BeforeAgent(ctx, &adk.ChatModelAgentContext{})
+	if sent := modelToolNames(t, f, "cmd", "read_file"); len(sent) != 2 {
+		t.Errorf("tools sent after enabling = %v, want both", sent)
+	}
+	if disabled := f.Disabled(); len(disabled) != 0 {
+		t.Errorf("Disabled() = %v, want none", disabled)
+	}
+}
@@ -1,11 +1,20 @@
 package chatbot
 
 import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/cloudwego/eino/schema"
 )
 
+// MaxAttachmentSize is the size of the largest file LoadFile attaches
+const MaxAttachmentSize = 20 * 1024 * 1024
+
 // FileData represents file data for multimodal messages
 type FileData struct {
 	URL      string
@@ -14,6 +23,39 @@ type FileData struct {
 	FileSize int64
 }
 
+// LoadFile reads a local file as an attachment with a data URL. The MIME type is inferred
+// from the extension, or from the content when the extension is unknown.
+func LoadFile(path string) (FileData, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return FileData{}, err
+	}
+	if info.IsDir() {
+		return FileData{}, fmt.Errorf("%s is a directory", path)
+	}
+	if info.Size() > MaxAttachmentSize {
+		return FileData{}, fmt.Errorf("%s is %d bytes, larger than the limit of %d bytes", path, info.Size(), MaxAttachmentSize)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return FileData{}, err
+	}
+	mimeType := mime.TypeByExtension(strings.ToLower(filepath.Ext(path)))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	// Parameters such as the charset don't belong in the data URL of the model input
+	if mediaType, _, err := mime.ParseMediaType(mimeType); err == nil {
+		mimeType = mediaType
+	}
+	return FileData{
+		URL:      "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data),
+		Type:     mimeType,
+		Name:     filepath.Base(path),
+		FileSize: int64(len(data)),
+	}, nil
+}
+
 // createMultimodalUserMessage creates a user message with text and files
 // Supports image, audio, and video file types.
 // Other file types (PDF, Word, Excel, etc.) are skipped as they require text extraction.
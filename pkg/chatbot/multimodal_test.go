@@ -0,0 +1,45 @@
+package chatbot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	png := filepath.Join(dir, "chart.png")
+	if err := os.WriteFile(png, []byte("\x89PNG\r\n\x1a\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	noExt := filepath.Join(dir, "notes")
+	if err := os.WriteFile(noExt, []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := LoadFile(png)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if file.Type != "image/png" || file.Name != "chart.png" || file.FileSize != 8 ||
+		file.URL != "data:image/png;base64,iVBORw0KGgo=" {
+		t.Errorf("LoadFile() = %+v, want a png data URL", file)
+	}
+
+	// Without an extension the type is detected from the content, without the charset
+	file, err = LoadFile(noExt)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if file.Type != "text/plain" || !strings.HasPrefix(file.URL, "data:text/plain;base64,aGVsbG8=") {
+		t.Errorf("LoadFile() = %+v, want a text/plain data URL", file)
+	}
+
+	if _, err := LoadFile(dir); err == nil {
+		t.Error("LoadFile() of a directory succeeded, want an error")
+	}
+	if _, err := LoadFile(filepath.Join(dir, "missing")); err == nil {
+		t.Error("LoadFile() of a missing file succeeded, want an error")
+	}
+}
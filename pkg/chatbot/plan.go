@@ -0,0 +1,73 @@
+package chatbot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Arvintian/chat-agent/pkg/chatbot/middleware"
+	"github.com/Arvintian/chat-agent/pkg/mcp"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// PlanToolName is the tool name of the approval target asking to approve the plan of a turn
+const PlanToolName = "plan"
+
+// plannedCall is a tool call held by the plan gate
+type plannedCall struct {
+	id   string
+	info *middleware.PlanInfo
+}
+
+// planSummary is the details of the plan approval target: the plan given by the model
+// and the tool calls it starts with
+type planSummary struct {
+	Plan      string            `json:"plan"`
+	ToolCalls []plannedToolCall `json:"tool_calls"`
+}
+
+type plannedToolCall struct {
+	Tool      string `json:"tool"`
+	Arguments string `json:"arguments"`
+}
+
+// requestPlanApproval asks the handler to approve the plan with a single approval target
+// summarizing it, instead of one per tool call
+func (cb *ChatBot) requestPlanApproval(plan string, calls []plannedCall) (*mcp.ApprovalResult, error) {
+	summary := planSummary{Plan: plan, ToolCalls: make([]plannedToolCall, 0, len(calls))}
+	for _, call := range calls {
+		summary.ToolCalls = append(summary.ToolCalls, plannedToolCall{Tool: call.info.ToolName, Arguments: call.info.ArgumentsInJSON})
+	}
+	details, err := json.Marshal(summary)
+	if err != nil {
+		return nil, err
+	}
+	target := ApprovalTarget{ID: calls[0].id, ToolName: PlanToolName, ArgumentsInfo: string(details)}
+	results, err := cb.handler.SendApprovalRequest([]ApprovalTarget{target})
+	if err != nil {
+		return nil, err
+	}
+	result := results[target.ID]
+	if result == nil {
+		return nil, fmt.Errorf("no answer to the plan approval")
+	}
+	return result, nil
+}
+
+// rejectPlan ends the turn without running the held tool calls. The plan stays in the
+// context with a result for each call, so the next turn starts from a valid conversation.
+func (cb *ChatBot) rejectPlan(ctx context.Context, calls []plannedCall, result *mcp.ApprovalResult) error {
+	reason := "the user rejected the plan"
+	if result.DisapproveReason != nil && *result.DisapproveReason != "" {
+		reason += ": " + *result.DisapproveReason
+	}
+	for _, call := range calls {
+		content := fmt.Sprintf("tool '%s' was not run, %s", call.info.ToolName, reason)
+		cb.manager.AddMessage(ctx, schema.ToolMessage(content, call.info.ToolCallID, schema.WithToolName(call.info.ToolName)))
+	}
+	cb.awaitingInterrupt = false
+	cb.handler.SendComplete("Plan rejected, no tool was run")
+	cb.handler.SendMessageCount()
+	return nil
+}
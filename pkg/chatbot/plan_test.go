@@ -0,0 +1,121 @@
+package chatbot
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Arvintian/chat-agent/pkg/chatbot/middleware"
+	"github.com/Arvintian/chat-agent/pkg/manager"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// planningModel gives a plan with a tool call, then answers with the tool result
+type planningModel struct{}
+
+func (m *planningModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	if last := input[len(input)-1]; last.Role == schema.Tool {
+		return schema.AssistantMessage("done: "+last.Content, nil), nil
+	}
+	return schema.AssistantMessage("1. list the files", []schema.ToolCall{{
+		ID:       "call-1",
+		Function: schema.FunctionCall{Name: "ls", Arguments: `{"dir":"."}`},
+	}}), nil
+}
+
+func (m *planningModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	msg, err := m.Generate(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return schema.StreamReaderFromArray([]*schema.Message{msg}), nil
+}
+
+func (m *planningModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+// lsTool counts its runs
+type lsTool struct{ runs *int }
+
+func (l lsTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{Name: "ls", Desc: "list the files"}, nil
+}
+
+func (l lsTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	*l.runs++
+	return "a.txt", nil
+}
+
+// newPlanChatBot returns a chatbot in plan mode, running the ls tool without approval
+func newPlanChatBot(t *testing.T, runs *int) (*ChatBot, *manager.Manager) {
+	t.Helper()
+	ctx := context.Background()
+	gate := middleware.NewPlanGate()
+	agent, err := adk.NewChatModelAgent(ctx, &adk.ChatModelAgentConfig{
+		Name:        "test",
+		Description: "test agent",
+		Model:       &planningModel{},
+		ToolsConfig: adk.ToolsConfig{ToolsNodeConfig: compose.ToolsNodeConfig{
+			Tools: []tool.BaseTool{lsTool{runs: runs}},
+		}},
+		Handlers: []adk.ChatModelAgentMiddleware{gate},
+	})
+	if err != nil {
+		t.Fatalf("NewChatModelAgent() error = %v", err)
+	}
+	m := manager.NewManager(manager.Config{MaxMessageRounds: 10})
+	cb := NewChatBot(ctx, agent, m, nil, nil)
+	cb.SetPlanGate(gate)
+	return &cb, m
+}
+
+func TestPlanModeApproved(t *testing.T) {
+	runs := 0
+	cb, _ := newPlanChatBot(t, &runs)
+	handler := NewBufferedChatHandler(true)
+	cb.SetHandler(handler)
+
+	if err := cb.StreamChatWithHandler(context.Background(), "clean up", nil); err != nil {
+		t.Fatalf("StreamChatWithHandler() error = %v", err)
+	}
+	if runs != 1 {
+		t.Errorf("tool ran %d times, want 1 after the plan was approved", runs)
+	}
+	if result := handler.Result(); result.Response != "done: a.txt" {
+		t.Errorf("Response = %q, want the answer after the tool call", result.Response)
+	}
+}
+
+func TestPlanModeRejected(t *testing.T) {
+	runs := 0
+	cb, m := newPlanChatBot(t, &runs)
+	cb.SetHandler(NewBufferedChatHandler(false))
+
+	if err := cb.StreamChatWithHandler(context.Background(), "clean up", nil); err != nil {
+		t.Fatalf("StreamChatWithHandler() error = %v, want the turn to end cleanly", err)
+	}
+	if runs != 0 {
+		t.Errorf("tool ran %d times, want none after the plan was rejected", runs)
+	}
+	if cb.CanResume() {
+		t.Error("CanResume() = true after the plan was rejected")
+	}
+
+	// The plan is kept with a result for its tool call
+	messages := m.GetMessages()
+	if len(messages) != 3 {
+		t.Fatalf("context has %d messages, want the user message, the plan and the tool result", len(messages))
+	}
+	if plan := messages[1]; plan.Content != "1. list the files" || len(plan.ToolCalls) != 1 {
+		t.Errorf("plan message = %+v, want the plan with its tool call", plan)
+	}
+	if result := messages[2]; result.Role != schema.Tool || result.ToolCallID != "call-1" || !strings.Contains(result.Content, "rejected the plan") {
+		t.Errorf("tool message = %+v, want the rejection of the call", result)
+	}
+}
@@ -0,0 +1,216 @@
+package chatbot
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+	"github.com/Arvintian/chat-agent/pkg/utils"
+)
+
+const (
+	defaultProjectTreeMaxDepth   = 3
+	defaultProjectTreeMaxEntries = 200
+	defaultProjectTreeCacheTTL   = 300 // in seconds
+)
+
+// projectTreeCache keeps rendered trees so that new sessions do not walk the directory again
+var projectTreeCache = struct {
+	sync.Mutex
+	entries map[string]cachedProjectTree
+}{entries: make(map[string]cachedProjectTree)}
+
+type cachedProjectTree struct {
+	tree    string
+	created time.Time
+}
+
+// ignoreRule is a single pattern of a .gitignore file
+type ignoreRule struct {
+	base     string // directory of the .gitignore file, relative to the tree root
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool // the pattern is matched against the path instead of the name
+}
+
+// projectTree returns the compact tree of the configured directory for the system prompt
+func projectTree(cfg *config.ProjectTree) (string, error) {
+	dir := cfg.Dir
+	if dir == "" {
+		dir = "."
+	}
+	dir, err := utils.ExpandPath(dir)
+	if err != nil {
+		return "", err
+	}
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	maxDepth := cfg.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultProjectTreeMaxDepth
+	}
+	maxEntries := cfg.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = defaultProjectTreeMaxEntries
+	}
+	cacheTTL := cfg.CacheTTL
+	if cacheTTL <= 0 {
+		cacheTTL = defaultProjectTreeCacheTTL
+	}
+
+	key := fmt.Sprintf("%s|%d|%d", dir, maxDepth, maxEntries)
+	projectTreeCache.Lock()
+	defer projectTreeCache.Unlock()
+	if cached, ok := projectTreeCache.entries[key]; ok && time.Since(cached.created) < time.Duration(cacheTTL)*time.Second {
+		return cached.tree, nil
+	}
+
+	tree, err := buildProjectTree(dir, maxDepth, maxEntries)
+	if err != nil {
+		return "", err
+	}
+	projectTreeCache.entries[key] = cachedProjectTree{tree: tree, created: time.Now()}
+	return tree, nil
+}
+
+// buildProjectTree lists the directory up to maxDepth levels and maxEntries entries,
+// skipping .git and everything ignored by .gitignore files
+func buildProjectTree(dir string, maxDepth, maxEntries int) (string, error) {
+	if _, err := os.ReadDir(dir); err != nil {
+		return "", fmt.Errorf("failed to read project directory: %w", err)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Project layout of %s (directories end with /):\n", dir)
+	entries := 0
+	truncated := false
+
+	var walk func(rel string, depth int, rules []ignoreRule)
+	walk = func(rel string, depth int, rules []ignoreRule) {
+		current := filepath.Join(dir, filepath.FromSlash(rel))
+		if data, err := os.ReadFile(filepath.Join(current, ".gitignore")); err == nil {
+			rules = append(rules[:len(rules):len(rules)], parseGitignore(string(data), rel)...)
+		}
+		items, err := os.ReadDir(current)
+		if err != nil {
+			return
+		}
+		for _, item := range items {
+			if item.Name() == ".git" {
+				continue
+			}
+			itemRel := path.Join(rel, item.Name())
+			if isIgnored(rules, itemRel, item.IsDir()) {
+				continue
+			}
+			if entries >= maxEntries {
+				truncated = true
+				return
+			}
+			entries++
+			sb.WriteString(strings.Repeat("  ", depth))
+			sb.WriteString(item.Name())
+			if item.IsDir() {
+				sb.WriteString("/")
+			}
+			sb.WriteString("\n")
+			if item.IsDir() && depth+1 < maxDepth {
+				walk(itemRel, depth+1, rules)
+			}
+		}
+	}
+	walk("", 0, nil)
+
+	if truncated {
+		fmt.Fprintf(&sb, "... (truncated at %d entries)\n", maxEntries)
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+// parseGitignore parses the patterns of a .gitignore file located in the base directory
+func parseGitignore(data, base string) []ignoreRule {
+	var rules []ignoreRule
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// isIgnored reports whether the path, relative to the tree root, is ignored.
+// The last matching rule wins, so negated patterns can re-include paths.
+func isIgnored(rules []ignoreRule, rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		name := rel
+		if rule.base != "" {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			name = strings.TrimPrefix(rel, rule.base+"/")
+		}
+		var matched bool
+		if rule.anchored {
+			matched = matchSegments(strings.Split(rule.pattern, "/"), strings.Split(name, "/"))
+		} else {
+			matched, _ = path.Match(rule.pattern, path.Base(name))
+		}
+		if matched {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// matchSegments matches path segments against pattern segments, where ** matches
+// any number of segments
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
@@ -0,0 +1,87 @@
+package chatbot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+)
+
+// writeFiles creates the files, with parent directories, under dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBuildProjectTreeRespectsGitignore(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".gitignore":           "*.log\n/build/\nvendor/\n!keep.log\n",
+		".git/HEAD":            "ref: refs/heads/main",
+		"main.go":              "",
+		"debug.log":            "",
+		"keep.log":             "",
+		"build/out":            "",
+		"vendor/lib.go":        "",
+		"pkg/app/app.go":       "",
+		"pkg/app/.gitignore":   "generated.go\n",
+		"pkg/app/generated.go": "",
+		"pkg/app/deep/x/y.go":  "",
+	})
+
+	tree, err := buildProjectTree(dir, 3, 100)
+	if err != nil {
+		t.Fatalf("buildProjectTree() error = %v", err)
+	}
+	for _, want := range []string{"main.go", "keep.log", "pkg/", "  app/", "    app.go", "    deep/"} {
+		if !strings.Contains(tree, "\n"+want+"\n") && !strings.HasSuffix(tree, "\n"+want) {
+			t.Errorf("tree is missing %q:\n%s", want, tree)
+		}
+	}
+	for _, unwanted := range []string{"HEAD", "debug.log", "build/", "vendor/", "generated.go", "x/"} {
+		if strings.Contains(tree, unwanted) {
+			t.Errorf("tree contains %q:\n%s", unwanted, tree)
+		}
+	}
+}
+
+func TestBuildProjectTreeTruncates(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a": "", "b": "", "c": "", "d/e": ""})
+
+	tree, err := buildProjectTree(dir, 3, 2)
+	if err != nil {
+		t.Fatalf("buildProjectTree() error = %v", err)
+	}
+	if strings.Contains(tree, "\nc\n") || !strings.HasSuffix(tree, "(truncated at 2 entries)") {
+		t.Errorf("tree = %q, want 2 entries and a truncation note", tree)
+	}
+}
+
+func TestProjectTreeIsCached(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"first.go": ""})
+	cfg := &config.ProjectTree{Dir: dir}
+
+	if _, err := projectTree(cfg); err != nil {
+		t.Fatalf("projectTree() error = %v", err)
+	}
+	writeFiles(t, dir, map[string]string{"second.go": ""})
+	tree, err := projectTree(cfg)
+	if err != nil {
+		t.Fatalf("projectTree() error = %v", err)
+	}
+	if strings.Contains(tree, "second.go") {
+		t.Errorf("tree = %q, want the cached listing", tree)
+	}
+}
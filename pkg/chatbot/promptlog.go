@@ -0,0 +1,114 @@
+package chatbot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Arvintian/chat-agent/pkg/chatbot/middleware"
+	"github.com/Arvintian/chat-agent/pkg/logger"
+	"github.com/cloudwego/eino/schema"
+)
+
+// PromptLog writes the exact model input of every model call, as built by GenModelInput,
+// to a dedicated file. Each line is a JSON record; secrets are redacted before writing.
+type PromptLog struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// promptLogRecord is a single line of the prompt log
+type promptLogRecord struct {
+	Time     time.Time         `json:"time"`
+	Chat     string            `json:"chat"`
+	Session  string            `json:"session"`
+	Messages []*schema.Message `json:"messages"`
+}
+
+var (
+	promptLogMu sync.RWMutex
+	promptLog   *PromptLog
+)
+
+// OpenPromptLog opens the prompt log file for appending, creating it if needed
+func OpenPromptLog(path string) (*PromptLog, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open prompt log: %w", err)
+	}
+	return &PromptLog{file: file}, nil
+}
+
+// SetPromptLog sets the prompt log used by chat sessions; nil disables prompt logging
+func SetPromptLog(log *PromptLog) {
+	promptLogMu.Lock()
+	defer promptLogMu.Unlock()
+	promptLog = log
+}
+
+// currentPromptLog returns the prompt log used by chat sessions, or nil
+func currentPromptLog() *PromptLog {
+	promptLogMu.RLock()
+	defer promptLogMu.RUnlock()
+	return promptLog
+}
+
+// Write appends the model input of a chat session to the log
+func (l *PromptLog) Write(chatName, sessionID string, messages []*schema.Message, redactor *middleware.Redactor) {
+	record := promptLogRecord{
+		Time:     time.Now(),
+		Chat:     chatName,
+		Session:  sessionID,
+		Messages: redactMessages(messages, redactor),
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		logger.Warn("chatbot", fmt.Sprintf("Failed to encode prompt log record: %v", err))
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		logger.Warn("chatbot", fmt.Sprintf("Failed to write prompt log: %v", err))
+	}
+}
+
+// Close closes the prompt log file
+func (l *PromptLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// redactMessages returns copies of the messages with secrets masked in their text,
+// reasoning and tool call arguments. The original messages are not modified.
+func redactMessages(messages []*schema.Message, redactor *middleware.Redactor) []*schema.Message {
+	if redactor == nil {
+		return messages
+	}
+	redacted := make([]*schema.Message, 0, len(messages))
+	for _, msg := range messages {
+		m := *msg
+		m.Content = redactor.Redact(m.Content)
+		m.ReasoningContent = redactor.Redact(m.ReasoningContent)
+		if len(m.ToolCalls) > 0 {
+			m.ToolCalls = make([]schema.ToolCall, len(msg.ToolCalls))
+			for i, tc := range msg.ToolCalls {
+				tc.Function.Arguments = redactor.Redact(tc.Function.Arguments)
+				m.ToolCalls[i] = tc
+			}
+		}
+		if len(m.UserInputMultiContent) > 0 {
+			m.UserInputMultiContent = make([]schema.MessageInputPart, len(msg.UserInputMultiContent))
+			for i, part := range msg.UserInputMultiContent {
+				part.Text = redactor.Redact(part.Text)
+				m.UserInputMultiContent[i] = part
+			}
+		}
+		redacted = append(redacted, &m)
+	}
+	return redacted
+}
@@ -0,0 +1,70 @@
+package chatbot
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Arvintian/chat-agent/pkg/chatbot/middleware"
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestPromptLogWritesRedactedRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompts.jsonl")
+	promptLog, err := OpenPromptLog(path)
+	if err != nil {
+		t.Fatalf("OpenPromptLog() error = %v", err)
+	}
+	redactor, err := middleware.NewRedactor(middleware.DefaultRedactionPatterns, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := "api_key=supersecretvalue"
+	toolCall := schema.ToolCall{ID: "call_1", Function: schema.FunctionCall{Name: "cmd", Arguments: `{"command":"curl -H ` + secret + `"}`}}
+	messages := []*schema.Message{
+		schema.SystemMessage("You are a test assistant."),
+		schema.UserMessage("my " + secret),
+		schema.AssistantMessage("", []schema.ToolCall{toolCall}),
+	}
+	promptLog.Write("default", "s1", messages, redactor)
+	promptLog.Write("default", "s1", messages[:1], redactor)
+	if err := promptLog.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The messages passed to the model are left untouched
+	if !strings.Contains(messages[1].Content, "supersecretvalue") || !strings.Contains(messages[2].ToolCalls[0].Function.Arguments, "supersecretvalue") {
+		t.Error("Write() modified the logged messages")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var records []promptLogRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), "supersecretvalue") {
+			t.Errorf("prompt log contains the secret: %s", scanner.Text())
+		}
+		var record promptLogRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid prompt log line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 {
+		t.Fatalf("prompt log has %d records, want 2", len(records))
+	}
+	if records[0].Chat != "default" || records[0].Session != "s1" || len(records[0].Messages) != 3 {
+		t.Errorf("first record = %+v, want 3 messages of chat default, session s1", records[0])
+	}
+	if records[0].Messages[0].Content != "You are a test assistant." {
+		t.Errorf("system prompt = %q, want it logged as is", records[0].Messages[0].Content)
+	}
+}
@@ -0,0 +1,93 @@
+package chatbot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+	"github.com/Arvintian/chat-agent/pkg/manager"
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// reasoningModel streams its reasoning, then its answer
+type reasoningModel struct{}
+
+func (m *reasoningModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	return &schema.Message{Role: schema.Assistant, Content: "Answer", ReasoningContent: "let me think"}, nil
+}
+
+func (m *reasoningModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return schema.StreamReaderFromArray([]*schema.Message{
+		{Role: schema.Assistant, ReasoningContent: "let me think"},
+		schema.AssistantMessage("Answer", nil),
+	}), nil
+}
+
+func (m *reasoningModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+// displayHandler records the chunks with their type and first flag, and the thinking states
+type displayHandler struct {
+	*recordingHandler
+}
+
+func (h *displayHandler) SendChunk(content string, first, last bool, contentType string) {
+	if content != "" {
+		h.record(fmt.Sprintf("%s:%s:%v", contentType, content, first))
+	}
+}
+
+func (h *displayHandler) SendThinking(status bool) {
+	h.record(fmt.Sprintf("thinking:%v", status))
+}
+
+func TestReasoningDisplay(t *testing.T) {
+	for _, tt := range []struct {
+		mode string
+		want []string
+	}{
+		{config.ReasoningDisplayShow, []string{"thinking:true", "thinking:let me think:true", "thinking:false", "response:Answer:false", "thinking:false"}},
+		{config.ReasoningDisplaySummary, []string{"thinking:true", "thinking:false", "response:Answer:true", "thinking:false"}},
+		{config.ReasoningDisplayHide, []string{"thinking:false", "response:Answer:true", "thinking:false"}},
+	} {
+		t.Run(tt.mode, func(t *testing.T) {
+			ctx := context.Background()
+			agent, err := adk.NewChatModelAgent(ctx, &adk.ChatModelAgentConfig{
+				Name:        "test",
+				Description: "test agent",
+				Model:       &reasoningModel{},
+			})
+			if err != nil {
+				t.Fatalf("NewChatModelAgent() error = %v", err)
+			}
+			m := manager.NewManager(manager.Config{MaxMessageRounds: 10})
+			cb := NewChatBot(ctx, agent, m, nil, nil)
+			handler := &displayHandler{recordingHandler: &recordingHandler{}}
+			cb.SetHandler(handler)
+			cb.SetReasoningDisplay(tt.mode)
+
+			if err := cb.StreamChatWithHandler(ctx, "hello", nil); err != nil {
+				t.Fatalf("StreamChatWithHandler() error = %v", err)
+			}
+			var got []string
+			for _, event := range handler.recorded() {
+				if event != "count" && event != "complete" {
+					got = append(got, event)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("events = %q, want %q", got, tt.want)
+			}
+
+			// Hidden reasoning is still kept in the context
+			messages := m.GetMessages()
+			if last := messages[len(messages)-1]; last.Content != "Answer" || last.ReasoningContent != "let me think" {
+				t.Errorf("last message = %+v, want the answer with its reasoning", last)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package chatbot
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/Arvintian/chat-agent/pkg/manager"
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// stoppableModel blocks its first stream until the context is cancelled and answers afterwards
+type stoppableModel struct {
+	mu    sync.Mutex
+	calls int
+	// inputs holds the messages of every call
+	inputs [][]*schema.Message
+	// started is closed when the first stream begins
+	started chan struct{}
+}
+
+func (m *stoppableModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	return schema.AssistantMessage("done", nil), nil
+}
+
+func (m *stoppableModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	m.mu.Lock()
+	m.calls++
+	first := m.calls == 1
+	m.inputs = append(m.inputs, input)
+	m.mu.Unlock()
+	if first {
+		close(m.started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return schema.StreamReaderFromArray([]*schema.Message{schema.AssistantMessage("done", nil)}), nil
+}
+
+func (m *stoppableModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+func TestResumeStoppedTurn(t *testing.T) {
+	ctx := context.Background()
+	chatModel := &stoppableModel{started: make(chan struct{})}
+	agent, err := adk.NewChatModelAgent(ctx, &adk.ChatModelAgentConfig{
+		Name:        "test",
+		Description: "test agent",
+		Model:       chatModel,
+	})
+	if err != nil {
+		t.Fatalf("NewChatModelAgent() error = %v", err)
+	}
+	m := manager.NewManager(manager.Config{MaxMessageRounds: 10})
+	cb := NewChatBot(ctx, agent, m, nil, nil)
+	handler := &recordingHandler{}
+	cb.SetHandler(handler)
+
+	if err := cb.ResumeWithHandler(ctx); err == nil {
+		t.Fatal("ResumeWithHandler() error = nil without a stopped turn")
+	}
+
+	turnCtx, cancel := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() {
+		done <- cb.StreamChatWithHandler(turnCtx, "hello", nil)
+	}()
+	<-chatModel.started
+	cancel()
+	<-done
+
+	if !cb.CanResume() {
+		t.Fatal("CanResume() = false after the turn was stopped")
+	}
+
+	if err := cb.ResumeWithHandler(ctx); err != nil {
+		t.Fatalf("ResumeWithHandler() error = %v", err)
+	}
+	if cb.CanResume() {
+		t.Error("CanResume() = true after the resumed turn completed")
+	}
+
+	// The resumed run continues from the stopped turn's user message without a new one
+	last := chatModel.inputs[len(chatModel.inputs)-1]
+	users := 0
+	for _, msg := range last {
+		if msg.Role == schema.User {
+			users++
+		}
+	}
+	if users != 1 {
+		t.Errorf("resumed run saw %d user messages, want 1", users)
+	}
+	messages := m.GetMessages()
+	if got := messages[len(messages)-1]; got.Role != schema.Assistant || got.Content != "done" {
+		t.Errorf("last message = %+v, want the resumed answer", got)
+	}
+}
+
+func TestDiscardStopped(t *testing.T) {
+	cb := &ChatBot{stopped: &stoppedTurn{}}
+	cb.DiscardStopped()
+	if cb.CanResume() {
+		t.Error("CanResume() = true after DiscardStopped")
+	}
+}
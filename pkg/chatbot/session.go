@@ -25,6 +25,7 @@ import (
 	"github.com/Arvintian/chat-agent/pkg/utils"
 
 	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
@@ -42,6 +43,18 @@ type ChatSession struct {
 	Manager         *manager.Manager
 	Tools           []tool.BaseTool
 	MCPClient       *mcp.Client
+	MCPInitErr      error                 // joined per-server errors of MCP servers that failed to initialize
+	Approvals       *ApprovalMemory       // tools approved for the rest of the session
+	PlanGate        *middleware.PlanGate  // holds the tool calls until the plan is approved, nil unless plan mode is on
+	Skills          *skillloader.Registry // skills of the chat, nil without a skill configuration
+	redactor        *middleware.Redactor
+	toolFilter      *middleware.ToolFilter
+	agentConfig     *adk.ChatModelAgentConfig // rebuilds the agent when the model is switched
+	initSystem      string                    // system prompt of the first round, before rendering
+	toolSchemas     []*schema.ToolInfo
+	modelOverride   string // model switched to at runtime, kept across reloads
+	thinking        *bool  // thinking turned on or off at runtime, kept across reloads
+	multimodal      bool   // the model reads attached documents itself
 	persistence     *store.PersistenceStore
 	cleanupRegistry *cleanupRegistry
 	hookManager     *hook.HookManager
@@ -76,13 +89,36 @@ func InitChatSession(ctx context.Context, cfg *config.Config, chatName string, s
 
 	// chatmodel
 	providerFactory := providers.NewFactory(cfg)
-	model, err := providerFactory.CreateChatModel(ctx, preset.Model)
+	model, err := providerFactory.CreateChatModelWithFallbacks(ctx, preset.Model, preset.Fallbacks)
 	if err != nil {
 		return nil, err
 	}
 
 	var tools []tool.BaseTool
-	systemPrompt, err := config.ResolveSystemPrompt(cfg, preset.System)
+	systemPrompt, err := config.ChatSystemPrompt(cfg, preset)
+	if err != nil {
+		return nil, err
+	}
+	if preset.RemoteInstruction != nil {
+		fragment, err := fetchRemoteInstruction(ctx, preset.RemoteInstruction)
+		if err != nil {
+			logger.Warn("session", fmt.Sprintf("Failed to load remote instruction for chat %s, using the local system prompt: %v", chatName, err))
+		} else if fragment != "" {
+			systemPrompt = appendInstruction(systemPrompt, fragment)
+		}
+	}
+	if preset.ProjectTree != nil {
+		tree, err := projectTree(preset.ProjectTree)
+		if err != nil {
+			logger.Warn("session", fmt.Sprintf("Failed to list the project tree for chat %s: %v", chatName, err))
+		} else {
+			systemPrompt = appendInstruction(systemPrompt, tree)
+		}
+	}
+	if preset.PlanMode {
+		systemPrompt = appendInstruction(systemPrompt, middleware.PlanInstruction)
+	}
+	systemPrompt, err = config.WrapSystemPrompt(cfg, systemPrompt)
 	if err != nil {
 		return nil, err
 	}
@@ -93,7 +129,11 @@ func InitChatSession(ctx context.Context, cfg *config.Config, chatName string, s
 		if !ok {
 			return nil, fmt.Errorf("tool config %s not found", builtinTool)
 		}
-		builtinToolList, err := builtintools.GetBuiltinTools(context.WithValue(ctx, "cleanup", cleanupRegistry), toolCfg.Category, toolCfg.Params)
+		params, err := config.ChatToolParams(preset, toolCfg)
+		if err != nil {
+			return nil, fmt.Errorf("chat %s: %w", chatName, err)
+		}
+		builtinToolList, err := builtintools.GetBuiltinTools(context.WithValue(ctx, "cleanup", cleanupRegistry), toolCfg.Category, params)
 		if err != nil {
 			return nil, err
 		}
@@ -103,6 +143,10 @@ func InitChatSession(ctx context.Context, cfg *config.Config, chatName string, s
 		} else if toolCfg.AutoApproval {
 			tools = append(tools, builtinToolList...)
 		} else {
+			rules, err := mcp.CompileApprovalRules(toolCfg.ApprovalRules)
+			if err != nil {
+				return nil, fmt.Errorf("tool config %s: %w", builtinTool, err)
+			}
 			for _, item := range builtinToolList {
 				info, err := item.Info(ctx)
 				if err != nil {
@@ -111,13 +155,14 @@ func InitChatSession(ctx context.Context, cfg *config.Config, chatName string, s
 				if slices.Contains(toolCfg.AutoApprovalTools, info.Name) {
 					tools = append(tools, item)
 				} else {
-					tools = append(tools, mcp.InvokableApprovableTool{InvokableTool: item.(tool.InvokableTool)})
+					tools = append(tools, mcp.InvokableApprovableTool{InvokableTool: item.(tool.InvokableTool), Rules: rules})
 				}
 			}
 		}
 	}
 
 	// skills
+	var skillRegistry *skillloader.Registry
 	if preset.Skill != nil {
 		skillDir, err := utils.ExpandPath(preset.Skill.Dir)
 		if err != nil {
@@ -129,6 +174,7 @@ func InitChatSession(ctx context.Context, cfg *config.Config, chatName string, s
 		if err := registry.Initialize(ctx); err != nil {
 			return nil, err
 		}
+		skillRegistry = registry
 		systemPrompt = skillmw.NewSkillsMiddleware(registry).InjectPrompt(systemPrompt)
 		skillstools := skilltools.NewSkillTools(registry)
 		if preset.Skill.Timeout <= 0 {
@@ -153,28 +199,22 @@ func InitChatSession(ctx context.Context, cfg *config.Config, chatName string, s
 
 	// mcp client - only initialize if MCP servers are configured
 	var mcpclient *mcp.Client
+	var mcpInitErr error
 	if len(preset.MCPServers) > 0 {
-		toolsChan, errChan := make(chan []tool.BaseTool, 1), make(chan error, 1)
-		go func() {
-			mcpclient = mcp.NewClient(cfg)
-			if err := mcpclient.InitializeForChat(ctx, preset); err != nil {
-				toolsChan <- nil
-				errChan <- err
-			}
-			mcptools := mcpclient.GetToolListForServers(preset.MCPServers)
-			toolsChan <- mcptools
-			errChan <- nil
-		}()
-		select {
-		case <-time.After(10 * time.Second):
-			return nil, fmt.Errorf("load mcp tools timeout")
-		case err := <-errChan:
-			if err != nil {
-				return nil, err
-			}
-			mcptools := <-toolsChan
-			tools = append(tools, mcptools...)
+		// Servers initialize concurrently with individual timeouts; a failing server
+		// only loses its own tools and is reported, the others remain available
+		mcpclient = mcp.NewClient(cfg)
+		if err := mcpclient.InitializeForChat(ctx, preset); err != nil {
+			mcpInitErr = err
+			logger.Warn("session", fmt.Sprintf("Some MCP servers of chat %s failed to initialize: %v", chatName, err))
 		}
+		tools = append(tools, mcpclient.GetToolListForServers(preset.MCPServers)...)
+	}
+
+	// description overrides apply to builtin, skill and MCP tools alike
+	tools, err = applyToolDescriptions(ctx, tools, cfg.Descriptions)
+	if err != nil {
+		return nil, err
 	}
 
 	var hookMgr *hook.HookManager
@@ -199,6 +239,10 @@ func InitChatSession(ctx context.Context, cfg *config.Config, chatName string, s
 		if err != nil {
 			return nil, err
 		}
+		initSystemPrompt, err = config.WrapSystemPrompt(cfg, initSystemPrompt)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// init agent
@@ -210,13 +254,78 @@ func InitChatSession(ctx context.Context, cfg *config.Config, chatName string, s
 	if preset.MaxRetries > 0 {
 		maxRetries = preset.MaxRetries
 	}
+	retryPolicy := utils.DefaultRetryPolicy()
+	if preset.MaxRetryWait > 0 {
+		retryPolicy.MaxTotalWait = time.Duration(preset.MaxRetryWait) * time.Second
+	}
+	retrier := utils.NewRetrier(retryPolicy)
 
 	// Build handlers for the agent
 	var agentHandlers []adk.ChatModelAgentMiddleware
 
+	// Record every tool call the model makes, including the calls the other handlers short-circuit
+	if auditLog := currentAuditLog(); auditLog != nil {
+		agentHandlers = append(agentHandlers, middleware.NewAuditToolCalls(auditLog, sessionID, chatName))
+	}
+
 	// Add initSystemPrompt middleware if an init system prompt is configured
 	if initSystemPrompt != "" {
-		agentHandlers = append(agentHandlers, middleware.NewInitSystemPrompt(initSystemPrompt, systemPrompt, renderSystemPrompt))
+		agentHandlers = append(agentHandlers, middleware.NewInitSystemPrompt(initSystemPrompt, systemPrompt, RenderSystemPrompt))
+	}
+
+	// Mask secrets in tool results if redaction is enabled
+	var redactor *middleware.Redactor
+	if cfg.Redaction != nil && cfg.Redaction.Enabled {
+		var patterns []string
+		if !cfg.Redaction.DisableDefaults {
+			patterns = append(patterns, middleware.DefaultRedactionPatterns...)
+		}
+		patterns = append(patterns, cfg.Redaction.Patterns...)
+		redactor, err = middleware.NewRedactor(patterns, cfg.Redaction.Replacement)
+		if err != nil {
+			return nil, err
+		}
+		agentHandlers = append(agentHandlers, middleware.NewRedactToolResults(redactor))
+	}
+
+	// Short-circuit tool calls that keep failing the same way within a turn
+	if preset.RepeatedFailures != nil {
+		maxRepeats := 2
+		if preset.RepeatedFailures.MaxRepeats > 0 {
+			maxRepeats = preset.RepeatedFailures.MaxRepeats
+		}
+		markers := append(slices.Clone(middleware.DefaultFailureMarkers), preset.RepeatedFailures.Markers...)
+		agentHandlers = append(agentHandlers, middleware.NewRepeatedFailureGuard(maxRepeats, markers))
+	}
+
+	// Reject tool calls whose arguments don't match the tool's parameters before they run
+	argumentValidator, err := middleware.NewToolArgumentValidator(toolSchemas)
+	if err != nil {
+		return nil, err
+	}
+	agentHandlers = append(agentHandlers, argumentValidator)
+
+	// Tell tool failures apart from provider failures in the errors of the turn
+	agentHandlers = append(agentHandlers, middleware.NewClassifyToolErrors())
+
+	// Hide the tools disabled at runtime from the model
+	toolFilter := middleware.NewToolFilter()
+	agentHandlers = append(agentHandlers, toolFilter)
+
+	// Hold the tool calls of a turn until its plan is approved
+	var planGate *middleware.PlanGate
+	if preset.PlanMode {
+		planGate = middleware.NewPlanGate()
+		agentHandlers = append(agentHandlers, planGate)
+	}
+
+	// The prompt log always masks secrets, using the default patterns unless redaction is configured
+	promptRedactor := redactor
+	if promptRedactor == nil {
+		promptRedactor, err = middleware.NewRedactor(middleware.DefaultRedactionPatterns, "")
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	agentConfig := &adk.ChatModelAgentConfig{
@@ -226,8 +335,15 @@ func InitChatSession(ctx context.Context, cfg *config.Config, chatName string, s
 		Model:       model,
 		MaxIterations: maxIterations,
 		ModelRetryConfig: &adk.ModelRetryConfig{
-			MaxRetries:  maxRetries,
-			IsRetryAble: utils.IsRetryAble,
+			MaxRetries: maxRetries,
+			ShouldRetry: func(ctx context.Context, retryCtx *adk.RetryContext) *adk.RetryDecision {
+				delay, ok := retrier.Next(ctx, retryCtx.RetryAttempt, retryCtx.Err)
+				if !ok {
+					return nil
+				}
+				logger.Warn("session", fmt.Sprintf("Model call failed: %v, retrying in %s", retryCtx.Err, delay))
+				return &adk.RetryDecision{Retry: true, Backoff: delay}
+			},
 		},
 		GenModelInput: func(ctx context.Context, instruction string, input *adk.AgentInput) ([]adk.Message, error) {
 			var inputMessages []*schema.Message
@@ -241,7 +357,7 @@ func InitChatSession(ctx context.Context, cfg *config.Config, chatName string, s
 			}
 			msgs := make([]adk.Message, 0, len(input.Messages)+1)
 
-			rendered, err := renderSystemPrompt(instruction)
+			rendered, err := RenderSystemPrompt(instruction)
 			if err != nil {
 				return nil, err
 			}
@@ -254,6 +370,9 @@ func InitChatSession(ctx context.Context, cfg *config.Config, chatName string, s
 				msgs = append(msgs, msg)
 			}
 			msgs = append([]adk.Message{sp}, msgs...)
+			if promptLog := currentPromptLog(); promptLog != nil {
+				promptLog.Write(chatName, sessionID, msgs, promptRedactor)
+			}
 			return msgs, nil
 		},
 		Handlers: agentHandlers,
@@ -273,44 +392,25 @@ func InitChatSession(ctx context.Context, cfg *config.Config, chatName string, s
 	}
 
 	// init manager
-	contextModel, err := providerFactory.CreateChatModel(ctx, preset.Model)
+	contextModel, err := newContextModel(ctx, providerFactory, preset.Model, preset.Fallbacks, toolSchemas)
 	if err != nil {
 		return nil, err
 	}
-	// Only bind tools to the context model if there are any, to avoid "no tools to bind" error
-	if len(toolSchemas) > 0 {
-		contextModel, err = contextModel.WithTools(toolSchemas)
-		if err != nil {
-			return nil, err
-		}
-	}
-	manager := manager.NewManager(preset.MaxMessageRounds)
+	manager := manager.NewManager(manager.Config{
+		MaxMessageRounds: preset.MaxMessageRounds,
+		MaxTokens:        preset.MaxContextTokens,
+	})
 	manager.SetChatModel(contextModel)
-	if preset.FullMessageRounds > 0 {
-		manager.SetFullMessageRounds(preset.FullMessageRounds)
-	}
+	applyManagerSettings(manager, preset)
 
 	// Only setup persistence callbacks and load messages if persistence is enabled
 	if contextPersistenceEnabled {
-		// Define persistence callback for later use
-		persistenceCallback := func(msg *schema.Message) error {
-			return persistence.SaveMessage(msg)
-		}
-
-		// Set compression complete callback for full overwrite when compression completes
-		compressionCompleteCallback := func(messages []*schema.Message) error {
-			return persistence.SaveMessagesOverwrite(messages)
-		}
-
 		// Load persisted messages if any (without triggering persistence callback)
 		persistedMessages, err := persistence.LoadMessages()
 		var loadedMessageCount int
 		if err != nil {
 			logger.Warn("chatbot", fmt.Sprintf("Failed to load persisted messages: %v", err))
 		} else if len(persistedMessages) > 0 {
-			// Temporarily set callback to nil to avoid re-saving loaded messages
-			manager.SetPersistenceCallback(nil)
-
 			// Restore messages from persistence and reconstruct rounds based on user messages
 			// Each user message indicates a new round, so we need to call IncRound before adding it
 			for i, msg := range persistedMessages {
@@ -322,22 +422,11 @@ func InitChatSession(ctx context.Context, cfg *config.Config, chatName string, s
 			}
 			loadedMessageCount = len(persistedMessages)
 
-			// Re-enable persistence callback after loading
-			manager.SetPersistenceCallback(persistenceCallback)
-
 			logger.Info("chatbot", fmt.Sprintf("Loaded %d messages from persistence for session %s", loadedMessageCount, sessionID))
-		} else {
-			// No persisted messages, just enable the callback for future messages
-			manager.SetPersistenceCallback(persistenceCallback)
 		}
-
-		// Set compression complete callback after initialization
-		manager.SetCompressionCompleteCallback(compressionCompleteCallback)
-	} else {
-		// Persistence is disabled, set nil callbacks
-		manager.SetPersistenceCallback(nil)
-		manager.SetCompressionCompleteCallback(nil)
 	}
+	// Enable the persistence callbacks only after loading, to avoid re-saving loaded messages
+	bindManagerPersistence(manager, persistence)
 
 	session := &ChatSession{
 		ID:              sessionID,
@@ -347,6 +436,16 @@ func InitChatSession(ctx context.Context, cfg *config.Config, chatName string, s
 		Manager:         manager,
 		Tools:           tools,
 		MCPClient:       mcpclient,
+		MCPInitErr:      mcpInitErr,
+		Approvals:       NewApprovalMemory(),
+		PlanGate:        planGate,
+		Skills:          skillRegistry,
+		redactor:        redactor,
+		toolFilter:      toolFilter,
+		agentConfig:     agentConfig,
+		initSystem:      initSystemPrompt,
+		toolSchemas:     toolSchemas,
+		multimodal:      cfg.Models[preset.Model].Multimodal,
 		persistence:     persistence,
 		cleanupRegistry: cleanupRegistry,
 		hookManager:     hookMgr,
@@ -355,6 +454,225 @@ func InitChatSession(ctx context.Context, cfg *config.Config, chatName string, s
 	return session, nil
 }
 
+// newContextModel creates the model the manager uses to compress the context
+func newContextModel(ctx context.Context, factory *providers.Factory, modelName string, fallbacks []string, toolSchemas []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	contextModel, err := factory.CreateChatModelWithFallbacks(ctx, modelName, fallbacks)
+	if err != nil {
+		return nil, err
+	}
+	// Only bind tools to the context model if there are any, to avoid "no tools to bind" error
+	if len(toolSchemas) > 0 {
+		return contextModel.WithTools(toolSchemas)
+	}
+	return contextModel, nil
+}
+
+// SwitchModel rebuilds the agent of the session with another model of the config. The
+// tools, system prompt, middlewares and conversation context are kept. The caller must
+// create a new ChatBot for the new agent.
+func (s *ChatSession) SwitchModel(ctx context.Context, cfg *config.Config, modelName string) error {
+	if err := s.rebuildModel(ctx, cfg, modelName, s.thinking); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.modelOverride = modelName
+	return nil
+}
+
+// SetThinking rebuilds the agent of the session with the thinking of its model turned on
+// or off, whatever the model config says. Like SwitchModel, everything else is kept and
+// the caller must create a new ChatBot for the new agent.
+func (s *ChatSession) SetThinking(ctx context.Context, cfg *config.Config, enabled bool) error {
+	return s.rebuildModel(ctx, cfg, s.Preset.Model, &enabled)
+}
+
+// Thinking reports whether the model of the session thinks: the setting of SetThinking,
+// or of the model config. A mixed model thinks when one of its models does.
+func (s *ChatSession) Thinking(cfg *config.Config) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.thinking != nil {
+		return *s.thinking
+	}
+	modelCfg := cfg.Models[s.Preset.Model]
+	if modelCfg.Thinking {
+		return true
+	}
+	for _, entry := range modelCfg.Mixed {
+		if entry.Thinking {
+			return true
+		}
+	}
+	return false
+}
+
+// rebuildModel rebuilds the agent and the context model of the session with a model of
+// the config, with thinking overridden unless it is nil
+func (s *ChatSession) rebuildModel(ctx context.Context, cfg *config.Config, modelName string, thinking *bool) error {
+	if _, ok := cfg.Models[modelName]; !ok {
+		return fmt.Errorf("model does not exist: %s", modelName)
+	}
+	providerFactory := providers.NewFactory(cfg)
+	if thinking != nil {
+		providerFactory = providerFactory.WithThinking(*thinking)
+	}
+	chatModel, err := providerFactory.CreateChatModelWithFallbacks(ctx, modelName, s.Preset.Fallbacks)
+	if err != nil {
+		return err
+	}
+	contextModel, err := newContextModel(ctx, providerFactory, modelName, s.Preset.Fallbacks, s.toolSchemas)
+	if err != nil {
+		return err
+	}
+	agentConfig := *s.agentConfig
+	agentConfig.Model = chatModel
+	agent, err := adk.NewChatModelAgent(ctx, &agentConfig)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Agent = agent
+	s.agentConfig = &agentConfig
+	s.Preset.Model = modelName
+	s.thinking = thinking
+	s.multimodal = cfg.Models[modelName].Multimodal
+	s.Manager.SetChatModel(contextModel)
+	return nil
+}
+
+// ReloadChatSession re-runs tool assembly and MCP initialization for the chat of the given
+// session and returns a new session whose agent uses the refreshed tool set. The conversation
+// context is preserved by carrying over the existing manager. The old session is only closed
+// once the new one has been initialized, so it remains usable if the reload fails.
+func ReloadChatSession(ctx context.Context, cfg *config.Config, old *ChatSession, debug bool) (*ChatSession, error) {
+	session, err := InitChatSession(ctx, cfg, old.Name, old.ID, debug)
+	if err != nil {
+		return nil, err
+	}
+	// Keep the model switched to at runtime
+	if old.modelOverride != "" {
+		if err := session.SwitchModel(ctx, cfg, old.modelOverride); err != nil {
+			logger.Warn("session", fmt.Sprintf("Failed to keep model %s after reload, using %s: %v", old.modelOverride, session.Preset.Model, err))
+		}
+	}
+	// Keep the thinking turned on or off at runtime
+	if old.thinking != nil {
+		if err := session.SetThinking(ctx, cfg, *old.thinking); err != nil {
+			logger.Warn("session", fmt.Sprintf("Failed to keep thinking %v after reload: %v", *old.thinking, err))
+		}
+	}
+	// Move the existing manager over to the new session: its callbacks still point to the
+	// persistence store of the old session, and the reloaded preset may change its limits
+	old.Manager.SetChatModel(session.Manager.GetChatModel())
+	applyManagerSettings(old.Manager, session.Preset)
+	bindManagerPersistence(old.Manager, session.persistence)
+	session.Manager = old.Manager
+	session.Approvals = old.Approvals
+	for _, name := range old.toolFilter.Disabled() {
+		session.toolFilter.SetEnabled(name, false)
+	}
+	if err := old.Close(); err != nil {
+		logger.Warn("session", fmt.Sprintf("Failed to close session %s after reload: %v", old.ID, err))
+	}
+	return session, nil
+}
+
+// applyManagerSettings applies the context limits of the chat preset to the manager
+func applyManagerSettings(m *manager.Manager, preset config.Chat) {
+	m.SetMaxMessageRounds(preset.MaxMessageRounds)
+	if preset.FullMessageRounds > 0 {
+		m.SetFullMessageRounds(preset.FullMessageRounds)
+	}
+	m.SetMaxRounds(preset.MaxRounds)
+	m.SetMaxTokens(preset.MaxContextTokens)
+}
+
+// bindManagerPersistence points the manager's persistence callbacks to the given store,
+// or clears them when persistence is disabled
+func bindManagerPersistence(m *manager.Manager, persistence *store.PersistenceStore) {
+	if persistence == nil {
+		m.SetPersistenceCallback(nil)
+		m.SetCompressionCompleteCallback(nil)
+		return
+	}
+	m.SetPersistenceCallback(func(msg *schema.Message) error {
+		return persistence.SaveMessage(msg)
+	})
+	// Full overwrite when compression completes
+	m.SetCompressionCompleteCallback(func(messages []*schema.Message) error {
+		return persistence.SaveMessagesOverwrite(messages)
+	})
+}
+
+// DiffToolNames compares two tool sets by name and returns the sorted names of added and removed tools
+func DiffToolNames(ctx context.Context, oldTools, newTools []tool.BaseTool) (added, removed []string) {
+	toolNames := func(tools []tool.BaseTool) map[string]bool {
+		names := make(map[string]bool, len(tools))
+		for _, t := range tools {
+			if info, err := t.Info(ctx); err == nil {
+				names[info.Name] = true
+			}
+		}
+		return names
+	}
+	oldNames, newNames := toolNames(oldTools), toolNames(newTools)
+	for name := range newNames {
+		if !oldNames[name] {
+			added = append(added, name)
+		}
+	}
+	for name := range oldNames {
+		if !newNames[name] {
+			removed = append(removed, name)
+		}
+	}
+	slices.Sort(added)
+	slices.Sort(removed)
+	return added, removed
+}
+
+// ToolState describes a tool of the session and whether it is offered to the model
+type ToolState struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+}
+
+// ToolStates lists the tools of the session in order
+func (s *ChatSession) ToolStates(ctx context.Context) []ToolState {
+	states := make([]ToolState, 0, len(s.Tools))
+	for _, t := range s.Tools {
+		info, err := t.Info(ctx)
+		if err != nil {
+			continue
+		}
+		states = append(states, ToolState{
+			Name:        info.Name,
+			Description: info.Desc,
+			Enabled:     s.toolFilter == nil || s.toolFilter.Enabled(info.Name),
+		})
+	}
+	return states
+}
+
+// SetToolEnabled enables or disables a tool of the session from the next turn on. A call
+// of the tool that is already in progress, e.g. waiting for approval, still completes.
+func (s *ChatSession) SetToolEnabled(ctx context.Context, name string, enabled bool) error {
+	if s.toolFilter == nil {
+		return fmt.Errorf("tools can't be toggled in this session")
+	}
+	for _, state := range s.ToolStates(ctx) {
+		if state.Name == name {
+			s.toolFilter.SetEnabled(name, enabled)
+			return nil
+		}
+	}
+	return fmt.Errorf("tool not found: %s", name)
+}
+
 // NewCleanupRegistry creates a new cleanup registry for the session
 func NewCleanupRegistry() *cleanupRegistry {
 	return utils.NewCleanupRegistry()
@@ -441,6 +759,68 @@ func (s *ChatSession) RemoveLastRound() {
 	}
 }
 
+// PopLastAssistantRound removes the last user message and the response to it, and returns
+// the user message to send again (used for /retry). The persisted messages are overwritten
+// as well. Returns nil if there is no user message.
+func (s *ChatSession) PopLastAssistantRound() *schema.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Manager == nil {
+		return nil
+	}
+	userMsg := s.Manager.PopLastAssistantRound()
+	if userMsg != nil && s.persistence != nil {
+		if err := s.persistence.SaveMessagesOverwrite(s.Manager.GetFullMessages()); err != nil {
+			logger.Warn("chatbot", fmt.Sprintf("Failed to overwrite persistence after removing last round: %v", err))
+		}
+	}
+	return userMsg
+}
+
+// EditUserMessage replaces the user message of the n-th round holding one, counted like
+// in Manager.TruncateToRound, by the new text and drops everything after it, so the
+// message can be answered again. The persisted messages are overwritten as well. Returns
+// the edited message.
+func (s *ChatSession) EditUserMessage(n int, content string) (*schema.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Manager == nil {
+		return nil, fmt.Errorf("no conversation to edit")
+	}
+	if err := s.Manager.TruncateToRound(n); err != nil {
+		return nil, err
+	}
+	userMsg, err := s.Manager.ReplaceUserMessageAt(-1, content)
+	if err != nil {
+		return nil, err
+	}
+	if s.persistence != nil {
+		if err := s.persistence.SaveMessagesOverwrite(s.Manager.GetFullMessages()); err != nil {
+			logger.Warn("chatbot", fmt.Sprintf("Failed to overwrite persistence after editing a message: %v", err))
+		}
+	}
+	return userMsg, nil
+}
+
+// ReplaceMessages replaces the conversation context with the given messages (used for loading
+// a saved conversation). The persisted messages are overwritten as well.
+func (s *ChatSession) ReplaceMessages(messages []*schema.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Manager != nil {
+		s.Manager.ReplaceMessages(messages)
+
+		if s.persistence != nil {
+			if err := s.persistence.SaveMessagesOverwrite(s.Manager.GetFullMessages()); err != nil {
+				logger.Warn("chatbot", fmt.Sprintf("Failed to overwrite persistence after loading messages: %v", err))
+			}
+		}
+	}
+}
+
 // GetLastUserMessage returns the last user message from the conversation, if any.
 // Used for redo/regenerate functionality.
 func (s *ChatSession) GetLastUserMessage() string {
@@ -464,6 +844,13 @@ func (s *ChatSession) GetMessageCount() int {
 	return s.Manager.GetMessageCount()
 }
 
+// Multimodal reports whether the model of the session reads attached files itself
+func (s *ChatSession) Multimodal() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.multimodal
+}
+
 // PersistenceStore returns the persistence store for this session
 func (s *ChatSession) PersistenceStore() *store.PersistenceStore {
 	s.mu.Lock()
@@ -471,7 +858,9 @@ func (s *ChatSession) PersistenceStore() *store.PersistenceStore {
 	return s.persistence
 }
 
-func (s *ChatSession) OnKeep() error {
+// OnKeep executes the session keep hook with the full message history. The label,
+// which may be empty, is passed to the hook to categorize the kept session.
+func (s *ChatSession) OnKeep(label string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -483,7 +872,7 @@ func (s *ChatSession) OnKeep() error {
 
 	// Execute session keep hook with full message history
 	if s.hookManager != nil {
-		if err := s.hookManager.OnSessionKeep(context.Background(), s.ID, s.Name, messages); err != nil {
+		if err := s.hookManager.OnSessionKeep(context.Background(), s.ID, s.Name, label, messages); err != nil {
 			// Log error but don't fail the clear operation
 			logger.Warn("chatbot", fmt.Sprintf("Session keep hook failed: %v", err))
 		}
@@ -510,8 +899,26 @@ func (s *ChatSession) OnGenModelInput(ctx context.Context, instruction string, i
 	return resultMessages, nil
 }
 
-// renderSystemPrompt renders system prompt using Go template with built-in variables
-func renderSystemPrompt(systemPrompt string) (string, error) {
+// OnResponse executes the onresponse hook if configured
+// It returns the message to store in the context in place of the final assistant message
+func (s *ChatSession) OnResponse(ctx context.Context, userMessage, response *schema.Message) *schema.Message {
+	if s.hookManager == nil {
+		return response
+	}
+
+	message, err := s.hookManager.OnResponse(ctx, s.ID, s.Name, userMessage, response)
+	if err != nil {
+		// Log error but store the original response
+		logger.Warn("chatbot", fmt.Sprintf("OnResponse hook failed: %v, storing the original response", err))
+		return response
+	}
+
+	return message
+}
+
+// RenderSystemPrompt renders system prompt using Go template with built-in variables.
+// It is also used for other user-facing templates such as the CLI welcome message.
+func RenderSystemPrompt(systemPrompt string) (string, error) {
 	if systemPrompt == "" {
 		return "", nil
 	}
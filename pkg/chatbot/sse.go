@@ -0,0 +1,89 @@
+package chatbot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// sseWriteTimeout bounds a write to a server-sent events stream, like the write deadline
+// of WebSocket connections
+const sseWriteTimeout = 5 * time.Second
+
+// errSSEClosed is returned for writes to a closed stream
+var errSSEClosed = errors.New("event stream closed")
+
+// SSEConn is a server-sent events stream used as a connection of a WSSession, for clients
+// that can't open a WebSocket. Each message is sent as an event whose data is the same
+// {type, payload} JSON as on a WebSocket; the client sends its messages with separate
+// HTTP requests.
+type SSEConn struct {
+	w      http.ResponseWriter
+	rc     *http.ResponseController
+	mu     sync.Mutex
+	closed bool
+	done   chan struct{}
+}
+
+// NewSSEConn starts an event stream on the response
+func NewSSEConn(w http.ResponseWriter) *SSEConn {
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// Keep reverse proxies such as nginx from buffering the stream
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	c := &SSEConn{w: w, rc: http.NewResponseController(w), done: make(chan struct{})}
+	c.rc.Flush()
+	return c
+}
+
+// WriteJSON sends v as the data of an event
+func (c *SSEConn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.write(fmt.Sprintf("data: %s\n\n", data))
+}
+
+// Ping sends a comment, so proxies keep the stream open and dead clients are detected
+func (c *SSEConn) Ping() error {
+	return c.write(": ping\n\n")
+}
+
+func (c *SSEConn) write(frame string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return errSSEClosed
+	}
+	// Not every ResponseWriter supports deadlines, the write is unbounded then
+	c.rc.SetWriteDeadline(time.Now().Add(sseWriteTimeout))
+	defer c.rc.SetWriteDeadline(time.Time{})
+	if _, err := c.w.Write([]byte(frame)); err != nil {
+		return err
+	}
+	return c.rc.Flush()
+}
+
+// Close ends the stream: later writes fail and Done is closed. The handler serving the
+// stream must return then.
+func (c *SSEConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		c.closed = true
+		close(c.done)
+	}
+	return nil
+}
+
+// Done is closed when the stream is closed, e.g. after a failed write
+func (c *SSEConn) Done() <-chan struct{} {
+	return c.done
+}
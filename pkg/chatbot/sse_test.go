@@ -0,0 +1,72 @@
+package chatbot
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSEConnStreamsSessionMessages(t *testing.T) {
+	session := NewWSSession(nil, "test-session", nil)
+	opened := make(chan *SSEConn)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn := NewSSEConn(w)
+		session.AddConn(conn)
+		opened <- conn
+		select {
+		case <-conn.Done():
+		case <-r.Context().Done():
+			conn.Close()
+		}
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", got)
+	}
+	conn := <-opened
+
+	// Messages are sent with the same JSON as on a WebSocket, pings are comments
+	conn.Ping()
+	NewWSChatHandler(session).SendChunk("hello", true, false, "text")
+	reader := bufio.NewReader(resp.Body)
+	var data string
+	deadline := time.Now().Add(5 * time.Second)
+	for data == "" && time.Now().Before(deadline) {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("ReadString() error = %v", err)
+		}
+		if after, ok := strings.CutPrefix(line, "data: "); ok {
+			data = strings.TrimSpace(after)
+		}
+	}
+	var msg WSMessage
+	if err := json.Unmarshal([]byte(data), &msg); err != nil {
+		t.Fatalf("invalid event data %q: %v", data, err)
+	}
+	var payload map[string]interface{}
+	json.Unmarshal(msg.Payload, &payload)
+	if msg.Type != "chunk" || payload["content"] != "hello" {
+		t.Errorf("got %s %v, want a chunk with hello", msg.Type, payload)
+	}
+
+	// A closed stream fails the write and is dropped from the session
+	conn.Close()
+	session.SendMessage("chunk", map[string]string{"content": "dropped"})
+	if got := session.ConnCount(); got != 0 {
+		t.Errorf("ConnCount() = %d after the stream closed, want 0", got)
+	}
+	if err := conn.WriteJSON("late"); err != errSSEClosed {
+		t.Errorf("WriteJSON() after Close = %v, want errSSEClosed", err)
+	}
+}
@@ -1,14 +1,20 @@
 package chatbot
 
 import (
+	"fmt"
 	"os"
 	"strings"
 
 	"golang.org/x/term"
 )
 
+// toolResultPreviewLimit is the maximum number of characters of a tool result shown in the CLI
+const toolResultPreviewLimit = 500
+
 type StreamFilter struct {
 	pendingOutput []string
+	// fences holds back code fences until they are complete, see NewMarkdownStreamFilter
+	fences *fenceTracker
 }
 
 func NewStreamFilter() *StreamFilter {
@@ -17,7 +23,23 @@ func NewStreamFilter() *StreamFilter {
 	}
 }
 
+// NewMarkdownStreamFilter returns a StreamFilter that also holds back code fences until
+// they are closed, so a markdown renderer gets whole fences: a fenced block is emitted at
+// once, and a line that may open a fence is held until it is complete. Other text is
+// emitted as it arrives.
+func NewMarkdownStreamFilter() *StreamFilter {
+	f := NewStreamFilter()
+	f.fences = &fenceTracker{lineStart: true}
+	return f
+}
+
 func (f *StreamFilter) Process(chunk string) *string {
+	if f.fences != nil {
+		chunk = f.fences.process(chunk)
+		if chunk == "" {
+			return nil
+		}
+	}
 	if strings.HasSuffix(chunk, "\n") {
 		f.pendingOutput = append(f.pendingOutput, chunk)
 		return nil
@@ -32,6 +54,11 @@ func (f *StreamFilter) Process(chunk string) *string {
 }
 
 func (f *StreamFilter) Finish() *string {
+	if f.fences != nil {
+		if rest := f.fences.flush(); rest != "" {
+			f.pendingOutput = append(f.pendingOutput, rest)
+		}
+	}
 	if len(f.pendingOutput) > 0 {
 		result := strings.TrimRight(strings.Join(f.pendingOutput, ""), "\n")
 		f.pendingOutput = make([]string, 0)
@@ -40,6 +67,150 @@ func (f *StreamFilter) Finish() *string {
 	return nil
 }
 
+// fenceTracker follows the code fences of streamed markdown. pending is the text held
+// back: an open fence with its lines so far, or the start of a line that may open one.
+type fenceTracker struct {
+	pending   string
+	fence     string // marker of the open fence, e.g. ``` or ~~~~, empty outside a fence
+	held      int    // length of the complete lines of the open fence in pending
+	lineStart bool   // pending starts at the beginning of a line
+}
+
+// process adds a chunk and returns the text that can be emitted
+func (t *fenceTracker) process(chunk string) string {
+	t.pending += chunk
+	var out strings.Builder
+	for {
+		rest := t.pending[t.held:]
+		line, after, complete := strings.Cut(rest, "\n")
+		if t.fence != "" {
+			if !complete {
+				break
+			}
+			t.held += len(line) + 1
+			if closesFence(line, t.fence) {
+				out.WriteString(t.pending[:t.held])
+				t.pending, t.held, t.fence = after, 0, ""
+			}
+			continue
+		}
+		if !complete {
+			// A partial line is emitted unless it may become a fence
+			if rest != "" && !(t.lineStart && mayOpenFence(rest)) {
+				out.WriteString(rest)
+				t.pending, t.lineStart = "", false
+			}
+			break
+		}
+		if t.lineStart {
+			if marker := openingFence(line); marker != "" {
+				t.fence = marker
+				t.held = len(line) + 1
+				continue
+			}
+		}
+		out.WriteString(line + "\n")
+		t.pending, t.lineStart = after, true
+	}
+	return out.String()
+}
+
+// flush returns the text held back, e.g. a fence left open at the end of the response
+func (t *fenceTracker) flush() string {
+	rest := t.pending
+	t.pending, t.fence, t.held, t.lineStart = "", "", 0, true
+	return rest
+}
+
+// fenceIndent strips the up to 3 spaces a fence line may be indented with
+func fenceIndent(line string) (string, bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	return trimmed, len(line)-len(trimmed) <= 3
+}
+
+// openingFence returns the marker of the fence the line opens, or ""
+func openingFence(line string) string {
+	trimmed, ok := fenceIndent(line)
+	if !ok || trimmed == "" || (trimmed[0] != '`' && trimmed[0] != '~') {
+		return ""
+	}
+	marker := trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, trimmed[:1]))]
+	if len(marker) < 3 {
+		return ""
+	}
+	// The info string of a backtick fence can't contain backticks
+	if marker[0] == '`' && strings.Contains(trimmed[len(marker):], "`") {
+		return ""
+	}
+	return marker
+}
+
+// closesFence reports whether the line closes the fence opened by marker
+func closesFence(line, marker string) bool {
+	trimmed, ok := fenceIndent(strings.TrimRight(line, " \t\r"))
+	return ok && len(trimmed) >= len(marker) && strings.Trim(trimmed, marker[:1]) == ""
+}
+
+// mayOpenFence reports whether the beginning of a line may be a fence opening line
+func mayOpenFence(partial string) bool {
+	trimmed, ok := fenceIndent(partial)
+	if !ok {
+		return false
+	}
+	for _, marker := range []string{"```", "~~~"} {
+		if strings.HasPrefix(marker, trimmed) || strings.HasPrefix(trimmed, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// toolCallIDs remembers the tool call ID per index, since providers usually
+// only send the ID with the first chunk of a streamed tool call
+type toolCallIDs map[int]string
+
+// resolve returns the ID of the tool call at index, recording id when it is set
+func (t toolCallIDs) resolve(index int, id string) string {
+	if id != "" {
+		t[index] = id
+		return id
+	}
+	return t[index]
+}
+
+// SplitChunk splits s into parts of at most max characters. A part ends after a newline,
+// or else a space, in the second half of the limit when there is one, so lines and words
+// are not cut, and never inside a UTF-8 character. max <= 0 returns s unsplit.
+func SplitChunk(s string, max int) []string {
+	runes := []rune(s)
+	if max <= 0 || len(runes) <= max {
+		return []string{s}
+	}
+	var parts []string
+	for len(runes) > max {
+		cut := splitPoint(runes[:max])
+		parts = append(parts, string(runes[:cut]))
+		runes = runes[cut:]
+	}
+	if len(runes) > 0 {
+		parts = append(parts, string(runes))
+	}
+	return parts
+}
+
+// splitPoint returns where a part of at most len(runes) characters ends
+func splitPoint(runes []rune) int {
+	half := len(runes) / 2
+	for _, boundary := range []string{"\n", " \t"} {
+		for i := len(runes) - 1; i >= half; i-- {
+			if strings.ContainsRune(boundary, runes[i]) {
+				return i + 1
+			}
+		}
+	}
+	return len(runes)
+}
+
 // TrimLeadingWhitespace strips leading whitespace characters (space, tab, newline, carriage return)
 func TrimLeadingWhitespace(s string) string {
 	return strings.TrimLeftFunc(s, func(r rune) bool {
@@ -66,3 +237,13 @@ func TruncateToTermWidth(s string) (string, bool) {
 	backKeep := availableWidth - 3 - frontKeep
 	return s[:frontKeep] + "..." + s[len(s)-backKeep:], true
 }
+
+// TruncateToolResult trims a tool result for display, keeping at most limit characters
+func TruncateToolResult(s string, limit int) string {
+	s = strings.TrimSpace(s)
+	runes := []rune(s)
+	if limit <= 0 || len(runes) <= limit {
+		return s
+	}
+	return fmt.Sprintf("%s... (%d more characters)", string(runes[:limit]), len(runes)-limit)
+}
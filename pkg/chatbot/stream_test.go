@@ -0,0 +1,105 @@
+package chatbot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToolCallIDsReuseFirstChunkID(t *testing.T) {
+	ids := toolCallIDs{}
+
+	// Providers send the ID only with the first chunk of each tool call
+	chunks := []struct {
+		index int
+		id    string
+		want  string
+	}{
+		{index: 0, id: "call_a", want: "call_a"},
+		{index: 0, id: "", want: "call_a"},
+		{index: 1, id: "call_b", want: "call_b"},
+		{index: 0, id: "", want: "call_a"},
+		{index: 1, id: "", want: "call_b"},
+	}
+	for i, c := range chunks {
+		if got := ids.resolve(c.index, c.id); got != c.want {
+			t.Errorf("chunk %d: resolve(%d, %q) = %q, want %q", i, c.index, c.id, got, c.want)
+		}
+	}
+
+	// A chunk for an index that never carried an ID has no ID
+	if got := ids.resolve(2, ""); got != "" {
+		t.Errorf("resolve(2, \"\") = %q, want empty", got)
+	}
+}
+
+func TestSplitChunk(t *testing.T) {
+	tests := []struct {
+		in   string
+		max  int
+		want []string
+	}{
+		{"short", 10, []string{"short"}},
+		{"no limit at all", 0, []string{"no limit at all"}},
+		{"one two three four", 10, []string{"one two ", "three four"}},
+		{"line one\nline two", 12, []string{"line one\n", "line two"}},
+		{"abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"a bcdefghij", 6, []string{"a bcde", "fghij"}},
+		{"日本語のテキスト", 3, []string{"日本語", "のテキ", "スト"}},
+	}
+	for _, tt := range tests {
+		got := SplitChunk(tt.in, tt.max)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("SplitChunk(%q, %d) = %q, want %q", tt.in, tt.max, got, tt.want)
+		}
+		if strings.Join(got, "") != tt.in {
+			t.Errorf("SplitChunk(%q, %d) parts do not join to the input", tt.in, tt.max)
+		}
+	}
+}
+
+func TestMarkdownStreamFilter(t *testing.T) {
+	text := "Here is the code:\n\n```go\nfunc main() {\n\tfmt.Println(\"```\")\n}\n```\nDone, ~~~ is a fence too:\n  ~~~~\n~~~\n~~~~\nEnd."
+	fences := []string{"```go\nfunc main() {\n\tfmt.Println(\"```\")\n}\n```\n", "  ~~~~\n~~~\n~~~~\n"}
+	for _, size := range []int{1, 2, 3, 5, 7, len(text)} {
+		f := NewMarkdownStreamFilter()
+		var outputs []string
+		for i := 0; i < len(text); i += size {
+			if out := f.Process(text[i:min(i+size, len(text))]); out != nil {
+				outputs = append(outputs, *out)
+			}
+		}
+		if out := f.Finish(); out != nil {
+			outputs = append(outputs, *out)
+		}
+		if got := strings.Join(outputs, ""); got != text {
+			t.Errorf("chunks of %d: output = %q, want %q", size, got, text)
+		}
+		// Every fence is emitted within a single output
+		for _, fence := range fences {
+			whole := false
+			for _, out := range outputs {
+				whole = whole || strings.Contains(out, fence)
+			}
+			if !whole {
+				t.Errorf("chunks of %d: fence %q split across outputs %q", size, fence, outputs)
+			}
+		}
+	}
+}
+
+func TestMarkdownStreamFilterUnclosedFence(t *testing.T) {
+	f := NewMarkdownStreamFilter()
+	var got []string
+	for _, chunk := range []string{"Text ", "then\n``", "`\ncode\n"} {
+		if out := f.Process(chunk); out != nil {
+			got = append(got, *out)
+		}
+	}
+	if strings.Join(got, "|") != "Text " {
+		t.Errorf("outputs = %q, want the text before the fence", got)
+	}
+	// The open fence is emitted at the end, after the line held for its newline
+	if out := f.Finish(); out == nil || *out != "then\n```\ncode" {
+		t.Errorf("Finish() = %v, want the rest with the fence", out)
+	}
+}
@@ -0,0 +1,72 @@
+package chatbot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Arvintian/chat-agent/pkg/manager"
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// slowModel streams a partial answer and then stalls until the context ends
+type slowModel struct{}
+
+func (m *slowModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (m *slowModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	reader, writer := schema.Pipe[*schema.Message](1)
+	go func() {
+		defer writer.Close()
+		writer.Send(schema.AssistantMessage("partial", nil), nil)
+		<-ctx.Done()
+		writer.Send(nil, ctx.Err())
+	}()
+	return reader, nil
+}
+
+func (m *slowModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+func TestRequestTimeoutKeepsPartialResponse(t *testing.T) {
+	ctx := context.Background()
+	agent, err := adk.NewChatModelAgent(ctx, &adk.ChatModelAgentConfig{
+		Name:        "test",
+		Description: "test agent",
+		Model:       &slowModel{},
+	})
+	if err != nil {
+		t.Fatalf("NewChatModelAgent() error = %v", err)
+	}
+	m := manager.NewManager(manager.Config{MaxMessageRounds: 10})
+	cb := NewChatBot(ctx, agent, m, nil, nil)
+	handler := &recordingHandler{}
+	cb.SetHandler(handler)
+	cb.SetRequestTimeout(50 * time.Millisecond)
+
+	err = cb.StreamChatWithHandler(ctx, "hello", nil)
+	if !errors.Is(err, ErrRequestTimeout) {
+		t.Fatalf("StreamChatWithHandler() error = %v, want ErrRequestTimeout", err)
+	}
+	if cb.CanResume() {
+		t.Error("CanResume() = true after a timed out turn")
+	}
+	for _, event := range handler.recorded() {
+		if strings.HasPrefix(event, "error:") {
+			t.Errorf("timed out turn sent %q, want only a completion", event)
+		}
+	}
+
+	messages := m.GetMessages()
+	if got := messages[len(messages)-1]; got.Role != schema.Assistant || got.Content != "partial" {
+		t.Errorf("last message = %+v, want the partial answer", got)
+	}
+}
@@ -0,0 +1,64 @@
+package chatbot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+)
+
+// ValidateFiles checks the files attached to a message by a client against the uploads
+// limits of its chat: their number, their total size and their types. The size of the
+// data of a data URL must match the declared file size in any case. The error names the
+// offending file.
+func ValidateFiles(limits *config.Uploads, files []FileData) error {
+	var uploads config.Uploads
+	if limits != nil {
+		uploads = *limits
+	}
+	if uploads.MaxFiles > 0 && len(files) > uploads.MaxFiles {
+		return fmt.Errorf("%d files attached, at most %d are accepted per message", len(files), uploads.MaxFiles)
+	}
+
+	var total int64
+	for _, file := range files {
+		size, isData := dataURLSize(file.URL)
+		if !isData {
+			// Remote files are fetched by the provider, their declared size is all there is
+			size = file.FileSize
+		} else if file.FileSize > 0 && file.FileSize != size {
+			return fmt.Errorf("file %s: declared size is %d bytes but its data is %d bytes", file.Name, file.FileSize, size)
+		}
+		if len(uploads.AllowedTypes) > 0 {
+			// The type of a data URL is what the model gets, it has to be allowed as well
+			dataType, _ := parseDataURL(file.URL)
+			for _, mimeType := range []string{file.Type, dataType} {
+				if mimeType != "" && !matchFileType(uploads.AllowedTypes, file.Name, mimeType) {
+					return fmt.Errorf("file %s: type %s is not accepted, allowed types: %s", file.Name, mimeType, strings.Join(uploads.AllowedTypes, ", "))
+				}
+			}
+			if file.Type == "" && dataType == "" && !matchFileType(uploads.AllowedTypes, file.Name, "") {
+				return fmt.Errorf("file %s: unknown type, allowed types: %s", file.Name, strings.Join(uploads.AllowedTypes, ", "))
+			}
+		}
+		total += size
+		if uploads.MaxSize > 0 && total > int64(uploads.MaxSize)<<20 {
+			return fmt.Errorf("file %s: the files of the message are larger than the limit of %d MB", file.Name, uploads.MaxSize)
+		}
+	}
+	return nil
+}
+
+// dataURLSize returns the size of the decoded data of a data URL, and false if the URL
+// is not a data URL
+func dataURLSize(url string) (int64, bool) {
+	metadata, data, ok := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+	if !ok || !strings.HasPrefix(url, "data:") {
+		return 0, false
+	}
+	if !strings.HasSuffix(metadata, ";base64") {
+		return int64(len(data)), true
+	}
+	n := len(strings.TrimRight(data, "="))
+	return int64(n) * 3 / 4, true
+}
@@ -0,0 +1,52 @@
+package chatbot
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+)
+
+func TestValidateFiles(t *testing.T) {
+	dataURL := func(mimeType string, size int) string {
+		return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(make([]byte, size))
+	}
+	limits := &config.Uploads{MaxFiles: 2, MaxSize: 1, AllowedTypes: []string{"image/*", ".pdf"}}
+
+	tests := []struct {
+		name   string
+		limits *config.Uploads
+		files  []FileData
+		want   string
+	}{
+		{"no limits", nil, []FileData{{Name: "a.bin", Type: "application/octet-stream", URL: dataURL("application/octet-stream", 5<<20)}}, ""},
+		{"accepted", limits, []FileData{
+			{Name: "a.png", Type: "image/png", URL: dataURL("image/png", 1000), FileSize: 1000},
+			{Name: "b.pdf", Type: "application/pdf", URL: dataURL("application/pdf", 1001)},
+		}, ""},
+		{"too many", limits, []FileData{{Name: "a.png"}, {Name: "b.png"}, {Name: "c.png"}}, "3 files attached, at most 2"},
+		{"too large", limits, []FileData{
+			{Name: "a.png", Type: "image/png", URL: dataURL("image/png", 600<<10)},
+			{Name: "b.png", Type: "image/png", URL: dataURL("image/png", 600<<10)},
+		}, "file b.png: the files of the message are larger than the limit of 1 MB"},
+		{"type", limits, []FileData{{Name: "a.exe", Type: "application/x-msdownload", URL: dataURL("application/x-msdownload", 10)}}, "file a.exe: type application/x-msdownload is not accepted"},
+		{"data url type", limits, []FileData{{Name: "a.png", Type: "image/png", URL: dataURL("text/html", 10)}}, "file a.png: type text/html is not accepted"},
+		{"declared size", nil, []FileData{{Name: "a.png", Type: "image/png", URL: dataURL("image/png", 1002), FileSize: 10}}, "file a.png: declared size is 10 bytes but its data is 1002 bytes"},
+		{"remote", limits, []FileData{{Name: "a.png", Type: "image/png", URL: "https://example.com/a.png", FileSize: 2 << 20}}, "larger than the limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateFiles(tt.limits, tt.files)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("ValidateFiles() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ValidateFiles() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
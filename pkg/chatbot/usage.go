@@ -0,0 +1,89 @@
+package chatbot
+
+import (
+	"fmt"
+
+	"github.com/Arvintian/chat-agent/pkg/metrics"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// TokenUsage counts the tokens reported by the provider
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Add adds the usage of another response
+func (u *TokenUsage) Add(other TokenUsage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+}
+
+// IsZero reports whether no tokens were counted
+func (u TokenUsage) IsZero() bool {
+	return u.PromptTokens == 0 && u.CompletionTokens == 0 && u.TotalTokens == 0
+}
+
+// String formats the usage for display
+func (u TokenUsage) String() string {
+	return fmt.Sprintf("%d prompt + %d completion = %d tokens", u.PromptTokens, u.CompletionTokens, u.TotalTokens)
+}
+
+// UsageHandler is an optional interface for a Handler that reports the tokens used by
+// a turn and by the whole session
+type UsageHandler interface {
+	SendUsage(turn, session TokenUsage)
+}
+
+// responseUsage collects the usage of one model response. Providers report it on the
+// final chunk of a stream, which usually has no content (OpenRouter sends it right
+// before [DONE] with empty choices), and some repeat a running total on every chunk,
+// so the last reported usage is kept instead of summing the chunks.
+type responseUsage struct {
+	usage    TokenUsage
+	reported bool
+}
+
+// observe records the usage of a response message or stream chunk, if it has any
+func (r *responseUsage) observe(msg *schema.Message) {
+	if msg == nil || msg.ResponseMeta == nil || msg.ResponseMeta.Usage == nil {
+		return
+	}
+	usage := msg.ResponseMeta.Usage
+	r.usage = TokenUsage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+	}
+	if r.usage.TotalTokens == 0 {
+		r.usage.TotalTokens = r.usage.PromptTokens + r.usage.CompletionTokens
+	}
+	r.reported = true
+}
+
+// addTo adds the usage of the response to the turn, once the response is complete
+func (r *responseUsage) addTo(turn *TokenUsage) {
+	if r.reported {
+		turn.Add(r.usage)
+	}
+}
+
+// Usage returns the tokens used since the chatbot was created
+func (cb *ChatBot) Usage() TokenUsage {
+	return cb.usage
+}
+
+// finishUsage adds the usage of a turn to the session total and reports both
+func (cb *ChatBot) finishUsage(turn TokenUsage) {
+	if turn.IsZero() {
+		return
+	}
+	cb.usage.Add(turn)
+	metrics.ObserveTokens(turn.PromptTokens, turn.CompletionTokens)
+	if handler, ok := cb.handler.(UsageHandler); ok {
+		handler.SendUsage(turn, cb.usage)
+	}
+}
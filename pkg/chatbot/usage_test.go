@@ -0,0 +1,79 @@
+package chatbot
+
+import (
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+func usageChunk(content string, prompt, completion, total int) *schema.Message {
+	msg := schema.AssistantMessage(content, nil)
+	msg.ResponseMeta = &schema.ResponseMeta{Usage: &schema.TokenUsage{
+		PromptTokens:     prompt,
+		CompletionTokens: completion,
+		TotalTokens:      total,
+	}}
+	return msg
+}
+
+func TestResponseUsageKeepsLastReport(t *testing.T) {
+	turn := TokenUsage{}
+
+	// Usage only on the final chunk, which has no content
+	first := responseUsage{}
+	for _, chunk := range []*schema.Message{
+		schema.AssistantMessage("hel", nil),
+		schema.AssistantMessage("lo", nil),
+		usageChunk("", 10, 2, 12),
+	} {
+		first.observe(chunk)
+	}
+	first.addTo(&turn)
+
+	// A running total on every chunk, without the total field
+	second := responseUsage{}
+	for _, chunk := range []*schema.Message{
+		usageChunk("a", 20, 1, 0),
+		usageChunk("b", 20, 2, 0),
+	} {
+		second.observe(chunk)
+	}
+	second.addTo(&turn)
+
+	// No usage reported at all
+	third := responseUsage{}
+	third.observe(schema.AssistantMessage("c", nil))
+	third.addTo(&turn)
+
+	want := TokenUsage{PromptTokens: 30, CompletionTokens: 4, TotalTokens: 34}
+	if turn != want {
+		t.Errorf("turn usage = %+v, want %+v", turn, want)
+	}
+}
+
+// usageHandler is a recordingHandler that reports token usage
+type usageHandler struct {
+	recordingHandler
+	turn, session TokenUsage
+}
+
+func (h *usageHandler) SendUsage(turn, session TokenUsage) {
+	h.turn, h.session = turn, session
+}
+
+func TestFinishUsageAccumulates(t *testing.T) {
+	handler := &usageHandler{}
+	cb := &ChatBot{}
+	cb.SetHandler(NewMultiHandler(&recordingHandler{}, handler))
+
+	cb.finishUsage(TokenUsage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12})
+	cb.finishUsage(TokenUsage{})
+	cb.finishUsage(TokenUsage{PromptTokens: 15, CompletionTokens: 3, TotalTokens: 18})
+
+	if handler.turn.TotalTokens != 18 || handler.session.TotalTokens != 30 {
+		t.Errorf("reported turn = %+v, session = %+v, want 18 and 30 tokens", handler.turn, handler.session)
+	}
+	if cb.Usage().PromptTokens != 25 {
+		t.Errorf("Usage() = %+v, want 25 prompt tokens", cb.Usage())
+	}
+}
@@ -5,11 +5,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/Arvintian/chat-agent/pkg/config"
+	chaterrors "github.com/Arvintian/chat-agent/pkg/errors"
+	"github.com/Arvintian/chat-agent/pkg/manager"
+	"github.com/Arvintian/chat-agent/pkg/mcp"
+	"github.com/Arvintian/chat-agent/pkg/metrics"
 
 	"github.com/gorilla/websocket"
 )
@@ -17,22 +22,68 @@ import (
 // Default approval timeout
 const DefaultApprovalTimeout = 5 * time.Minute
 
+// approvalWarningLead is how long before an approval request expires the client is warned
+const approvalWarningLead = 30 * time.Second
+
+// approvalTimedOutReason is given to the model for tool calls whose approval timed out
+var approvalTimedOutReason = chaterrors.ErrApprovalTimeout.Error()
+
+// approvalShutdownReason is given to the model for tool calls whose approval was pending
+// when the server shut down
+const approvalShutdownReason = "the server is shutting down"
+
+// approvalCancelledReason is given to the model for tool calls whose approval was
+// cancelled by the user, or pending when the turn was stopped
+const approvalCancelledReason = "the user cancelled the approval"
+
+// Default message sent when an in-flight chat turn is stopped
+const defaultCancelReason = "Response stopped by user"
+
 // WebSocket message types
 type WSMessage struct {
 	Type    string          `json:"type"`
 	Payload json.RawMessage `json:"payload"`
 }
 
-// ApprovalRequest holds the approval ID and result channel
+// ApprovalRequest holds the approval ID, the tool calls to approve and the result channel
 type ApprovalRequest struct {
 	ApprovalID string
+	Targets    []ApprovalTarget
 	ResultChan chan ApprovalResultMap
 }
 
-// WSSession represents a WebSocket session with its connection
+// ApprovalLimiter bounds the approval requests waiting for an answer at the same time,
+// across all sessions of a server
+type ApprovalLimiter interface {
+	// AcquireApproval reserves a slot for a pending approval, false if none is free
+	AcquireApproval() bool
+	// ReleaseApproval frees a slot reserved by AcquireApproval
+	ReleaseApproval()
+}
+
+// QuestionRequest holds the question ID and answer channel of a clarifying question
+type QuestionRequest struct {
+	QuestionID string
+	AnswerChan chan string
+}
+
+// Conn is a client connection of a WSSession, a *websocket.Conn or an SSEConn
+type Conn interface {
+	WriteJSON(v interface{}) error
+	Close() error
+}
+
+// WSSession represents a WebSocket session with its connections. A session usually has
+// one connection; when the server shares sessions between connections, messages are sent
+// to all of them and any of them can answer approval requests.
 type WSSession struct {
-	conn        *websocket.Conn
-	connMu      sync.Mutex
+	conns  []Conn
+	connMu sync.Mutex
+	// writeMu serializes the frames written to the connections: the chat stream, approval
+	// requests, pings and replies run in different goroutines, and a WebSocket connection
+	// supports only one concurrent writer. It is taken before connMu, which only guards
+	// conns so a slow client doesn't block adding or removing connections.
+	writeMu     sync.Mutex
 	cfg         *config.Config
 	SessionID   string
 	ChatName    string
@@ -53,16 +104,28 @@ type WSSession struct {
 	approvalTimeout time.Duration
 	pendingApproval *ApprovalRequest
 	approvalMu      sync.Mutex
+	approvalLimiter ApprovalLimiter
+
+	// Question state for clarifying questions asked by the ask_user tool
+	pendingQuestion *QuestionRequest
+	questionMu      sync.Mutex
 
 	// Cancel state for stopping ongoing chat
-	cancelMu    sync.Mutex
-	cancelFunc  context.CancelFunc
-	isCancelled bool
+	cancelMu     sync.Mutex
+	cancelFunc   context.CancelFunc
+	isCancelled  bool
+	cancelReason string
+	// turnDone is closed when the in-flight chat turn returns, nil when no turn is running
+	turnDone chan struct{}
+
+	// shutdown is closed when the server starts shutting down, pending approvals and
+	// questions are released then instead of waiting for the client
+	shutdown     chan struct{}
+	shutdownOnce sync.Once
 }
 
 func NewWSSession(conn *websocket.Conn, sessionID string, cfg *config.Config) *WSSession {
 	session := &WSSession{
-		conn:            conn,
 		cfg:             cfg,
 		SessionID:       sessionID,
 		ChatName:        "",
@@ -72,10 +135,46 @@ func NewWSSession(conn *websocket.Conn, sessionID string, cfg *config.Config) *W
 		approvalTimeout: DefaultApprovalTimeout,
 		pendingApproval: nil,
 		isCancelled:     false,
+		shutdown:        make(chan struct{}),
+	}
+	if conn != nil {
+		session.conns = []Conn{conn}
 	}
 	return session
 }
 
+// AddConn adds a connection to the session, messages are sent to all connections
+func (s *WSSession) AddConn(conn Conn) {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	s.conns = append(s.conns, conn)
+}
+
+// RemoveConn removes a connection from the session and returns the number of connections left
+func (s *WSSession) RemoveConn(conn Conn) int {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	s.conns = slices.DeleteFunc(s.conns, func(c Conn) bool { return c == conn })
+	return len(s.conns)
+}
+
+// ConnCount returns the number of connections of the session
+func (s *WSSession) ConnCount() int {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	return len(s.conns)
+}
+
+// CloseConns closes the connections of the session, their handlers then detach them
+func (s *WSSession) CloseConns() {
+	s.connMu.Lock()
+	conns := slices.Clone(s.conns)
+	s.connMu.Unlock()
+	for _, c := range conns {
+		c.Close()
+	}
+}
+
 // MarkClosed marks the session as closed so that subsequent SendMessage/SendPing
 // calls are silently dropped instead of writing to a closed connection.
 func (s *WSSession) MarkClosed() {
@@ -87,16 +186,19 @@ func (s *WSSession) IsClosed() bool {
 	return s.closed.Load()
 }
 
-// SetCancelled marks the session as cancelled
+// SetCancelled marks the session as cancelled. A pending approval request or
+// question is aborted, so the stopped turn does not keep waiting for the client.
 func (s *WSSession) SetCancelled() {
 	s.cancelMu.Lock()
-	defer s.cancelMu.Unlock()
 	if !s.isCancelled {
 		s.isCancelled = true
 		if s.cancelFunc != nil {
 			s.cancelFunc()
 		}
 	}
+	s.cancelMu.Unlock()
+
+	s.abortPending()
 }
 
 // IsCancelled returns true if the session is cancelled
@@ -113,54 +215,168 @@ func (s *WSSession) ResetCancel() {
 	if s.isCancelled {
 		s.isCancelled = false
 		s.cancelFunc = nil
+		s.cancelReason = ""
 	}
 }
 
-// SetCancelFunc sets the cancel function for the current request
-func (s *WSSession) SetCancelFunc(cancelFunc context.CancelFunc) {
+// CancelReason returns the message describing why the current request was stopped
+func (s *WSSession) CancelReason() string {
 	s.cancelMu.Lock()
 	defer s.cancelMu.Unlock()
+	if s.cancelReason == "" {
+		return defaultCancelReason
+	}
+	return s.cancelReason
+}
+
+// BeginTurn registers the cancel function of a new chat turn. The returned function
+// must be called when the turn returns, so that CancelTurn can wait for it.
+func (s *WSSession) BeginTurn(cancelFunc context.CancelFunc) func() {
+	done := make(chan struct{})
+	s.cancelMu.Lock()
 	s.cancelFunc = cancelFunc
+	s.turnDone = done
+	s.cancelMu.Unlock()
+	return func() {
+		s.cancelMu.Lock()
+		if s.turnDone == done {
+			s.turnDone = nil
+		}
+		s.cancelMu.Unlock()
+		close(done)
+	}
+}
+
+// InTurn reports whether a chat turn is in flight
+func (s *WSSession) InTurn() bool {
+	s.cancelMu.Lock()
+	defer s.cancelMu.Unlock()
+	return s.turnDone != nil
+}
+
+// AwaitingApproval reports whether the turn waits for the answer to an approval request
+func (s *WSSession) AwaitingApproval() bool {
+	s.approvalMu.Lock()
+	defer s.approvalMu.Unlock()
+	return s.pendingApproval != nil
+}
+
+// CancelTurn cancels the in-flight chat turn, if any, and waits up to timeout for it to
+// return. Pending approvals and questions are aborted so the turn does not keep waiting
+// for the client. Returns true if a turn was in flight.
+func (s *WSSession) CancelTurn(reason string, timeout time.Duration) bool {
+	s.cancelMu.Lock()
+	done := s.turnDone
+	if done == nil {
+		s.cancelMu.Unlock()
+		return false
+	}
+	if !s.isCancelled {
+		s.isCancelled = true
+		s.cancelReason = reason
+		if s.cancelFunc != nil {
+			s.cancelFunc()
+		}
+	}
+	s.cancelMu.Unlock()
+
+	s.abortPending()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		log.Printf("Session %s: In-flight turn did not stop within %v", s.SessionID, timeout)
+	}
+	return true
+}
+
+// BeginShutdown tells the clients that the server is shutting down and that the in-flight
+// turn, if any, gets drainTimeout to complete. A pending approval request is denied and a
+// pending question cancelled, so the turn doesn't wait for the client until the drain
+// times out.
+func (s *WSSession) BeginShutdown(drainTimeout time.Duration) {
+	s.shutdownOnce.Do(func() {
+		s.SendMessage("shutting_down", map[string]interface{}{
+			"drain_seconds": int(drainTimeout.Round(time.Second).Seconds()),
+		})
+		close(s.shutdown)
+	})
+}
+
+// abortPending denies a pending approval request as cancelled and releases a pending
+// question without an answer
+func (s *WSSession) abortPending() {
+	s.CancelApproval("")
+
+	s.questionMu.Lock()
+	if s.pendingQuestion != nil {
+		close(s.pendingQuestion.AnswerChan)
+		s.pendingQuestion = nil
+	}
+	s.questionMu.Unlock()
 }
 
 func (s *WSSession) SendMessage(msgType string, content interface{}) {
 	if s.IsClosed() {
 		return
 	}
-	s.connMu.Lock()
-	defer s.connMu.Unlock()
-	// Set write deadline to prevent blocking forever on slow clients.
-	// Without this, a blocked SendMessage holds connMu, starving SendPing,
-	// which causes pongWait to expire and the connection to be closed.
-	s.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
-	defer s.conn.SetWriteDeadline(time.Time{})
 	data := WSMessage{Type: msgType}
 	payload, _ := json.Marshal(content)
 	data.Payload = payload
-	if err := s.conn.WriteJSON(data); err != nil {
-		log.Printf("Error sending message to session %s: %v", s.SessionID, err)
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.connMu.Lock()
+	conns := slices.Clone(s.conns)
+	s.connMu.Unlock()
+	for _, conn := range conns {
+		if err := s.writeJSON(conn, data); err != nil {
+			log.Printf("Error sending message to session %s: %v", s.SessionID, err)
+			// A connection is unusable after a failed write. Drop it so the other
+			// connections of the session keep receiving; closing it ends its read loop.
+			s.RemoveConn(conn)
+			conn.Close()
+		}
+	}
+}
+
+// writeJSON writes a message to one connection, writeMu must be held
+func (s *WSSession) writeJSON(c Conn, data WSMessage) error {
+	conn, ok := c.(*websocket.Conn)
+	if !ok {
+		// Other connections bound their writes themselves
+		return c.WriteJSON(data)
+	}
+	// Set write deadline to prevent blocking forever on slow clients.
+	// Without this, a blocked SendMessage holds writeMu, starving SendPing,
+	// which causes pongWait to expire and the connection to be closed.
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	defer conn.SetWriteDeadline(time.Time{})
+	if err := conn.WriteJSON(data); err != nil {
+		return err
 	}
 	// Reset read deadline: a successful write proves the connection is alive,
 	// so give ReadMessage more time. This prevents SendPing starvation from
 	// causing a premature pongWait timeout.
 	if s.readTimeout > 0 {
-		s.conn.SetReadDeadline(time.Now().Add(s.readTimeout))
+		conn.SetReadDeadline(time.Now().Add(s.readTimeout))
 	}
+	return nil
 }
 
-// SendPing sends a WebSocket ping frame to the client.
+// SendPing sends a WebSocket ping frame to one connection of the session.
 // Used for keepalive to detect dead connections (e.g., mobile network loss).
 // The write deadline ensures we don't block forever if the connection is dead.
 // The deadline is cleared after the write to avoid affecting subsequent writes.
-func (s *WSSession) SendPing() {
+func (s *WSSession) SendPing(conn *websocket.Conn) {
 	if s.IsClosed() {
 		return
 	}
-	s.connMu.Lock()
-	defer s.connMu.Unlock()
-	s.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
-	defer s.conn.SetWriteDeadline(time.Time{}) // Clear write deadline after ping
-	if err := s.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	defer conn.SetWriteDeadline(time.Time{}) // Clear write deadline after ping
+	if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 		log.Printf("Ping failed for session %s: %v", s.SessionID, err)
 	}
 }
@@ -180,6 +396,11 @@ func (s *WSSession) SendError(errMsg string) {
 	s.SendMessage("error", map[string]string{"error": errMsg})
 }
 
+// SendErrorCode sends an error with its machine-readable code, see ErrorCode
+func (s *WSSession) SendErrorCode(errMsg, code string) {
+	s.SendMessage("error", map[string]string{"error": errMsg, "code": code})
+}
+
 // HandleApprovalResponse processes an approval response from the client
 // This method is called from the main read loop when an approval_response message is received
 func (s *WSSession) HandleApprovalResponse(approvalID string, results ApprovalResultMap) {
@@ -217,13 +438,61 @@ func (s *WSSession) HandleApprovalResponse(approvalID string, results ApprovalRe
 		// Log and silently ignore - the timeout handler will clean up
 		log.Printf("Session %s: Approval result channel full or closed for %s (timeout may have fired)", s.SessionID, approvalID)
 	}
+	// Let the other connections of the session close their approval dialog
+	s.SendMessage("approval_resolved", map[string]string{"approval_id": approvalID})
 }
 
-// SetApprovalTimeout sets the timeout for approval requests
+// CancelApproval denies the tool calls of the pending approval request as cancelled by
+// the user, unblocking SendApprovalRequest. An empty approvalID cancels any pending
+// request. Returns false if the request is not pending, e.g. it was answered first.
+func (s *WSSession) CancelApproval(approvalID string) bool {
+	s.approvalMu.Lock()
+	req := s.pendingApproval
+	if req == nil || (approvalID != "" && req.ApprovalID != approvalID) {
+		s.approvalMu.Unlock()
+		return false
+	}
+	// Cleared under the lock, so a racing approval response finds no pending request
+	s.pendingApproval = nil
+	s.approvalMu.Unlock()
+
+	log.Printf("Session %s: Approval request %s cancelled", s.SessionID, req.ApprovalID)
+	// The channel is buffered and only written by the holder of the pending request
+	req.ResultChan <- deniedResults(req.Targets, approvalCancelledReason)
+	s.SendMessage("approval_resolved", map[string]string{"approval_id": req.ApprovalID})
+	return true
+}
+
+// HandleQuestionResponse processes the answer to a clarifying question from the client
+func (s *WSSession) HandleQuestionResponse(questionID string, answer string) {
+	s.questionMu.Lock()
+	if s.pendingQuestion == nil || s.pendingQuestion.QuestionID != questionID {
+		s.questionMu.Unlock()
+		log.Printf("Session %s: Ignoring answer for unknown or stale question %s", s.SessionID, questionID)
+		return
+	}
+	answerChan := s.pendingQuestion.AnswerChan
+	s.pendingQuestion = nil
+	s.questionMu.Unlock()
+
+	select {
+	case answerChan <- answer:
+		log.Printf("Session %s: Answer sent successfully for %s", s.SessionID, questionID)
+	default:
+		log.Printf("Session %s: Answer channel full or closed for %s (timeout may have fired)", s.SessionID, questionID)
+	}
+}
+
+// SetApprovalTimeout sets the timeout for approval requests, 0 uses DefaultApprovalTimeout
 func (s *WSSession) SetApprovalTimeout(timeout time.Duration) {
 	s.approvalTimeout = timeout
 }
 
+// SetApprovalLimiter sets the limiter shared by the sessions of a server, nil means no limit
+func (s *WSSession) SetApprovalLimiter(limiter ApprovalLimiter) {
+	s.approvalLimiter = limiter
+}
+
 // SetReadTimeout sets the read timeout used to reset the read deadline after writes.
 func (s *WSSession) SetReadTimeout(d time.Duration) {
 	s.readTimeout = d
@@ -254,14 +523,33 @@ func (h *WSChatHandler) SendChunk(content string, first, last bool, contentType
 }
 
 func (h *WSChatHandler) SendToolCall(name string, arguments string, id string, streaming bool) {
+	// "index" carries the same value as "id" for clients that predate the id field
 	h.session.SendMessage("tool_call", map[string]interface{}{
 		"name":      name,
 		"arguments": arguments,
+		"id":        id,
 		"index":     id,
 		"streaming": streaming,
 	})
 }
 
+// SendToolProgress sends output of a running tool call, keyed by the tool call ID
+func (h *WSChatHandler) SendToolProgress(id string, progress string) {
+	h.session.SendMessage("tool_progress", map[string]string{
+		"id":       id,
+		"progress": progress,
+	})
+}
+
+// SendModelFallback tells the client that a model call was answered by a fallback model
+func (h *WSChatHandler) SendModelFallback(from, to, reason string) {
+	h.session.SendMessage("model_fallback", map[string]string{
+		"from":   from,
+		"to":     to,
+		"reason": reason,
+	})
+}
+
 func (h *WSChatHandler) SendThinking(status bool) {
 	h.session.SendMessage("thinking", map[string]interface{}{"status": status})
 }
@@ -275,6 +563,35 @@ func (h *WSChatHandler) SendError(err string) {
 	h.session.SendError(err)
 }
 
+// SendErrorCode sends an error of the turn with its code
+func (h *WSChatHandler) SendErrorCode(message, code string) {
+	log.Printf("SendError: %v (%s)\n", message, code)
+	h.session.SendErrorCode(message, code)
+}
+
+// SendContentFiltered tells the client that the provider blocked the response
+func (h *WSChatHandler) SendContentFiltered(message string) {
+	log.Printf("SendContentFiltered: %v\n", message)
+	h.session.SendMessage("content_filtered", map[string]string{"message": message})
+}
+
+// SendUsage sends the tokens used by the turn and by the chat to the client
+func (h *WSChatHandler) SendUsage(turn, session TokenUsage) {
+	h.session.SendMessage("usage", map[string]TokenUsage{
+		"turn":    turn,
+		"session": session,
+	})
+}
+
+// SendContextEvent tells the client that older turns are being summarized, or were
+func (h *WSChatHandler) SendContextEvent(event manager.Event) {
+	h.session.SendMessage("context_event", map[string]interface{}{
+		"event":   event.Type,
+		"rounds":  event.Rounds,
+		"summary": event.Summary,
+	})
+}
+
 // SendApprovalRequest sends an approval request to the client and waits for the result
 func (h *WSChatHandler) SendMessageCount() {
 	if h.session != nil {
@@ -282,8 +599,10 @@ func (h *WSChatHandler) SendMessageCount() {
 	}
 }
 
-func (h *WSChatHandler) SendApprovalRequest(targets []ApprovalTarget) (ApprovalResultMap, error) {
+func (h *WSChatHandler) SendApprovalRequest(targets []ApprovalTarget) (results ApprovalResultMap, err error) {
 	session := h.session
+	metrics.ObserveApprovalRequest(len(targets))
+	defer func() { observeApprovalResults(targets, results, err) }()
 
 	// Generate a unique approval ID
 	approvalID := generateApprovalID()
@@ -293,6 +612,7 @@ func (h *WSChatHandler) SendApprovalRequest(targets []ApprovalTarget) (ApprovalR
 	resultChan := make(chan ApprovalResultMap, 1)
 	req := &ApprovalRequest{
 		ApprovalID: approvalID,
+		Targets:    targets,
 		ResultChan: resultChan,
 	}
 
@@ -304,6 +624,17 @@ func (h *WSChatHandler) SendApprovalRequest(targets []ApprovalTarget) (ApprovalR
 			"tool":    t.ToolName,
 			"details": t.ArgumentsInfo,
 		}
+		if t.Preview != "" {
+			targetList[i]["preview"] = t.Preview
+		}
+	}
+
+	// No new approval is asked while the server shuts down
+	select {
+	case <-session.shutdown:
+		log.Printf("Session %s: Denied approval request %s, the server is shutting down", session.SessionID, approvalID)
+		return deniedResults(targets, approvalShutdownReason), nil
+	default:
 	}
 
 	// Store pending approval request (thread-safe)
@@ -313,6 +644,16 @@ func (h *WSChatHandler) SendApprovalRequest(targets []ApprovalTarget) (ApprovalR
 		log.Printf("Session %s: Approval channel busy with pending request %s", session.SessionID, session.pendingApproval.ApprovalID)
 		return nil, fmt.Errorf("approval channel is busy")
 	}
+	// Each pending approval holds this goroutine until it is answered or times out,
+	// so their number is capped across the server
+	if session.approvalLimiter != nil {
+		if !session.approvalLimiter.AcquireApproval() {
+			session.approvalMu.Unlock()
+			log.Printf("Session %s: Rejected approval request %s, too many pending approvals on the server", session.SessionID, approvalID)
+			return nil, fmt.Errorf("too many approval requests are pending on the server, please try again later")
+		}
+		defer session.approvalLimiter.ReleaseApproval()
+	}
 	session.pendingApproval = req
 	session.approvalMu.Unlock()
 
@@ -323,39 +664,168 @@ func (h *WSChatHandler) SendApprovalRequest(targets []ApprovalTarget) (ApprovalR
 		"targets":     targetList,
 	})
 
-	// Wait for response with timeout
+	// Wait for response with timeout, warning the client before it expires
 	timeout := session.approvalTimeout
 	if timeout <= 0 {
 		timeout = DefaultApprovalTimeout
 	}
+	lead := min(approvalWarningLead, timeout/2)
 	log.Printf("Session %s: Waiting for approval response for %s (timeout: %v)", session.SessionID, approvalID, timeout)
 
+	warning := time.NewTimer(timeout - lead)
+	defer warning.Stop()
+	expired := time.NewTimer(timeout)
+	defer expired.Stop()
+	for {
+		select {
+		case result := <-resultChan:
+			log.Printf("Session %s: Received approval response for %s with %d results", session.SessionID, approvalID, len(result))
+			// Clear pending approval
+			session.approvalMu.Lock()
+			session.pendingApproval = nil
+			session.approvalMu.Unlock()
+
+			if result == nil {
+				return nil, fmt.Errorf("approval request got stale response")
+			}
+			return result, nil
+		case <-warning.C:
+			session.SendMessage("approval_warning", map[string]interface{}{
+				"approval_id":       approvalID,
+				"remaining_seconds": int(lead.Round(time.Second).Seconds()),
+			})
+		case <-expired.C:
+			log.Printf("Session %s: Approval request %s timed out after %v", session.SessionID, approvalID, timeout)
+
+			// Clear pending approval on timeout, telling the clients why it was resolved
+			session.clearPendingApproval(approvalID)
+			session.SendMessage("approval_resolved", map[string]string{
+				"approval_id": approvalID,
+				"code":        chaterrors.CodeApprovalTimeout,
+			})
+
+			// Deny the tool calls so the model learns why, instead of aborting the turn
+			return deniedResults(targets, approvalTimedOutReason), nil
+		case <-session.shutdown:
+			log.Printf("Session %s: Approval request %s denied, the server is shutting down", session.SessionID, approvalID)
+			session.clearPendingApproval(approvalID)
+			session.SendMessage("approval_resolved", map[string]string{"approval_id": approvalID})
+			return deniedResults(targets, approvalShutdownReason), nil
+		}
+	}
+}
+
+// clearPendingApproval clears the pending approval request if it is still the given one
+func (s *WSSession) clearPendingApproval(approvalID string) {
+	s.approvalMu.Lock()
+	defer s.approvalMu.Unlock()
+	if s.pendingApproval != nil && s.pendingApproval.ApprovalID == approvalID {
+		s.pendingApproval = nil
+	}
+}
+
+// observeApprovalResults records the result of each tool call of an approval request
+func observeApprovalResults(targets []ApprovalTarget, results ApprovalResultMap, err error) {
+	for _, t := range targets {
+		result := results[t.ID]
+		switch {
+		case err != nil || result == nil:
+			metrics.ObserveApprovalResult(metrics.ApprovalRejected)
+		case result.Approved:
+			metrics.ObserveApprovalResult(metrics.ApprovalApproved)
+		case result.DisapproveReason == nil:
+			metrics.ObserveApprovalResult(metrics.ApprovalDenied)
+		default:
+			switch *result.DisapproveReason {
+			case approvalTimedOutReason:
+				metrics.ObserveApprovalResult(metrics.ApprovalTimeout)
+			case approvalCancelledReason:
+				metrics.ObserveApprovalResult(metrics.ApprovalCancelled)
+			case approvalShutdownReason:
+				metrics.ObserveApprovalResult(metrics.ApprovalShutdown)
+			default:
+				metrics.ObserveApprovalResult(metrics.ApprovalDenied)
+			}
+		}
+	}
+}
+
+// deniedResults denies all targets with the reason
+func deniedResults(targets []ApprovalTarget, reason string) ApprovalResultMap {
+	results := make(ApprovalResultMap, len(targets))
+	for _, t := range targets {
+		reason := reason
+		results[t.ID] = &mcp.ApprovalResult{Approved: false, DisapproveReason: &reason}
+	}
+	return results
+}
+
+// SendQuestion sends a clarifying question to the client and waits for the answer.
+// The approval timeout also applies to questions; the question is aborted when the turn is cancelled.
+func (h *WSChatHandler) SendQuestion(question string) (string, error) {
+	session := h.session
+
+	questionID := generateQuestionID()
+	req := &QuestionRequest{
+		QuestionID: questionID,
+		AnswerChan: make(chan string, 1),
+	}
+
 	select {
-	case result := <-resultChan:
-		log.Printf("Session %s: Received approval response for %s with %d results", session.SessionID, approvalID, len(result))
-		// Clear pending approval
-		session.approvalMu.Lock()
-		session.pendingApproval = nil
-		session.approvalMu.Unlock()
+	case <-session.shutdown:
+		return "", fmt.Errorf("question was not asked, %s", approvalShutdownReason)
+	default:
+	}
+
+	session.questionMu.Lock()
+	if session.pendingQuestion != nil {
+		session.questionMu.Unlock()
+		return "", fmt.Errorf("question channel is busy")
+	}
+	session.pendingQuestion = req
+	session.questionMu.Unlock()
+
+	log.Printf("Session %s: Sending question %s", session.SessionID, questionID)
+	session.SendMessage("question", map[string]interface{}{
+		"question_id": questionID,
+		"question":    question,
+	})
+
+	timeout := session.approvalTimeout
+	if timeout <= 0 {
+		timeout = DefaultApprovalTimeout
+	}
 
-		if result == nil {
-			return nil, fmt.Errorf("approval request got stale response")
+	select {
+	case answer, ok := <-req.AnswerChan:
+		if !ok {
+			return "", fmt.Errorf("question %s was cancelled", questionID)
 		}
-		return result, nil
+		return answer, nil
 	case <-time.After(timeout):
-		log.Printf("Session %s: Approval request %s timed out after %v", session.SessionID, approvalID, timeout)
-
-		// Clear pending approval on timeout
-		session.approvalMu.Lock()
-		if session.pendingApproval != nil && session.pendingApproval.ApprovalID == approvalID {
-			session.pendingApproval = nil
+		log.Printf("Session %s: Question %s timed out after %v", session.SessionID, questionID, timeout)
+		session.questionMu.Lock()
+		if session.pendingQuestion != nil && session.pendingQuestion.QuestionID == questionID {
+			session.pendingQuestion = nil
 		}
-		session.approvalMu.Unlock()
-
-		return nil, fmt.Errorf("approval request timed out after %v", timeout)
+		session.questionMu.Unlock()
+		return "", fmt.Errorf("question timed out after %v", timeout)
+	case <-session.shutdown:
+		log.Printf("Session %s: Question %s cancelled, the server is shutting down", session.SessionID, questionID)
+		session.questionMu.Lock()
+		if session.pendingQuestion != nil && session.pendingQuestion.QuestionID == questionID {
+			session.pendingQuestion = nil
+		}
+		session.questionMu.Unlock()
+		return "", fmt.Errorf("question %s was cancelled, %s", questionID, approvalShutdownReason)
 	}
 }
 
+// generateQuestionID generates a unique question ID
+func generateQuestionID() string {
+	return fmt.Sprintf("question-%d", time.Now().UnixNano())
+}
+
 // generateApprovalID generates a unique approval request ID
 func generateApprovalID() string {
 	return fmt.Sprintf("approval-%d", time.Now().UnixNano())
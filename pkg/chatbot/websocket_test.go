@@ -0,0 +1,696 @@
+package chatbot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newClosedWSSession creates a session without a connection; messages sent to it are dropped
+func newClosedWSSession() *WSSession {
+	session := NewWSSession(nil, "test-session", nil)
+	session.MarkClosed()
+	return session
+}
+
+func TestCancelTurnDuringStream(t *testing.T) {
+	session := newClosedWSSession()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	endTurn := session.BeginTurn(cancel)
+	returned := make(chan struct{})
+	go func() {
+		defer endTurn()
+		defer close(returned)
+		// Simulate a streaming turn that runs until its context is cancelled
+		<-ctx.Done()
+	}()
+
+	if !session.CancelTurn("switching chat", time.Second) {
+		t.Fatal("CancelTurn() = false, want true for an in-flight turn")
+	}
+	select {
+	case <-returned:
+	default:
+		t.Fatal("CancelTurn() returned before the turn finished")
+	}
+	if ctx.Err() == nil {
+		t.Error("turn context was not cancelled")
+	}
+	if !session.IsCancelled() {
+		t.Error("IsCancelled() = false after CancelTurn")
+	}
+	if got := session.CancelReason(); got != "switching chat" {
+		t.Errorf("CancelReason() = %q, want %q", got, "switching chat")
+	}
+
+	// No turn is in flight anymore
+	if session.CancelTurn("switching chat", time.Second) {
+		t.Error("CancelTurn() = true after the turn finished")
+	}
+
+	session.ResetCancel()
+	if got := session.CancelReason(); got != defaultCancelReason {
+		t.Errorf("CancelReason() after ResetCancel = %q, want %q", got, defaultCancelReason)
+	}
+}
+
+func TestCancelTurnAbortsPendingApproval(t *testing.T) {
+	session := newClosedWSSession()
+	handler := NewWSChatHandler(session)
+
+	_, cancel := context.WithCancel(context.Background())
+	endTurn := session.BeginTurn(cancel)
+	results := make(chan ApprovalResultMap, 1)
+	go func() {
+		defer endTurn()
+		res, err := handler.SendApprovalRequest([]ApprovalTarget{{ID: "1", ToolName: "cmd"}})
+		if err != nil {
+			t.Errorf("SendApprovalRequest() error = %v", err)
+		}
+		results <- res
+	}()
+
+	// Wait until the approval request is pending
+	deadline := time.Now().Add(time.Second)
+	for {
+		session.approvalMu.Lock()
+		pending := session.pendingApproval != nil
+		session.approvalMu.Unlock()
+		if pending {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("approval request was not registered")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if !session.CancelTurn("switching chat", time.Second) {
+		t.Fatal("CancelTurn() = false, want true for an in-flight turn")
+	}
+	assertCancelledApproval(t, <-results)
+}
+
+func TestCancelTurnAbortsPendingQuestion(t *testing.T) {
+	session := newClosedWSSession()
+	handler := NewWSChatHandler(session)
+
+	_, cancel := context.WithCancel(context.Background())
+	endTurn := session.BeginTurn(cancel)
+	errs := make(chan error, 1)
+	go func() {
+		defer endTurn()
+		_, err := handler.SendQuestion("which file?")
+		errs <- err
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		session.questionMu.Lock()
+		pending := session.pendingQuestion != nil
+		session.questionMu.Unlock()
+		if pending {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("question was not registered")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if !session.CancelTurn("switching chat", time.Second) {
+		t.Fatal("CancelTurn() = false, want true for an in-flight turn")
+	}
+	if err := <-errs; err == nil {
+		t.Error("SendQuestion() error = nil after CancelTurn, want an error")
+	}
+}
+
+func TestInTurn(t *testing.T) {
+	session := newClosedWSSession()
+	if session.InTurn() {
+		t.Fatal("InTurn() = true before any turn started")
+	}
+
+	_, cancel := context.WithCancel(context.Background())
+	endTurn := session.BeginTurn(cancel)
+	if !session.InTurn() {
+		t.Error("InTurn() = false while a turn is running")
+	}
+	endTurn()
+	if session.InTurn() {
+		t.Error("InTurn() = true after the turn finished")
+	}
+}
+
+func TestHandleQuestionResponseIgnoresStaleID(t *testing.T) {
+	session := newClosedWSSession()
+	handler := NewWSChatHandler(session)
+
+	answers := make(chan string, 1)
+	go func() {
+		answer, _ := handler.SendQuestion("which file?")
+		answers <- answer
+	}()
+
+	var questionID string
+	deadline := time.Now().Add(time.Second)
+	for questionID == "" {
+		session.questionMu.Lock()
+		if session.pendingQuestion != nil {
+			questionID = session.pendingQuestion.QuestionID
+		}
+		session.questionMu.Unlock()
+		if time.Now().After(deadline) {
+			t.Fatal("question was not registered")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Answers for other or stale questions are ignored and leave the question pending
+	session.HandleQuestionResponse("question-0", "wrong")
+	session.HandleQuestionResponse("", "wrong")
+	session.questionMu.Lock()
+	pending := session.pendingQuestion
+	session.questionMu.Unlock()
+	if pending == nil || pending.QuestionID != questionID {
+		t.Fatal("mismatched answer cleared the pending question")
+	}
+	select {
+	case answer := <-answers:
+		t.Fatalf("SendQuestion() returned %q for a mismatched answer", answer)
+	default:
+	}
+
+	session.HandleQuestionResponse(questionID, "main.go")
+	select {
+	case answer := <-answers:
+		if answer != "main.go" {
+			t.Errorf("SendQuestion() = %q, want %q", answer, "main.go")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("SendQuestion() did not return after the matching answer")
+	}
+
+	// A repeated answer for the completed question is ignored
+	session.HandleQuestionResponse(questionID, "again")
+	session.questionMu.Lock()
+	defer session.questionMu.Unlock()
+	if session.pendingQuestion != nil {
+		t.Error("pendingQuestion set after a repeated answer")
+	}
+}
+
+func TestSetCancelledAbortsPendingApproval(t *testing.T) {
+	session := newClosedWSSession()
+	handler := NewWSChatHandler(session)
+
+	_, cancel := context.WithCancel(context.Background())
+	endTurn := session.BeginTurn(cancel)
+	defer endTurn()
+	results := make(chan ApprovalResultMap, 1)
+	go func() {
+		res, _ := handler.SendApprovalRequest([]ApprovalTarget{{ID: "1", ToolName: "cmd"}})
+		results <- res
+	}()
+	waitPendingApproval(t, session)
+
+	session.SetCancelled()
+	select {
+	case res := <-results:
+		assertCancelledApproval(t, res)
+	case <-time.After(time.Second):
+		t.Fatal("SendApprovalRequest() kept waiting after SetCancelled")
+	}
+}
+
+func TestCancelApproval(t *testing.T) {
+	session := newClosedWSSession()
+	handler := NewWSChatHandler(session)
+
+	results := make(chan ApprovalResultMap, 1)
+	go func() {
+		res, _ := handler.SendApprovalRequest([]ApprovalTarget{{ID: "1", ToolName: "cmd"}, {ID: "2", ToolName: "cmd"}})
+		results <- res
+	}()
+	approvalID := waitPendingApproval(t, session)
+
+	if session.CancelApproval("approval-stale") {
+		t.Error("CancelApproval() = true for another approval ID")
+	}
+	if !session.CancelApproval(approvalID) {
+		t.Fatal("CancelApproval() = false for the pending approval")
+	}
+	res := <-results
+	if len(res) != 2 {
+		t.Fatalf("got %d results, want 2", len(res))
+	}
+	assertCancelledApproval(t, res)
+	if session.IsCancelled() {
+		t.Error("CancelApproval() cancelled the turn")
+	}
+	if session.CancelApproval(approvalID) {
+		t.Error("CancelApproval() = true for an approval already cancelled")
+	}
+}
+
+// TestStopRacesApprovalResponse stops the turn while the approval response arrives: exactly
+// one of them resolves the request and no goroutine is left waiting
+func TestStopRacesApprovalResponse(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		session := newClosedWSSession()
+		handler := NewWSChatHandler(session)
+		results := make(chan ApprovalResultMap, 1)
+		go func() {
+			res, _ := handler.SendApprovalRequest([]ApprovalTarget{{ID: "1", ToolName: "cmd"}})
+			results <- res
+		}()
+		approvalID := waitPendingApproval(t, session)
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			session.HandleApprovalResponse(approvalID, ApprovalResultMap{"1": {Approved: true}})
+		}()
+		go func() {
+			defer wg.Done()
+			session.SetCancelled()
+		}()
+		wg.Wait()
+
+		select {
+		case res := <-results:
+			if res["1"] == nil {
+				t.Fatalf("SendApprovalRequest() = %v, want a result for the tool call", res)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("SendApprovalRequest() kept waiting")
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("%d goroutines after the races, %d before", after, before)
+	}
+}
+
+// waitPendingApproval waits until an approval request is pending and returns its ID
+func waitPendingApproval(t *testing.T, session *WSSession) string {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		session.approvalMu.Lock()
+		pending := session.pendingApproval
+		session.approvalMu.Unlock()
+		if pending != nil {
+			return pending.ApprovalID
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("approval request was not registered")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// assertCancelledApproval checks that every tool call was denied as cancelled
+func assertCancelledApproval(t *testing.T, results ApprovalResultMap) {
+	t.Helper()
+	if len(results) == 0 {
+		t.Fatal("got no results, want the tool calls denied as cancelled")
+	}
+	for id, result := range results {
+		if result.Approved || result.DisapproveReason == nil || *result.DisapproveReason != approvalCancelledReason {
+			t.Errorf("result %s = %+v, want denied as cancelled", id, result)
+		}
+	}
+}
+
+// countingLimiter allows up to max pending approvals
+type countingLimiter struct {
+	max, pending int
+}
+
+func (l *countingLimiter) AcquireApproval() bool {
+	if l.pending >= l.max {
+		return false
+	}
+	l.pending++
+	return true
+}
+
+func (l *countingLimiter) ReleaseApproval() { l.pending-- }
+
+func TestSendApprovalRequestRespectsLimiter(t *testing.T) {
+	session := newClosedWSSession()
+	limiter := &countingLimiter{max: 0}
+	session.SetApprovalLimiter(limiter)
+	handler := NewWSChatHandler(session)
+
+	if _, err := handler.SendApprovalRequest([]ApprovalTarget{{ID: "1", ToolName: "cmd"}}); err == nil {
+		t.Fatal("SendApprovalRequest() error = nil with no free slot, want a rejection")
+	}
+	session.approvalMu.Lock()
+	pending := session.pendingApproval
+	session.approvalMu.Unlock()
+	if pending != nil {
+		t.Error("rejected approval request was left pending")
+	}
+
+	// A slot acquired for an approval is released once it ends
+	limiter.max = 1
+	session.SetApprovalTimeout(10 * time.Millisecond)
+	handler.SendApprovalRequest([]ApprovalTarget{{ID: "1", ToolName: "cmd"}})
+	if limiter.pending != 0 {
+		t.Errorf("pending approvals = %d after the request ended, want 0", limiter.pending)
+	}
+}
+
+func TestSendMessageSurvivesDroppedConnection(t *testing.T) {
+	session := NewWSSession(nil, "test-session", nil)
+	serverConns := make(chan *websocket.Conn, 2)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Upgrade() error = %v", err)
+			return
+		}
+		session.AddConn(conn)
+		serverConns <- conn
+	}))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	var clients []*websocket.Conn
+	for i := 0; i < 2; i++ {
+		client, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		if err != nil {
+			t.Fatalf("Dial() error = %v", err)
+		}
+		defer client.Close()
+		clients = append(clients, client)
+	}
+	dropped := <-serverConns
+	<-serverConns
+	if got := session.ConnCount(); got != 2 {
+		t.Fatalf("ConnCount() = %d, want 2", got)
+	}
+
+	// Both connections receive the message
+	session.SendMessage("chunk", map[string]string{"content": "hello"})
+	for i, client := range clients {
+		client.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var msg WSMessage
+		if err := client.ReadJSON(&msg); err != nil {
+			t.Fatalf("client %d ReadJSON() error = %v", i, err)
+		}
+		if msg.Type != "chunk" {
+			t.Errorf("client %d got message type %q, want chunk", i, msg.Type)
+		}
+	}
+
+	// A connection dropping mid-stream is removed, the other keeps receiving
+	dropped.Close()
+	session.SendMessage("chunk", map[string]string{"content": "world"})
+	if got := session.ConnCount(); got != 1 {
+		t.Errorf("ConnCount() = %d after a failed write, want 1", got)
+	}
+	remaining := clients[0]
+	if remaining.LocalAddr().String() == dropped.RemoteAddr().String() {
+		remaining = clients[1]
+	}
+	remaining.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg WSMessage
+	if err := remaining.ReadJSON(&msg); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	var payload map[string]string
+	json.Unmarshal(msg.Payload, &payload)
+	if payload["content"] != "world" {
+		t.Errorf("content = %q, want world", payload["content"])
+	}
+
+	if got := session.RemoveConn(dropped); got != 1 {
+		t.Errorf("RemoveConn() of a dropped connection = %d, want 1", got)
+	}
+}
+
+// frameConn writes each message byte by byte to a shared stream, so concurrent writes
+// interleave their frames and are counted
+type frameConn struct {
+	mu         sync.Mutex
+	stream     []byte
+	writing    atomic.Int32
+	concurrent atomic.Int32
+}
+
+func (c *frameConn) WriteJSON(v interface{}) error {
+	if c.writing.Add(1) > 1 {
+		c.concurrent.Add(1)
+	}
+	defer c.writing.Add(-1)
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	for _, b := range append(data, '\n') {
+		c.mu.Lock()
+		c.stream = append(c.stream, b)
+		c.mu.Unlock()
+		runtime.Gosched()
+	}
+	return nil
+}
+
+func (c *frameConn) Close() error { return nil }
+
+func TestConcurrentSendChunkKeepsFramesIntact(t *testing.T) {
+	session := NewWSSession(nil, "test-session", nil)
+	conn := &frameConn{}
+	session.AddConn(conn)
+
+	const writers, chunks = 20, 50
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < chunks; i++ {
+				session.SendChunk(fmt.Sprintf("writer %d chunk %d", w, i), i == 0, i == chunks-1, "text")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := conn.concurrent.Load(); n > 0 {
+		t.Errorf("%d writes ran concurrently with another one", n)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(conn.stream), "\n"), "\n")
+	if len(lines) != writers*chunks {
+		t.Fatalf("got %d frames, want %d", len(lines), writers*chunks)
+	}
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		var msg WSMessage
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			t.Fatalf("corrupted frame %q: %v", line, err)
+		}
+		var payload struct {
+			Content string `json:"content"`
+		}
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil || msg.Type != "chunk" {
+			t.Fatalf("frame %q is not a chunk: %v", line, err)
+		}
+		seen[payload.Content] = true
+	}
+	if len(seen) != writers*chunks {
+		t.Errorf("got %d distinct chunks, want %d", len(seen), writers*chunks)
+	}
+}
+
+func TestConcurrentSendChunkAndPing(t *testing.T) {
+	session, client := newConnectedWSSession(t)
+	session.connMu.Lock()
+	conn := session.conns[0].(*websocket.Conn)
+	session.connMu.Unlock()
+
+	const writers, chunks = 10, 50
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < chunks; i++ {
+				session.SendChunk(fmt.Sprintf("writer %d chunk %d", w, i), false, false, "text")
+				if i%10 == 0 {
+					session.SendPing(conn)
+				}
+			}
+		}()
+	}
+
+	// A concurrent write would panic in gorilla/websocket or corrupt the frames read here
+	client.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for i := 0; i < writers*chunks; i++ {
+		var msg WSMessage
+		if err := client.ReadJSON(&msg); err != nil {
+			t.Fatalf("ReadJSON() of frame %d error = %v", i, err)
+		}
+		if msg.Type != "chunk" {
+			t.Fatalf("frame %d type = %q, want chunk", i, msg.Type)
+		}
+	}
+	wg.Wait()
+}
+
+// newConnectedWSSession creates a session with one connection and returns the client end
+func newConnectedWSSession(t *testing.T) (*WSSession, *websocket.Conn) {
+	t.Helper()
+	session := NewWSSession(nil, "test-session", nil)
+	upgrader := websocket.Upgrader{}
+	connected := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Upgrade() error = %v", err)
+			return
+		}
+		session.AddConn(conn)
+		close(connected)
+	}))
+	t.Cleanup(server.Close)
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	<-connected
+	return session, client
+}
+
+// readPayload reads the next message, which must be of the given type, into payload
+func readPayload(t *testing.T, client *websocket.Conn, msgType string, payload interface{}) {
+	t.Helper()
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg WSMessage
+	if err := client.ReadJSON(&msg); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if msg.Type != msgType {
+		t.Fatalf("got message type %q, want %q", msg.Type, msgType)
+	}
+	if err := json.Unmarshal(msg.Payload, payload); err != nil {
+		t.Fatalf("invalid %s payload: %v", msgType, err)
+	}
+}
+
+type approvalOutcome struct {
+	results ApprovalResultMap
+	err     error
+}
+
+func TestApprovalTimeoutWarnsAndDenies(t *testing.T) {
+	session, client := newConnectedWSSession(t)
+	session.SetApprovalTimeout(200 * time.Millisecond)
+	outcome := make(chan approvalOutcome, 1)
+	go func() {
+		results, err := NewWSChatHandler(session).SendApprovalRequest([]ApprovalTarget{{ID: "call-1", ToolName: "cmd"}})
+		outcome <- approvalOutcome{results, err}
+	}()
+
+	var request, warning, resolved map[string]interface{}
+	readPayload(t, client, "approval_request", &request)
+	readPayload(t, client, "approval_warning", &warning)
+	if warning["approval_id"] != request["approval_id"] {
+		t.Errorf("warning for %v, want %v", warning["approval_id"], request["approval_id"])
+	}
+	readPayload(t, client, "approval_resolved", &resolved)
+
+	got := <-outcome
+	if got.err != nil {
+		t.Fatalf("SendApprovalRequest() error = %v, want the calls denied", got.err)
+	}
+	result := got.results["call-1"]
+	if result == nil || result.Approved || result.DisapproveReason == nil || *result.DisapproveReason != approvalTimedOutReason {
+		t.Errorf("result = %+v, want denied with reason %q", result, approvalTimedOutReason)
+	}
+}
+
+func TestApprovalResponseAfterWarning(t *testing.T) {
+	session, client := newConnectedWSSession(t)
+	session.SetApprovalTimeout(400 * time.Millisecond)
+	outcome := make(chan approvalOutcome, 1)
+	go func() {
+		results, err := NewWSChatHandler(session).SendApprovalRequest([]ApprovalTarget{{ID: "call-1", ToolName: "cmd"}})
+		outcome <- approvalOutcome{results, err}
+	}()
+
+	var request, warning map[string]interface{}
+	readPayload(t, client, "approval_request", &request)
+	readPayload(t, client, "approval_warning", &warning)
+	session.HandleApprovalResponse(request["approval_id"].(string), ApprovalResultMap{
+		"call-1": {Approved: true},
+	})
+
+	got := <-outcome
+	if got.err != nil {
+		t.Fatalf("SendApprovalRequest() error = %v", got.err)
+	}
+	if result := got.results["call-1"]; result == nil || !result.Approved {
+		t.Errorf("result = %+v, want approved", result)
+	}
+}
+
+func TestShutdownDeniesPendingApproval(t *testing.T) {
+	session, client := newConnectedWSSession(t)
+	outcome := make(chan approvalOutcome, 1)
+	go func() {
+		results, err := NewWSChatHandler(session).SendApprovalRequest([]ApprovalTarget{{ID: "call-1", ToolName: "cmd"}})
+		outcome <- approvalOutcome{results, err}
+	}()
+
+	var request, notice, resolved map[string]interface{}
+	readPayload(t, client, "approval_request", &request)
+	session.BeginShutdown(10 * time.Second)
+	session.BeginShutdown(10 * time.Second)
+	readPayload(t, client, "shutting_down", &notice)
+	if notice["drain_seconds"] != float64(10) {
+		t.Errorf("drain_seconds = %v, want 10", notice["drain_seconds"])
+	}
+	readPayload(t, client, "approval_resolved", &resolved)
+	if resolved["approval_id"] != request["approval_id"] {
+		t.Errorf("resolved %v, want %v", resolved["approval_id"], request["approval_id"])
+	}
+
+	select {
+	case got := <-outcome:
+		result := got.results["call-1"]
+		if got.err != nil || result == nil || result.Approved || result.DisapproveReason == nil || *result.DisapproveReason != approvalShutdownReason {
+			t.Errorf("SendApprovalRequest() = %+v, %v, want denied with reason %q", result, got.err, approvalShutdownReason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pending approval was not released by the shutdown")
+	}
+
+	// Approvals asked after the shutdown started are denied without asking the client
+	results, err := NewWSChatHandler(session).SendApprovalRequest([]ApprovalTarget{{ID: "call-2", ToolName: "cmd"}})
+	if result := results["call-2"]; err != nil || result == nil || result.Approved {
+		t.Errorf("SendApprovalRequest() after shutdown = %+v, %v, want denied", result, err)
+	}
+}
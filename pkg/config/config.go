@@ -5,6 +5,8 @@ import (
 	"os"
 	"strings"
 
+	chaterrors "github.com/Arvintian/chat-agent/pkg/errors"
+	"github.com/Arvintian/chat-agent/pkg/utils"
 	"gopkg.in/yaml.v3"
 )
 
@@ -19,6 +21,44 @@ type Config struct {
 	MCPServers    map[string]MCPServer `yaml:"mcpServers,omitempty"`
 	Tools         map[string]Tool      `yaml:"tools,omitempty"`
 	SystemPrompts map[string]string    `yaml:"systemPrompts,omitempty"`
+	// SystemPreamble and SystemPostamble are added before and after every chat's
+	// system prompt. Both accept the same references as a chat's system prompt.
+	SystemPreamble  string `yaml:"systemPreamble,omitempty"`
+	SystemPostamble string `yaml:"systemPostamble,omitempty"`
+	// MCPAllowedCommands restricts which executables stdio MCP servers may run.
+	// Empty means no restriction.
+	MCPAllowedCommands []string `yaml:"mcpAllowedCommands,omitempty"`
+	// MCPInitConcurrency limits how many MCP servers are initialized in parallel (default 4)
+	MCPInitConcurrency int `yaml:"mcpInitConcurrency,omitempty"`
+	// MCPInitTimeout is the per-server initialization timeout in seconds (default 10)
+	MCPInitTimeout int `yaml:"mcpInitTimeout,omitempty"`
+	// Redaction masks secrets in tool results before they reach the model
+	Redaction *Redaction `yaml:"redaction,omitempty"`
+	// Descriptions overrides the descriptions of builtin and MCP tools, keyed by tool name
+	Descriptions map[string]ToolDescription `yaml:"descriptions,omitempty"`
+	// Audit writes a record of every tool call and its approval to a file
+	Audit *Audit `yaml:"audit,omitempty"`
+}
+
+// Audit configures the audit log of the tool calls
+type Audit struct {
+	Path       string `yaml:"path,omitempty"`       // file the records are appended to
+	MaxSize    int    `yaml:"maxSize,omitempty"`    // size in MB before the file is rotated, default is 100
+	MaxBackups int    `yaml:"maxBackups,omitempty"` // rotated files kept, default is 5
+}
+
+// ToolDescription overrides the description a tool presents to the model
+type ToolDescription struct {
+	Desc   string            `yaml:"desc,omitempty"`   // replaces the tool description
+	Params map[string]string `yaml:"params,omitempty"` // replaces parameter descriptions, keyed by parameter name
+}
+
+// Redaction configures masking of secrets in tool results
+type Redaction struct {
+	Enabled         bool     `yaml:"enabled"`
+	DisableDefaults bool     `yaml:"disableDefaults,omitempty"` // do not use the built-in patterns
+	Patterns        []string `yaml:"patterns,omitempty"`        // additional regular expressions
+	Replacement     string   `yaml:"replacement,omitempty"`     // default is "[REDACTED]"
 }
 
 // UnmarshalYAML implements custom YAML unmarshaling for backward compatibility.
@@ -32,24 +72,122 @@ func (c *Config) UnmarshalYAML(value *yaml.Node) error {
 type Chat struct {
 	Desc              string        `yaml:"desc"`
 	System            string        `yaml:"system"`
+	SystemFiles       []string      `yaml:"systemFiles,omitempty"` // files joined before the system prompt, e.g. a shared base prompt
+	WorkDir           string        `yaml:"workDir,omitempty"`     // overrides the workDir of the builtin tools of the chat
 	InitSystem        string        `yaml:"initSystem,omitempty"`      // System prompt for the first round (no context)
 	Model             string        `yaml:"model"`
+	Fallbacks         []string      `yaml:"fallbacks,omitempty"` // models tried in order when the model's provider is unavailable
 	MaxMessageRounds  int           `yaml:"maxMessageRounds"`
 	FullMessageRounds int           `yaml:"fullMessageRounds,omitempty"`
+	MaxRounds         int           `yaml:"maxRounds,omitempty"`        // hard cap on rounds kept in context, even without compression
+	MaxContextTokens  int           `yaml:"maxContextTokens,omitempty"` // token budget of the context, replaces the round limit
 	MaxIterations     int           `yaml:"maxIterations"`
 	MaxRetries        int           `yaml:"maxRetries"`
+	MaxRetryWait      int           `yaml:"maxRetryWait,omitempty"` // seconds spent waiting between retries of one model call (default: 120)
 	MCPServers        []string      `yaml:"mcpServers,omitempty"`
 	Skill             *Skill        `yaml:"skill,omitempty"`
 	Tools             []string      `yaml:"tools,omitempty"`
 	Default           bool          `yaml:"default"`
 	Hooks             *SessionHooks `yaml:"hooks,omitempty"`
 	Persistence       bool          `yaml:"persistence"`
+	// RemoteInstruction is fetched at session init and appended to the system prompt
+	RemoteInstruction *RemoteInstruction `yaml:"remoteInstruction,omitempty"`
+	// FileRouting routes attached files to a tool before the message reaches the model
+	FileRouting []FileRoute `yaml:"fileRouting,omitempty"`
+	// ApprovalTimeout is the number of seconds an approval request waits for an answer in
+	// serve mode before its tool calls are denied, 0 means 5 minutes
+	ApprovalTimeout int `yaml:"approvalTimeout,omitempty"`
+	// MaxApprovalTargets caps the tool calls in a single approval request, 0 means no cap
+	MaxApprovalTargets int `yaml:"maxApprovalTargets,omitempty"`
+	// ApprovalOverflow is what happens to tool calls beyond MaxApprovalTargets:
+	// "batch" (default) asks for them in further requests, "deny" denies them
+	ApprovalOverflow string `yaml:"approvalOverflow,omitempty"`
+	// RepeatedFailures stops the model from retrying a tool call that keeps failing the same way
+	RepeatedFailures *RepeatedFailures `yaml:"repeatedFailures,omitempty"`
+	// ProjectTree adds a tree of the working directory to the system prompt
+	ProjectTree *ProjectTree `yaml:"projectTree,omitempty"`
+	// PlanMode asks the model for a plan before it calls tools and holds the first tool
+	// calls of each turn in serve mode until the user approves the plan
+	PlanMode bool `yaml:"planMode,omitempty"`
+	// RequestTimeout bounds a single turn in serve mode, in seconds. 0 means no timeout.
+	RequestTimeout int `yaml:"requestTimeout,omitempty"`
+	// MaxChunkLength splits streamed chunks longer than this many characters, 0 means no limit
+	MaxChunkLength int `yaml:"maxChunkLength,omitempty"`
+	// StoreReasoning keeps the reasoning of assistant messages in the context, default is true.
+	// Reasoning is still shown live when it is not stored.
+	StoreReasoning *bool `yaml:"storeReasoning,omitempty"`
+	// ReasoningDisplay is how the reasoning of the model is shown: "show" (default), "summary"
+	// shows a thinking indicator without the reasoning, "hide" shows nothing
+	ReasoningDisplay string `yaml:"reasoningDisplay,omitempty"`
+	// ExtractDocuments sends the text of attached documents (PDF, Office, text files)
+	// instead of the files, for models that only read text
+	ExtractDocuments bool `yaml:"extractDocuments,omitempty"`
+	// Uploads limits the files attached to the messages of the web clients
+	Uploads *Uploads `yaml:"uploads,omitempty"`
+}
+
+// Uploads limits the files attached to a message, 0 or empty means no limit
+type Uploads struct {
+	MaxFiles     int      `yaml:"maxFiles,omitempty"`     // files per message
+	MaxSize      int      `yaml:"maxSize,omitempty"`      // total size in MB of the files of a message
+	AllowedTypes []string `yaml:"allowedTypes,omitempty"` // MIME types (image/png), MIME prefixes (image/*) or extensions (.pdf)
+}
+
+// StoresReasoning reports whether reasoning is kept in the context of the chat
+func (c Chat) StoresReasoning() bool {
+	return c.StoreReasoning == nil || *c.StoreReasoning
+}
+
+// ProjectTree configures the project layout included in the system prompt
+type ProjectTree struct {
+	Dir        string `yaml:"dir,omitempty"`        // default is the current directory
+	MaxDepth   int    `yaml:"maxDepth,omitempty"`   // default is 3
+	MaxEntries int    `yaml:"maxEntries,omitempty"` // default is 200
+	CacheTTL   int    `yaml:"cacheTtl,omitempty"`   // in seconds, default is 300
+}
+
+// RepeatedFailures configures detection of identical failing tool calls within a turn
+type RepeatedFailures struct {
+	MaxRepeats int      `yaml:"maxRepeats,omitempty"` // identical failures before the call is short-circuited, default is 2
+	Markers    []string `yaml:"markers,omitempty"`    // additional result texts that mark a tool call as failed
+}
+
+// Reasoning display modes of a chat
+const (
+	ReasoningDisplayShow    = "show"
+	ReasoningDisplaySummary = "summary"
+	ReasoningDisplayHide    = "hide"
+)
+
+const (
+	// ApprovalOverflowBatch asks for tool calls beyond the cap in further approval requests
+	ApprovalOverflowBatch = "batch"
+	// ApprovalOverflowDeny denies tool calls beyond the cap without asking
+	ApprovalOverflowDeny = "deny"
+)
+
+// FileRoute maps attached file types to a tool that pre-processes them
+type FileRoute struct {
+	Types []string `yaml:"types"`          // MIME types (text/csv), MIME prefixes (text/*) or extensions (.csv)
+	Tool  string   `yaml:"tool"`           // name of a tool loaded in the chat
+	Args  string   `yaml:"args,omitempty"` // arguments template, default passes name, type, url and content as JSON
+}
+
+// RemoteInstruction represents an instruction fragment fetched from a URL
+type RemoteInstruction struct {
+	URL      string            `yaml:"url"`
+	Headers  map[string]string `yaml:"headers,omitempty"`
+	Timeout  int               `yaml:"timeout,omitempty"`  // in seconds, default is 10
+	CacheTTL int               `yaml:"cacheTtl,omitempty"` // in seconds, default is 300
 }
 
 // SessionHooks represents session-related hooks configuration
 type SessionHooks struct {
 	Keep          *SessionHookConfig `yaml:"keep,omitempty"`
 	GenModelInput *SessionHookConfig `yaml:"genModelInput,omitempty"`
+	// OnResponse receives the final assistant message of a turn and may replace the
+	// message stored in the context. The streamed response is not changed.
+	OnResponse *SessionHookConfig `yaml:"onResponse,omitempty"`
 }
 
 // SessionHookConfig represents the configuration for a single hook
@@ -63,6 +201,7 @@ type SessionHookConfig struct {
 	Args       []string          `yaml:"args,omitempty"`
 	Timeout    int               `yaml:"timeout,omitempty"` // in seconds, default is 30
 	Env        map[string]string `yaml:"env,omitempty"`     // environment variables for the hook script
+	Proxy      string            `yaml:"proxy,omitempty"`   // proxy URL for http type, the environment proxy when empty
 }
 
 type Skill struct {
@@ -80,6 +219,32 @@ type Provider struct {
 	APIKey  string            `yaml:"apiKey,omitempty"`
 	Headers map[string]string `yaml:"headers,omitempty"`
 	Timeout int               `yaml:"timeout,omitempty"` // in seconds
+	// Proxy is the http://, https:// or socks5:// URL of the proxy the requests go
+	// through, the proxy of the environment is used when it is empty
+	Proxy string `yaml:"proxy,omitempty"`
+	// Project and Location are the Google Cloud project and region of a vertex provider
+	Project  string `yaml:"project,omitempty"`
+	Location string `yaml:"location,omitempty"`
+	// CredentialsFile is the service account key file of a vertex provider, the application
+	// default credentials are used when it is empty
+	CredentialsFile string `yaml:"credentialsFile,omitempty"`
+	// Region and Profile are the AWS region and the shared credentials profile of a bedrock
+	// provider, the AWS credential chain is used when no profile is set
+	Region  string `yaml:"region,omitempty"`
+	Profile string `yaml:"profile,omitempty"`
+	// Defaults holds sampling parameters inherited by every model of the provider
+	// unless the model sets them itself
+	Defaults *ModelDefaults `yaml:"defaults,omitempty"`
+}
+
+// ModelDefaults holds the default sampling parameters of a provider
+type ModelDefaults struct {
+	ReasoningEffort *string        `yaml:"reasoningEffort,omitempty"`
+	MaxTokens       int            `yaml:"maxTokens,omitempty"`
+	Temperature     float64        `yaml:"temperature,omitempty"`
+	TopP            float64        `yaml:"topP,omitempty"`
+	TopK            int            `yaml:"topK,omitempty"`
+	ExtraBody       map[string]any `yaml:"extraBody,omitempty"`
 }
 
 // ModelParams holds the common parameters for a model configuration.
@@ -94,6 +259,18 @@ type ModelParams struct {
 	TopP            float64        `yaml:"topP,omitempty"`
 	TopK            int            `yaml:"topK,omitempty"`
 	ExtraBody       map[string]any `yaml:"extraBody"`
+	// Stop sequences end the generation, Seed makes the sampling reproducible. They and
+	// the penalties are ignored by the providers that can't send them
+	Stop             []string `yaml:"stop,omitempty"`
+	Seed             *int     `yaml:"seed,omitempty"`
+	FrequencyPenalty float64  `yaml:"frequencyPenalty,omitempty"`
+	PresencePenalty  float64  `yaml:"presencePenalty,omitempty"`
+	// CachePrompt marks the system prompt and tools as cacheable, for providers with
+	// explicit prompt caching (claude)
+	CachePrompt bool `yaml:"cachePrompt,omitempty"`
+	// Multimodal marks a model reading attached documents itself, extractDocuments keeps
+	// sending them as files
+	Multimodal bool `yaml:"multimodal,omitempty"`
 }
 
 // Model represents AI model configuration
@@ -118,6 +295,7 @@ type MCPServer struct {
 	// for sse & streamable-http
 	URL               string            `yaml:"url,omitempty"`
 	Headers           map[string]string `yaml:"headers,omitempty"`
+	Proxy             string            `yaml:"proxy,omitempty"` // proxy URL, the environment proxy when empty
 	AutoApproval      bool              `yaml:"autoApproval"`
 	AutoApprovalTools []string          `yaml:"autoApprovalTools"`
 	// Tool filtering: include only these tools (if non-empty, only these tools are kept)
@@ -133,6 +311,11 @@ type MCPServer struct {
 	// LowercaseTools: if true, all discovered tool names are lowercased before
 	// filtering (include/exclude/autoApprovalTools/noConcurrentTools) and registration.
 	LowercaseTools bool `yaml:"lowercaseTools,omitempty"`
+	// Retries: number of times a tool call is retried after a transport error (default: 0).
+	// Errors returned by the server are not retried.
+	Retries int `yaml:"retries,omitempty"`
+	// RetryBackoff: milliseconds before the first retry, doubled for each further retry (default: 500)
+	RetryBackoff int `yaml:"retryBackoff,omitempty"`
 }
 
 type Tool struct {
@@ -140,10 +323,55 @@ type Tool struct {
 	Params            map[string]interface{} `yaml:"params"`
 	AutoApproval      bool                   `yaml:"autoApproval"`
 	AutoApprovalTools []string               `yaml:"autoApprovalTools"`
+	// ApprovalRules decide the approval of the tool calls from their arguments, the first
+	// matching rule wins and calls no rule matches ask for approval
+	ApprovalRules []ApprovalRule `yaml:"approvalRules,omitempty"`
+}
+
+// Actions of an approval rule
+const (
+	ApprovalApprove = "approve"
+	ApprovalDeny    = "deny"
+	ApprovalAsk     = "ask"
+)
+
+// ApprovalRule matches tool calls by tool name and arguments. All the conditions set must
+// match, a rule without conditions matches every call.
+type ApprovalRule struct {
+	// Tool: name of the tool, empty matches every tool of the category
+	Tool string `yaml:"tool,omitempty"`
+	// Args: regular expression searched in the JSON arguments of the call
+	Args string `yaml:"args,omitempty"`
+	// Params: regular expressions each matching the value of an argument, e.g.
+	// command: '^git (status|diff|log)\b'
+	Params map[string]string `yaml:"params,omitempty"`
+	// Action: approve, deny or ask
+	Action string `yaml:"action"`
+	// Reason: told to the model when a call is denied (optional)
+	Reason string `yaml:"reason,omitempty"`
 }
 
 // LoadConfig loads configuration from file and saves to global variable
 func LoadConfig(configPath string) (*Config, error) {
+	return LoadConfigProfile(configPath, "")
+}
+
+// LoadConfigProfile loads configuration from file like LoadConfig, with the named profile
+// of its profiles section merged over it, see applyProfile. No profile loads the file as is.
+func LoadConfigProfile(configPath, profile string) (*Config, error) {
+	cfg, err := parseConfigFile(configPath, profile)
+	if err != nil {
+		return nil, chaterrors.Wrap(chaterrors.ErrConfigInvalid, err)
+	}
+
+	// Save to global variable
+	globalConfig = cfg
+
+	return cfg, nil
+}
+
+// parseConfigFile reads, parses and validates the configuration file
+func parseConfigFile(configPath, profile string) (*Config, error) {
 	// Check if configuration file exists
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
 		return nil, fmt.Errorf("configuration file does not exist: %s", configPath)
@@ -156,14 +384,27 @@ func LoadConfig(configPath string) (*Config, error) {
 	}
 
 	// Parse YAML
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse configuration file: %w", err)
+	}
+	if profile != "" {
+		if err := applyProfile(&doc, profile); err != nil {
+			return nil, fmt.Errorf("configuration file %s: %w", configPath, err)
+		}
+	}
 	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	if len(doc.Content) > 0 {
+		if err := doc.Decode(&cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse configuration file: %w", err)
+		}
+	}
+	if err := cfg.expandEnvVars(); err != nil {
 		return nil, fmt.Errorf("failed to parse configuration file: %w", err)
 	}
-
-	// Save to global variable
-	globalConfig = &cfg
-
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration file %s:\n%w", configPath, err)
+	}
 	return &cfg, nil
 }
 
@@ -179,7 +420,10 @@ func GetConfig() *Config {
 func ResolveSystemPrompt(cfg *Config, prompt string) (string, error) {
 	const filePrefix = "@file:"
 	if strings.HasPrefix(prompt, filePrefix) {
-		filePath := strings.TrimSpace(prompt[len(filePrefix):])
+		filePath, err := utils.ExpandPath(strings.TrimSpace(prompt[len(filePrefix):]))
+		if err != nil {
+			return "", fmt.Errorf("invalid system prompt file: %w", err)
+		}
 		data, err := os.ReadFile(filePath)
 		if err != nil {
 			return "", fmt.Errorf("failed to read system prompt file %s: %w", filePath, err)
@@ -192,6 +436,72 @@ func ResolveSystemPrompt(cfg *Config, prompt string) (string, error) {
 	return prompt, nil
 }
 
+// ChatSystemPrompt composes the system prompt of a chat: the contents of its system
+// files in order, followed by its resolved system prompt. Empty parts are skipped.
+func ChatSystemPrompt(cfg *Config, chat Chat) (string, error) {
+	var parts []string
+	for _, file := range chat.SystemFiles {
+		path, err := utils.ExpandPath(file)
+		if err != nil {
+			return "", fmt.Errorf("invalid system file %q: %w", file, err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read system file %s: %w", path, err)
+		}
+		if part := strings.TrimSpace(string(data)); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	prompt, err := ResolveSystemPrompt(cfg, chat.System)
+	if err != nil {
+		return "", err
+	}
+	if prompt != "" {
+		parts = append(parts, prompt)
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// ChatToolParams returns the params of a builtin tool used by a chat: the params of the
+// tool config, with workDir replaced by the workDir of the chat when it is set. ~ and
+// relative paths are expanded. The params of the tool config are not modified.
+func ChatToolParams(chat Chat, tool Tool) (map[string]interface{}, error) {
+	if chat.WorkDir == "" {
+		return tool.Params, nil
+	}
+	workDir, err := utils.ExpandPath(chat.WorkDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid workDir %q: %w", chat.WorkDir, err)
+	}
+	params := make(map[string]interface{}, len(tool.Params)+1)
+	for k, v := range tool.Params {
+		params[k] = v
+	}
+	params["workDir"] = workDir
+	return params, nil
+}
+
+// WrapSystemPrompt adds the global system preamble and postamble around a chat's
+// resolved system prompt. Empty parts are skipped.
+func WrapSystemPrompt(cfg *Config, prompt string) (string, error) {
+	preamble, err := ResolveSystemPrompt(cfg, cfg.SystemPreamble)
+	if err != nil {
+		return "", err
+	}
+	postamble, err := ResolveSystemPrompt(cfg, cfg.SystemPostamble)
+	if err != nil {
+		return "", err
+	}
+	var parts []string
+	for _, part := range []string{preamble, prompt, postamble} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
+
 // normalizeNodeKeys recursively normalizes mapping node keys from snake_case to camelCase.
 // This provides backward compatibility: old configs with snake_case keys still work.
 // Keys inside extraBody / extra_body values are left untouched to preserve the original
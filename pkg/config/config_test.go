@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"gopkg.in/yaml.v3"
@@ -432,6 +434,9 @@ func TestExtraBodyKeysPreserved(t *testing.T) {
 	tmp := t.TempDir()
 	path := tmp + "/config.yml"
 	data := `
+providers:
+  openai:
+    type: openai
 models:
   gpt4:
     provider: openai
@@ -478,6 +483,9 @@ func TestExtraBodyMixedCase(t *testing.T) {
 	tmp := t.TempDir()
 	path := tmp + "/config.yml"
 	data := `
+providers:
+  openai:
+    type: openai
 models:
   gpt4:
     provider: openai
@@ -580,3 +588,230 @@ func TestResolveSystemPrompt(t *testing.T) {
 		t.Errorf("ResolveSystemPrompt(@file:) = %q, want %q", got, content)
 	}
 }
+
+func TestWrapSystemPrompt(t *testing.T) {
+	cfg := &Config{
+		SystemPrompts: map[string]string{
+			"policy": "Follow the usage policy.",
+		},
+		SystemPreamble:  "policy",
+		SystemPostamble: "Be concise.",
+	}
+
+	got, err := WrapSystemPrompt(cfg, "You are a helpful assistant.")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "Follow the usage policy.\n\nYou are a helpful assistant.\n\nBe concise."
+	if got != want {
+		t.Errorf("WrapSystemPrompt() = %q, want %q", got, want)
+	}
+
+	got, err = WrapSystemPrompt(cfg, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want = "Follow the usage policy.\n\nBe concise."
+	if got != want {
+		t.Errorf("WrapSystemPrompt(empty) = %q, want %q", got, want)
+	}
+
+	got, err = WrapSystemPrompt(&Config{}, "prompt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "prompt" {
+		t.Errorf("WrapSystemPrompt(no wrappers) = %q, want %q", got, "prompt")
+	}
+
+	cfg.SystemPreamble = "@file:/nonexistent/path.txt"
+	if _, err := WrapSystemPrompt(cfg, "prompt"); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestChatSystemPrompt(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.md")
+	if err := os.WriteFile(base, []byte("You are {{.os}} expert.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", dir)
+	t.Setenv("USERPROFILE", dir)
+	if err := os.WriteFile(filepath.Join(dir, "team.md"), []byte("Use the team conventions."), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{SystemPrompts: map[string]string{"reviewer": "Review the code."}}
+	chat := Chat{SystemFiles: []string{base, "~/team.md"}, System: "reviewer"}
+	got, err := ChatSystemPrompt(cfg, chat)
+	if err != nil {
+		t.Fatalf("ChatSystemPrompt() error = %v", err)
+	}
+	want := "You are {{.os}} expert.\n\nUse the team conventions.\n\nReview the code."
+	if got != want {
+		t.Errorf("ChatSystemPrompt() = %q, want %q", got, want)
+	}
+
+	if got, err := ChatSystemPrompt(cfg, Chat{System: "inline"}); err != nil || got != "inline" {
+		t.Errorf("ChatSystemPrompt(no files) = %q, %v, want the system prompt", got, err)
+	}
+
+	missing := filepath.Join(dir, "missing.md")
+	_, err = ChatSystemPrompt(cfg, Chat{SystemFiles: []string{missing}})
+	if err == nil || !strings.Contains(err.Error(), missing) {
+		t.Errorf("ChatSystemPrompt(missing file) error = %v, want it to name the file", err)
+	}
+}
+
+func TestChatToolParams(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("USERPROFILE", dir)
+	tool := Tool{Category: "filesystem", Params: map[string]interface{}{"workDir": "/srv/shared", "maxBytes": 100}}
+
+	params, err := ChatToolParams(Chat{}, tool)
+	if err != nil || params["workDir"] != "/srv/shared" {
+		t.Errorf("ChatToolParams(no workDir) = %v, %v, want the tool params", params, err)
+	}
+
+	params, err = ChatToolParams(Chat{WorkDir: "~/project"}, tool)
+	if err != nil {
+		t.Fatalf("ChatToolParams() error = %v", err)
+	}
+	if want := filepath.Join(dir, "project"); params["workDir"] != want || params["maxBytes"] != 100 {
+		t.Errorf("ChatToolParams() = %v, want workDir %s with the other params", params, want)
+	}
+	if tool.Params["workDir"] != "/srv/shared" {
+		t.Errorf("the tool config was modified: %v", tool.Params)
+	}
+
+	params, err = ChatToolParams(Chat{WorkDir: "project"}, Tool{})
+	if err != nil || !filepath.IsAbs(params["workDir"].(string)) {
+		t.Errorf("ChatToolParams(relative workDir) = %v, %v, want an absolute workDir", params, err)
+	}
+}
+
+func TestChatStoresReasoning(t *testing.T) {
+	var cfg Config
+	data := "chats:\n  default:\n    model: m\n  lean:\n    model: m\n    store_reasoning: false\n"
+	if err := yaml.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatalf("failed to unmarshal config: %v", err)
+	}
+	if !cfg.Chats["default"].StoresReasoning() {
+		t.Error("StoresReasoning() = false without the option, want true")
+	}
+	if cfg.Chats["lean"].StoresReasoning() {
+		t.Error("StoresReasoning() = true with storeReasoning: false, want false")
+	}
+}
+
+func TestValidateReportsBrokenReferences(t *testing.T) {
+	cfg := &Config{
+		Providers: map[string]Provider{"openai": {Type: "openai"}},
+		Models: map[string]Model{
+			"gpt":   {ModelParams: ModelParams{Provider: "openai"}},
+			"typo":  {ModelParams: ModelParams{Provider: "opnai"}},
+			"mixed": {Mixed: []MixedModel{{ModelParams: ModelParams{Provider: "openai"}}, {ModelParams: ModelParams{Provider: "claude"}}}},
+		},
+		Chats: map[string]Chat{
+			"default": {Model: "gpt"},
+			"code":    {Model: "gtp"},
+			"empty":   {},
+			"backup":  {Model: "gpt", Fallbacks: []string{"mixed", "cluade"}},
+			"quiet":   {Model: "gpt", ReasoningDisplay: "hidden"},
+		},
+	}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate() = nil, want the broken references")
+	}
+	for _, want := range []string{
+		`models.typo: provider "opnai" does not exist (available: openai)`,
+		`models.mixed.mixed[1]: provider "claude" does not exist`,
+		`chats.code: model "gtp" does not exist (available: gpt, mixed, typo)`,
+		`chats.empty: model is required`,
+		`chats.backup.fallbacks[1]: model "cluade" does not exist`,
+		`chats.quiet: unknown reasoningDisplay "hidden" (show, summary or hide)`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, want it to contain %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "chats.default") || strings.Contains(err.Error(), "models.gpt:") {
+		t.Errorf("Validate() = %v, reports valid references", err)
+	}
+
+	delete(cfg.Models, "typo")
+	delete(cfg.Models, "mixed")
+	delete(cfg.Chats, "code")
+	delete(cfg.Chats, "empty")
+	delete(cfg.Chats, "backup")
+	cfg.Chats["quiet"] = Chat{Model: "gpt", ReasoningDisplay: ReasoningDisplayHide}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() = %v for a valid configuration", err)
+	}
+}
+
+func TestValidateApprovalRules(t *testing.T) {
+	cfg := &Config{
+		Tools: map[string]Tool{
+			"cmd": {Category: "cmd", ApprovalRules: []ApprovalRule{
+				{Tool: "cmd", Params: map[string]string{"command": `^git (status|diff|log)\b`}, Action: ApprovalApprove},
+				{Action: "allow"},
+				{Args: "(", Action: ApprovalDeny},
+				{Params: map[string]string{"command": "["}, Action: ApprovalAsk},
+				{},
+			}},
+		},
+	}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate() = nil, want the invalid approval rules")
+	}
+	for _, want := range []string{
+		`tools.cmd.approvalRules[1]: unknown action "allow"`,
+		`tools.cmd.approvalRules[2]: invalid args pattern`,
+		`tools.cmd.approvalRules[3]: invalid pattern of param command`,
+		`tools.cmd.approvalRules[4]: action is required`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, want it to contain %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "approvalRules[0]") {
+		t.Errorf("Validate() = %v, reports a valid rule", err)
+	}
+}
+
+func TestValidateProxies(t *testing.T) {
+	cfg := &Config{
+		Providers: map[string]Provider{
+			"corp":  {Type: "openai", Proxy: "socks5://127.0.0.1:1080"},
+			"local": {Type: "ollama", Proxy: "ftp://proxy:21"},
+		},
+		MCPServers: map[string]MCPServer{
+			"remote": {Type: "sse", URL: "https://mcp.example.com/sse", Proxy: "proxy:8080"},
+		},
+		Models: map[string]Model{"m": {ModelParams: ModelParams{Provider: "corp", Model: "m"}}},
+		Chats: map[string]Chat{
+			"default": {Model: "m", Hooks: &SessionHooks{OnResponse: &SessionHookConfig{Type: "http", Proxy: "http://"}}},
+		},
+	}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate() = nil, want the invalid proxy URLs")
+	}
+	for _, want := range []string{
+		`providers.local: invalid proxy URL "ftp://proxy:21"`,
+		`mcpServers.remote: invalid proxy URL "proxy:8080"`,
+		`chats.default.hooks.onResponse: invalid proxy URL "http://"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, want it to contain %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "providers.corp") {
+		t.Errorf("Validate() = %v, reports a valid proxy", err)
+	}
+}
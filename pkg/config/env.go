@@ -0,0 +1,150 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// expandEnv replaces ${VAR} and ${VAR:-default} references in s with the value of the
+// environment variable. The default is used when the variable is unset or empty. A
+// variable that is unset and has no default is an error, so a missing secret doesn't
+// silently become an empty value. $${ is kept as a literal ${.
+func expandEnv(s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var sb strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			sb.WriteString(s)
+			return sb.String(), nil
+		}
+		if start > 0 && s[start-1] == '$' {
+			sb.WriteString(s[:start])
+			sb.WriteString("{")
+			s = s[start+2:]
+			continue
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated variable reference in %q", s)
+		}
+		ref := s[start+2 : start+end]
+		name, def, hasDefault := strings.Cut(ref, ":-")
+		if name == "" {
+			return "", fmt.Errorf("empty variable reference in %q", s)
+		}
+		value, ok := os.LookupEnv(name)
+		if value == "" && hasDefault {
+			value = def
+		} else if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		sb.WriteString(s[:start])
+		sb.WriteString(value)
+		s = s[start+end+1:]
+	}
+}
+
+// expandEnvMap expands the values of m in place
+func expandEnvMap(m map[string]string, field string) error {
+	for key, value := range m {
+		expanded, err := expandEnv(value)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", field, key, err)
+		}
+		m[key] = expanded
+	}
+	return nil
+}
+
+// expandEnvVars expands environment variable references in the fields holding
+// addresses and secrets: provider URLs, API keys, headers, projects and credential
+// files, MCP server URLs, headers
+// and environments, and session hook URLs, headers and environments. Other values,
+// system prompts for example, are kept verbatim.
+func (c *Config) expandEnvVars() error {
+	var err error
+	for _, name := range sortedNames(c.Providers) {
+		provider := c.Providers[name]
+		field := "providers." + name
+		if provider.BaseURL, err = expandEnv(provider.BaseURL); err != nil {
+			return fmt.Errorf("%s.baseUrl: %w", field, err)
+		}
+		if provider.APIKey, err = expandEnv(provider.APIKey); err != nil {
+			return fmt.Errorf("%s.apiKey: %w", field, err)
+		}
+		if err := expandEnvMap(provider.Headers, field+".headers"); err != nil {
+			return err
+		}
+		if provider.Project, err = expandEnv(provider.Project); err != nil {
+			return fmt.Errorf("%s.project: %w", field, err)
+		}
+		if provider.CredentialsFile, err = expandEnv(provider.CredentialsFile); err != nil {
+			return fmt.Errorf("%s.credentialsFile: %w", field, err)
+		}
+		if provider.Proxy, err = expandEnv(provider.Proxy); err != nil {
+			return fmt.Errorf("%s.proxy: %w", field, err)
+		}
+		c.Providers[name] = provider
+	}
+	for _, name := range sortedNames(c.MCPServers) {
+		server := c.MCPServers[name]
+		field := "mcpServers." + name
+		if server.URL, err = expandEnv(server.URL); err != nil {
+			return fmt.Errorf("%s.url: %w", field, err)
+		}
+		if err := expandEnvMap(server.Headers, field+".headers"); err != nil {
+			return err
+		}
+		if err := expandEnvMap(server.Env, field+".env"); err != nil {
+			return err
+		}
+		if server.Proxy, err = expandEnv(server.Proxy); err != nil {
+			return fmt.Errorf("%s.proxy: %w", field, err)
+		}
+		c.MCPServers[name] = server
+	}
+	for _, name := range sortedNames(c.Chats) {
+		hooks := c.Chats[name].Hooks
+		if hooks == nil {
+			continue
+		}
+		for _, hook := range []struct {
+			name   string
+			config *SessionHookConfig
+		}{{"keep", hooks.Keep}, {"genModelInput", hooks.GenModelInput}, {"onResponse", hooks.OnResponse}} {
+			if hook.config == nil {
+				continue
+			}
+			field := "chats." + name + ".hooks." + hook.name
+			if hook.config.URL, err = expandEnv(hook.config.URL); err != nil {
+				return fmt.Errorf("%s.url: %w", field, err)
+			}
+			if err := expandEnvMap(hook.config.Headers, field+".headers"); err != nil {
+				return err
+			}
+			if err := expandEnvMap(hook.config.Env, field+".env"); err != nil {
+				return err
+			}
+			if hook.config.Proxy, err = expandEnv(hook.config.Proxy); err != nil {
+				return fmt.Errorf("%s.proxy: %w", field, err)
+			}
+		}
+	}
+	return nil
+}
+
+// sortedNames returns the keys of m in order, so the first error reported doesn't change
+// between runs
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("CHAT_AGENT_TEST_KEY", "secret")
+	t.Setenv("CHAT_AGENT_TEST_EMPTY", "")
+
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"plain", "plain", false},
+		{"${CHAT_AGENT_TEST_KEY}", "secret", false},
+		{"Bearer ${CHAT_AGENT_TEST_KEY}!", "Bearer secret!", false},
+		{"${CHAT_AGENT_TEST_UNSET:-fallback}", "fallback", false},
+		{"${CHAT_AGENT_TEST_EMPTY:-fallback}", "fallback", false},
+		{"${CHAT_AGENT_TEST_EMPTY}", "", false},
+		{"${CHAT_AGENT_TEST_KEY:-fallback}", "secret", false},
+		{"${CHAT_AGENT_TEST_UNSET:-}", "", false},
+		{"$${CHAT_AGENT_TEST_KEY}", "${CHAT_AGENT_TEST_KEY}", false},
+		{"$HOME stays", "$HOME stays", false},
+		{"${CHAT_AGENT_TEST_UNSET}", "", true},
+		{"${CHAT_AGENT_TEST_KEY", "", true},
+		{"${}", "", true},
+	}
+	for _, tt := range tests {
+		got, err := expandEnv(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("expandEnv(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("expandEnv(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigExpandsEnv(t *testing.T) {
+	t.Setenv("CHAT_AGENT_TEST_KEY", "sk-from-env")
+	t.Setenv("CHAT_AGENT_TEST_TOKEN", "token")
+	content := `
+providers:
+  openai:
+    type: openai
+    baseUrl: ${CHAT_AGENT_TEST_URL:-https://api.openai.com/v1}
+    apiKey: ${CHAT_AGENT_TEST_KEY}
+models:
+  gpt:
+    provider: openai
+    model: gpt-4o
+mcpServers:
+  github:
+    type: stdio
+    cmd: github-mcp
+    env:
+      TOKEN: ${CHAT_AGENT_TEST_TOKEN}
+chats:
+  default:
+    model: gpt
+    system: "Keep ${THIS} as written"
+    hooks:
+      keep:
+        enabled: true
+        type: http
+        url: ${CHAT_AGENT_TEST_HOOK_URL:-http://localhost:8080/keep}
+`
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	provider := cfg.Providers["openai"]
+	if provider.APIKey != "sk-from-env" || provider.BaseURL != "https://api.openai.com/v1" {
+		t.Errorf("provider = %+v, want the expanded key and default URL", provider)
+	}
+	if got := cfg.MCPServers["github"].Env["TOKEN"]; got != "token" {
+		t.Errorf("TOKEN = %q, want %q", got, "token")
+	}
+	chat := cfg.Chats["default"]
+	if got := chat.Hooks.Keep.URL; got != "http://localhost:8080/keep" {
+		t.Errorf("hook url = %q, want the default", got)
+	}
+	if chat.System != "Keep ${THIS} as written" {
+		t.Errorf("system = %q, want it verbatim", chat.System)
+	}
+}
+
+func TestLoadConfigUnsetEnv(t *testing.T) {
+	content := `
+providers:
+  openai:
+    type: openai
+    apiKey: ${CHAT_AGENT_TEST_UNSET_KEY}
+`
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), "providers.openai.apiKey") || !strings.Contains(err.Error(), "CHAT_AGENT_TEST_UNSET_KEY") {
+		t.Errorf("LoadConfig() error = %v, want the field and variable named", err)
+	}
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// profilesKey is the top-level section of the configuration holding the profiles
+const profilesKey = "profiles"
+
+// applyProfile merges the named profile of the profiles section over the rest of the
+// configuration document and removes the section. Mappings are merged key by key
+// recursively, the profile winning on conflicts, e.g. a profile setting
+// chats.default.model changes only the model of the chat. Any other value, lists
+// included, is replaced as a whole, and null unsets it.
+func applyProfile(doc *yaml.Node, profile string) error {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("profile %q is not defined, the configuration has no profiles", profile)
+	}
+	root := doc.Content[0]
+	var profiles *yaml.Node
+	for i := 0; i < len(root.Content); i += 2 {
+		if root.Content[i].Value == profilesKey {
+			profiles = resolveAlias(root.Content[i+1])
+			root.Content = slices.Delete(slices.Clone(root.Content), i, i+2)
+			break
+		}
+	}
+	if profiles == nil || profiles.Kind != yaml.MappingNode {
+		return fmt.Errorf("profile %q is not defined, the configuration has no profiles", profile)
+	}
+
+	// The profile names are matched as written, before the keys are normalized
+	var names []string
+	for i := 0; i < len(profiles.Content); i += 2 {
+		name := profiles.Content[i].Value
+		if name != profile {
+			names = append(names, name)
+			continue
+		}
+		overlay := resolveAlias(profiles.Content[i+1])
+		if overlay.Kind != yaml.MappingNode {
+			return fmt.Errorf("profile %q must be a mapping of configuration sections", profile)
+		}
+		normalizeNodeKeys(root)
+		normalizeNodeKeys(overlay)
+		doc.Content[0] = mergeNodes(root, overlay)
+		return nil
+	}
+	sort.Strings(names)
+	return fmt.Errorf("profile %q is not defined, available profiles: %s", profile, strings.Join(names, ", "))
+}
+
+// mergeNodes returns overlay merged over base: mappings are merged key by key, any other
+// overlay value replaces the base one. The nodes are left unchanged, since aliases may
+// share them.
+func mergeNodes(base, overlay *yaml.Node) *yaml.Node {
+	base, overlay = resolveAlias(base), resolveAlias(overlay)
+	if base.Kind != yaml.MappingNode || overlay.Kind != yaml.MappingNode {
+		return overlay
+	}
+	merged := *base
+	merged.Content = slices.Clone(base.Content)
+	for i := 0; i < len(overlay.Content); i += 2 {
+		key, value := overlay.Content[i], overlay.Content[i+1]
+		found := false
+		for j := 0; j < len(merged.Content); j += 2 {
+			if merged.Content[j].Value == key.Value {
+				merged.Content[j+1] = mergeNodes(merged.Content[j+1], value)
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged.Content = append(merged.Content, key, value)
+		}
+	}
+	return &merged
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
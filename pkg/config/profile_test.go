@@ -0,0 +1,85 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	chaterrors "github.com/Arvintian/chat-agent/pkg/errors"
+)
+
+func TestLoadConfigProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	data := `
+providers:
+  openai:
+    type: openai
+    apiKey: sk-personal
+    headers:
+      X-Team: personal
+models:
+  gpt4:
+    provider: openai
+    model: gpt-4
+chats:
+  default:
+    model: gpt4
+    system: "You are a helpful assistant."
+    tools: [cmd, read]
+profiles:
+  work:
+    providers:
+      openai:
+        api_key: sk-work
+      azure:
+        type: openai
+        baseUrl: https://example.openai.azure.com
+    models:
+      gpt4-work:
+        provider: azure
+        model: gpt-4
+    chats:
+      default:
+        model: gpt4-work
+        tools: [read]
+  empty: {}
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfigProfile(path, "work")
+	if err != nil {
+		t.Fatalf("LoadConfigProfile() error = %v", err)
+	}
+	openai := cfg.Providers["openai"]
+	if openai.APIKey != "sk-work" || openai.Type != "openai" || openai.Headers["X-Team"] != "personal" {
+		t.Errorf("openai provider = %+v, want the profile's key merged over the base provider", openai)
+	}
+	if _, ok := cfg.Providers["azure"]; !ok {
+		t.Error("the provider added by the profile is missing")
+	}
+	chat := cfg.Chats["default"]
+	if chat.Model != "gpt4-work" || chat.System != "You are a helpful assistant." {
+		t.Errorf("default chat = %+v, want the profile's model and the base system prompt", chat)
+	}
+	if strings.Join(chat.Tools, ",") != "read" {
+		t.Errorf("Tools = %v, want the profile's list replacing the base one", chat.Tools)
+	}
+
+	base, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if base.Providers["openai"].APIKey != "sk-personal" || base.Chats["default"].Model != "gpt4" {
+		t.Errorf("LoadConfig() applied a profile: %+v", base.Chats["default"])
+	}
+
+	if _, err := LoadConfigProfile(path, "home"); err == nil || !strings.Contains(err.Error(), "available profiles: empty, work") {
+		t.Errorf("LoadConfigProfile() of an unknown profile error = %v, want the available profiles", err)
+	} else if !errors.Is(err, chaterrors.ErrConfigInvalid) {
+		t.Errorf("LoadConfigProfile() of an unknown profile error = %v, want ErrConfigInvalid", err)
+	}
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Arvintian/chat-agent/pkg/utils"
+)
+
+// Validate checks the references between the sections of the configuration: every
+// model uses an existing provider and every chat an existing model. All broken
+// references are reported, each with the names that exist. The approval rules of the
+// tools and the proxy URLs are checked too.
+func (c *Config) Validate() error {
+	var errs []error
+	for _, name := range sortedNames(c.Providers) {
+		if err := checkProxy(c.Providers[name].Proxy); err != nil {
+			errs = append(errs, fmt.Errorf("providers.%s: %w", name, err))
+		}
+	}
+	for _, name := range sortedNames(c.MCPServers) {
+		if err := checkProxy(c.MCPServers[name].Proxy); err != nil {
+			errs = append(errs, fmt.Errorf("mcpServers.%s: %w", name, err))
+		}
+	}
+	for _, name := range sortedNames(c.Models) {
+		model := c.Models[name]
+		if len(model.Mixed) > 0 {
+			for i, entry := range model.Mixed {
+				if err := c.checkProvider(entry.Provider); err != nil {
+					errs = append(errs, fmt.Errorf("models.%s.mixed[%d]: %w", name, i, err))
+				}
+			}
+			continue
+		}
+		if err := c.checkProvider(model.Provider); err != nil {
+			errs = append(errs, fmt.Errorf("models.%s: %w", name, err))
+		}
+	}
+	for _, name := range sortedNames(c.Chats) {
+		chat := c.Chats[name]
+		switch _, ok := c.Models[chat.Model]; {
+		case chat.Model == "":
+			errs = append(errs, fmt.Errorf("chats.%s: model is required", name))
+		case !ok:
+			errs = append(errs, fmt.Errorf("chats.%s: model %q does not exist%s", name, chat.Model, available(c.Models)))
+		}
+		for i, fallback := range chat.Fallbacks {
+			if _, ok := c.Models[fallback]; !ok {
+				errs = append(errs, fmt.Errorf("chats.%s.fallbacks[%d]: model %q does not exist%s", name, i, fallback, available(c.Models)))
+			}
+		}
+		switch chat.ReasoningDisplay {
+		case "", ReasoningDisplayShow, ReasoningDisplaySummary, ReasoningDisplayHide:
+		default:
+			errs = append(errs, fmt.Errorf("chats.%s: unknown reasoningDisplay %q (show, summary or hide)", name, chat.ReasoningDisplay))
+		}
+		if hooks := chat.Hooks; hooks != nil {
+			for _, hook := range []struct {
+				name   string
+				config *SessionHookConfig
+			}{{"keep", hooks.Keep}, {"genModelInput", hooks.GenModelInput}, {"onResponse", hooks.OnResponse}} {
+				if hook.config == nil {
+					continue
+				}
+				if err := checkProxy(hook.config.Proxy); err != nil {
+					errs = append(errs, fmt.Errorf("chats.%s.hooks.%s: %w", name, hook.name, err))
+				}
+			}
+		}
+	}
+	for _, name := range sortedNames(c.Tools) {
+		for i, rule := range c.Tools[name].ApprovalRules {
+			if err := rule.validate(); err != nil {
+				errs = append(errs, fmt.Errorf("tools.%s.approvalRules[%d]: %w", name, i, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validate checks the action and the regular expressions of an approval rule
+func (r ApprovalRule) validate() error {
+	switch r.Action {
+	case ApprovalApprove, ApprovalDeny, ApprovalAsk:
+	case "":
+		return fmt.Errorf("action is required (approve, deny or ask)")
+	default:
+		return fmt.Errorf("unknown action %q (approve, deny or ask)", r.Action)
+	}
+	if _, err := regexp.Compile(r.Args); err != nil {
+		return fmt.Errorf("invalid args pattern: %w", err)
+	}
+	for _, param := range sortedNames(r.Params) {
+		if _, err := regexp.Compile(r.Params[param]); err != nil {
+			return fmt.Errorf("invalid pattern of param %s: %w", param, err)
+		}
+	}
+	return nil
+}
+
+// checkProvider checks that a model's provider is set and exists
+func (c *Config) checkProvider(provider string) error {
+	if provider == "" {
+		return fmt.Errorf("provider is required")
+	}
+	if _, ok := c.Providers[provider]; !ok {
+		return fmt.Errorf("provider %q does not exist%s", provider, available(c.Providers))
+	}
+	return nil
+}
+
+// checkProxy checks a proxy URL, if one is set
+func checkProxy(proxy string) error {
+	if proxy == "" {
+		return nil
+	}
+	_, err := utils.ParseProxyURL(proxy)
+	return err
+}
+
+// available lists the names of a section for an error message
+func available[V any](m map[string]V) string {
+	if len(m) == 0 {
+		return ", none is configured"
+	}
+	return " (available: " + strings.Join(sortedNames(m), ", ") + ")"
+}
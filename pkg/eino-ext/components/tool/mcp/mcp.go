@@ -19,12 +19,15 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/bytedance/sonic"
 	"github.com/eino-contrib/jsonschema"
 	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
 	"github.com/mark3labs/mcp-go/mcp"
 
 	"github.com/cloudwego/eino/components/tool"
@@ -48,6 +51,12 @@ type Config struct {
 
 	// Meta specifies the metadata passed to mcp server when requesting.
 	Meta *mcp.Meta
+
+	// MaxRetries is the number of times a tool call is retried after a transport error.
+	// Errors returned by the server, including tool errors, are never retried.
+	MaxRetries int
+	// RetryBackoff is the wait before the first retry, doubled for each further retry
+	RetryBackoff time.Duration
 }
 
 func GetTools(ctx context.Context, conf *Config) ([]tool.BaseTool, error) {
@@ -98,6 +107,8 @@ func GetTools(ctx context.Context, conf *Config) ([]tool.BaseTool, error) {
 			},
 			toolCallResultHandler: conf.ToolCallResultHandler,
 			meta:                  conf.Meta,
+			maxRetries:            conf.MaxRetries,
+			retryBackoff:          conf.RetryBackoff,
 		})
 	}
 
@@ -110,6 +121,8 @@ type toolHelper struct {
 	customHeaders         map[string]string
 	toolCallResultHandler func(ctx context.Context, name string, result *mcp.CallToolResult) (*mcp.CallToolResult, error)
 	meta                  *mcp.Meta
+	maxRetries            int
+	retryBackoff          time.Duration
 }
 
 func (m *toolHelper) Info(ctx context.Context) (*schema.ToolInfo, error) {
@@ -129,7 +142,7 @@ func (m *toolHelper) InvokableRun(ctx context.Context, argumentsInJSON string, o
 		}
 	}
 
-	result, err := m.cli.CallTool(ctx, mcp.CallToolRequest{
+	request := mcp.CallToolRequest{
 		Request: mcp.Request{
 			Method: "tools/call",
 		},
@@ -139,7 +152,18 @@ func (m *toolHelper) InvokableRun(ctx context.Context, argumentsInJSON string, o
 			Arguments: json.RawMessage(argumentsInJSON),
 			Meta:      specOptions.meta,
 		},
-	})
+	}
+	result, err := m.cli.CallTool(ctx, request)
+	backoff := m.retryBackoff
+	for attempt := 0; err != nil && attempt < m.maxRetries && isTransportError(err); attempt++ {
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("failed to call mcp tool: %w", err)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		result, err = m.cli.CallTool(ctx, request)
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to call mcp tool: %w", err)
 	}
@@ -161,3 +185,10 @@ func (m *toolHelper) InvokableRun(ctx context.Context, argumentsInJSON string, o
 
 	return marshaledResult, nil
 }
+
+// isTransportError reports whether the tool call failed to reach the server or to get
+// its response, as opposed to an error returned by the server
+func isTransportError(err error) bool {
+	var transportErr *transport.Error
+	return errors.As(err, &transportErr)
+}
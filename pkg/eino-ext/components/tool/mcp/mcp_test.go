@@ -18,9 +18,12 @@ package mcp
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/cloudwego/eino/components/tool"
+	"github.com/mark3labs/mcp-go/client/transport"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/stretchr/testify/assert"
 )
@@ -163,3 +166,50 @@ func (m *mockMCPClient) Close() error {
 func (m *mockMCPClient) OnNotification(handler func(notification mcp.JSONRPCNotification)) {
 	panic("implement me")
 }
+
+// flakyMCPClient fails the first calls with the given error and answers afterwards
+type flakyMCPClient struct {
+	mockMCPClient
+	failures int
+	err      error
+	calls    int
+}
+
+func (m *flakyMCPClient) CallTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	m.calls++
+	if m.calls <= m.failures {
+		return nil, m.err
+	}
+	return m.mockMCPClient.CallTool(ctx, request)
+}
+
+func TestToolRetriesTransportErrors(t *testing.T) {
+	ctx := context.Background()
+	cli := &flakyMCPClient{failures: 2, err: transport.NewError(errors.New("connection reset"))}
+	tools, err := GetTools(ctx, &Config{Cli: cli, ToolNameList: []string{"name"}, MaxRetries: 2, RetryBackoff: time.Millisecond})
+	assert.NoError(t, err)
+
+	result, err := tools[0].(tool.InvokableTool).InvokableRun(ctx, "{\"input\": \"123\"}")
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"content\":[{\"type\":\"text\",\"text\":\"hello\"}]}", result)
+	assert.Equal(t, 3, cli.calls)
+
+	// Retries are exhausted
+	cli = &flakyMCPClient{failures: 3, err: transport.NewError(errors.New("connection reset"))}
+	tools, err = GetTools(ctx, &Config{Cli: cli, ToolNameList: []string{"name"}, MaxRetries: 2, RetryBackoff: time.Millisecond})
+	assert.NoError(t, err)
+	_, err = tools[0].(tool.InvokableTool).InvokableRun(ctx, "{\"input\": \"123\"}")
+	assert.Error(t, err)
+	assert.Equal(t, 3, cli.calls)
+}
+
+func TestToolDoesNotRetryServerErrors(t *testing.T) {
+	ctx := context.Background()
+	cli := &flakyMCPClient{failures: 1, err: mcp.ErrInvalidParams}
+	tools, err := GetTools(ctx, &Config{Cli: cli, ToolNameList: []string{"name"}, MaxRetries: 2, RetryBackoff: time.Millisecond})
+	assert.NoError(t, err)
+
+	_, err = tools[0].(tool.InvokableTool).InvokableRun(ctx, "{\"input\": \"123\"}")
+	assert.ErrorIs(t, err, mcp.ErrInvalidParams)
+	assert.Equal(t, 1, cli.calls)
+}
@@ -0,0 +1,84 @@
+// Package errors defines the classes of the errors of a chat turn, so callers can tell
+// provider, tool and configuration failures apart without matching error messages.
+// Errors are classified with Wrap and checked with errors.Is against the class.
+package errors
+
+import (
+	"errors"
+)
+
+// Error classes
+var (
+	// ErrProviderUnavailable the provider of the model can't serve the request at the
+	// moment: network errors, authentication failures, rate limits and server errors
+	ErrProviderUnavailable = errors.New("provider unavailable")
+
+	// ErrProviderRejected the provider rejected the request itself, e.g. a context too long
+	ErrProviderRejected = errors.New("provider rejected the request")
+
+	// ErrToolExecution a tool call failed
+	ErrToolExecution = errors.New("tool execution failed")
+
+	// ErrConfigInvalid the configuration could not be loaded
+	ErrConfigInvalid = errors.New("invalid configuration")
+
+	// ErrApprovalTimeout an approval request was not answered in time
+	ErrApprovalTimeout = errors.New("approval timed out")
+)
+
+// Error codes, sent to clients alongside the error message
+const (
+	CodeProviderUnavailable = "provider_unavailable"
+	CodeProviderRejected    = "provider_rejected"
+	CodeToolExecution       = "tool_execution"
+	CodeConfigInvalid       = "config_invalid"
+	CodeApprovalTimeout     = "approval_timeout"
+	// CodeInternal is the code of the errors without a class
+	CodeInternal = "internal"
+)
+
+// codes maps the classes to their codes, in the order they are checked
+var codes = []struct {
+	class error
+	code  string
+}{
+	{ErrApprovalTimeout, CodeApprovalTimeout},
+	{ErrConfigInvalid, CodeConfigInvalid},
+	{ErrToolExecution, CodeToolExecution},
+	{ErrProviderUnavailable, CodeProviderUnavailable},
+	{ErrProviderRejected, CodeProviderRejected},
+}
+
+// classified is an error put in a class. Its message is the message of the error, so
+// classifying an error doesn't change what users see.
+type classified struct {
+	class error
+	err   error
+}
+
+func (e *classified) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap makes both the class and the wrapped error match errors.Is and errors.As
+func (e *classified) Unwrap() []error {
+	return []error{e.class, e.err}
+}
+
+// Wrap puts err in class. A nil err, or an err already in the class, is returned as is.
+func Wrap(class, err error) error {
+	if err == nil || errors.Is(err, class) {
+		return err
+	}
+	return &classified{class: class, err: err}
+}
+
+// Code returns the code of the class of err, CodeInternal when it has none
+func Code(err error) string {
+	for _, c := range codes {
+		if errors.Is(err, c.class) {
+			return c.code
+		}
+	}
+	return CodeInternal
+}
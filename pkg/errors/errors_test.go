@@ -0,0 +1,48 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	cause := fmt.Errorf("call failed: %w", context.DeadlineExceeded)
+	err := Wrap(ErrProviderUnavailable, cause)
+
+	if err.Error() != cause.Error() {
+		t.Errorf("Error() = %q, want the message of the wrapped error %q", err.Error(), cause.Error())
+	}
+	if !errors.Is(err, ErrProviderUnavailable) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wrap() = %v, want it to match the class and the wrapped error", err)
+	}
+	if Wrap(ErrProviderUnavailable, err) != err {
+		t.Error("Wrap() of an error already in the class, want it returned as is")
+	}
+	if Wrap(ErrToolExecution, nil) != nil {
+		t.Error("Wrap(nil) != nil")
+	}
+}
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{Wrap(ErrProviderUnavailable, errors.New("status code: 503")), CodeProviderUnavailable},
+		{Wrap(ErrProviderRejected, errors.New("status code: 400")), CodeProviderRejected},
+		{fmt.Errorf("turn failed: %w", Wrap(ErrToolExecution, errors.New("exit 1"))), CodeToolExecution},
+		{Wrap(ErrConfigInvalid, errors.New("no models")), CodeConfigInvalid},
+		{ErrApprovalTimeout, CodeApprovalTimeout},
+		// A tool failing because of an approval timeout reports the timeout
+		{Wrap(ErrToolExecution, ErrApprovalTimeout), CodeApprovalTimeout},
+		{errors.New("boom"), CodeInternal},
+		{nil, CodeInternal},
+	}
+	for _, tt := range tests {
+		if got := Code(tt.err); got != tt.want {
+			t.Errorf("Code(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Package extract extracts the text of documents, so their content can be sent to models
+// that only read text. Only the standard library is used: plain text, PDF, Office Open
+// XML (docx, xlsx, pptx) and OpenDocument (odt, ods, odp) files are supported. PDF
+// extraction is best effort, scanned documents and fonts without a text encoding yield
+// no text.
+package extract
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrUnsupported is returned for documents whose text can't be extracted
+var ErrUnsupported = errors.New("unsupported document type")
+
+// ErrNoText is returned when a supported document contains no extractable text
+var ErrNoText = errors.New("no text found in the document")
+
+// maxDecompressedSize caps the decompressed size of a document part, against archives
+// and streams that expand to huge sizes
+const maxDecompressedSize = 64 << 20
+
+type kind int
+
+const (
+	kindUnsupported kind = iota
+	kindText
+	kindPDF
+	kindDOCX
+	kindXLSX
+	kindPPTX
+	kindODF
+)
+
+var mimeKinds = map[string]kind{
+	"application/pdf": kindPDF,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   kindDOCX,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         kindXLSX,
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": kindPPTX,
+	"application/vnd.oasis.opendocument.text":                                   kindODF,
+	"application/vnd.oasis.opendocument.spreadsheet":                            kindODF,
+	"application/vnd.oasis.opendocument.presentation":                           kindODF,
+	"application/json":   kindText,
+	"application/xml":    kindText,
+	"application/yaml":   kindText,
+	"application/x-yaml": kindText,
+}
+
+var extKinds = map[string]kind{
+	".pdf":  kindPDF,
+	".docx": kindDOCX,
+	".xlsx": kindXLSX,
+	".pptx": kindPPTX,
+	".odt":  kindODF,
+	".ods":  kindODF,
+	".odp":  kindODF,
+	".txt":  kindText,
+	".md":   kindText,
+	".csv":  kindText,
+	".tsv":  kindText,
+	".json": kindText,
+	".xml":  kindText,
+	".yaml": kindText,
+	".yml":  kindText,
+	".log":  kindText,
+}
+
+// detect returns the kind of a document from its MIME type, or its file extension when
+// the type is missing or generic
+func detect(mimeType, name string) kind {
+	if mediaType, _, err := mime.ParseMediaType(mimeType); err == nil {
+		if k, ok := mimeKinds[mediaType]; ok {
+			return k
+		}
+		if strings.HasPrefix(mediaType, "text/") {
+			return kindText
+		}
+	}
+	return extKinds[strings.ToLower(filepath.Ext(name))]
+}
+
+// Supported reports whether text can be extracted from a document with the MIME type and
+// file name
+func Supported(mimeType, name string) bool {
+	return detect(mimeType, name) != kindUnsupported
+}
+
+// Text extracts the text of a document. The MIME type decides the format, the extension
+// of the file name is used when the type is missing or generic.
+func Text(data []byte, mimeType, name string) (string, error) {
+	var text string
+	var err error
+	switch detect(mimeType, name) {
+	case kindText:
+		if !utf8.Valid(data) {
+			return "", fmt.Errorf("%s is not valid UTF-8 text", name)
+		}
+		text = string(data)
+	case kindPDF:
+		text, err = pdfText(data)
+	case kindDOCX:
+		text, err = docxText(data)
+	case kindXLSX:
+		text, err = xlsxText(data)
+	case kindPPTX:
+		text, err = pptxText(data)
+	case kindODF:
+		text, err = odfText(data)
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupported, mimeType)
+	}
+	if err != nil {
+		return "", err
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", ErrNoText
+	}
+	return text, nil
+}
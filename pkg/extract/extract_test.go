@@ -0,0 +1,129 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func zipDocument(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func pdfDocument(t *testing.T, content string, compress bool) []byte {
+	t.Helper()
+	stream := []byte(content)
+	filter := ""
+	if compress {
+		var buf bytes.Buffer
+		w := zlib.NewWriter(&buf)
+		w.Write(stream)
+		w.Close()
+		stream = buf.Bytes()
+		filter = " /Filter /FlateDecode"
+	}
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	pdf.WriteString("3 0 obj\n<< /Type /XObject /Subtype /Image /Width 1 /Height 1 /Length 5 >>\nstream\nBT (image) Tj ET\nendstream\nendobj\n")
+	fmt.Fprintf(&pdf, "4 0 obj\n<< /Length %d%s >>\nstream\n", len(stream), filter)
+	pdf.Write(stream)
+	pdf.WriteString("\nendstream\nendobj\ntrailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return pdf.Bytes()
+}
+
+func TestPDFText(t *testing.T) {
+	content := "BT /F1 12 Tf 72 720 Td (Hello \\(PDF\\)) Tj 0 -14 Td [(Second) -300 (line)] TJ T* <FEFF00E9007400E9> Tj ET"
+	for _, compress := range []bool{false, true} {
+		got, err := Text(pdfDocument(t, content, compress), "application/pdf", "doc.pdf")
+		if err != nil {
+			t.Fatalf("compress=%v: %v", compress, err)
+		}
+		want := "Hello (PDF)\nSecond line\nété"
+		if got != want {
+			t.Errorf("compress=%v: got %q, want %q", compress, got, want)
+		}
+	}
+}
+
+func TestPDFWithoutText(t *testing.T) {
+	_, err := Text(pdfDocument(t, "0 0 m 10 10 l S", true), "application/pdf", "scan.pdf")
+	if !errors.Is(err, ErrNoText) {
+		t.Errorf("got %v, want ErrNoText", err)
+	}
+	if _, err := Text([]byte("%PDF-1.4\ntrailer << /Encrypt 5 0 R >>"), "application/pdf", "x.pdf"); err == nil {
+		t.Error("encrypted document extracted")
+	}
+}
+
+func TestOfficeText(t *testing.T) {
+	docx := zipDocument(t, map[string]string{
+		"word/document.xml": `<w:document xmlns:w="w"><w:body><w:p><w:r><w:t>Hello</w:t></w:r><w:r><w:t xml:space="preserve"> world</w:t></w:r></w:p><w:p><w:r><w:t>Bye</w:t></w:r></w:p></w:body></w:document>`,
+	})
+	xlsx := zipDocument(t, map[string]string{
+		"xl/sharedStrings.xml":     `<sst><si><t>Name</t></si><si><r><t>Al</t></r><r><t>ice</t></r></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row><c t="s"><v>0</v></c><c t="inlineStr"><is><t>Age</t></is></c></row><row><c t="s"><v>1</v></c><c><v>30</v></c></row></sheetData></worksheet>`,
+	})
+	pptx := zipDocument(t, map[string]string{
+		"ppt/slides/slide10.xml": `<p:sld xmlns:a="a" xmlns:p="p"><a:p><a:r><a:t>Last</a:t></a:r></a:p></p:sld>`,
+		"ppt/slides/slide2.xml":  `<p:sld xmlns:a="a" xmlns:p="p"><a:p><a:r><a:t>First</a:t></a:r></a:p></p:sld>`,
+	})
+	odt := zipDocument(t, map[string]string{
+		"content.xml": `<office:document-content xmlns:office="o" xmlns:text="t"><office:body><text:h>Title</text:h><text:p>Some<text:s/>text</text:p></office:body></office:document-content>`,
+	})
+
+	tests := []struct {
+		data     []byte
+		mimeType string
+		name     string
+		want     string
+	}{
+		{docx, "application/vnd.openxmlformats-officedocument.wordprocessingml.document", "a.docx", "Hello world\nBye"},
+		{xlsx, "application/octet-stream", "a.xlsx", "[Sheet 1]\nName\tAge\nAlice\t30"},
+		{pptx, "", "a.pptx", "[Slide 1]\nFirst\n[Slide 2]\nLast"},
+		{odt, "application/vnd.oasis.opendocument.text", "a.odt", "Title\nSome text"},
+		{[]byte("  plain text\n"), "text/plain; charset=utf-8", "a", "plain text"},
+		{[]byte(`{"a": 1}`), "", "data.json", `{"a": 1}`},
+	}
+	for _, tt := range tests {
+		got, err := Text(tt.data, tt.mimeType, tt.name)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	if Supported("image/png", "a.png") {
+		t.Error("image reported as supported")
+	}
+	if !Supported("", "REPORT.PDF") {
+		t.Error("pdf extension not detected")
+	}
+	if _, err := Text([]byte{1, 2}, "image/png", "a.png"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("got %v, want ErrUnsupported", err)
+	}
+	if _, err := Text([]byte{0xff, 0xfe}, "text/plain", "a.txt"); err == nil || !strings.Contains(err.Error(), "UTF-8") {
+		t.Errorf("got %v, want an invalid UTF-8 error", err)
+	}
+}
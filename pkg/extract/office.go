@@ -0,0 +1,256 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// openZip opens an Office Open XML or OpenDocument archive
+func openZip(data []byte) (*zip.Reader, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the document archive: %w", err)
+	}
+	return r, nil
+}
+
+// readZipFile returns the content of a file in the archive, nil if it doesn't exist
+func readZipFile(r *zip.Reader, name string) ([]byte, error) {
+	f, err := r.Open(name)
+	if err != nil {
+		return nil, nil
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, maxDecompressedSize))
+}
+
+// numberedFiles returns the archive files named prefix<N>.xml, ordered by N
+func numberedFiles(r *zip.Reader, prefix string) []string {
+	type numbered struct {
+		name string
+		n    int
+	}
+	var files []numbered
+	for _, f := range r.File {
+		if !strings.HasPrefix(f.Name, prefix) || !strings.HasSuffix(f.Name, ".xml") {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(f.Name, prefix), ".xml"))
+		if err != nil {
+			continue
+		}
+		files = append(files, numbered{f.Name, n})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].n < files[j].n })
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = f.name
+	}
+	return names
+}
+
+// xmlText collects the character data of the text elements of an XML document. A line
+// ends after each paragraph element, tab and break elements become a tab and a line.
+func xmlText(data []byte, textElems, paragraphElems map[string]bool) (string, error) {
+	var sb strings.Builder
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return sb.String(), nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to parse the document: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch {
+			case textElems[t.Name.Local]:
+				depth++
+			case t.Name.Local == "tab":
+				sb.WriteString("\t")
+			case t.Name.Local == "br" || t.Name.Local == "line-break":
+				sb.WriteString("\n")
+			case t.Name.Local == "s" && depth > 0:
+				sb.WriteString(" ")
+			}
+		case xml.EndElement:
+			if textElems[t.Name.Local] && depth > 0 {
+				depth--
+			}
+			if paragraphElems[t.Name.Local] {
+				sb.WriteString("\n")
+			}
+		case xml.CharData:
+			if depth > 0 {
+				sb.Write(t)
+			}
+		}
+	}
+}
+
+var (
+	ooxmlText       = map[string]bool{"t": true}
+	ooxmlParagraphs = map[string]bool{"p": true}
+)
+
+// docxText extracts the paragraphs of a Word document
+func docxText(data []byte) (string, error) {
+	r, err := openZip(data)
+	if err != nil {
+		return "", err
+	}
+	doc, err := readZipFile(r, "word/document.xml")
+	if err != nil {
+		return "", err
+	}
+	if doc == nil {
+		return "", fmt.Errorf("word/document.xml not found in the document")
+	}
+	return xmlText(doc, ooxmlText, ooxmlParagraphs)
+}
+
+// pptxText extracts the text of the slides of a PowerPoint presentation
+func pptxText(data []byte) (string, error) {
+	r, err := openZip(data)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for i, name := range numberedFiles(r, "ppt/slides/slide") {
+		slide, err := readZipFile(r, name)
+		if err != nil {
+			return "", err
+		}
+		text, err := xmlText(slide, ooxmlText, ooxmlParagraphs)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&sb, "[Slide %d]\n%s\n", i+1, strings.TrimSpace(text))
+	}
+	return sb.String(), nil
+}
+
+// xlsxText extracts the cell values of the sheets of an Excel workbook, a line per row
+// with the cells separated by tabs
+func xlsxText(data []byte) (string, error) {
+	r, err := openZip(data)
+	if err != nil {
+		return "", err
+	}
+	shared, err := xlsxSharedStrings(r)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for i, name := range numberedFiles(r, "xl/worksheets/sheet") {
+		sheet, err := readZipFile(r, name)
+		if err != nil {
+			return "", err
+		}
+		rows, err := xlsxRows(sheet, shared)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&sb, "[Sheet %d]\n", i+1)
+		for _, row := range rows {
+			sb.WriteString(strings.Join(row, "\t"))
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String(), nil
+}
+
+// xlsxSharedStrings returns the shared strings table of a workbook
+func xlsxSharedStrings(r *zip.Reader) ([]string, error) {
+	data, err := readZipFile(r, "xl/sharedStrings.xml")
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var table struct {
+		Items []struct {
+			Text string `xml:"t"`
+			Runs []struct {
+				Text string `xml:"t"`
+			} `xml:"r"`
+		} `xml:"si"`
+	}
+	if err := xml.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("failed to parse the shared strings: %w", err)
+	}
+	strs := make([]string, len(table.Items))
+	for i, item := range table.Items {
+		text := item.Text
+		for _, run := range item.Runs {
+			text += run.Text
+		}
+		strs[i] = text
+	}
+	return strs, nil
+}
+
+// xlsxRows returns the non-empty rows of a worksheet
+func xlsxRows(data []byte, shared []string) ([][]string, error) {
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(data, &sheet); err != nil {
+		return nil, fmt.Errorf("failed to parse the worksheet: %w", err)
+	}
+	var rows [][]string
+	for _, row := range sheet.Rows {
+		cells := make([]string, 0, len(row.Cells))
+		empty := true
+		for _, cell := range row.Cells {
+			value := cell.Value
+			switch cell.Type {
+			case "s":
+				if n, err := strconv.Atoi(value); err == nil && n >= 0 && n < len(shared) {
+					value = shared[n]
+				}
+			case "inlineStr":
+				value = cell.Inline
+			}
+			if value != "" {
+				empty = false
+			}
+			cells = append(cells, value)
+		}
+		if !empty {
+			rows = append(rows, cells)
+		}
+	}
+	return rows, nil
+}
+
+// odfElems are the paragraph and heading elements of OpenDocument content
+var odfElems = map[string]bool{"p": true, "h": true}
+
+// odfText extracts the paragraphs and headings of an OpenDocument file
+func odfText(data []byte) (string, error) {
+	r, err := openZip(data)
+	if err != nil {
+		return "", err
+	}
+	content, err := readZipFile(r, "content.xml")
+	if err != nil {
+		return "", err
+	}
+	if content == nil {
+		return "", fmt.Errorf("content.xml not found in the document")
+	}
+	return xmlText(content, odfElems, odfElems)
+}
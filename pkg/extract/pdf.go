@@ -0,0 +1,356 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+var (
+	pdfStream    = []byte("stream")
+	pdfEndStream = []byte("endstream")
+	pdfObj       = []byte("obj")
+)
+
+// pdfSkippedStreams mark the streams holding no page content: images, fonts and the
+// object and cross-reference streams
+var pdfSkippedStreams = []string{"/Image", "/FontFile", "/Length1", "/ObjStm", "/XRef", "/Metadata"}
+
+// pdfText extracts the text shown by the content streams of a PDF document, in the order
+// of the streams in the file. Only uncompressed and FlateDecode streams are read.
+func pdfText(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("%PDF-")) {
+		return "", errors.New("not a PDF document")
+	}
+	if bytes.Contains(data, []byte("/Encrypt")) {
+		return "", errors.New("encrypted PDF documents are not supported")
+	}
+	var sb strings.Builder
+	for pos := 0; ; {
+		start := bytes.Index(data[pos:], pdfStream)
+		if start < 0 {
+			break
+		}
+		start += pos
+		end := bytes.Index(data[start:], pdfEndStream)
+		if end < 0 {
+			break
+		}
+		end += start
+		pos = end + len(pdfEndStream)
+		// The "stream" of "endstream" has no dictionary before it
+		if start >= 3 && string(data[start-3:start]) == "end" {
+			continue
+		}
+		dict := data[:start]
+		if obj := bytes.LastIndex(dict, pdfObj); obj >= 0 {
+			dict = dict[obj:]
+		}
+		content, ok := pdfStreamContent(dict, data[start+len(pdfStream):end])
+		if !ok {
+			continue
+		}
+		sb.WriteString(pdfContentText(content))
+	}
+	return sb.String(), nil
+}
+
+// pdfStreamContent returns the decoded bytes of a content stream, false for streams that
+// don't hold page content or use an unsupported filter
+func pdfStreamContent(dict, raw []byte) ([]byte, bool) {
+	d := string(dict)
+	for _, skipped := range pdfSkippedStreams {
+		if strings.Contains(d, skipped) {
+			return nil, false
+		}
+	}
+	raw = bytes.TrimPrefix(raw, []byte("\r"))
+	raw = bytes.TrimPrefix(raw, []byte("\n"))
+	if !strings.Contains(d, "/Filter") {
+		return raw, true
+	}
+	if !strings.Contains(d, "/FlateDecode") || strings.Count(d, "Decode") > 1 {
+		return nil, false
+	}
+	r, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, false
+	}
+	defer r.Close()
+	content, err := io.ReadAll(io.LimitReader(r, maxDecompressedSize))
+	// A truncated stream still yields the text decoded so far
+	if err != nil && len(content) == 0 {
+		return nil, false
+	}
+	return content, true
+}
+
+// pdfContentText runs the text operators of a content stream. Text objects and moves to
+// another line end a line, large negative kerning in TJ arrays becomes a space.
+func pdfContentText(content []byte) string {
+	if !bytes.Contains(content, []byte("BT")) {
+		return ""
+	}
+	var sb strings.Builder
+	lex := &pdfLexer{data: content}
+	var operands []pdfToken
+	newline := func() {
+		if sb.Len() > 0 && !strings.HasSuffix(sb.String(), "\n") {
+			sb.WriteString("\n")
+		}
+	}
+	for {
+		tok, ok := lex.next()
+		if !ok {
+			break
+		}
+		if tok.kind != pdfOperator {
+			operands = append(operands, tok)
+			continue
+		}
+		switch tok.text {
+		case "Tj":
+			if s, ok := lastString(operands); ok {
+				sb.WriteString(s)
+			}
+		case "'", "\"":
+			newline()
+			if s, ok := lastString(operands); ok {
+				sb.WriteString(s)
+			}
+		case "TJ":
+			for _, op := range operands {
+				switch op.kind {
+				case pdfString:
+					sb.WriteString(op.text)
+				case pdfNumber:
+					if n, err := strconv.ParseFloat(op.text, 64); err == nil && n < -200 {
+						sb.WriteString(" ")
+					}
+				}
+			}
+		case "T*", "ET":
+			newline()
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				if ty, err := strconv.ParseFloat(operands[len(operands)-1].text, 64); err == nil && ty != 0 {
+					newline()
+				}
+			}
+		case "Tm":
+			newline()
+		}
+		operands = operands[:0]
+	}
+	newline()
+	return sb.String()
+}
+
+func lastString(operands []pdfToken) (string, bool) {
+	if len(operands) == 0 || operands[len(operands)-1].kind != pdfString {
+		return "", false
+	}
+	return operands[len(operands)-1].text, true
+}
+
+type pdfTokenKind int
+
+const (
+	pdfOperator pdfTokenKind = iota
+	pdfString
+	pdfNumber
+	pdfOther
+)
+
+type pdfToken struct {
+	kind pdfTokenKind
+	text string
+}
+
+// pdfLexer splits a content stream into tokens. Array brackets are dropped, so the
+// elements of a TJ array are its operands; dictionaries and names are opaque operands.
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func (l *pdfLexer) next() (pdfToken, bool) {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case isPDFSpace(c) || c == '[' || c == ']':
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		case c == '(':
+			return pdfToken{pdfString, decodePDFString(l.literal())}, true
+		case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+			l.pos += 2
+			return pdfToken{kind: pdfOther}, true
+		case c == '>' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '>':
+			l.pos += 2
+			return pdfToken{kind: pdfOther}, true
+		case c == '<':
+			return pdfToken{pdfString, decodePDFString(l.hex())}, true
+		case c == '/':
+			l.pos++
+			l.word()
+			return pdfToken{kind: pdfOther}, true
+		default:
+			w := l.word()
+			if w == "" {
+				// A stray delimiter
+				l.pos++
+				continue
+			}
+			if (w[0] >= '0' && w[0] <= '9') || w[0] == '-' || w[0] == '+' || w[0] == '.' {
+				return pdfToken{pdfNumber, w}, true
+			}
+			return pdfToken{pdfOperator, w}, true
+		}
+	}
+	return pdfToken{}, false
+}
+
+// word reads a run of regular characters
+func (l *pdfLexer) word() string {
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	return string(l.data[start:l.pos])
+}
+
+// literal reads a (string) with balanced parentheses and escapes
+func (l *pdfLexer) literal() []byte {
+	var out []byte
+	depth := 0
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			if depth > 0 {
+				out = append(out, c)
+			}
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+			out = append(out, c)
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				// A line continuation
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					n := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						n = n*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					out = append(out, byte(n))
+				} else {
+					out = append(out, e)
+				}
+			}
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// hex reads a <hex string>, an odd last digit is followed by an implicit 0
+func (l *pdfLexer) hex() []byte {
+	l.pos++
+	var out []byte
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		c := l.data[l.pos]
+		l.pos++
+		if isPDFSpace(c) {
+			continue
+		}
+		digits = append(digits, c)
+		if len(digits) == 2 {
+			if n, err := strconv.ParseUint(string(digits), 16, 8); err == nil {
+				out = append(out, byte(n))
+			}
+			digits = digits[:0]
+		}
+	}
+	l.pos++
+	if len(digits) == 1 {
+		if n, err := strconv.ParseUint(string(digits)+"0", 16, 8); err == nil {
+			out = append(out, byte(n))
+		}
+	}
+	return out
+}
+
+// decodePDFString decodes a text string: UTF-16BE with a byte order mark, otherwise
+// single bytes read as Latin-1. Control characters are dropped, since strings of fonts
+// with custom encodings decode to them.
+func decodePDFString(b []byte) string {
+	var runes []rune
+	if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF {
+		units := make([]uint16, 0, len(b)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		runes = utf16.Decode(units)
+	} else {
+		runes = make([]rune, len(b))
+		for i, c := range b {
+			runes[i] = rune(c)
+		}
+	}
+	var sb strings.Builder
+	for _, r := range runes {
+		if r == '\n' || r == '\t' || !unicode.IsControl(r) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
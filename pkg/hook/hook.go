@@ -14,6 +14,7 @@ import (
 
 	"github.com/Arvintian/chat-agent/pkg/config"
 	"github.com/Arvintian/chat-agent/pkg/logger"
+	"github.com/Arvintian/chat-agent/pkg/utils"
 	"github.com/cloudwego/eino/schema"
 )
 
@@ -43,6 +44,12 @@ type SessionHookData struct {
 	SessionName string            `json:"session_name"`
 	Messages    []*schema.Message `json:"messages"`
 	Timestamp   string            `json:"timestamp"`
+	// Label is given by the user to categorize a kept session, empty when none was given
+	Label string `json:"label,omitempty"`
+	// UserMessage and Response are the user message of a turn and the final assistant
+	// message answering it, passed to the onResponse hook
+	UserMessage *schema.Message `json:"user_message,omitempty"`
+	Response    *schema.Message `json:"response,omitempty"`
 }
 
 // GenModelInputResult represents the result returned by genmodelinput hook
@@ -50,10 +57,17 @@ type GenModelInputResult struct {
 	Messages []*schema.Message `json:"messages"`
 }
 
+// OnResponseResult represents the result returned by the onresponse hook. A nil message
+// keeps the response unchanged.
+type OnResponseResult struct {
+	Message *schema.Message `json:"message"`
+}
+
 // HookManager manages session hooks
 type HookManager struct {
 	sessionKeep   *config.SessionHookConfig
 	genModelInput *config.SessionHookConfig
+	onResponse    *config.SessionHookConfig
 	baseDir       string
 }
 
@@ -65,13 +79,14 @@ func NewHookManager(hooksConfig *config.SessionHooks) *HookManager {
 	return &HookManager{
 		sessionKeep:   hooksConfig.Keep,
 		genModelInput: hooksConfig.GenModelInput,
+		onResponse:    hooksConfig.OnResponse,
 		baseDir:       baseDir,
 	}
 }
 
 // executeHookScript executes a hook script or HTTP request with the given configuration and session data
 // It returns the raw output and any error from execution
-func (hm *HookManager) executeHook(ctx context.Context, cfg *config.SessionHookConfig, sessionID string, sessionName string, messages []*schema.Message, logPrefix string) ([]byte, error) {
+func (hm *HookManager) executeHook(ctx context.Context, cfg *config.SessionHookConfig, hookData SessionHookData, logPrefix string) ([]byte, error) {
 	if cfg == nil || !cfg.Enabled {
 		return nil, nil
 	}
@@ -87,21 +102,21 @@ func (hm *HookManager) executeHook(ctx context.Context, cfg *config.SessionHookC
 		timeout = 30 // default timeout
 	}
 
+	hookData.Timestamp = time.Now().Format(time.RFC3339)
+
 	switch hookType {
 	case "script":
-		return hm.executeScriptHook(ctx, cfg, sessionID, sessionName, messages, logPrefix, timeout)
+		return hm.executeScriptHook(ctx, cfg, hookData, logPrefix, timeout)
 	case "http":
-		return hm.executeHTTPHook(ctx, cfg, sessionID, sessionName, messages, logPrefix, timeout)
+		return hm.executeHTTPHook(ctx, cfg, hookData, logPrefix, timeout)
 	default:
 		return nil, fmt.Errorf("unknown hook type: %s, supported types: script, http", hookType)
 	}
 }
 
-// executeScriptHook executes a local script hook
-func (hm *HookManager) executeScriptHook(ctx context.Context, cfg *config.SessionHookConfig, sessionID string, sessionName string, messages []*schema.Message, logPrefix string, timeout int) ([]byte, error) {
-	scriptPath := cfg.ScriptPath
-
-	// Expand ~ to home directory and make path absolute
+// resolveScriptPath expands ~ to the home directory and makes a relative script path
+// relative to the base directory
+func (hm *HookManager) resolveScriptPath(scriptPath string) string {
 	if filepath.HasPrefix(scriptPath, "~") {
 		homeDir, err := os.UserHomeDir()
 		if err != nil {
@@ -110,11 +125,32 @@ func (hm *HookManager) executeScriptHook(ctx context.Context, cfg *config.Sessio
 			scriptPath = filepath.Join(homeDir, scriptPath[1:])
 		}
 	}
-
-	// If path is relative, make it relative to base directory
 	if !filepath.IsAbs(scriptPath) {
 		scriptPath = filepath.Join(hm.baseDir, scriptPath)
 	}
+	return scriptPath
+}
+
+// ScriptPaths returns the resolved script paths of the enabled script hooks, keyed by
+// hook name (keep, genModelInput, onResponse)
+func (hm *HookManager) ScriptPaths() map[string]string {
+	paths := make(map[string]string)
+	for name, cfg := range map[string]*config.SessionHookConfig{
+		"keep":          hm.sessionKeep,
+		"genModelInput": hm.genModelInput,
+		"onResponse":    hm.onResponse,
+	} {
+		if cfg == nil || !cfg.Enabled || (cfg.Type != "" && cfg.Type != "script") {
+			continue
+		}
+		paths[name] = hm.resolveScriptPath(cfg.ScriptPath)
+	}
+	return paths
+}
+
+// executeScriptHook executes a local script hook
+func (hm *HookManager) executeScriptHook(ctx context.Context, cfg *config.SessionHookConfig, hookData SessionHookData, logPrefix string, timeout int) ([]byte, error) {
+	scriptPath := hm.resolveScriptPath(cfg.ScriptPath)
 
 	// Check if script exists
 	if _, err := os.Stat(scriptPath); os.IsNotExist(err) {
@@ -146,14 +182,7 @@ func (hm *HookManager) executeScriptHook(ctx context.Context, cfg *config.Sessio
 	// Set working directory to base dir
 	cmd.Dir = hm.baseDir
 
-	// Prepare JSON data to pass via stdin
-	hookData := SessionHookData{
-		SessionID:   sessionID,
-		SessionName: sessionName,
-		Messages:    messages,
-		Timestamp:   time.Now().Format(time.RFC3339),
-	}
-
+	// Pass the session data as JSON via stdin
 	jsonData, err := json.MarshalIndent(hookData, "", "  ")
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal session data: %w", err)
@@ -191,7 +220,7 @@ func (hm *HookManager) executeScriptHook(ctx context.Context, cfg *config.Sessio
 }
 
 // executeHTTPHook executes an HTTP request hook
-func (hm *HookManager) executeHTTPHook(ctx context.Context, cfg *config.SessionHookConfig, sessionID string, sessionName string, messages []*schema.Message, logPrefix string, timeout int) ([]byte, error) {
+func (hm *HookManager) executeHTTPHook(ctx context.Context, cfg *config.SessionHookConfig, hookData SessionHookData, logPrefix string, timeout int) ([]byte, error) {
 	url := cfg.URL
 	if url == "" {
 		return nil, fmt.Errorf("HTTP URL is required for http type hook")
@@ -202,14 +231,7 @@ func (hm *HookManager) executeHTTPHook(ctx context.Context, cfg *config.SessionH
 		method = "POST"
 	}
 
-	// Prepare JSON data to send as request body
-	hookData := SessionHookData{
-		SessionID:   sessionID,
-		SessionName: sessionName,
-		Messages:    messages,
-		Timestamp:   time.Now().Format(time.RFC3339),
-	}
-
+	// Send the session data as JSON request body
 	jsonData, err := json.MarshalIndent(hookData, "", "  ")
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal session data: %w", err)
@@ -219,9 +241,14 @@ func (hm *HookManager) executeHTTPHook(ctx context.Context, cfg *config.SessionH
 
 	startTime := time.Now()
 
-	// Create HTTP client with timeout
+	// Create HTTP client with timeout, sending the request through the proxy of the hook
+	transport, err := utils.NewProxyTransport(cfg.Proxy)
+	if err != nil {
+		return nil, err
+	}
 	client := &http.Client{
-		Timeout: time.Duration(timeout) * time.Second,
+		Transport: transport,
+		Timeout:   time.Duration(timeout) * time.Second,
 	}
 
 	// Create request
@@ -269,9 +296,15 @@ func (hm *HookManager) executeHTTPHook(ctx context.Context, cfg *config.SessionH
 	return body, nil
 }
 
-// OnSessionClear executes the session clear hook if enabled
-func (hm *HookManager) OnSessionKeep(ctx context.Context, sessionID string, sessionName string, messages []*schema.Message) error {
-	output, err := hm.executeHook(ctx, hm.sessionKeep, sessionID, sessionName, messages, "Session keep hook")
+// OnSessionKeep executes the session keep hook if enabled. The label, which may be
+// empty, is passed to the hook to categorize the kept session.
+func (hm *HookManager) OnSessionKeep(ctx context.Context, sessionID string, sessionName string, label string, messages []*schema.Message) error {
+	output, err := hm.executeHook(ctx, hm.sessionKeep, SessionHookData{
+		SessionID:   sessionID,
+		SessionName: sessionName,
+		Messages:    messages,
+		Label:       label,
+	}, "Session keep hook")
 	if err != nil {
 		return err
 	}
@@ -283,7 +316,11 @@ func (hm *HookManager) OnSessionKeep(ctx context.Context, sessionID string, sess
 // OnGenModelInput executes the genmodelinput hook if enabled
 // It passes session data via stdin and expects JSON output with []message
 func (hm *HookManager) OnGenModelInput(ctx context.Context, sessionID string, sessionName string, messages []*schema.Message) ([]*schema.Message, error) {
-	output, err := hm.executeHook(ctx, hm.genModelInput, sessionID, sessionName, messages, "GenModelInput hook")
+	output, err := hm.executeHook(ctx, hm.genModelInput, SessionHookData{
+		SessionID:   sessionID,
+		SessionName: sessionName,
+		Messages:    messages,
+	}, "GenModelInput hook")
 	if err != nil {
 		return messages, err
 	}
@@ -302,3 +339,34 @@ func (hm *HookManager) OnGenModelInput(ctx context.Context, sessionID string, se
 	logInfo("Genmodelinput hook processed %d messages", len(result.Messages))
 	return result.Messages, nil
 }
+
+// OnResponse executes the onresponse hook if enabled
+// It passes the user message and the final assistant message via stdin and expects JSON
+// output with the message to store instead of the response, or no output to keep it
+func (hm *HookManager) OnResponse(ctx context.Context, sessionID string, sessionName string, userMessage *schema.Message, response *schema.Message) (*schema.Message, error) {
+	output, err := hm.executeHook(ctx, hm.onResponse, SessionHookData{
+		SessionID:   sessionID,
+		SessionName: sessionName,
+		UserMessage: userMessage,
+		Response:    response,
+	}, "OnResponse hook")
+	if err != nil {
+		return response, err
+	}
+
+	if len(bytes.TrimSpace(output)) == 0 {
+		return response, nil
+	}
+
+	var result OnResponseResult
+	if err := json.Unmarshal(output, &result); err != nil {
+		logWarn("Failed to parse onresponse hook output as JSON: %v", err)
+		return response, nil // Return the original response on parse error
+	}
+	if result.Message == nil {
+		return response, nil
+	}
+	// The stored message is always an assistant message
+	result.Message.Role = schema.Assistant
+	return result.Message, nil
+}
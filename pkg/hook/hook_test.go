@@ -0,0 +1,83 @@
+package hook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestOnResponseReplacesMessage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data SessionHookData
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if data.UserMessage == nil || data.UserMessage.Content != "question" {
+			http.Error(w, "missing user message", http.StatusBadRequest)
+			return
+		}
+		if strings.Contains(data.Response.Content, "keep") {
+			return
+		}
+		json.NewEncoder(w).Encode(OnResponseResult{
+			Message: &schema.Message{Content: strings.ReplaceAll(data.Response.Content, "sk-123", "[REDACTED]")},
+		})
+	}))
+	defer server.Close()
+
+	hm := NewHookManager(&config.SessionHooks{
+		OnResponse: &config.SessionHookConfig{Enabled: true, Type: "http", URL: server.URL},
+	})
+	user := schema.UserMessage("question")
+
+	message, err := hm.OnResponse(context.Background(), "id", "chat", user, schema.AssistantMessage("the key is sk-123", nil))
+	if err != nil {
+		t.Fatalf("OnResponse() error = %v", err)
+	}
+	if message.Role != schema.Assistant || message.Content != "the key is [REDACTED]" {
+		t.Errorf("OnResponse() = %+v, want the redacted assistant message", message)
+	}
+
+	response := schema.AssistantMessage("keep this", nil)
+	message, err = hm.OnResponse(context.Background(), "id", "chat", user, response)
+	if err != nil || message != response {
+		t.Errorf("OnResponse() = %+v, %v, want the response unchanged on empty output", message, err)
+	}
+}
+
+func TestOnResponseDisabled(t *testing.T) {
+	hm := NewHookManager(&config.SessionHooks{})
+	response := schema.AssistantMessage("answer", nil)
+	message, err := hm.OnResponse(context.Background(), "id", "chat", schema.UserMessage("question"), response)
+	if err != nil || message != response {
+		t.Errorf("OnResponse() = %+v, %v, want the response unchanged", message, err)
+	}
+}
+
+func TestHTTPHookProxy(t *testing.T) {
+	// The proxy gets the request for the hook URL and answers it itself
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		json.NewEncoder(w).Encode(OnResponseResult{Message: &schema.Message{Content: "via proxy"}})
+	}))
+	defer proxy.Close()
+
+	hm := NewHookManager(&config.SessionHooks{
+		OnResponse: &config.SessionHookConfig{Enabled: true, Type: "http", URL: "http://hooks.example.com/on-response", Proxy: proxy.URL},
+	})
+	message, err := hm.OnResponse(context.Background(), "id", "chat", schema.UserMessage("question"), schema.AssistantMessage("answer", nil))
+	if err != nil {
+		t.Fatalf("OnResponse() error = %v", err)
+	}
+	if proxied != "http://hooks.example.com/on-response" || message.Content != "via proxy" {
+		t.Errorf("proxy got %q and OnResponse() = %+v, want the hook request sent through the proxy", proxied, message)
+	}
+}
@@ -2,7 +2,9 @@ package manager
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
 
@@ -19,6 +21,35 @@ type PersistenceCallback func(*schema.Message) error
 // This allows the caller to persist the modified messages (full overwrite mode)
 type CompressionCompleteCallback func([]*schema.Message) error
 
+// EventType is the kind of a context Event
+type EventType string
+
+const (
+	// EventCompressionStarted is sent when older rounds start being summarized
+	EventCompressionStarted EventType = "compression_started"
+	// EventCompressionCompleted is sent when the summary replaced the older rounds, or
+	// with an empty summary when summarizing failed
+	EventCompressionCompleted EventType = "compression_completed"
+	// EventOversizedMessage is sent when a message alone exceeds the token budget. The
+	// message is kept, the model may reject it.
+	EventOversizedMessage EventType = "oversized_message"
+)
+
+// Event reports a change of the conversation context made by the manager
+type Event struct {
+	Type EventType
+	// Rounds is the number of rounds being summarized
+	Rounds int
+	// Summary is the generated summary, only set on EventCompressionCompleted
+	Summary string
+	// Tokens is the estimated size of the message, only set on EventOversizedMessage
+	Tokens int
+}
+
+// EventHook is called with the context events of a manager. It is called without the
+// manager lock held, so it may call back into the manager.
+type EventHook func(Event)
+
 const (
 	DefaultMaxMessageRound   int = 10
 	DefaultFullMessageRounds int = 1
@@ -29,6 +60,27 @@ const (
 	CompressionThreshold int = 8
 )
 
+// Config configures a Manager. By default the context is limited by its number of rounds;
+// setting MaxTokens limits it by its estimated number of tokens instead.
+type Config struct {
+	// MaxMessageRounds is the maximum number of rounds in the context (default: 10)
+	MaxMessageRounds int
+	// MaxTokens is the token budget of the context, 0 limits it by rounds. Older rounds are
+	// summarized once the context reaches ~70% of the budget, or dropped when there is no
+	// compression model.
+	MaxTokens int
+	// TokenCounter estimates the tokens of the messages, ApproxTokenCounter when nil
+	TokenCounter TokenCounter
+}
+
+// State is the serializable conversation state of a Manager
+type State struct {
+	Messages       [][]*schema.Message `json:"messages"`
+	CompressBuffer [][]*schema.Message `json:"compress_buffer,omitempty"`
+	Round          int                 `json:"round"`
+	Pinned         []*schema.Message   `json:"pinned,omitempty"`
+}
+
 // Manager manages conversation context with intelligent context management capabilities
 type Manager struct {
 	// messages stores the conversation history (full messages, never modified)
@@ -41,6 +93,14 @@ type Manager struct {
 	// older rounds will be simplified (first user message + last ai response)
 	fullMessageRounds int
 
+	// maxRounds is a hard cap on the rounds kept in the context when no compression
+	// runs. 0 means maxMessageRound is used as the cap.
+	maxRounds int
+
+	// maxTokens is the token budget of the context, 0 means the context is limited by rounds
+	maxTokens    int
+	tokenCounter TokenCounter
+
 	round int
 
 	// chatmodel for compressing messages when threshold is reached
@@ -51,23 +111,40 @@ type Manager struct {
 	// compression related fields
 	compressing    bool                // indicates if compression is in progress
 	compressBuffer [][]*schema.Message // buffer for original messages waiting to be compressed
+	// compressEpoch changes when the rounds of the compress buffer are edited, the summary
+	// of a compression started before is discarded
+	compressEpoch int
+
+	// pinned user messages are kept verbatim at the front of the context, they are never
+	// summarized and survive the rounds holding them being dropped or compressed
+	pinned []*schema.Message
 
 	// persistence callback for auto-saving messages
 	persistenceCallback PersistenceCallback
 
 	// compression complete callback for persisting modified messages after compression
 	compressionCompleteCallback CompressionCompleteCallback
+
+	// eventHook is notified when compression starts and completes
+	eventHook EventHook
 }
 
 // NewManager creates a new Manager instance
-func NewManager(maxMessageRound int) *Manager {
+func NewManager(cfg Config) *Manager {
+	maxMessageRound := cfg.MaxMessageRounds
 	if maxMessageRound <= 0 {
 		maxMessageRound = DefaultMaxMessageRound
 	}
+	tokenCounter := cfg.TokenCounter
+	if tokenCounter == nil {
+		tokenCounter = ApproxTokenCounter{}
+	}
 	return &Manager{
 		messages:            make([][]*schema.Message, 0),
 		maxMessageRound:     maxMessageRound,
 		fullMessageRounds:   DefaultFullMessageRounds,
+		maxTokens:           max(cfg.MaxTokens, 0),
+		tokenCounter:        tokenCounter,
 		round:               0,
 		chatmodel:           nil,
 		compressing:         false,
@@ -90,6 +167,23 @@ func (m *Manager) SetCompressionCompleteCallback(cb CompressionCompleteCallback)
 	m.compressionCompleteCallback = cb
 }
 
+// SetEventHook sets the hook notified of context events, nil removes it
+func (m *Manager) SetEventHook(hook EventHook) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.eventHook = hook
+}
+
+// SetMaxMessageRounds sets the maximum number of message rounds in the context
+func (m *Manager) SetMaxMessageRounds(rounds int) {
+	if rounds <= 0 {
+		rounds = DefaultMaxMessageRound
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxMessageRound = rounds
+}
+
 // SetFullMessageRounds sets how many recent rounds to keep full messages
 func (m *Manager) SetFullMessageRounds(rounds int) {
 	if rounds < 1 {
@@ -100,6 +194,23 @@ func (m *Manager) SetFullMessageRounds(rounds int) {
 	m.fullMessageRounds = rounds
 }
 
+// SetMaxRounds sets the hard cap on rounds kept in the context
+func (m *Manager) SetMaxRounds(rounds int) {
+	if rounds < 0 {
+		rounds = 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxRounds = rounds
+}
+
+// SetMaxTokens sets the token budget of the context, 0 limits the context by rounds
+func (m *Manager) SetMaxTokens(tokens int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxTokens = max(tokens, 0)
+}
+
 // SetChatModel sets the chat model for message compression
 func (m *Manager) SetChatModel(chatmodel model.ToolCallingChatModel) {
 	m.mu.Lock()
@@ -116,7 +227,6 @@ func (m *Manager) GetChatModel() model.ToolCallingChatModel {
 // AddMessage adds a message to the context
 func (m *Manager) AddMessage(ctx context.Context, message *schema.Message) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
 
 	// Ensure we have at least one round
 	if len(m.messages) == 0 {
@@ -127,14 +237,30 @@ func (m *Manager) AddMessage(ctx context.Context, message *schema.Message) {
 	m.messages[m.round] = append(m.messages[m.round], message)
 
 	// If the number of rounds exceeds the limit, trim messages
-	m.trimMessages(context.Background())
+	persisted := m.trimMessages(context.Background())
 
-	// Auto-save single message to persistence if callback is set (inside lock)
-	if m.persistenceCallback != nil {
+	// Auto-save single message to persistence if callback is set (inside lock),
+	// unless trimming already rewrote the history including this message
+	if !persisted && m.persistenceCallback != nil {
 		if err := m.persistenceCallback(message); err != nil {
 			logger.Warn("manager", fmt.Sprintf("Failed to auto-save message: %v", err))
 		}
 	}
+
+	// A message over the token budget on its own is kept, trimming can't make it fit
+	tokens := 0
+	if m.maxTokens > 0 {
+		tokens = m.tokenCounter.CountTokens(message)
+	}
+	maxTokens, hook := m.maxTokens, m.eventHook
+	m.mu.Unlock()
+
+	if maxTokens > 0 && tokens > maxTokens {
+		logger.Warn("manager", fmt.Sprintf("A %s message of ~%d tokens exceeds the context budget of %d tokens", message.Role, tokens, maxTokens))
+		if hook != nil {
+			hook(Event{Type: EventOversizedMessage, Tokens: tokens})
+		}
+	}
 }
 
 func (m *Manager) IncRound() {
@@ -298,8 +424,17 @@ func (m *Manager) validateAndCleanRound(messages []*schema.Message) []*schema.Me
 // compression) when the window is small.
 //
 // When maxMessageRound >= CompressionThreshold, async compression is triggered at
-// ~70% of the limit, using the chatmodel to summarize older rounds.
-func (m *Manager) trimMessages(ctx context.Context) {
+// ~70% of the limit, using the chatmodel to summarize older rounds. Without a chatmodel,
+// maxRounds (or maxMessageRound) caps the window instead.
+//
+// When maxTokens is set, the estimated tokens of the context are limited instead of its
+// rounds, see trimTokens.
+//
+// Returns true if the messages were persisted in full while trimming.
+func (m *Manager) trimMessages(ctx context.Context) bool {
+	if m.maxTokens > 0 {
+		return m.trimTokens(ctx)
+	}
 	if m.maxMessageRound < CompressionThreshold {
 		// Simple truncation: keep only the most recent rounds within the limit.
 		// No compression model needed in this mode.
@@ -308,7 +443,17 @@ func (m *Manager) trimMessages(ctx context.Context) {
 			m.messages = m.messages[1:]
 			m.round = len(m.messages) - 1
 		}
-		return
+		return m.capRounds(m.maxRounds)
+	}
+
+	if m.chatmodel == nil {
+		// Without a compression model the window would grow unbounded, so fall back
+		// to maxMessageRound as the hard cap
+		maxRounds := m.maxRounds
+		if maxRounds <= 0 {
+			maxRounds = m.maxMessageRound
+		}
+		return m.capRounds(maxRounds)
 	}
 
 	// Start async compression early at ~70% of maxMessageRound threshold
@@ -319,9 +464,128 @@ func (m *Manager) trimMessages(ctx context.Context) {
 	if asyncCompressThreshold < 4 {
 		asyncCompressThreshold = 4
 	}
-	if len(m.messages) >= asyncCompressThreshold && !m.compressing && m.chatmodel != nil {
+	if len(m.messages) >= asyncCompressThreshold && !m.compressing {
 		go m.compressMessagesAsync(ctx)
 	}
+	return false
+}
+
+// trimTokens keeps the estimated tokens of the context within maxTokens. With a
+// chatmodel, async compression is triggered at ~70% of the budget, as long as a round
+// before the current one is left to summarize. Without one, the oldest rounds are dropped
+// until the context fits. maxRounds still caps the rounds in both cases.
+func (m *Manager) trimTokens(ctx context.Context) bool {
+	persisted := m.capRounds(m.maxRounds)
+	if m.chatmodel == nil {
+		return m.capTokens(m.maxTokens) || persisted
+	}
+	// Only a summary before the current round leaves nothing new to summarize
+	older := len(m.messages) - 1
+	if older > 0 && isSummaryRound(m.messages[0]) {
+		older--
+	}
+	threshold := int(float64(m.maxTokens) * 0.7)
+	if older > 0 && m.contextTokens() >= threshold && !m.compressing {
+		go m.compressMessagesAsync(ctx)
+	}
+	return persisted
+}
+
+// contextTokens estimates the tokens of the pinned messages and the rounds in the context
+func (m *Manager) contextTokens() int {
+	tokens := m.pinnedTokens()
+	for _, round := range m.messages {
+		tokens += m.roundTokens(round)
+	}
+	return tokens
+}
+
+// roundTokens estimates the tokens of a round, pinned messages are counted once by
+// pinnedTokens
+func (m *Manager) roundTokens(round []*schema.Message) int {
+	tokens := 0
+	for _, msg := range m.unpinned(round) {
+		tokens += m.tokenCounter.CountTokens(msg)
+	}
+	return tokens
+}
+
+func (m *Manager) pinnedTokens() int {
+	tokens := 0
+	for _, msg := range m.pinned {
+		tokens += m.tokenCounter.CountTokens(msg)
+	}
+	return tokens
+}
+
+// capTokens discards the oldest rounds until the context fits in maxTokens, keeping a
+// leading summary round and the current round like capRounds, and persists the remaining
+// messages. Returns true if the messages were persisted through the compression complete
+// callback.
+func (m *Manager) capTokens(maxTokens int) bool {
+	tokens := m.contextTokens()
+	if tokens <= maxTokens {
+		return false
+	}
+
+	first := 0
+	if len(m.messages) > 0 && isSummaryRound(m.messages[0]) {
+		first = 1
+	}
+	dropped := false
+	for tokens > maxTokens && len(m.messages)-first > 1 {
+		tokens -= m.roundTokens(m.messages[first])
+		m.messages = append(m.messages[:first], m.messages[first+1:]...)
+		dropped = true
+	}
+	if !dropped {
+		return false
+	}
+	m.round = len(m.messages) - 1
+	return m.persistTrimmed(first)
+}
+
+// capRounds discards the oldest rounds until at most maxRounds remain and persists
+// the remaining messages. It is only used when no compression runs, so the compression
+// buffer is never touched. A leading summary round and the current round are kept.
+// Returns true if the messages were persisted through the compression complete callback.
+func (m *Manager) capRounds(maxRounds int) bool {
+	if maxRounds <= 0 || len(m.messages) <= maxRounds {
+		return false
+	}
+
+	first := 0
+	if isSummaryRound(m.messages[0]) {
+		first = 1
+	}
+	for len(m.messages) > maxRounds && len(m.messages)-first > 1 {
+		m.messages = append(m.messages[:first], m.messages[first+1:]...)
+	}
+	m.round = len(m.messages) - 1
+	return m.persistTrimmed(first)
+}
+
+// persistTrimmed cleans the new oldest round after rounds were dropped and persists the
+// remaining messages. Returns true if they were persisted through the compression
+// complete callback.
+func (m *Manager) persistTrimmed(first int) bool {
+	// Keep tool call / tool result pairing intact in the new oldest round
+	if first < len(m.messages)-1 {
+		m.messages[first] = m.validateAndCleanRound(m.messages[first])
+	}
+
+	if m.compressionCompleteCallback == nil {
+		return false
+	}
+	if err := m.compressionCompleteCallback(m.flatten(m.messages)); err != nil {
+		logger.Warn("manager", fmt.Sprintf("Failed to persist messages after dropping rounds: %v", err))
+	}
+	return true
+}
+
+// isSummaryRound reports whether the round holds a previous conversation summary
+func isSummaryRound(round []*schema.Message) bool {
+	return len(round) > 0 && strings.HasPrefix(round[0].Content, "[Previous Conversation Summary]:")
 }
 
 // compressMessagesAsync performs asynchronous compression in a goroutine
@@ -355,12 +619,19 @@ func (m *Manager) compressMessagesAsync(ctx context.Context) {
 
 	m.messages = m.messages[numToCompress:]
 	m.round = len(m.messages) - 1
-	m.mu.Unlock()
+	hook := m.eventHook
+	epoch := m.compressEpoch
 
-	// Flatten messages for compression
+	// Flatten messages for compression, pinned messages are kept verbatim instead
 	flatMessages := make([]*schema.Message, 0)
 	for _, round := range messagesToCompress {
-		flatMessages = append(flatMessages, round...)
+		flatMessages = append(flatMessages, m.unpinned(round)...)
+	}
+	m.mu.Unlock()
+
+	rounds := len(messagesToCompress)
+	if hook != nil {
+		hook(Event{Type: EventCompressionStarted, Rounds: rounds})
 	}
 
 	// Perform compression without holding the main lock
@@ -369,37 +640,123 @@ func (m *Manager) compressMessagesAsync(ctx context.Context) {
 		summary = m.doCompression(ctx, flatMessages)
 	}
 
-	// Mark compression as complete
+	// Mark compression as complete, the hook is called once the lock is released
 	m.mu.Lock()
+	hook = m.eventHook
+	defer func() {
+		if hook != nil {
+			hook(Event{Type: EventCompressionCompleted, Rounds: rounds, Summary: summary})
+		}
+	}()
 	defer func() {
 		m.compressing = false
 		m.mu.Unlock()
 	}()
 
-	if summary != "" {
-		summaryMessage := schema.AssistantMessage(fmt.Sprintf("[Previous Conversation Summary]: %s", summary), nil)
-		if len(m.messages) > 0 && len(m.messages[0]) > 0 && strings.HasPrefix(m.messages[0][0].Content, "[Previous Conversation Summary]:") {
-			m.messages = m.messages[1:]
-		}
-		m.messages = append([][]*schema.Message{{summaryMessage}}, m.messages...)
-		m.round = len(m.messages) - 1
-		m.compressBuffer = make([][]*schema.Message, 0)
+	if summary != "" && epoch == m.compressEpoch {
+		m.applySummaryLocked(summary)
+	}
+}
 
-		// Flatten m.messages to []*schema.Message for persistence (m.compressBuffer is already cleared)
-		allMessages := make([]*schema.Message, 0)
-		for _, round := range m.messages {
-			allMessages = append(allMessages, round...)
-		}
+// applySummaryLocked puts the summary in front of the context in place of the rounds of
+// the compress buffer and persists the result. The caller must hold m.mu.
+func (m *Manager) applySummaryLocked(summary string) {
+	summaryMessage := schema.AssistantMessage(fmt.Sprintf("[Previous Conversation Summary]: %s", summary), nil)
+	if len(m.messages) > 0 && isSummaryRound(m.messages[0]) {
+		m.messages = m.messages[1:]
+	}
+	m.messages = append([][]*schema.Message{{summaryMessage}}, m.messages...)
+	m.round = len(m.messages) - 1
+	m.compressBuffer = make([][]*schema.Message, 0)
 
-		// Persist modified messages after compression
-		if m.compressionCompleteCallback != nil {
-			if err := m.compressionCompleteCallback(allMessages); err != nil {
-				logger.Warn("manager", fmt.Sprintf("Failed to persist messages after compression: %v", err))
-			}
+	// Persist modified messages after compression (m.compressBuffer is already cleared)
+	if m.compressionCompleteCallback != nil {
+		if err := m.compressionCompleteCallback(m.flatten(m.messages)); err != nil {
+			logger.Warn("manager", fmt.Sprintf("Failed to persist messages after compression: %v", err))
 		}
 	}
 }
 
+// ErrCompressionInProgress is returned by ForceCompress while the context is being compressed
+var ErrCompressionInProgress = errors.New("a compression of the context is already in progress")
+
+// CompressResult reports a compression run by ForceCompress
+type CompressResult struct {
+	// Rounds is the number of rounds replaced by the summary
+	Rounds int
+	// Messages and Tokens are the size of the context after the compression, the tokens
+	// are estimated
+	Messages int
+	Tokens   int
+}
+
+// ForceCompress summarizes the whole context except the most recent round right away,
+// whatever its size. A previous summary is summarized again with the other rounds.
+func (m *Manager) ForceCompress(ctx context.Context) (CompressResult, error) {
+	m.mu.Lock()
+	if m.compressing {
+		m.mu.Unlock()
+		return CompressResult{}, ErrCompressionInProgress
+	}
+	if m.chatmodel == nil {
+		m.mu.Unlock()
+		return CompressResult{}, fmt.Errorf("no model to summarize the context")
+	}
+	older := len(m.messages) - 1
+	if older < 1 || (older == 1 && isSummaryRound(m.messages[0]) && len(m.compressBuffer) == 0) {
+		m.mu.Unlock()
+		return CompressResult{}, fmt.Errorf("nothing to compact, the context has no round before the last one")
+	}
+	m.compressing = true
+
+	// Rounds of a failed compression are still in the buffer, they are summarized too
+	for _, round := range m.messages[:older] {
+		m.compressBuffer = append(m.compressBuffer, append([]*schema.Message(nil), round...))
+	}
+	m.messages = m.messages[older:]
+	m.round = len(m.messages) - 1
+	rounds := len(m.compressBuffer)
+	flatMessages := make([]*schema.Message, 0)
+	for _, round := range m.compressBuffer {
+		flatMessages = append(flatMessages, m.unpinned(round)...)
+	}
+	hook := m.eventHook
+	epoch := m.compressEpoch
+	m.mu.Unlock()
+
+	if hook != nil {
+		hook(Event{Type: EventCompressionStarted, Rounds: rounds})
+	}
+	summary := ""
+	if len(flatMessages) > 0 {
+		summary = m.doCompression(ctx, flatMessages)
+	}
+
+	m.mu.Lock()
+	edited := epoch != m.compressEpoch
+	if summary != "" && !edited {
+		m.applySummaryLocked(summary)
+	}
+	m.compressing = false
+	result := CompressResult{Rounds: rounds, Tokens: m.contextTokens()}
+	for _, round := range m.getAllRounds() {
+		result.Messages += len(round)
+	}
+	hook = m.eventHook
+	m.mu.Unlock()
+
+	if hook != nil {
+		hook(Event{Type: EventCompressionCompleted, Rounds: rounds, Summary: summary})
+	}
+	if edited {
+		return result, fmt.Errorf("the context was edited while it was summarized, the summary was discarded")
+	}
+	if summary == "" {
+		return result, fmt.Errorf("failed to summarize the context, the %d rounds are kept", rounds)
+	}
+	return result, nil
+}
+
 // doCompression performs the actual compression logic
 func (m *Manager) doCompression(ctx context.Context, flatMessages []*schema.Message) string {
 	if len(flatMessages) == 0 {
@@ -435,20 +792,21 @@ func (m *Manager) getAllRounds() [][]*schema.Message {
 
 // GetMessages retrieves simplified messages in the current context
 // Old rounds are simplified (first user message + last assistant response)
+// Pinned messages come first, in the order they were pinned.
 // The returned messages are guaranteed to have proper tool_call / tool_result pairing.
 func (m *Manager) GetMessages() []*schema.Message {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	allRounds := m.getAllRounds()
-	simplifiedMessages := make([]*schema.Message, 0)
+	simplifiedMessages := append([]*schema.Message(nil), m.pinned...)
 
 	// Determine cutoff index based on total rounds
 	totalRounds := len(allRounds)
 	if totalRounds <= m.fullMessageRounds {
 		// All rounds are recent, return full messages
 		for _, round := range allRounds {
-			simplifiedMessages = append(simplifiedMessages, round...)
+			simplifiedMessages = append(simplifiedMessages, m.unpinned(round)...)
 		}
 		return m.validateAndCleanRound(simplifiedMessages)
 	}
@@ -457,6 +815,7 @@ func (m *Manager) GetMessages() []*schema.Message {
 
 	// Process each round
 	for i, round := range allRounds {
+		round = m.unpinned(round)
 		if len(round) == 0 {
 			continue
 		}
@@ -489,18 +848,185 @@ func (m *Manager) GetMessages() []*schema.Message {
 }
 
 // GetFullMessages retrieves all full messages in the current context
-// This includes all original messages without any simplification.
+// This includes all original messages without any simplification, with the pinned
+// messages first like GetMessages.
 // The returned messages are guaranteed to have proper tool_call / tool_result pairing.
 func (m *Manager) GetFullMessages() []*schema.Message {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	allRounds := m.getAllRounds()
-	fullMessages := make([]*schema.Message, 0)
-	for _, round := range allRounds {
-		fullMessages = append(fullMessages, round...)
+	return m.validateAndCleanRound(m.flatten(m.getAllRounds()))
+}
+
+// flatten returns the pinned messages followed by the other messages of the rounds
+func (m *Manager) flatten(rounds [][]*schema.Message) []*schema.Message {
+	messages := append([]*schema.Message(nil), m.pinned...)
+	for _, round := range rounds {
+		messages = append(messages, m.unpinned(round)...)
+	}
+	return messages
+}
+
+// PinMessage pins the n-th user message of the full context, counting from 1 with the
+// pinned messages first, or the last one when n is 0. A pinned message is kept verbatim at the front of the context: it is never
+// summarized and stays when its round is dropped. With a token budget, the pinned
+// messages must fit in it.
+func (m *Manager) PinMessage(n int) (*schema.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var users []*schema.Message
+	for _, msg := range m.flatten(m.getAllRounds()) {
+		if msg.Role == schema.User {
+			users = append(users, msg)
+		}
+	}
+	if len(users) == 0 {
+		return nil, fmt.Errorf("no user message to pin")
+	}
+	if n < 0 || n > len(users) {
+		return nil, fmt.Errorf("no user message %d, the context has %d", n, len(users))
+	}
+	msg := users[len(users)-1]
+	if n > 0 {
+		msg = users[n-1]
+	}
+	if slices.Contains(m.pinned, msg) {
+		return nil, fmt.Errorf("the message is already pinned")
+	}
+	if m.maxTokens > 0 {
+		if tokens := m.pinnedTokens() + m.tokenCounter.CountTokens(msg); tokens > m.maxTokens {
+			return nil, fmt.Errorf("the pinned messages would take ~%d tokens, more than the context budget of %d tokens", tokens, m.maxTokens)
+		}
+	}
+	m.pinned = append(m.pinned, msg)
+	return msg, nil
+}
+
+// UnpinMessage unpins the n-th pinned message, counting from 1, or all of them when n is
+// 0. An unpinned message whose round was summarized or dropped leaves the context.
+func (m *Manager) UnpinMessage(n int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if n == 0 {
+		m.pinned = nil
+		return nil
+	}
+	if n < 0 || n > len(m.pinned) {
+		return fmt.Errorf("no pinned message %d, %d messages are pinned", n, len(m.pinned))
+	}
+	m.pinned = slices.Delete(m.pinned, n-1, n)
+	return nil
+}
+
+// PinnedMessages returns the pinned messages in the order they were pinned
+func (m *Manager) PinnedMessages() []*schema.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*schema.Message(nil), m.pinned...)
+}
+
+// unpinned returns the messages of the round that are not pinned
+func (m *Manager) unpinned(round []*schema.Message) []*schema.Message {
+	if len(m.pinned) == 0 || !slices.ContainsFunc(round, m.isPinned) {
+		return round
+	}
+	messages := make([]*schema.Message, 0, len(round))
+	for _, msg := range round {
+		if !m.isPinned(msg) {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
+
+func (m *Manager) isPinned(msg *schema.Message) bool {
+	return slices.Contains(m.pinned, msg)
+}
+
+// unpinRounds unpins the messages of rounds removed from the conversation
+func (m *Manager) unpinRounds(rounds [][]*schema.Message) {
+	for _, round := range rounds {
+		m.pinned = slices.DeleteFunc(m.pinned, func(msg *schema.Message) bool {
+			return slices.Contains(round, msg)
+		})
+	}
+}
+
+// relinkPinned points the pinned messages to the equal messages of the rounds, which are
+// distinct copies once a state was decoded
+func (m *Manager) relinkPinned() {
+	for i, pinned := range m.pinned {
+		for _, round := range m.getAllRounds() {
+			for _, msg := range round {
+				if msg.Role == pinned.Role && msg.Content == pinned.Content {
+					m.pinned[i] = msg
+				}
+			}
+		}
+	}
+}
+
+// Snapshot returns a copy of the conversation state, including rounds still waiting
+// to be compressed
+func (m *Manager) Snapshot() State {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return State{
+		Messages:       copyRounds(m.messages),
+		CompressBuffer: copyRounds(m.compressBuffer),
+		Round:          m.round,
+		Pinned:         append([]*schema.Message(nil), m.pinned...),
+	}
+}
+
+// Restore replaces the conversation state with a snapshot. The persistence callbacks
+// are not invoked.
+func (m *Manager) Restore(state State) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = copyRounds(state.Messages)
+	m.compressBuffer = copyRounds(state.CompressBuffer)
+	m.round = state.Round
+	if m.round < 0 || m.round >= len(m.messages) {
+		m.round = max(len(m.messages)-1, 0)
+	}
+	m.pinned = append([]*schema.Message(nil), state.Pinned...)
+	m.relinkPinned()
+}
+
+// ReplaceMessages replaces the conversation with the given messages. A new round starts
+// at each user message, and every round is validated like at the end of a round, so
+// tool calls without results do not break the next model call. The pinned messages are
+// unpinned. The persistence callbacks are not invoked.
+func (m *Manager) ReplaceMessages(messages []*schema.Message) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rounds := make([][]*schema.Message, 0)
+	for _, msg := range messages {
+		if len(rounds) == 0 || (msg.Role == schema.User && len(rounds[len(rounds)-1]) > 0) {
+			rounds = append(rounds, make([]*schema.Message, 0))
+		}
+		rounds[len(rounds)-1] = append(rounds[len(rounds)-1], msg)
+	}
+	for i, round := range rounds {
+		rounds[i] = m.validateAndCleanRound(round)
+	}
+	m.messages = rounds
+	m.compressBuffer = make([][]*schema.Message, 0)
+	m.round = max(len(m.messages)-1, 0)
+	m.pinned = nil
+}
+
+// copyRounds copies the round slices, the messages themselves are shared
+func copyRounds(rounds [][]*schema.Message) [][]*schema.Message {
+	copied := make([][]*schema.Message, 0, len(rounds))
+	for _, round := range rounds {
+		copied = append(copied, append([]*schema.Message(nil), round...))
 	}
-	return m.validateAndCleanRound(fullMessages)
+	return copied
 }
 
 // Clear clears the context (preserves system messages)
@@ -510,6 +1036,7 @@ func (m *Manager) Clear() {
 	m.round = 0
 	m.messages = make([][]*schema.Message, 0)
 	m.compressBuffer = make([][]*schema.Message, 0)
+	m.pinned = nil
 }
 
 // RemoveLastRound removes the last round of messages from the context.
@@ -523,12 +1050,154 @@ func (m *Manager) RemoveLastRound() {
 		return
 	}
 
+	m.unpinRounds(m.messages[len(m.messages)-1:])
 	m.messages = m.messages[:len(m.messages)-1]
 	if m.round >= len(m.messages) {
 		m.round = len(m.messages) - 1
 	}
 }
 
+// PopLastAssistantRound removes the last round holding a user message, together with
+// the assistant and tool messages answering it, and returns that user message so it can
+// be sent again. Empty rounds after it are dropped as well, and the round that becomes
+// the last one is cleaned of tool calls without results. Returns nil if there is no user
+// message to retry.
+func (m *Manager) PopLastAssistantRound() *schema.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		var userMsg *schema.Message
+		for _, msg := range m.messages[i] {
+			if msg.Role == schema.User {
+				userMsg = msg
+				break
+			}
+		}
+		if userMsg == nil {
+			if len(m.messages[i]) > 0 {
+				// A round without a user message, e.g. the summary of compressed rounds
+				return nil
+			}
+			continue
+		}
+
+		m.unpinRounds(m.messages[i:])
+		m.messages = m.messages[:i]
+		m.round = len(m.messages) - 1
+		if m.round >= 0 {
+			m.messages[m.round] = m.validateAndCleanRound(m.messages[m.round])
+		} else {
+			m.round = 0
+		}
+		return userMsg
+	}
+	return nil
+}
+
+// TruncateToRound drops the rounds after the n-th round holding a user message, counting
+// from 0 over the rounds of the context, or from the end when n is negative. Summarized
+// rounds are not counted. The kept last round is cleaned of tool calls without results.
+func (m *Manager) TruncateToRound(n int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i, err := m.userRound(n)
+	if err != nil {
+		return err
+	}
+	i = m.contextRound(i)
+	m.unpinRounds(m.messages[i+1:])
+	m.messages = m.messages[:i+1]
+	m.round = i
+	m.messages[i] = m.validateAndCleanRound(m.messages[i])
+	return nil
+}
+
+// ReplaceUserMessageAt replaces the user message of the n-th round holding one, counted
+// like in TruncateToRound, by a copy with the new text, attached files are kept. The rest
+// of the round, the response to the replaced message, is dropped. Returns the new message.
+func (m *Manager) ReplaceUserMessageAt(n int, content string) (*schema.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i, err := m.userRound(n)
+	if err != nil {
+		return nil, err
+	}
+	i = m.contextRound(i)
+	round := m.messages[i]
+	j := slices.IndexFunc(round, func(msg *schema.Message) bool { return msg.Role == schema.User })
+	old := round[j]
+	edited := withText(old, content)
+	m.messages[i] = m.validateAndCleanRound(append(round[:j:j], edited))
+	if k := slices.Index(m.pinned, old); k >= 0 {
+		m.pinned[k] = edited
+	}
+	return edited, nil
+}
+
+// userRound returns the index in getAllRounds of the n-th round holding a user message,
+// counting from 0, or from the end when n is negative
+func (m *Manager) userRound(n int) (int, error) {
+	var rounds []int
+	for i, round := range m.getAllRounds() {
+		if slices.ContainsFunc(round, func(msg *schema.Message) bool { return msg.Role == schema.User }) {
+			rounds = append(rounds, i)
+		}
+	}
+	k := n
+	if n < 0 {
+		k = len(rounds) + n
+	}
+	if k < 0 || k >= len(rounds) {
+		return 0, fmt.Errorf("no round %d, the context has %d rounds with a user message", n, len(rounds))
+	}
+	return rounds[k], nil
+}
+
+// contextRound maps the index of a round in getAllRounds to its index in m.messages. A
+// round of the compress buffer is first put back in the context by restoreCompressBuffer.
+func (m *Manager) contextRound(i int) int {
+	if i < len(m.compressBuffer) {
+		m.restoreCompressBuffer()
+		return i
+	}
+	return i - len(m.compressBuffer)
+}
+
+// restoreCompressBuffer puts the rounds waiting to be compressed back in front of the
+// context, so they can be edited. A compression in progress is discarded: its summary
+// would hold the content before the edit.
+func (m *Manager) restoreCompressBuffer() {
+	m.messages = append(m.compressBuffer, m.messages...)
+	m.compressBuffer = make([][]*schema.Message, 0)
+	m.round = len(m.messages) - 1
+	m.compressEpoch++
+}
+
+// withText returns a copy of the user message with its text replaced, in the text part
+// of a multimodal message
+func withText(msg *schema.Message, text string) *schema.Message {
+	edited := *msg
+	edited.Content = text
+	if len(msg.UserInputMultiContent) == 0 {
+		return &edited
+	}
+	textPart := schema.MessageInputPart{Type: schema.ChatMessagePartTypeText, Text: text}
+	parts := make([]schema.MessageInputPart, 0, len(msg.UserInputMultiContent)+1)
+	for _, part := range msg.UserInputMultiContent {
+		if part.Type != schema.ChatMessagePartTypeText {
+			parts = append(parts, part)
+		}
+	}
+	if text != "" {
+		parts = append([]schema.MessageInputPart{textPart}, parts...)
+	}
+	edited.UserInputMultiContent = parts
+	return &edited
+}
+
 // GetLastUserMessage returns the content of the last user message in the conversation.
 // Returns empty string if no user message is found.
 func (m *Manager) GetLastUserMessage() string {
@@ -0,0 +1,632 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// addRounds adds n rounds of a user question and an assistant answer
+func addRounds(m *Manager, start, n int) {
+	ctx := context.Background()
+	for i := start; i < start+n; i++ {
+		if i > 0 || len(m.messages) > 0 {
+			m.IncRound()
+		}
+		m.AddMessage(ctx, schema.UserMessage(fmt.Sprintf("question %d", i)))
+		m.AddMessage(ctx, schema.AssistantMessage(fmt.Sprintf("answer %d", i), nil))
+	}
+}
+
+func TestMaxRoundsWithoutCompression(t *testing.T) {
+	m := NewManager(Config{MaxMessageRounds: 10})
+	m.SetMaxRounds(3)
+
+	var persisted []*schema.Message
+	appended := 0
+	m.SetPersistenceCallback(func(*schema.Message) error {
+		appended++
+		return nil
+	})
+	m.SetCompressionCompleteCallback(func(messages []*schema.Message) error {
+		persisted = messages
+		return nil
+	})
+
+	addRounds(m, 0, 5)
+
+	if got := len(m.messages); got != 3 {
+		t.Fatalf("rounds = %d, want 3", got)
+	}
+	if got := m.messages[0][0].Content; got != "question 2" {
+		t.Errorf("oldest round starts with %q, want %q", got, "question 2")
+	}
+	// The last rewrite happens when "question 4" is added, "answer 4" is appended afterwards
+	if len(persisted) != 5 || persisted[0].Content != "question 2" || persisted[4].Content != "question 4" {
+		t.Errorf("persisted %v, want the remaining rounds up to %q", persisted, "question 4")
+	}
+	// Messages that triggered a rewrite are not appended again
+	if appended != 8 {
+		t.Errorf("appended %d messages, want 8", appended)
+	}
+}
+
+func TestMaxRoundsDefaultsToMaxMessageRound(t *testing.T) {
+	m := NewManager(Config{MaxMessageRounds: CompressionThreshold})
+	addRounds(m, 0, CompressionThreshold+2)
+
+	if got := len(m.messages); got != CompressionThreshold {
+		t.Fatalf("rounds = %d, want %d", got, CompressionThreshold)
+	}
+}
+
+func TestMaxRoundsKeepsSummaryRound(t *testing.T) {
+	m := NewManager(Config{MaxMessageRounds: 10})
+	m.SetMaxRounds(2)
+	m.messages = [][]*schema.Message{{schema.AssistantMessage("[Previous Conversation Summary]: earlier", nil)}}
+	m.round = 0
+
+	addRounds(m, 0, 3)
+
+	if got := len(m.messages); got != 2 {
+		t.Fatalf("rounds = %d, want 2", got)
+	}
+	if !isSummaryRound(m.messages[0]) {
+		t.Errorf("oldest round = %q, want the summary round", m.messages[0][0].Content)
+	}
+	if got := m.messages[1][0].Content; got != "question 2" {
+		t.Errorf("current round starts with %q, want %q", got, "question 2")
+	}
+}
+
+func TestMaxRoundsWithSimpleTruncation(t *testing.T) {
+	m := NewManager(Config{MaxMessageRounds: 5})
+	m.SetMaxRounds(2)
+	addRounds(m, 0, 4)
+
+	if got := len(m.messages); got != 2 {
+		t.Fatalf("rounds = %d, want 2", got)
+	}
+	if got := m.messages[0][0].Content; got != "question 2" {
+		t.Errorf("oldest round starts with %q, want %q", got, "question 2")
+	}
+}
+
+func TestMaxRoundsNeverDropsCurrentRound(t *testing.T) {
+	m := NewManager(Config{MaxMessageRounds: 10})
+	m.SetMaxRounds(1)
+	addRounds(m, 0, 2)
+
+	if got := len(m.messages); got != 1 {
+		t.Fatalf("rounds = %d, want 1", got)
+	}
+	if got := len(m.messages[0]); got != 2 {
+		t.Errorf("current round has %d messages, want 2", got)
+	}
+}
+
+func TestSnapshotAndRestore(t *testing.T) {
+	m := NewManager(Config{MaxMessageRounds: 10})
+	addRounds(m, 0, 3)
+	m.compressBuffer = [][]*schema.Message{{schema.UserMessage("earlier")}}
+
+	state := m.Snapshot()
+	restored := NewManager(Config{MaxMessageRounds: 10})
+	persisted := 0
+	restored.SetPersistenceCallback(func(*schema.Message) error {
+		persisted++
+		return nil
+	})
+	restored.Restore(state)
+
+	if got := restored.GetMessageCount(); got != 7 {
+		t.Errorf("restored message count = %d, want 7", got)
+	}
+	if persisted != 0 {
+		t.Errorf("restore persisted %d messages, want none", persisted)
+	}
+
+	// Later messages continue the last round and do not change the snapshot
+	restored.AddMessage(context.Background(), schema.AssistantMessage("more", nil))
+	if got := len(restored.messages[2]); got != 3 {
+		t.Errorf("current round has %d messages, want 3", got)
+	}
+	if got := len(state.Messages[2]); got != 2 {
+		t.Errorf("snapshot round has %d messages, want 2", got)
+	}
+}
+
+func TestReplaceMessagesCleansRounds(t *testing.T) {
+	m := NewManager(Config{MaxMessageRounds: 10})
+	addRounds(m, 0, 2)
+
+	call := schema.ToolCall{ID: "call_1", Function: schema.FunctionCall{Name: "cmd"}}
+	m.ReplaceMessages([]*schema.Message{
+		schema.AssistantMessage("[Previous Conversation Summary]: earlier", nil),
+		schema.UserMessage("list files"),
+		schema.AssistantMessage("", []schema.ToolCall{call}),
+		schema.UserMessage("never mind"),
+		schema.AssistantMessage("ok", nil),
+	})
+
+	if got := len(m.messages); got != 3 {
+		t.Fatalf("rounds = %d, want 3", got)
+	}
+	if !isSummaryRound(m.messages[0]) {
+		t.Errorf("first round = %q, want the summary", m.messages[0][0].Content)
+	}
+	// The tool call without a result is dropped
+	if got := len(m.messages[1]); got != 1 {
+		t.Errorf("second round has %d messages, want only the user message", got)
+	}
+	if m.round != 2 {
+		t.Errorf("round = %d, want 2", m.round)
+	}
+}
+
+// summaryModel answers every request with the same summary
+type summaryModel struct {
+	model.ToolCallingChatModel
+}
+
+func (summaryModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	return schema.AssistantMessage("the summary", nil), nil
+}
+
+func TestEventHookReportsCompression(t *testing.T) {
+	m := NewManager(Config{MaxMessageRounds: 10})
+	m.SetChatModel(summaryModel{})
+	addRounds(m, 0, 4)
+
+	var events []Event
+	m.SetEventHook(func(event Event) {
+		// The hook runs without the manager lock, calling back must not deadlock
+		m.GetMessages()
+		events = append(events, event)
+	})
+	m.compressMessagesAsync(context.Background())
+
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2: %+v", len(events), events)
+	}
+	if events[0].Type != EventCompressionStarted || events[0].Rounds != 2 {
+		t.Errorf("first event = %+v, want compression_started of 2 rounds", events[0])
+	}
+	if events[1].Type != EventCompressionCompleted || events[1].Rounds != 2 || events[1].Summary != "the summary" {
+		t.Errorf("second event = %+v, want compression_completed of 2 rounds with the summary", events[1])
+	}
+	if !isSummaryRound(m.messages[0]) {
+		t.Errorf("oldest round = %q, want the summary round", m.messages[0][0].Content)
+	}
+}
+
+func TestForceCompressKeepsLastRound(t *testing.T) {
+	m := NewManager(Config{MaxMessageRounds: 10})
+	var summarized []*schema.Message
+	m.SetChatModel(recordingSummaryModel{input: &summarized})
+	m.messages = [][]*schema.Message{{schema.AssistantMessage("[Previous Conversation Summary]: earlier", nil)}}
+	m.round = 0
+	addRounds(m, 0, 3)
+
+	result, err := m.ForceCompress(context.Background())
+	if err != nil {
+		t.Fatalf("ForceCompress() error = %v", err)
+	}
+	if result.Rounds != 3 || result.Messages != 3 || result.Tokens == 0 {
+		t.Errorf("result = %+v, want 3 rounds compacted into a context of 3 messages", result)
+	}
+	if len(m.messages) != 2 || !isSummaryRound(m.messages[0]) || m.messages[1][0].Content != "question 2" {
+		t.Errorf("messages = %v, want the summary and the last round", m.messages)
+	}
+	// The previous summary is summarized again with the other rounds
+	if len(summarized) != 6 || summarized[0].Content != "[Previous Conversation Summary]: earlier" {
+		t.Errorf("summarized %v, want the previous summary and two rounds", summarized)
+	}
+
+	if _, err := m.ForceCompress(context.Background()); err == nil {
+		t.Error("ForceCompress() of a summary and one round succeeded, want nothing to compact")
+	}
+	m.compressing = true
+	addRounds(m, 3, 1)
+	if _, err := m.ForceCompress(context.Background()); err != ErrCompressionInProgress {
+		t.Errorf("ForceCompress() during a compression error = %v, want ErrCompressionInProgress", err)
+	}
+}
+
+// fixedCounter counts the same number of tokens for every message
+type fixedCounter int
+
+func (c fixedCounter) CountTokens(*schema.Message) int { return int(c) }
+
+func TestMaxTokensDropsOldestRoundsWithoutModel(t *testing.T) {
+	m := NewManager(Config{MaxMessageRounds: 10, MaxTokens: 50, TokenCounter: fixedCounter(10)})
+	addRounds(m, 0, 5)
+
+	if got := len(m.messages); got != 2 {
+		t.Fatalf("rounds = %d, want 2", got)
+	}
+	if got := m.messages[0][0].Content; got != "question 3" {
+		t.Errorf("oldest round starts with %q, want %q", got, "question 3")
+	}
+}
+
+func TestMaxTokensKeepsOversizedMessage(t *testing.T) {
+	m := NewManager(Config{MaxTokens: 20})
+	var events []Event
+	m.SetEventHook(func(event Event) { events = append(events, event) })
+
+	addRounds(m, 0, 1)
+	m.IncRound()
+	huge := strings.Repeat("word ", 100)
+	m.AddMessage(context.Background(), schema.UserMessage(huge))
+
+	if got := len(m.messages); got != 1 {
+		t.Fatalf("rounds = %d, want only the current round", got)
+	}
+	if got := m.messages[0][0].Content; got != huge {
+		t.Errorf("current round starts with %q, want the oversized message", got)
+	}
+	if len(events) != 1 || events[0].Type != EventOversizedMessage || events[0].Tokens <= 20 {
+		t.Errorf("events = %+v, want one oversized_message over 20 tokens", events)
+	}
+}
+
+func TestMaxTokensTriggersCompression(t *testing.T) {
+	m := NewManager(Config{MaxMessageRounds: 10, MaxTokens: 100, TokenCounter: fixedCounter(10)})
+	m.SetChatModel(summaryModel{})
+	completed := make(chan Event, 1)
+	m.SetEventHook(func(event Event) {
+		if event.Type == EventCompressionCompleted {
+			completed <- event
+		}
+	})
+
+	// 4 rounds reach 70% of the budget, far below the round limit
+	addRounds(m, 0, 4)
+
+	select {
+	case event := <-completed:
+		if event.Summary != "the summary" {
+			t.Errorf("summary = %q, want %q", event.Summary, "the summary")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("compression was not triggered")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !isSummaryRound(m.messages[0]) {
+		t.Errorf("oldest round = %q, want the summary round", m.messages[0][0].Content)
+	}
+}
+
+func TestPopLastAssistantRound(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager(Config{MaxMessageRounds: 10})
+	if m.PopLastAssistantRound() != nil {
+		t.Fatal("popped a round of an empty context")
+	}
+
+	addRounds(m, 0, 1)
+	// A round interrupted during a tool call, then the round to retry
+	m.IncRound()
+	m.AddMessage(ctx, schema.UserMessage("question 1"))
+	m.AddMessage(ctx, schema.AssistantMessage("", []schema.ToolCall{{ID: "call-1"}}))
+	m.IncRound()
+	m.AddMessage(ctx, schema.UserMessage("question 2"))
+	m.AddMessage(ctx, schema.AssistantMessage("", []schema.ToolCall{{ID: "call-2"}}))
+	m.AddMessage(ctx, schema.ToolMessage("result", "call-2"))
+	m.AddMessage(ctx, schema.AssistantMessage("answer 2", nil))
+	m.IncRound()
+
+	userMsg := m.PopLastAssistantRound()
+	if userMsg == nil || userMsg.Content != "question 2" {
+		t.Fatalf("popped %v, want question 2", userMsg)
+	}
+	if len(m.messages) != 2 || m.round != 1 {
+		t.Fatalf("rounds = %d, current = %d, want 2 and 1", len(m.messages), m.round)
+	}
+	if got := m.messages[1]; len(got) != 1 || got[0].Content != "question 1" {
+		t.Errorf("last round = %v, want only the user message without the dangling tool call", got)
+	}
+
+	// A retried message is added back as a new round
+	m.IncRound()
+	m.AddMessage(ctx, userMsg)
+	if got := m.GetLastUserMessage(); got != "question 2" {
+		t.Errorf("last user message = %q, want question 2", got)
+	}
+
+	summary := NewManager(Config{MaxMessageRounds: 10})
+	summary.AddMessage(ctx, schema.AssistantMessage("[Previous Conversation Summary]: earlier", nil))
+	if summary.PopLastAssistantRound() != nil || len(summary.messages) != 1 {
+		t.Error("the summary round was popped")
+	}
+}
+
+// recordingSummaryModel answers with a summary and records the messages it summarized
+type recordingSummaryModel struct {
+	model.ToolCallingChatModel
+	input *[]*schema.Message
+}
+
+func (r recordingSummaryModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	*r.input = input
+	return schema.AssistantMessage("the summary", nil), nil
+}
+
+func TestPinnedMessageSurvivesCompression(t *testing.T) {
+	var summarized []*schema.Message
+	m := NewManager(Config{MaxMessageRounds: 10})
+	m.SetChatModel(recordingSummaryModel{input: &summarized})
+	var persisted []*schema.Message
+	m.SetCompressionCompleteCallback(func(messages []*schema.Message) error {
+		persisted = messages
+		return nil
+	})
+	addRounds(m, 0, 4)
+
+	pinned, err := m.PinMessage(1)
+	if err != nil || pinned.Content != "question 0" {
+		t.Fatalf("PinMessage(1) = %v, %v, want question 0", pinned, err)
+	}
+	if _, err := m.PinMessage(1); err == nil {
+		t.Error("pinned the same message twice")
+	}
+	m.compressMessagesAsync(context.Background())
+
+	for _, msg := range summarized {
+		if msg.Content == "question 0" {
+			t.Error("the pinned message was summarized")
+		}
+	}
+	messages := m.GetMessages()
+	if messages[0].Content != "question 0" || !strings.HasPrefix(messages[1].Content, "[Previous Conversation Summary]:") {
+		t.Errorf("context starts with %q, %q, want the pinned message then the summary", messages[0].Content, messages[1].Content)
+	}
+	count := 0
+	for _, msg := range messages {
+		if msg.Content == "question 0" {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("the pinned message is %d times in the context, want once", count)
+	}
+	if len(persisted) == 0 || persisted[0].Content != "question 0" {
+		t.Errorf("persisted %v, want the pinned message first", persisted)
+	}
+
+	if err := m.UnpinMessage(1); err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range m.GetMessages() {
+		if msg.Content == "question 0" {
+			t.Error("the unpinned message of a summarized round is still in the context")
+		}
+	}
+}
+
+func TestPinnedMessageMovesToFront(t *testing.T) {
+	m := NewManager(Config{MaxMessageRounds: 10})
+	addRounds(m, 0, 3)
+
+	if _, err := m.PinMessage(0); err != nil {
+		t.Fatal(err)
+	}
+	messages := m.GetMessages()
+	if messages[0].Content != "question 2" {
+		t.Errorf("context starts with %q, want the pinned last user message", messages[0].Content)
+	}
+	if got := len(m.GetFullMessages()); got != 6 {
+		t.Errorf("full messages = %d, want 6 without duplicates", got)
+	}
+	if _, err := m.PinMessage(5); err == nil {
+		t.Error("pinned a user message that doesn't exist")
+	}
+
+	// Retrying the round of a pinned message unpins it
+	m.IncRound()
+	if userMsg := m.PopLastAssistantRound(); userMsg == nil || userMsg.Content != "question 2" {
+		t.Fatalf("popped %v, want question 2", userMsg)
+	}
+	if got := m.PinnedMessages(); len(got) != 0 {
+		t.Errorf("pinned = %v after popping its round, want none", got)
+	}
+}
+
+func TestPinnedMessageSurvivesDroppedRounds(t *testing.T) {
+	m := NewManager(Config{MaxMessageRounds: 4})
+	addRounds(m, 0, 1)
+	if _, err := m.PinMessage(1); err != nil {
+		t.Fatal(err)
+	}
+	addRounds(m, 1, 6)
+
+	messages := m.GetMessages()
+	if messages[0].Content != "question 0" {
+		t.Errorf("context starts with %q, want the pinned message", messages[0].Content)
+	}
+	if got := len(m.messages); got != 4 {
+		t.Errorf("rounds = %d, want 4", got)
+	}
+}
+
+func TestPinnedMessagesWithinTokenBudget(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager(Config{MaxMessageRounds: 10, MaxTokens: 45, TokenCounter: fixedCounter(10)})
+	addRounds(m, 0, 1)
+	if _, err := m.PinMessage(1); err != nil {
+		t.Fatal(err)
+	}
+
+	// The pinned message counts in the budget once, the oldest rounds make room for it
+	addRounds(m, 1, 1)
+	m.IncRound()
+	m.AddMessage(ctx, schema.UserMessage("question 2"))
+	messages := m.GetMessages()
+	var contents []string
+	for _, msg := range messages {
+		contents = append(contents, msg.Content)
+	}
+	if got := strings.Join(contents, ","); got != "question 0,question 1,answer 1,question 2" {
+		t.Errorf("context = %s, want the pinned message and the rounds fitting in the budget", got)
+	}
+
+	// A message that can't fit in the budget with the pinned ones is not pinned
+	huge := NewManager(Config{MaxTokens: 20})
+	huge.AddMessage(ctx, schema.UserMessage(strings.Repeat("word ", 100)))
+	_, err := huge.PinMessage(0)
+	if err == nil || !strings.Contains(err.Error(), "more than the context budget of 20 tokens") {
+		t.Fatalf("PinMessage() over the budget = %v, want an error", err)
+	}
+	if got := huge.PinnedMessages(); len(got) != 0 {
+		t.Errorf("pinned = %v, want none", got)
+	}
+}
+
+func TestSnapshotRestoresPinnedMessages(t *testing.T) {
+	m := NewManager(Config{MaxMessageRounds: 10})
+	addRounds(m, 0, 2)
+	if _, err := m.PinMessage(1); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(m.Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewManager(Config{MaxMessageRounds: 10})
+	restored.Restore(state)
+	messages := restored.GetFullMessages()
+	if len(messages) != 4 || messages[0].Content != "question 0" || messages[1].Content != "answer 0" {
+		t.Errorf("restored context = %v, want the pinned message once at the front", messages)
+	}
+}
+
+func TestTruncateAndReplaceUserMessage(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager(Config{MaxMessageRounds: 10})
+	addRounds(m, 0, 1)
+	m.IncRound()
+	m.AddMessage(ctx, &schema.Message{Role: schema.User, Content: "question 1", UserInputMultiContent: []schema.MessageInputPart{
+		{Type: schema.ChatMessagePartTypeText, Text: "question 1"},
+		{Type: schema.ChatMessagePartTypeImageURL, Image: &schema.MessageInputImage{MessagePartCommon: schema.MessagePartCommon{MIMEType: "image/png"}}},
+	}})
+	m.AddMessage(ctx, schema.AssistantMessage("", []schema.ToolCall{{ID: "call-1"}}))
+	m.AddMessage(ctx, schema.ToolMessage("result", "call-1"))
+	m.AddMessage(ctx, schema.AssistantMessage("answer 1", nil))
+	addRounds(m, 2, 2)
+	if _, err := m.PinMessage(4); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.TruncateToRound(4); err == nil {
+		t.Error("truncated to a missing round")
+	}
+	if err := m.TruncateToRound(1); err != nil {
+		t.Fatalf("TruncateToRound(1) error = %v", err)
+	}
+	if len(m.messages) != 2 || m.round != 1 || len(m.messages[1]) != 4 {
+		t.Fatalf("rounds = %v, current = %d, want the first two rounds", m.messages, m.round)
+	}
+	if len(m.PinnedMessages()) != 0 {
+		t.Error("the pinned message of a dropped round is still pinned")
+	}
+
+	edited, err := m.ReplaceUserMessageAt(-1, "edited question")
+	if err != nil {
+		t.Fatalf("ReplaceUserMessageAt(-1) error = %v", err)
+	}
+	if got := m.messages[1]; len(got) != 1 || got[0] != edited {
+		t.Errorf("last round = %v, want only the edited message", got)
+	}
+	parts := edited.UserInputMultiContent
+	if edited.Content != "edited question" || len(parts) != 2 || parts[0].Text != "edited question" || parts[1].Type != schema.ChatMessagePartTypeImageURL {
+		t.Errorf("edited message = %+v, want the new text with the image kept", edited)
+	}
+	if got := m.GetLastUserMessage(); got != "edited question" {
+		t.Errorf("last user message = %q, want the edited question", got)
+	}
+}
+
+func TestTruncateAndReplaceAfterCompressBuffer(t *testing.T) {
+	// Rounds waiting to be compressed come first in the round count
+	m := NewManager(Config{MaxMessageRounds: 10})
+	addRounds(m, 2, 3)
+	m.compressBuffer = [][]*schema.Message{
+		{schema.UserMessage("question 0"), schema.AssistantMessage("answer 0", nil)},
+		{schema.UserMessage("question 1"), schema.AssistantMessage("answer 1", nil)},
+	}
+
+	if err := m.TruncateToRound(3); err != nil {
+		t.Fatalf("TruncateToRound(3) error = %v", err)
+	}
+	if len(m.compressBuffer) != 2 || len(m.messages) != 2 || m.round != 1 || m.messages[1][0].Content != "question 3" {
+		t.Fatalf("buffer = %v, rounds = %v, current = %d, want the buffer kept and rounds 2 and 3", m.compressBuffer, m.messages, m.round)
+	}
+
+	edited, err := m.ReplaceUserMessageAt(2, "edited question")
+	if err != nil {
+		t.Fatalf("ReplaceUserMessageAt(2) error = %v", err)
+	}
+	if got := m.messages[0]; len(got) != 1 || got[0] != edited {
+		t.Errorf("round 2 = %v, want only the edited message", got)
+	}
+	if got := m.messages[1][0].Content; got != "question 3" {
+		t.Errorf("round 3 starts with %q, want it untouched", got)
+	}
+
+	if err := m.TruncateToRound(4); err == nil {
+		t.Error("truncated to a missing round")
+	}
+}
+
+// blockingSummaryModel summarizes once it is released
+type blockingSummaryModel struct {
+	model.ToolCallingChatModel
+	started, release chan struct{}
+}
+
+func (b blockingSummaryModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	close(b.started)
+	<-b.release
+	return schema.AssistantMessage("the summary", nil), nil
+}
+
+func TestTruncateDiscardsPendingSummary(t *testing.T) {
+	m := NewManager(Config{MaxMessageRounds: 10})
+	summaryModel := blockingSummaryModel{started: make(chan struct{}), release: make(chan struct{})}
+	m.SetChatModel(summaryModel)
+	addRounds(m, 0, 4)
+
+	done := make(chan struct{})
+	go func() {
+		m.compressMessagesAsync(context.Background())
+		close(done)
+	}()
+	<-summaryModel.started
+	// Round 0 waits to be compressed
+	if err := m.TruncateToRound(0); err != nil {
+		t.Fatalf("TruncateToRound(0) error = %v", err)
+	}
+	close(summaryModel.release)
+	<-done
+
+	messages := m.GetMessages()
+	if len(messages) != 2 || messages[0].Content != "question 0" || messages[1].Content != "answer 0" {
+		t.Errorf("context = %v, want only the first round without a summary", messages)
+	}
+	if len(m.compressBuffer) != 0 {
+		t.Errorf("compress buffer = %v, want it empty", m.compressBuffer)
+	}
+}
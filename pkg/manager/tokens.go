@@ -0,0 +1,62 @@
+package manager
+
+import (
+	"unicode/utf8"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	// messageOverheadTokens approximates the tokens of the role and message framing
+	messageOverheadTokens = 4
+	// mediaPartTokens approximates the tokens of an image, audio or video part, whose
+	// size in tokens doesn't depend on the length of its URL or data
+	mediaPartTokens = 1000
+)
+
+// TokenCounter estimates the number of tokens of a message
+type TokenCounter interface {
+	CountTokens(message *schema.Message) int
+}
+
+// ApproxTokenCounter estimates tokens without a tokenizer: about four ASCII characters
+// per token and one token per other character, which overestimates most texts slightly.
+type ApproxTokenCounter struct{}
+
+// CountTokens implements TokenCounter
+func (ApproxTokenCounter) CountTokens(message *schema.Message) int {
+	if message == nil {
+		return 0
+	}
+	tokens := messageOverheadTokens + approxTextTokens(message.Content) + approxTextTokens(message.ReasoningContent)
+	for _, call := range message.ToolCalls {
+		tokens += approxTextTokens(call.Function.Name) + approxTextTokens(call.Function.Arguments)
+	}
+	for _, part := range message.UserInputMultiContent {
+		if part.Type == schema.ChatMessagePartTypeText {
+			tokens += approxTextTokens(part.Text)
+		} else {
+			tokens += mediaPartTokens
+		}
+	}
+	for _, part := range message.AssistantGenMultiContent {
+		if part.Type == schema.ChatMessagePartTypeText {
+			tokens += approxTextTokens(part.Text)
+		} else {
+			tokens += mediaPartTokens
+		}
+	}
+	return tokens
+}
+
+func approxTextTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
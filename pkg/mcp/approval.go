@@ -20,6 +20,8 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/Arvintian/chat-agent/pkg/config"
+
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
@@ -34,14 +36,50 @@ type ApprovalInfo struct {
 type ApprovalResult struct {
 	Approved         bool
 	DisapproveReason *string
+	// Always asks to approve the same tool call with the same arguments for the rest of the session
+	Always bool
+}
+
+// Approval decisions of a tool call, recorded with WithApprovalDecision
+const (
+	ApprovalNotRequired    = "not_required"
+	ApprovalApproved       = "approved"
+	ApprovalApprovedByRule = "approved_by_rule"
+	ApprovalDenied         = "denied"
+	ApprovalDeniedByRule   = "denied_by_rule"
+)
+
+// ApprovalDecision is how the approval of a tool call was resolved
+type ApprovalDecision struct {
+	Decision string
+	Reason   string
+}
+
+type approvalDecisionKey struct{}
+
+// WithApprovalDecision returns a context in which an approvable tool records how the
+// approval of its call was resolved in decision. decision is left as is for tools
+// that need no approval.
+func WithApprovalDecision(ctx context.Context, decision *ApprovalDecision) context.Context {
+	return context.WithValue(ctx, approvalDecisionKey{}, decision)
+}
+
+// recordApprovalDecision records the approval decision in the context, if it has a recorder
+func recordApprovalDecision(ctx context.Context, decision, reason string) {
+	if d, ok := ctx.Value(approvalDecisionKey{}).(*ApprovalDecision); ok && d != nil {
+		d.Decision = decision
+		d.Reason = reason
+	}
 }
 
 func (ai *ApprovalInfo) String() string {
-	return fmt.Sprintf("ToolCall: (%s) interrupted, waiting for your approval, please answer with Y/N", ai.ToolName)
+	return fmt.Sprintf("ToolCall: (%s) interrupted, waiting for your approval, please answer with Y/N (A to always approve this exact call in this session)", ai.ToolName)
 }
 
 type InvokableApprovableTool struct {
 	tool.InvokableTool
+	// Rules approve or deny calls without asking, calls no rule decides ask for approval
+	Rules ApprovalRules
 }
 
 func (i InvokableApprovableTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
@@ -55,7 +93,18 @@ func (i InvokableApprovableTool) InvokableRun(ctx context.Context, argumentsInJS
 	}
 
 	wasInterrupted, _, storedArguments := compose.GetInterruptState[string](ctx)
-	if !wasInterrupted { // initial invocation, interrupt and wait for approval
+	if !wasInterrupted { // initial invocation, interrupt and wait for approval unless a rule decides
+		switch action, reason := i.Rules.Evaluate(toolInfo.Name, argumentsInJSON); action {
+		case config.ApprovalApprove:
+			recordApprovalDecision(ctx, ApprovalApprovedByRule, reason)
+			return i.InvokableTool.InvokableRun(ctx, argumentsInJSON, opts...)
+		case config.ApprovalDeny:
+			if reason == "" {
+				reason = "denied by an approval rule"
+			}
+			recordApprovalDecision(ctx, ApprovalDeniedByRule, reason)
+			return fmt.Sprintf("tool '%s' disapproved, reason: %s", toolInfo.Name, reason), nil
+		}
 		return "", compose.StatefulInterrupt(ctx, &ApprovalInfo{
 			ToolName:        toolInfo.Name,
 			ArgumentsInJSON: argumentsInJSON,
@@ -76,13 +125,16 @@ func (i InvokableApprovableTool) InvokableRun(ctx context.Context, argumentsInJS
 	}
 
 	if data.Approved {
+		recordApprovalDecision(ctx, ApprovalApproved, "")
 		return i.InvokableTool.InvokableRun(ctx, storedArguments, opts...)
 	}
 
 	if data.DisapproveReason != nil {
+		recordApprovalDecision(ctx, ApprovalDenied, *data.DisapproveReason)
 		return fmt.Sprintf("tool '%s' disapproved, reason: %s", toolInfo.Name, *data.DisapproveReason), nil
 	}
 
+	recordApprovalDecision(ctx, ApprovalDenied, "")
 	return fmt.Sprintf("tool '%s' disapproved", toolInfo.Name), nil
 }
 
@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+)
+
+// ApprovalRules decide the approval of tool calls from their tool name and arguments
+type ApprovalRules []approvalRule
+
+type approvalRule struct {
+	tool   string
+	args   *regexp.Regexp
+	params map[string]*regexp.Regexp
+	action string
+	reason string
+}
+
+// CompileApprovalRules compiles the regular expressions of the configured rules
+func CompileApprovalRules(rules []config.ApprovalRule) (ApprovalRules, error) {
+	compiled := make(ApprovalRules, 0, len(rules))
+	for i, rule := range rules {
+		switch rule.Action {
+		case config.ApprovalApprove, config.ApprovalDeny, config.ApprovalAsk:
+		default:
+			return nil, fmt.Errorf("approval rule %d: unknown action %q", i, rule.Action)
+		}
+		item := approvalRule{tool: rule.Tool, action: rule.Action, reason: rule.Reason}
+		if rule.Args != "" {
+			re, err := regexp.Compile(rule.Args)
+			if err != nil {
+				return nil, fmt.Errorf("approval rule %d: invalid args pattern: %w", i, err)
+			}
+			item.args = re
+		}
+		if len(rule.Params) > 0 {
+			item.params = make(map[string]*regexp.Regexp, len(rule.Params))
+			for param, pattern := range rule.Params {
+				re, err := regexp.Compile(pattern)
+				if err != nil {
+					return nil, fmt.Errorf("approval rule %d: invalid pattern of param %s: %w", i, param, err)
+				}
+				item.params[param] = re
+			}
+		}
+		compiled = append(compiled, item)
+	}
+	return compiled, nil
+}
+
+// Evaluate returns the action and the reason of the first rule matching the call, ask
+// when no rule matches
+func (r ApprovalRules) Evaluate(toolName, argumentsInJSON string) (action string, reason string) {
+	var args map[string]any
+	decoded := false
+	for _, rule := range r {
+		if rule.tool != "" && rule.tool != toolName {
+			continue
+		}
+		if rule.args != nil && !rule.args.MatchString(argumentsInJSON) {
+			continue
+		}
+		if len(rule.params) > 0 {
+			if !decoded {
+				decoded = true
+				if err := json.Unmarshal([]byte(argumentsInJSON), &args); err != nil {
+					args = nil
+				}
+			}
+			if !rule.matchParams(args) {
+				continue
+			}
+		}
+		return rule.action, rule.reason
+	}
+	return config.ApprovalAsk, ""
+}
+
+// matchParams reports whether every param pattern matches its argument. String arguments
+// are matched as is, other values as JSON; missing arguments never match.
+func (rule approvalRule) matchParams(args map[string]any) bool {
+	for param, re := range rule.params {
+		value, ok := args[param]
+		if !ok {
+			return false
+		}
+		text, ok := value.(string)
+		if !ok {
+			data, err := json.Marshal(value)
+			if err != nil {
+				return false
+			}
+			text = string(data)
+		}
+		if !re.MatchString(text) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,124 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestApprovalRulesEvaluate(t *testing.T) {
+	rules, err := CompileApprovalRules([]config.ApprovalRule{
+		{Tool: "cmd", Params: map[string]string{"command": `^git (status|diff|log)\b`}, Action: config.ApprovalApprove},
+		{Tool: "cmd", Params: map[string]string{"command": `^rm\b`}, Action: config.ApprovalDeny, Reason: "no deletions"},
+		{Tool: "write_file", Args: `"path":"/etc/`, Action: config.ApprovalDeny},
+		{Tool: "cmd", Params: map[string]string{"timeout": `^[0-9]{1,2}$`}, Action: config.ApprovalApprove},
+		{Tool: "read_file", Action: config.ApprovalApprove},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		tool       string
+		args       string
+		wantAction string
+		wantReason string
+	}{
+		{"param matches", "cmd", `{"command":"git status"}`, config.ApprovalApprove, ""},
+		{"param matches with options", "cmd", `{"command":"git log --oneline"}`, config.ApprovalApprove, ""},
+		{"param anchored", "cmd", `{"command":"echo git status"}`, config.ApprovalAsk, ""},
+		{"word boundary", "cmd", `{"command":"git statusx"}`, config.ApprovalAsk, ""},
+		{"other command asks", "cmd", `{"command":"git push"}`, config.ApprovalAsk, ""},
+		{"deny with reason", "cmd", `{"command":"rm -rf build"}`, config.ApprovalDeny, "no deletions"},
+		{"first match wins", "cmd", `{"command":"git diff","timeout":5}`, config.ApprovalApprove, ""},
+		{"non string param as JSON", "cmd", `{"command":"make","timeout":30}`, config.ApprovalApprove, ""},
+		{"non string param no match", "cmd", `{"command":"make","timeout":300}`, config.ApprovalAsk, ""},
+		{"missing param", "cmd", `{}`, config.ApprovalAsk, ""},
+		{"invalid JSON", "cmd", `git status`, config.ApprovalAsk, ""},
+		{"args pattern", "write_file", `{"path":"/etc/hosts","content":""}`, config.ApprovalDeny, ""},
+		{"args pattern no match", "write_file", `{"path":"/tmp/x","content":"/etc/"}`, config.ApprovalAsk, ""},
+		{"rule without conditions", "read_file", `{"path":"/etc/passwd"}`, config.ApprovalApprove, ""},
+		{"other tool", "list_directory", `{"path":"/"}`, config.ApprovalAsk, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, reason := rules.Evaluate(tt.tool, tt.args)
+			if action != tt.wantAction || reason != tt.wantReason {
+				t.Fatalf("Evaluate(%s, %s) = %q, %q, want %q, %q", tt.tool, tt.args, action, reason, tt.wantAction, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestApprovalRulesDefaultAsk(t *testing.T) {
+	var rules ApprovalRules
+	if action, _ := rules.Evaluate("cmd", `{"command":"ls"}`); action != config.ApprovalAsk {
+		t.Fatalf("Evaluate() without rules = %q, want ask", action)
+	}
+}
+
+func TestCompileApprovalRulesErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rule config.ApprovalRule
+		want string
+	}{
+		{"unknown action", config.ApprovalRule{Action: "allow"}, `unknown action "allow"`},
+		{"invalid args", config.ApprovalRule{Args: "(", Action: config.ApprovalAsk}, "invalid args pattern"},
+		{"invalid param", config.ApprovalRule{Params: map[string]string{"command": "["}, Action: config.ApprovalAsk}, "invalid pattern of param command"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompileApprovalRules([]config.ApprovalRule{tt.rule})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("CompileApprovalRules() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+type recordingTool struct {
+	calls []string
+}
+
+func (r *recordingTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{Name: "cmd"}, nil
+}
+
+func (r *recordingTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	r.calls = append(r.calls, argumentsInJSON)
+	return "ok", nil
+}
+
+func TestInvokableApprovableToolRules(t *testing.T) {
+	rules, err := CompileApprovalRules([]config.ApprovalRule{
+		{Params: map[string]string{"command": `^git status$`}, Action: config.ApprovalApprove},
+		{Params: map[string]string{"command": `^rm\b`}, Action: config.ApprovalDeny},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := &recordingTool{}
+	approvable := InvokableApprovableTool{InvokableTool: base, Rules: rules}
+
+	out, err := approvable.InvokableRun(context.Background(), `{"command":"git status"}`)
+	if err != nil || out != "ok" {
+		t.Fatalf("approved call = %q, %v, want ok", out, err)
+	}
+	out, err = approvable.InvokableRun(context.Background(), `{"command":"rm -rf /"}`)
+	if err != nil || !strings.Contains(out, "disapproved, reason: denied by an approval rule") {
+		t.Fatalf("denied call = %q, %v", out, err)
+	}
+	if len(base.calls) != 1 {
+		t.Fatalf("tool ran %d times, want only the approved call", len(base.calls))
+	}
+	if _, err := approvable.InvokableRun(context.Background(), `{"command":"ls"}`); err == nil {
+		t.Fatal("call matching no rule ran without asking for approval")
+	}
+}
@@ -2,89 +2,168 @@ package mcp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"slices"
 	"sync"
+	"time"
 
 	"github.com/Arvintian/chat-agent/pkg/config"
+	"github.com/Arvintian/chat-agent/pkg/logger"
 
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/mark3labs/mcp-go/client"
 )
 
+const (
+	// DefaultInitConcurrency is the default number of MCP servers initialized in parallel
+	DefaultInitConcurrency = 4
+	// DefaultInitTimeout is the default time allowed for a single MCP server to initialize
+	DefaultInitTimeout = 10 * time.Second
+)
+
 // Client MCP client structure
 type Client struct {
-	mu            sync.RWMutex
-	clients       map[string]*client.Client
-	tools         map[string]tool.BaseTool
-	config        *config.Config
-	serverMutexes map[string]*sync.Mutex // per-server mutex for NoConcurrent=true servers
+	mu      sync.RWMutex
+	clients map[string]*client.Client
+	tools   map[string]tool.BaseTool
+	config  *config.Config
 }
 
 // NewClient creates a new MCP client
 func NewClient(cfg *config.Config) *Client {
 	return &Client{
-		clients:       make(map[string]*client.Client),
-		tools:         make(map[string]tool.BaseTool),
-		config:        cfg,
-		serverMutexes: make(map[string]*sync.Mutex),
+		clients: make(map[string]*client.Client),
+		tools:   make(map[string]tool.BaseTool),
+		config:  cfg,
 	}
 }
 
 // Initialize initializes the MCP client
 func (c *Client) Initialize(ctx context.Context) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	// Validate configuration
 	if err := ValidateConfig(c.config); err != nil {
 		return NewMCPError("initialize", "", "", fmt.Errorf("configuration validation failed: %w", err))
 	}
 
-	// Create clients for each configured MCP server
-	for serverName, serverConfig := range c.config.MCPServers {
-		client, err := c.createMCPClient(ctx, serverName, serverConfig)
-		if err != nil {
-			return NewMCPError("initialize", serverName, "", fmt.Errorf("failed to create MCP client: %w", err))
-		}
-		c.clients[serverName] = client
-	}
-
-	// Discover and register all tools
-	if err := c.discoverTools(ctx); err != nil {
-		return NewMCPError("initialize", "", "", fmt.Errorf("failed to discover MCP tools: %w", err))
+	serverNames := make([]string, 0, len(c.config.MCPServers))
+	for serverName := range c.config.MCPServers {
+		serverNames = append(serverNames, serverName)
 	}
-
-	return nil
+	return c.initializeServers(ctx, serverNames)
 }
 
+// InitializeForChat initializes the MCP servers referenced by the chat.
+// Servers are initialized concurrently, each with its own timeout. Tools of servers
+// that initialized successfully are registered even if others fail; the failures
+// are returned joined together as *MCPError values, one per server.
 func (c *Client) InitializeForChat(ctx context.Context, chat config.Chat) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	// Validate configuration
 	if err := ValidateConfig(c.config); err != nil {
 		return NewMCPError("initialize", "", "", fmt.Errorf("configuration validation failed: %w", err))
 	}
 
-	// Create clients for each configured MCP server
-	for serverName, serverConfig := range c.config.MCPServers {
-		if !slices.Contains(chat.MCPServers, serverName) {
+	serverNames := make([]string, 0, len(chat.MCPServers))
+	for serverName := range c.config.MCPServers {
+		if slices.Contains(chat.MCPServers, serverName) {
+			serverNames = append(serverNames, serverName)
+		}
+	}
+	return c.initializeServers(ctx, serverNames)
+}
+
+// initResult holds the outcome of initializing a single MCP server
+type initResult struct {
+	serverName string
+	client     *client.Client
+	tools      map[string]tool.BaseTool
+	err        error
+}
+
+// initializeServers initializes the given servers with bounded concurrency and registers
+// the tools of every server that succeeded
+func (c *Client) initializeServers(ctx context.Context, serverNames []string) error {
+	concurrency := c.config.MCPInitConcurrency
+	if concurrency <= 0 {
+		concurrency = DefaultInitConcurrency
+	}
+	timeout := DefaultInitTimeout
+	if c.config.MCPInitTimeout > 0 {
+		timeout = time.Duration(c.config.MCPInitTimeout) * time.Second
+	}
+
+	sem := make(chan struct{}, concurrency)
+	results := make(chan initResult, len(serverNames))
+	for _, serverName := range serverNames {
+		go func(serverName string) {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results <- c.initializeServer(ctx, serverName, c.config.MCPServers[serverName], timeout)
+		}(serverName)
+	}
+
+	var errs []error
+	for range serverNames {
+		result := <-results
+		if result.err != nil {
+			logger.Warn("mcp", fmt.Sprintf("MCP server %s failed to initialize: %v", result.serverName, result.err))
+			errs = append(errs, result.err)
 			continue
 		}
-		client, err := c.createMCPClient(ctx, serverName, serverConfig)
-		if err != nil {
-			return NewMCPError("initialize", serverName, "", fmt.Errorf("failed to create MCP client: %w", err))
+		c.mu.Lock()
+		c.clients[result.serverName] = result.client
+		for name, t := range result.tools {
+			c.tools[name] = t
 		}
-		c.clients[serverName] = client
+		c.mu.Unlock()
+		logger.Info("mcp", fmt.Sprintf("MCP server %s initialized with %d tools", result.serverName, len(result.tools)))
 	}
 
-	// Discover and register all tools
-	if err := c.discoverTools(ctx); err != nil {
-		return NewMCPError("initialize", "", "", fmt.Errorf("failed to discover MCP tools: %w", err))
+	return errors.Join(errs...)
+}
+
+// initializeServer connects to a single MCP server and discovers its tools.
+// The connection itself keeps using ctx, since transports such as SSE hold a
+// long-lived stream bound to it; only the initialization handshake is bounded by timeout.
+func (c *Client) initializeServer(ctx context.Context, serverName string, serverConfig config.MCPServer, timeout time.Duration) initResult {
+	result := initResult{serverName: serverName}
+	if err := ValidateCommandAllowed(c.config, serverName, serverConfig); err != nil {
+		result.err = err
+		return result
 	}
 
-	return nil
+	done := make(chan initResult, 1)
+	initCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	go func() {
+		r := initResult{serverName: serverName}
+		r.client, r.err = c.createMCPClient(ctx, serverName, serverConfig)
+		if r.err != nil {
+			r.err = NewMCPError("initialize", serverName, "", fmt.Errorf("failed to create MCP client: %w", r.err))
+			done <- r
+			return
+		}
+		r.tools, r.err = c.discoverServerTools(initCtx, serverName, serverConfig, r.client)
+		if r.err != nil {
+			r.client.Close()
+			r.err = NewMCPError("initialize", serverName, "", fmt.Errorf("failed to discover MCP tools: %w", r.err))
+		}
+		done <- r
+	}()
+
+	select {
+	case r := <-done:
+		return r
+	case <-initCtx.Done():
+		// Release the client once the pending initialization returns
+		go func() {
+			if r := <-done; r.client != nil && r.err == nil {
+				r.client.Close()
+			}
+		}()
+		result.err = NewMCPError("initialize", serverName, "", fmt.Errorf("initialization timed out after %v: %w", timeout, initCtx.Err()))
+		return result
+	}
 }
 
 // GetTools gets all available MCP tools
@@ -0,0 +1,71 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+
+	mcpProtocol "github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestInitializeForChatPartialFailure(t *testing.T) {
+	mcpServer := server.NewMCPServer("healthy", "1.0.0")
+	mcpServer.AddTool(mcpProtocol.NewTool("echo"), func(ctx context.Context, req mcpProtocol.CallToolRequest) (*mcpProtocol.CallToolResult, error) {
+		return mcpProtocol.NewToolResultText("ok"), nil
+	})
+	healthy := server.NewTestStreamableHTTPServer(mcpServer)
+	defer healthy.Close()
+
+	// A server that accepts the connection but never answers the handshake
+	release := make(chan struct{})
+	hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer hanging.Close()
+	defer close(release)
+
+	cfg := &config.Config{
+		MCPServers: map[string]config.MCPServer{
+			"healthy": {Type: "streamable-http", URL: healthy.URL + "/mcp"},
+			"hanging": {Type: "streamable-http", URL: hanging.URL + "/mcp"},
+			"blocked": {Type: "stdio", Cmd: "sh"},
+		},
+		MCPAllowedCommands: []string{"not-sh"},
+		MCPInitTimeout:     1,
+	}
+	c := NewClient(cfg)
+	defer c.Close()
+
+	start := time.Now()
+	err := c.InitializeForChat(context.Background(), config.Chat{MCPServers: []string{"healthy", "hanging", "blocked"}})
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("InitializeForChat() took %v, want the hanging server to time out after ~1s", elapsed)
+	}
+	if err == nil {
+		t.Fatal("InitializeForChat() error = nil, want the failures of hanging and blocked")
+	}
+	if !errors.Is(err, ErrCommandNotAllowed) {
+		t.Errorf("expected ErrCommandNotAllowed for the blocked server, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected a timeout error for the hanging server, got %v", err)
+	}
+
+	tools := c.GetTools()
+	if _, ok := tools["healthy_echo"]; !ok {
+		t.Errorf("tools of the healthy server were not registered, got %v", tools)
+	}
+	if len(tools) != 1 {
+		t.Errorf("got %d tools, want 1", len(tools))
+	}
+}
@@ -105,6 +105,49 @@ func validateCommand(serverName, command string) error {
 	return nil
 }
 
+// ValidateCommandAllowed checks the server command against the configured
+// mcpAllowedCommands. An entry matches when it equals the configured command,
+// or when both resolve to the same executable path. An empty allowlist allows all commands.
+func ValidateCommandAllowed(cfg *config.Config, serverName string, serverConfig config.MCPServer) error {
+	if cfg == nil || len(cfg.MCPAllowedCommands) == 0 || serverConfig.Cmd == "" {
+		return nil
+	}
+
+	parts := strings.Fields(serverConfig.Cmd)
+	if len(parts) == 0 {
+		return NewMCPError("validate", serverName, "", fmt.Errorf("command cannot be empty"))
+	}
+	cmdPath := resolveCommandPath(parts[0])
+
+	for _, allowed := range cfg.MCPAllowedCommands {
+		allowed = strings.TrimSpace(allowed)
+		if allowed == "" {
+			continue
+		}
+		if allowed == parts[0] || resolveCommandPath(allowed) == cmdPath {
+			return nil
+		}
+	}
+
+	return NewMCPError("validate", serverName, "",
+		fmt.Errorf("%w: %s is not listed in mcpAllowedCommands", ErrCommandNotAllowed, parts[0]))
+}
+
+// resolveCommandPath resolves a command to an absolute, symlink-free path when possible
+func resolveCommandPath(command string) string {
+	resolved := command
+	if p, err := exec.LookPath(command); err == nil {
+		resolved = p
+	}
+	if abs, err := filepath.Abs(resolved); err == nil {
+		resolved = abs
+	}
+	if real, err := filepath.EvalSymlinks(resolved); err == nil {
+		resolved = real
+	}
+	return resolved
+}
+
 // validateURL validates URL format
 func validateURL(serverName, url string) error {
 	// Simple URL format validation
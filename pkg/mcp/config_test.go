@@ -0,0 +1,36 @@
+package mcp
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+)
+
+func TestValidateCommandAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		cmd     string
+		wantErr bool
+	}{
+		{"empty allowlist allows all", nil, "sh", false},
+		{"exact name match", []string{"sh"}, "sh", false},
+		{"resolved path match", []string{"sh"}, "/bin/sh", false},
+		{"not listed", []string{"sh"}, "go", true},
+		{"url server ignored", []string{"sh"}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{MCPAllowedCommands: tt.allowed}
+			err := ValidateCommandAllowed(cfg, "test", config.MCPServer{Cmd: tt.cmd})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateCommandAllowed() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrCommandNotAllowed) {
+				t.Errorf("expected ErrCommandNotAllowed, got %v", err)
+			}
+		})
+	}
+}
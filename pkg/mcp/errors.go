@@ -21,6 +21,9 @@ var (
 
 	// ErrConnectionFailed MCP connection failed
 	ErrConnectionFailed = errors.New("MCP connection failed")
+
+	// ErrCommandNotAllowed MCP server command is not in the allowlist
+	ErrCommandNotAllowed = errors.New("MCP server command not allowed")
 )
 
 // MCPError MCP error wrapper
@@ -4,11 +4,13 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"net/http"
 	"net/url"
 	"time"
 
 	"github.com/Arvintian/chat-agent/pkg/config"
 	"github.com/Arvintian/chat-agent/pkg/logger"
+	"github.com/Arvintian/chat-agent/pkg/utils"
 
 	"github.com/mark3labs/mcp-go/client"
 	"github.com/mark3labs/mcp-go/client/transport"
@@ -80,8 +82,13 @@ func (c *Client) createStreamableHTTPClient(ctx context.Context, serverConfig co
 		return nil, fmt.Errorf("StreamableHTTP type MCP server must specify URL")
 	}
 
-	// Prepare client options
-	var options []transport.StreamableHTTPCOption
+	httpClient, err := newHTTPClient(serverConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prepare client options, the timeout is set on the HTTP client so it goes last
+	options := []transport.StreamableHTTPCOption{transport.WithHTTPBasicClient(httpClient)}
 
 	// Add request headers
 	if len(serverConfig.Headers) > 0 {
@@ -115,8 +122,13 @@ func (c *Client) createSSEClient(ctx context.Context, serverConfig config.MCPSer
 		return nil, fmt.Errorf("invalid SSE server URL: %w", err)
 	}
 
+	httpClient, err := newHTTPClient(serverConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	// Prepare client options
-	var options []transport.ClientOption
+	options := []transport.ClientOption{transport.WithHTTPClient(httpClient)}
 	if len(serverConfig.Headers) > 0 {
 		options = append(options, transport.WithHeaders(serverConfig.Headers))
 	}
@@ -133,3 +145,13 @@ func (c *Client) createSSEClient(ctx context.Context, serverConfig config.MCPSer
 
 	return mcpClient, nil
 }
+
+// newHTTPClient creates the HTTP client of an MCP server, sending the requests through
+// the proxy of the server or the environment's
+func newHTTPClient(serverConfig config.MCPServer) (*http.Client, error) {
+	transport, err := utils.NewProxyTransport(serverConfig.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("MCP server %s: %w", serverConfig.URL, err)
+	}
+	return &http.Client{Transport: transport}, nil
+}
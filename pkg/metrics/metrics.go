@@ -0,0 +1,108 @@
+// Package metrics defines the Prometheus metrics of the serve mode. They are recorded
+// whether or not they are exposed; Register adds them to the default registry, which
+// is served on /metrics when the server runs with --metrics.
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "chat_agent"
+
+// Results of a chat request
+const (
+	ResultOK      = "ok"
+	ResultError   = "error"
+	ResultTimeout = "timeout"
+	ResultStopped = "stopped"
+)
+
+// Results of a tool call submitted for approval
+const (
+	ApprovalApproved  = "approved"
+	ApprovalDenied    = "denied"
+	ApprovalTimeout   = "timeout"
+	ApprovalCancelled = "cancelled"
+	ApprovalShutdown  = "shutdown"
+	ApprovalRejected  = "rejected"
+)
+
+var (
+	chatRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "chat_requests_total",
+		Help:      "Chat requests by result: ok, error, timeout or stopped.",
+	}, []string{"result"})
+	chatDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "chat_request_duration_seconds",
+		Help:      "Duration of the chat requests, including the time waiting for approvals.",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"result"})
+	toolCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tool_calls_total",
+		Help:      "Tool calls that returned a result, by tool name.",
+	}, []string{"tool"})
+	tokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tokens_total",
+		Help:      "Tokens reported by the providers, by type: prompt or completion.",
+	}, []string{"type"})
+	approvalRequests = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "approval_requests_total",
+		Help:      "Tool calls submitted to the user for approval.",
+	})
+	approvalResults = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "approval_results_total",
+		Help:      "Tool calls submitted for approval by result: approved, denied, timeout, cancelled, shutdown or rejected.",
+	}, []string{"result"})
+	modelErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "model_errors_total",
+		Help:      "Chat requests that failed with an error of the model or the agent.",
+	})
+)
+
+// Register adds the metrics and the given collectors, e.g. the session gauges of the
+// server, to the default Prometheus registry
+func Register(collectors ...prometheus.Collector) {
+	prometheus.MustRegister(chatRequests, chatDuration, toolCalls, tokens, approvalRequests, approvalResults, modelErrors)
+	prometheus.MustRegister(collectors...)
+}
+
+// ObserveChatRequest records a chat request that ended with result after d
+func ObserveChatRequest(result string, d time.Duration) {
+	chatRequests.WithLabelValues(result).Inc()
+	chatDuration.WithLabelValues(result).Observe(d.Seconds())
+}
+
+// ObserveToolCall records a tool call that returned a result
+func ObserveToolCall(tool string) {
+	toolCalls.WithLabelValues(tool).Inc()
+}
+
+// ObserveTokens records the tokens of a response
+func ObserveTokens(prompt, completion int) {
+	tokens.WithLabelValues("prompt").Add(float64(max(prompt, 0)))
+	tokens.WithLabelValues("completion").Add(float64(max(completion, 0)))
+}
+
+// ObserveApprovalRequest records n tool calls submitted for approval
+func ObserveApprovalRequest(n int) {
+	approvalRequests.Add(float64(n))
+}
+
+// ObserveApprovalResult records the result of a tool call submitted for approval
+func ObserveApprovalResult(result string) {
+	approvalResults.WithLabelValues(result).Inc()
+}
+
+// ObserveModelError records a chat request failed by the model or the agent
+func ObserveModelError() {
+	modelErrors.Inc()
+}
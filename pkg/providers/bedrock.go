@@ -0,0 +1,247 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// bedrockSigningName is the service name of Bedrock runtime requests in their SigV4 signature
+const bedrockSigningName = "bedrock"
+
+// bedrockMaxErrorBody is the maximum number of bytes read from an error response
+const bedrockMaxErrorBody = 64 * 1024
+
+// createBedrockModel creates a model served by AWS Bedrock through the Converse API,
+// which speaks the same protocol for the Claude, Llama, Mistral and Nova models. The
+// credentials are resolved by the AWS credential chain: the environment, the profile
+// of the provider or the default one in ~/.aws, then the container or instance role.
+func (f *Factory) createBedrockModel(ctx context.Context, modelCfg *config.Model, providerCfg *config.Provider) (model.ToolCallingChatModel, error) {
+	var opts []func(*awsconfig.LoadOptions) error
+	if providerCfg.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(providerCfg.Region))
+	}
+	if providerCfg.Profile != "" {
+		opts = append(opts, awsconfig.WithSharedConfigProfile(providerCfg.Profile))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the AWS configuration of the bedrock provider: %w", err)
+	}
+	if awsCfg.Region == "" {
+		return nil, fmt.Errorf("bedrock provider requires region, the AWS region, e.g. us-east-1 (or set AWS_REGION)")
+	}
+	if awsCfg.Credentials == nil {
+		return nil, fmt.Errorf("no AWS credentials for the bedrock provider: set profile, or configure the AWS credential chain (AWS_ACCESS_KEY_ID, ~/.aws/credentials or an instance role)")
+	}
+
+	endpoint := providerCfg.BaseURL
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", awsCfg.Region)
+	}
+	client, err := newProviderClient(providerCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	cm := &BedrockChatModel{
+		client:      client,
+		endpoint:    strings.TrimSuffix(endpoint, "/"),
+		region:      awsCfg.Region,
+		credentials: awsCfg.Credentials,
+		signer:      v4.NewSigner(),
+		model:       modelCfg.Model,
+		extra:       modelCfg.ExtraBody,
+	}
+	if modelCfg.MaxTokens > 0 {
+		cm.maxTokens = &modelCfg.MaxTokens
+	}
+	if modelCfg.Temperature > 0 {
+		temp := float32(modelCfg.Temperature)
+		cm.temperature = &temp
+	}
+	if modelCfg.TopP > 0 {
+		topP := float32(modelCfg.TopP)
+		cm.topP = &topP
+	}
+	cm.stop = modelCfg.Stop
+	ignoreParams(modelCfg, paramSeed, paramFrequencyPenalty, paramPresencePenalty)
+	// Report responses stopped by the content filter or a guardrail instead of an empty answer
+	return NewContentFilterChatModel(cm), nil
+}
+
+// BedrockChatModel calls a model of AWS Bedrock with the Converse API, signing the
+// requests with SigV4. extra is sent as the additional model request fields, e.g. the
+// thinking configuration of Claude.
+type BedrockChatModel struct {
+	client      *http.Client
+	endpoint    string
+	region      string
+	credentials aws.CredentialsProvider
+	signer      *v4.Signer
+
+	model       string
+	maxTokens   *int
+	temperature *float32
+	topP        *float32
+	stop        []string
+	extra       map[string]any
+	tools       []*schema.ToolInfo
+}
+
+// Generate implements BaseChatModel
+func (m *BedrockChatModel) Generate(ctx context.Context, messages []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	resp, err := m.do(ctx, "converse", messages, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var result converseResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse the bedrock response: %w", err)
+	}
+	return converseResponseMessage(&result), nil
+}
+
+// Stream implements BaseChatModel
+func (m *BedrockChatModel) Stream(ctx context.Context, messages []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	resp, err := m.do(ctx, "converse-stream", messages, opts)
+	if err != nil {
+		return nil, err
+	}
+	reader, writer := schema.Pipe[*schema.Message](16)
+	go func() {
+		defer resp.Body.Close()
+		defer writer.Close()
+		decoder := eventstream.NewDecoder()
+		var payload []byte
+		for {
+			event, err := decoder.Decode(resp.Body, payload)
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					writer.Send(nil, fmt.Errorf("failed to read the bedrock stream: %w", err))
+				}
+				return
+			}
+			payload = event.Payload[:0]
+			if messageType := headerString(event.Headers, ":message-type"); messageType != "event" {
+				writer.Send(nil, bedrockStreamError(event))
+				return
+			}
+			chunk, err := converseStreamChunk(headerString(event.Headers, ":event-type"), event.Payload)
+			if err == nil && chunk == nil {
+				continue
+			}
+			if closed := writer.Send(chunk, err); closed || err != nil {
+				return
+			}
+		}
+	}()
+	return reader, nil
+}
+
+// WithTools returns a new BedrockChatModel with the tools bound
+func (m *BedrockChatModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	withTools := *m
+	withTools.tools = tools
+	return &withTools, nil
+}
+
+// do sends a signed Converse request of the action, converse or converse-stream
+func (m *BedrockChatModel) do(ctx context.Context, action string, messages []*schema.Message, opts []model.Option) (*http.Response, error) {
+	options := model.GetCommonOptions(&model.Options{
+		Model:       &m.model,
+		MaxTokens:   m.maxTokens,
+		Temperature: m.temperature,
+		TopP:        m.topP,
+		Stop:        m.stop,
+		Tools:       m.tools,
+	}, opts...)
+	request, err := buildConverseRequest(messages, options.Tools, options, m.extra)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the bedrock request: %w", err)
+	}
+
+	target := fmt.Sprintf("%s/model/%s/%s", m.endpoint, escapeModelID(*options.Model), action)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if action == "converse-stream" {
+		req.Header.Set("Accept", "application/vnd.amazon.eventstream")
+	}
+	creds, err := m.credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the AWS credentials of the bedrock provider: %w", err)
+	}
+	hash := sha256.Sum256(body)
+	if err := m.signer.SignHTTP(ctx, creds, req, hex.EncodeToString(hash[:]), bedrockSigningName, m.region, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to sign the bedrock request: %w", err)
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, bedrockMaxErrorBody))
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		message := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+			message = apiErr.Message
+		}
+		return nil, fmt.Errorf("bedrock error, status code: %d, type: %s, message: %s",
+			resp.StatusCode, resp.Header.Get("X-Amzn-ErrorType"), message)
+	}
+	return resp, nil
+}
+
+// headerString returns the string value of an event stream header, empty when missing
+func headerString(headers eventstream.Headers, name string) string {
+	value := headers.Get(name)
+	if value == nil {
+		return ""
+	}
+	return value.String()
+}
+
+// bedrockStreamError returns the error of an exception event of the stream
+func bedrockStreamError(event eventstream.Message) error {
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	message := strings.TrimSpace(string(event.Payload))
+	if json.Unmarshal(event.Payload, &apiErr) == nil && apiErr.Message != "" {
+		message = apiErr.Message
+	}
+	kind := headerString(event.Headers, ":exception-type")
+	if kind == "" {
+		kind = headerString(event.Headers, ":error-code")
+	}
+	return fmt.Errorf("bedrock stream error, type: %s, message: %s", kind, message)
+}
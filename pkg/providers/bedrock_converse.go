@@ -0,0 +1,426 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// bedrockSignatureKey is the Extra key of an assistant message holding the signature of
+// its reasoning, which Bedrock requires to get the reasoning back in later requests
+const bedrockSignatureKey = "bedrock_reasoning_signature"
+
+// Bedrock Converse API messages, see
+// https://docs.aws.amazon.com/bedrock/latest/APIReference/API_runtime_Converse.html
+
+type converseRequest struct {
+	Messages                     []converseMessage        `json:"messages"`
+	System                       []converseContent        `json:"system,omitempty"`
+	InferenceConfig              *converseInferenceConfig `json:"inferenceConfig,omitempty"`
+	ToolConfig                   *converseToolConfig      `json:"toolConfig,omitempty"`
+	AdditionalModelRequestFields map[string]any           `json:"additionalModelRequestFields,omitempty"`
+}
+
+type converseMessage struct {
+	Role    string            `json:"role"`
+	Content []converseContent `json:"content"`
+}
+
+// converseContent is a content block, exactly one of its fields is set
+type converseContent struct {
+	Text             string              `json:"text,omitempty"`
+	Image            *converseImage      `json:"image,omitempty"`
+	Document         *converseDocument   `json:"document,omitempty"`
+	ToolUse          *converseToolUse    `json:"toolUse,omitempty"`
+	ToolResult       *converseToolResult `json:"toolResult,omitempty"`
+	ReasoningContent *converseReasoning  `json:"reasoningContent,omitempty"`
+}
+
+type converseSource struct {
+	Bytes string `json:"bytes"` // base64 encoded
+}
+
+type converseImage struct {
+	Format string         `json:"format"`
+	Source converseSource `json:"source"`
+}
+
+type converseDocument struct {
+	Format string         `json:"format"`
+	Name   string         `json:"name"`
+	Source converseSource `json:"source"`
+}
+
+type converseToolUse struct {
+	ToolUseID string          `json:"toolUseId"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input,omitempty"`
+}
+
+type converseToolResult struct {
+	ToolUseID string            `json:"toolUseId"`
+	Content   []converseContent `json:"content"`
+}
+
+type converseReasoning struct {
+	ReasoningText *converseReasoningText `json:"reasoningText,omitempty"`
+}
+
+type converseReasoningText struct {
+	Text      string `json:"text"`
+	Signature string `json:"signature,omitempty"`
+}
+
+type converseInferenceConfig struct {
+	MaxTokens     *int     `json:"maxTokens,omitempty"`
+	Temperature   *float32 `json:"temperature,omitempty"`
+	TopP          *float32 `json:"topP,omitempty"`
+	StopSequences []string `json:"stopSequences,omitempty"`
+}
+
+type converseToolConfig struct {
+	Tools      []converseTool `json:"tools"`
+	ToolChoice map[string]any `json:"toolChoice,omitempty"`
+}
+
+type converseTool struct {
+	ToolSpec converseToolSpec `json:"toolSpec"`
+}
+
+type converseToolSpec struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	InputSchema struct {
+		JSON any `json:"json"`
+	} `json:"inputSchema"`
+}
+
+type converseUsage struct {
+	InputTokens          int `json:"inputTokens"`
+	OutputTokens         int `json:"outputTokens"`
+	TotalTokens          int `json:"totalTokens"`
+	CacheReadInputTokens int `json:"cacheReadInputTokens"`
+}
+
+type converseResponse struct {
+	Output struct {
+		Message converseMessage `json:"message"`
+	} `json:"output"`
+	StopReason string        `json:"stopReason"`
+	Usage      converseUsage `json:"usage"`
+}
+
+// converseStreamEvent holds the fields of the ConverseStream events
+type converseStreamEvent struct {
+	ContentBlockIndex int `json:"contentBlockIndex"`
+	Start             *struct {
+		ToolUse *converseToolUse `json:"toolUse"`
+	} `json:"start"`
+	Delta *struct {
+		Text    *string `json:"text"`
+		ToolUse *struct {
+			Input string `json:"input"`
+		} `json:"toolUse"`
+		ReasoningContent *struct {
+			Text      string `json:"text"`
+			Signature string `json:"signature"`
+		} `json:"reasoningContent"`
+	} `json:"delta"`
+	StopReason string         `json:"stopReason"`
+	Usage      *converseUsage `json:"usage"`
+}
+
+// bedrockDocumentFormats maps the MIME types of the documents Bedrock reads to their format
+var bedrockDocumentFormats = map[string]string{
+	"application/pdf":          "pdf",
+	"text/csv":                 "csv",
+	"application/msword":       "doc",
+	"application/vnd.ms-excel": "xls",
+	"text/html":                "html",
+	"text/plain":               "txt",
+	"text/markdown":            "md",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": "docx",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":       "xlsx",
+}
+
+// buildConverseRequest converts the messages, tools and options of a call to a Converse
+// request. Consecutive messages of the same Converse role are merged, since the API wants
+// the user and assistant turns to alternate, and tool results are sent as user content.
+func buildConverseRequest(messages []*schema.Message, tools []*schema.ToolInfo, options *model.Options, extra map[string]any) (*converseRequest, error) {
+	req := &converseRequest{AdditionalModelRequestFields: extra}
+	for _, msg := range messages {
+		if msg.Role == schema.System {
+			if msg.Content != "" {
+				req.System = append(req.System, converseContent{Text: msg.Content})
+			}
+			continue
+		}
+		role, content, err := converseContentOf(msg)
+		if err != nil {
+			return nil, err
+		}
+		if len(content) == 0 {
+			continue
+		}
+		if last := len(req.Messages) - 1; last >= 0 && req.Messages[last].Role == role {
+			req.Messages[last].Content = append(req.Messages[last].Content, content...)
+			continue
+		}
+		req.Messages = append(req.Messages, converseMessage{Role: role, Content: content})
+	}
+
+	if options.MaxTokens != nil || options.Temperature != nil || options.TopP != nil || len(options.Stop) > 0 {
+		req.InferenceConfig = &converseInferenceConfig{
+			MaxTokens:     options.MaxTokens,
+			Temperature:   options.Temperature,
+			TopP:          options.TopP,
+			StopSequences: options.Stop,
+		}
+	}
+
+	// Bedrock rejects tool calls in the history when no tool is configured, so the tools
+	// are sent even when the model may not call them
+	if len(tools) > 0 {
+		req.ToolConfig = &converseToolConfig{}
+		for _, info := range tools {
+			params, err := info.ParamsOneOf.ToJSONSchema()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get the parameters of tool %s: %w", info.Name, err)
+			}
+			spec := converseToolSpec{Name: info.Name, Description: info.Desc}
+			spec.InputSchema.JSON = params
+			if params == nil {
+				spec.InputSchema.JSON = map[string]any{"type": "object", "properties": map[string]any{}}
+			}
+			req.ToolConfig.Tools = append(req.ToolConfig.Tools, converseTool{ToolSpec: spec})
+		}
+		if options.ToolChoice != nil && *options.ToolChoice == schema.ToolChoiceForced {
+			req.ToolConfig.ToolChoice = map[string]any{"any": map[string]any{}}
+		}
+	}
+	return req, nil
+}
+
+// converseContentOf returns the Converse role and content blocks of a message
+func converseContentOf(msg *schema.Message) (string, []converseContent, error) {
+	var content []converseContent
+	switch msg.Role {
+	case schema.Tool:
+		result := converseToolResult{ToolUseID: msg.ToolCallID, Content: []converseContent{{Text: msg.Content}}}
+		if msg.Content == "" {
+			// Empty text blocks are rejected
+			result.Content = []converseContent{{Text: "(no output)"}}
+		}
+		return "user", []converseContent{{ToolResult: &result}}, nil
+
+	case schema.Assistant:
+		if signature, _ := msg.Extra[bedrockSignatureKey].(string); signature != "" && msg.ReasoningContent != "" {
+			content = append(content, converseContent{ReasoningContent: &converseReasoning{
+				ReasoningText: &converseReasoningText{Text: msg.ReasoningContent, Signature: signature},
+			}})
+		}
+		if msg.Content != "" {
+			content = append(content, converseContent{Text: msg.Content})
+		}
+		for _, call := range msg.ToolCalls {
+			input := json.RawMessage(call.Function.Arguments)
+			if strings.TrimSpace(call.Function.Arguments) == "" {
+				input = json.RawMessage("{}")
+			}
+			content = append(content, converseContent{ToolUse: &converseToolUse{
+				ToolUseID: call.ID,
+				Name:      call.Function.Name,
+				Input:     input,
+			}})
+		}
+		return "assistant", content, nil
+	}
+
+	if msg.Content != "" {
+		content = append(content, converseContent{Text: msg.Content})
+	}
+	for _, part := range msg.UserInputMultiContent {
+		block, err := converseInputPart(part)
+		if err != nil {
+			return "", nil, err
+		}
+		if block != nil {
+			content = append(content, *block)
+		}
+	}
+	return "user", content, nil
+}
+
+// converseInputPart converts a part of a user message. Files must be inline: Bedrock
+// doesn't fetch URLs.
+func converseInputPart(part schema.MessageInputPart) (*converseContent, error) {
+	switch part.Type {
+	case schema.ChatMessagePartTypeText:
+		if part.Text == "" {
+			return nil, nil
+		}
+		return &converseContent{Text: part.Text}, nil
+	case schema.ChatMessagePartTypeImageURL:
+		if part.Image == nil || part.Image.Base64Data == nil {
+			return nil, fmt.Errorf("bedrock only accepts images sent as data, not as a URL")
+		}
+		format := strings.TrimPrefix(part.Image.MIMEType, "image/")
+		if format == "jpg" {
+			format = "jpeg"
+		}
+		return &converseContent{Image: &converseImage{Format: format, Source: converseSource{Bytes: *part.Image.Base64Data}}}, nil
+	case schema.ChatMessagePartTypeFileURL:
+		if part.File == nil || part.File.Base64Data == nil {
+			return nil, fmt.Errorf("bedrock only accepts files sent as data, not as a URL")
+		}
+		format, ok := bedrockDocumentFormats[part.File.MIMEType]
+		if !ok {
+			return nil, fmt.Errorf("bedrock does not accept %s files", part.File.MIMEType)
+		}
+		return &converseContent{Document: &converseDocument{
+			Format: format,
+			Name:   bedrockDocumentName(part.File.Name),
+			Source: converseSource{Bytes: *part.File.Base64Data},
+		}}, nil
+	}
+	return nil, fmt.Errorf("bedrock does not accept %s content", part.Type)
+}
+
+// bedrockDocumentName returns a document name Bedrock accepts: letters, digits, single
+// spaces, hyphens, parentheses and square brackets, without the extension
+func bedrockDocumentName(name string) string {
+	if i := strings.LastIndex(name, "."); i > 0 {
+		name = name[:i]
+	}
+	var sb strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("-()[]", r):
+			sb.WriteRune(r)
+		case !strings.HasSuffix(sb.String(), " "):
+			sb.WriteRune(' ')
+		}
+	}
+	if result := strings.TrimSpace(sb.String()); result != "" {
+		return result
+	}
+	return "document"
+}
+
+// escapeModelID escapes a model ID or ARN for the request path, as the AWS SDK does:
+// every byte but the unreserved characters is percent-encoded
+func escapeModelID(id string) string {
+	var sb strings.Builder
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("-_.~", c) >= 0 {
+			sb.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&sb, "%%%02X", c)
+	}
+	return sb.String()
+}
+
+// converseResponseMessage converts the response of a Converse request
+func converseResponseMessage(resp *converseResponse) *schema.Message {
+	msg := &schema.Message{
+		Role: schema.Assistant,
+		ResponseMeta: &schema.ResponseMeta{
+			FinishReason: converseFinishReason(resp.StopReason),
+			Usage:        resp.Usage.tokenUsage(),
+		},
+	}
+	var text strings.Builder
+	for _, block := range resp.Output.Message.Content {
+		switch {
+		case block.ToolUse != nil:
+			msg.ToolCalls = append(msg.ToolCalls, schema.ToolCall{
+				ID:       block.ToolUse.ToolUseID,
+				Type:     "function",
+				Function: schema.FunctionCall{Name: block.ToolUse.Name, Arguments: string(block.ToolUse.Input)},
+			})
+		case block.ReasoningContent != nil && block.ReasoningContent.ReasoningText != nil:
+			msg.ReasoningContent += block.ReasoningContent.ReasoningText.Text
+			if signature := block.ReasoningContent.ReasoningText.Signature; signature != "" {
+				msg.Extra = map[string]any{bedrockSignatureKey: signature}
+			}
+		default:
+			text.WriteString(block.Text)
+		}
+	}
+	msg.Content = text.String()
+	return msg
+}
+
+// converseStreamChunk converts a ConverseStream event to a message chunk, nil for the
+// events without content
+func converseStreamChunk(eventType string, payload []byte) (*schema.Message, error) {
+	var event converseStreamEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to parse the bedrock %s event: %w", eventType, err)
+	}
+	chunk := &schema.Message{Role: schema.Assistant}
+	index := event.ContentBlockIndex
+	switch eventType {
+	case "contentBlockStart":
+		if event.Start == nil || event.Start.ToolUse == nil {
+			return nil, nil
+		}
+		chunk.ToolCalls = []schema.ToolCall{{
+			Index:    &index,
+			ID:       event.Start.ToolUse.ToolUseID,
+			Type:     "function",
+			Function: schema.FunctionCall{Name: event.Start.ToolUse.Name},
+		}}
+	case "contentBlockDelta":
+		switch delta := event.Delta; {
+		case delta == nil:
+			return nil, nil
+		case delta.Text != nil:
+			chunk.Content = *delta.Text
+		case delta.ToolUse != nil:
+			chunk.ToolCalls = []schema.ToolCall{{Index: &index, Function: schema.FunctionCall{Arguments: delta.ToolUse.Input}}}
+		case delta.ReasoningContent != nil:
+			chunk.ReasoningContent = delta.ReasoningContent.Text
+			if delta.ReasoningContent.Signature != "" {
+				chunk.Extra = map[string]any{bedrockSignatureKey: delta.ReasoningContent.Signature}
+			}
+		default:
+			return nil, nil
+		}
+	case "messageStop":
+		chunk.ResponseMeta = &schema.ResponseMeta{FinishReason: converseFinishReason(event.StopReason)}
+	case "metadata":
+		if event.Usage == nil {
+			return nil, nil
+		}
+		chunk.ResponseMeta = &schema.ResponseMeta{Usage: event.Usage.tokenUsage()}
+	default:
+		return nil, nil
+	}
+	return chunk, nil
+}
+
+// converseFinishReason maps the stop reasons of blocked responses to the content_filter
+// finish reason, the others are kept
+func converseFinishReason(stopReason string) string {
+	switch stopReason {
+	case "content_filtered", "guardrail_intervened":
+		return finishReasonContentFilter
+	}
+	return stopReason
+}
+
+func (u converseUsage) tokenUsage() *schema.TokenUsage {
+	usage := &schema.TokenUsage{
+		PromptTokens:     u.InputTokens,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+	usage.PromptTokenDetails.CachedTokens = u.CacheReadInputTokens
+	return usage
+}
@@ -0,0 +1,161 @@
+package providers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestBuildConverseRequest(t *testing.T) {
+	image := "aW1hZ2U="
+	user := schema.UserMessage("what is in the picture?")
+	user.UserInputMultiContent = []schema.MessageInputPart{{
+		Type:  schema.ChatMessagePartTypeImageURL,
+		Image: &schema.MessageInputImage{MessagePartCommon: schema.MessagePartCommon{MIMEType: "image/jpg", Base64Data: &image}},
+	}}
+	assistant := schema.AssistantMessage("", []schema.ToolCall{
+		{ID: "call-1", Function: schema.FunctionCall{Name: "read_file", Arguments: `{"path":"a.txt"}`}},
+		{ID: "call-2", Function: schema.FunctionCall{Name: "list_files"}},
+	})
+	assistant.ReasoningContent = "I should look"
+	assistant.Extra = map[string]any{bedrockSignatureKey: "sig"}
+	messages := []*schema.Message{
+		schema.SystemMessage("be brief"),
+		user,
+		assistant,
+		schema.ToolMessage("hello", "call-1"),
+		schema.ToolMessage("", "call-2"),
+		schema.UserMessage("thanks"),
+	}
+	tools := []*schema.ToolInfo{{
+		Name: "read_file",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"path": {Type: schema.String, Required: true},
+		}),
+	}, {Name: "list_files"}}
+	maxTokens := 256
+	forced := schema.ToolChoiceForced
+
+	req, err := buildConverseRequest(messages, tools, &model.Options{MaxTokens: &maxTokens, ToolChoice: &forced}, map[string]any{"top_k": 5})
+	if err != nil {
+		t.Fatalf("buildConverseRequest() error = %v", err)
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	body := string(data)
+	for _, want := range []string{
+		`"system":[{"text":"be brief"}]`,
+		`{"role":"user","content":[{"text":"what is in the picture?"},{"image":{"format":"jpeg","source":{"bytes":"aW1hZ2U="}}}]}`,
+		`{"role":"assistant","content":[{"reasoningContent":{"reasoningText":{"text":"I should look","signature":"sig"}}},` +
+			`{"toolUse":{"toolUseId":"call-1","name":"read_file","input":{"path":"a.txt"}}},{"toolUse":{"toolUseId":"call-2","name":"list_files","input":{}}}]}`,
+		`{"role":"user","content":[{"toolResult":{"toolUseId":"call-1","content":[{"text":"hello"}]}},` +
+			`{"toolResult":{"toolUseId":"call-2","content":[{"text":"(no output)"}]}},{"text":"thanks"}]}`,
+		`"inferenceConfig":{"maxTokens":256}`,
+		`"inputSchema":{"json":{"properties":{},"type":"object"}}`,
+		`"toolChoice":{"any":{}}`,
+		`"additionalModelRequestFields":{"top_k":5}`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("request = %s\nwant it to contain %s", body, want)
+		}
+	}
+	if len(req.Messages) != 3 {
+		t.Errorf("request has %d messages, want the tool results merged with the next user message", len(req.Messages))
+	}
+
+	url := "https://example.com/a.png"
+	remote := schema.UserMessage("")
+	remote.UserInputMultiContent = []schema.MessageInputPart{{
+		Type:  schema.ChatMessagePartTypeImageURL,
+		Image: &schema.MessageInputImage{MessagePartCommon: schema.MessagePartCommon{URL: &url}},
+	}}
+	if _, err := buildConverseRequest([]*schema.Message{remote}, nil, &model.Options{}, nil); err == nil {
+		t.Error("buildConverseRequest() error = nil for an image URL, want an error")
+	}
+}
+
+func TestConverseResponseMessage(t *testing.T) {
+	var resp converseResponse
+	err := json.Unmarshal([]byte(`{
+		"output": {"message": {"role": "assistant", "content": [
+			{"reasoningContent": {"reasoningText": {"text": "thinking", "signature": "sig"}}},
+			{"text": "Reading it."},
+			{"toolUse": {"toolUseId": "call-1", "name": "read_file", "input": {"path": "a.txt"}}}
+		]}},
+		"stopReason": "tool_use",
+		"usage": {"inputTokens": 10, "outputTokens": 5, "totalTokens": 15, "cacheReadInputTokens": 4}
+	}`), &resp)
+	if err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	msg := converseResponseMessage(&resp)
+	if msg.Content != "Reading it." || msg.ReasoningContent != "thinking" || msg.Extra[bedrockSignatureKey] != "sig" {
+		t.Errorf("message = %+v, want the text, the reasoning and its signature", msg)
+	}
+	if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].ID != "call-1" || msg.ToolCalls[0].Function.Arguments != `{"path": "a.txt"}` {
+		t.Errorf("tool calls = %+v, want the read_file call", msg.ToolCalls)
+	}
+	usage := msg.ResponseMeta.Usage
+	if usage.PromptTokens != 10 || usage.CompletionTokens != 5 || usage.TotalTokens != 15 || usage.PromptTokenDetails.CachedTokens != 4 {
+		t.Errorf("usage = %+v", usage)
+	}
+
+	resp.StopReason = "guardrail_intervened"
+	if msg := converseResponseMessage(&resp); !contentFiltered(msg) {
+		t.Error("a response stopped by a guardrail is not reported as filtered")
+	}
+}
+
+func TestConverseStreamChunks(t *testing.T) {
+	events := []struct {
+		kind    string
+		payload string
+	}{
+		{"messageStart", `{"role":"assistant"}`},
+		{"contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":"Let me "}}`},
+		{"contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":"check."}}`},
+		{"contentBlockStop", `{"contentBlockIndex":0}`},
+		{"contentBlockStart", `{"contentBlockIndex":1,"start":{"toolUse":{"toolUseId":"call-1","name":"read_file"}}}`},
+		{"contentBlockDelta", `{"contentBlockIndex":1,"delta":{"toolUse":{"input":"{\"path\":"}}}`},
+		{"contentBlockDelta", `{"contentBlockIndex":1,"delta":{"toolUse":{"input":"\"a.txt\"}"}}}`},
+		{"messageStop", `{"stopReason":"tool_use"}`},
+		{"metadata", `{"usage":{"inputTokens":3,"outputTokens":2,"totalTokens":5}}`},
+	}
+	var chunks []*schema.Message
+	for _, event := range events {
+		chunk, err := converseStreamChunk(event.kind, []byte(event.payload))
+		if err != nil {
+			t.Fatalf("converseStreamChunk(%s) error = %v", event.kind, err)
+		}
+		if chunk != nil {
+			chunks = append(chunks, chunk)
+		}
+	}
+	msg, err := schema.ConcatMessages(chunks)
+	if err != nil {
+		t.Fatalf("ConcatMessages() error = %v", err)
+	}
+	if msg.Content != "Let me check." {
+		t.Errorf("content = %q", msg.Content)
+	}
+	if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].ID != "call-1" || msg.ToolCalls[0].Function.Name != "read_file" ||
+		msg.ToolCalls[0].Function.Arguments != `{"path":"a.txt"}` {
+		t.Errorf("tool calls = %+v, want the streamed read_file call", msg.ToolCalls)
+	}
+	if msg.ResponseMeta.FinishReason != "tool_use" || msg.ResponseMeta.Usage.TotalTokens != 5 {
+		t.Errorf("response meta = %+v", msg.ResponseMeta)
+	}
+}
+
+func TestEscapeModelID(t *testing.T) {
+	got := escapeModelID("arn:aws:bedrock:us-east-1::foundation-model/meta.llama3-70b-instruct-v1:0")
+	want := "arn%3Aaws%3Abedrock%3Aus-east-1%3A%3Afoundation-model%2Fmeta.llama3-70b-instruct-v1%3A0"
+	if got != want {
+		t.Errorf("escapeModelID() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,99 @@
+package providers
+
+import (
+	"sort"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+)
+
+// Capabilities are what the API of a provider type supports. Whether a given model
+// supports them too depends on the model, e.g. not every model of an OpenAI-compatible
+// API reads images.
+type Capabilities struct {
+	// Thinking: the thinking setting of the models is sent to the provider
+	Thinking bool
+	// Images: messages may carry images
+	Images bool
+	// Proxy: the requests go through the proxy of the provider, the other provider
+	// types use the proxy of the environment
+	Proxy bool
+}
+
+// providerCapabilities are the capabilities of the supported provider types. Bedrock
+// models only think when their thinking configuration is set in extraBody.
+var providerCapabilities = map[string]Capabilities{
+	"openai":     {Thinking: true, Images: true, Proxy: true},
+	"claude":     {Thinking: true, Images: true},
+	"gemini":     {Thinking: true, Images: true},
+	"vertex":     {Thinking: true, Images: true},
+	"qwen":       {Thinking: true, Images: true},
+	"qianfan":    {Thinking: true, Images: true},
+	"ark":        {Thinking: true, Images: true},
+	"deepseek":   {Thinking: true},
+	"ollama":     {Thinking: true, Images: true},
+	"openrouter": {Thinking: true, Images: true, Proxy: true},
+	"bedrock":    {Images: true, Proxy: true},
+}
+
+// ProviderCapabilities returns the capabilities of a provider type, none for unknown types
+func ProviderCapabilities(providerType string) Capabilities {
+	return providerCapabilities[providerType]
+}
+
+// MixedProviderType is the provider type reported for mixed models
+const MixedProviderType = "mixed"
+
+// ModelInfo describes a configured model and what it can do
+type ModelInfo struct {
+	Name string `json:"name"`
+	// Provider is the name of the provider in the configuration, empty for mixed models
+	Provider     string `json:"provider,omitempty"`
+	ProviderType string `json:"provider_type"`
+	Model        string `json:"model,omitempty"`
+	// Thinking is set when thinking is enabled and the provider supports it
+	Thinking bool `json:"thinking"`
+	// Multimodal is set when the model reads attached documents itself
+	Multimodal bool `json:"multimodal"`
+	// Images is set when images can be attached to the messages of the model
+	Images bool `json:"images"`
+}
+
+// DescribeModels describes the models of the configuration, sorted by name. A mixed
+// model only has the capabilities all its models share.
+func DescribeModels(cfg *config.Config) []ModelInfo {
+	models := make([]ModelInfo, 0, len(cfg.Models))
+	for name, modelCfg := range cfg.Models {
+		if len(modelCfg.Mixed) == 0 {
+			info := describeModel(cfg, modelCfg.ModelParams)
+			info.Name = name
+			models = append(models, info)
+			continue
+		}
+		info := ModelInfo{Name: name, ProviderType: MixedProviderType, Thinking: true, Multimodal: true, Images: true}
+		for _, entry := range modelCfg.Mixed {
+			sub := describeModel(cfg, entry.ModelParams)
+			info.Thinking = info.Thinking && sub.Thinking
+			info.Multimodal = info.Multimodal && sub.Multimodal
+			info.Images = info.Images && sub.Images
+		}
+		models = append(models, info)
+	}
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].Name < models[j].Name
+	})
+	return models
+}
+
+// describeModel describes a single model, without its name
+func describeModel(cfg *config.Config, params config.ModelParams) ModelInfo {
+	providerType := cfg.Providers[params.Provider].Type
+	capabilities := ProviderCapabilities(providerType)
+	return ModelInfo{
+		Provider:     params.Provider,
+		ProviderType: providerType,
+		Model:        params.Model,
+		Thinking:     params.Thinking && capabilities.Thinking,
+		Multimodal:   params.Multimodal,
+		Images:       capabilities.Images,
+	}
+}
@@ -0,0 +1,41 @@
+package providers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+)
+
+func TestProviderCapabilitiesCoverProviderTypes(t *testing.T) {
+	for _, providerType := range ProviderTypes {
+		if _, ok := providerCapabilities[providerType]; !ok {
+			t.Errorf("provider type %s has no capabilities", providerType)
+		}
+	}
+}
+
+func TestDescribeModels(t *testing.T) {
+	cfg := &config.Config{
+		Providers: map[string]config.Provider{
+			"anthropic": {Type: "claude"},
+			"ds":        {Type: "deepseek"},
+		},
+		Models: map[string]config.Model{
+			"sonnet":   {ModelParams: config.ModelParams{Provider: "anthropic", Model: "claude-sonnet", Thinking: true, Multimodal: true}},
+			"reasoner": {ModelParams: config.ModelParams{Provider: "ds", Model: "deepseek-reasoner", Thinking: true}},
+			"mixed": {Mixed: []config.MixedModel{
+				{ModelParams: config.ModelParams{Provider: "anthropic", Model: "claude-sonnet", Thinking: true}},
+				{ModelParams: config.ModelParams{Provider: "ds", Model: "deepseek-chat"}},
+			}},
+		},
+	}
+	want := []ModelInfo{
+		{Name: "mixed", ProviderType: MixedProviderType},
+		{Name: "reasoner", Provider: "ds", ProviderType: "deepseek", Model: "deepseek-reasoner", Thinking: true},
+		{Name: "sonnet", Provider: "anthropic", ProviderType: "claude", Model: "claude-sonnet", Thinking: true, Multimodal: true, Images: true},
+	}
+	if got := DescribeModels(cfg); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("DescribeModels() = %+v, want %+v", got, want)
+	}
+}
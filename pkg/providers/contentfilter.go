@@ -0,0 +1,67 @@
+package providers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// ErrContentFiltered is returned when the provider blocked the response with its content filter
+var ErrContentFiltered = errors.New("the response was blocked by the provider's content filter")
+
+// finishReasonContentFilter is the OpenAI-compatible finish reason of a filtered response
+const finishReasonContentFilter = "content_filter"
+
+// ContentFilterChatModel reports responses that finish with the content_filter finish
+// reason as ErrContentFiltered. Without it a blocked response looks like an empty answer.
+type ContentFilterChatModel struct {
+	model model.ToolCallingChatModel
+}
+
+// NewContentFilterChatModel wraps a model to detect responses blocked by the provider
+func NewContentFilterChatModel(m model.ToolCallingChatModel) *ContentFilterChatModel {
+	return &ContentFilterChatModel{model: m}
+}
+
+// Generate implements BaseChatModel
+func (m *ContentFilterChatModel) Generate(ctx context.Context, messages []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	msg, err := m.model.Generate(ctx, messages, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if contentFiltered(msg) {
+		return nil, ErrContentFiltered
+	}
+	return msg, nil
+}
+
+// Stream implements BaseChatModel. Chunks received before the filter triggered are
+// passed through, the chunk carrying the finish reason is replaced by the error.
+func (m *ContentFilterChatModel) Stream(ctx context.Context, messages []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	stream, err := m.model.Stream(ctx, messages, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return schema.StreamReaderWithConvert(stream, func(msg *schema.Message) (*schema.Message, error) {
+		if contentFiltered(msg) {
+			return nil, ErrContentFiltered
+		}
+		return msg, nil
+	}), nil
+}
+
+// WithTools returns a new ContentFilterChatModel wrapping the model with the tools bound
+func (m *ContentFilterChatModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	withTools, err := m.model.WithTools(tools)
+	if err != nil {
+		return nil, err
+	}
+	return NewContentFilterChatModel(withTools), nil
+}
+
+// contentFiltered reports whether the message finished because of the content filter
+func contentFiltered(msg *schema.Message) bool {
+	return msg != nil && msg.ResponseMeta != nil && msg.ResponseMeta.FinishReason == finishReasonContentFilter
+}
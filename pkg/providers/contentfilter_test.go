@@ -0,0 +1,75 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// finishModel returns its messages as the response, the last one with the finish reason
+type finishModel struct {
+	chunks       []string
+	finishReason string
+}
+
+func (m *finishModel) messages() []*schema.Message {
+	msgs := make([]*schema.Message, len(m.chunks))
+	for i, chunk := range m.chunks {
+		msgs[i] = schema.AssistantMessage(chunk, nil)
+	}
+	msgs[len(msgs)-1].ResponseMeta = &schema.ResponseMeta{FinishReason: m.finishReason}
+	return msgs
+}
+
+func (m *finishModel) Generate(ctx context.Context, messages []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	return schema.ConcatMessages(m.messages())
+}
+
+func (m *finishModel) Stream(ctx context.Context, messages []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return schema.StreamReaderFromArray(m.messages()), nil
+}
+
+func (m *finishModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+func TestContentFilterGenerate(t *testing.T) {
+	ctx := context.Background()
+	filtered := NewContentFilterChatModel(&finishModel{chunks: []string{""}, finishReason: "content_filter"})
+	if _, err := filtered.Generate(ctx, nil); !errors.Is(err, ErrContentFiltered) {
+		t.Errorf("Generate() error = %v, want ErrContentFiltered", err)
+	}
+
+	ok := NewContentFilterChatModel(&finishModel{chunks: []string{"hello"}, finishReason: "stop"})
+	msg, err := ok.Generate(ctx, nil)
+	if err != nil || msg.Content != "hello" {
+		t.Errorf("Generate() = %v, %v, want the response", msg, err)
+	}
+}
+
+func TestContentFilterStream(t *testing.T) {
+	m, err := NewContentFilterChatModel(&finishModel{chunks: []string{"partial", ""}, finishReason: "content_filter"}).WithTools(nil)
+	if err != nil {
+		t.Fatalf("WithTools() error = %v", err)
+	}
+	stream, err := m.Stream(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	defer stream.Close()
+
+	first, err := stream.Recv()
+	if err != nil || first.Content != "partial" {
+		t.Fatalf("first Recv() = %v, %v, want the partial chunk", first, err)
+	}
+	if _, err := stream.Recv(); !errors.Is(err, ErrContentFiltered) {
+		t.Errorf("second Recv() error = %v, want ErrContentFiltered", err)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("third Recv() error = %v, want io.EOF", err)
+	}
+}
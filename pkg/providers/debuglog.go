@@ -0,0 +1,115 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sync/atomic"
+
+	"github.com/Arvintian/chat-agent/pkg/chatbot/middleware"
+	"github.com/Arvintian/chat-agent/pkg/config"
+	"github.com/Arvintian/chat-agent/pkg/logger"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// authorizationPattern matches the value of Authorization headers, which providers may
+// echo in error messages
+const authorizationPattern = `(?i)\b(?:proxy-)?authorization["']?\s*[:=]\s*["']?(?P<secret>[^"'\r\n,}]+)`
+
+var debugLog atomic.Bool
+
+// SetDebugLog enables logging the requests and responses of the models created after
+// the call, e.g. when the --debug flag is set
+func SetDebugLog(enabled bool) {
+	debugLog.Store(enabled)
+}
+
+// DebugLogChatModel logs the messages sent to a model and its responses through the
+// logger, with API keys and Authorization headers redacted. Streamed responses are
+// logged chunk by chunk as they are received.
+type DebugLogChatModel struct {
+	model    model.ToolCallingChatModel
+	name     string
+	tools    []string
+	redactor *middleware.Redactor
+}
+
+// NewDebugLogChatModel wraps a model to log its traffic under name, redacting the
+// secrets matched by redactor
+func NewDebugLogChatModel(m model.ToolCallingChatModel, name string, redactor *middleware.Redactor) *DebugLogChatModel {
+	return &DebugLogChatModel{model: m, name: name, redactor: redactor}
+}
+
+// newProviderRedactor returns a redactor for the default secret patterns, Authorization
+// headers and the literal API key and header values of the provider
+func newProviderRedactor(providerCfg *config.Provider) (*middleware.Redactor, error) {
+	patterns := append([]string{authorizationPattern}, middleware.DefaultRedactionPatterns...)
+	if providerCfg.APIKey != "" {
+		patterns = append(patterns, regexp.QuoteMeta(providerCfg.APIKey))
+	}
+	for _, value := range providerCfg.Headers {
+		if value != "" {
+			patterns = append(patterns, regexp.QuoteMeta(value))
+		}
+	}
+	return middleware.NewRedactor(patterns, "")
+}
+
+// Generate implements BaseChatModel
+func (m *DebugLogChatModel) Generate(ctx context.Context, messages []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	m.logRequest("generate", messages)
+	msg, err := m.model.Generate(ctx, messages, opts...)
+	if err != nil {
+		m.log("error: %s", m.redactor.Redact(err.Error()))
+		return nil, err
+	}
+	m.log("response: %s", m.encode(msg))
+	return msg, nil
+}
+
+// Stream implements BaseChatModel
+func (m *DebugLogChatModel) Stream(ctx context.Context, messages []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	m.logRequest("stream", messages)
+	stream, err := m.model.Stream(ctx, messages, opts...)
+	if err != nil {
+		m.log("error: %s", m.redactor.Redact(err.Error()))
+		return nil, err
+	}
+	return schema.StreamReaderWithConvert(stream, func(msg *schema.Message) (*schema.Message, error) {
+		m.log("chunk: %s", m.encode(msg))
+		return msg, nil
+	}), nil
+}
+
+// WithTools returns a new DebugLogChatModel wrapping the model with the tools bound
+func (m *DebugLogChatModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	withTools, err := m.model.WithTools(tools)
+	if err != nil {
+		return nil, err
+	}
+	wrapped := NewDebugLogChatModel(withTools, m.name, m.redactor)
+	for _, info := range tools {
+		wrapped.tools = append(wrapped.tools, info.Name)
+	}
+	return wrapped, nil
+}
+
+func (m *DebugLogChatModel) logRequest(method string, messages []*schema.Message) {
+	m.log("%s request with %d messages, tools %v: %s", method, len(messages), m.tools, m.encode(messages))
+}
+
+func (m *DebugLogChatModel) log(format string, args ...any) {
+	logger.Debug("provider", fmt.Sprintf("[%s] ", m.name)+fmt.Sprintf(format, args...))
+}
+
+// encode returns v as JSON with the secrets redacted
+func (m *DebugLogChatModel) encode(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("<failed to encode: %v>", err)
+	}
+	return m.redactor.Redact(string(data))
+}
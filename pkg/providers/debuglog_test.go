@@ -0,0 +1,59 @@
+package providers
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestProviderRedactorMasksCredentials(t *testing.T) {
+	redactor, err := newProviderRedactor(&config.Provider{
+		APIKey:  "plain-key-123",
+		Headers: map[string]string{"X-Api-Token": "header-secret-456"},
+	})
+	if err != nil {
+		t.Fatalf("newProviderRedactor() error = %v", err)
+	}
+	got := redactor.Redact(`key plain-key-123, header header-secret-456, {"Authorization":"Basic dXNlcjpwYXNz"}`)
+	for _, secret := range []string{"plain-key-123", "header-secret-456", "dXNlcjpwYXNz"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("Redact() = %q, contains %q", got, secret)
+		}
+	}
+}
+
+func TestDebugLogChatModelPassesThrough(t *testing.T) {
+	redactor, err := newProviderRedactor(&config.Provider{})
+	if err != nil {
+		t.Fatalf("newProviderRedactor() error = %v", err)
+	}
+	inner := &recordModel{}
+	m := NewDebugLogChatModel(inner, "test/model", redactor)
+	messages := []*schema.Message{schema.UserMessage("hello")}
+
+	msg, err := m.Generate(context.Background(), messages)
+	if err != nil || msg.Content != "ok" {
+		t.Fatalf("Generate() = %v, %v, want ok", msg, err)
+	}
+	if len(inner.messages) != 1 {
+		t.Errorf("the model got %d messages, want 1", len(inner.messages))
+	}
+
+	stream, err := m.Stream(context.Background(), messages)
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	defer stream.Close()
+	chunk, err := stream.Recv()
+	if err != nil || chunk.Content != "ok" {
+		t.Fatalf("Recv() = %v, %v, want ok", chunk, err)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("Recv() error = %v, want io.EOF", err)
+	}
+}
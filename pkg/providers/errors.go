@@ -0,0 +1,67 @@
+package providers
+
+import (
+	"context"
+	"errors"
+
+	chaterrors "github.com/Arvintian/chat-agent/pkg/errors"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// ClassifiedErrorChatModel puts the errors of a model in the provider classes of
+// pkg/errors: ErrProviderUnavailable when the provider can't serve the request at the
+// moment, ErrProviderRejected otherwise. Cancelled calls and responses blocked by the
+// content filter are returned as is.
+type ClassifiedErrorChatModel struct {
+	model model.ToolCallingChatModel
+}
+
+// NewClassifiedErrorChatModel wraps a model to classify its errors
+func NewClassifiedErrorChatModel(m model.ToolCallingChatModel) *ClassifiedErrorChatModel {
+	return &ClassifiedErrorChatModel{model: m}
+}
+
+// Generate implements BaseChatModel
+func (m *ClassifiedErrorChatModel) Generate(ctx context.Context, messages []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	msg, err := m.model.Generate(ctx, messages, opts...)
+	if err != nil {
+		return nil, classifyError(ctx, err)
+	}
+	return msg, nil
+}
+
+// Stream implements BaseChatModel, errors received with the chunks are classified too
+func (m *ClassifiedErrorChatModel) Stream(ctx context.Context, messages []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	stream, err := m.model.Stream(ctx, messages, opts...)
+	if err != nil {
+		return nil, classifyError(ctx, err)
+	}
+	return schema.StreamReaderWithConvert(stream, func(msg *schema.Message) (*schema.Message, error) {
+		return msg, nil
+	}, schema.WithErrWrapper(func(err error) error {
+		return classifyError(ctx, err)
+	})), nil
+}
+
+// WithTools returns a new ClassifiedErrorChatModel wrapping the model with the tools bound
+func (m *ClassifiedErrorChatModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	withTools, err := m.model.WithTools(tools)
+	if err != nil {
+		return nil, err
+	}
+	return NewClassifiedErrorChatModel(withTools), nil
+}
+
+// classifyError puts an error of the provider in its class
+func classifyError(ctx context.Context, err error) error {
+	switch {
+	case ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, ErrContentFiltered):
+		return err
+	case isUnavailable(ctx, err):
+		return chaterrors.Wrap(chaterrors.ErrProviderUnavailable, err)
+	default:
+		return chaterrors.Wrap(chaterrors.ErrProviderRejected, err)
+	}
+}
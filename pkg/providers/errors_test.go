@@ -0,0 +1,73 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	chaterrors "github.com/Arvintian/chat-agent/pkg/errors"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// streamModel streams the chunks of its stream
+type streamModel struct {
+	stream *schema.StreamReader[*schema.Message]
+}
+
+func (m *streamModel) Generate(ctx context.Context, messages []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	return schema.ConcatMessageStream(m.stream)
+}
+
+func (m *streamModel) Stream(ctx context.Context, messages []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return m.stream, nil
+}
+
+func (m *streamModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+func TestClassifiedErrorChatModel(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"unavailable", errors.New("error, status code: 503, message: overloaded"), chaterrors.CodeProviderUnavailable},
+		{"rejected", errors.New("error, status code: 400, message: context too long"), chaterrors.CodeProviderRejected},
+		{"content filtered", ErrContentFiltered, chaterrors.CodeInternal},
+		{"cancelled", context.Canceled, chaterrors.CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewClassifiedErrorChatModel(&failingModel{err: tt.err})
+			_, err := m.Generate(context.Background(), nil)
+			if got := chaterrors.Code(err); got != tt.want || !errors.Is(err, tt.err) {
+				t.Errorf("Generate() error = %v with code %q, want %q", err, got, tt.want)
+			}
+			if _, err := m.Stream(context.Background(), nil); chaterrors.Code(err) != tt.want {
+				t.Errorf("Stream() error = %v, want code %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifiedErrorChatModelStreamChunkError(t *testing.T) {
+	sr, sw := schema.Pipe[*schema.Message](2)
+	sw.Send(schema.AssistantMessage("partial", nil), nil)
+	sw.Send(nil, errors.New("read tcp: connection reset by peer"))
+	sw.Close()
+
+	reader, err := NewClassifiedErrorChatModel(&streamModel{stream: sr}).Stream(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	defer reader.Close()
+	if msg, err := reader.Recv(); err != nil || msg.Content != "partial" {
+		t.Fatalf("Recv() = %v, %v, want the first chunk", msg, err)
+	}
+	if _, err := reader.Recv(); !errors.Is(err, chaterrors.ErrProviderUnavailable) {
+		t.Errorf("Recv() error = %v, want ErrProviderUnavailable", err)
+	}
+}
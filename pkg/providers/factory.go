@@ -3,15 +3,23 @@ package providers
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/Arvintian/chat-agent/pkg/config"
+	"github.com/Arvintian/chat-agent/pkg/logger"
 
 	"github.com/cloudwego/eino/components/model"
 )
 
+// ProviderTypes are the supported values of a provider's type
+var ProviderTypes = []string{"openai", "claude", "gemini", "vertex", "qwen", "qianfan", "ark", "deepseek", "ollama", "openrouter", "bedrock"}
+
 // Factory is used to create ChatModel for different providers
 type Factory struct {
 	cfg *config.Config
+	// thinking overrides the thinking setting of the models when set
+	thinking *bool
 }
 
 // NewFactory creates a new Factory
@@ -19,6 +27,12 @@ func NewFactory(cfg *config.Config) *Factory {
 	return &Factory{cfg: cfg}
 }
 
+// WithThinking returns a factory creating the models with thinking turned on or off,
+// whatever their config says
+func (f *Factory) WithThinking(enabled bool) *Factory {
+	return &Factory{cfg: f.cfg, thinking: &enabled}
+}
+
 // CreateChatModel creates corresponding ChatModel based on model name
 func (f *Factory) CreateChatModel(ctx context.Context, modelName string) (model.ToolCallingChatModel, error) {
 	// Get model configuration
@@ -41,6 +55,32 @@ func (f *Factory) CreateChatModel(ctx context.Context, modelName string) (model.
 	return f.createSingleModel(ctx, &modelCfg, &providerCfg)
 }
 
+// CreateChatModelWithFallbacks creates the ChatModel of modelName, falling back to the
+// fallbacks models in order when its provider is unavailable
+func (f *Factory) CreateChatModelWithFallbacks(ctx context.Context, modelName string, fallbacks []string) (model.ToolCallingChatModel, error) {
+	cm, err := f.CreateChatModel(ctx, modelName)
+	if err != nil || len(fallbacks) == 0 {
+		return cm, err
+	}
+	names := []string{modelName}
+	models := []model.ToolCallingChatModel{cm}
+	for _, name := range fallbacks {
+		if slices.Contains(names, name) {
+			continue
+		}
+		fallback, err := f.CreateChatModel(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("fallback model %s: %w", name, err)
+		}
+		names = append(names, name)
+		models = append(models, fallback)
+	}
+	if len(models) == 1 {
+		return cm, nil
+	}
+	return NewFallbackChatModel(names, models), nil
+}
+
 // createMixedModel creates a MixedChatModel that round-robins across all
 // sub-models defined in the model's Mixed configuration.
 func (f *Factory) createMixedModel(ctx context.Context, modelCfg *config.Model) (model.ToolCallingChatModel, error) {
@@ -74,8 +114,28 @@ func (f *Factory) createMixedModel(ctx context.Context, modelCfg *config.Model)
 	return NewMixedChatModel(models, weights), nil
 }
 
-// createSingleModel creates a ChatModel for a single provider configuration.
+// createSingleModel creates a ChatModel for a single provider configuration, logging
+// its traffic when debug logging is enabled. Its errors are classified.
 func (f *Factory) createSingleModel(ctx context.Context, modelCfg *config.Model, providerCfg *config.Provider) (model.ToolCallingChatModel, error) {
+	if providerCfg.Proxy != "" && !ProviderCapabilities(providerCfg.Type).Proxy {
+		logger.Warn("providers", fmt.Sprintf("The %s provider doesn't support proxy, model %s uses the proxy of the environment", providerCfg.Type, modelCfg.Model))
+	}
+	cm, err := f.createProviderModel(ctx, f.withThinking(withProviderDefaults(modelCfg, providerCfg.Defaults)), providerCfg)
+	if err != nil {
+		return nil, err
+	}
+	if debugLog.Load() {
+		redactor, err := newProviderRedactor(providerCfg)
+		if err != nil {
+			return nil, err
+		}
+		cm = NewDebugLogChatModel(cm, providerCfg.Type+"/"+modelCfg.Model, redactor)
+	}
+	return NewClassifiedErrorChatModel(cm), nil
+}
+
+// createProviderModel creates the ChatModel of the provider type
+func (f *Factory) createProviderModel(ctx context.Context, modelCfg *config.Model, providerCfg *config.Provider) (model.ToolCallingChatModel, error) {
 	switch providerCfg.Type {
 	case "openai":
 		return f.createOpenAIModel(ctx, modelCfg, providerCfg)
@@ -83,6 +143,8 @@ func (f *Factory) createSingleModel(ctx context.Context, modelCfg *config.Model,
 		return f.createClaudeModel(ctx, modelCfg, providerCfg)
 	case "gemini":
 		return f.createGeminiModel(ctx, modelCfg, providerCfg)
+	case "vertex":
+		return f.createVertexModel(ctx, modelCfg, providerCfg)
 	case "qwen":
 		return f.createQwenModel(ctx, modelCfg, providerCfg)
 	case "qianfan":
@@ -95,7 +157,62 @@ func (f *Factory) createSingleModel(ctx context.Context, modelCfg *config.Model,
 		return f.createOllamaModel(ctx, modelCfg, providerCfg)
 	case "openrouter":
 		return f.createOpenRouterModel(ctx, modelCfg, providerCfg)
+	case "bedrock":
+		return f.createBedrockModel(ctx, modelCfg, providerCfg)
+	case "":
+		return nil, fmt.Errorf("provider type is not set, supported types: %s", strings.Join(ProviderTypes, ", "))
 	default:
-		return nil, fmt.Errorf("unsupported provider type: %s", providerCfg.Type)
+		return nil, fmt.Errorf("unsupported provider type %q, supported types: %s", providerCfg.Type, strings.Join(ProviderTypes, ", "))
+	}
+}
+
+// withThinking returns a copy of the model configuration with the thinking override of
+// the factory applied. Turning thinking off also drops the reasoning effort, which some
+// providers honor on its own.
+func (f *Factory) withThinking(modelCfg *config.Model) *config.Model {
+	if f.thinking == nil {
+		return modelCfg
+	}
+	overridden := *modelCfg
+	overridden.Thinking = *f.thinking
+	if !overridden.Thinking {
+		overridden.ReasoningEffort = nil
+	}
+	return &overridden
+}
+
+// withProviderDefaults returns a copy of the model configuration with the unset
+// sampling parameters taken from the provider defaults. ExtraBody is merged key by
+// key, with the model's keys taking precedence.
+func withProviderDefaults(modelCfg *config.Model, defaults *config.ModelDefaults) *config.Model {
+	if defaults == nil {
+		return modelCfg
+	}
+	merged := *modelCfg
+	if merged.ReasoningEffort == nil {
+		merged.ReasoningEffort = defaults.ReasoningEffort
+	}
+	if merged.MaxTokens == 0 {
+		merged.MaxTokens = defaults.MaxTokens
+	}
+	if merged.Temperature == 0 {
+		merged.Temperature = defaults.Temperature
+	}
+	if merged.TopP == 0 {
+		merged.TopP = defaults.TopP
+	}
+	if merged.TopK == 0 {
+		merged.TopK = defaults.TopK
+	}
+	if len(defaults.ExtraBody) > 0 {
+		extraBody := make(map[string]any, len(defaults.ExtraBody)+len(modelCfg.ExtraBody))
+		for k, v := range defaults.ExtraBody {
+			extraBody[k] = v
+		}
+		for k, v := range modelCfg.ExtraBody {
+			extraBody[k] = v
+		}
+		merged.ExtraBody = extraBody
 	}
+	return &merged
 }
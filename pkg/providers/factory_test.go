@@ -0,0 +1,82 @@
+package providers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+)
+
+func TestWithProviderDefaults(t *testing.T) {
+	effort := "high"
+	defaults := &config.ModelDefaults{
+		ReasoningEffort: &effort,
+		MaxTokens:       1024,
+		Temperature:     0.7,
+		TopP:            0.9,
+		ExtraBody:       map[string]any{"a": 1, "b": 1},
+	}
+	modelCfg := &config.Model{ModelParams: config.ModelParams{
+		Model:       "m",
+		Temperature: 0.2,
+		ExtraBody:   map[string]any{"b": 2},
+	}}
+
+	got := withProviderDefaults(modelCfg, defaults)
+	if got.Temperature != 0.2 {
+		t.Errorf("Temperature = %v, want the model's 0.2", got.Temperature)
+	}
+	if got.TopP != 0.9 || got.MaxTokens != 1024 {
+		t.Errorf("TopP, MaxTokens = %v, %v, want the provider defaults", got.TopP, got.MaxTokens)
+	}
+	if got.ReasoningEffort == nil || *got.ReasoningEffort != "high" {
+		t.Errorf("ReasoningEffort = %v, want %q", got.ReasoningEffort, "high")
+	}
+	if got.ExtraBody["a"] != 1 || got.ExtraBody["b"] != 2 {
+		t.Errorf("ExtraBody = %v, want defaults merged with the model's keys winning", got.ExtraBody)
+	}
+	// The configured model is left untouched
+	if modelCfg.TopP != 0 || len(modelCfg.ExtraBody) != 1 {
+		t.Errorf("model config was modified: %+v", modelCfg.ModelParams)
+	}
+
+	if withProviderDefaults(modelCfg, nil) != modelCfg {
+		t.Error("withProviderDefaults() without defaults should return the model config as is")
+	}
+}
+
+func TestFactoryWithThinking(t *testing.T) {
+	effort := "high"
+	modelCfg := &config.Model{ModelParams: config.ModelParams{Model: "m", ReasoningEffort: &effort}}
+	f := NewFactory(&config.Config{})
+	if f.withThinking(modelCfg) != modelCfg {
+		t.Error("withThinking() without an override should return the model config as is")
+	}
+
+	if got := f.WithThinking(true).withThinking(modelCfg); !got.Thinking || got.ReasoningEffort != &effort {
+		t.Errorf("thinking on: Thinking, ReasoningEffort = %v, %v, want thinking with the configured effort", got.Thinking, got.ReasoningEffort)
+	}
+	if got := f.WithThinking(false).withThinking(modelCfg); got.Thinking || got.ReasoningEffort != nil {
+		t.Errorf("thinking off: Thinking, ReasoningEffort = %v, %v, want no thinking and no effort", got.Thinking, got.ReasoningEffort)
+	}
+	if modelCfg.Thinking || modelCfg.ReasoningEffort == nil {
+		t.Errorf("model config was modified: %+v", modelCfg.ModelParams)
+	}
+}
+
+func TestCreateChatModelRejectsUnknownProviderType(t *testing.T) {
+	f := NewFactory(&config.Config{
+		Providers: map[string]config.Provider{"local": {Type: "opneai"}},
+		Models:    map[string]config.Model{"gpt": {ModelParams: config.ModelParams{Provider: "local", Model: "gpt-4o"}}},
+	})
+	_, err := f.CreateChatModel(context.Background(), "gpt")
+	if err == nil {
+		t.Fatal("CreateChatModel() error = nil for an unknown provider type")
+	}
+	for _, want := range []string{`"opneai"`, strings.Join(ProviderTypes, ", ")} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("CreateChatModel() error = %v, want it to contain %s", err, want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Arvintian/chat-agent/pkg/logger"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// FallbackReporter is told when a model call falls back to the next model of the chain
+type FallbackReporter interface {
+	ReportModelFallback(from, to string, err error)
+}
+
+type fallbackReporterKey struct{}
+
+// WithFallbackReporter returns a context in which the model calls report their fallbacks
+// to reporter
+func WithFallbackReporter(ctx context.Context, reporter FallbackReporter) context.Context {
+	return context.WithValue(ctx, fallbackReporterKey{}, reporter)
+}
+
+// FallbackChatModel calls its models in order: when a model fails because its provider
+// is unavailable, the call is made again with the next one. Errors caused by the request
+// itself, such as invalid tool arguments or a context too long, are returned right away
+// since every model would reject the request the same way. A stream only falls back
+// when it fails to start, chunks already received can't be taken back.
+type FallbackChatModel struct {
+	names  []string
+	models []model.ToolCallingChatModel
+}
+
+// NewFallbackChatModel creates a model trying models in order, names are the model names
+// reported on fallback. At least one model is required.
+func NewFallbackChatModel(names []string, models []model.ToolCallingChatModel) *FallbackChatModel {
+	return &FallbackChatModel{names: names, models: models}
+}
+
+// Generate implements BaseChatModel
+func (m *FallbackChatModel) Generate(ctx context.Context, messages []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	for i, cm := range m.models {
+		msg, err := cm.Generate(ctx, messages, opts...)
+		if err == nil || !m.fallback(ctx, i, err) {
+			return msg, err
+		}
+	}
+	return nil, fmt.Errorf("fallback chat model has no model")
+}
+
+// Stream implements BaseChatModel
+func (m *FallbackChatModel) Stream(ctx context.Context, messages []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	for i, cm := range m.models {
+		stream, err := cm.Stream(ctx, messages, opts...)
+		if err == nil || !m.fallback(ctx, i, err) {
+			return stream, err
+		}
+	}
+	return nil, fmt.Errorf("fallback chat model has no model")
+}
+
+// WithTools returns a new FallbackChatModel with the tools bound to every model
+func (m *FallbackChatModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	models := make([]model.ToolCallingChatModel, len(m.models))
+	for i, cm := range m.models {
+		withTools, err := cm.WithTools(tools)
+		if err != nil {
+			return nil, fmt.Errorf("failed to bind tools to model %s: %w", m.names[i], err)
+		}
+		models[i] = withTools
+	}
+	return NewFallbackChatModel(m.names, models), nil
+}
+
+// fallback decides whether the call failed by model i is made again with the next model,
+// and reports the fallback
+func (m *FallbackChatModel) fallback(ctx context.Context, i int, err error) bool {
+	if i+1 >= len(m.models) || !isUnavailable(ctx, err) {
+		return false
+	}
+	from, to := m.names[i], m.names[i+1]
+	logger.Warn("providers", fmt.Sprintf("Model %s failed: %v, falling back to %s", from, err, to))
+	if reporter, ok := ctx.Value(fallbackReporterKey{}).(FallbackReporter); ok {
+		reporter.ReportModelFallback(from, to, err)
+	}
+	return true
+}
+
+// statusCodePattern finds the HTTP status of a failed request in the error messages of
+// the provider SDKs, e.g. "status code: 503" or `POST "https://...": 529 Overloaded`
+var statusCodePattern = regexp.MustCompile(`(?i)(?:status(?: code)?[:=]?\s*|": )(\d{3})\b`)
+
+// unavailableMarkers are parts of the error messages of a provider that can't serve the
+// request at the moment
+var unavailableMarkers = []string{
+	"connection refused", "connection reset", "no such host", "i/o timeout", "tls handshake",
+	"service unavailable", "bad gateway", "gateway timeout", "overloaded", "too many requests",
+	"rate limit",
+}
+
+// isUnavailable reports whether the error means the provider is unavailable, rather than
+// the request being invalid: network errors, authentication failures, unknown models,
+// rate limits and server errors. A cancelled call is never retried with another model.
+func isUnavailable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, ErrContentFiltered) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	if match := statusCodePattern.FindStringSubmatch(err.Error()); match != nil {
+		code, _ := strconv.Atoi(match[1])
+		switch {
+		case code == 401, code == 403, code == 404, code == 408, code == 429, code >= 500:
+			return true
+		case code >= 400:
+			// Bad request, payload too large, unprocessable: the request itself is wrong
+			return false
+		}
+	}
+	info := strings.ToLower(err.Error())
+	for _, marker := range unavailableMarkers {
+		if strings.Contains(info, marker) {
+			return true
+		}
+	}
+	return false
+}
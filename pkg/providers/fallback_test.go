@@ -0,0 +1,104 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// failingModel fails every call with err
+type failingModel struct {
+	err   error
+	calls int
+}
+
+func (m *failingModel) Generate(ctx context.Context, messages []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	m.calls++
+	return nil, m.err
+}
+
+func (m *failingModel) Stream(ctx context.Context, messages []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	m.calls++
+	return nil, m.err
+}
+
+func (m *failingModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+type fallbackRecorder struct {
+	fallbacks []string
+}
+
+func (r *fallbackRecorder) ReportModelFallback(from, to string, err error) {
+	r.fallbacks = append(r.fallbacks, from+"->"+to)
+}
+
+func TestFallbackChatModelFallsBackOnUnavailableProvider(t *testing.T) {
+	primary := &failingModel{err: errors.New("error, status code: 503, message: service unavailable")}
+	second := &failingModel{err: errors.New(`POST "https://api.example.com/v1/messages": 529 Overloaded`)}
+	m := NewFallbackChatModel([]string{"primary", "second", "third"},
+		[]model.ToolCallingChatModel{primary, second, &mockModel{name: "third"}})
+
+	recorder := &fallbackRecorder{}
+	msg, err := m.Generate(WithFallbackReporter(context.Background(), recorder), nil)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if msg.Content != "third" {
+		t.Errorf("Generate() answered by %q, want third", msg.Content)
+	}
+	if len(recorder.fallbacks) != 2 || recorder.fallbacks[0] != "primary->second" || recorder.fallbacks[1] != "second->third" {
+		t.Errorf("fallbacks = %v, want [primary->second second->third]", recorder.fallbacks)
+	}
+}
+
+func TestFallbackChatModelKeepsRequestErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"bad request", errors.New("error, status code: 400, message: invalid tool call arguments")},
+		{"too large", errors.New(`POST "https://api.example.com/v1/messages": 413 Request Entity Too Large`)},
+		{"content filtered", fmt.Errorf("stream: %w", ErrContentFiltered)},
+		{"unknown", errors.New("failed to parse tool arguments")},
+	}
+	for _, tt := range tests {
+		primary := &failingModel{err: tt.err}
+		fallback := &failingModel{err: errors.New("unexpected call")}
+		m := NewFallbackChatModel([]string{"primary", "fallback"}, []model.ToolCallingChatModel{primary, fallback})
+		if _, err := m.Stream(context.Background(), nil); !errors.Is(err, tt.err) {
+			t.Errorf("%s: Stream() error = %v, want %v", tt.name, err, tt.err)
+		}
+		if fallback.calls != 0 {
+			t.Errorf("%s: fell back on a request error", tt.name)
+		}
+	}
+}
+
+func TestFallbackChatModelStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	fallback := &failingModel{err: errors.New("unexpected call")}
+	m := NewFallbackChatModel([]string{"primary", "fallback"},
+		[]model.ToolCallingChatModel{&failingModel{err: errors.New("connection reset by peer")}, fallback})
+	if _, err := m.Generate(ctx, nil); err == nil {
+		t.Fatal("Generate() error = nil, want the error of the primary")
+	}
+	if fallback.calls != 0 {
+		t.Error("fell back after the call was cancelled")
+	}
+}
+
+func TestFallbackChatModelReturnsLastError(t *testing.T) {
+	last := errors.New("dial tcp: lookup api.example.com: no such host")
+	m := NewFallbackChatModel([]string{"primary", "fallback"},
+		[]model.ToolCallingChatModel{&failingModel{err: errors.New("status code: 429")}, &failingModel{err: last}})
+	if _, err := m.Generate(context.Background(), nil); !errors.Is(err, last) {
+		t.Errorf("Generate() error = %v, want %v", err, last)
+	}
+}
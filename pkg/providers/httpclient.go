@@ -1,6 +1,13 @@
 package providers
 
-import "net/http"
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+	"github.com/Arvintian/chat-agent/pkg/utils"
+)
 
 // headerTransport injects custom headers into every HTTP request.
 type headerTransport struct {
@@ -15,11 +22,19 @@ func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	return t.base.RoundTrip(req)
 }
 
-func newHeaderClient(headers map[string]string) *http.Client {
-	return &http.Client{
-		Transport: &headerTransport{
-			base:    http.DefaultTransport,
-			headers: headers,
-		},
+// newProviderClient creates the HTTP client of a provider: its requests go through the
+// proxy of the provider, or the environment's, and carry the headers of the provider
+func newProviderClient(providerCfg *config.Provider) (*http.Client, error) {
+	transport, err := utils.NewProxyTransport(providerCfg.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("provider %s: %w", providerCfg.Type, err)
+	}
+	client := &http.Client{Transport: transport}
+	if len(providerCfg.Headers) > 0 {
+		client.Transport = &headerTransport{base: transport, headers: providerCfg.Headers}
+	}
+	if providerCfg.Timeout > 0 {
+		client.Timeout = time.Duration(providerCfg.Timeout) * time.Second
 	}
+	return client, nil
 }
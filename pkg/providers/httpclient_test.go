@@ -0,0 +1,35 @@
+package providers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+)
+
+func TestProviderClientProxy(t *testing.T) {
+	// The proxy gets the request for the provider and answers it itself
+	var proxied, header string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied, header = r.URL.String(), r.Header.Get("X-Team")
+	}))
+	defer proxy.Close()
+
+	client, err := newProviderClient(&config.Provider{Type: "openai", Proxy: proxy.URL, Headers: map[string]string{"X-Team": "agents"}})
+	if err != nil {
+		t.Fatalf("newProviderClient() error = %v", err)
+	}
+	resp, err := client.Get("http://api.example.com/v1/models")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+	if proxied != "http://api.example.com/v1/models" || header != "agents" {
+		t.Errorf("proxy got %q with header %q, want the request with the provider headers", proxied, header)
+	}
+
+	if _, err := newProviderClient(&config.Provider{Type: "openai", Proxy: "ftp://proxy"}); err == nil {
+		t.Error("newProviderClient() error = nil, want an invalid proxy URL")
+	}
+}
@@ -0,0 +1,96 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// emptyChoice stands in for the choices of a stream chunk that has none
+var emptyChoice = json.RawMessage(`[{"index":0,"delta":{}}]`)
+
+// openRouterStreamTransport repairs the event streams of OpenRouter for the openrouter
+// client, which reads the first choice of every chunk and panics on chunks without
+// one. Keep-alive comments like ": OPENROUTER PROCESSING" are dropped and chunks
+// without choices, like usage-only frames, get an empty choice so their usage is kept.
+type openRouterStreamTransport struct {
+	base http.RoundTripper
+}
+
+// newOpenRouterClient creates a client repairing the event streams received through base
+func newOpenRouterClient(base http.RoundTripper) *http.Client {
+	return &http.Client{Transport: &openRouterStreamTransport{base: base}}
+}
+
+func (t *openRouterStreamTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return resp, err
+	}
+	resp.Body = newOpenRouterStreamReader(resp.Body)
+	resp.ContentLength = -1
+	resp.Header.Del("Content-Length")
+	return resp, nil
+}
+
+// openRouterStreamReader repairs an event stream line by line
+type openRouterStreamReader struct {
+	body   io.ReadCloser
+	reader *bufio.Reader
+	buf    bytes.Buffer
+	err    error
+}
+
+func newOpenRouterStreamReader(body io.ReadCloser) *openRouterStreamReader {
+	return &openRouterStreamReader{body: body, reader: bufio.NewReader(body)}
+}
+
+func (r *openRouterStreamReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 && r.err == nil {
+		line, err := r.reader.ReadBytes('\n')
+		r.err = err
+		if len(line) > 0 {
+			r.buf.Write(repairStreamLine(line))
+		}
+	}
+	if r.buf.Len() > 0 {
+		return r.buf.Read(p)
+	}
+	return 0, r.err
+}
+
+func (r *openRouterStreamReader) Close() error {
+	return r.body.Close()
+}
+
+// repairStreamLine drops comment lines and adds an empty choice to the data of a chunk
+// without choices. Other lines, including errors and [DONE], are returned as is.
+func repairStreamLine(line []byte) []byte {
+	if bytes.HasPrefix(line, []byte(":")) {
+		return nil
+	}
+	data, ok := bytes.CutPrefix(line, []byte("data:"))
+	if !ok {
+		return line
+	}
+	var chunk map[string]json.RawMessage
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return line
+	}
+	if _, failed := chunk["error"]; failed {
+		return line
+	}
+	var choices []json.RawMessage
+	if err := json.Unmarshal(chunk["choices"], &choices); err == nil && len(choices) > 0 {
+		return line
+	}
+	chunk["choices"] = emptyChoice
+	repaired, err := json.Marshal(chunk)
+	if err != nil {
+		return line
+	}
+	return append(append([]byte("data: "), repaired...), '\n')
+}
@@ -0,0 +1,88 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenRouterStreamTransport(t *testing.T) {
+	stream := strings.Join([]string{
+		": OPENROUTER PROCESSING",
+		"",
+		`data: {"id":"1","choices":[{"index":0,"delta":{"content":"Hel"}}]}`,
+		"",
+		`data: {"id":"1","choices":[]}`,
+		"",
+		": OPENROUTER PROCESSING",
+		"",
+		`data: {"id":"1","choices":[{"index":0,"delta":{"content":"lo"},"finish_reason":"stop"}]}`,
+		"",
+		`data: {"id":"1","choices":[],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`,
+		"",
+		`data: {"error":{"code":502,"message":"upstream failed"}}`,
+		"",
+		"data: [DONE]",
+		"",
+	}, "\n")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, stream)
+	}))
+	defer server.Close()
+
+	resp, err := newOpenRouterClient(http.DefaultTransport).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+
+	var content strings.Builder
+	var usage bool
+	for _, line := range strings.Split(string(body), "\n") {
+		if strings.HasPrefix(line, ":") {
+			t.Errorf("stream has the keep-alive line %q", line)
+		}
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || data == "[DONE]" || strings.Contains(data, `"error"`) {
+			continue
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+			Usage *struct {
+				TotalTokens int `json:"total_tokens"`
+			} `json:"usage"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("invalid chunk %q: %v", data, err)
+		}
+		if len(chunk.Choices) == 0 {
+			t.Errorf("chunk %q has no choices", data)
+			continue
+		}
+		content.WriteString(chunk.Choices[0].Delta.Content)
+		if chunk.Usage != nil && chunk.Usage.TotalTokens == 5 {
+			usage = true
+		}
+	}
+	if content.String() != "Hello" || !usage {
+		t.Errorf("content = %q, usage kept = %v, want the streamed text and the usage", content.String(), usage)
+	}
+	for _, want := range []string{`data: {"error":{"code":502,"message":"upstream failed"}}`, "data: [DONE]"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("stream = %s\nwant it to keep %s", body, want)
+		}
+	}
+}
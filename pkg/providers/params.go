@@ -0,0 +1,46 @@
+package providers
+
+import (
+	"fmt"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+	"github.com/Arvintian/chat-agent/pkg/logger"
+)
+
+// Sampling parameters that not every provider can send, named as in the configuration
+const (
+	paramStop             = "stop"
+	paramSeed             = "seed"
+	paramFrequencyPenalty = "frequencyPenalty"
+	paramPresencePenalty  = "presencePenalty"
+)
+
+// ignoreParams logs at debug level the parameters among params that are set on the
+// model but that its provider can't send
+func ignoreParams(modelCfg *config.Model, params ...string) {
+	for _, param := range params {
+		var set bool
+		switch param {
+		case paramStop:
+			set = len(modelCfg.Stop) > 0
+		case paramSeed:
+			set = modelCfg.Seed != nil
+		case paramFrequencyPenalty:
+			set = modelCfg.FrequencyPenalty != 0
+		case paramPresencePenalty:
+			set = modelCfg.PresencePenalty != 0
+		}
+		if set {
+			logger.Debug("providers", fmt.Sprintf("Model %s: the %s provider doesn't support %s, ignoring it", modelCfg.Model, modelCfg.Provider, param))
+		}
+	}
+}
+
+// optionalFloat32 returns a pointer to v as a float32, or nil when v is unset
+func optionalFloat32(v float64) *float32 {
+	if v == 0 {
+		return nil
+	}
+	f := float32(v)
+	return &f
+}
@@ -0,0 +1,61 @@
+package providers
+
+import (
+	"context"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// PromptCacheChatModel marks the system prompt of each request as a cache breakpoint, so
+// providers with explicit prompt caching reuse the cached prefix instead of processing it
+// again. The prefix ends with the system prompt and includes the tool definitions, which
+// come before it in the prompt.
+type PromptCacheChatModel struct {
+	model model.ToolCallingChatModel
+	mark  func(*schema.Message) *schema.Message
+}
+
+// NewPromptCacheChatModel wraps a model to mark the system prompt with mark, which returns
+// a marked copy of the message
+func NewPromptCacheChatModel(m model.ToolCallingChatModel, mark func(*schema.Message) *schema.Message) *PromptCacheChatModel {
+	return &PromptCacheChatModel{model: m, mark: mark}
+}
+
+// Generate implements BaseChatModel
+func (m *PromptCacheChatModel) Generate(ctx context.Context, messages []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	return m.model.Generate(ctx, m.markSystemPrompt(messages), opts...)
+}
+
+// Stream implements BaseChatModel
+func (m *PromptCacheChatModel) Stream(ctx context.Context, messages []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return m.model.Stream(ctx, m.markSystemPrompt(messages), opts...)
+}
+
+// WithTools returns a new PromptCacheChatModel wrapping the model with the tools bound
+func (m *PromptCacheChatModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	withTools, err := m.model.WithTools(tools)
+	if err != nil {
+		return nil, err
+	}
+	return NewPromptCacheChatModel(withTools, m.mark), nil
+}
+
+// markSystemPrompt returns the messages with the last leading system message marked. The
+// messages of the caller are not modified, they are kept in the conversation context.
+func (m *PromptCacheChatModel) markSystemPrompt(messages []*schema.Message) []*schema.Message {
+	last := -1
+	for i, msg := range messages {
+		if msg == nil || msg.Role != schema.System {
+			break
+		}
+		last = i
+	}
+	if last < 0 {
+		return messages
+	}
+	marked := make([]*schema.Message, len(messages))
+	copy(marked, messages)
+	marked[last] = m.mark(messages[last])
+	return marked
+}
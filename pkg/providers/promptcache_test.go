@@ -0,0 +1,74 @@
+package providers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// recordModel records the messages of the last request
+type recordModel struct {
+	messages []*schema.Message
+}
+
+func (m *recordModel) Generate(ctx context.Context, messages []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	m.messages = messages
+	return schema.AssistantMessage("ok", nil), nil
+}
+
+func (m *recordModel) Stream(ctx context.Context, messages []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	m.messages = messages
+	return schema.StreamReaderFromArray([]*schema.Message{schema.AssistantMessage("ok", nil)}), nil
+}
+
+func (m *recordModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+func markCached(msg *schema.Message) *schema.Message {
+	marked := *msg
+	marked.Extra = map[string]any{"cached": true}
+	return &marked
+}
+
+func TestPromptCacheMarksSystemPrompt(t *testing.T) {
+	inner := &recordModel{}
+	m := NewPromptCacheChatModel(inner, markCached)
+	messages := []*schema.Message{
+		schema.SystemMessage("system"),
+		schema.UserMessage("hello"),
+		schema.SystemMessage("not a prompt"),
+	}
+	if _, err := m.Generate(context.Background(), messages); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(inner.messages) != len(messages) {
+		t.Fatalf("got %d messages, want %d", len(inner.messages), len(messages))
+	}
+	for i, msg := range inner.messages {
+		cached := msg.Extra["cached"] == true
+		if cached != (i == 0) {
+			t.Errorf("message %d cached = %v, want %v", i, cached, i == 0)
+		}
+	}
+	if messages[0].Extra != nil {
+		t.Errorf("the caller's system message was modified")
+	}
+}
+
+func TestPromptCacheWithoutSystemPrompt(t *testing.T) {
+	inner := &recordModel{}
+	m, err := NewPromptCacheChatModel(inner, markCached).WithTools(nil)
+	if err != nil {
+		t.Fatalf("WithTools() error = %v", err)
+	}
+	messages := []*schema.Message{schema.UserMessage("hello")}
+	if _, err := m.Stream(context.Background(), messages); err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	if len(inner.messages) != 1 || inner.messages[0] != messages[0] {
+		t.Errorf("messages = %v, want them unchanged", inner.messages)
+	}
+}
@@ -2,9 +2,11 @@ package providers
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/Arvintian/chat-agent/pkg/config"
+	"github.com/Arvintian/chat-agent/pkg/utils"
 	"github.com/eino-contrib/ollama/api"
 
 	"github.com/cloudwego/eino-ext/components/model/ark"
@@ -42,10 +44,10 @@ func (f *Factory) createOpenAIModel(ctx context.Context, modelCfg *config.Model,
 		cfg.Timeout = time.Duration(providerCfg.Timeout) * time.Second
 	}
 
-	if len(providerCfg.Headers) > 0 {
-		client := newHeaderClient(providerCfg.Headers)
-		if providerCfg.Timeout > 0 {
-			client.Timeout = time.Duration(providerCfg.Timeout) * time.Second
+	if len(providerCfg.Headers) > 0 || providerCfg.Proxy != "" {
+		client, err := newProviderClient(providerCfg)
+		if err != nil {
+			return nil, err
 		}
 		cfg.HTTPClient = client
 	}
@@ -61,6 +63,10 @@ func (f *Factory) createOpenAIModel(ctx context.Context, modelCfg *config.Model,
 		topP := float32(modelCfg.TopP)
 		cfg.TopP = &topP
 	}
+	cfg.Stop = modelCfg.Stop
+	cfg.Seed = modelCfg.Seed
+	cfg.FrequencyPenalty = optionalFloat32(modelCfg.FrequencyPenalty)
+	cfg.PresencePenalty = optionalFloat32(modelCfg.PresencePenalty)
 
 	return openai.NewChatModel(ctx, cfg)
 }
@@ -86,12 +92,27 @@ func (f *Factory) createClaudeModel(ctx context.Context, modelCfg *config.Model,
 		topP := float32(modelCfg.TopP)
 		cfg.TopP = &topP
 	}
+	cfg.StopSequences = modelCfg.Stop
+	ignoreParams(modelCfg, paramSeed, paramFrequencyPenalty, paramPresencePenalty)
 
-	return claude.NewChatModel(ctx, cfg)
+	cm, err := claude.NewChatModel(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if modelCfg.CachePrompt {
+		// Cache the tools and the system prompt, which don't change between the turns
+		return NewPromptCacheChatModel(cm, claude.SetMessageBreakpoint), nil
+	}
+	return cm, nil
 }
 
 // createGeminiModel creates Gemini model
 func (f *Factory) createGeminiModel(ctx context.Context, modelCfg *config.Model, providerCfg *config.Provider) (model.ToolCallingChatModel, error) {
+	return gemini.NewChatModel(ctx, geminiConfig(modelCfg))
+}
+
+// geminiConfig returns the model configuration shared by the Gemini and Vertex AI providers
+func geminiConfig(modelCfg *config.Model) *gemini.Config {
 	cfg := &gemini.Config{
 		Model: modelCfg.Model,
 	}
@@ -114,8 +135,8 @@ func (f *Factory) createGeminiModel(ctx context.Context, modelCfg *config.Model,
 		topP := float32(modelCfg.TopP)
 		cfg.TopP = &topP
 	}
-
-	return gemini.NewChatModel(ctx, cfg)
+	ignoreParams(modelCfg, paramStop, paramSeed, paramFrequencyPenalty, paramPresencePenalty)
+	return cfg
 }
 
 // createQwenModel creates Qwen model
@@ -138,6 +159,10 @@ func (f *Factory) createQwenModel(ctx context.Context, modelCfg *config.Model, p
 		topP := float32(modelCfg.TopP)
 		cfg.TopP = &topP
 	}
+	cfg.Stop = modelCfg.Stop
+	cfg.Seed = modelCfg.Seed
+	cfg.FrequencyPenalty = optionalFloat32(modelCfg.FrequencyPenalty)
+	cfg.PresencePenalty = optionalFloat32(modelCfg.PresencePenalty)
 
 	return qwen.NewChatModel(ctx, cfg)
 }
@@ -166,6 +191,14 @@ func (f *Factory) createQianfanModel(ctx context.Context, modelCfg *config.Model
 		topP := float32(modelCfg.TopP)
 		cfg.TopP = &topP
 	}
+	cfg.Stop = modelCfg.Stop
+	cfg.Seed = modelCfg.Seed
+	if modelCfg.FrequencyPenalty != 0 {
+		cfg.FrequencyPenalty = &modelCfg.FrequencyPenalty
+	}
+	if modelCfg.PresencePenalty != 0 {
+		cfg.PresencePenalty = &modelCfg.PresencePenalty
+	}
 
 	return qianfan.NewChatModel(ctx, cfg)
 }
@@ -199,6 +232,10 @@ func (f *Factory) createArkModel(ctx context.Context, modelCfg *config.Model, pr
 		topP := float32(modelCfg.TopP)
 		cfg.TopP = &topP
 	}
+	cfg.Stop = modelCfg.Stop
+	cfg.FrequencyPenalty = optionalFloat32(modelCfg.FrequencyPenalty)
+	cfg.PresencePenalty = optionalFloat32(modelCfg.PresencePenalty)
+	ignoreParams(modelCfg, paramSeed)
 
 	return ark.NewChatModel(ctx, cfg)
 }
@@ -232,6 +269,10 @@ func (f *Factory) createDeepSeekModel(ctx context.Context, modelCfg *config.Mode
 		topP := float32(modelCfg.TopP)
 		cfg.TopP = topP
 	}
+	cfg.Stop = modelCfg.Stop
+	cfg.FrequencyPenalty = float32(modelCfg.FrequencyPenalty)
+	cfg.PresencePenalty = float32(modelCfg.PresencePenalty)
+	ignoreParams(modelCfg, paramSeed)
 
 	return deepseek.NewChatModel(ctx, cfg)
 }
@@ -257,13 +298,24 @@ func (f *Factory) createOllamaModel(ctx context.Context, modelCfg *config.Model,
 	if modelCfg.TopK > 0 {
 		options.TopK = modelCfg.TopK
 	}
-	if modelCfg.Temperature > 0 || modelCfg.TopP > 0 || modelCfg.TopK > 0 {
+	options.Stop = modelCfg.Stop
+	if modelCfg.Seed != nil {
+		options.Seed = *modelCfg.Seed
+	}
+	options.FrequencyPenalty = float32(modelCfg.FrequencyPenalty)
+	options.PresencePenalty = float32(modelCfg.PresencePenalty)
+	if modelCfg.Temperature > 0 || modelCfg.TopP > 0 || modelCfg.TopK > 0 || len(modelCfg.Stop) > 0 ||
+		modelCfg.Seed != nil || modelCfg.FrequencyPenalty != 0 || modelCfg.PresencePenalty != 0 {
 		cfg.Options = &options
 	}
 	return ollama.NewChatModel(ctx, cfg)
 }
 
 func (f *Factory) createOpenRouterModel(ctx context.Context, modelCfg *config.Model, providerCfg *config.Provider) (model.ToolCallingChatModel, error) {
+	transport, err := utils.NewProxyTransport(providerCfg.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("provider %s: %w", providerCfg.Type, err)
+	}
 	effort := openrouter.EffortOfMedium
 	if !modelCfg.Thinking {
 		effort = openrouter.EffortOfNone
@@ -277,6 +329,8 @@ func (f *Factory) createOpenRouterModel(ctx context.Context, modelCfg *config.Mo
 			Exclude: !modelCfg.Thinking,
 			Enabled: &modelCfg.Thinking,
 		},
+		// Drops the keep-alive comments and chunks without choices the client can't read
+		HTTPClient: newOpenRouterClient(transport),
 	}
 
 	if modelCfg.MaxTokens > 0 {
@@ -290,6 +344,15 @@ func (f *Factory) createOpenRouterModel(ctx context.Context, modelCfg *config.Mo
 		topP := float32(modelCfg.TopP)
 		cfg.TopP = &topP
 	}
+	cfg.Stop = modelCfg.Stop
+	cfg.Seed = modelCfg.Seed
+	cfg.FrequencyPenalty = optionalFloat32(modelCfg.FrequencyPenalty)
+	cfg.PresencePenalty = optionalFloat32(modelCfg.PresencePenalty)
 
-	return openrouter.NewChatModel(ctx, cfg)
+	cm, err := openrouter.NewChatModel(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	// Report responses blocked by the upstream provider instead of an empty answer
+	return NewContentFilterChatModel(cm), nil
 }
@@ -0,0 +1,80 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Arvintian/chat-agent/pkg/config"
+	"github.com/Arvintian/chat-agent/pkg/utils"
+
+	"cloud.google.com/go/auth"
+	"cloud.google.com/go/auth/credentials"
+	"github.com/cloudwego/eino-ext/components/model/gemini"
+	"github.com/cloudwego/eino/components/model"
+	"google.golang.org/genai"
+)
+
+// vertexScope is the OAuth scope of the Vertex AI API
+const vertexScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// createVertexModel creates a Gemini model served by Vertex AI in a Google Cloud project.
+// It authenticates with the service account key of credentialsFile, or with the
+// application default credentials when no file is configured.
+func (f *Factory) createVertexModel(ctx context.Context, modelCfg *config.Model, providerCfg *config.Provider) (model.ToolCallingChatModel, error) {
+	if providerCfg.Project == "" {
+		return nil, fmt.Errorf("vertex provider requires project, the Google Cloud project ID")
+	}
+	if providerCfg.Location == "" {
+		return nil, fmt.Errorf("vertex provider requires location, the Google Cloud region, e.g. us-central1")
+	}
+	creds, err := vertexCredentials(providerCfg.CredentialsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	clientCfg := &genai.ClientConfig{
+		Backend:     genai.BackendVertexAI,
+		Project:     providerCfg.Project,
+		Location:    providerCfg.Location,
+		Credentials: creds,
+		HTTPOptions: genai.HTTPOptions{BaseURL: providerCfg.BaseURL},
+	}
+	if len(providerCfg.Headers) > 0 {
+		clientCfg.HTTPOptions.Headers = make(http.Header, len(providerCfg.Headers))
+		for k, v := range providerCfg.Headers {
+			clientCfg.HTTPOptions.Headers.Set(k, v)
+		}
+	}
+	client, err := genai.NewClient(ctx, clientCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the Vertex AI client: %w", err)
+	}
+
+	cfg := geminiConfig(modelCfg)
+	cfg.Client = client
+	return gemini.NewChatModel(ctx, cfg)
+}
+
+// vertexCredentials loads the service account key file, or the application default
+// credentials when path is empty
+func vertexCredentials(path string) (*auth.Credentials, error) {
+	opts := &credentials.DetectOptions{Scopes: []string{vertexScope}}
+	if path != "" {
+		path, err := utils.ExpandPath(path)
+		if err != nil {
+			return nil, err
+		}
+		creds, err := credentials.NewCredentialsFromFile(credentials.ServiceAccount, path, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the vertex credentials file %s: %w", path, err)
+		}
+		return creds, nil
+	}
+	creds, err := credentials.DetectDefault(opts)
+	if err != nil {
+		return nil, fmt.Errorf("no credentials for the vertex provider: set credentialsFile to a service account key file, "+
+			"or configure the application default credentials (GOOGLE_APPLICATION_CREDENTIALS or gcloud auth application-default login): %w", err)
+	}
+	return creds, nil
+}
@@ -64,6 +64,97 @@ type EventHandler interface {
 	OnReconnected()
 }
 
+// ToolsReloadedHandler is an optional interface for an EventHandler that wants to
+// be notified after the tools of the current chat are re-initialized.
+type ToolsReloadedHandler interface {
+	OnToolsReloaded(payload *ToolsReloadedPayload)
+}
+
+// ContentFilteredHandler is an optional interface for an EventHandler that handles
+// responses blocked by the provider's content filter. Without it they are passed to OnError.
+type ContentFilteredHandler interface {
+	OnContentFiltered(payload *ContentFilteredPayload)
+}
+
+// UsageHandler is an optional interface for an EventHandler that wants the token
+// usage reported after each turn.
+type UsageHandler interface {
+	OnUsage(payload *UsagePayload)
+}
+
+// ToolProgressHandler is an optional interface for an EventHandler that wants the
+// intermediate output of running tool calls.
+type ToolProgressHandler interface {
+	OnToolProgress(payload *ToolProgressPayload)
+}
+
+// ModelFallbackHandler is an optional interface for an EventHandler that wants to know
+// when a fallback model answers in place of the chat's model.
+type ModelFallbackHandler interface {
+	OnModelFallback(payload *ModelFallbackPayload)
+}
+
+// ToolsHandler is an optional interface for an EventHandler that wants the tool list
+// sent in reply to ListTools and ToggleTool.
+type ToolsHandler interface {
+	OnTools(payload *ToolsPayload)
+}
+
+// ApprovalWarningHandler is an optional interface for an EventHandler that wants to be
+// warned before a pending approval request expires.
+type ApprovalWarningHandler interface {
+	OnApprovalWarning(payload *ApprovalWarningPayload)
+}
+
+// ShuttingDownHandler is an optional interface for an EventHandler that wants to know
+// when the server starts shutting down.
+type ShuttingDownHandler interface {
+	OnShuttingDown(payload *ShuttingDownPayload)
+}
+
+// ApprovalResolvedHandler is an optional interface for an EventHandler that wants to know
+// when an approval request was answered, possibly by another connection of the session.
+type ApprovalResolvedHandler interface {
+	OnApprovalResolved(payload *ApprovalResolvedPayload)
+}
+
+// PinnedHandler is an optional interface for an EventHandler that wants the pinned
+// messages sent in reply to Pin and Unpin.
+type PinnedHandler interface {
+	OnPinned(payload *PinnedPayload)
+}
+
+// CompactedHandler is an optional interface for an EventHandler that wants the result
+// of Compact.
+type CompactedHandler interface {
+	OnCompacted(payload *CompactedPayload)
+}
+
+// RateLimitedHandler is an optional interface for an EventHandler that wants to know when
+// a chat message was not answered because of the rate limits of the server.
+type RateLimitedHandler interface {
+	OnRateLimited(payload *RateLimitedPayload)
+}
+
+// SessionInfoHandler is an optional interface for an EventHandler that wants the session
+// metadata sent in reply to SessionInfo.
+type SessionInfoHandler interface {
+	OnSessionInfo(payload *SessionInfoPayload)
+}
+
+// ContextEventHandler is an optional interface for an EventHandler that wants to know
+// when the server summarizes older rounds of the conversation.
+type ContextEventHandler interface {
+	OnContextEvent(payload *ContextEventPayload)
+}
+
+// QuestionHandler is an optional interface for an EventHandler that can answer
+// clarifying questions asked by the model. The handler should call SendQuestionResponse
+// to provide the answer. Questions sent to a handler without it are answered empty.
+type QuestionHandler interface {
+	OnQuestion(payload *QuestionPayload)
+}
+
 // Client is a WebSocket client SDK for the chat-agent serve mode.
 // It manages the connection lifecycle and message passing.
 type Client struct {
@@ -211,11 +302,23 @@ func (c *Client) Regenerate() error {
 	return c.sendCommand(CmdRegenerate, ChatRequest{})
 }
 
+// EditMessage replaces the user message of a round by text and regenerates the response
+// from there, dropping the later rounds. round counts the rounds of the context with a
+// user message from 0, or from the end when negative.
+func (c *Client) EditMessage(round int, text string) error {
+	return c.sendCommand(CmdEditMessage, EditMessagePayload{Round: round, Message: text})
+}
+
 // Stop stops the current ongoing response.
 func (c *Client) Stop() error {
 	return c.sendCommand(CmdStop, nil)
 }
 
+// Resume continues the last response after it was stopped.
+func (c *Client) Resume() error {
+	return c.sendCommand(CmdResume, nil)
+}
+
 // Clear clears the conversation context for the current chat.
 func (c *Client) Clear() error {
 	return c.sendCommand(CmdClear, nil)
@@ -226,11 +329,62 @@ func (c *Client) Keep() error {
 	return c.sendCommand(CmdKeep, nil)
 }
 
+// KeepWithLabel triggers the keep hook with a label the hook can use to categorize the session.
+func (c *Client) KeepWithLabel(label string) error {
+	return c.sendCommand(CmdKeep, KeepPayload{Label: label})
+}
+
+// Pin pins the n-th user message of the current chat, counting from 1, or the last one
+// when n is 0. Pinned messages are never summarized when the context is compressed.
+func (c *Client) Pin(n int) error {
+	return c.sendCommand(CmdPin, PinPayload{Index: n})
+}
+
+// Unpin unpins the n-th pinned message, counting from 1, or all of them when n is 0.
+func (c *Client) Unpin(n int) error {
+	return c.sendCommand(CmdPin, PinPayload{Index: n, Unpin: true})
+}
+
+// Compact summarizes the context of the current chat except the last round right away,
+// instead of waiting for it to fill up. The result is sent as a compacted message.
+func (c *Client) Compact() error {
+	return c.sendCommand(CmdCompact, nil)
+}
+
+// SessionInfo requests the metadata of the session: its chats with their message counts
+// and tools, and whether a response is in progress.
+func (c *Client) SessionInfo() error {
+	return c.sendCommand(CmdSessionInfo, nil)
+}
+
+// ReloadTools re-initializes the tools of the current chat, keeping the conversation context.
+func (c *Client) ReloadTools() error {
+	return c.sendCommand(CmdReloadTools, nil)
+}
+
+// ListTools requests the tools of the current chat and whether each is enabled.
+func (c *Client) ListTools() error {
+	return c.sendCommand(CmdListTools, nil)
+}
+
+// ToggleTool enables or disables a tool of the current chat from the next turn on.
+func (c *Client) ToggleTool(name string, enabled bool) error {
+	return c.sendCommand(CmdToggleTool, ToggleToolPayload{Name: name, Enabled: enabled})
+}
+
 // DeselectChat deselects the current chat and returns to the selection page.
 func (c *Client) DeselectChat() error {
 	return c.sendCommand(CmdDeselectChat, nil)
 }
 
+// SendQuestionResponse sends the user's answer to a clarifying question back to the server.
+func (c *Client) SendQuestionResponse(questionID string, answer string) error {
+	return c.sendCommand(CmdQuestionResponse, QuestionResponsePayload{
+		QuestionID: questionID,
+		Answer:     answer,
+	})
+}
+
 // SendApprovalResponse sends the user's approval decision back to the server.
 func (c *Client) SendApprovalResponse(approvalID string, results map[string]ApprovalItem) error {
 	return c.sendCommand(CmdApprovalResponse, ApprovalResponsePayload{
@@ -239,6 +393,12 @@ func (c *Client) SendApprovalResponse(approvalID string, results map[string]Appr
 	})
 }
 
+// CancelApproval cancels a pending approval request, its tool calls are denied and the
+// response continues.
+func (c *Client) CancelApproval(approvalID string) error {
+	return c.sendCommand(CmdCancelApproval, CancelApprovalPayload{ApprovalID: approvalID})
+}
+
 // ---- Internal methods ----
 
 func (c *Client) sendCommand(cmdType string, payload interface{}) error {
@@ -486,6 +646,104 @@ func (c *Client) dispatch(msg *WSMessage) {
 		if c.unmarshalPayload(msg.Payload, &payload) {
 			c.handler.OnCleared(&payload)
 		}
+	case MsgQuestion:
+		var payload QuestionPayload
+		if !c.unmarshalPayload(msg.Payload, &payload) {
+			return
+		}
+		if handler, ok := c.handler.(QuestionHandler); ok {
+			handler.OnQuestion(&payload)
+		} else if err := c.SendQuestionResponse(payload.QuestionID, ""); err != nil {
+			log.Printf("serve sdk: failed to answer question %s: %v", payload.QuestionID, err)
+		}
+	case MsgContentFiltered:
+		var payload ContentFilteredPayload
+		if !c.unmarshalPayload(msg.Payload, &payload) {
+			return
+		}
+		if handler, ok := c.handler.(ContentFilteredHandler); ok {
+			handler.OnContentFiltered(&payload)
+		} else {
+			c.handler.OnError(&ErrorPayload{Error: payload.Message})
+		}
+	case MsgToolsReloaded:
+		var payload ToolsReloadedPayload
+		handler, ok := c.handler.(ToolsReloadedHandler)
+		if ok && c.unmarshalPayload(msg.Payload, &payload) {
+			handler.OnToolsReloaded(&payload)
+		}
+	case MsgTools:
+		var payload ToolsPayload
+		handler, ok := c.handler.(ToolsHandler)
+		if ok && c.unmarshalPayload(msg.Payload, &payload) {
+			handler.OnTools(&payload)
+		}
+	case MsgApprovalWarning:
+		var payload ApprovalWarningPayload
+		handler, ok := c.handler.(ApprovalWarningHandler)
+		if ok && c.unmarshalPayload(msg.Payload, &payload) {
+			handler.OnApprovalWarning(&payload)
+		}
+	case MsgShuttingDown:
+		var payload ShuttingDownPayload
+		handler, ok := c.handler.(ShuttingDownHandler)
+		if ok && c.unmarshalPayload(msg.Payload, &payload) {
+			handler.OnShuttingDown(&payload)
+		}
+	case MsgApprovalResolved:
+		var payload ApprovalResolvedPayload
+		handler, ok := c.handler.(ApprovalResolvedHandler)
+		if ok && c.unmarshalPayload(msg.Payload, &payload) {
+			handler.OnApprovalResolved(&payload)
+		}
+	case MsgPinned:
+		var payload PinnedPayload
+		handler, ok := c.handler.(PinnedHandler)
+		if ok && c.unmarshalPayload(msg.Payload, &payload) {
+			handler.OnPinned(&payload)
+		}
+	case MsgSessionInfo:
+		var payload SessionInfoPayload
+		handler, ok := c.handler.(SessionInfoHandler)
+		if ok && c.unmarshalPayload(msg.Payload, &payload) {
+			handler.OnSessionInfo(&payload)
+		}
+	case MsgCompacted:
+		var payload CompactedPayload
+		handler, ok := c.handler.(CompactedHandler)
+		if ok && c.unmarshalPayload(msg.Payload, &payload) {
+			handler.OnCompacted(&payload)
+		}
+	case MsgRateLimited:
+		var payload RateLimitedPayload
+		handler, ok := c.handler.(RateLimitedHandler)
+		if ok && c.unmarshalPayload(msg.Payload, &payload) {
+			handler.OnRateLimited(&payload)
+		}
+	case MsgContextEvent:
+		var payload ContextEventPayload
+		handler, ok := c.handler.(ContextEventHandler)
+		if ok && c.unmarshalPayload(msg.Payload, &payload) {
+			handler.OnContextEvent(&payload)
+		}
+	case MsgUsage:
+		var payload UsagePayload
+		handler, ok := c.handler.(UsageHandler)
+		if ok && c.unmarshalPayload(msg.Payload, &payload) {
+			handler.OnUsage(&payload)
+		}
+	case MsgToolProgress:
+		var payload ToolProgressPayload
+		handler, ok := c.handler.(ToolProgressHandler)
+		if ok && c.unmarshalPayload(msg.Payload, &payload) {
+			handler.OnToolProgress(&payload)
+		}
+	case MsgModelFallback:
+		var payload ModelFallbackPayload
+		handler, ok := c.handler.(ModelFallbackHandler)
+		if ok && c.unmarshalPayload(msg.Payload, &payload) {
+			handler.OnModelFallback(&payload)
+		}
 	default:
 		log.Printf("serve sdk: unknown message type: %s", msg.Type)
 	}
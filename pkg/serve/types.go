@@ -3,22 +3,40 @@
 // streaming responses, tool calls, thinking indicators, approval requests, and more.
 package serve
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Message types sent from server to client.
 const (
-	MsgSessionInit     = "session_init"
-	MsgChatSelected    = "chat_selected"
-	MsgChunk           = "chunk"
-	MsgToolCall        = "tool_call"
-	MsgThinking        = "thinking"
-	MsgComplete        = "complete"
-	MsgError           = "error"
-	MsgApprovalRequest = "approval_request"
-	MsgMessageCount    = "message_count"
-	MsgStopped         = "stopped"
-	MsgKept            = "kept"
-	MsgCleared         = "cleared"
+	MsgSessionInit      = "session_init"
+	MsgChatSelected     = "chat_selected"
+	MsgChunk            = "chunk"
+	MsgToolCall         = "tool_call"
+	MsgThinking         = "thinking"
+	MsgComplete         = "complete"
+	MsgError            = "error"
+	MsgApprovalRequest  = "approval_request"
+	MsgMessageCount     = "message_count"
+	MsgStopped          = "stopped"
+	MsgKept             = "kept"
+	MsgCleared          = "cleared"
+	MsgToolsReloaded    = "tools_reloaded"
+	MsgQuestion         = "question"
+	MsgContentFiltered  = "content_filtered"
+	MsgUsage            = "usage"
+	MsgTools            = "tools"
+	MsgApprovalResolved = "approval_resolved"
+	MsgContextEvent     = "context_event"
+	MsgApprovalWarning  = "approval_warning"
+	MsgShuttingDown     = "shutting_down"
+	MsgToolProgress     = "tool_progress"
+	MsgPinned           = "pinned"
+	MsgSessionInfo      = "session_info"
+	MsgRateLimited      = "rate_limited"
+	MsgCompacted        = "compacted"
+	MsgModelFallback    = "model_fallback"
 )
 
 // Message types sent from client to server.
@@ -26,11 +44,21 @@ const (
 	CmdSelectChat       = "select_chat"
 	CmdChat             = "chat"
 	CmdRegenerate       = "regenerate"
+	CmdEditMessage      = "edit_message"
 	CmdStop             = "stop"
+	CmdResume           = "resume"
 	CmdClear            = "clear"
 	CmdKeep             = "keep"
 	CmdApprovalResponse = "approval_response"
+	CmdCancelApproval   = "cancel_approval"
 	CmdDeselectChat     = "deselect_chat"
+	CmdReloadTools      = "reload_tools"
+	CmdQuestionResponse = "question_response"
+	CmdListTools        = "list_tools"
+	CmdToggleTool       = "toggle_tool"
+	CmdPin              = "pin"
+	CmdSessionInfo      = "session_info"
+	CmdCompact          = "compact"
 )
 
 // WSMessage is the raw WebSocket message format used by the server protocol.
@@ -62,13 +90,39 @@ type ChunkPayload struct {
 }
 
 // ToolCallPayload is sent when the model invokes a tool.
+// Streaming updates and the final completion of the same tool call share the same ID.
 type ToolCallPayload struct {
 	Name      string `json:"name"`
 	Arguments string `json:"arguments"`
-	Index     string `json:"index"`
+	ID        string `json:"id"`
+	Index     string `json:"index"` // deprecated: same value as ID, kept for older servers
 	Streaming bool   `json:"streaming"`
 }
 
+// ToolProgressPayload is received with intermediate output of a running tool call,
+// e.g. the lines printed by a command before it exits.
+type ToolProgressPayload struct {
+	ID       string `json:"id"` // the tool call ID, as in ToolCallPayload
+	Progress string `json:"progress"`
+}
+
+// ModelFallbackPayload is received when a model call of the turn is answered by a
+// fallback model of the chat because the provider of the model failed.
+type ModelFallbackPayload struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Reason string `json:"reason"`
+}
+
+// CallID returns the identifier used to correlate streaming updates with the
+// completion of a tool call, falling back to Index for older servers.
+func (p *ToolCallPayload) CallID() string {
+	if p.ID != "" {
+		return p.ID
+	}
+	return p.Index
+}
+
 // ThinkingPayload indicates whether the model is in a thinking/reasoning phase.
 type ThinkingPayload struct {
 	Status bool `json:"status"`
@@ -79,9 +133,29 @@ type CompletePayload struct {
 	Message string `json:"message"`
 }
 
-// ErrorPayload carries an error message.
+// ErrorPayload carries an error message. Code is set for the errors of a chat turn, e.g.
+// "provider_unavailable", "tool_execution" or "internal".
 type ErrorPayload struct {
 	Error string `json:"error"`
+	Code  string `json:"code,omitempty"`
+}
+
+// ContentFilteredPayload is received when the provider blocked the response with its content filter.
+type ContentFilteredPayload struct {
+	Message string `json:"message"`
+}
+
+// TokenUsagePayload counts the tokens reported by the provider.
+type TokenUsagePayload struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// UsagePayload is received after a turn with the tokens it used and the running total of the chat.
+type UsagePayload struct {
+	Turn    TokenUsagePayload `json:"turn"`
+	Session TokenUsagePayload `json:"session"`
 }
 
 // ApprovalTargetPayload describes a single target requiring approval.
@@ -89,6 +163,8 @@ type ApprovalTargetPayload struct {
 	ID      string `json:"id"`
 	Tool    string `json:"tool"`
 	Details string `json:"details"`
+	// Preview is a readable preview of the call, e.g. the diff of a file edit (optional)
+	Preview string `json:"preview,omitempty"`
 }
 
 // ApprovalRequestPayload is sent when tool execution requires user approval.
@@ -97,14 +173,44 @@ type ApprovalRequestPayload struct {
 	Targets    []ApprovalTargetPayload  `json:"targets"`
 }
 
+// ApprovalWarningPayload is sent shortly before a pending approval request expires.
+// Tool calls whose approval times out are denied.
+type ApprovalWarningPayload struct {
+	ApprovalID       string `json:"approval_id"`
+	RemainingSeconds int    `json:"remaining_seconds"`
+}
+
+// ShuttingDownPayload is sent when the server starts shutting down. The in-flight response
+// gets DrainSeconds to complete, new messages other than answers and stop are rejected.
+type ShuttingDownPayload struct {
+	DrainSeconds int `json:"drain_seconds"`
+}
+
+// ApprovalResolvedPayload is sent to all connections of a session once an approval
+// request was answered, by any of them.
+type ApprovalResolvedPayload struct {
+	ApprovalID string `json:"approval_id"`
+	// Code is "approval_timeout" when nobody answered the request in time
+	Code string `json:"code,omitempty"`
+}
+
+// QuestionPayload is sent when the model asks the user a clarifying question.
+// The handler should call SendQuestionResponse with the answer.
+type QuestionPayload struct {
+	QuestionID string `json:"question_id"`
+	Question   string `json:"question"`
+}
+
 // MessageCountPayload carries the current message count.
 type MessageCountPayload struct {
 	Count int `json:"count"`
 }
 
 // StoppedPayload is sent when a response is stopped by the user.
+// If Resumable is true, the response can be continued with Client.Resume.
 type StoppedPayload struct {
-	Message string `json:"message"`
+	Message   string `json:"message"`
+	Resumable bool   `json:"resumable"`
 }
 
 // KeptPayload is sent after a keep hook execution.
@@ -113,6 +219,54 @@ type KeptPayload struct {
 	Message  string `json:"message"`
 }
 
+// PinnedPayload is sent after a message is pinned or unpinned, with the contents of the
+// pinned messages in the order they were pinned.
+type PinnedPayload struct {
+	ChatName string   `json:"chat_name,omitempty"`
+	Message  string   `json:"message"`
+	Pinned   []string `json:"pinned"`
+}
+
+// CompactedPayload is sent after the context was compacted on request: Rounds rounds
+// were replaced by a summary, MessageCount and Tokens are the size of the context after
+// it, the tokens are estimated.
+type CompactedPayload struct {
+	ChatName     string `json:"chat_name,omitempty"`
+	Rounds       int    `json:"rounds"`
+	MessageCount int    `json:"message_count"`
+	Tokens       int    `json:"tokens"`
+}
+
+// RateLimitedPayload is received instead of a response when the server limits the chat
+// messages of the session or the responses streamed at the same time.
+type RateLimitedPayload struct {
+	Reason string `json:"reason"`
+	// RetryAfter is the number of seconds after which the message may be sent again
+	RetryAfter int `json:"retry_after"`
+}
+
+// SessionInfoPayload is received in reply to session_info. It holds the metadata of the
+// session, not its conversation.
+type SessionInfoPayload struct {
+	SessionID    string            `json:"session_id"`
+	ChatName     string            `json:"chat_name"`
+	CreatedAt    time.Time         `json:"created_at"`
+	LastActivity time.Time         `json:"last_activity"`
+	Connections  int               `json:"connections"`
+	Streaming    bool              `json:"streaming"`
+	Chats        []ChatInfoPayload `json:"chats"`
+}
+
+// ChatInfoPayload is the metadata of a chat of the session. A chat restored from the
+// server's session store is not initialized until it is selected again.
+type ChatInfoPayload struct {
+	Name         string   `json:"name"`
+	Active       bool     `json:"active"`
+	Initialized  bool     `json:"initialized"`
+	MessageCount int      `json:"message_count"`
+	Tools        []string `json:"tools"`
+}
+
 // ClearedPayload is sent after the conversation context is cleared.
 type ClearedPayload struct {
 	ChatName     string `json:"chat_name,omitempty"`
@@ -120,6 +274,43 @@ type ClearedPayload struct {
 	MessageCount int    `json:"message_count"`
 }
 
+// ToolsReloadedPayload is sent after the tools of the current chat are re-initialized.
+type ToolsReloadedPayload struct {
+	ChatName  string   `json:"chat_name,omitempty"`
+	Message   string   `json:"message"`
+	ToolCount int      `json:"tool_count"`
+	Added     []string `json:"added"`
+	Removed   []string `json:"removed"`
+}
+
+// ToolPayload describes a tool of the current chat.
+type ToolPayload struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+}
+
+// Context events carried by ContextEventPayload.
+const (
+	ContextCompressionStarted   = "compression_started"
+	ContextCompressionCompleted = "compression_completed"
+)
+
+// ContextEventPayload is sent when older rounds of the conversation start being
+// summarized and when the summary replaced them. Summary is empty when summarizing failed.
+type ContextEventPayload struct {
+	Event   string `json:"event"`
+	Rounds  int    `json:"rounds"`
+	Summary string `json:"summary,omitempty"`
+}
+
+// ToolsPayload is received in reply to list_tools and toggle_tool.
+type ToolsPayload struct {
+	ChatName string        `json:"chat_name,omitempty"`
+	Tools    []ToolPayload `json:"tools"`
+	Message  string        `json:"message,omitempty"`
+}
+
 // FilePayload represents a file attachment in a chat request.
 type FilePayload struct {
 	URL      string `json:"url"`
@@ -139,6 +330,7 @@ type ChatRequest struct {
 type ApprovalItem struct {
 	Approved bool   `json:"approved"`
 	Reason   string `json:"reason,omitempty"`
+	Always   bool   `json:"always,omitempty"` // approve the same call for the rest of the session
 }
 
 // ApprovalResponsePayload is the payload for approval_response command.
@@ -146,3 +338,39 @@ type ApprovalResponsePayload struct {
 	ApprovalID string                  `json:"approval_id"`
 	Results    map[string]ApprovalItem `json:"results"`
 }
+
+// CancelApprovalPayload is the payload for cancel_approval command.
+type CancelApprovalPayload struct {
+	ApprovalID string `json:"approval_id"`
+}
+
+// KeepPayload is the payload for keep command.
+type KeepPayload struct {
+	Label string `json:"label,omitempty"`
+}
+
+// PinPayload is the payload for pin command. Index counts from 1, 0 pins the last user
+// message or unpins all of them.
+type PinPayload struct {
+	Index int  `json:"index,omitempty"`
+	Unpin bool `json:"unpin,omitempty"`
+}
+
+// EditMessagePayload is the payload for edit_message command. Round counts the rounds of
+// the context with a user message from 0, or from the end when negative.
+type EditMessagePayload struct {
+	Round   int    `json:"round"`
+	Message string `json:"message"`
+}
+
+// ToggleToolPayload is the payload for toggle_tool command.
+type ToggleToolPayload struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+}
+
+// QuestionResponsePayload is the payload for question_response command.
+type QuestionResponsePayload struct {
+	QuestionID string `json:"question_id"`
+	Answer     string `json:"answer"`
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Arvintian/chat-agent/pkg/logger"
+	"github.com/Arvintian/chat-agent/pkg/manager"
+)
+
+// SessionStore persists web sessions so that conversations survive server restarts
+type SessionStore interface {
+	// Load returns all stored sessions
+	Load() ([]*StoredSession, error)
+	// Save writes a session, replacing the stored copy
+	Save(session *StoredSession) error
+	// Delete removes a stored session; deleting a missing session is not an error
+	Delete(sessionID string) error
+}
+
+// StoredSession is the serializable part of a web session
+type StoredSession struct {
+	ID        string                 `json:"id"`
+	ChatName  string                 `json:"chat_name"`
+	Chats     map[string]*StoredChat `json:"chats"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// StoredChat is the conversation state of a chat within a stored session
+type StoredChat struct {
+	Context manager.State `json:"context"`
+}
+
+// FileSessionStore stores each session as a JSON file in a directory. Files are
+// written to a temporary file first and renamed, so a crash or shutdown during a
+// write never leaves a half-written session behind.
+type FileSessionStore struct {
+	dir string
+}
+
+// NewFileSessionStore creates a session store in the given directory, creating it if needed
+func NewFileSessionStore(dir string) (*FileSessionStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %w", err)
+	}
+	return &FileSessionStore{dir: dir}, nil
+}
+
+// Load reads all session files. Leftover temporary files are removed and unreadable
+// files are skipped with a warning, so a single bad file does not lose the others.
+func (s *FileSessionStore) Load() ([]*StoredSession, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session directory: %w", err)
+	}
+	var sessions []*StoredSession
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			continue
+		}
+		if strings.HasPrefix(name, ".tmp-") {
+			os.Remove(filepath.Join(s.dir, name))
+			continue
+		}
+		if filepath.Ext(name) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, name))
+		if err != nil {
+			logger.Warn("store", fmt.Sprintf("Failed to read session file %s: %v", name, err))
+			continue
+		}
+		var session StoredSession
+		if err := json.Unmarshal(data, &session); err != nil || session.ID == "" {
+			logger.Warn("store", fmt.Sprintf("Skipping invalid session file %s: %v", name, err))
+			continue
+		}
+		sessions = append(sessions, &session)
+	}
+	return sessions, nil
+}
+
+// Save writes the session atomically
+func (s *FileSessionStore) Save(session *StoredSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to encode session %s: %w", session.ID, err)
+	}
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create session file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write session %s: %w", session.ID, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync session %s: %w", session.ID, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write session %s: %w", session.ID, err)
+	}
+	if err := os.Rename(tmp.Name(), s.sessionFile(session.ID)); err != nil {
+		return fmt.Errorf("failed to save session %s: %w", session.ID, err)
+	}
+	return nil
+}
+
+// Delete removes the stored session
+func (s *FileSessionStore) Delete(sessionID string) error {
+	if err := os.Remove(s.sessionFile(sessionID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete session %s: %w", sessionID, err)
+	}
+	return nil
+}
+
+// sessionFile returns the file of a session. Session IDs come from clients, so the
+// file name is derived from a hash instead of the ID itself.
+func (s *FileSessionStore) sessionFile(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json")
+}
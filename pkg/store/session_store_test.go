@@ -0,0 +1,91 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Arvintian/chat-agent/pkg/manager"
+	"github.com/cloudwego/eino/schema"
+)
+
+func testStoredSession(id string) *StoredSession {
+	return &StoredSession{
+		ID:       id,
+		ChatName: "default",
+		Chats: map[string]*StoredChat{
+			"default": {Context: manager.State{
+				Messages: [][]*schema.Message{
+					{schema.UserMessage("hello"), schema.AssistantMessage("hi", nil)},
+				},
+				CompressBuffer: [][]*schema.Message{{schema.UserMessage("earlier")}},
+			}},
+		},
+		CreatedAt: time.Now().Truncate(time.Second),
+	}
+}
+
+func TestFileSessionStoreRoundTrip(t *testing.T) {
+	s, err := NewFileSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileSessionStore() error = %v", err)
+	}
+	if err := s.Save(testStoredSession("../escape")); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	sessions, err := s.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != "../escape" {
+		t.Fatalf("Load() = %+v, want the saved session", sessions)
+	}
+	chat := sessions[0].Chats["default"]
+	if chat == nil || len(chat.Context.Messages) != 1 || chat.Context.Messages[0][1].Content != "hi" {
+		t.Errorf("chat context = %+v, want the saved rounds", chat)
+	}
+	if len(chat.Context.CompressBuffer) != 1 {
+		t.Errorf("compress buffer = %+v, want the saved round", chat.Context.CompressBuffer)
+	}
+
+	if err := s.Delete("../escape"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := s.Delete("../escape"); err != nil {
+		t.Errorf("Delete() of a missing session error = %v", err)
+	}
+	if sessions, _ := s.Load(); len(sessions) != 0 {
+		t.Errorf("Load() after Delete() = %d sessions, want 0", len(sessions))
+	}
+}
+
+func TestFileSessionStoreSkipsPartialFiles(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileSessionStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileSessionStore() error = %v", err)
+	}
+	if err := s.Save(testStoredSession("good")); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	// A temporary file left by an interrupted save and a truncated session file
+	if err := os.WriteFile(filepath.Join(dir, ".tmp-123"), []byte(`{"id":"tmp"`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{"id":"broken","chats":`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := s.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != "good" {
+		t.Errorf("Load() = %+v, want only the complete session", sessions)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".tmp-123")); !os.IsNotExist(err) {
+		t.Error("leftover temporary file was not removed")
+	}
+}
@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// QuestionInfo is the interrupt info of the ask_user tool
+type QuestionInfo struct {
+	Question   string
+	ToolCallID string
+}
+
+// QuestionAnswer is the resume data of the ask_user tool
+type QuestionAnswer struct {
+	Answer string
+}
+
+func (qi *QuestionInfo) String() string {
+	return fmt.Sprintf("Question: %s", qi.Question)
+}
+
+// AskUserArgs represents the arguments of the ask_user tool
+type AskUserArgs struct {
+	Question string `json:"question"`
+}
+
+// AskUserTool pauses the agent and asks the user a clarifying question.
+// The free-text answer is returned to the model as the tool result.
+type AskUserTool struct {
+	description string
+}
+
+func getAskUserTools(ctx context.Context, params map[string]interface{}) ([]tool.BaseTool, error) {
+	description := "Ask the user a clarifying question and wait for a free-text answer. " +
+		"Use it when the task is ambiguous or requires information only the user has, instead of guessing."
+	if desc, ok := params["description"].(string); ok && desc != "" {
+		description = desc
+	}
+	return []tool.BaseTool{&AskUserTool{description: description}}, nil
+}
+
+func (t *AskUserTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: "ask_user",
+		Desc: t.description,
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"question": {
+				Type:     schema.String,
+				Desc:     "The question to ask the user",
+				Required: true,
+			},
+		}),
+	}, nil
+}
+
+func (t *AskUserTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	wasInterrupted, _, storedQuestion := compose.GetInterruptState[string](ctx)
+	if !wasInterrupted {
+		// First time, interrupt and wait for the answer
+		var args AskUserArgs
+		if err := json.Unmarshal([]byte(argumentsInJSON), &args); err != nil {
+			return "", fmt.Errorf("failed to parse arguments: %w", err)
+		}
+		if args.Question == "" {
+			return "", fmt.Errorf("question is required")
+		}
+		return "", compose.StatefulInterrupt(ctx, &QuestionInfo{
+			Question:   args.Question,
+			ToolCallID: compose.GetToolCallID(ctx),
+		}, args.Question)
+	}
+
+	isResumeTarget, hasData, data := compose.GetResumeContext[*QuestionAnswer](ctx)
+	if !isResumeTarget {
+		// Was interrupted but not resumed, re-interrupt
+		return "", compose.StatefulInterrupt(ctx, &QuestionInfo{
+			Question:   storedQuestion,
+			ToolCallID: compose.GetToolCallID(ctx),
+		}, storedQuestion)
+	}
+
+	if !hasData || data.Answer == "" {
+		return "The user did not answer the question", nil
+	}
+	return data.Answer, nil
+}
+
+// Ensure AskUserTool implements tool.InvokableTool
+var _ tool.InvokableTool = (*AskUserTool)(nil)
+
+func init() {
+	schema.Register[*QuestionInfo]()
+}
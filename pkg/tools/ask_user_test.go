@@ -0,0 +1,137 @@
+package tools
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// memoryCheckPointStore is an in-memory compose.CheckPointStore for tests
+type memoryCheckPointStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func (s *memoryCheckPointStore) Get(ctx context.Context, checkPointID string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.data[checkPointID]
+	return data, ok, nil
+}
+
+func (s *memoryCheckPointStore) Set(ctx context.Context, checkPointID string, checkPoint []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[checkPointID] = checkPoint
+	return nil
+}
+
+// newAskUserRunnable compiles a graph that runs the ask_user tool for an assistant tool call
+func newAskUserRunnable(t *testing.T) compose.Runnable[*schema.Message, []*schema.Message] {
+	t.Helper()
+	ctx := context.Background()
+	toolsNode, err := compose.NewToolNode(ctx, &compose.ToolsNodeConfig{
+		Tools: []tool.BaseTool{&AskUserTool{description: "ask"}},
+	})
+	if err != nil {
+		t.Fatalf("NewToolNode() error = %v", err)
+	}
+	g := compose.NewGraph[*schema.Message, []*schema.Message]()
+	if err := g.AddToolsNode("tools", toolsNode); err != nil {
+		t.Fatalf("AddToolsNode() error = %v", err)
+	}
+	if err := g.AddEdge(compose.START, "tools"); err != nil {
+		t.Fatalf("AddEdge() error = %v", err)
+	}
+	if err := g.AddEdge("tools", compose.END); err != nil {
+		t.Fatalf("AddEdge() error = %v", err)
+	}
+	runnable, err := g.Compile(ctx, compose.WithCheckPointStore(&memoryCheckPointStore{data: map[string][]byte{}}))
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	return runnable
+}
+
+func askUserCall(question string) *schema.Message {
+	return schema.AssistantMessage("", []schema.ToolCall{{
+		ID:   "call_1",
+		Type: "function",
+		Function: schema.FunctionCall{
+			Name:      "ask_user",
+			Arguments: `{"question":"` + question + `"}`,
+		},
+	}})
+}
+
+// interruptQuestion runs the graph until ask_user interrupts and returns the interrupt context
+func interruptQuestion(t *testing.T, runnable compose.Runnable[*schema.Message, []*schema.Message], checkPointID string) *compose.InterruptCtx {
+	t.Helper()
+	_, err := runnable.Invoke(context.Background(), askUserCall("which file?"), compose.WithCheckPointID(checkPointID))
+	info, ok := compose.ExtractInterruptInfo(err)
+	if !ok {
+		t.Fatalf("Invoke() error = %v, want an interrupt", err)
+	}
+	for _, ic := range info.InterruptContexts {
+		if !ic.IsRootCause {
+			continue
+		}
+		question, ok := ic.Info.(*QuestionInfo)
+		if !ok {
+			t.Fatalf("interrupt info = %T, want *QuestionInfo", ic.Info)
+		}
+		if question.Question != "which file?" || question.ToolCallID != "call_1" {
+			t.Fatalf("interrupt info = %+v, want question %q for call_1", question, "which file?")
+		}
+		return ic
+	}
+	t.Fatal("no root cause interrupt context")
+	return nil
+}
+
+func TestAskUserInterruptAndResume(t *testing.T) {
+	runnable := newAskUserRunnable(t)
+	ic := interruptQuestion(t, runnable, "answered")
+
+	ctx := compose.ResumeWithData(context.Background(), ic.ID, &QuestionAnswer{Answer: "main.go"})
+	out, err := runnable.Invoke(ctx, askUserCall("which file?"), compose.WithCheckPointID("answered"))
+	if err != nil {
+		t.Fatalf("resume Invoke() error = %v", err)
+	}
+	if len(out) != 1 || out[0].Content != "main.go" {
+		t.Fatalf("resume output = %+v, want a single tool message with the answer", out)
+	}
+}
+
+func TestAskUserResumeWithoutAnswer(t *testing.T) {
+	runnable := newAskUserRunnable(t)
+	ic := interruptQuestion(t, runnable, "empty")
+
+	ctx := compose.ResumeWithData(context.Background(), ic.ID, &QuestionAnswer{})
+	out, err := runnable.Invoke(ctx, askUserCall("which file?"), compose.WithCheckPointID("empty"))
+	if err != nil {
+		t.Fatalf("resume Invoke() error = %v", err)
+	}
+	if len(out) != 1 || out[0].Content != "The user did not answer the question" {
+		t.Fatalf("resume output = %+v, want the no-answer result", out)
+	}
+}
+
+func TestAskUserReinterruptsWhenNotResumed(t *testing.T) {
+	runnable := newAskUserRunnable(t)
+	interruptQuestion(t, runnable, "pending")
+
+	// Running again without resume data must ask the stored question again
+	interruptQuestion(t, runnable, "pending")
+}
+
+func TestAskUserRequiresQuestion(t *testing.T) {
+	tool := &AskUserTool{}
+	if _, err := tool.InvokableRun(context.Background(), `{"question":""}`); err == nil {
+		t.Error("InvokableRun() error = nil for an empty question")
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"io"
 	"os/exec"
-	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -27,6 +26,9 @@ type taskPlatform interface {
 	killProcess(cmd *exec.Cmd) error
 }
 
+// BackgroundTask is a command running in the background. Status, ExitCode and EndTime
+// change when the task ends and are guarded by mu, read them with State. Output and
+// Stderr guard themselves.
 type BackgroundTask struct {
 	ID         string
 	Command    string
@@ -34,8 +36,8 @@ type BackgroundTask struct {
 	StartTime  time.Time
 	EndTime    *time.Time
 	Status     TaskStatus
-	Output     strings.Builder
-	Stderr     strings.Builder
+	Output     *OutputBuffer
+	Stderr     *OutputBuffer
 	ExitCode   *int
 	Process    *exec.Cmd
 	CancelFunc context.CancelFunc
@@ -43,11 +45,19 @@ type BackgroundTask struct {
 	platform   taskPlatform
 }
 
+// TaskState is a consistent snapshot of the fields of a task that change when it ends
+type TaskState struct {
+	Status   TaskStatus
+	ExitCode *int
+	EndTime  *time.Time
+}
+
 type BackgroundTaskManager struct {
-	tasks    map[string]*BackgroundTask
-	taskID   atomic.Uint64
-	mu       sync.RWMutex
-	outputMu sync.Mutex
+	tasks  map[string]*BackgroundTask
+	taskID atomic.Uint64
+	mu     sync.RWMutex
+	// MaxOutputBytes is the output kept per stream of a task, DefaultMaxTaskOutput if not positive
+	MaxOutputBytes int
 }
 
 var (
@@ -79,6 +89,8 @@ func (tm *BackgroundTaskManager) StartTask(command, workdir string) (*Background
 		WorkingDir: workdir,
 		StartTime:  time.Now(),
 		Status:     TaskStatusRunning,
+		Output:     NewOutputBuffer(tm.MaxOutputBytes),
+		Stderr:     NewOutputBuffer(tm.MaxOutputBytes),
 		CancelFunc: cancel,
 	}
 
@@ -128,11 +140,11 @@ func (tm *BackgroundTaskManager) monitorTask(ctx context.Context, task *Backgrou
 
 	go func() {
 		defer wg.Done()
+		// ScanLines drops the trailing \r of Windows line endings, so background
+		// output always uses \n
 		scanner := bufio.NewScanner(stdout)
 		for scanner.Scan() {
-			tm.outputMu.Lock()
 			task.Output.WriteString(scanner.Text() + "\n")
-			tm.outputMu.Unlock()
 		}
 	}()
 
@@ -140,9 +152,7 @@ func (tm *BackgroundTaskManager) monitorTask(ctx context.Context, task *Backgrou
 		defer wg.Done()
 		scanner := bufio.NewScanner(stderr)
 		for scanner.Scan() {
-			tm.outputMu.Lock()
 			task.Stderr.WriteString(scanner.Text() + "\n")
-			tm.outputMu.Unlock()
 		}
 	}()
 
@@ -242,7 +252,7 @@ func (tm *BackgroundTaskManager) RemoveTask(id string) error {
 		return fmt.Errorf("task not found: %s", id)
 	}
 
-	if task.Status == TaskStatusRunning {
+	if task.State().Status == TaskStatusRunning {
 		tm.mu.Unlock()
 		if err := tm.killTaskInternal(id); err != nil {
 			return err
@@ -259,6 +269,11 @@ func (tm *BackgroundTaskManager) RemoveTask(id string) error {
 }
 
 func (tm *BackgroundTaskManager) GetTaskOutput(id string, follow bool) (<-chan string, error) {
+	return tm.GetTaskOutputContext(context.Background(), id, follow)
+}
+
+// GetTaskOutputContext is GetTaskOutput, following the output only until ctx is done
+func (tm *BackgroundTaskManager) GetTaskOutputContext(ctx context.Context, id string, follow bool) (<-chan string, error) {
 	task, ok := tm.GetTask(id)
 	if !ok {
 		return nil, fmt.Errorf("task not found: %s", id)
@@ -269,55 +284,59 @@ func (tm *BackgroundTaskManager) GetTaskOutput(id string, follow bool) (<-chan s
 	go func() {
 		defer close(ch)
 
-		stdoutPos := 0
-		stderrPos := 0
-		task.mu.Lock()
-		status := task.Status
-		task.mu.Unlock()
+		// Positions are offsets in all the output of a stream, output dropped from the
+		// buffer before it was read is reported with a notice
+		var stdoutPos, stderrPos int64
 
 		for {
-			task.mu.Lock()
-			stdoutLen := task.Output.Len()
-			stderrLen := task.Stderr.Len()
-			task.mu.Unlock()
-
-			if stdoutLen > stdoutPos {
-				task.mu.Lock()
-				stdoutContent := task.Output.String()[stdoutPos:]
-				task.mu.Unlock()
+			// The status is read before the output, so the output written before the
+			// task ended is always read
+			status := task.State().Status
+
+			if content, next, dropped := task.Output.ReadFrom(stdoutPos); next > stdoutPos {
+				if dropped > 0 {
+					content = truncationNotice(dropped) + content
+				}
 				select {
-				case ch <- stdoutContent:
-					stdoutPos = stdoutLen
+				case ch <- content:
+					stdoutPos = next
 				default:
 				}
 			}
 
-			if stderrLen > stderrPos {
-				task.mu.Lock()
-				stderrContent := task.Stderr.String()[stderrPos:]
-				task.mu.Unlock()
+			if content, next, dropped := task.Stderr.ReadFrom(stderrPos); next > stderrPos {
+				if dropped > 0 {
+					content = truncationNotice(dropped) + content
+				}
 				select {
-				case ch <- "STDERR: " + stderrContent:
-					stderrPos = stderrLen
+				case ch <- "STDERR: " + content:
+					stderrPos = next
 				default:
 				}
 			}
 
-			task.mu.Lock()
-			status = task.Status
-			task.mu.Unlock()
-
 			if status != TaskStatusRunning || !follow {
 				break
 			}
 
-			time.Sleep(100 * time.Millisecond)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(100 * time.Millisecond):
+			}
 		}
 	}()
 
 	return ch, nil
 }
 
+// State returns the status, exit code and end time of the task
+func (t *BackgroundTask) State() TaskState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return TaskState{Status: t.Status, ExitCode: t.ExitCode, EndTime: t.EndTime}
+}
+
 func (t *BackgroundTask) GetDuration() string {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -329,12 +348,17 @@ func (t *BackgroundTask) GetDuration() string {
 	return end.Sub(t.StartTime).String()
 }
 
+// GetOutputString returns the kept output of the task, each stream starting with a
+// notice when its earlier output was dropped
 func (t *BackgroundTask) GetOutputString() string {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-
 	output := t.Output.String()
+	if dropped := t.Output.Dropped(); dropped > 0 {
+		output = truncationNotice(dropped) + output
+	}
 	stderr := t.Stderr.String()
+	if dropped := t.Stderr.Dropped(); dropped > 0 {
+		stderr = truncationNotice(dropped) + stderr
+	}
 
 	if stderr != "" {
 		return output + "\nSTDERR:\n" + stderr
@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Run with -race: the task writes its output and ends while it is read
+func TestBackgroundTaskConcurrentAccess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+	tm := NewBackgroundTaskManager()
+	tm.MaxOutputBytes = 256
+	task, err := tm.StartTask("for i in $(seq 1 200); do echo line $i; echo err $i >&2; done; exit 2", "")
+	if err != nil {
+		t.Fatalf("StartTask() error = %v", err)
+	}
+	defer tm.RemoveTask(task.ID)
+	bg := &RunBackgroundCommandTool{TaskManager: tm}
+
+	var wg sync.WaitGroup
+	for _, action := range []string{"list", "show", "output"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task.State().Status == TaskStatusRunning {
+				if _, err := bg.InvokableRun(context.Background(), `{"action":"`+action+`","task_id":"`+task.ID+`"}`); err != nil {
+					t.Errorf("%s error = %v", action, err)
+					return
+				}
+				task.GetOutputString()
+				task.GetDuration()
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		output, err := tm.GetTaskOutput(task.ID, true)
+		if err != nil {
+			t.Errorf("GetTaskOutput() error = %v", err)
+			return
+		}
+		for range output {
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("task did not end")
+	}
+
+	state := task.State()
+	if state.Status != TaskStatusFailed || state.ExitCode == nil || *state.ExitCode != 2 || state.EndTime == nil {
+		t.Errorf("State() = %+v, want failed with exit code 2", state)
+	}
+	if output := task.GetOutputString(); !strings.Contains(output, "line 200\n") || !strings.Contains(output, "err 200\n") {
+		t.Errorf("GetOutputString() = %q, want the last lines of both streams", output)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os/exec"
 	"runtime"
 	"strings"
@@ -18,7 +19,8 @@ import (
 const DEFAULT_CMD_TIMEOUT = 5
 
 func getCommandTools(ctx context.Context, params map[string]interface{}) ([]tool.BaseTool, error) {
-	var cfg RunTerminalCommandTool
+	// Newlines are normalized unless explicitly disabled
+	cfg := RunTerminalCommandTool{NormalizeNewlines: true}
 	bts, err := json.Marshal(params)
 	if err != nil {
 		return nil, err
@@ -30,7 +32,9 @@ func getCommandTools(ctx context.Context, params map[string]interface{}) ([]tool
 		cfg.Timeout = DEFAULT_CMD_TIMEOUT
 	}
 
+	// The background tasks belong to the session of the tools, they are killed when it closes
 	tm := NewBackgroundTaskManager()
+	tm.MaxOutputBytes = cfg.MaxBackgroundOutput
 
 	if v, ok := ctx.Value("cleanup").(*utils.CleanupRegistry); ok {
 		v.Register(func() {
@@ -40,9 +44,12 @@ func getCommandTools(ctx context.Context, params map[string]interface{}) ([]tool
 		})
 	}
 	cmdTool := RunTerminalCommandTool{
-		WorkingDir:  cfg.WorkingDir,
-		Timeout:     time.Duration(cfg.Timeout) * time.Second,
-		TaskManager: tm,
+		WorkingDir:        cfg.WorkingDir,
+		Timeout:           time.Duration(cfg.Timeout) * time.Second,
+		NormalizeNewlines: cfg.NormalizeNewlines,
+		AllowedCommands:   cfg.AllowedCommands,
+		DeniedCommands:    cfg.DeniedCommands,
+		TaskManager:       tm,
 	}
 	cmdBgTool := RunBackgroundCommandTool{
 		TaskManager: tm,
@@ -51,10 +58,19 @@ func getCommandTools(ctx context.Context, params map[string]interface{}) ([]tool
 }
 
 type RunTerminalCommandTool struct {
-	WorkingDir      string        `json:"workDir"`
-	Timeout         time.Duration `json:"timeout"`
-	AllowedCommands []string
-	TaskManager     *BackgroundTaskManager
+	WorkingDir string        `json:"workDir"`
+	Timeout    time.Duration `json:"timeout"`
+	// AllowedCommands are the command prefixes that may run, empty allows all commands
+	AllowedCommands []string `json:"allowedCommands"`
+	// DeniedCommands are the command prefixes that never run, they take precedence over AllowedCommands
+	DeniedCommands []string `json:"deniedCommands"`
+	// NormalizeNewlines converts \r\n in the command output to \n, so output of
+	// PowerShell on Windows looks the same as on other platforms
+	NormalizeNewlines bool `json:"normalizeNewlines"`
+	// MaxBackgroundOutput is the output in bytes kept per stream of a background task,
+	// older output is dropped
+	MaxBackgroundOutput int `json:"maxBackgroundOutput"`
+	TaskManager         *BackgroundTaskManager
 }
 
 type RunTerminalCommandArgs struct {
@@ -67,7 +83,7 @@ func (t *RunTerminalCommandTool) Info(ctx context.Context) (*schema.ToolInfo, er
 	return &schema.ToolInfo{
 		Name: "cmd",
 		Desc: fmt.Sprintf(`Execute a terminal command, wait exit and return the output, bash on Unix, PowerShell on Windows, current system is %s.
-Long-running tasks cannot be executed; they will timeout after %v and be killed. Use background=true to run commands in the background, then use the "cmd_bg" tool to manage background tasks (list, show, output, remove).
+Long-running tasks cannot be executed; they will timeout after %v and be killed. Use background=true to run commands in the background, then use the "cmd_bg" tool to manage background tasks (list, show, output, wait, remove).
 `, runtime.GOOS, t.Timeout),
 		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
 			"command": {
@@ -100,18 +116,9 @@ func (t *RunTerminalCommandTool) InvokableRun(ctx context.Context, argumentsInJS
 		return fmt.Sprintf("command is required"), nil
 	}
 
-	// Check allowed commands if configured
-	if len(t.AllowedCommands) > 0 {
-		allowed := false
-		for _, prefix := range t.AllowedCommands {
-			if strings.HasPrefix(args.Command, prefix) {
-				allowed = true
-				break
-			}
-		}
-		if !allowed {
-			return "", fmt.Errorf("command not allowed: %s", args.Command)
-		}
+	// Check allowed and denied commands if configured
+	if msg := t.checkCommand(args.Command); msg != "" {
+		return msg, nil
 	}
 
 	// Determine working directory
@@ -139,6 +146,11 @@ func (t *RunTerminalCommandTool) InvokableRun(ctx context.Context, argumentsInJS
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
+	// Stream the output lines to the client while the command runs
+	progress := newProgressWriter(ctx)
+	if progress != nil {
+		cmd.Stdout = io.MultiWriter(&stdout, progress)
+	}
 
 	// cmd run and wait
 	err := cmd.Start()
@@ -156,6 +168,9 @@ func (t *RunTerminalCommandTool) InvokableRun(ctx context.Context, argumentsInJS
 		err = <-done
 		err = fmt.Errorf("command timed out or context canceled, process killed. %v", err)
 	}
+	if progress != nil {
+		progress.Flush()
+	}
 
 	// Build result
 	var result strings.Builder
@@ -182,9 +197,53 @@ func (t *RunTerminalCommandTool) InvokableRun(ctx context.Context, argumentsInJS
 		return "(command completed with no output)", nil
 	}
 
+	if t.NormalizeNewlines {
+		return normalizeNewlines(result.String()), nil
+	}
 	return result.String(), nil
 }
 
+// commandSeparators split a command line into the commands it runs
+var commandSeparators = strings.NewReplacer("&&", "\n", "||", "\n", ";", "\n", "|", "\n")
+
+// checkCommand applies the configured denied and allowed command prefixes to every
+// command of a command line. It returns why the command line may not run, or "" if it may.
+func (t *RunTerminalCommandTool) checkCommand(commandLine string) string {
+	if len(t.DeniedCommands) == 0 && len(t.AllowedCommands) == 0 {
+		return ""
+	}
+	for _, command := range strings.Split(commandSeparators.Replace(commandLine), "\n") {
+		command = strings.TrimSpace(command)
+		if command == "" {
+			continue
+		}
+		if prefix, ok := matchCommandPrefix(command, t.DeniedCommands); ok {
+			return fmt.Sprintf("command denied: %q matches the denied command %q configured for this tool", command, prefix)
+		}
+		if len(t.AllowedCommands) > 0 {
+			if _, ok := matchCommandPrefix(command, t.AllowedCommands); !ok {
+				return fmt.Sprintf("command not allowed: %q does not start with any allowed command configured for this tool (%s)", command, strings.Join(t.AllowedCommands, ", "))
+			}
+		}
+	}
+	return ""
+}
+
+// matchCommandPrefix returns the first prefix the command starts with
+func matchCommandPrefix(command string, prefixes []string) (string, bool) {
+	for _, prefix := range prefixes {
+		if prefix != "" && strings.HasPrefix(command, prefix) {
+			return prefix, true
+		}
+	}
+	return "", false
+}
+
+// normalizeNewlines converts Windows line endings to \n
+func normalizeNewlines(s string) string {
+	return strings.ReplaceAll(s, "\r\n", "\n")
+}
+
 func (t *RunTerminalCommandTool) runInBackground(command, workdir string) (string, error) {
 	task, err := t.TaskManager.StartTask(command, workdir)
 	if err != nil {
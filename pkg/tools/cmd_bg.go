@@ -4,19 +4,29 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/schema"
 )
 
+// DEFAULT_WAIT_TIMEOUT is the time in seconds the wait action follows the output of a task
+const DEFAULT_WAIT_TIMEOUT = 30
+
+// waitContextLines is the number of output lines returned before a matching line
+const waitContextLines = 10
+
 type RunBackgroundCommandTool struct {
 	TaskManager *BackgroundTaskManager
 }
 
 type RunBackgroundCommandArgs struct {
-	Action string `json:"action"`
-	TaskID string `json:"task_id,omitempty"`
+	Action  string  `json:"action"`
+	TaskID  string  `json:"task_id,omitempty"`
+	Until   string  `json:"until,omitempty"`
+	Timeout float64 `json:"timeout,omitempty"`
 }
 
 func (t *RunBackgroundCommandTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
@@ -26,16 +36,27 @@ func (t *RunBackgroundCommandTool) Info(ctx context.Context) (*schema.ToolInfo,
 		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
 			"action": {
 				Type: schema.String,
-				Desc: `Action to perform: list, show, output, remove.
+				Desc: `Action to perform: list, show, output, wait, remove.
 - list: List all background tasks
 - show: Show details of a task
 - output: Get output of a task
+- wait: Follow the output of a task until a line matches the until pattern, e.g. a dev server being ready, returning the matching lines. Returns early if the task exits.
 - remove: Remove/kill a task`,
 				Required: true,
 			},
 			"task_id": {
 				Type:     schema.String,
-				Desc:     "Task ID (required for show, output, remove actions)",
+				Desc:     "Task ID (required for show, output, wait, remove actions)",
+				Required: false,
+			},
+			"until": {
+				Type:     schema.String,
+				Desc:     "Regular expression a line of output must match (required for wait action), e.g. 'Listening on|ready'",
+				Required: false,
+			},
+			"timeout": {
+				Type:     schema.Number,
+				Desc:     fmt.Sprintf("Seconds to wait for the pattern (wait action), default %d", DEFAULT_WAIT_TIMEOUT),
 				Required: false,
 			},
 		}),
@@ -68,7 +89,8 @@ func (t *RunBackgroundCommandTool) InvokableRun(ctx context.Context, argumentsIn
 		result.WriteString("\n")
 
 		for _, task := range tasks {
-			status := string(task.Status)
+			state := task.State()
+			status := string(state.Status)
 			duration := task.GetDuration()
 			command := task.Command
 			if len(command) > 30 {
@@ -76,8 +98,8 @@ func (t *RunBackgroundCommandTool) InvokableRun(ctx context.Context, argumentsIn
 			}
 
 			exitCode := "N/A"
-			if task.ExitCode != nil {
-				exitCode = fmt.Sprintf("%d", *task.ExitCode)
+			if state.ExitCode != nil {
+				exitCode = fmt.Sprintf("%d", *state.ExitCode)
 			}
 
 			result.WriteString(fmt.Sprintf("%-6s %-10s %-20s %-15s %-30s\n", task.ID, status, duration, exitCode, command))
@@ -97,6 +119,23 @@ func (t *RunBackgroundCommandTool) InvokableRun(ctx context.Context, argumentsIn
 		}
 		return t.formatTaskOutput(args.TaskID)
 
+	case "wait":
+		if args.TaskID == "" {
+			return "", fmt.Errorf("task_id is required for wait action")
+		}
+		if args.Until == "" {
+			return "", fmt.Errorf("until is required for wait action")
+		}
+		pattern, err := regexp.Compile(args.Until)
+		if err != nil {
+			return fmt.Sprintf("invalid until pattern: %v", err), nil
+		}
+		timeout := time.Duration(DEFAULT_WAIT_TIMEOUT) * time.Second
+		if args.Timeout > 0 {
+			timeout = time.Duration(args.Timeout * float64(time.Second))
+		}
+		return t.waitForOutput(ctx, args.TaskID, pattern, timeout)
+
 	case "remove", "rm", "kill", "stop":
 		if args.TaskID == "" {
 			return "", fmt.Errorf("task_id is required for remove action")
@@ -105,16 +144,17 @@ func (t *RunBackgroundCommandTool) InvokableRun(ctx context.Context, argumentsIn
 		if !ok {
 			return "", fmt.Errorf("task not found: %s", args.TaskID)
 		}
+		running := task.State().Status == TaskStatusRunning
 		if err := t.TaskManager.RemoveTask(args.TaskID); err != nil {
 			return "", fmt.Errorf("failed to remove task: %w", err)
 		}
-		if task.Status == TaskStatusRunning {
+		if running {
 			return fmt.Sprintf("Task %s killed and removed", args.TaskID), nil
 		}
 		return fmt.Sprintf("Task %s removed", args.TaskID), nil
 
 	default:
-		return "", fmt.Errorf("unknown action: %s\nAvailable actions: list, show, output, remove", args.Action)
+		return "", fmt.Errorf("unknown action: %s\nAvailable actions: list, show, output, wait, remove", args.Action)
 	}
 }
 
@@ -126,20 +166,21 @@ func (t *RunBackgroundCommandTool) formatTaskDetails(taskID string) (string, err
 		return "", fmt.Errorf("task not found: %s", taskID)
 	}
 
+	state := task.State()
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("Task ID: %s\n", task.ID))
-	sb.WriteString(fmt.Sprintf("Status: %s\n", task.Status))
+	sb.WriteString(fmt.Sprintf("Status: %s\n", state.Status))
 	sb.WriteString(fmt.Sprintf("Command: %s\n", task.Command))
 	sb.WriteString(fmt.Sprintf("Working Directory: %s\n", task.WorkingDir))
 	sb.WriteString(fmt.Sprintf("Start Time: %s\n", task.StartTime.Format("2006-01-02 15:04:05")))
-	if task.EndTime != nil {
-		sb.WriteString(fmt.Sprintf("End Time: %s\n", task.EndTime.Format("2006-01-02 15:04:05")))
+	if state.EndTime != nil {
+		sb.WriteString(fmt.Sprintf("End Time: %s\n", state.EndTime.Format("2006-01-02 15:04:05")))
 		sb.WriteString(fmt.Sprintf("Duration: %s\n", task.GetDuration()))
 	} else {
 		sb.WriteString(fmt.Sprintf("Running for: %s\n", task.GetDuration()))
 	}
-	if task.ExitCode != nil {
-		sb.WriteString(fmt.Sprintf("Exit Code: %d\n", *task.ExitCode))
+	if state.ExitCode != nil {
+		sb.WriteString(fmt.Sprintf("Exit Code: %d\n", *state.ExitCode))
 	}
 
 	return sb.String(), nil
@@ -156,5 +197,113 @@ func (t *RunBackgroundCommandTool) formatTaskOutput(taskID string) (string, erro
 		output = "(no output yet)"
 	}
 
+	if dropped := task.Output.Dropped() + task.Stderr.Dropped(); dropped > 0 {
+		return fmt.Sprintf("Task %s Output (truncated, %d bytes of earlier output dropped, showing the most recent output):\n%s\n", taskID, dropped, output), nil
+	}
 	return fmt.Sprintf("Task %s Output:\n%s\n", taskID, output), nil
 }
+
+// waitForOutput follows the output of a task until a line matches pattern, returning the
+// line with the lines before it. It returns early when the task exits, with its status
+// and last lines, or when the timeout passes.
+func (t *RunBackgroundCommandTool) waitForOutput(ctx context.Context, taskID string, pattern *regexp.Regexp, timeout time.Duration) (string, error) {
+	task, ok := t.TaskManager.GetTask(taskID)
+	if !ok {
+		return "", fmt.Errorf("task not found: %s", taskID)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	output, err := t.TaskManager.GetTaskOutputContext(waitCtx, taskID, true)
+	if err != nil {
+		return "", err
+	}
+
+	scanner := &outputLineScanner{pattern: pattern}
+	for {
+		select {
+		case chunk, ok := <-output:
+			if ok {
+				if scanner.scan(chunk) {
+					return fmt.Sprintf("Task %s output matched %q:\n%s\n", taskID, pattern, scanner.context()), nil
+				}
+				continue
+			}
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			if waitCtx.Err() == nil {
+				// The task exited, its last line may not end with a newline
+				if scanner.flush() {
+					return fmt.Sprintf("Task %s output matched %q:\n%s\n", taskID, pattern, scanner.context()), nil
+				}
+				state := task.State()
+				code := "N/A"
+				if state.ExitCode != nil {
+					code = fmt.Sprintf("%d", *state.ExitCode)
+				}
+				return fmt.Sprintf("Task %s exited before its output matched %q (status: %s, exit code: %s). Last output:\n%s\n", taskID, pattern, state.Status, code, scanner.context()), nil
+			}
+			output = nil
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			return fmt.Sprintf("Timed out after %s waiting for the output of task %s to match %q, the task is still running. Last output:\n%s\n", timeout, taskID, pattern, scanner.context()), nil
+		}
+	}
+}
+
+// outputLineScanner matches the lines of task output chunks, which are not split on
+// lines: the unterminated end of each stream is kept until the rest of its line arrives
+type outputLineScanner struct {
+	pattern *regexp.Regexp
+	// recent are the last lines scanned, ending with the matching line
+	recent  []string
+	partial [2]string
+}
+
+// scan scans the lines of an output chunk, stderr chunks start with "STDERR: ", and
+// reports whether a line matched
+func (s *outputLineScanner) scan(chunk string) bool {
+	stream, prefix := 0, ""
+	if rest, ok := strings.CutPrefix(chunk, "STDERR: "); ok {
+		stream, prefix, chunk = 1, "STDERR: ", rest
+	}
+	lines := strings.Split(s.partial[stream]+chunk, "\n")
+	s.partial[stream] = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		if s.add(prefix, line) {
+			return true
+		}
+	}
+	return false
+}
+
+// flush scans the unterminated lines and reports whether one matched
+func (s *outputLineScanner) flush() bool {
+	for stream, prefix := range []string{"", "STDERR: "} {
+		line := s.partial[stream]
+		s.partial[stream] = ""
+		if line != "" && s.add(prefix, line) {
+			return true
+		}
+	}
+	return false
+}
+
+// add keeps a line of the stream with prefix and reports whether it matches
+func (s *outputLineScanner) add(prefix, line string) bool {
+	s.recent = append(s.recent, prefix+truncateLine(line))
+	if len(s.recent) > waitContextLines+1 {
+		s.recent = s.recent[1:]
+	}
+	return s.pattern.MatchString(line)
+}
+
+func (s *outputLineScanner) context() string {
+	if len(s.recent) == 0 {
+		return "(no output)"
+	}
+	return strings.Join(s.recent, "\n")
+}
@@ -0,0 +1,52 @@
+package tools
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackgroundWait(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+	tests := []struct {
+		name    string
+		command string
+		until   string
+		want    []string
+	}{
+		{"match", "echo starting; sleep 0.2; echo 'Listening on :8080'; sleep 30", "Listening on", []string{"output matched", "starting\nListening on :8080"}},
+		{"stderr", "echo 'server ready' >&2; sleep 30", "^server ready$", []string{"output matched", "STDERR: server ready"}},
+		{"unterminated", "printf 'done'", "done", []string{"output matched", "done"}},
+		{"exited", "echo 'build failed'; exit 3", "ready", []string{"exited before", "status: failed, exit code: 3", "build failed"}},
+		{"timeout", "echo waiting; sleep 30", "ready", []string{"Timed out after 500ms", "still running", "waiting"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm := NewBackgroundTaskManager()
+			task, err := tm.StartTask(tt.command, "")
+			if err != nil {
+				t.Fatalf("StartTask() error = %v", err)
+			}
+			defer tm.RemoveTask(task.ID)
+			bg := &RunBackgroundCommandTool{TaskManager: tm}
+
+			start := time.Now()
+			result, err := bg.InvokableRun(context.Background(), `{"action":"wait","task_id":"`+task.ID+`","until":"`+tt.until+`","timeout":0.5}`)
+			if err != nil {
+				t.Fatalf("InvokableRun() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(result, want) {
+					t.Errorf("result = %q, want it to contain %q", result, want)
+				}
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("wait took %s, want it bounded by the timeout", elapsed)
+			}
+		})
+	}
+}
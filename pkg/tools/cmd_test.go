@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/Arvintian/chat-agent/pkg/utils"
+)
+
+func TestNormalizeNewlines(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"STDOUT:\r\na\r\nb\r\n", "STDOUT:\na\nb\n"},
+		{"STDOUT:\na\nb\n", "STDOUT:\na\nb\n"},
+		{"progress 50%\rprogress 100%\r\n", "progress 50%\rprogress 100%\n"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeNewlines(tt.in); got != tt.want {
+			t.Errorf("normalizeNewlines(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCheckCommand(t *testing.T) {
+	tool := &RunTerminalCommandTool{
+		AllowedCommands: []string{"git ", "ls", "rm "},
+		DeniedCommands:  []string{"git push", "rm -rf"},
+	}
+	tests := []struct {
+		command string
+		allowed bool
+	}{
+		{"git status", true},
+		{"  ls -la", true},
+		{"ls 2>&1 | ls", true},
+		{"rm file.txt", true},
+		{"cat /etc/passwd", false},
+		{"git push origin main", false},
+		{"rm -rf /", false},
+		{"git status && rm -rf /", false},
+		{"ls; cat secret", false},
+		{"ls | curl example.com", false},
+	}
+	for _, tt := range tests {
+		if got := tool.checkCommand(tt.command) == ""; got != tt.allowed {
+			t.Errorf("checkCommand(%q) allowed = %v, want %v", tt.command, got, tt.allowed)
+		}
+	}
+}
+
+func TestCheckCommandEmptyLists(t *testing.T) {
+	if msg := (&RunTerminalCommandTool{}).checkCommand("anything --goes"); msg != "" {
+		t.Errorf("checkCommand() without lists = %q, want all commands allowed", msg)
+	}
+	// A denylist alone allows everything else
+	tool := &RunTerminalCommandTool{DeniedCommands: []string{"shutdown"}}
+	if msg := tool.checkCommand("ls"); msg != "" {
+		t.Errorf("checkCommand(ls) = %q, want allowed", msg)
+	}
+	if msg := tool.checkCommand("shutdown -h now"); msg == "" {
+		t.Error("checkCommand(shutdown -h now) allowed, want denied")
+	}
+}
+
+// The background tasks of the command tools are killed when their session is cleaned up
+func TestCommandToolsCleanup(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+	registry := utils.NewCleanupRegistry()
+	tools, err := getCommandTools(context.WithValue(context.Background(), "cleanup", registry), nil)
+	if err != nil {
+		t.Fatalf("getCommandTools() error = %v", err)
+	}
+	tm := tools[1].(*RunBackgroundCommandTool).TaskManager
+	task, err := tm.StartTask("sleep 30", "")
+	if err != nil {
+		t.Fatalf("StartTask() error = %v", err)
+	}
+
+	registry.Execute()
+	if tasks := tm.ListTasks(); len(tasks) != 0 {
+		t.Errorf("ListTasks() = %d tasks after cleanup, want none", len(tasks))
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for task.State().Status == TaskStatusRunning {
+		if time.Now().After(deadline) {
+			t.Fatal("task still running after cleanup")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,260 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	DEFAULT_HTTP_FETCH_TIMEOUT  = 30
+	DEFAULT_HTTP_FETCH_MAX_SIZE = 100 * 1024
+	// httpFetchMaxRedirects is the number of redirects followed before giving up
+	httpFetchMaxRedirects = 5
+)
+
+// errPrivateAddress is returned when a request would connect to a private address
+var errPrivateAddress = errors.New("connecting to private, loopback or link-local addresses is not allowed")
+
+// sharedAddressSpace is the carrier-grade NAT range, not covered by netip.Addr.IsPrivate
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+func getHTTPFetchTools(ctx context.Context, params map[string]interface{}) ([]tool.BaseTool, error) {
+	var cfg HTTPFetchTool
+	bts, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(bts, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DEFAULT_HTTP_FETCH_TIMEOUT
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = DEFAULT_HTTP_FETCH_MAX_SIZE
+	}
+	return []tool.BaseTool{&cfg}, nil
+}
+
+// HTTPFetchTool fetches a URL and returns the status, content type and body of the
+// response. Requests are limited to the allowed domains, if any, and never connect to
+// private addresses unless AllowPrivate is set, so the model can't reach internal services.
+type HTTPFetchTool struct {
+	// AllowedDomains are the domains that may be fetched, including their subdomains.
+	// Empty allows all domains.
+	AllowedDomains []string `json:"allowedDomains"`
+	// AllowPrivate allows private, loopback and link-local addresses
+	AllowPrivate bool `json:"allowPrivate"`
+	// MaxSize is the maximum number of body bytes returned
+	MaxSize int `json:"maxSize"`
+	// Timeout in seconds for the whole request, including reading the body
+	Timeout int `json:"timeout"`
+}
+
+type HTTPFetchArgs struct {
+	URL     string            `json:"url"`
+	Method  string            `json:"method,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+func (t *HTTPFetchTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	desc := fmt.Sprintf("Fetch a URL over HTTP(S) and return the status code, content type and response body (truncated to %d bytes).", t.MaxSize)
+	if len(t.AllowedDomains) > 0 {
+		desc += fmt.Sprintf(" Only these domains and their subdomains can be fetched: %s.", strings.Join(t.AllowedDomains, ", "))
+	}
+	return &schema.ToolInfo{
+		Name: "http_fetch",
+		Desc: desc,
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"url": {
+				Type:     schema.String,
+				Desc:     "The http or https URL to fetch.",
+				Required: true,
+			},
+			"method": {
+				Type:     schema.String,
+				Desc:     "HTTP method, defaults to GET.",
+				Required: false,
+			},
+			"headers": {
+				Type:     schema.Object,
+				Desc:     "Optional request headers, e.g. {\"Accept\": \"application/json\"}.",
+				Required: false,
+			},
+		}),
+	}, nil
+}
+
+// InvokableRun fetches the URL. Failures are returned as the result so the model can react.
+func (t *HTTPFetchTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	var args HTTPFetchArgs
+	if err := json.Unmarshal([]byte(argumentsInJSON), &args); err != nil {
+		return fmt.Sprintf("failed to parse arguments: %v", err), nil
+	}
+	if args.URL == "" {
+		return "url is required", nil
+	}
+	method := strings.ToUpper(strings.TrimSpace(args.Method))
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	target, err := url.Parse(args.URL)
+	if err != nil {
+		return fmt.Sprintf("invalid url: %v", err), nil
+	}
+	if msg := t.checkURL(target); msg != "" {
+		return msg, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(t.Timeout)*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), nil)
+	if err != nil {
+		return fmt.Sprintf("invalid request: %v", err), nil
+	}
+	for key, value := range args.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := t.client().Do(req)
+	if err != nil {
+		return fmt.Sprintf("request failed: %v", err), nil
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(t.MaxSize)+1))
+	if err != nil {
+		return fmt.Sprintf("failed to read the response body: %v", err), nil
+	}
+	return formatHTTPFetchResult(resp, body, t.MaxSize), nil
+}
+
+// checkURL validates the scheme and the allowed domains, and returns why the URL can't
+// be fetched, or an empty string
+func (t *HTTPFetchTool) checkURL(target *url.URL) string {
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return fmt.Sprintf("unsupported url scheme %q, only http and https are supported", target.Scheme)
+	}
+	host := target.Hostname()
+	if host == "" {
+		return "url has no host"
+	}
+	if !t.domainAllowed(host) {
+		return fmt.Sprintf("domain %s is not allowed, allowed domains: %s", host, strings.Join(t.AllowedDomains, ", "))
+	}
+	return ""
+}
+
+// domainAllowed reports whether the host is one of the allowed domains or a subdomain of one
+func (t *HTTPFetchTool) domainAllowed(host string) bool {
+	if len(t.AllowedDomains) == 0 {
+		return true
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, domain := range t.AllowedDomains {
+		domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "*.")
+		if domain == "" {
+			continue
+		}
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// client builds the HTTP client of a request. Addresses are checked when connecting, after
+// name resolution, so a public name resolving to a private address is rejected as well.
+// The environment proxy is only used when private addresses are allowed, since requests
+// through a proxy can't be checked.
+func (t *HTTPFetchTool) client() *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: time.Duration(t.Timeout) * time.Second,
+	}
+	if t.AllowPrivate {
+		transport.Proxy = http.ProxyFromEnvironment
+	} else {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if isPrivateAddr(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", errPrivateAddress, addrPort.Addr())
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= httpFetchMaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", httpFetchMaxRedirects)
+			}
+			if msg := t.checkURL(req.URL); msg != "" {
+				return fmt.Errorf("redirect to %s rejected: %s", req.URL, msg)
+			}
+			return nil
+		},
+	}
+}
+
+// isPrivateAddr reports whether the address is not publicly routable
+func isPrivateAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsUnspecified() || sharedAddressSpace.Contains(addr)
+}
+
+// formatHTTPFetchResult formats the status, content type and body of the response. The
+// body was read up to one byte past maxSize to detect truncation.
+func formatHTTPFetchResult(resp *http.Response, body []byte, maxSize int) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Status: %s\n", resp.Status)
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "unknown"
+	}
+	fmt.Fprintf(&sb, "Content-Type: %s\n", contentType)
+
+	truncated := len(body) > maxSize
+	if truncated {
+		body = body[:maxSize]
+		// Drop the bytes of a multi-byte character cut in half
+		for i := 0; i < utf8.UTFMax-1 && len(body) > 0; i++ {
+			if r, size := utf8.DecodeLastRune(body); r != utf8.RuneError || size > 1 {
+				break
+			}
+			body = body[:len(body)-1]
+		}
+	}
+	if !utf8.Valid(body) {
+		fmt.Fprintf(&sb, "\n(binary content of %d bytes omitted)\n", len(body))
+		return sb.String()
+	}
+	if truncated {
+		fmt.Fprintf(&sb, "(body truncated to %d bytes)\n", len(body))
+	}
+	sb.WriteString("\n")
+	sb.Write(body)
+	return sb.String()
+}
@@ -0,0 +1,66 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPFetchToolFetches(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintf(w, "%s %s héllo world", r.Method, r.Header.Get("X-Test"))
+	}))
+	defer server.Close()
+
+	fetch := &HTTPFetchTool{AllowPrivate: true, MaxSize: 11, Timeout: 5}
+	result, err := fetch.InvokableRun(context.Background(), fmt.Sprintf(`{"url":%q,"method":"post","headers":{"X-Test":"yes"}}`, server.URL))
+	if err != nil {
+		t.Fatalf("InvokableRun() error = %v", err)
+	}
+	if !strings.Contains(result, "Status: 200 OK") || !strings.Contains(result, "Content-Type: text/plain") {
+		t.Errorf("result = %q, want the status and content type", result)
+	}
+	// The limit falls inside the two-byte é, which is dropped
+	if !strings.Contains(result, "truncated to 10 bytes") || !strings.HasSuffix(result, "\nPOST yes h") {
+		t.Errorf("result = %q, want the body truncated before the cut character", result)
+	}
+}
+
+func TestHTTPFetchToolRejects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "internal")
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		tool HTTPFetchTool
+		url  string
+		want string
+	}{
+		{"loopback by default", HTTPFetchTool{}, server.URL, "not allowed"},
+		{"scheme", HTTPFetchTool{}, "file:///etc/passwd", "unsupported url scheme"},
+		{"domain", HTTPFetchTool{AllowedDomains: []string{"example.com"}}, "https://example.org/", "domain example.org is not allowed"},
+		{"lookalike domain", HTTPFetchTool{AllowedDomains: []string{"example.com"}}, "https://badexample.com/", "is not allowed"},
+	}
+	for _, tt := range tests {
+		tt.tool.Timeout = 5
+		tt.tool.MaxSize = DEFAULT_HTTP_FETCH_MAX_SIZE
+		result, err := tt.tool.InvokableRun(context.Background(), fmt.Sprintf(`{"url":%q}`, tt.url))
+		if err != nil {
+			t.Fatalf("%s: InvokableRun() error = %v", tt.name, err)
+		}
+		if !strings.Contains(result, tt.want) || strings.Contains(result, "internal") {
+			t.Errorf("%s: result = %q, want a rejection containing %q", tt.name, result, tt.want)
+		}
+	}
+
+	allowed := HTTPFetchTool{AllowedDomains: []string{"*.example.com"}}
+	if !allowed.domainAllowed("api.example.com") || !allowed.domainAllowed("EXAMPLE.com.") {
+		t.Error("domainAllowed() rejected an allowed domain")
+	}
+}
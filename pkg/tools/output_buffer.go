@@ -0,0 +1,113 @@
+package tools
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DefaultMaxTaskOutput is the output kept per stream of a background task
+const DefaultMaxTaskOutput = 4 << 20
+
+// OutputBuffer keeps the most recent output of a stream in a ring of a fixed size, so a
+// long-running process can't use unlimited memory. Positions are offsets in everything
+// written, they stay valid after older output is dropped.
+type OutputBuffer struct {
+	mu    sync.Mutex
+	data  []byte // grows up to max, then is overwritten from head
+	head  int    // index of the oldest byte once data is full
+	max   int
+	total int64 // bytes written, including the dropped ones
+}
+
+// NewOutputBuffer returns a buffer keeping the last maxBytes bytes,
+// DefaultMaxTaskOutput if maxBytes is not positive
+func NewOutputBuffer(maxBytes int) *OutputBuffer {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxTaskOutput
+	}
+	return &OutputBuffer{max: maxBytes}
+}
+
+// Write appends p, dropping the oldest output beyond the size of the buffer
+func (b *OutputBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := len(p)
+	b.total += int64(n)
+	if len(p) >= b.max {
+		b.data = append(b.data[:0], p[len(p)-b.max:]...)
+		b.head = 0
+		return n, nil
+	}
+	if free := b.max - len(b.data); free > 0 {
+		m := min(free, len(p))
+		b.data = append(b.data, p[:m]...)
+		p = p[m:]
+	}
+	for len(p) > 0 {
+		m := copy(b.data[b.head:], p)
+		p = p[m:]
+		b.head = (b.head + m) % b.max
+	}
+	return n, nil
+}
+
+// WriteString appends s, like Write
+func (b *OutputBuffer) WriteString(s string) (int, error) {
+	return b.Write([]byte(s))
+}
+
+// String returns the output kept in the buffer
+func (b *OutputBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.bytes())
+}
+
+// Len returns the number of bytes written, including the dropped ones. It is the
+// position of the next write.
+func (b *OutputBuffer) Len() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.total
+}
+
+// Dropped returns the number of bytes dropped to keep the buffer in its size
+func (b *OutputBuffer) Dropped() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.total - int64(len(b.data))
+}
+
+// ReadFrom returns the output from position pos, the position after it and the number of
+// bytes from pos that were dropped before they could be read
+func (b *OutputBuffer) ReadFrom(pos int64) (output string, next int64, dropped int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	start := b.total - int64(len(b.data))
+	if pos < start {
+		dropped = start - pos
+		pos = start
+	}
+	if pos >= b.total {
+		return "", b.total, dropped
+	}
+	return string(b.bytes()[pos-start:]), b.total, dropped
+}
+
+// bytes returns the kept output in order, b.mu must be held
+func (b *OutputBuffer) bytes() []byte {
+	if b.head == 0 {
+		return b.data
+	}
+	out := make([]byte, 0, len(b.data))
+	out = append(out, b.data[b.head:]...)
+	return append(out, b.data[:b.head]...)
+}
+
+// truncationNotice is the marker of output dropped before the kept output
+func truncationNotice(dropped int64) string {
+	return fmt.Sprintf("[... %d bytes of earlier output dropped ...]\n", dropped)
+}
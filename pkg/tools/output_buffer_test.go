@@ -0,0 +1,52 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOutputBufferKeepsRecentOutput(t *testing.T) {
+	b := NewOutputBuffer(10)
+	b.WriteString("0123")
+	b.WriteString("456789")
+	if got := b.String(); got != "0123456789" || b.Dropped() != 0 {
+		t.Fatalf("String() = %q, Dropped() = %d, want the whole output", got, b.Dropped())
+	}
+
+	// Wraps around, dropping the oldest bytes
+	b.WriteString("abcd")
+	if got := b.String(); got != "456789abcd" {
+		t.Errorf("String() = %q, want 456789abcd", got)
+	}
+	if got := b.Dropped(); got != 4 {
+		t.Errorf("Dropped() = %d, want 4", got)
+	}
+
+	// A write larger than the buffer keeps its tail
+	b.WriteString(strings.Repeat("x", 5) + "ABCDEFGHIJ")
+	if got := b.String(); got != "ABCDEFGHIJ" {
+		t.Errorf("String() = %q, want ABCDEFGHIJ", got)
+	}
+	if got := b.Len(); got != 29 {
+		t.Errorf("Len() = %d, want 29", got)
+	}
+}
+
+func TestOutputBufferReadFrom(t *testing.T) {
+	b := NewOutputBuffer(8)
+	b.WriteString("line1\n")
+	out, pos, dropped := b.ReadFrom(0)
+	if out != "line1\n" || pos != 6 || dropped != 0 {
+		t.Fatalf("ReadFrom(0) = %q, %d, %d", out, pos, dropped)
+	}
+	if out, next, _ := b.ReadFrom(pos); out != "" || next != pos {
+		t.Errorf("ReadFrom(%d) without new output = %q, %d", pos, out, next)
+	}
+
+	// The reader fell behind: the output it missed is reported as dropped
+	b.WriteString("line2\nline3\n")
+	out, next, dropped := b.ReadFrom(pos)
+	if out != "2\nline3\n" || next != 18 || dropped != 4 {
+		t.Errorf("ReadFrom(%d) = %q, %d, %d, want \"2\\nline3\\n\", 18, 4", pos, out, next, dropped)
+	}
+}
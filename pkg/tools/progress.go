@@ -0,0 +1,88 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/eino/compose"
+)
+
+// ProgressReporter receives the intermediate output of running tool calls, e.g. the lines
+// a command prints before it exits. Tools report progress with ReportProgress; tools that
+// don't report any work unchanged.
+type ProgressReporter interface {
+	ReportToolProgress(toolCallID, progress string)
+}
+
+// maxProgressLine is the length after which a line without newline, such as a progress
+// bar redrawn with \r, is reported anyway
+const maxProgressLine = 4096
+
+type progressReporterKey struct{}
+
+// WithProgressReporter returns a context in which the tool calls report their progress
+// to reporter
+func WithProgressReporter(ctx context.Context, reporter ProgressReporter) context.Context {
+	return context.WithValue(ctx, progressReporterKey{}, reporter)
+}
+
+// progressReporter returns the reporter of the context, nil if there is none
+func progressReporter(ctx context.Context) ProgressReporter {
+	reporter, _ := ctx.Value(progressReporterKey{}).(ProgressReporter)
+	return reporter
+}
+
+// ReportProgress reports intermediate output of the tool call running with ctx. It does
+// nothing when the caller doesn't collect progress.
+func ReportProgress(ctx context.Context, progress string) {
+	if reporter := progressReporter(ctx); reporter != nil && progress != "" {
+		reporter.ReportToolProgress(compose.GetToolCallID(ctx), progress)
+	}
+}
+
+// progressWriter reports the complete lines written to it as progress, the lines of a
+// write are reported together
+type progressWriter struct {
+	ctx     context.Context
+	mu      sync.Mutex
+	partial []byte // the last line, until its newline is written
+}
+
+// newProgressWriter returns a writer reporting lines as progress of the tool call of ctx,
+// nil if the caller doesn't collect progress
+func newProgressWriter(ctx context.Context) *progressWriter {
+	if progressReporter(ctx) == nil {
+		return nil
+	}
+	return &progressWriter{ctx: ctx}
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.partial = append(w.partial, p...)
+	end := bytes.LastIndexByte(w.partial, '\n')
+	if end < 0 {
+		if len(w.partial) >= maxProgressLine {
+			ReportProgress(w.ctx, string(w.partial))
+			w.partial = w.partial[:0]
+		}
+		return len(p), nil
+	}
+	lines := strings.ReplaceAll(string(w.partial[:end+1]), "\r\n", "\n")
+	w.partial = append(w.partial[:0], w.partial[end+1:]...)
+	ReportProgress(w.ctx, lines)
+	return len(p), nil
+}
+
+// Flush reports the last line if it has no newline
+func (w *progressWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.partial) > 0 {
+		ReportProgress(w.ctx, string(w.partial))
+		w.partial = w.partial[:0]
+	}
+}
@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// progressRecorder records the reported progress
+type progressRecorder struct {
+	progress []string
+}
+
+func (r *progressRecorder) ReportToolProgress(toolCallID, progress string) {
+	r.progress = append(r.progress, progress)
+}
+
+func TestProgressWriterReportsLines(t *testing.T) {
+	if newProgressWriter(context.Background()) != nil {
+		t.Fatal("newProgressWriter() without a reporter != nil")
+	}
+
+	recorder := &progressRecorder{}
+	w := newProgressWriter(WithProgressReporter(context.Background(), recorder))
+	w.Write([]byte("first\r\nsec"))
+	w.Write([]byte("ond\nthird\nfou"))
+	w.Write([]byte("rth"))
+	w.Flush()
+	want := []string{"first\n", "second\nthird\n", "fourth"}
+	if strings.Join(recorder.progress, "|") != strings.Join(want, "|") {
+		t.Errorf("progress = %q, want %q", recorder.progress, want)
+	}
+
+	// A long line without newline is reported without waiting for the newline
+	recorder.progress = nil
+	w.Write([]byte(strings.Repeat("=", maxProgressLine)))
+	if len(recorder.progress) != 1 {
+		t.Errorf("got %d reports for a long line, want 1", len(recorder.progress))
+	}
+}
+
+func TestRunTerminalCommandReportsProgress(t *testing.T) {
+	recorder := &progressRecorder{}
+	cmdTool := &RunTerminalCommandTool{Timeout: 5 * time.Second}
+	ctx := WithProgressReporter(context.Background(), recorder)
+	out, err := cmdTool.InvokableRun(ctx, `{"command": "echo one; echo two"}`)
+	if err != nil {
+		t.Fatalf("InvokableRun() error = %v", err)
+	}
+	if !strings.Contains(out, "one\ntwo") {
+		t.Errorf("output = %q, want the command output", out)
+	}
+	if got := strings.Join(recorder.progress, ""); got != "one\ntwo\n" {
+		t.Errorf("progress = %q, want the output lines", got)
+	}
+}
@@ -0,0 +1,272 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	DEFAULT_SEARCH_MAX_RESULTS   = 100
+	DEFAULT_SEARCH_MAX_FILE_SIZE = 1024 * 1024
+	// searchMaxLineLength is the number of characters of a matching line returned
+	searchMaxLineLength = 300
+	// searchBinaryProbe is the number of leading bytes checked for a NUL byte to skip binary files
+	searchBinaryProbe = 8000
+)
+
+func getSearchTools(ctx context.Context, params map[string]interface{}) ([]tool.BaseTool, error) {
+	var cfg SearchTool
+	bts, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(bts, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.WorkDir == "" {
+		return nil, fmt.Errorf("workDir params empty")
+	}
+	if cfg.MaxResults <= 0 {
+		cfg.MaxResults = DEFAULT_SEARCH_MAX_RESULTS
+	}
+	if cfg.MaxFileSize <= 0 {
+		cfg.MaxFileSize = DEFAULT_SEARCH_MAX_FILE_SIZE
+	}
+	return []tool.BaseTool{&cfg}, nil
+}
+
+// SearchTool searches the contents of the files under WorkDir for a regular expression
+// or a literal string. Files and directories ignored by .gitignore files, the .git
+// directory, binary files and files larger than MaxFileSize are skipped. Symbolic links
+// are not followed, so the search stays inside WorkDir.
+type SearchTool struct {
+	WorkDir string `json:"workDir"`
+	// MaxResults is the number of matching lines returned before asking for a narrower query
+	MaxResults int `json:"maxResults"`
+	// MaxFileSize in bytes, larger files are skipped
+	MaxFileSize int64 `json:"maxFileSize"`
+}
+
+type SearchArgs struct {
+	Pattern    string `json:"pattern"`
+	Path       string `json:"path,omitempty"`
+	Literal    bool   `json:"literal,omitempty"`
+	IgnoreCase bool   `json:"ignoreCase,omitempty"`
+	Include    string `json:"include,omitempty"`
+}
+
+// searchState holds the matches of a search
+type searchState struct {
+	re      *regexp.Regexp
+	include string
+	max     int
+	results []string
+	more    bool // more than max lines matched
+}
+
+func (t *SearchTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: "grep",
+		Desc: fmt.Sprintf("Search the contents of the files in the working directory for a regular expression or a literal string. "+
+			"Returns the matching lines as path:line: text, ordered by path and line, at most %d lines. "+
+			"Files ignored by .gitignore, binary files and files larger than %d bytes are skipped.", t.MaxResults, t.MaxFileSize),
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"pattern": {
+				Type:     schema.String,
+				Desc:     "The regular expression (Go RE2 syntax) or, with literal, the string to search for.",
+				Required: true,
+			},
+			"path": {
+				Type:     schema.String,
+				Desc:     "A file or directory relative to the working directory to search in, defaults to the whole working directory.",
+				Required: false,
+			},
+			"literal": {
+				Type:     schema.Boolean,
+				Desc:     "Match the pattern as a literal string instead of a regular expression.",
+				Required: false,
+			},
+			"ignoreCase": {
+				Type:     schema.Boolean,
+				Desc:     "Match case-insensitively.",
+				Required: false,
+			},
+			"include": {
+				Type:     schema.String,
+				Desc:     "Only search files whose name matches this glob, e.g. \"*.go\".",
+				Required: false,
+			},
+		}),
+	}, nil
+}
+
+// InvokableRun runs the search. Failures are returned as the result so the model can react.
+func (t *SearchTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	var args SearchArgs
+	if err := json.Unmarshal([]byte(argumentsInJSON), &args); err != nil {
+		return fmt.Sprintf("failed to parse arguments: %v", err), nil
+	}
+	if args.Pattern == "" {
+		return "pattern is required", nil
+	}
+	expr := args.Pattern
+	if args.Literal {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if args.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Sprintf("invalid pattern: %v", err), nil
+	}
+	if args.Include != "" {
+		if _, err := path.Match(args.Include, ""); err != nil {
+			return fmt.Sprintf("invalid include glob: %v", err), nil
+		}
+	}
+
+	root, err := filepath.Abs(t.WorkDir)
+	if err != nil {
+		return fmt.Sprintf("invalid working directory: %v", err), nil
+	}
+	rel := filepath.ToSlash(filepath.Clean(args.Path))
+	if rel == "." || rel == "/" {
+		rel = ""
+	}
+	if filepath.IsAbs(args.Path) || rel == ".." || strings.HasPrefix(rel, "../") {
+		return fmt.Sprintf("path %s is outside the working directory", args.Path), nil
+	}
+
+	// Collect the .gitignore rules of the root and the directories leading to the path. The
+	// path itself is searched even when ignored, since it was asked for.
+	rules := readGitignore(root, "")
+	if rel != "" {
+		parts := strings.Split(rel, "/")
+		dir := ""
+		for _, part := range parts[:len(parts)-1] {
+			dir = joinRel(dir, part)
+			rules = append(rules, readGitignore(filepath.Join(root, filepath.FromSlash(dir)), dir)...)
+		}
+	}
+
+	info, err := os.Lstat(filepath.Join(root, filepath.FromSlash(rel)))
+	if err != nil {
+		return fmt.Sprintf("path %s not found", args.Path), nil
+	}
+	state := &searchState{re: re, include: args.Include, max: t.MaxResults}
+	if info.IsDir() {
+		err = t.searchDir(ctx, state, root, rel, rules)
+	} else {
+		t.searchFile(state, root, rel, info)
+	}
+	if err != nil {
+		return fmt.Sprintf("search failed: %v", err), nil
+	}
+	return formatSearchResult(state), nil
+}
+
+// searchDir searches the entries of a directory in name order, so the results are the
+// same between runs. rules holds the .gitignore rules of the directory's parents.
+func (t *SearchTool) searchDir(ctx context.Context, state *searchState, root, dir string, rules []ignoreRule) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	abs := filepath.Join(root, filepath.FromSlash(dir))
+	if dir != "" {
+		rules = append(rules, readGitignore(abs, dir)...)
+	}
+	entries, err := os.ReadDir(abs)
+	if err != nil {
+		// An unreadable directory doesn't fail the whole search
+		return nil
+	}
+	for _, entry := range entries {
+		if state.more {
+			return nil
+		}
+		rel := joinRel(dir, entry.Name())
+		if entry.Type()&os.ModeSymlink != 0 || ignored(rules, rel, entry.IsDir()) {
+			continue
+		}
+		if entry.IsDir() {
+			if entry.Name() == ".git" {
+				continue
+			}
+			if err := t.searchDir(ctx, state, root, rel, rules); err != nil {
+				return err
+			}
+			continue
+		}
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		t.searchFile(state, root, rel, info)
+	}
+	return nil
+}
+
+// searchFile adds the matching lines of a file, unless it is filtered out, too large or binary
+func (t *SearchTool) searchFile(state *searchState, root, rel string, info os.FileInfo) {
+	if !info.Mode().IsRegular() || info.Size() > t.MaxFileSize {
+		return
+	}
+	if state.include != "" {
+		if ok, _ := path.Match(state.include, path.Base(rel)); !ok {
+			return
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(rel)))
+	if err != nil {
+		return
+	}
+	if bytes.IndexByte(data[:min(len(data), searchBinaryProbe)], 0) >= 0 {
+		return
+	}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if !state.re.MatchString(line) {
+			continue
+		}
+		if len(state.results) == state.max {
+			state.more = true
+			return
+		}
+		state.results = append(state.results, fmt.Sprintf("%s:%d: %s", rel, i+1, truncateLine(line)))
+	}
+}
+
+// truncateLine shortens a matching line to searchMaxLineLength characters
+func truncateLine(line string) string {
+	if utf8.RuneCountInString(line) <= searchMaxLineLength {
+		return line
+	}
+	runes := []rune(line)
+	return string(runes[:searchMaxLineLength]) + "..."
+}
+
+func formatSearchResult(state *searchState) string {
+	if len(state.results) == 0 {
+		return "no matches found"
+	}
+	result := strings.Join(state.results, "\n")
+	if state.more {
+		result += fmt.Sprintf("\n\ntoo many matches, only the first %d are shown: narrow your query with a more specific pattern, a path or an include glob", state.max)
+	}
+	return result
+}
@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSearchFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		file := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestSearchToolFindsMatches(t *testing.T) {
+	root := writeSearchFiles(t, map[string]string{
+		".gitignore":         "build/\n*.log\n!keep.log\n",
+		"b.go":               "package main\nfunc TODO() {}\n",
+		"a.txt":              "nothing\ntodo: later\r\n",
+		"sub/c.go":           "// TODO: sub\n",
+		"sub/.gitignore":     "/local.go\n",
+		"sub/local.go":       "// TODO: ignored by sub\n",
+		"build/out.go":       "// TODO: ignored\n",
+		"debug.log":          "TODO ignored\n",
+		"keep.log":           "TODO kept\n",
+		"bin.dat":            "TODO\x00binary",
+		".git/HEAD":          "TODO in git\n",
+		"other/deep/file.md": "Todo deep\n",
+	})
+	search := &SearchTool{WorkDir: root, MaxResults: 10, MaxFileSize: 1024}
+
+	result, err := search.InvokableRun(context.Background(), `{"pattern":"todo","ignoreCase":true}`)
+	if err != nil {
+		t.Fatalf("InvokableRun() error = %v", err)
+	}
+	want := strings.Join([]string{
+		"a.txt:2: todo: later",
+		"b.go:2: func TODO() {}",
+		"keep.log:1: TODO kept",
+		"other/deep/file.md:1: Todo deep",
+		"sub/c.go:1: // TODO: sub",
+	}, "\n")
+	if result != want {
+		t.Errorf("result = %q, want %q", result, want)
+	}
+
+	result, _ = search.InvokableRun(context.Background(), `{"pattern":"TODO()","literal":true,"path":"./","include":"*.go"}`)
+	if result != "b.go:2: func TODO() {}" {
+		t.Errorf("literal result = %q", result)
+	}
+	result, _ = search.InvokableRun(context.Background(), `{"pattern":"TODO","path":"sub"}`)
+	if result != "sub/c.go:1: // TODO: sub" {
+		t.Errorf("path result = %q", result)
+	}
+	result, _ = search.InvokableRun(context.Background(), `{"pattern":"absent"}`)
+	if result != "no matches found" {
+		t.Errorf("no match result = %q", result)
+	}
+}
+
+func TestSearchToolLimits(t *testing.T) {
+	var lines []string
+	for i := 0; i < 5; i++ {
+		lines = append(lines, fmt.Sprintf("match %d", i))
+	}
+	root := writeSearchFiles(t, map[string]string{
+		"many.txt": strings.Join(lines, "\n"),
+		"big.txt":  "match " + strings.Repeat("x", 100),
+	})
+	search := &SearchTool{WorkDir: root, MaxResults: 3, MaxFileSize: 50}
+
+	result, _ := search.InvokableRun(context.Background(), `{"pattern":"match"}`)
+	if !strings.HasPrefix(result, "many.txt:1: match 0\nmany.txt:2: match 1\nmany.txt:3: match 2\n") ||
+		!strings.Contains(result, "too many matches") || strings.Contains(result, "big.txt") {
+		t.Errorf("result = %q, want three matches and the narrowing hint", result)
+	}
+
+	for _, args := range []string{`{"pattern":"match","path":"../"}`, `{"pattern":"match","path":"/etc"}`} {
+		if result, _ := search.InvokableRun(context.Background(), args); !strings.Contains(result, "outside the working directory") {
+			t.Errorf("%s: result = %q, want a rejection", args, result)
+		}
+	}
+	if result, _ := search.InvokableRun(context.Background(), `{"pattern":"("}`); !strings.HasPrefix(result, "invalid pattern") {
+		t.Errorf("result = %q, want an invalid pattern", result)
+	}
+}
@@ -126,6 +126,11 @@ func (t *SmartCmdTool) InvokableRun(ctx context.Context, argumentsInJSON string,
 		return "", fmt.Errorf("command is required")
 	}
 
+	// Denied commands are rejected before asking for approval
+	if msg := t.baseTool.checkCommand(args.Command); msg != "" {
+		return msg, nil
+	}
+
 	// Check if command is dangerous
 	if t.isDangerousCommand(args.Command) {
 		// This is a dangerous command, require approval
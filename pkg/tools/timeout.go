@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// DEFAULT_TOOL_TIMEOUT is the time in seconds a builtin tool call may run when its tool
+// config sets no timeout
+const DEFAULT_TOOL_TIMEOUT = 60
+
+// toolTimeoutGrace is added to the timeout of a call before it is abandoned, so a tool
+// enforcing the same timeout itself, like cmd, still returns its own partial result
+var toolTimeoutGrace = 5 * time.Second
+
+// TimeoutTool bounds the calls of a tool. A call running longer than Timeout has its
+// context cancelled and returns a timed out result the model can react to. A tool that
+// ignores the cancellation, e.g. a file operation stalled on a network mount, is left
+// to finish in the background: its result is dropped.
+type TimeoutTool struct {
+	tool.InvokableTool
+	Name    string
+	Timeout time.Duration
+}
+
+type toolCallResult struct {
+	output string
+	err    error
+}
+
+func (t *TimeoutTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	callCtx, cancel := context.WithTimeout(ctx, t.Timeout+toolTimeoutGrace)
+	defer cancel()
+	done := make(chan toolCallResult, 1)
+	go func() {
+		output, err := t.InvokableTool.InvokableRun(callCtx, argumentsInJSON, opts...)
+		done <- toolCallResult{output: output, err: err}
+	}()
+	select {
+	case result := <-done:
+		return result.output, result.err
+	case <-callCtx.Done():
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return fmt.Sprintf("tool %s timed out after %s and was cancelled", t.Name, t.Timeout), nil
+	}
+}
+
+// withTimeout wraps the invokable tools to bound their calls with the timeout of the
+// tool config params, in seconds, DEFAULT_TOOL_TIMEOUT when it is not set
+func withTimeout(ctx context.Context, tools []tool.BaseTool, params map[string]interface{}) ([]tool.BaseTool, error) {
+	timeout := time.Duration(DEFAULT_TOOL_TIMEOUT) * time.Second
+	switch v := params["timeout"].(type) {
+	case int:
+		if v > 0 {
+			timeout = time.Duration(v) * time.Second
+		}
+	case float64:
+		if v > 0 {
+			timeout = time.Duration(v * float64(time.Second))
+		}
+	}
+	wrapped := make([]tool.BaseTool, len(tools))
+	for i, t := range tools {
+		invokable, ok := t.(tool.InvokableTool)
+		if !ok {
+			wrapped[i] = t
+			continue
+		}
+		info, err := t.Info(ctx)
+		if err != nil {
+			return nil, err
+		}
+		wrapped[i] = &TimeoutTool{InvokableTool: invokable, Name: info.Name, Timeout: timeout}
+	}
+	return wrapped, nil
+}
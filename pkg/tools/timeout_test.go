@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// sleepTool sleeps for the duration of its arguments, returning early when its context
+// is cancelled unless it ignores cancellation
+type sleepTool struct {
+	ignoreCancel bool
+}
+
+func (t *sleepTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{Name: "sleep"}, nil
+}
+
+func (t *sleepTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	d, _ := time.ParseDuration(argumentsInJSON)
+	if t.ignoreCancel {
+		time.Sleep(d)
+		return "slept", nil
+	}
+	select {
+	case <-time.After(d):
+		return "slept", nil
+	case <-ctx.Done():
+		return "woken: " + ctx.Err().Error(), nil
+	}
+}
+
+func TestTimeoutToolAbandonsHungCalls(t *testing.T) {
+	defer func(grace time.Duration) { toolTimeoutGrace = grace }(toolTimeoutGrace)
+	toolTimeoutGrace = 10 * time.Millisecond
+	tools, err := withTimeout(context.Background(), []tool.BaseTool{&sleepTool{ignoreCancel: true}}, map[string]interface{}{"timeout": 0.01})
+	if err != nil {
+		t.Fatalf("withTimeout() error = %v", err)
+	}
+	timeoutTool := tools[0].(*TimeoutTool)
+	if timeoutTool.Timeout != 10*time.Millisecond {
+		t.Errorf("Timeout = %s, want 10ms", timeoutTool.Timeout)
+	}
+
+	start := time.Now()
+	result, err := timeoutTool.InvokableRun(context.Background(), "1m")
+	if err != nil {
+		t.Fatalf("InvokableRun() error = %v", err)
+	}
+	if result != "tool sleep timed out after 10ms and was cancelled" {
+		t.Errorf("result = %q, want the timed out result", result)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("InvokableRun() took %s, want it to return after the grace period", elapsed)
+	}
+
+	if result, _ := timeoutTool.InvokableRun(context.Background(), "1ms"); result != "slept" {
+		t.Errorf("result = %q, want the result of a call within the timeout", result)
+	}
+}
+
+func TestTimeoutToolDefaultsAndCancellation(t *testing.T) {
+	tools, err := withTimeout(context.Background(), []tool.BaseTool{&sleepTool{}}, map[string]interface{}{"timeout": 0})
+	if err != nil {
+		t.Fatalf("withTimeout() error = %v", err)
+	}
+	timeoutTool := tools[0].(*TimeoutTool)
+	if timeoutTool.Timeout != DEFAULT_TOOL_TIMEOUT*time.Second {
+		t.Errorf("Timeout = %s, want the default", timeoutTool.Timeout)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	result, err := timeoutTool.InvokableRun(ctx, "1m")
+	if !strings.Contains(result, "canceled") && err == nil {
+		t.Errorf("InvokableRun() = %q, %v, want the cancellation passed to the tool", result, err)
+	}
+}
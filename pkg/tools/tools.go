@@ -14,9 +14,19 @@ import (
 
 type GetToolsFunc func(params map[string]interface{}) ([]tool.BaseTool, error)
 
-var ExemptAutoApprovalTools = []string{"cmd_bg", "smart_cmd"}
+var ExemptAutoApprovalTools = []string{"cmd_bg", "smart_cmd", "ask_user"}
 
+// GetBuiltinTools creates the tools of a builtin category. Their calls are bounded by the
+// timeout param of the tool config, see TimeoutTool.
 func GetBuiltinTools(ctx context.Context, category string, params map[string]interface{}) ([]tool.BaseTool, error) {
+	tools, err := getBuiltinTools(ctx, category, params)
+	if err != nil {
+		return nil, err
+	}
+	return withTimeout(ctx, tools, params)
+}
+
+func getBuiltinTools(ctx context.Context, category string, params map[string]interface{}) ([]tool.BaseTool, error) {
 	switch category {
 	case "filesystem":
 		return getFileSystemTools(ctx, params)
@@ -24,6 +34,14 @@ func GetBuiltinTools(ctx context.Context, category string, params map[string]int
 		return getCommandTools(ctx, params)
 	case "smart_cmd":
 		return getSmartCommandTools(ctx, params)
+	case "ask_user":
+		return getAskUserTools(ctx, params)
+	case "http_fetch":
+		return getHTTPFetchTools(ctx, params)
+	case "search":
+		return getSearchTools(ctx, params)
+	case "web_search":
+		return getWebSearchTools(ctx, params)
 	}
 	return nil, fmt.Errorf("not found %s tools", category)
 }
@@ -0,0 +1,301 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	DEFAULT_WEB_SEARCH_TIMEOUT     = 15
+	DEFAULT_WEB_SEARCH_MAX_RESULTS = 5
+	// webSearchResultsLimit caps the results the model may ask for
+	webSearchResultsLimit = 20
+	// webSearchMaxResponse is the maximum number of bytes read from the backend
+	webSearchMaxResponse = 4 * 1024 * 1024
+	// braveSearchURL is the endpoint of the Brave backend when no url is configured
+	braveSearchURL = "https://api.search.brave.com/res/v1/web/search"
+)
+
+// Search backends of the web_search tool
+const (
+	WebSearchSearXNG = "searxng"
+	WebSearchBrave   = "brave"
+	WebSearchJSON    = "json"
+)
+
+func getWebSearchTools(ctx context.Context, params map[string]interface{}) ([]tool.BaseTool, error) {
+	var cfg WebSearchTool
+	bts, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(bts, &cfg); err != nil {
+		return nil, err
+	}
+	cfg.Backend = strings.ToLower(strings.TrimSpace(cfg.Backend))
+	switch cfg.Backend {
+	case WebSearchSearXNG, WebSearchJSON:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("web_search %s backend requires url", cfg.Backend)
+		}
+	case WebSearchBrave:
+		if cfg.URL == "" {
+			cfg.URL = braveSearchURL
+		}
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("web_search brave backend requires apiKey")
+		}
+	case "":
+		return nil, fmt.Errorf("web_search requires backend (searxng, brave or json)")
+	default:
+		return nil, fmt.Errorf("unknown web_search backend %q (searxng, brave or json)", cfg.Backend)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DEFAULT_WEB_SEARCH_TIMEOUT
+	}
+	if cfg.MaxResults <= 0 {
+		cfg.MaxResults = DEFAULT_WEB_SEARCH_MAX_RESULTS
+	}
+	cfg.MaxResults = min(cfg.MaxResults, webSearchResultsLimit)
+	return []tool.BaseTool{&cfg}, nil
+}
+
+// WebSearchTool searches the web with a search API and returns the title, URL and
+// snippet of each result. The backend is SearXNG, Brave or a generic JSON endpoint whose
+// fields are configured. Unlike http_fetch the backend URL is set by the configuration,
+// not by the model, so it may be a private address, e.g. a local SearXNG instance.
+type WebSearchTool struct {
+	// Backend is searxng, brave or json
+	Backend string `json:"backend"`
+	// URL is the base URL of the SearXNG instance or the search endpoint of the other backends
+	URL    string `json:"url"`
+	APIKey string `json:"apiKey"`
+	// Timeout in seconds of a search
+	Timeout int `json:"timeout"`
+	// MaxResults is the number of results returned when the model doesn't ask for a count
+	MaxResults int `json:"maxResults"`
+
+	// Fields of the json backend. The query is sent in the QueryParam parameter of a GET
+	// request, the results are read from the ResultsField array of the response, a
+	// dot-separated path.
+	QueryParam   string `json:"queryParam"`
+	CountParam   string `json:"countParam"`
+	ResultsField string `json:"resultsField"`
+	TitleField   string `json:"titleField"`
+	URLField     string `json:"urlField"`
+	SnippetField string `json:"snippetField"`
+}
+
+type WebSearchArgs struct {
+	Query string `json:"query"`
+	Count int    `json:"count,omitempty"`
+}
+
+// WebSearchResult is a result of a search
+type WebSearchResult struct {
+	Title   string
+	URL     string
+	Snippet string
+}
+
+func (t *WebSearchTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: "web_search",
+		Desc: fmt.Sprintf("Search the web and return the title, URL and snippet of each result (%d by default, at most %d). "+
+			"Use http_fetch, if available, to read a page of the results.", t.MaxResults, webSearchResultsLimit),
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"query": {
+				Type:     schema.String,
+				Desc:     "The search query.",
+				Required: true,
+			},
+			"count": {
+				Type:     schema.Integer,
+				Desc:     "Number of results to return.",
+				Required: false,
+			},
+		}),
+	}, nil
+}
+
+// InvokableRun runs the search. Failures, e.g. an unreachable backend, are returned as
+// the result so the turn goes on and the model can react.
+func (t *WebSearchTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	var args WebSearchArgs
+	if err := json.Unmarshal([]byte(argumentsInJSON), &args); err != nil {
+		return fmt.Sprintf("failed to parse arguments: %v", err), nil
+	}
+	args.Query = strings.TrimSpace(args.Query)
+	if args.Query == "" {
+		return "query is required", nil
+	}
+	count := args.Count
+	if count <= 0 {
+		count = t.MaxResults
+	}
+	count = min(count, webSearchResultsLimit)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(t.Timeout)*time.Second)
+	defer cancel()
+	req, err := t.newRequest(ctx, args.Query, count)
+	if err != nil {
+		return fmt.Sprintf("invalid search request: %v", err), nil
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Sprintf("web search backend is unreachable: %v", err), nil
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, webSearchMaxResponse))
+	if err != nil {
+		return fmt.Sprintf("failed to read the web search response: %v", err), nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Sprintf("web search backend returned %s: %s", resp.Status, truncateLine(strings.TrimSpace(string(body)))), nil
+	}
+
+	results, err := t.parseResults(body)
+	if err != nil {
+		return fmt.Sprintf("failed to parse the web search response: %v", err), nil
+	}
+	if len(results) > count {
+		results = results[:count]
+	}
+	return formatWebSearchResults(args.Query, results), nil
+}
+
+// newRequest builds the search request of the backend
+func (t *WebSearchTool) newRequest(ctx context.Context, query string, count int) (*http.Request, error) {
+	endpoint, err := url.Parse(t.URL)
+	if err != nil {
+		return nil, err
+	}
+	values := endpoint.Query()
+	switch t.Backend {
+	case WebSearchSearXNG:
+		endpoint = endpoint.JoinPath("search")
+		values.Set("q", query)
+		values.Set("format", "json")
+	case WebSearchBrave:
+		values.Set("q", query)
+		values.Set("count", fmt.Sprint(count))
+	default:
+		values.Set(orDefault(t.QueryParam, "q"), query)
+		if t.CountParam != "" {
+			values.Set(t.CountParam, fmt.Sprint(count))
+		}
+	}
+	endpoint.RawQuery = values.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if t.APIKey != "" {
+		if t.Backend == WebSearchBrave {
+			req.Header.Set("X-Subscription-Token", t.APIKey)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+t.APIKey)
+		}
+	}
+	return req, nil
+}
+
+// parseResults reads the results of the backend response
+func (t *WebSearchTool) parseResults(body []byte) ([]WebSearchResult, error) {
+	var resultsField, titleField, urlField, snippetField string
+	switch t.Backend {
+	case WebSearchSearXNG:
+		resultsField, titleField, urlField, snippetField = "results", "title", "url", "content"
+	case WebSearchBrave:
+		resultsField, titleField, urlField, snippetField = "web.results", "title", "url", "description"
+	default:
+		resultsField = orDefault(t.ResultsField, "results")
+		titleField = orDefault(t.TitleField, "title")
+		urlField = orDefault(t.URLField, "url")
+		snippetField = orDefault(t.SnippetField, "snippet")
+	}
+
+	var response interface{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+	value := response
+	for _, key := range strings.Split(resultsField, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("no %s array in the response", resultsField)
+		}
+		value = object[key]
+	}
+	if value == nil {
+		// Brave omits the web results when nothing matches
+		return nil, nil
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s is not an array", resultsField)
+	}
+
+	results := make([]WebSearchResult, 0, len(items))
+	for _, item := range items {
+		object, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		result := WebSearchResult{
+			Title:   stringField(object, titleField),
+			URL:     stringField(object, urlField),
+			Snippet: stringField(object, snippetField),
+		}
+		if result.URL == "" {
+			continue
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// formatWebSearchResults formats the results as a numbered list
+func formatWebSearchResults(query string, results []WebSearchResult) string {
+	if len(results) == 0 {
+		return fmt.Sprintf("No results found for %q", query)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Results for %q:\n", query)
+	for i, result := range results {
+		title := result.Title
+		if title == "" {
+			title = result.URL
+		}
+		fmt.Fprintf(&sb, "\n%d. %s\n   %s\n", i+1, title, result.URL)
+		if snippet := strings.Join(strings.Fields(result.Snippet), " "); snippet != "" {
+			fmt.Fprintf(&sb, "   %s\n", snippet)
+		}
+	}
+	return sb.String()
+}
+
+// stringField returns the string value of a field, or an empty string
+func stringField(object map[string]interface{}, field string) string {
+	value, _ := object[field].(string)
+	return strings.TrimSpace(value)
+}
+
+// orDefault returns value, or fallback when value is empty
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
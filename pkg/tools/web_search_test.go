@@ -0,0 +1,142 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebSearchToolBackends(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		query := r.URL.Query()
+		switch r.URL.Path {
+		case "/search":
+			if query.Get("format") != "json" {
+				http.Error(w, "format", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintf(w, `{"results":[{"title":"SearX %s","url":"https://a.example/","content":"first\n  snippet"},{"title":"Two","url":"https://b.example/","content":"second"}]}`, query.Get("q"))
+		case "/brave":
+			if r.Header.Get("X-Subscription-Token") != "secret" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprintf(w, `{"web":{"results":[{"title":"Brave %s","url":"https://c.example/","description":"count %s"}]}}`, query.Get("q"), query.Get("count"))
+		case "/json":
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprintf(w, `{"data":{"hits":[{"name":"Custom %s","link":"https://d.example/","text":"custom"},{"name":"no link"}]}}`, query.Get("query"))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		tool WebSearchTool
+		args string
+		want []string
+		skip string
+	}{
+		{
+			name: "searxng",
+			tool: WebSearchTool{Backend: WebSearchSearXNG, URL: server.URL},
+			args: `{"query":"go","count":1}`,
+			want: []string{`Results for "go"`, "1. SearX go\n   https://a.example/\n   first snippet"},
+			skip: "https://b.example/",
+		},
+		{
+			name: "brave",
+			tool: WebSearchTool{Backend: WebSearchBrave, URL: server.URL + "/brave", APIKey: "secret"},
+			args: `{"query":"go"}`,
+			want: []string{"1. Brave go", "count 5"},
+		},
+		{
+			name: "json",
+			tool: WebSearchTool{Backend: WebSearchJSON, URL: server.URL + "/json", APIKey: "secret", QueryParam: "query",
+				ResultsField: "data.hits", TitleField: "name", URLField: "link", SnippetField: "text"},
+			args: `{"query":"go"}`,
+			want: []string{"1. Custom go\n   https://d.example/\n   custom"},
+			skip: "no link",
+		},
+	}
+	for _, tt := range tests {
+		tt.tool.Timeout = 5
+		tt.tool.MaxResults = DEFAULT_WEB_SEARCH_MAX_RESULTS
+		result, err := tt.tool.InvokableRun(context.Background(), tt.args)
+		if err != nil {
+			t.Fatalf("%s: InvokableRun() error = %v", tt.name, err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(result, want) {
+				t.Errorf("%s: result = %q, want it to contain %q", tt.name, result, want)
+			}
+		}
+		if tt.skip != "" && strings.Contains(result, tt.skip) {
+			t.Errorf("%s: result = %q, want no %q", tt.name, result, tt.skip)
+		}
+	}
+}
+
+func TestWebSearchToolFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closedURL := closed.URL
+	closed.Close()
+
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{"unreachable", closedURL, "web search backend is unreachable"},
+		{"status", server.URL, "429 Too Many Requests: quota exceeded"},
+	}
+	for _, tt := range tests {
+		search := &WebSearchTool{Backend: WebSearchJSON, URL: tt.url, Timeout: 5, MaxResults: 5}
+		result, err := search.InvokableRun(context.Background(), `{"query":"go"}`)
+		if err != nil {
+			t.Fatalf("%s: InvokableRun() error = %v", tt.name, err)
+		}
+		if !strings.Contains(result, tt.want) {
+			t.Errorf("%s: result = %q, want %q", tt.name, result, tt.want)
+		}
+	}
+}
+
+func TestGetWebSearchToolsValidates(t *testing.T) {
+	tests := []struct {
+		name   string
+		params map[string]interface{}
+		want   string
+	}{
+		{"no backend", map[string]interface{}{"url": "http://localhost"}, "requires backend"},
+		{"unknown backend", map[string]interface{}{"backend": "bing"}, "unknown web_search backend"},
+		{"no url", map[string]interface{}{"backend": "searxng"}, "requires url"},
+		{"no brave key", map[string]interface{}{"backend": "brave"}, "requires apiKey"},
+	}
+	for _, tt := range tests {
+		_, err := getWebSearchTools(context.Background(), tt.params)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	tools, err := getWebSearchTools(context.Background(), map[string]interface{}{"backend": "Brave", "apiKey": "key", "maxResults": 100})
+	if err != nil {
+		t.Fatalf("getWebSearchTools() error = %v", err)
+	}
+	search := tools[0].(*WebSearchTool)
+	if search.URL != braveSearchURL || search.MaxResults != webSearchResultsLimit || search.Timeout != DEFAULT_WEB_SEARCH_TIMEOUT {
+		t.Errorf("tool = %+v, want the brave defaults and maxResults capped", search)
+	}
+}
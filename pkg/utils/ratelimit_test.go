@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterRefills(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewRateLimiter(6, 2)
+	l.now = func() time.Time { return now }
+
+	for i := range 2 {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("Allow() #%d = false, want the burst to pass", i+1)
+		}
+	}
+	ok, wait := l.Allow("a")
+	if ok || wait != 10*time.Second {
+		t.Fatalf("Allow() after the burst = %v, %v, want false, 10s", ok, wait)
+	}
+	if ok, _ := l.Allow("b"); !ok {
+		t.Error("Allow() of another key = false, want its own bucket")
+	}
+
+	// One token is back after 10s, the rejected call took none
+	now = now.Add(10 * time.Second)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Error("Allow() after 10s = false, want a refilled token")
+	}
+	if ok, wait := l.Allow("a"); ok || wait != 10*time.Second {
+		t.Errorf("Allow() = %v, %v, want false, 10s", ok, wait)
+	}
+
+	// A long pause refills up to the burst only
+	now = now.Add(time.Hour)
+	for i := range 2 {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("Allow() #%d after an hour = false", i+1)
+		}
+	}
+	if ok, _ := l.Allow("a"); ok {
+		t.Error("Allow() = true beyond the burst")
+	}
+}
+
+func TestRateLimiterDefaultBurst(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewRateLimiter(3, 0)
+	l.now = func() time.Time { return now }
+	for i := range 3 {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("Allow() #%d = false, want a burst of perMinute", i+1)
+		}
+	}
+	if ok, _ := l.Allow("a"); ok {
+		t.Error("Allow() = true beyond perMinute")
+	}
+	l.Forget("a")
+	if ok, _ := l.Allow("a"); !ok {
+		t.Error("Allow() after Forget = false, want a full bucket")
+	}
+}
@@ -2,7 +2,12 @@ package utils
 
 import (
 	"context"
+	"errors"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 func IsRetryAble(ctx context.Context, err error) bool {
@@ -15,3 +20,133 @@ func IsRetryAble(ctx context.Context, err error) bool {
 	}
 	return false
 }
+
+// RetryPolicy computes the wait before retrying a rate limited model call. A delay
+// requested by the provider (Retry-After) is honored, otherwise the wait grows
+// exponentially from BaseDelay up to MaxDelay. MaxTotalWait caps the sum of the waits of
+// one retry cycle, so a provider that keeps rejecting requests can't stall a session.
+type RetryPolicy struct {
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	MaxTotalWait time.Duration // 0 disables the cap
+}
+
+// DefaultRetryPolicy returns the policy used for model calls
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		BaseDelay:    time.Second,
+		MaxDelay:     30 * time.Second,
+		MaxTotalWait: 2 * time.Minute,
+	}
+}
+
+// Backoff returns the wait before the given retry attempt (starting at 1). The delay
+// requested by the error takes precedence over the exponential backoff.
+func (p RetryPolicy) Backoff(attempt int, err error) time.Duration {
+	if delay, ok := RetryAfter(err); ok {
+		return delay
+	}
+	return p.exponential(attempt)
+}
+
+func (p RetryPolicy) exponential(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return delay
+}
+
+// RetryAfterError is implemented by errors that carry the delay requested by the server,
+// usually taken from the Retry-After header of the HTTP response
+type RetryAfterError interface {
+	RetryAfter() time.Duration
+}
+
+// retryAfterPattern matches the retry hints providers put in their error messages, e.g.
+// "Retry-After: 20", "retry_after": 1.5, "Please try again in 6.5s" or "retryDelay": "25s"
+var retryAfterPattern = regexp.MustCompile(`(?i)(?:retry[-_ ]?after|retry[-_ ]?delay|try again in)["']?\s*[:=]?\s*["']?(\d+(?:\.\d+)?)\s*(ms|milliseconds?|s|secs?|seconds?|m|mins?|minutes?)?\b`)
+
+// RetryAfter returns the delay requested by the server for a rate limited request, from a
+// wrapped RetryAfterError or from a hint in the error message. Hints without a unit are
+// in seconds, like the Retry-After header.
+func RetryAfter(err error) (time.Duration, bool) {
+	if err == nil {
+		return 0, false
+	}
+	var hinted RetryAfterError
+	if errors.As(err, &hinted) {
+		if delay := hinted.RetryAfter(); delay > 0 {
+			return delay, true
+		}
+	}
+	match := retryAfterPattern.FindStringSubmatch(err.Error())
+	if match == nil {
+		return 0, false
+	}
+	value, parseErr := strconv.ParseFloat(match[1], 64)
+	if parseErr != nil || value <= 0 {
+		return 0, false
+	}
+	unit := time.Second
+	switch strings.ToLower(match[2]) {
+	case "ms", "millisecond", "milliseconds":
+		unit = time.Millisecond
+	case "m", "min", "mins", "minute", "minutes":
+		unit = time.Minute
+	}
+	return time.Duration(value * float64(unit)), true
+}
+
+// Retrier applies a RetryPolicy to the retry cycles of one model, keeping track of the
+// time already waited in the current cycle
+type Retrier struct {
+	policy RetryPolicy
+
+	mu     sync.Mutex
+	waited time.Duration
+}
+
+// NewRetrier creates a Retrier for the policy
+func NewRetrier(policy RetryPolicy) *Retrier {
+	return &Retrier{policy: policy}
+}
+
+// Next decides whether the failed call should be retried and how long to wait before.
+// Attempt 1 starts a new retry cycle. A call is not retried when the error is not
+// retryable or when the wait would exceed the total wait left in the cycle; an
+// exponential delay is shortened to fit, a delay requested by the server is not since
+// retrying earlier would fail again.
+func (r *Retrier) Next(ctx context.Context, attempt int, err error) (time.Duration, bool) {
+	if err == nil || !IsRetryAble(ctx, err) {
+		return 0, false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if attempt <= 1 {
+		r.waited = 0
+	}
+
+	delay, hinted := RetryAfter(err)
+	if !hinted {
+		delay = r.policy.exponential(attempt)
+	}
+	if r.policy.MaxTotalWait > 0 {
+		remaining := r.policy.MaxTotalWait - r.waited
+		if remaining <= 0 || (hinted && delay > remaining) {
+			return 0, false
+		}
+		if delay > remaining {
+			delay = remaining
+		}
+	}
+	r.waited += delay
+	return delay, true
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		err  string
+		want time.Duration
+		ok   bool
+	}{
+		{"error, status code: 429, message: Rate limit reached. Please try again in 6.5s.", 6500 * time.Millisecond, true},
+		{"429 Too Many Requests, Retry-After: 20", 20 * time.Second, true},
+		{`{"error": {"code": 429, "retry_after": 1.5}}`, 1500 * time.Millisecond, true},
+		{`"retryDelay": "25s"`, 25 * time.Second, true},
+		{"rate limited, retry after 250ms", 250 * time.Millisecond, true},
+		{"too many requests, try again in 2 minutes", 2 * time.Minute, true},
+		{"status code: 429, message: too many requests", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := RetryAfter(errors.New(tt.err))
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("RetryAfter(%q) = %v, %v, want %v, %v", tt.err, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRetrierCapsTotalWait(t *testing.T) {
+	ctx := context.Background()
+	r := NewRetrier(RetryPolicy{BaseDelay: time.Second, MaxDelay: 4 * time.Second, MaxTotalWait: 10 * time.Second})
+	rateLimited := errors.New("status code: 429, message: too many requests")
+
+	var delays []time.Duration
+	for attempt := 1; ; attempt++ {
+		delay, ok := r.Next(ctx, attempt, rateLimited)
+		if !ok {
+			break
+		}
+		delays = append(delays, delay)
+	}
+	// 1s, 2s, 4s, 3s to fit the 10s cap
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 3 * time.Second}
+	if len(delays) != len(want) {
+		t.Fatalf("delays = %v, want %v", delays, want)
+	}
+	for i := range want {
+		if delays[i] != want[i] {
+			t.Fatalf("delays = %v, want %v", delays, want)
+		}
+	}
+
+	// A new cycle starts over, a requested delay beyond the cap is not waited for
+	if _, ok := r.Next(ctx, 1, errors.New("too many requests, retry after 30s")); ok {
+		t.Error("Next() retried with a Retry-After beyond the total wait")
+	}
+	if delay, ok := r.Next(ctx, 1, errors.New("too many requests, retry after 5s")); !ok || delay != 5*time.Second {
+		t.Errorf("Next() = %v, %v, want 5s", delay, ok)
+	}
+	if _, ok := r.Next(ctx, 1, errors.New("invalid api key")); ok {
+		t.Error("Next() retried an error that is not retryable")
+	}
+}
@@ -451,6 +451,28 @@ function updateClearBadge(count) {
     }
 }
 
+// Show the token usage reported by the provider on the message count badge
+function updateUsage(turn, session) {
+    const countEl = document.getElementById('clear-count');
+    if (!countEl || !turn || !session) return;
+    countEl.title = `Last turn: ${turn.total_tokens} tokens (${turn.prompt_tokens} prompt, ${turn.completion_tokens} completion)\nChat total: ${session.total_tokens} tokens`;
+}
+
+// Show that older turns are being summarized, they are replaced by the summary when done
+function handleContextEvent(payload) {
+    const indicator = document.getElementById('context-indicator');
+    if (!indicator) return;
+    if (payload.event === 'compression_started') {
+        indicator.style.display = 'inline';
+        setStatus(`Summarizing earlier conversation (${payload.rounds} rounds)…`, false);
+    } else if (payload.event === 'compression_completed') {
+        indicator.style.display = 'none';
+        if (payload.summary) {
+            setStatus(`Summarized ${payload.rounds} earlier rounds`, false);
+        }
+    }
+}
+
 // Detect if device is mobile
 function isMobileDevice() {
     return /Android|webOS|iPhone|iPad|iPod|BlackBerry|IEMobile|Opera Mini/i.test(navigator.userAgent) ||
@@ -499,6 +521,7 @@ let toolCalls = {};
 // Track pending approval requests
 let pendingApprovals = {};
 let currentApprovalId = null;
+let currentQuestionId = null;
 
 // File upload functions are now in file-upload.js module
 // Access via window.FileUploadHandler
@@ -1068,10 +1091,13 @@ function handleMessage(msg) {
             displayToolCall(
                 msg.payload.name,
                 msg.payload.arguments,
-                msg.payload.index,
+                msg.payload.id || msg.payload.index,
                 msg.payload.streaming
             );
             break;
+        case 'tool_progress':
+            displayToolProgress(msg.payload.id, msg.payload.progress);
+            break;
         case 'complete':
             // 只有在生成中才重置状态（避免重复处理）
             if (isGenerating) {
@@ -1091,6 +1117,10 @@ function handleMessage(msg) {
             }
             smartScrollToBottom(true);
             //setStatus('Response completed', false);
+            // The turn ended early, e.g. on the request timeout
+            if (msg.payload.message) {
+                setStatus(msg.payload.message, true);
+            }
             break;
         case 'error':
             setStatus(msg.payload.error, true);
@@ -1118,6 +1148,22 @@ function handleMessage(msg) {
                 addRegenerateButton(lastUserMessageElement);
             }
             break;
+        case 'content_filtered':
+            // The provider blocked the response, this is not an agent failure
+            setStatus(msg.payload.message, true);
+            if (isGenerating) {
+                const inputFiltered = document.getElementById('message-input');
+                if (inputFiltered) {
+                    inputFiltered.disabled = false;
+                    if (!isMobileDevice()) {
+                        inputFiltered.focus();
+                    }
+                }
+                isGenerating = false;
+                updateSendButton();
+                addRegenerateButton(lastUserMessageElement);
+            }
+            break;
         case 'stopped':
             // 只有在生成中才重置状态
             if (isGenerating) {
@@ -1133,6 +1179,10 @@ function handleMessage(msg) {
                 }
                 // Show regenerate button after stop (partial response)
                 addRegenerateButton(lastUserMessageElement);
+                // Let the user continue the stopped response
+                if (msg.payload && msg.payload.resumable) {
+                    addResumeButton(lastUserMessageElement);
+                }
             }
             break;
         case 'cleared':
@@ -1145,9 +1195,39 @@ function handleMessage(msg) {
         case 'kept':
             setStatus(msg.payload.message, false);
             break;
+        case 'tools_reloaded':
+            setStatus(msg.payload.message, false);
+            showToast(msg.payload.message, false);
+            if (document.getElementById('tools-modal').style.display !== 'none') {
+                requestToolList();
+            }
+            break;
+        case 'tools':
+            renderToolList(msg.payload.tools || []);
+            if (msg.payload.message) {
+                showToast(msg.payload.message, false);
+            }
+            break;
         case 'approval_request':
             handleApprovalRequest(msg.payload);
             break;
+        case 'approval_warning':
+            if (currentApprovalId === msg.payload.approval_id) {
+                setStatus(`Approval expires in ${msg.payload.remaining_seconds}s, the tool calls will be denied`, true);
+            }
+            break;
+        case 'shutting_down':
+            setStatus('The server is shutting down, the current response can still complete', true);
+            break;
+        case 'approval_resolved':
+            // Answered from another window sharing the session
+            if (currentApprovalId === msg.payload.approval_id) {
+                hideApprovalModal();
+            }
+            break;
+        case 'question':
+            showQuestionModal(msg.payload);
+            break;
         case 'thinking':
             break;
         case 'message_count':
@@ -1156,6 +1236,12 @@ function handleMessage(msg) {
                 updateClearBadge(msg.payload.count);
             }
             break;
+        case 'usage':
+            updateUsage(msg.payload.turn, msg.payload.session);
+            break;
+        case 'context_event':
+            handleContextEvent(msg.payload);
+            break;
         default:
             console.log('Unknown message type:', msg.type);
     }
@@ -1172,7 +1258,9 @@ function handleApprovalRequest(payload) {
         pendingApprovals[target.id] = {
             tool: target.tool,
             details: target.details,
+            preview: target.preview,
             approved: null,  // null = no decision yet, true = approved, false = denied
+            always: false,   // approve the same call for the rest of the session
             reason: ''
         };
     });
@@ -1194,7 +1282,10 @@ function showApprovalModal(targets) {
 
         // Format the details - single line (same as tool-call dialog)
         let detailsHtml = '';
-        if (target.details) {
+        if (target.preview) {
+            // Readable preview of the call, e.g. the diff of a file edit
+            detailsHtml = `<pre>${escapeHtml(target.preview)}</pre>`;
+        } else if (target.details) {
             try {
                 const detailsObj = typeof target.details === 'string'
                     ? JSON.parse(target.details)
@@ -1215,6 +1306,7 @@ function showApprovalModal(targets) {
                 <div class="approval-result" id="approval-result-${escapeHtml(target.id)}"></div>
                 <div class="approval-actions">
                     <button class="btn-approve" onclick="approveTarget('${escapeHtml(target.id)}')">Approve</button>
+                    <button class="btn-approve" title="Approve this exact call for the rest of the session" onclick="approveTarget('${escapeHtml(target.id)}', true)">Always</button>
                     <button class="btn-deny" onclick="denyTarget('${escapeHtml(target.id)}')">Deny</button>
                 </div>
             </div>
@@ -1230,16 +1322,19 @@ function showApprovalModal(targets) {
     document.body.style.overflow = 'hidden'; // Prevent background scrolling
 }
 
-// Approve a specific target
-function approveTarget(targetId) {
+// Approve a specific target, optionally for the rest of the session
+function approveTarget(targetId, always = false) {
     if (pendingApprovals[targetId]) {
         pendingApprovals[targetId].approved = true;
+        pendingApprovals[targetId].always = always;
         pendingApprovals[targetId].reason = '';
 
         // Update UI
         const resultEl = document.getElementById(`approval-result-${targetId}`);
         if (resultEl) {
-            resultEl.innerHTML = '<span class="approved-text">✅ Approved</span>';
+            resultEl.innerHTML = always
+                ? '<span class="approved-text">✅ Always approved</span>'
+                : '<span class="approved-text">✅ Approved</span>';
             resultEl.className = 'approval-result approved';
         }
 
@@ -1355,6 +1450,7 @@ function submitApprovals() {
         if (target.approved === true || target.approved === false) {
             results[targetId] = {
                 approved: target.approved,
+                always: target.approved === true && target.always,
                 reason: target.reason || ''
             };
             decisionCount++;
@@ -1390,6 +1486,41 @@ function submitApprovals() {
     pendingApprovals = {};
 }
 
+// Show a clarifying question from the ask_user tool
+function showQuestionModal(payload) {
+    currentQuestionId = payload.question_id;
+    document.getElementById('question-text').textContent = payload.question;
+    const answer = document.getElementById('question-answer');
+    answer.value = '';
+    document.getElementById('question-modal').style.display = 'flex';
+    document.body.style.overflow = 'hidden'; // Prevent background scrolling
+    answer.focus();
+}
+
+// Send the answer to the pending question
+function submitAnswer() {
+    if (!currentQuestionId) {
+        return;
+    }
+
+    if (ws && ws.readyState === WebSocket.OPEN) {
+        ws.send(JSON.stringify({
+            type: 'question_response',
+            payload: {
+                question_id: currentQuestionId,
+                answer: document.getElementById('question-answer').value.trim()
+            }
+        }));
+    } else {
+        showToast('WebSocket not connected', true);
+        return;
+    }
+
+    document.getElementById('question-modal').style.display = 'none';
+    document.body.style.overflow = ''; // Restore scrolling
+    currentQuestionId = null;
+}
+
 // Hide approval modal
 function hideApprovalModal() {
     document.getElementById('approval-modal').style.display = 'none';
@@ -1398,25 +1529,22 @@ function hideApprovalModal() {
     pendingApprovals = {};
 }
 
-// Cancel approval (deny all with default reason)
+// Cancel approval (the server denies all tool calls as cancelled)
 function cancelApprovals() {
-    // Deny all pending targets
-    Object.keys(pendingApprovals).forEach(targetId => {
-        if (pendingApprovals[targetId].approved === null || pendingApprovals[targetId].approved === undefined) {
-            pendingApprovals[targetId].approved = false;
-            pendingApprovals[targetId].reason = 'Cancelled by user';
-
-            // Update UI
-            const resultEl = document.getElementById(`approval-result-${targetId}`);
-            if (resultEl) {
-                resultEl.innerHTML = '<span class="denied-text">❌ Cancelled</span>';
-                resultEl.className = 'approval-result denied';
+    if (!currentApprovalId) {
+        return;
+    }
+    if (ws && ws.readyState === WebSocket.OPEN) {
+        ws.send(JSON.stringify({
+            type: 'cancel_approval',
+            payload: {
+                approval_id: currentApprovalId
             }
-        }
-    });
-
-    // Submit the approvals (all denied)
-    submitApprovals();
+        }));
+    } else {
+        console.error('WebSocket not open, readyState:', ws ? ws.readyState : 'ws is null');
+    }
+    hideApprovalModal();
 }
 
 function sendMessage() {
@@ -1562,14 +1690,63 @@ function addRegenerateButton(messageElement) {
     footer.appendChild(regenBtn);
 }
 
-// Remove regenerate button from the last user message
+// Add resume button to continue a stopped response
+function addResumeButton(messageElement) {
+    if (!messageElement) return;
+
+    // Don't add if already has a resume button
+    if (messageElement.querySelector('.resume-btn')) return;
+
+    const footer = messageElement.querySelector('.message-footer');
+    if (!footer) return;
+
+    const resumeBtn = document.createElement('button');
+    resumeBtn.className = 'copy-btn resume-btn';
+    resumeBtn.title = 'Continue the stopped response';
+    resumeBtn.innerHTML = `
+        <svg width="14" height="14" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round">
+            <polygon points="5 3 19 12 5 21 5 3"></polygon>
+        </svg>
+        <span class="copy-text">Resume</span>
+    `;
+    resumeBtn.onclick = function (e) {
+        e.stopPropagation();
+        resumeResponse();
+    };
+    footer.appendChild(resumeBtn);
+}
+
+// Remove regenerate and resume buttons from the last user message
 function removeRegenerateFromLastMessage() {
     if (lastUserMessageElement) {
-        const regenBtn = lastUserMessageElement.querySelector('.regen-btn');
-        if (regenBtn) {
-            regenBtn.remove();
-        }
+        lastUserMessageElement.querySelectorAll('.regen-btn, .resume-btn').forEach(btn => btn.remove());
+    }
+}
+
+// Continue the last response after it was stopped
+function resumeResponse() {
+    if (isGenerating) return;
+
+    if (!ws || ws.readyState !== WebSocket.OPEN) {
+        showToast('WebSocket not connected', true);
+        return;
+    }
+
+    removeRegenerateFromLastMessage();
+    scrollToBottom(true);
+
+    // Disable input and set generating state
+    const input = document.getElementById('message-input');
+    if (input) {
+        input.disabled = true;
     }
+    isGenerating = true;
+    updateSendButton();
+
+    ws.send(JSON.stringify({
+        type: 'resume',
+        payload: {}
+    }));
 }
 
 // Regenerate response - resend the last user message
@@ -1789,6 +1966,30 @@ function addMessage(text, type) {
     smartScrollToBottom();
 }
 
+// Output shown for a running tool call, older output is dropped
+const MAX_TOOL_PROGRESS_LENGTH = 4000;
+
+// Show intermediate output of a running tool call below its arguments
+function displayToolProgress(index, progress) {
+    const toolCall = toolCalls[index];
+    if (!toolCall || !progress) {
+        return;
+    }
+    if (!toolCall.progressElement) {
+        const pre = document.createElement('pre');
+        pre.className = 'tool-progress';
+        toolCall.element.appendChild(pre);
+        toolCall.progressElement = pre;
+    }
+    let text = toolCall.progressElement.textContent + progress;
+    if (text.length > MAX_TOOL_PROGRESS_LENGTH) {
+        text = text.slice(text.length - MAX_TOOL_PROGRESS_LENGTH);
+    }
+    toolCall.progressElement.textContent = text;
+    toolCall.progressElement.scrollTop = toolCall.progressElement.scrollHeight;
+    smartScrollToBottom();
+}
+
 function displayToolCall(name, args, index, streaming) {
     // Get or create the tool call entry
     let toolCall = toolCalls[index];
@@ -2467,13 +2668,91 @@ function keepSession() {
     }
 
     if (ws && ws.readyState === WebSocket.OPEN) {
-        ws.send(JSON.stringify({ type: 'keep', payload: {} }));
+        // Optional label passed to the hook to categorize the kept session
+        const label = window.prompt('Label for the kept session (optional):', '');
+        if (label === null) {
+            return;
+        }
+        ws.send(JSON.stringify({ type: 'keep', payload: { label: label.trim() } }));
         showToast('Executing keep hook...', false);
     } else {
         showToast('WebSocket not connected', true);
     }
 }
 
+// Show the tools of the current chat, with a checkbox to enable or disable each
+function showToolsModal() {
+    if (!currentChat) {
+        showToast('Please select a chat first', true);
+        return;
+    }
+    document.getElementById('tools-list').textContent = 'Loading...';
+    document.getElementById('tools-modal').style.display = 'flex';
+    requestToolList();
+}
+
+function hideToolsModal() {
+    document.getElementById('tools-modal').style.display = 'none';
+}
+
+function requestToolList() {
+    if (ws && ws.readyState === WebSocket.OPEN) {
+        ws.send(JSON.stringify({ type: 'list_tools', payload: {} }));
+    } else {
+        showToast('WebSocket not connected', true);
+    }
+}
+
+function renderToolList(tools) {
+    const list = document.getElementById('tools-list');
+    list.innerHTML = '';
+    if (tools.length === 0) {
+        list.textContent = 'This chat has no tools.';
+        return;
+    }
+    tools.forEach(tool => {
+        const label = document.createElement('label');
+        const checkbox = document.createElement('input');
+        checkbox.type = 'checkbox';
+        checkbox.checked = tool.enabled;
+        checkbox.onchange = () => toggleTool(tool.name, checkbox.checked);
+        const text = document.createElement('span');
+        const name = document.createElement('strong');
+        name.textContent = tool.name;
+        const desc = document.createElement('span');
+        desc.className = 'tool-desc';
+        desc.textContent = tool.description;
+        text.appendChild(name);
+        text.appendChild(desc);
+        label.appendChild(checkbox);
+        label.appendChild(text);
+        list.appendChild(label);
+    });
+}
+
+function toggleTool(name, enabled) {
+    if (ws && ws.readyState === WebSocket.OPEN) {
+        ws.send(JSON.stringify({ type: 'toggle_tool', payload: { name: name, enabled: enabled } }));
+    } else {
+        showToast('WebSocket not connected', true);
+    }
+}
+
+// Reload tools - re-initialize tools and MCP servers of the current chat
+function reloadTools() {
+    if (!currentChat) {
+        showToast('Please select a chat first', true);
+        return;
+    }
+
+    if (ws && ws.readyState === WebSocket.OPEN) {
+        ws.send(JSON.stringify({ type: 'reload_tools', payload: {} }));
+        showToast('Reloading tools...', false);
+    } else {
+        showToast('WebSocket not connected', true);
+    }
+}
+
 // autoResize function removed - input box height is now fixed via CSS
 
 function handleKeyDown(e) {